@@ -0,0 +1,230 @@
+// Package chaos injects faults into the Cartridge Go services so integration
+// tests can exercise their resilience paths: client retries, command
+// redelivery, health transitions on missed heartbeats and partial batch writes.
+//
+// An Injector is built from a Config. Tests construct one directly; a service
+// process reads its Config with FromEnv, which only honours the CHAOS_*
+// variables in binaries built with the chaos build tag, so production builds
+// can never be configured to fail on purpose. A nil or zero Injector injects
+// nothing.
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Config selects the faults to inject. Rates are fractions between 0 and 1.
+type Config struct {
+	// Match limits faults to gRPC calls whose full method, or HTTP requests
+	// whose path, contains one of these substrings. Empty matches everything.
+	Match []string
+
+	// ErrorRate is the share of matching calls failed with ErrorCode, or for
+	// HTTP with the corresponding status code.
+	ErrorRate float64
+	// ErrorCode defaults to Unavailable, which clients treat as retryable.
+	ErrorCode codes.Code
+	// Latency delays each matching call by a random duration up to Latency.
+	Latency time.Duration
+	// DropRate is the share of matching HTTP requests dropped: the connection
+	// is closed without a response and the handler never runs, as if the
+	// request was lost in transit.
+	DropRate float64
+	// PartialRate is the share of batch writes that only partly succeed. Each
+	// service decides what a batch is; see Injector.Partial.
+	PartialRate float64
+
+	// Seed makes the injected faults reproducible. Zero seeds from the clock.
+	Seed int64
+}
+
+// Enabled reports whether c injects any fault.
+func (c Config) Enabled() bool {
+	return c.ErrorRate > 0 || c.Latency > 0 || c.DropRate > 0 || c.PartialRate > 0
+}
+
+// Injector decides, call by call, which faults to inject.
+type Injector struct {
+	cfg Config
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New returns an Injector for cfg, or nil when cfg injects nothing.
+func New(cfg Config) *Injector {
+	if !cfg.Enabled() {
+		return nil
+	}
+	if cfg.ErrorCode == codes.OK {
+		cfg.ErrorCode = codes.Unavailable
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{cfg: cfg, rand: rand.New(rand.NewSource(seed))}
+}
+
+// Partial decides the fate of a batch of n items that would otherwise all be
+// written, returning how many leading items to write. It returns n unless the
+// batch is chosen to partly fail.
+func (i *Injector) Partial(n int) int {
+	if i == nil || n == 0 || !i.roll(i.cfg.PartialRate) {
+		return n
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Intn(n)
+}
+
+// UnaryServerInterceptor injects latency and errors into matching unary calls
+// before they reach the handler.
+func (i *Injector) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := i.call(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func (i *Injector) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := i.call(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// UnaryClientInterceptor injects latency and errors into matching calls made
+// over a client connection, without sending them.
+func (i *Injector) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := i.call(ctx, method); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// HTTP returns middleware that injects latency, errors and dropped requests
+// into matching requests. Injected errors are plain-text responses with the
+// HTTP status corresponding to ErrorCode, such as 503 for Unavailable.
+func (i *Injector) HTTP(next http.Handler) http.Handler {
+	if i == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !i.matches(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := i.delay(r.Context()); err != nil {
+			return
+		}
+		if i.roll(i.cfg.DropRate) {
+			// The server recovers ErrAbortHandler by closing the connection
+			// without logging it.
+			panic(http.ErrAbortHandler)
+		}
+		if i.roll(i.cfg.ErrorRate) {
+			http.Error(w, "chaos: injected fault", httpStatus(i.cfg.ErrorCode))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// call applies the latency and error faults to a gRPC method.
+func (i *Injector) call(ctx context.Context, method string) error {
+	if i == nil || !i.matches(method) {
+		return nil
+	}
+	if err := i.delay(ctx); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if i.roll(i.cfg.ErrorRate) {
+		return status.Errorf(i.cfg.ErrorCode, "chaos: injected fault in %s", method)
+	}
+	return nil
+}
+
+func (i *Injector) matches(name string) bool {
+	if len(i.cfg.Match) == 0 {
+		return true
+	}
+	for _, pattern := range i.cfg.Match {
+		if strings.Contains(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay sleeps for a random share of the configured latency, returning early
+// with ctx's error if it ends first.
+func (i *Injector) delay(ctx context.Context) error {
+	if i.cfg.Latency <= 0 {
+		return nil
+	}
+	i.mu.Lock()
+	d := time.Duration(i.rand.Int63n(int64(i.cfg.Latency) + 1))
+	i.mu.Unlock()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// roll reports whether a fault with the given rate happens this time.
+func (i *Injector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Float64() < rate
+}
+
+// httpStatus maps a gRPC code to the HTTP status a service would answer with.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,118 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewInjectsNothingByDefault(t *testing.T) {
+	if New(Config{Match: []string{"Store"}}) != nil {
+		t.Fatal("expected no injector for a config without faults")
+	}
+	var injector *Injector
+	if got := injector.Partial(7); got != 7 {
+		t.Fatalf("nil injector: expected the whole batch, got %d", got)
+	}
+	called := false
+	injector.HTTP(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Fatal("nil injector: expected the handler to run")
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	intercept := New(Config{ErrorRate: 1, ErrorCode: codes.ResourceExhausted, Match: []string{"StoreBatch"}}).UnaryServerInterceptor()
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	_, err := intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/replay.v1.Replay/StoreBatch"}, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected an injected ResourceExhausted, got %v", err)
+	}
+	resp, err := intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/replay.v1.Replay/Sample"}, handler)
+	if err != nil || resp != "ok" {
+		t.Fatalf("expected unmatched methods to pass through, got %v %v", resp, err)
+	}
+}
+
+func TestLatencyRespectsDeadline(t *testing.T) {
+	intercept := New(Config{Latency: time.Hour, Seed: 1}).UnaryClientInterceptor()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := intercept(ctx, "/replay.v1.Replay/GetStats", nil, nil, nil, func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		t.Fatal("expected the call not to be sent")
+		return nil
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestHTTPDropsAndFails(t *testing.T) {
+	var handled int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handled++ })
+	dropper := httptest.NewServer(New(Config{DropRate: 1, Match: []string{"/heartbeat"}}).HTTP(next))
+	defer dropper.Close()
+
+	if _, err := http.Post(dropper.URL+"/api/v1/runs/r1/heartbeat", "application/json", nil); err == nil {
+		t.Fatal("expected a dropped request to fail in transit")
+	}
+	res, err := http.Get(dropper.URL + "/api/v1/runs/r1")
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("expected unmatched paths to be served, got %v %v", res, err)
+	}
+	res.Body.Close()
+	if handled != 1 {
+		t.Fatalf("expected only the unmatched request handled, got %d", handled)
+	}
+
+	rec := httptest.NewRecorder()
+	New(Config{ErrorRate: 1}).HTTP(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected an injected 503, got %d", rec.Code)
+	}
+}
+
+func TestPartial(t *testing.T) {
+	injector := New(Config{PartialRate: 1, Seed: 42})
+	for i := 0; i < 20; i++ {
+		if got := injector.Partial(10); got < 0 || got >= 10 {
+			t.Fatalf("expected a strict prefix of the batch, got %d of 10", got)
+		}
+	}
+	if got := injector.Partial(0); got != 0 {
+		t.Fatalf("expected an empty batch to stay empty, got %d", got)
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	t.Setenv("CHAOS_ERROR_RATE", "0.25")
+	t.Setenv("CHAOS_ERROR_CODE", "deadline_exceeded")
+	t.Setenv("CHAOS_LATENCY", "50ms")
+	t.Setenv("CHAOS_MATCH", "/heartbeat, StoreBatch")
+	cfg, set, err := parseEnv()
+	if err != nil || !set {
+		t.Fatalf("parse: %v (set %v)", err, set)
+	}
+	if cfg.ErrorRate != 0.25 || cfg.ErrorCode != codes.DeadlineExceeded || cfg.Latency != 50*time.Millisecond || len(cfg.Match) != 2 || cfg.Match[1] != "StoreBatch" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	t.Setenv("CHAOS_DROP_RATE", "2")
+	if _, _, err := parseEnv(); err == nil {
+		t.Fatal("expected a rate above 1 to be rejected")
+	}
+
+	if !Built {
+		if _, err := FromEnv(); err == nil {
+			t.Fatal("expected CHAOS_* variables to be rejected without the chaos build tag")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package chaos
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// envPrefix starts the name of every variable FromEnv reads.
+const envPrefix = "CHAOS_"
+
+// parseEnv reads a Config from the CHAOS_* variables, reporting whether any
+// was set.
+func parseEnv() (Config, bool, error) {
+	var cfg Config
+	var set bool
+	lookup := func(name string) (string, bool) {
+		value, ok := os.LookupEnv(envPrefix + name)
+		set = set || ok
+		return value, ok && value != ""
+	}
+	rate := func(name string, into *float64) error {
+		raw, ok := lookup(name)
+		if !ok {
+			return nil
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 || value > 1 {
+			return fmt.Errorf("%s%s must be a number between 0 and 1, got %q", envPrefix, name, raw)
+		}
+		*into = value
+		return nil
+	}
+	for name, into := range map[string]*float64{
+		"ERROR_RATE":   &cfg.ErrorRate,
+		"DROP_RATE":    &cfg.DropRate,
+		"PARTIAL_RATE": &cfg.PartialRate,
+	} {
+		if err := rate(name, into); err != nil {
+			return Config{}, set, err
+		}
+	}
+	if raw, ok := lookup("ERROR_CODE"); ok {
+		if err := cfg.ErrorCode.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(raw)))); err != nil {
+			return Config{}, set, fmt.Errorf("%sERROR_CODE must name a gRPC status code, got %q", envPrefix, raw)
+		}
+	}
+	if raw, ok := lookup("LATENCY"); ok {
+		latency, err := time.ParseDuration(raw)
+		if err != nil || latency < 0 {
+			return Config{}, set, fmt.Errorf("%sLATENCY must be a non-negative duration, got %q", envPrefix, raw)
+		}
+		cfg.Latency = latency
+	}
+	if raw, ok := lookup("MATCH"); ok {
+		for _, pattern := range strings.Split(raw, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				cfg.Match = append(cfg.Match, pattern)
+			}
+		}
+	}
+	if raw, ok := lookup("SEED"); ok {
+		seed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return Config{}, set, fmt.Errorf("%sSEED must be an integer, got %q", envPrefix, raw)
+		}
+		cfg.Seed = seed
+	}
+	if cfg.ErrorCode == codes.OK {
+		cfg.ErrorCode = codes.Unavailable
+	}
+	return cfg, set, nil
+}
@@ -0,0 +1,14 @@
+//go:build chaos
+
+package chaos
+
+// Built reports whether the binary was built with the chaos tag.
+const Built = true
+
+// FromEnv reads the faults to inject from CHAOS_ERROR_RATE, CHAOS_ERROR_CODE,
+// CHAOS_LATENCY, CHAOS_DROP_RATE, CHAOS_PARTIAL_RATE, CHAOS_MATCH and
+// CHAOS_SEED. With none set it returns a Config that injects nothing.
+func FromEnv() (Config, error) {
+	cfg, _, err := parseEnv()
+	return cfg, err
+}
@@ -0,0 +1,19 @@
+//go:build !chaos
+
+package chaos
+
+import "fmt"
+
+// Built reports whether the binary was built with the chaos tag.
+const Built = false
+
+// FromEnv returns a Config that injects nothing: fault injection is only
+// available in binaries built with the chaos tag. Setting any CHAOS_* variable
+// is an error, so a deployment expecting faults does not silently run without
+// them.
+func FromEnv() (Config, error) {
+	if _, set, _ := parseEnv(); set {
+		return Config{}, fmt.Errorf("%s* variables are set but this binary was built without the chaos build tag", envPrefix)
+	}
+	return Config{}, nil
+}
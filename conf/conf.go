@@ -0,0 +1,381 @@
+// Package conf loads the configuration of the Cartridge Go services into a
+// struct, from four sources in increasing order of precedence: the defaults
+// declared on its fields, a YAML file, environment variables and command-line
+// flags.
+//
+// Each exported field is a setting, and each nested struct a section of the
+// YAML file. Struct tags declare where a setting may come from:
+//
+//	type ServerConfig struct {
+//		Port        int           `env:"PORT" flag:"port" default:"8080" usage:"HTTP port"`
+//		ReadTimeout time.Duration `env:"READ_TIMEOUT" default:"30s"`
+//	}
+//
+// A setting's YAML key is its yaml tag, or its field name in snake_case, and
+// its path is the keys of its sections and itself joined by dots, such as
+// server.read_timeout. Errors name settings by their path.
+//
+// Settings may be strings, booleans, integers, floats, time.Durations (see
+// ParseDuration), Sizes, types implementing encoding.TextUnmarshaler, and
+// slices and string-keyed maps of those. In the environment and on the command
+// line a slice is a comma-separated list and a map a comma-separated list of
+// key=value pairs.
+//
+// Once loaded, any struct in the configuration implementing Validator is
+// validated, innermost first.
+package conf
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable naming the YAML file to load, unless a
+// -config flag or WithFile names one.
+const FileEnv = "CONFIG_FILE"
+
+// Validator is implemented by configuration structs with checks that go beyond
+// parsing, such as ranges and settings that depend on one another. Paths in the
+// FieldErrors it returns are relative to the struct; any other error is
+// reported against the struct itself.
+type Validator interface {
+	Validate() error
+}
+
+// Option configures Load.
+type Option func(*loader)
+
+// WithFile loads the YAML file at path instead of the one named by the -config
+// flag or CONFIG_FILE. An empty path loads no file.
+func WithFile(path string) Option {
+	return func(l *loader) {
+		l.file = path
+		l.fileSet = true
+	}
+}
+
+// WithEnv looks environment variables up with lookup rather than
+// os.LookupEnv.
+func WithEnv(lookup func(key string) (string, bool)) Option {
+	return func(l *loader) { l.lookupEnv = lookup }
+}
+
+// WithFlags defines a flag on fs for each setting with a flag tag, and a
+// -config flag naming the YAML file, then parses args with fs. Flags the
+// caller defined on fs beforehand are parsed along with them.
+func WithFlags(fs *flag.FlagSet, args []string) Option {
+	return func(l *loader) {
+		l.flags = fs
+		l.args = args
+	}
+}
+
+// Load fills dst, a pointer to a struct, from its defaults, the YAML file,
+// environment variables and flags, in that order, then validates it. Every
+// setting that fails to parse or validate is reported in the returned Errors.
+func Load(dst any, opts ...Option) error {
+	root := reflect.ValueOf(dst)
+	if root.Kind() != reflect.Pointer || root.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("conf: Load needs a pointer to a struct, got %T", dst)
+	}
+	l := &loader{lookupEnv: os.LookupEnv, byPath: make(map[string]*setting)}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.collect(root.Elem(), "")
+
+	for _, s := range l.settings {
+		if s.def != "" {
+			l.set(s, s.def, "")
+		}
+	}
+	if err := l.parseFlags(); err != nil {
+		return err
+	}
+	if !l.fileSet {
+		l.file, _ = l.lookupEnv(FileEnv)
+	}
+	if l.file != "" {
+		if err := l.loadFile(root.Elem()); err != nil {
+			return err
+		}
+	}
+	for _, s := range l.settings {
+		if s.env == "" {
+			continue
+		}
+		if raw, ok := l.lookupEnv(s.env); ok && raw != "" {
+			l.set(s, raw, "env "+s.env)
+		}
+	}
+	if l.flags != nil {
+		l.flags.Visit(func(f *flag.Flag) {
+			if v, ok := f.Value.(*flagValue); ok && v.setting != nil {
+				l.set(v.setting, v.raw, "flag -"+f.Name)
+			}
+		})
+	}
+	if len(l.errs) > 0 {
+		return l.errs
+	}
+
+	l.validate(root.Elem(), "")
+	return l.errs.Err()
+}
+
+// setting is a field that holds a single value.
+type setting struct {
+	path  string
+	value reflect.Value
+	env   string
+	flag  string
+	def   string
+	usage string
+	// source is where the value was last set from, empty for its default.
+	source string
+}
+
+type loader struct {
+	file      string
+	fileSet   bool
+	lookupEnv func(string) (string, bool)
+	flags     *flag.FlagSet
+	args      []string
+
+	settings []*setting
+	byPath   map[string]*setting
+	errs     Errors
+}
+
+// collect records the settings of the struct v, whose path is prefix.
+func (l *loader) collect(v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := yamlKey(field)
+		if !field.IsExported() || key == "-" {
+			continue
+		}
+		path := join(prefix, key)
+		if isSection(field.Type) {
+			l.collect(v.Field(i), path)
+			continue
+		}
+		s := &setting{
+			path:  path,
+			value: v.Field(i),
+			env:   field.Tag.Get("env"),
+			flag:  field.Tag.Get("flag"),
+			def:   field.Tag.Get("default"),
+			usage: field.Tag.Get("usage"),
+		}
+		l.settings = append(l.settings, s)
+		l.byPath[path] = s
+	}
+}
+
+// set parses raw into s, recording an error against the setting if it is
+// invalid.
+func (l *loader) set(s *setting, raw, source string) {
+	if err := parseInto(s.value, raw); err != nil {
+		l.errs = append(l.errs, &FieldError{Path: s.path, Source: source, Err: err})
+		return
+	}
+	s.source = source
+}
+
+// parseFlags defines the flags and parses the arguments. Flag values are
+// only checked here; they are applied last, over the file and environment.
+func (l *loader) parseFlags() error {
+	if l.flags == nil {
+		return nil
+	}
+	file := &flagValue{}
+	l.flags.Var(file, "config", "YAML configuration `file` (overrides "+FileEnv+")")
+	for _, s := range l.settings {
+		if s.flag == "" {
+			continue
+		}
+		usage := s.usage
+		if s.env != "" {
+			usage = strings.TrimSpace(usage + " (env " + s.env + ")")
+		}
+		l.flags.Var(&flagValue{setting: s, raw: s.def}, s.flag, usage)
+	}
+	if err := l.flags.Parse(l.args); err != nil {
+		return err
+	}
+	if file.set && !l.fileSet {
+		l.file, l.fileSet = file.raw, true
+	}
+	return nil
+}
+
+// loadFile applies the settings in the YAML file to the struct root.
+func (l *loader) loadFile(root reflect.Value) error {
+	data, err := os.ReadFile(l.file)
+	if err != nil {
+		return fmt.Errorf("conf: read %s: %w", l.file, err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("conf: parse %s: %w", l.file, err)
+	}
+	l.applyFile(root.Type(), doc, "", "file "+l.file)
+	return nil
+}
+
+func (l *loader) applyFile(t reflect.Type, doc map[string]any, prefix, source string) {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.IsExported() && yamlKey(field) != "-" {
+			fields[yamlKey(field)] = field
+		}
+	}
+	for key, value := range doc {
+		path := join(prefix, key)
+		field, ok := fields[key]
+		if !ok {
+			l.errs = append(l.errs, &FieldError{Path: path, Source: source, Err: errors.New("unknown setting")})
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		if isSection(field.Type) {
+			section, ok := value.(map[string]any)
+			if !ok {
+				l.errs = append(l.errs, &FieldError{Path: path, Source: source, Err: errors.New("expected a section of settings")})
+				continue
+			}
+			l.applyFile(field.Type, section, path, source)
+			continue
+		}
+		s := l.byPath[path]
+		if err := decodeInto(s.value, value); err != nil {
+			l.errs = append(l.errs, &FieldError{Path: path, Source: source, Err: err})
+			continue
+		}
+		s.source = source
+	}
+}
+
+// validate runs the Validators in the struct v, whose path is prefix, nested
+// structs before the struct holding them.
+func (l *loader) validate(v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if key := yamlKey(field); field.IsExported() && key != "-" && isSection(field.Type) {
+			l.validate(v.Field(i), join(prefix, key))
+		}
+	}
+	validator, ok := v.Addr().Interface().(Validator)
+	if !ok {
+		return
+	}
+	err := validator.Validate()
+	if err == nil {
+		return
+	}
+	var fieldErrs Errors
+	var fieldErr *FieldError
+	switch {
+	case errors.As(err, &fieldErrs):
+	case errors.As(err, &fieldErr):
+		fieldErrs = Errors{fieldErr}
+	default:
+		fieldErrs = Errors{{Err: err}}
+	}
+	for _, e := range fieldErrs {
+		e.Path = join(prefix, e.Path)
+		if s, ok := l.byPath[e.Path]; ok && e.Source == "" {
+			e.Source = s.source
+		}
+		l.errs = append(l.errs, e)
+	}
+}
+
+// flagValue holds a flag's argument until the other sources are loaded.
+type flagValue struct {
+	setting *setting
+	raw     string
+	set     bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.raw
+}
+
+func (f *flagValue) Set(raw string) error {
+	if f.setting != nil {
+		if err := parseInto(reflect.New(f.setting.value.Type()).Elem(), raw); err != nil {
+			return err
+		}
+	}
+	f.raw, f.set = raw, true
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be set with a bare -flag.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.setting != nil && f.setting.value.Kind() == reflect.Bool
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isSection reports whether fields of type t hold sections rather than
+// settings.
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func yamlKey(field reflect.StructField) string {
+	if key, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); key != "" {
+		return key
+	}
+	return snakeCase(field.Name)
+}
+
+// snakeCase converts a Go name to snake_case, keeping initialisms together:
+// HeartbeatStaleAfter becomes heartbeat_stale_after and DBName db_name.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if key == "" {
+		return prefix
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,207 @@
+package conf
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Server struct {
+		Port        int           `env:"PORT" flag:"port" default:"8080"`
+		ReadTimeout time.Duration `env:"READ_TIMEOUT" default:"30s"`
+		Verbose     bool          `flag:"verbose"`
+	}
+	Store struct {
+		MaxMessageSize Size               `env:"MAX_MESSAGE_SIZE" default:"4MiB"`
+		Retention      time.Duration      `env:"RETENTION" default:"7d"`
+		Peers          []string           `env:"PEERS"`
+		Rates          map[string]float64 `env:"RATES"`
+	}
+	DBName string   `env:"DB_NAME" default:"cartridge"`
+	URLs   []string `yaml:"urls"`
+}
+
+func (c *testConfig) Validate() error {
+	var errs Errors
+	if c.Server.Port <= 0 {
+		errs.Add("server.port", "must be positive")
+	}
+	if c.Server.ReadTimeout < time.Second {
+		errs.Add("server.read_timeout", "must be at least 1s")
+	}
+	return errs.Err()
+}
+
+func env(vars map[string]string) Option {
+	return WithEnv(func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	})
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	var cfg testConfig
+	if err := Load(&cfg, env(nil)); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.ReadTimeout != 30*time.Second || cfg.DBName != "cartridge" {
+		t.Fatalf("expected the defaults, got %+v", cfg)
+	}
+	if cfg.Store.MaxMessageSize != 4*MiB || cfg.Store.Retention != 7*24*time.Hour {
+		t.Fatalf("expected the size and duration defaults parsed, got %+v", cfg.Store)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+server:
+  port: 9000
+  read_timeout: 1m
+store:
+  peers: [a, b]
+  rates:
+    gpu: 2.5
+db_name: staging
+urls: http://x, http://y
+`)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var cfg testConfig
+	err := Load(&cfg,
+		env(map[string]string{"CONFIG_FILE": path, "PORT": "9100", "READ_TIMEOUT": "", "MAX_MESSAGE_SIZE": "16 MiB", "RATES": "cpu=0.5,gpu=3"}),
+		WithFlags(fs, []string{"-port", "9200", "-verbose"}),
+	)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Server.Port != 9200 || !cfg.Server.Verbose {
+		t.Fatalf("expected flags to override the environment, got %+v", cfg.Server)
+	}
+	if cfg.Server.ReadTimeout != time.Minute || cfg.DBName != "staging" {
+		t.Fatalf("expected the file to override defaults, and empty variables ignored, got %+v", cfg)
+	}
+	if cfg.Store.MaxMessageSize != 16*MiB || cfg.Store.Rates["gpu"] != 3 || cfg.Store.Rates["cpu"] != 0.5 {
+		t.Fatalf("expected the environment to override the file, got %+v", cfg.Store)
+	}
+	if len(cfg.Store.Peers) != 2 || cfg.Store.Peers[1] != "b" || len(cfg.URLs) != 2 || cfg.URLs[1] != "http://y" {
+		t.Fatalf("expected YAML and comma-separated lists, got %v and %v", cfg.Store.Peers, cfg.URLs)
+	}
+}
+
+func TestLoadConfigFlag(t *testing.T) {
+	path := writeFile(t, "db_name: from-flag\n")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var cfg testConfig
+	if err := Load(&cfg, env(map[string]string{"CONFIG_FILE": "/does/not/exist"}), WithFlags(fs, []string{"-config", path})); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.DBName != "from-flag" {
+		t.Fatalf("expected -config to override CONFIG_FILE, got %q", cfg.DBName)
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := Load(&cfg, env(nil), WithFlags(fs, []string{"-port", "eighty"})); err == nil {
+		t.Fatal("expected an invalid flag value to be rejected")
+	}
+}
+
+func TestLoadErrorsNameFieldPaths(t *testing.T) {
+	path := writeFile(t, "server:\n  read_timeout: 10ms\n  port: 0\nstore:\n  max_message_size: lots\n  unknown: 1\n")
+	var cfg testConfig
+	err := Load(&cfg, WithFile(path), env(map[string]string{"RETENTION": "soon"}))
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expected three parse errors, got %v", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"store.max_message_size: invalid size \"lots\" (from file " + path + ")",
+		"store.unknown: unknown setting",
+		"store.retention: invalid duration \"soon\" (from env RETENTION)",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("expected %q in %q", want, msg)
+		}
+	}
+
+	path = writeFile(t, "server:\n  read_timeout: 10ms\n  port: 0\n")
+	err = Load(&cfg, WithFile(path), env(nil))
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected two validation errors, got %v", err)
+	}
+	if errs[1].Path != "server.read_timeout" || errs[1].Source != "file "+path {
+		t.Fatalf("expected the validation error traced to the file, got %+v", errs[1])
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for raw, want := range map[string]time.Duration{
+		"90s":    90 * time.Second,
+		"7d":     7 * 24 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"0.5d":   12 * time.Hour,
+		"-1d30m": -(24*time.Hour + 30*time.Minute),
+	} {
+		if got, err := ParseDuration(raw); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"d", "1dd", "1d-2h", "1e3d", "soon"} {
+		if _, err := ParseDuration(raw); err == nil {
+			t.Errorf("ParseDuration(%q): expected an error", raw)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for raw, want := range map[string]Size{
+		"512":    512,
+		"64kB":   64000,
+		"4 MiB":  4 * MiB,
+		"1.5KiB": 1536,
+		"2gib":   2 * GiB,
+		"1 TB":   1000 * 1000 * 1000 * 1000,
+	} {
+		if got, err := ParseSize(raw); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "-1", "4 MB extra", "0.5B", "lots"} {
+		if _, err := ParseSize(raw); err == nil {
+			t.Errorf("ParseSize(%q): expected an error", raw)
+		}
+	}
+	if got := (4 * MiB).String(); got != "4MiB" {
+		t.Fatalf("expected 4MiB, got %s", got)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"HeartbeatStaleAfter": "heartbeat_stale_after",
+		"DBName":              "db_name",
+		"APIKeys":             "api_keys",
+		"RunIDPattern":        "run_id_pattern",
+		"NATS":                "nats",
+		"URLExpiry":           "url_expiry",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
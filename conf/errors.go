@@ -0,0 +1,64 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a problem with one setting.
+type FieldError struct {
+	// Path names the setting by its YAML keys, such as server.read_timeout.
+	Path string
+	// Source is where the offending value came from, such as
+	// "env READ_TIMEOUT"; it is empty for defaults.
+	Source string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	msg := e.Err.Error()
+	if e.Path != "" {
+		msg = e.Path + ": " + msg
+	}
+	if e.Source != "" {
+		msg += " (from " + e.Source + ")"
+	}
+	return msg
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects the problems found in a configuration. Validators build one
+// with Add and return its Err.
+type Errors []*FieldError
+
+// Add records a problem with the setting at path.
+func (e *Errors) Add(path, format string, args ...any) {
+	*e = append(*e, &FieldError{Path: path, Err: fmt.Errorf(format, args...)})
+}
+
+// Err returns e as an error, or nil when it is empty.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
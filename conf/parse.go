@@ -0,0 +1,250 @@
+package conf
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a duration as time.ParseDuration does, additionally
+// accepting a leading number of days, as in "7d" or "1d12h".
+func ParseDuration(s string) (time.Duration, error) {
+	days, rest, ok := strings.Cut(s, "d")
+	if !ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return d, nil
+	}
+	n, err := strconv.ParseFloat(days, 64)
+	if err != nil || strings.ContainsAny(days, "eE") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	d := time.Duration(n * float64(24*time.Hour))
+	if rest == "" {
+		return d, nil
+	}
+	if strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	extra, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if n < 0 {
+		return d - extra, nil
+	}
+	return d + extra, nil
+}
+
+// Size is a number of bytes. It parses from a whole number of bytes or one
+// with a unit: decimal (kB, MB, GB, TB) or binary (KiB, MiB, GiB, TiB), with
+// or without a space, in any case.
+type Size uint64
+
+// Common sizes.
+const (
+	Byte Size = 1
+	KiB       = 1024 * Byte
+	MiB       = 1024 * KiB
+	GiB       = 1024 * MiB
+	TiB       = 1024 * GiB
+)
+
+var sizeUnits = map[string]Size{
+	"":    Byte,
+	"b":   Byte,
+	"k":   1000,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"kib": KiB,
+	"mib": MiB,
+	"gib": GiB,
+	"tib": TiB,
+}
+
+// ParseSize parses a size such as "512", "64kB" or "4 MiB".
+func ParseSize(s string) (Size, error) {
+	trimmed := strings.TrimSpace(s)
+	split := strings.IndexFunc(trimmed, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if split < 0 {
+		split = len(trimmed)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(trimmed[split:]))]
+	n, err := strconv.ParseFloat(trimmed[:split], 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	bytes := n * float64(unit)
+	if bytes != math.Trunc(bytes) || bytes >= math.MaxUint64 {
+		return 0, fmt.Errorf("invalid size %q: not a whole number of bytes", s)
+	}
+	return Size(bytes), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Size) UnmarshalText(text []byte) error {
+	size, err := ParseSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// String formats s in the largest binary unit that divides it, such as "4MiB".
+func (s Size) String() string {
+	for _, unit := range []struct {
+		size Size
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if s != 0 && s%unit.size == 0 {
+			return strconv.FormatUint(uint64(s/unit.size), 10) + unit.name
+		}
+	}
+	return strconv.FormatUint(uint64(s), 10) + "B"
+}
+
+// parseInto parses raw, as found in the environment or on the command line,
+// into v.
+func parseInto(v reflect.Value, raw string) error {
+	switch {
+	case v.Kind() == reflect.Slice && !implementsText(v.Type()):
+		items := splitList(raw)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := parseScalar(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case v.Kind() == reflect.Map:
+		entries := reflect.MakeMap(v.Type())
+		for _, pair := range splitList(raw) {
+			key, value, ok := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return fmt.Errorf("expected key=value, got %q", pair)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := parseScalar(elem, strings.TrimSpace(value)); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			entries.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(entries)
+		return nil
+	}
+	return parseScalar(v, raw)
+}
+
+// decodeInto stores a value decoded from YAML into v. Lists and maps may also
+// be written as they are in the environment.
+func decodeInto(v reflect.Value, value any) error {
+	switch value := value.(type) {
+	case []any:
+		if v.Kind() != reflect.Slice || implementsText(v.Type()) {
+			return fmt.Errorf("expected a single value, got a list")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(value), len(value))
+		for i, item := range value {
+			if err := parseScalar(slice.Index(i), scalarText(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case map[string]any:
+		if v.Kind() != reflect.Map {
+			return fmt.Errorf("expected a single value, got a map")
+		}
+		entries := reflect.MakeMap(v.Type())
+		for key, item := range value {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := parseScalar(elem, scalarText(item)); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			entries.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(entries)
+		return nil
+	}
+	return parseInto(v, scalarText(value))
+}
+
+// scalarText renders a YAML scalar as it would be written in the environment.
+func scalarText(value any) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func parseScalar(v reflect.Value, raw string) error {
+	if implementsText(v.Type()) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+	if v.Type() == durationType {
+		d, err := ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	raw = strings.TrimSpace(raw)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid non-negative integer %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+func implementsText(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,911 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: engine/v1/engine.proto
+
+package enginev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EngineId struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EnvId   string `protobuf:"bytes,1,opt,name=env_id,json=envId,proto3" json:"env_id,omitempty"`
+	BuildId string `protobuf:"bytes,2,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+}
+
+func (x *EngineId) Reset() {
+	*x = EngineId{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EngineId) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EngineId) ProtoMessage() {}
+
+func (x *EngineId) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EngineId.ProtoReflect.Descriptor instead.
+func (*EngineId) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EngineId) GetEnvId() string {
+	if x != nil {
+		return x.EnvId
+	}
+	return ""
+}
+
+func (x *EngineId) GetBuildId() string {
+	if x != nil {
+		return x.BuildId
+	}
+	return ""
+}
+
+type Encoding struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	State         string `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	Action        string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Obs           string `protobuf:"bytes,3,opt,name=obs,proto3" json:"obs,omitempty"`
+	SchemaVersion uint32 `protobuf:"varint,4,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+}
+
+func (x *Encoding) Reset() {
+	*x = Encoding{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Encoding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Encoding) ProtoMessage() {}
+
+func (x *Encoding) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Encoding.ProtoReflect.Descriptor instead.
+func (*Encoding) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Encoding) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Encoding) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Encoding) GetObs() string {
+	if x != nil {
+		return x.Obs
+	}
+	return ""
+}
+
+func (x *Encoding) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+type MultiDiscrete struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Nvec []uint32 `protobuf:"varint,1,rep,packed,name=nvec,proto3" json:"nvec,omitempty"`
+}
+
+func (x *MultiDiscrete) Reset() {
+	*x = MultiDiscrete{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MultiDiscrete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MultiDiscrete) ProtoMessage() {}
+
+func (x *MultiDiscrete) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MultiDiscrete.ProtoReflect.Descriptor instead.
+func (*MultiDiscrete) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MultiDiscrete) GetNvec() []uint32 {
+	if x != nil {
+		return x.Nvec
+	}
+	return nil
+}
+
+type BoxSpec struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Low   []float32 `protobuf:"fixed32,1,rep,packed,name=low,proto3" json:"low,omitempty"`
+	High  []float32 `protobuf:"fixed32,2,rep,packed,name=high,proto3" json:"high,omitempty"`
+	Shape []uint32  `protobuf:"varint,3,rep,packed,name=shape,proto3" json:"shape,omitempty"`
+}
+
+func (x *BoxSpec) Reset() {
+	*x = BoxSpec{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BoxSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BoxSpec) ProtoMessage() {}
+
+func (x *BoxSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BoxSpec.ProtoReflect.Descriptor instead.
+func (*BoxSpec) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BoxSpec) GetLow() []float32 {
+	if x != nil {
+		return x.Low
+	}
+	return nil
+}
+
+func (x *BoxSpec) GetHigh() []float32 {
+	if x != nil {
+		return x.High
+	}
+	return nil
+}
+
+func (x *BoxSpec) GetShape() []uint32 {
+	if x != nil {
+		return x.Shape
+	}
+	return nil
+}
+
+type Capabilities struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id         *EngineId `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Enc        *Encoding `protobuf:"bytes,2,opt,name=enc,proto3" json:"enc,omitempty"`
+	MaxHorizon uint32    `protobuf:"varint,3,opt,name=max_horizon,json=maxHorizon,proto3" json:"max_horizon,omitempty"`
+	// Types that are assignable to ActionSpace:
+	//	*Capabilities_DiscreteN
+	//	*Capabilities_Multi
+	//	*Capabilities_Continuous
+	ActionSpace    isCapabilities_ActionSpace `protobuf_oneof:"action_space"`
+	PreferredBatch uint32                     `protobuf:"varint,20,opt,name=preferred_batch,json=preferredBatch,proto3" json:"preferred_batch,omitempty"`
+}
+
+func (x *Capabilities) Reset() {
+	*x = Capabilities{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Capabilities) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capabilities) ProtoMessage() {}
+
+func (x *Capabilities) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capabilities.ProtoReflect.Descriptor instead.
+func (*Capabilities) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Capabilities) GetId() *EngineId {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Capabilities) GetEnc() *Encoding {
+	if x != nil {
+		return x.Enc
+	}
+	return nil
+}
+
+func (x *Capabilities) GetMaxHorizon() uint32 {
+	if x != nil {
+		return x.MaxHorizon
+	}
+	return 0
+}
+
+func (m *Capabilities) GetActionSpace() isCapabilities_ActionSpace {
+	if m != nil {
+		return m.ActionSpace
+	}
+	return nil
+}
+
+func (x *Capabilities) GetDiscreteN() uint32 {
+	if x, ok := x.GetActionSpace().(*Capabilities_DiscreteN); ok {
+		return x.DiscreteN
+	}
+	return 0
+}
+
+func (x *Capabilities) GetMulti() *MultiDiscrete {
+	if x, ok := x.GetActionSpace().(*Capabilities_Multi); ok {
+		return x.Multi
+	}
+	return nil
+}
+
+func (x *Capabilities) GetContinuous() *BoxSpec {
+	if x, ok := x.GetActionSpace().(*Capabilities_Continuous); ok {
+		return x.Continuous
+	}
+	return nil
+}
+
+func (x *Capabilities) GetPreferredBatch() uint32 {
+	if x != nil {
+		return x.PreferredBatch
+	}
+	return 0
+}
+
+type isCapabilities_ActionSpace interface {
+	isCapabilities_ActionSpace()
+}
+
+type Capabilities_DiscreteN struct {
+	DiscreteN uint32 `protobuf:"varint,10,opt,name=discrete_n,json=discreteN,proto3,oneof"`
+}
+
+type Capabilities_Multi struct {
+	Multi *MultiDiscrete `protobuf:"bytes,11,opt,name=multi,proto3,oneof"`
+}
+
+type Capabilities_Continuous struct {
+	Continuous *BoxSpec `protobuf:"bytes,12,opt,name=continuous,proto3,oneof"`
+}
+
+func (*Capabilities_DiscreteN) isCapabilities_ActionSpace() {}
+
+func (*Capabilities_Multi) isCapabilities_ActionSpace() {}
+
+func (*Capabilities_Continuous) isCapabilities_ActionSpace() {}
+
+type ResetRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   *EngineId `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Seed uint64    `protobuf:"varint,2,opt,name=seed,proto3" json:"seed,omitempty"`
+	Hint []byte    `protobuf:"bytes,3,opt,name=hint,proto3" json:"hint,omitempty"`
+}
+
+func (x *ResetRequest) Reset() {
+	*x = ResetRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetRequest) ProtoMessage() {}
+
+func (x *ResetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetRequest.ProtoReflect.Descriptor instead.
+func (*ResetRequest) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ResetRequest) GetId() *EngineId {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *ResetRequest) GetSeed() uint64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *ResetRequest) GetHint() []byte {
+	if x != nil {
+		return x.Hint
+	}
+	return nil
+}
+
+type ResetResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	State []byte `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	Obs   []byte `protobuf:"bytes,2,opt,name=obs,proto3" json:"obs,omitempty"`
+}
+
+func (x *ResetResponse) Reset() {
+	*x = ResetResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetResponse) ProtoMessage() {}
+
+func (x *ResetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetResponse.ProtoReflect.Descriptor instead.
+func (*ResetResponse) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ResetResponse) GetState() []byte {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+func (x *ResetResponse) GetObs() []byte {
+	if x != nil {
+		return x.Obs
+	}
+	return nil
+}
+
+type StepRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     *EngineId `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State  []byte    `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Action []byte    `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+}
+
+func (x *StepRequest) Reset() {
+	*x = StepRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StepRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepRequest) ProtoMessage() {}
+
+func (x *StepRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepRequest.ProtoReflect.Descriptor instead.
+func (*StepRequest) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StepRequest) GetId() *EngineId {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *StepRequest) GetState() []byte {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+func (x *StepRequest) GetAction() []byte {
+	if x != nil {
+		return x.Action
+	}
+	return nil
+}
+
+type StepResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	State  []byte  `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	Obs    []byte  `protobuf:"bytes,2,opt,name=obs,proto3" json:"obs,omitempty"`
+	Reward float32 `protobuf:"fixed32,3,opt,name=reward,proto3" json:"reward,omitempty"`
+	Done   bool    `protobuf:"varint,4,opt,name=done,proto3" json:"done,omitempty"`
+	Info   uint64  `protobuf:"varint,5,opt,name=info,proto3" json:"info,omitempty"`
+}
+
+func (x *StepResponse) Reset() {
+	*x = StepResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_engine_v1_engine_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StepResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepResponse) ProtoMessage() {}
+
+func (x *StepResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_engine_v1_engine_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepResponse.ProtoReflect.Descriptor instead.
+func (*StepResponse) Descriptor() ([]byte, []int) {
+	return file_engine_v1_engine_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StepResponse) GetState() []byte {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+func (x *StepResponse) GetObs() []byte {
+	if x != nil {
+		return x.Obs
+	}
+	return nil
+}
+
+func (x *StepResponse) GetReward() float32 {
+	if x != nil {
+		return x.Reward
+	}
+	return 0
+}
+
+func (x *StepResponse) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+func (x *StepResponse) GetInfo() uint64 {
+	if x != nil {
+		return x.Info
+	}
+	return 0
+}
+
+var File_engine_v1_engine_proto protoreflect.FileDescriptor
+
+var file_engine_v1_engine_proto_rawDesc = []byte{
+	0x0a, 0x16, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2f, 0x76, 0x31, 0x2f, 0x65, 0x6e, 0x67, 0x69,
+	0x6e, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65,
+	0x2e, 0x76, 0x31, 0x22, 0x3c, 0x0a, 0x08, 0x45, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x49, 0x64, 0x12,
+	0x15, 0x0a, 0x06, 0x65, 0x6e, 0x76, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x6e, 0x76, 0x49, 0x64, 0x12, 0x19, 0x0a, 0x08, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x49,
+	0x64, 0x22, 0x71, 0x0a, 0x08, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a,
+	0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x6f,
+	0x62, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6f, 0x62, 0x73, 0x12, 0x25, 0x0a,
+	0x0e, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0d, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x22, 0x23, 0x0a, 0x0d, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x44, 0x69, 0x73,
+	0x63, 0x72, 0x65, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x76, 0x65, 0x63, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0d, 0x52, 0x04, 0x6e, 0x76, 0x65, 0x63, 0x22, 0x45, 0x0a, 0x07, 0x42, 0x6f, 0x78,
+	0x53, 0x70, 0x65, 0x63, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x77, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x02, 0x52, 0x03, 0x6c, 0x6f, 0x77, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x69, 0x67, 0x68, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x02, 0x52, 0x04, 0x68, 0x69, 0x67, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x68,
+	0x61, 0x70, 0x65, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x05, 0x73, 0x68, 0x61, 0x70, 0x65,
+	0x22, 0xbd, 0x02, 0x0a, 0x0c, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65,
+	0x73, 0x12, 0x23, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x67, 0x69, 0x6e, 0x65,
+	0x49, 0x64, 0x52, 0x02, 0x69, 0x64, 0x12, 0x25, 0x0a, 0x03, 0x65, 0x6e, 0x63, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e,
+	0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x03, 0x65, 0x6e, 0x63, 0x12, 0x1f, 0x0a,
+	0x0b, 0x6d, 0x61, 0x78, 0x5f, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x0a, 0x6d, 0x61, 0x78, 0x48, 0x6f, 0x72, 0x69, 0x7a, 0x6f, 0x6e, 0x12, 0x1f,
+	0x0a, 0x0a, 0x64, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x5f, 0x6e, 0x18, 0x0a, 0x20, 0x01,
+	0x28, 0x0d, 0x48, 0x00, 0x52, 0x09, 0x64, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x4e, 0x12,
+	0x30, 0x0a, 0x05, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18,
+	0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x75, 0x6c, 0x74, 0x69,
+	0x44, 0x69, 0x73, 0x63, 0x72, 0x65, 0x74, 0x65, 0x48, 0x00, 0x52, 0x05, 0x6d, 0x75, 0x6c, 0x74,
+	0x69, 0x12, 0x34, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x74, 0x69, 0x6e, 0x75, 0x6f, 0x75, 0x73, 0x18,
+	0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76,
+	0x31, 0x2e, 0x42, 0x6f, 0x78, 0x53, 0x70, 0x65, 0x63, 0x48, 0x00, 0x52, 0x0a, 0x63, 0x6f, 0x6e,
+	0x74, 0x69, 0x6e, 0x75, 0x6f, 0x75, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x70, 0x72, 0x65, 0x66, 0x65,
+	0x72, 0x72, 0x65, 0x64, 0x5f, 0x62, 0x61, 0x74, 0x63, 0x68, 0x18, 0x14, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x0e, 0x70, 0x72, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x42, 0x61, 0x74, 0x63, 0x68,
+	0x42, 0x0e, 0x0a, 0x0c, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x70, 0x61, 0x63, 0x65,
+	0x22, 0x5b, 0x0a, 0x0c, 0x52, 0x65, 0x73, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x23, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x65,
+	0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x49,
+	0x64, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x65, 0x65, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x04, 0x73, 0x65, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x69, 0x6e,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x68, 0x69, 0x6e, 0x74, 0x22, 0x37, 0x0a,
+	0x0d, 0x52, 0x65, 0x73, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6f, 0x62, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x03, 0x6f, 0x62, 0x73, 0x22, 0x60, 0x0a, 0x0b, 0x53, 0x74, 0x65, 0x70, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x23, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x13, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e,
+	0x67, 0x69, 0x6e, 0x65, 0x49, 0x64, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x76, 0x0a, 0x0c, 0x53, 0x74, 0x65, 0x70,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x6f, 0x62, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x6f, 0x62, 0x73,
+	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x77, 0x61, 0x72, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x02,
+	0x52, 0x06, 0x72, 0x65, 0x77, 0x61, 0x72, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x6f, 0x6e, 0x65,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x64, 0x6f, 0x6e, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x69, 0x6e, 0x66, 0x6f, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x69, 0x6e, 0x66, 0x6f,
+	0x32, 0xbe, 0x01, 0x0a, 0x06, 0x45, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x12, 0x3f, 0x0a, 0x0f, 0x47,
+	0x65, 0x74, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x13,
+	0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6e, 0x67, 0x69, 0x6e,
+	0x65, 0x49, 0x64, 0x1a, 0x17, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e,
+	0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x3a, 0x0a, 0x05,
+	0x52, 0x65, 0x73, 0x65, 0x74, 0x12, 0x17, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76,
+	0x31, 0x2e, 0x52, 0x65, 0x73, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18,
+	0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x65, 0x74,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37, 0x0a, 0x04, 0x53, 0x74, 0x65, 0x70,
+	0x12, 0x16, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x65,
+	0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e,
+	0x65, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x42, 0x2f, 0x5a, 0x2d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x63, 0x61, 0x72, 0x74, 0x72, 0x69, 0x64, 0x67, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
+	0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2f, 0x76, 0x31, 0x3b, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65,
+	0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_engine_v1_engine_proto_rawDescOnce sync.Once
+	file_engine_v1_engine_proto_rawDescData = file_engine_v1_engine_proto_rawDesc
+)
+
+func file_engine_v1_engine_proto_rawDescGZIP() []byte {
+	file_engine_v1_engine_proto_rawDescOnce.Do(func() {
+		file_engine_v1_engine_proto_rawDescData = protoimpl.X.CompressGZIP(file_engine_v1_engine_proto_rawDescData)
+	})
+	return file_engine_v1_engine_proto_rawDescData
+}
+
+var file_engine_v1_engine_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_engine_v1_engine_proto_goTypes = []any{
+	(*EngineId)(nil),      // 0: engine.v1.EngineId
+	(*Encoding)(nil),      // 1: engine.v1.Encoding
+	(*MultiDiscrete)(nil), // 2: engine.v1.MultiDiscrete
+	(*BoxSpec)(nil),       // 3: engine.v1.BoxSpec
+	(*Capabilities)(nil),  // 4: engine.v1.Capabilities
+	(*ResetRequest)(nil),  // 5: engine.v1.ResetRequest
+	(*ResetResponse)(nil), // 6: engine.v1.ResetResponse
+	(*StepRequest)(nil),   // 7: engine.v1.StepRequest
+	(*StepResponse)(nil),  // 8: engine.v1.StepResponse
+}
+var file_engine_v1_engine_proto_depIdxs = []int32{
+	0, // 0: engine.v1.Capabilities.id:type_name -> engine.v1.EngineId
+	1, // 1: engine.v1.Capabilities.enc:type_name -> engine.v1.Encoding
+	2, // 2: engine.v1.Capabilities.multi:type_name -> engine.v1.MultiDiscrete
+	3, // 3: engine.v1.Capabilities.continuous:type_name -> engine.v1.BoxSpec
+	0, // 4: engine.v1.ResetRequest.id:type_name -> engine.v1.EngineId
+	0, // 5: engine.v1.StepRequest.id:type_name -> engine.v1.EngineId
+	0, // 6: engine.v1.Engine.GetCapabilities:input_type -> engine.v1.EngineId
+	5, // 7: engine.v1.Engine.Reset:input_type -> engine.v1.ResetRequest
+	7, // 8: engine.v1.Engine.Step:input_type -> engine.v1.StepRequest
+	4, // 9: engine.v1.Engine.GetCapabilities:output_type -> engine.v1.Capabilities
+	6, // 10: engine.v1.Engine.Reset:output_type -> engine.v1.ResetResponse
+	8, // 11: engine.v1.Engine.Step:output_type -> engine.v1.StepResponse
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_engine_v1_engine_proto_init() }
+func file_engine_v1_engine_proto_init() {
+	if File_engine_v1_engine_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_engine_v1_engine_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*EngineId); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_engine_v1_engine_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*Encoding); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_engine_v1_engine_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*MultiDiscrete); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_engine_v1_engine_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*BoxSpec); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_engine_v1_engine_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*Capabilities); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_engine_v1_engine_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*ResetRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_engine_v1_engine_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*ResetResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_engine_v1_engine_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*StepRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_engine_v1_engine_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*StepResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_engine_v1_engine_proto_msgTypes[4].OneofWrappers = []any{
+		(*Capabilities_DiscreteN)(nil),
+		(*Capabilities_Multi)(nil),
+		(*Capabilities_Continuous)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_engine_v1_engine_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_engine_v1_engine_proto_goTypes,
+		DependencyIndexes: file_engine_v1_engine_proto_depIdxs,
+		MessageInfos:      file_engine_v1_engine_proto_msgTypes,
+	}.Build()
+	File_engine_v1_engine_proto = out.File
+	file_engine_v1_engine_proto_rawDesc = nil
+	file_engine_v1_engine_proto_goTypes = nil
+	file_engine_v1_engine_proto_depIdxs = nil
+}
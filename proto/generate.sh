@@ -0,0 +1,14 @@
+#!/bin/bash
+set -e
+
+# Regenerate the Go contracts next to their .proto files. Run from anywhere;
+# commit the result together with the .proto change.
+cd "$(dirname "$0")"
+
+protoc --go_out=. --go_opt=paths=source_relative \
+       --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+       --proto_path=. \
+       engine/v1/engine.proto \
+       replay/v1/replay.proto
+
+echo "Generated Go contracts in proto/"
@@ -0,0 +1,572 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: replay/v1/replay.proto
+
+package replayv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Replay_StoreTransition_FullMethodName  = "/replay.v1.Replay/StoreTransition"
+	Replay_StoreBatch_FullMethodName       = "/replay.v1.Replay/StoreBatch"
+	Replay_Sample_FullMethodName           = "/replay.v1.Replay/Sample"
+	Replay_SampleStream_FullMethodName     = "/replay.v1.Replay/SampleStream"
+	Replay_GetStats_FullMethodName         = "/replay.v1.Replay/GetStats"
+	Replay_UpdatePriorities_FullMethodName = "/replay.v1.Replay/UpdatePriorities"
+	Replay_Clear_FullMethodName            = "/replay.v1.Replay/Clear"
+	Replay_Version_FullMethodName          = "/replay.v1.Replay/Version"
+	Replay_Export_FullMethodName           = "/replay.v1.Replay/Export"
+	Replay_GetEpisode_FullMethodName       = "/replay.v1.Replay/GetEpisode"
+	Replay_Watch_FullMethodName            = "/replay.v1.Replay/Watch"
+)
+
+// ReplayClient is the client API for Replay service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ReplayClient interface {
+	StoreTransition(ctx context.Context, in *StoreTransitionRequest, opts ...grpc.CallOption) (*StoreTransitionResponse, error)
+	StoreBatch(ctx context.Context, in *StoreBatchRequest, opts ...grpc.CallOption) (*StoreBatchResponse, error)
+	Sample(ctx context.Context, in *SampleRequest, opts ...grpc.CallOption) (*SampleResponse, error)
+	SampleStream(ctx context.Context, in *SampleStreamRequest, opts ...grpc.CallOption) (Replay_SampleStreamClient, error)
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	UpdatePriorities(ctx context.Context, in *UpdatePrioritiesRequest, opts ...grpc.CallOption) (*UpdatePrioritiesResponse, error)
+	Clear(ctx context.Context, in *ClearRequest, opts ...grpc.CallOption) (*ClearResponse, error)
+	Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionResponse, error)
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (Replay_ExportClient, error)
+	GetEpisode(ctx context.Context, in *GetEpisodeRequest, opts ...grpc.CallOption) (*GetEpisodeResponse, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Replay_WatchClient, error)
+}
+
+type replayClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReplayClient(cc grpc.ClientConnInterface) ReplayClient {
+	return &replayClient{cc}
+}
+
+func (c *replayClient) StoreTransition(ctx context.Context, in *StoreTransitionRequest, opts ...grpc.CallOption) (*StoreTransitionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StoreTransitionResponse)
+	err := c.cc.Invoke(ctx, Replay_StoreTransition_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replayClient) StoreBatch(ctx context.Context, in *StoreBatchRequest, opts ...grpc.CallOption) (*StoreBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StoreBatchResponse)
+	err := c.cc.Invoke(ctx, Replay_StoreBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replayClient) Sample(ctx context.Context, in *SampleRequest, opts ...grpc.CallOption) (*SampleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SampleResponse)
+	err := c.cc.Invoke(ctx, Replay_Sample_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replayClient) SampleStream(ctx context.Context, in *SampleStreamRequest, opts ...grpc.CallOption) (Replay_SampleStreamClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Replay_ServiceDesc.Streams[0], Replay_SampleStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &replaySampleStreamClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Replay_SampleStreamClient interface {
+	Recv() (*SampleResponse, error)
+	grpc.ClientStream
+}
+
+type replaySampleStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *replaySampleStreamClient) Recv() (*SampleResponse, error) {
+	m := new(SampleResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *replayClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, Replay_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replayClient) UpdatePriorities(ctx context.Context, in *UpdatePrioritiesRequest, opts ...grpc.CallOption) (*UpdatePrioritiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdatePrioritiesResponse)
+	err := c.cc.Invoke(ctx, Replay_UpdatePriorities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replayClient) Clear(ctx context.Context, in *ClearRequest, opts ...grpc.CallOption) (*ClearResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClearResponse)
+	err := c.cc.Invoke(ctx, Replay_Clear_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replayClient) Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VersionResponse)
+	err := c.cc.Invoke(ctx, Replay_Version_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replayClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (Replay_ExportClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Replay_ServiceDesc.Streams[1], Replay_Export_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &replayExportClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Replay_ExportClient interface {
+	Recv() (*ExportResponse, error)
+	grpc.ClientStream
+}
+
+type replayExportClient struct {
+	grpc.ClientStream
+}
+
+func (x *replayExportClient) Recv() (*ExportResponse, error) {
+	m := new(ExportResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *replayClient) GetEpisode(ctx context.Context, in *GetEpisodeRequest, opts ...grpc.CallOption) (*GetEpisodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetEpisodeResponse)
+	err := c.cc.Invoke(ctx, Replay_GetEpisode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replayClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Replay_WatchClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Replay_ServiceDesc.Streams[2], Replay_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &replayWatchClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Replay_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type replayWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *replayWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReplayServer is the server API for Replay service.
+// All implementations must embed UnimplementedReplayServer
+// for forward compatibility
+type ReplayServer interface {
+	StoreTransition(context.Context, *StoreTransitionRequest) (*StoreTransitionResponse, error)
+	StoreBatch(context.Context, *StoreBatchRequest) (*StoreBatchResponse, error)
+	Sample(context.Context, *SampleRequest) (*SampleResponse, error)
+	SampleStream(*SampleStreamRequest, Replay_SampleStreamServer) error
+	GetStats(context.Context, *GetStatsRequest) (*StatsResponse, error)
+	UpdatePriorities(context.Context, *UpdatePrioritiesRequest) (*UpdatePrioritiesResponse, error)
+	Clear(context.Context, *ClearRequest) (*ClearResponse, error)
+	Version(context.Context, *VersionRequest) (*VersionResponse, error)
+	Export(*ExportRequest, Replay_ExportServer) error
+	GetEpisode(context.Context, *GetEpisodeRequest) (*GetEpisodeResponse, error)
+	Watch(*WatchRequest, Replay_WatchServer) error
+	mustEmbedUnimplementedReplayServer()
+}
+
+// UnimplementedReplayServer must be embedded to have forward compatible implementations.
+type UnimplementedReplayServer struct {
+}
+
+func (UnimplementedReplayServer) StoreTransition(context.Context, *StoreTransitionRequest) (*StoreTransitionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StoreTransition not implemented")
+}
+func (UnimplementedReplayServer) StoreBatch(context.Context, *StoreBatchRequest) (*StoreBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StoreBatch not implemented")
+}
+func (UnimplementedReplayServer) Sample(context.Context, *SampleRequest) (*SampleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sample not implemented")
+}
+func (UnimplementedReplayServer) SampleStream(*SampleStreamRequest, Replay_SampleStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method SampleStream not implemented")
+}
+func (UnimplementedReplayServer) GetStats(context.Context, *GetStatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedReplayServer) UpdatePriorities(context.Context, *UpdatePrioritiesRequest) (*UpdatePrioritiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePriorities not implemented")
+}
+func (UnimplementedReplayServer) Clear(context.Context, *ClearRequest) (*ClearResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clear not implemented")
+}
+func (UnimplementedReplayServer) Version(context.Context, *VersionRequest) (*VersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
+}
+func (UnimplementedReplayServer) Export(*ExportRequest, Replay_ExportServer) error {
+	return status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedReplayServer) GetEpisode(context.Context, *GetEpisodeRequest) (*GetEpisodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEpisode not implemented")
+}
+func (UnimplementedReplayServer) Watch(*WatchRequest, Replay_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedReplayServer) mustEmbedUnimplementedReplayServer() {}
+
+// UnsafeReplayServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReplayServer will
+// result in compilation errors.
+type UnsafeReplayServer interface {
+	mustEmbedUnimplementedReplayServer()
+}
+
+func RegisterReplayServer(s grpc.ServiceRegistrar, srv ReplayServer) {
+	s.RegisterService(&Replay_ServiceDesc, srv)
+}
+
+func _Replay_StoreTransition_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreTransitionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplayServer).StoreTransition(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replay_StoreTransition_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplayServer).StoreTransition(ctx, req.(*StoreTransitionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replay_StoreBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplayServer).StoreBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replay_StoreBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplayServer).StoreBatch(ctx, req.(*StoreBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replay_Sample_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SampleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplayServer).Sample(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replay_Sample_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplayServer).Sample(ctx, req.(*SampleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replay_SampleStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SampleStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReplayServer).SampleStream(m, &replaySampleStreamServer{ServerStream: stream})
+}
+
+type Replay_SampleStreamServer interface {
+	Send(*SampleResponse) error
+	grpc.ServerStream
+}
+
+type replaySampleStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *replaySampleStreamServer) Send(m *SampleResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Replay_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplayServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replay_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplayServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replay_UpdatePriorities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePrioritiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplayServer).UpdatePriorities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replay_UpdatePriorities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplayServer).UpdatePriorities(ctx, req.(*UpdatePrioritiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replay_Clear_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplayServer).Clear(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replay_Clear_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplayServer).Clear(ctx, req.(*ClearRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replay_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplayServer).Version(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replay_Version_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplayServer).Version(ctx, req.(*VersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replay_Export_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReplayServer).Export(m, &replayExportServer{ServerStream: stream})
+}
+
+type Replay_ExportServer interface {
+	Send(*ExportResponse) error
+	grpc.ServerStream
+}
+
+type replayExportServer struct {
+	grpc.ServerStream
+}
+
+func (x *replayExportServer) Send(m *ExportResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Replay_GetEpisode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEpisodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplayServer).GetEpisode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replay_GetEpisode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplayServer).GetEpisode(ctx, req.(*GetEpisodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replay_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReplayServer).Watch(m, &replayWatchServer{ServerStream: stream})
+}
+
+type Replay_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type replayWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *replayWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Replay_ServiceDesc is the grpc.ServiceDesc for Replay service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Replay_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "replay.v1.Replay",
+	HandlerType: (*ReplayServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StoreTransition",
+			Handler:    _Replay_StoreTransition_Handler,
+		},
+		{
+			MethodName: "StoreBatch",
+			Handler:    _Replay_StoreBatch_Handler,
+		},
+		{
+			MethodName: "Sample",
+			Handler:    _Replay_Sample_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Replay_GetStats_Handler,
+		},
+		{
+			MethodName: "UpdatePriorities",
+			Handler:    _Replay_UpdatePriorities_Handler,
+		},
+		{
+			MethodName: "Clear",
+			Handler:    _Replay_Clear_Handler,
+		},
+		{
+			MethodName: "Version",
+			Handler:    _Replay_Version_Handler,
+		},
+		{
+			MethodName: "GetEpisode",
+			Handler:    _Replay_GetEpisode_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SampleStream",
+			Handler:       _Replay_SampleStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Export",
+			Handler:       _Replay_Export_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Replay_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "replay/v1/replay.proto",
+}
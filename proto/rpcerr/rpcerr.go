@@ -0,0 +1,135 @@
+// Package rpcerr is the error model shared by Cartridge gRPC services. A
+// failed call's status carries a google.rpc.ErrorInfo in the "cartridge"
+// domain whose reason is a stable error code and whose "retryable" metadata
+// says whether the same call can succeed later. When the server knows how long
+// to wait, a google.rpc.RetryInfo carries the delay. Clients branch on these
+// details rather than on status messages.
+package rpcerr
+
+import (
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain is the ErrorInfo domain of errors raised by Cartridge services.
+const Domain = "cartridge"
+
+// RetryableKey is the ErrorInfo metadata key holding "true" when retrying the
+// call unchanged can succeed, and "false" otherwise.
+const RetryableKey = "retryable"
+
+// Error codes, carried as the ErrorInfo reason.
+const (
+	// InvalidRequest reports a malformed request, which fails again unchanged.
+	InvalidRequest = "INVALID_REQUEST"
+	// NoTransitions reports that nothing matched a Sample yet. It succeeds once
+	// actors have stored more transitions.
+	NoTransitions = "NO_TRANSITIONS"
+	// StorageFailure reports that the storage backend failed the operation.
+	StorageFailure = "STORAGE_FAILURE"
+	// Unavailable reports that the service cannot take the call right now.
+	Unavailable = "UNAVAILABLE"
+	// NotFound reports that the record asked for, such as an episode, is not
+	// stored.
+	NotFound = "NOT_FOUND"
+	// Unauthenticated reports a call without valid credentials, which fails
+	// again until the client is given the right ones.
+	Unauthenticated = "UNAUTHENTICATED"
+)
+
+// Option adds retry metadata to an error.
+type Option func(*options)
+
+type options struct {
+	retryable  bool
+	retryAfter time.Duration
+}
+
+// Retryable marks the call as worth retrying unchanged.
+func Retryable() Option {
+	return func(o *options) { o.retryable = true }
+}
+
+// RetryAfter marks the call as worth retrying once d has passed.
+func RetryAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.retryable = true
+		o.retryAfter = d
+	}
+}
+
+// New returns a status error with code and message whose details carry
+// reason, one of the error codes above, and the retry metadata in opts.
+// Errors are not retryable unless an option says so.
+func New(code codes.Code, reason, message string, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   Domain,
+		Metadata: map[string]string{RetryableKey: strconv.FormatBool(o.retryable)},
+	}}
+	if o.retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(o.retryAfter)})
+	}
+	st, err := status.New(code, message).WithDetails(details...)
+	if err != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+// Details describes a failed call.
+type Details struct {
+	Code    codes.Code
+	Message string
+	// Reason is the error code, empty when the error did not come from a
+	// Cartridge service.
+	Reason    string
+	Retryable bool
+	// RetryAfter is how long the server asked clients to wait; zero when it
+	// gave no delay.
+	RetryAfter time.Duration
+}
+
+// Parse reads the details of err. Errors without them, such as those raised by
+// the gRPC runtime, are retryable when their code is Unavailable,
+// ResourceExhausted, Aborted or DeadlineExceeded.
+func Parse(err error) Details {
+	st := status.Convert(err)
+	details := Details{Code: st.Code(), Message: st.Message()}
+	var info *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if detail.GetDomain() == Domain {
+				info = detail
+			}
+		case *errdetails.RetryInfo:
+			details.RetryAfter = detail.GetRetryDelay().AsDuration()
+		}
+	}
+	if info == nil {
+		details.Retryable = retryableCode(st.Code())
+		return details
+	}
+	details.Reason = info.GetReason()
+	details.Retryable = info.GetMetadata()[RetryableKey] == "true"
+	return details
+}
+
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
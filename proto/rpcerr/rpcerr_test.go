@@ -0,0 +1,41 @@
+package rpcerr
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseReadsDetails(t *testing.T) {
+	err := New(codes.FailedPrecondition, NoTransitions, "no transitions available", RetryAfter(2*time.Second))
+	got := Parse(err)
+	want := Details{Code: codes.FailedPrecondition, Message: "no transitions available", Reason: NoTransitions, Retryable: true, RetryAfter: 2 * time.Second}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	got = Parse(New(codes.Unavailable, Unavailable, "draining"))
+	if got.Retryable || got.RetryAfter != 0 {
+		t.Fatalf("the details decide retryability over the code, got %+v", got)
+	}
+	if got := Parse(New(codes.Internal, StorageFailure, "disk full", Retryable())); !got.Retryable || got.RetryAfter != 0 {
+		t.Fatalf("expected a retryable error without a delay, got %+v", got)
+	}
+}
+
+func TestParseFallsBackToCode(t *testing.T) {
+	cases := map[error]bool{
+		status.Error(codes.Unavailable, "connection refused"): true,
+		status.Error(codes.DeadlineExceeded, "deadline"):      true,
+		status.Error(codes.InvalidArgument, "bad"):            false,
+		errors.New("not a status"):                            false,
+	}
+	for err, retryable := range cases {
+		if got := Parse(err); got.Retryable != retryable || got.Reason != "" {
+			t.Fatalf("%v: expected retryable=%v without a reason, got %+v", err, retryable, got)
+		}
+	}
+}
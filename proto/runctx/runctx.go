@@ -0,0 +1,90 @@
+// Package runctx is the convention for carrying a run's identity between
+// Cartridge services. The orchestrator issues the run and experiment IDs.
+// Clients send them as gRPC metadata on every call made for the run and stamp
+// them onto the records they produce, so experience and events can be filtered
+// per run at every stage of the pipeline.
+package runctx
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// gRPC metadata keys.
+const (
+	MetadataRunID        = "cartridge-run-id"
+	MetadataExperimentID = "cartridge-experiment-id"
+)
+
+// Keys in a transition's metadata map.
+const (
+	RunIDKey        = "run_id"
+	ExperimentIDKey = "experiment_id"
+)
+
+// Context identifies the run a call or record belongs to.
+type Context struct {
+	RunID        string
+	ExperimentID string
+}
+
+// IsZero reports whether c names no run.
+func (c Context) IsZero() bool {
+	return c.RunID == "" && c.ExperimentID == ""
+}
+
+// NewOutgoingContext returns ctx with c appended to the outgoing gRPC metadata.
+func NewOutgoingContext(ctx context.Context, c Context) context.Context {
+	var pairs []string
+	if c.RunID != "" {
+		pairs = append(pairs, MetadataRunID, c.RunID)
+	}
+	if c.ExperimentID != "" {
+		pairs = append(pairs, MetadataExperimentID, c.ExperimentID)
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// FromIncomingContext returns the run context a client sent with the call
+// being served.
+func FromIncomingContext(ctx context.Context) Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return Context{
+		RunID:        first(md.Get(MetadataRunID)),
+		ExperimentID: first(md.Get(MetadataExperimentID)),
+	}
+}
+
+// FromLabels returns the run context stamped on a record's metadata.
+func FromLabels(labels map[string]string) Context {
+	return Context{RunID: labels[RunIDKey], ExperimentID: labels[ExperimentIDKey]}
+}
+
+// Stamp adds c to labels, a record's metadata, without replacing IDs the
+// record already carries. It returns labels, allocated if nil and c is set.
+func (c Context) Stamp(labels map[string]string) map[string]string {
+	if c.IsZero() {
+		return labels
+	}
+	if labels == nil {
+		labels = make(map[string]string, 2)
+	}
+	if _, ok := labels[RunIDKey]; !ok && c.RunID != "" {
+		labels[RunIDKey] = c.RunID
+	}
+	if _, ok := labels[ExperimentIDKey]; !ok && c.ExperimentID != "" {
+		labels[ExperimentIDKey] = c.ExperimentID
+	}
+	return labels
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,39 @@
+package runctx
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOutgoingMetadataRoundTrip(t *testing.T) {
+	want := Context{RunID: "run-1", ExperimentID: "exp-1"}
+	ctx := NewOutgoingContext(context.Background(), want)
+	md, _ := metadata.FromOutgoingContext(ctx)
+
+	got := FromIncomingContext(metadata.NewIncomingContext(context.Background(), md))
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if got := FromIncomingContext(context.Background()); !got.IsZero() {
+		t.Fatalf("expected no run context, got %+v", got)
+	}
+}
+
+func TestStampKeepsExistingIDs(t *testing.T) {
+	c := Context{RunID: "run-1", ExperimentID: "exp-1"}
+
+	labels := c.Stamp(nil)
+	if got := FromLabels(labels); got != c {
+		t.Fatalf("expected %+v, got %+v", c, got)
+	}
+
+	labels = c.Stamp(map[string]string{RunIDKey: "run-0", "seed": "7"})
+	if labels[RunIDKey] != "run-0" || labels[ExperimentIDKey] != "exp-1" || labels["seed"] != "7" {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+	if labels := (Context{}).Stamp(nil); labels != nil {
+		t.Fatalf("expected a zero context to leave labels nil, got %v", labels)
+	}
+}
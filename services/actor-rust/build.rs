@@ -1,3 +1,6 @@
+use std::env;
+use std::process::Command;
+
 fn main() -> Result<(), Box<dyn std::error::Error>> {
     // Generate protobuf code for engine and replay services
     tonic_build::configure()
@@ -9,5 +12,37 @@ fn main() -> Result<(), Box<dyn std::error::Error>> {
             ],
             &["../../proto"],
         )?;
+
+    // Stamp the build reported on /version. Release builds pass VERSION,
+    // GIT_COMMIT and BUILD_TIME; otherwise they are read from the checkout.
+    let version = env::var("VERSION")
+        .ok()
+        .filter(|v| !v.is_empty())
+        .unwrap_or_else(|| env::var("CARGO_PKG_VERSION").unwrap());
+    let commit = stamp("GIT_COMMIT", "git", &["rev-parse", "HEAD"]);
+    let build_time = stamp("BUILD_TIME", "date", &["-u", "+%Y-%m-%dT%H:%M:%SZ"]);
+    println!("cargo:rustc-env=ACTOR_VERSION={}", version);
+    println!("cargo:rustc-env=ACTOR_GIT_COMMIT={}", commit);
+    println!("cargo:rustc-env=ACTOR_BUILD_TIME={}", build_time);
+    for var in ["VERSION", "GIT_COMMIT", "BUILD_TIME"] {
+        println!("cargo:rerun-if-env-changed={}", var);
+    }
     Ok(())
-}
\ No newline at end of file
+}
+
+/// Value of the environment variable var, else the output of the command, else empty
+fn stamp(var: &str, program: &str, args: &[&str]) -> String {
+    if let Ok(value) = env::var(var) {
+        if !value.is_empty() {
+            return value;
+        }
+    }
+    Command::new(program)
+        .args(args)
+        .output()
+        .ok()
+        .filter(|output| output.status.success())
+        .and_then(|output| String::from_utf8(output.stdout).ok())
+        .map(|value| value.trim().to_string())
+        .unwrap_or_default()
+}
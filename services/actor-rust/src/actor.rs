@@ -1,12 +1,21 @@
 use anyhow::{anyhow, Result};
 use std::sync::{Arc, Mutex};
-use std::time::{Duration, SystemTime, UNIX_EPOCH};
+use std::time::{Duration, Instant, SystemTime, UNIX_EPOCH};
 use tokio::time::{interval, timeout};
-use tonic::{transport::Channel, Request};
-use tracing::{debug, error, info};
+use tonic::{codec::CompressionEncoding, transport::Channel, Request};
+use tracing::{debug, error, info, warn};
 
 use crate::config::Config;
+use crate::credentials::ReplayCredentials;
+use crate::discovery;
+use crate::flags::{self, FeatureFlags};
+use crate::orchestrator::OrchestratorClient;
 use crate::policy::{Policy, RandomPolicy};
+use crate::rpc_error::{Backoff, RpcError};
+use crate::run_context::RunContext;
+use crate::seeds::SeedSource;
+use crate::staleness::StalenessGuard;
+use crate::webhook::{EpisodeSummary, EpisodeWebhook};
 use crate::proto::engine::v1::{
     engine_client::EngineClient, EngineId, ResetRequest, StepRequest,
 };
@@ -14,13 +23,25 @@ use crate::proto::replay::v1::{
     replay_client::ReplayClient, StoreBatchRequest, Transition,
 };
 
+/// Batches kept for retry while the replay service is failing, beyond which
+/// the oldest transitions are dropped
+const MAX_BUFFERED_BATCHES: usize = 64;
+
 pub struct Actor {
     config: Config,
     engine_client: EngineClient<Channel>,
     replay_client: ReplayClient<Channel>,
+    replay_credentials: ReplayCredentials,
     policy: Arc<Mutex<Box<dyn Policy>>>,
     episode_count: Arc<Mutex<u32>>,
     transition_buffer: Arc<Mutex<Vec<Transition>>>,
+    replay_backoff: Arc<Mutex<Backoff>>,
+    seed_source: Arc<Mutex<SeedSource>>,
+    orchestrator: Option<OrchestratorClient>,
+    run_context: RunContext,
+    feature_flags: Arc<Mutex<FeatureFlags>>,
+    staleness_guard: Arc<Mutex<StalenessGuard>>,
+    episode_webhook: Option<Arc<Mutex<EpisodeWebhook>>>,
     shutdown_signal: Arc<Mutex<bool>>,
 }
 
@@ -28,20 +49,26 @@ impl Actor {
     pub async fn new(config: Config) -> Result<Self> {
         // Connect to engine service
         info!("Connecting to engine service at {}", config.engine_addr);
-        let engine_channel = tonic::transport::Endpoint::new(config.engine_addr.clone())?
-            .connect()
+        let engine_channel = discovery::grpc_channel(&config.engine_addr, config.discovery_refresh(), None)
             .await
             .map_err(|e| anyhow!("Failed to connect to engine at {}: {}", config.engine_addr, e))?;
 
         // Connect to replay service
-        info!("Connecting to replay service at {}", config.replay_addr);
-        let replay_channel = tonic::transport::Endpoint::new(config.replay_addr.clone())?
-            .connect()
+        let replay_credentials = ReplayCredentials::from_config(&config)?;
+        info!(
+            "Connecting to replay service at {} (tls: {}, token: {})",
+            config.replay_addr,
+            replay_credentials.tls().is_some(),
+            replay_credentials.has_token()
+        );
+        let replay_channel = discovery::grpc_channel(&config.replay_addr, config.discovery_refresh(), replay_credentials.tls())
             .await
             .map_err(|e| anyhow!("Failed to connect to replay at {}: {}", config.replay_addr, e))?;
 
         let mut engine_client = EngineClient::new(engine_channel);
-        let replay_client = ReplayClient::new(replay_channel);
+        // Accept gzip so compressed StoreBatch calls can be answered in kind
+        let replay_client =
+            ReplayClient::new(replay_channel).accept_compressed(CompressionEncoding::Gzip);
 
         // Get game capabilities to configure policy
         info!("Fetching capabilities for environment: {}", config.env_id);
@@ -61,6 +88,53 @@ impl Actor {
         let policy = RandomPolicy::new(&capabilities)
             .map_err(|e| anyhow!("Failed to create policy: {}", e))?;
 
+        let seed_source = SeedSource::from_config(&config).await?;
+        if let Some(pool_size) = seed_source.len() {
+            info!(
+                "Drawing reset seeds from a pool of {} ({} mode)",
+                pool_size, config.seed_pool_mode
+            );
+        }
+
+        let orchestrator = match (&config.orchestrator_addr, &config.run_id) {
+            (Some(addr), Some(run_id)) => {
+                Some(OrchestratorClient::new(addr, run_id, config.discovery_refresh()).await?)
+            }
+            _ => None,
+        };
+
+        let mut experiment_id = config.experiment_id.clone();
+        if let (None, Some(orchestrator)) = (&experiment_id, &orchestrator) {
+            match orchestrator.experiment_id().await {
+                Ok(id) => experiment_id = Some(id),
+                Err(e) => warn!("Failed to read run context, transitions will carry the run ID only: {}", e),
+            }
+        }
+        let run_context = RunContext::new(config.run_id.clone(), experiment_id)?;
+        if !run_context.is_empty() {
+            info!("Stamping transitions with run context {:?}", run_context);
+        }
+
+        let feature_flags = FeatureFlags::from_config(&config.feature_flags)?;
+
+        let staleness_guard = StalenessGuard::from_config(&config);
+        if staleness_guard.is_enabled() && policy.version().is_none() {
+            warn!("Policy staleness limits configured but the policy is not checkpoint-backed; limits will not apply");
+        }
+
+        let episode_webhook = match &config.episode_webhook_url {
+            Some(url) => {
+                info!("Posting episode summaries to {} every {} episodes", url, config.episode_webhook_every);
+                Some(Arc::new(Mutex::new(EpisodeWebhook::new(
+                    url,
+                    config.episode_webhook_every,
+                    &config.actor_id,
+                    &config.env_id,
+                )?)))
+            }
+            None => None,
+        };
+
         info!(
             "Actor {} initialized for environment {}",
             config.actor_id, config.env_id
@@ -74,9 +148,17 @@ impl Actor {
             config,
             engine_client,
             replay_client,
+            replay_credentials,
             policy: Arc::new(Mutex::new(Box::new(policy))),
             episode_count: Arc::new(Mutex::new(0)),
             transition_buffer: Arc::new(Mutex::new(Vec::new())),
+            replay_backoff: Arc::new(Mutex::new(Backoff::default())),
+            seed_source: Arc::new(Mutex::new(seed_source)),
+            orchestrator,
+            run_context,
+            feature_flags: Arc::new(Mutex::new(feature_flags)),
+            staleness_guard: Arc::new(Mutex::new(staleness_guard)),
+            episode_webhook,
             shutdown_signal: Arc::new(Mutex::new(false)),
         })
     }
@@ -86,6 +168,11 @@ impl Actor {
 
         // Setup flush timer for partial batches
         let mut flush_timer = interval(self.config.flush_interval());
+        let mut policy_check_timer = interval(self.config.policy_check_interval());
+        let mut flags_timer = interval(self.config.flags_refresh());
+        let check_latest_checkpoint = self.orchestrator.is_some()
+            && self.staleness_guard.lock().unwrap().is_enabled();
+        let mut collection_paused = false;
 
         loop {
             // Check shutdown signal
@@ -106,7 +193,53 @@ impl Actor {
                     }
                 }
 
+                _ = policy_check_timer.tick(), if check_latest_checkpoint => {
+                    if let Some(orchestrator) = &self.orchestrator {
+                        match orchestrator.latest_checkpoint_version().await {
+                            Ok(version) => self.staleness_guard.lock().unwrap().observe_latest_checkpoint(version),
+                            Err(e) => warn!("Failed to refresh latest checkpoint version: {}", e),
+                        }
+                    }
+                }
+
+                _ = flags_timer.tick(), if self.orchestrator.is_some() => {
+                    if let Some(orchestrator) = &self.orchestrator {
+                        match orchestrator.feature_flags().await {
+                            Ok(remote) => {
+                                let mut flags = self.feature_flags.lock().unwrap();
+                                if flags.update(remote) {
+                                    info!("Feature flags now {:?}", flags.all());
+                                }
+                            }
+                            Err(e) => warn!("Failed to refresh feature flags: {}", e),
+                        }
+                    }
+                }
+
                 _ = tokio::time::sleep(Duration::from_millis(1)) => {
+                    // Hold off collecting while the policy is stale
+                    let policy_version = self.policy.lock().unwrap().version();
+                    let stale_reason = self
+                        .staleness_guard
+                        .lock()
+                        .unwrap()
+                        .check(policy_version, SystemTime::now());
+                    match stale_reason {
+                        Some(reason) => {
+                            if !collection_paused {
+                                warn!("Pausing collection: {}", reason);
+                                collection_paused = true;
+                            }
+                            tokio::time::sleep(Duration::from_secs(1)).await;
+                            continue;
+                        }
+                        None if collection_paused => {
+                            info!("Policy is fresh again, resuming collection");
+                            collection_paused = false;
+                        }
+                        None => {}
+                    }
+
                     // Check episode limit
                     let current_episode_count = *self.episode_count.lock().unwrap();
                     if self.config.max_episodes > 0 && current_episode_count >= self.config.max_episodes as u32 {
@@ -116,7 +249,8 @@ impl Actor {
 
                     // Run an episode
                     match self.run_episode().await {
-                        Ok(_) => {
+                        Ok(summary) => {
+                            self.notify_episode_webhook(summary);
                             let mut count = self.episode_count.lock().unwrap();
                             *count += 1;
                             if *count % 10 == 0 {
@@ -135,6 +269,10 @@ impl Actor {
 
         // Flush any remaining transitions
         self.flush_buffer().await?;
+        let unsent = self.transition_buffer.lock().unwrap().len();
+        if unsent > 0 {
+            warn!("Stopping with {} transitions the replay service has not accepted", unsent);
+        }
         info!("Actor stopped gracefully");
         Ok(())
     }
@@ -144,8 +282,19 @@ impl Actor {
         info!("Shutdown signal set");
     }
 
-    async fn run_episode(&self) -> Result<()> {
+    fn notify_episode_webhook(&self, summary: EpisodeSummary) {
+        if let Some(webhook) = &self.episode_webhook {
+            let mut webhook = webhook.lock().unwrap();
+            if let Some(body) = webhook.record(summary) {
+                webhook.send(body);
+            }
+        }
+    }
+
+    async fn run_episode(&self) -> Result<EpisodeSummary> {
         let episode_count = *self.episode_count.lock().unwrap();
+        let seed = self.seed_source.lock().unwrap().next_seed()?;
+        let started = Instant::now();
 
         // Reset the game
         let reset_request = Request::new(ResetRequest {
@@ -153,7 +302,7 @@ impl Actor {
                 env_id: self.config.env_id.clone(),
                 build_id: "actor-rust".to_string(),
             }),
-            seed: SystemTime::now().duration_since(UNIX_EPOCH)?.as_nanos() as u64,
+            seed,
             hint: vec![],
         });
 
@@ -175,8 +324,9 @@ impl Actor {
         let mut current_state = reset_data.state;
         let mut current_obs = reset_data.obs;
         let mut step_number = 0u32;
+        let mut total_reward = 0.0f32;
 
-        debug!("Started episode {}", episode_id);
+        debug!("Started episode {} with seed {}", episode_id, seed);
 
         loop {
             // Select action using policy
@@ -205,6 +355,13 @@ impl Actor {
             .map_err(|e| anyhow!("Failed to step environment: {}", e))?;
 
             let step_data = step_response.into_inner();
+            total_reward += step_data.reward;
+
+            let mut metadata = std::collections::HashMap::from([(
+                "seed".to_string(),
+                seed.to_string(),
+            )]);
+            self.run_context.stamp(&mut metadata);
 
             // Create transition
             let transition = Transition {
@@ -221,7 +378,7 @@ impl Actor {
                 done: step_data.done,
                 priority: 1.0, // Default priority
                 timestamp: SystemTime::now().duration_since(UNIX_EPOCH)?.as_secs(),
-                metadata: std::collections::HashMap::new(),
+                metadata,
             };
 
             // Add to buffer
@@ -244,7 +401,15 @@ impl Actor {
                     step_number + 1,
                     step_data.reward
                 );
-                break;
+                return Ok(EpisodeSummary {
+                    episode_id,
+                    seed,
+                    steps: step_number + 1,
+                    total_reward,
+                    final_reward: step_data.reward,
+                    duration_ms: started.elapsed().as_millis() as u64,
+                    completed_at: SystemTime::now().duration_since(UNIX_EPOCH)?.as_secs(),
+                });
             }
 
             // Update state for next step
@@ -252,8 +417,6 @@ impl Actor {
             current_obs = step_data.obs;
             step_number += 1;
         }
-
-        Ok(())
     }
 
     async fn flush_buffer(&self) -> Result<()> {
@@ -262,23 +425,75 @@ impl Actor {
             if buffer.is_empty() {
                 return Ok(());
             }
+            // Keep collecting while the replay service recovers
+            if let Some(wait) = self.replay_backoff.lock().unwrap().remaining(Instant::now()) {
+                debug!("Replay backing off for {:?}, holding {} transitions", wait, buffer.len());
+                self.trim_buffer(&mut buffer);
+                return Ok(());
+            }
             std::mem::take(&mut *buffer)
         };
 
         debug!("Flushing {} transitions to replay service", transitions.len());
 
-        let request = Request::new(StoreBatchRequest { transitions });
+        let mut request = Request::new(StoreBatchRequest {
+            transitions: transitions.clone(),
+        });
+        self.run_context.apply(&mut request);
+        self.replay_credentials.apply(&mut request);
 
-        self.replay_client
-            .clone()
-            .store_batch(request)
-            .await
-            .map_err(|e| anyhow!("Failed to store batch: {}", e))?;
+        let mut replay_client = self.replay_client.clone();
+        if self.feature_flags.lock().unwrap().enabled(flags::COMPRESS_TRANSITIONS) {
+            replay_client = replay_client.send_compressed(CompressionEncoding::Gzip);
+        }
+        match replay_client.store_batch(request).await {
+            Ok(_) => {
+                self.replay_backoff.lock().unwrap().succeeded();
+                Ok(())
+            }
+            Err(status) => {
+                let error = RpcError::from_status(&status);
+                if !error.retryable {
+                    return Err(anyhow!("Failed to store batch of {} transitions: {}", transitions.len(), error));
+                }
 
-        Ok(())
+                let delay = self
+                    .replay_backoff
+                    .lock()
+                    .unwrap()
+                    .failed(error.retry_after, Instant::now());
+                warn!(
+                    "Failed to store batch of {} transitions, retrying in {:?}: {}",
+                    transitions.len(),
+                    delay,
+                    error
+                );
+
+                // Put the batch back ahead of anything collected since
+                let mut buffer = self.transition_buffer.lock().unwrap();
+                let newer = std::mem::replace(&mut *buffer, transitions);
+                buffer.extend(newer);
+                self.trim_buffer(&mut buffer);
+                Ok(())
+            }
+        }
+    }
+
+    fn trim_buffer(&self, buffer: &mut Vec<Transition>) {
+        let excess = trim_oldest(buffer, self.config.batch_size * MAX_BUFFERED_BATCHES);
+        if excess > 0 {
+            warn!("Replay buffer backlog full, dropped {} oldest transitions", excess);
+        }
     }
 }
 
+/// Drop the oldest transitions beyond limit, returning how many were dropped
+fn trim_oldest(buffer: &mut Vec<Transition>, limit: usize) -> usize {
+    let excess = buffer.len().saturating_sub(limit);
+    buffer.drain(..excess);
+    excess
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -289,7 +504,11 @@ mod tests {
         ClearRequest, ClearResponse, GetStatsRequest, SampleRequest, SampleResponse,
         StatsResponse, StoreBatchRequest, StoreBatchResponse, StoreTransitionRequest,
         StoreTransitionResponse, Transition, UpdatePrioritiesRequest,
-        UpdatePrioritiesResponse,
+        UpdatePrioritiesResponse, VersionRequest, VersionResponse,
+        GetEpisodeRequest, GetEpisodeResponse,
+        WatchEvent, WatchRequest,
+        SampleStreamRequest,
+        ExportRequest, ExportResponse,
     };
     use std::collections::HashMap;
     use std::net::TcpListener;
@@ -355,6 +574,47 @@ mod tests {
         ) -> Result<Response<ClearResponse>, Status> {
             Err(Status::unimplemented("clear not implemented in tests"))
         }
+
+        async fn version(
+            &self,
+            _request: tonic::Request<VersionRequest>,
+        ) -> Result<Response<VersionResponse>, Status> {
+            Err(Status::unimplemented("version not implemented in tests"))
+        }
+
+        type ExportStream = tokio_stream::Empty<Result<ExportResponse, Status>>;
+
+        async fn export(
+            &self,
+            _request: tonic::Request<ExportRequest>,
+        ) -> Result<Response<Self::ExportStream>, Status> {
+            Err(Status::unimplemented("export not implemented in tests"))
+        }
+
+        type SampleStreamStream = tokio_stream::Empty<Result<SampleResponse, Status>>;
+
+        async fn sample_stream(
+            &self,
+            _request: tonic::Request<SampleStreamRequest>,
+        ) -> Result<Response<Self::SampleStreamStream>, Status> {
+            Err(Status::unimplemented("sample_stream not implemented in tests"))
+        }
+
+        type WatchStream = tokio_stream::Empty<Result<WatchEvent, Status>>;
+
+        async fn watch(
+            &self,
+            _request: tonic::Request<WatchRequest>,
+        ) -> Result<Response<Self::WatchStream>, Status> {
+            Err(Status::unimplemented("watch not implemented in tests"))
+        }
+
+        async fn get_episode(
+            &self,
+            _request: tonic::Request<GetEpisodeRequest>,
+        ) -> Result<Response<GetEpisodeResponse>, Status> {
+            Err(Status::unimplemented("get_episode not implemented in tests"))
+        }
     }
 
     struct TestPolicy;
@@ -399,19 +659,48 @@ mod tests {
             config: Config {
                 engine_addr: format!("http://{}", addr),
                 replay_addr: format!("http://{}", addr),
+                replay_tls_ca_file: None,
+                replay_tls_cert_file: None,
+                replay_tls_key_file: None,
+                replay_tls_domain: None,
+                replay_token: None,
                 actor_id: "test-actor".into(),
                 env_id: "test-env".into(),
                 max_episodes: 1,
                 episode_timeout_secs: 1,
                 batch_size: 2,
                 flush_interval_secs: 1,
+                seed_pool: vec![],
+                seed_pool_file: None,
+                seed_pool_url: None,
+                seed_pool_mode: "sequential".into(),
+                orchestrator_addr: None,
+                discovery_refresh_secs: 30,
+                run_id: None,
+                experiment_id: None,
+                max_policy_age_secs: None,
+                max_policy_version_lag: None,
+                policy_check_interval_secs: 30,
+                episode_webhook_url: None,
+                episode_webhook_every: 1,
+                feature_flags: vec![],
+                flags_refresh_secs: 30,
+                status_addr: None,
                 log_level: "info".into(),
             },
             engine_client,
             replay_client,
+            replay_credentials: ReplayCredentials::default(),
             policy: Arc::new(Mutex::new(Box::new(TestPolicy))),
             episode_count: Arc::new(Mutex::new(0)),
             transition_buffer: Arc::new(Mutex::new(Vec::new())),
+            replay_backoff: Arc::new(Mutex::new(Backoff::default())),
+            seed_source: Arc::new(Mutex::new(SeedSource::Clock)),
+            orchestrator: None,
+            run_context: RunContext::default(),
+            feature_flags: Arc::new(Mutex::new(FeatureFlags::default())),
+            staleness_guard: Arc::new(Mutex::new(StalenessGuard::default())),
+            episode_webhook: None,
             shutdown_signal: Arc::new(Mutex::new(false)),
         };
 
@@ -457,4 +746,19 @@ mod tests {
         shutdown_tx.send(()).unwrap();
         server_handle.await.unwrap();
     }
+
+    #[test]
+    fn trim_oldest_keeps_newest_transitions() {
+        let mut buffer: Vec<Transition> = (0..5)
+            .map(|i| Transition {
+                id: format!("t{}", i),
+                ..Default::default()
+            })
+            .collect();
+
+        assert_eq!(trim_oldest(&mut buffer, 10), 0);
+        assert_eq!(trim_oldest(&mut buffer, 3), 2);
+        let ids: Vec<_> = buffer.iter().map(|t| t.id.as_str()).collect();
+        assert_eq!(ids, ["t2", "t3", "t4"]);
+    }
 }
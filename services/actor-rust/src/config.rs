@@ -1,24 +1,51 @@
 use anyhow::{anyhow, Result};
 use clap::Parser;
 use serde::{Deserialize, Serialize};
+use std::net::SocketAddr;
 use std::time::Duration;
 
+use crate::credentials;
+use crate::discovery::Target;
+use crate::flags::FeatureFlags;
+use crate::seeds::SeedPoolMode;
+
 #[derive(Parser, Debug, Clone, Serialize, Deserialize)]
 #[command(name = "actor")]
+#[command(version = crate::version::VERSION, long_version = crate::version::LONG_VERSION)]
 #[command(about = "Cartridge RL Actor Service")]
 #[command(long_about = "Actor service that runs game episodes and collects experience data.
 
 The actor connects to the engine service to simulate games and sends
 transition data to the replay service for training.")]
 pub struct Config {
-    /// Engine service address
+    /// Engine service address (http://host:port, dns+srv://<record> or consul://[agent]/<service>)
     #[arg(long, env = "ACTOR_ENGINE_ADDR", default_value = "http://localhost:50051")]
     pub engine_addr: String,
 
-    /// Replay service address
+    /// Replay service address (http://host:port, dns+srv://<record> or consul://[agent]/<service>)
     #[arg(long, env = "ACTOR_REPLAY_ADDR", default_value = "http://localhost:8080")]
     pub replay_addr: String,
 
+    /// PEM CA certificates that verify the replay service's TLS certificate (system roots when unset)
+    #[arg(long, env = "ACTOR_REPLAY_TLS_CA_FILE")]
+    pub replay_tls_ca_file: Option<String>,
+
+    /// PEM client certificate presented to the replay service for mutual TLS
+    #[arg(long, env = "ACTOR_REPLAY_TLS_CERT_FILE")]
+    pub replay_tls_cert_file: Option<String>,
+
+    /// PEM private key of the replay client certificate
+    #[arg(long, env = "ACTOR_REPLAY_TLS_KEY_FILE")]
+    pub replay_tls_key_file: Option<String>,
+
+    /// Name the replay service's certificate must be issued for (the host of each replica when unset)
+    #[arg(long, env = "ACTOR_REPLAY_TLS_DOMAIN")]
+    pub replay_tls_domain: Option<String>,
+
+    /// Bearer token sent to the replay service on every call
+    #[arg(long, env = "ACTOR_REPLAY_TOKEN", hide_env_values = true)]
+    pub replay_token: Option<String>,
+
     /// Unique actor identifier
     #[arg(long, env = "ACTOR_ACTOR_ID", default_value = "actor-rust-1")]
     pub actor_id: String,
@@ -43,6 +70,70 @@ pub struct Config {
     #[arg(long, env = "ACTOR_FLUSH_INTERVAL", default_value = "5")]
     pub flush_interval_secs: u64,
 
+    /// Comma-separated list of Reset seeds to draw episodes from
+    #[arg(long, env = "ACTOR_SEED_POOL", value_delimiter = ',')]
+    pub seed_pool: Vec<u64>,
+
+    /// File containing Reset seeds, one per line
+    #[arg(long, env = "ACTOR_SEED_POOL_FILE")]
+    pub seed_pool_file: Option<String>,
+
+    /// URL returning a JSON array of Reset seeds (e.g. an orchestrator evaluation suite)
+    #[arg(long, env = "ACTOR_SEED_POOL_URL")]
+    pub seed_pool_url: Option<String>,
+
+    /// How seeds are drawn from the pool (sequential, random)
+    #[arg(long, env = "ACTOR_SEED_POOL_MODE", default_value = "sequential")]
+    pub seed_pool_mode: String,
+
+    /// Orchestrator base URL used to look up the run's latest checkpoint
+    #[arg(long, env = "ACTOR_ORCHESTRATOR_ADDR")]
+    pub orchestrator_addr: Option<String>,
+
+    /// Interval to re-resolve discovered service addresses in seconds
+    #[arg(long, env = "ACTOR_DISCOVERY_REFRESH", default_value = "30")]
+    pub discovery_refresh_secs: u64,
+
+    /// Orchestrator run this actor collects experience for
+    #[arg(long, env = "ACTOR_RUN_ID")]
+    pub run_id: Option<String>,
+
+    /// Experiment the run belongs to (read from the orchestrator when unset)
+    #[arg(long, env = "ACTOR_EXPERIMENT_ID")]
+    pub experiment_id: Option<String>,
+
+    /// Pause collection when the loaded policy is older than this many seconds
+    #[arg(long, env = "ACTOR_MAX_POLICY_AGE")]
+    pub max_policy_age_secs: Option<u64>,
+
+    /// Pause collection when the loaded policy is more than K checkpoint versions behind the latest
+    #[arg(long, env = "ACTOR_MAX_POLICY_VERSION_LAG")]
+    pub max_policy_version_lag: Option<i64>,
+
+    /// Interval to refresh the latest checkpoint version in seconds
+    #[arg(long, env = "ACTOR_POLICY_CHECK_INTERVAL", default_value = "30")]
+    pub policy_check_interval_secs: u64,
+
+    /// Webhook URL that receives JSON summaries of completed episodes
+    #[arg(long, env = "ACTOR_EPISODE_WEBHOOK_URL")]
+    pub episode_webhook_url: Option<String>,
+
+    /// Number of completed episodes batched into each webhook call
+    #[arg(long, env = "ACTOR_EPISODE_WEBHOOK_EVERY", default_value = "1")]
+    pub episode_webhook_every: u32,
+
+    /// Comma-separated feature flags (name=true|false), e.g. compress_transitions=true
+    #[arg(long, env = "ACTOR_FEATURE_FLAGS", value_delimiter = ',')]
+    pub feature_flags: Vec<String>,
+
+    /// Interval to refresh the run's feature flags from the orchestrator in seconds
+    #[arg(long, env = "ACTOR_FLAGS_REFRESH", default_value = "30")]
+    pub flags_refresh_secs: u64,
+
+    /// Address serving the actor's build on GET /version (e.g. 0.0.0.0:9100)
+    #[arg(long, env = "ACTOR_STATUS_ADDR")]
+    pub status_addr: Option<String>,
+
     /// Log level (trace, debug, info, warn, error)
     #[arg(long, env = "ACTOR_LOG_LEVEL", default_value = "info")]
     pub log_level: String,
@@ -70,9 +161,86 @@ impl Config {
             return Err(anyhow!("flush_interval_secs must be greater than 0"));
         }
 
+        for addr in [Some(&self.engine_addr), Some(&self.replay_addr), self.orchestrator_addr.as_ref()]
+            .into_iter()
+            .flatten()
+        {
+            Target::parse(addr)?;
+        }
+
+        if self.replay_tls_cert_file.is_some() != self.replay_tls_key_file.is_some() {
+            return Err(anyhow!(
+                "replay_tls_cert_file and replay_tls_key_file must be set together"
+            ));
+        }
+
+        if let Some(token) = &self.replay_token {
+            credentials::authorization(token)?;
+        }
+
+        if self.discovery_refresh_secs == 0 {
+            return Err(anyhow!("discovery_refresh_secs must be greater than 0"));
+        }
+
+        let seed_sources = [
+            !self.seed_pool.is_empty(),
+            self.seed_pool_file.is_some(),
+            self.seed_pool_url.is_some(),
+        ];
+        if seed_sources.iter().filter(|set| **set).count() > 1 {
+            return Err(anyhow!(
+                "only one of seed_pool, seed_pool_file, or seed_pool_url may be set"
+            ));
+        }
+
+        SeedPoolMode::parse(&self.seed_pool_mode)?;
+
+        if let Some(lag) = self.max_policy_version_lag {
+            if lag < 0 {
+                return Err(anyhow!("max_policy_version_lag must be non-negative"));
+            }
+            if self.orchestrator_addr.is_none() || self.run_id.is_none() {
+                return Err(anyhow!(
+                    "max_policy_version_lag requires orchestrator_addr and run_id"
+                ));
+            }
+        }
+
+        if self.max_policy_age_secs == Some(0) {
+            return Err(anyhow!("max_policy_age_secs must be greater than 0"));
+        }
+
+        if self.policy_check_interval_secs == 0 {
+            return Err(anyhow!("policy_check_interval_secs must be greater than 0"));
+        }
+
+        if self.episode_webhook_every == 0 {
+            return Err(anyhow!("episode_webhook_every must be greater than 0"));
+        }
+
+        FeatureFlags::from_config(&self.feature_flags)?;
+
+        if self.flags_refresh_secs == 0 {
+            return Err(anyhow!("flags_refresh_secs must be greater than 0"));
+        }
+
+        if let Some(addr) = &self.status_addr {
+            addr.parse::<SocketAddr>()
+                .map_err(|e| anyhow!("invalid status_addr {}: {}", addr, e))?;
+        }
+
         Ok(())
     }
 
+    /// Whether the actor connects to the replay service over TLS: when its
+    /// address is https:// or any replay_tls_* option is set
+    pub fn replay_tls_enabled(&self) -> bool {
+        self.replay_addr.starts_with("https://")
+            || self.replay_tls_ca_file.is_some()
+            || self.replay_tls_cert_file.is_some()
+            || self.replay_tls_domain.is_some()
+    }
+
     pub fn episode_timeout(&self) -> Duration {
         Duration::from_secs(self.episode_timeout_secs)
     }
@@ -80,4 +248,16 @@ impl Config {
     pub fn flush_interval(&self) -> Duration {
         Duration::from_secs(self.flush_interval_secs)
     }
+
+    pub fn policy_check_interval(&self) -> Duration {
+        Duration::from_secs(self.policy_check_interval_secs)
+    }
+
+    pub fn discovery_refresh(&self) -> Duration {
+        Duration::from_secs(self.discovery_refresh_secs)
+    }
+
+    pub fn flags_refresh(&self) -> Duration {
+        Duration::from_secs(self.flags_refresh_secs)
+    }
 }
\ No newline at end of file
@@ -0,0 +1,98 @@
+//! Credentials the actor presents to the replay service: TLS, with a client
+//! certificate when the service requires mutual TLS, and a bearer token sent
+//! on every call.
+
+use anyhow::{anyhow, Result};
+use tonic::metadata::{Ascii, MetadataValue};
+use tonic::transport::{Certificate, ClientTlsConfig, Identity};
+use tonic::Request;
+
+use crate::config::Config;
+
+#[derive(Debug, Clone, Default)]
+pub struct ReplayCredentials {
+    tls: Option<ClientTlsConfig>,
+    authorization: Option<MetadataValue<Ascii>>,
+}
+
+impl ReplayCredentials {
+    /// TLS is used when the replay address is https:// or any replay_tls_*
+    /// option is set. Certificates are read from their files here.
+    pub fn from_config(config: &Config) -> Result<Self> {
+        let tls = if config.replay_tls_enabled() {
+            let mut tls = ClientTlsConfig::new();
+            if let Some(path) = &config.replay_tls_ca_file {
+                tls = tls.ca_certificate(Certificate::from_pem(read_pem(path)?));
+            }
+            if let (Some(cert), Some(key)) = (&config.replay_tls_cert_file, &config.replay_tls_key_file) {
+                tls = tls.identity(Identity::from_pem(read_pem(cert)?, read_pem(key)?));
+            }
+            if let Some(domain) = &config.replay_tls_domain {
+                tls = tls.domain_name(domain.clone());
+            }
+            Some(tls)
+        } else {
+            None
+        };
+        let authorization = config
+            .replay_token
+            .as_deref()
+            .map(authorization)
+            .transpose()?;
+        Ok(Self { tls, authorization })
+    }
+
+    pub fn tls(&self) -> Option<&ClientTlsConfig> {
+        self.tls.as_ref()
+    }
+
+    pub fn has_token(&self) -> bool {
+        self.authorization.is_some()
+    }
+
+    /// Send the bearer token, if any, as gRPC metadata on a request
+    pub fn apply<T>(&self, request: &mut Request<T>) {
+        if let Some(value) = &self.authorization {
+            request.metadata_mut().insert("authorization", value.clone());
+        }
+    }
+}
+
+/// The "authorization" metadata value carrying token
+pub fn authorization(token: &str) -> Result<MetadataValue<Ascii>> {
+    if token.is_empty() {
+        return Err(anyhow!("replay_token cannot be empty"));
+    }
+    MetadataValue::try_from(format!("Bearer {}", token))
+        .map_err(|_| anyhow!("Invalid replay_token, must be printable ASCII"))
+}
+
+fn read_pem(path: &str) -> Result<Vec<u8>> {
+    std::fs::read(path).map_err(|e| anyhow!("Failed to read {}: {}", path, e))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn sends_bearer_token() {
+        let credentials = ReplayCredentials {
+            tls: None,
+            authorization: Some(authorization("actor-secret").unwrap()),
+        };
+        let mut request = Request::new(());
+        credentials.apply(&mut request);
+        assert_eq!(request.metadata().get("authorization").unwrap(), "Bearer actor-secret");
+
+        let mut request = Request::new(());
+        ReplayCredentials::default().apply(&mut request);
+        assert!(request.metadata().get("authorization").is_none());
+    }
+
+    #[test]
+    fn rejects_invalid_tokens() {
+        assert!(authorization("").is_err());
+        assert!(authorization("line\nbreak").is_err());
+    }
+}
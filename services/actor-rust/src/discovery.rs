@@ -0,0 +1,432 @@
+//! Service discovery for the addresses the actor connects to.
+//!
+//! An address is one of:
+//! - `http://host:port`: a single, static endpoint
+//! - `dns+srv://<record>`: the targets of a DNS SRV record, e.g.
+//!   `dns+srv://_grpc._tcp.replay.cartridge.svc.cluster.local`
+//! - `consul://[agent]/<service>[?tag=<tag>]`: the instances of a service that
+//!   pass their Consul health checks. The agent defaults to `127.0.0.1:8500`.
+//!
+//! Discovered addresses are re-resolved in the background and calls are spread
+//! across every replica found.
+
+use anyhow::{anyhow, Result};
+use hickory_resolver::TokioAsyncResolver;
+use serde::Deserialize;
+use std::collections::BTreeSet;
+use std::fmt;
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::sync::Arc;
+use std::time::Duration;
+use tokio::sync::{mpsc, watch};
+use tonic::transport::channel::Change;
+use tonic::transport::{Channel, ClientTlsConfig, Endpoint};
+use tracing::{info, warn};
+
+const DEFAULT_CONSUL_AGENT: &str = "127.0.0.1:8500";
+
+/// Where the replicas of a service are found
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum Target {
+    Static(String),
+    Srv {
+        record: String,
+    },
+    Consul {
+        agent: String,
+        service: String,
+        tag: Option<String>,
+    },
+}
+
+impl Target {
+    pub fn parse(addr: &str) -> Result<Self> {
+        if let Some(record) = addr.strip_prefix("dns+srv://") {
+            let record = record.trim_end_matches('/');
+            if record.is_empty() || record.contains('/') {
+                return Err(anyhow!("invalid address {}: expected dns+srv://<record>", addr));
+            }
+            return Ok(Target::Srv {
+                record: record.to_string(),
+            });
+        }
+
+        if let Some(rest) = addr.strip_prefix("consul://") {
+            let (location, query) = match rest.split_once('?') {
+                Some((location, query)) => (location, Some(query)),
+                None => (rest, None),
+            };
+            let (agent, service) = location.split_once('/').unwrap_or(("", location));
+            let service = service.trim_end_matches('/');
+            if service.is_empty() || service.contains('/') {
+                return Err(anyhow!(
+                    "invalid address {}: expected consul://[agent]/<service>",
+                    addr
+                ));
+            }
+            let mut tag = None;
+            for option in query.into_iter().flat_map(|query| query.split('&')) {
+                match option.split_once('=') {
+                    Some(("tag", value)) if !value.is_empty() => tag = Some(value.to_string()),
+                    _ => return Err(anyhow!("invalid address {}: unsupported option {}", addr, option)),
+                }
+            }
+            return Ok(Target::Consul {
+                agent: if agent.is_empty() { DEFAULT_CONSUL_AGENT } else { agent }.to_string(),
+                service: service.to_string(),
+                tag,
+            });
+        }
+
+        if addr.is_empty() {
+            return Err(anyhow!("address cannot be empty"));
+        }
+        Ok(Target::Static(addr.trim_end_matches('/').to_string()))
+    }
+
+    /// Whether the replicas behind the target can change while the actor runs
+    pub fn is_dynamic(&self) -> bool {
+        !matches!(self, Target::Static(_))
+    }
+
+    /// Base URLs of the replicas currently behind the target, sorted
+    pub async fn resolve(&self, http: &reqwest::Client) -> Result<Vec<String>> {
+        let mut endpoints = match self {
+            Target::Static(addr) => vec![addr.clone()],
+            Target::Srv { record } => resolve_srv(record).await?,
+            Target::Consul {
+                agent,
+                service,
+                tag,
+            } => resolve_consul(http, agent, service, tag.as_deref()).await?,
+        };
+        endpoints.sort();
+        endpoints.dedup();
+        if endpoints.is_empty() {
+            return Err(anyhow!("no instances found for {}", self));
+        }
+        Ok(endpoints)
+    }
+}
+
+impl fmt::Display for Target {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Target::Static(addr) => write!(f, "{}", addr),
+            Target::Srv { record } => write!(f, "dns+srv://{}", record),
+            Target::Consul {
+                agent,
+                service,
+                tag: Some(tag),
+            } => write!(f, "consul://{}/{}?tag={}", agent, service, tag),
+            Target::Consul { agent, service, .. } => write!(f, "consul://{}/{}", agent, service),
+        }
+    }
+}
+
+async fn resolve_srv(record: &str) -> Result<Vec<String>> {
+    let resolver = TokioAsyncResolver::tokio_from_system_conf()
+        .map_err(|e| anyhow!("Failed to read DNS configuration: {}", e))?;
+    let lookup = resolver
+        .srv_lookup(record)
+        .await
+        .map_err(|e| anyhow!("SRV lookup for {} failed: {}", record, e))?;
+    Ok(lookup
+        .iter()
+        .map(|srv| endpoint_url(&srv.target().to_utf8(), srv.port()))
+        .collect())
+}
+
+/// Entry of Consul's /v1/health/service response
+#[derive(Debug, Deserialize)]
+struct ConsulEntry {
+    #[serde(rename = "Node")]
+    node: ConsulNode,
+    #[serde(rename = "Service")]
+    service: ConsulService,
+}
+
+#[derive(Debug, Deserialize)]
+struct ConsulNode {
+    #[serde(rename = "Address")]
+    address: String,
+}
+
+#[derive(Debug, Deserialize)]
+struct ConsulService {
+    #[serde(rename = "Address", default)]
+    address: String,
+    #[serde(rename = "Port")]
+    port: u16,
+}
+
+async fn resolve_consul(
+    http: &reqwest::Client,
+    agent: &str,
+    service: &str,
+    tag: Option<&str>,
+) -> Result<Vec<String>> {
+    let mut request = http
+        .get(format!("http://{}/v1/health/service/{}", agent, service))
+        .query(&[("passing", "true")]);
+    if let Some(tag) = tag {
+        request = request.query(&[("tag", tag)]);
+    }
+    let entries = request
+        .send()
+        .await
+        .map_err(|e| anyhow!("Consul lookup for {} failed: {}", service, e))?
+        .error_for_status()
+        .map_err(|e| anyhow!("Consul lookup for {} failed: {}", service, e))?
+        .json::<Vec<ConsulEntry>>()
+        .await
+        .map_err(|e| anyhow!("Invalid Consul response for {}: {}", service, e))?;
+    Ok(consul_endpoints(&entries))
+}
+
+/// Services registered without an address are reached at their node's
+fn consul_endpoints(entries: &[ConsulEntry]) -> Vec<String> {
+    entries
+        .iter()
+        .map(|entry| {
+            let host = if entry.service.address.is_empty() {
+                &entry.node.address
+            } else {
+                &entry.service.address
+            };
+            endpoint_url(host, entry.service.port)
+        })
+        .collect()
+}
+
+fn endpoint_url(host: &str, port: u16) -> String {
+    let host = host.trim_end_matches('.');
+    if host.contains(':') {
+        format!("http://[{}]:{}", host, port)
+    } else {
+        format!("http://{}:{}", host, port)
+    }
+}
+
+/// Resolves target, then keeps re-resolving it every `refresh` in the
+/// background, publishing each new set of replicas. A failed re-resolution
+/// keeps the replicas last found.
+pub async fn watch_target(target: Target, refresh: Duration) -> Result<watch::Receiver<Vec<String>>> {
+    let http = reqwest::Client::builder()
+        .timeout(Duration::from_secs(5))
+        .build()
+        .map_err(|e| anyhow!("Failed to build discovery client: {}", e))?;
+    let initial = target.resolve(&http).await?;
+    let (tx, rx) = watch::channel(initial);
+    if target.is_dynamic() {
+        tokio::spawn(async move {
+            let mut ticker = tokio::time::interval(refresh);
+            ticker.tick().await;
+            while !tx.is_closed() {
+                ticker.tick().await;
+                match target.resolve(&http).await {
+                    Ok(endpoints) if *tx.borrow() != endpoints => {
+                        info!("{} now resolves to {:?}", target, endpoints);
+                        tx.send_replace(endpoints);
+                    }
+                    Ok(_) => {}
+                    Err(e) => warn!(
+                        "Re-resolving {} failed, keeping {} known instances: {}",
+                        target,
+                        tx.borrow().len(),
+                        e
+                    ),
+                }
+            }
+        });
+    }
+    Ok(rx)
+}
+
+/// Connects to the gRPC service at addr. A static address is connected to
+/// directly; a discovered one balances calls across its replicas, re-resolved
+/// every `refresh`. With `tls`, every replica is connected to over TLS.
+pub async fn grpc_channel(addr: &str, refresh: Duration, tls: Option<&ClientTlsConfig>) -> Result<Channel> {
+    let target = Target::parse(addr)?;
+    if let Target::Static(addr) = &target {
+        return Ok(endpoint(addr, tls)?.connect().await?);
+    }
+    let tls = tls.cloned();
+
+    let mut endpoints = watch_target(target.clone(), refresh).await?;
+    let initial = endpoints.borrow_and_update().clone();
+    info!("{} resolves to {:?}", target, initial);
+
+    let (channel, changes) = Channel::balance_channel::<String>(16);
+    let mut current = BTreeSet::new();
+    sync_endpoints(&changes, &mut current, initial, tls.as_ref()).await?;
+    tokio::spawn(async move {
+        while endpoints.changed().await.is_ok() {
+            let latest = endpoints.borrow_and_update().clone();
+            if let Err(e) = sync_endpoints(&changes, &mut current, latest, tls.as_ref()).await {
+                warn!("Failed to update replicas of {}: {}", target, e);
+                if changes.is_closed() {
+                    return;
+                }
+            }
+        }
+    });
+    Ok(channel)
+}
+
+/// Adds and removes balanced endpoints so the channel matches latest
+async fn sync_endpoints(
+    changes: &mpsc::Sender<Change<String, Endpoint>>,
+    current: &mut BTreeSet<String>,
+    latest: Vec<String>,
+    tls: Option<&ClientTlsConfig>,
+) -> Result<()> {
+    let (added, removed) = diff(current, &latest);
+    for uri in removed {
+        changes
+            .send(Change::Remove(uri.clone()))
+            .await
+            .map_err(|_| anyhow!("channel closed"))?;
+        current.remove(&uri);
+    }
+    for uri in added {
+        let endpoint = endpoint(&uri, tls)?;
+        changes
+            .send(Change::Insert(uri.clone(), endpoint))
+            .await
+            .map_err(|_| anyhow!("channel closed"))?;
+        current.insert(uri);
+    }
+    Ok(())
+}
+
+/// Endpoint for the replica at uri. With `tls`, http:// URIs, such as those
+/// of discovered replicas, are connected to over https:// instead.
+fn endpoint(uri: &str, tls: Option<&ClientTlsConfig>) -> Result<Endpoint> {
+    let Some(tls) = tls else {
+        return Ok(Endpoint::from_shared(uri.to_string())?);
+    };
+    let uri = match uri.strip_prefix("http://") {
+        Some(rest) => format!("https://{}", rest),
+        None => uri.to_string(),
+    };
+    Ok(Endpoint::from_shared(uri)?.tls_config(tls.clone())?)
+}
+
+/// Replicas in latest but not current, and in current but not latest
+fn diff(current: &BTreeSet<String>, latest: &[String]) -> (Vec<String>, Vec<String>) {
+    let latest: BTreeSet<String> = latest.iter().cloned().collect();
+    let added = latest.difference(current).cloned().collect();
+    let removed = current.difference(&latest).cloned().collect();
+    (added, removed)
+}
+
+/// Round-robins HTTP requests across the replicas behind an address
+#[derive(Clone)]
+pub struct HttpEndpoints {
+    endpoints: watch::Receiver<Vec<String>>,
+    next: Arc<AtomicUsize>,
+}
+
+impl HttpEndpoints {
+    pub async fn new(addr: &str, refresh: Duration) -> Result<Self> {
+        let endpoints = watch_target(Target::parse(addr)?, refresh).await?;
+        Ok(Self {
+            endpoints,
+            next: Arc::new(AtomicUsize::new(0)),
+        })
+    }
+
+    /// Base URL of the replica to send the next request to
+    pub fn pick(&self) -> String {
+        let endpoints = self.endpoints.borrow();
+        let i = self.next.fetch_add(1, Ordering::Relaxed);
+        endpoints[i % endpoints.len()].clone()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parses_addresses() {
+        assert_eq!(
+            Target::parse("http://engine:50051/").unwrap(),
+            Target::Static("http://engine:50051".into())
+        );
+        assert_eq!(
+            Target::parse("dns+srv://_grpc._tcp.replay.svc").unwrap(),
+            Target::Srv {
+                record: "_grpc._tcp.replay.svc".into()
+            }
+        );
+        assert_eq!(
+            Target::parse("consul:///engine?tag=gpu").unwrap(),
+            Target::Consul {
+                agent: DEFAULT_CONSUL_AGENT.into(),
+                service: "engine".into(),
+                tag: Some("gpu".into()),
+            }
+        );
+        assert_eq!(
+            Target::parse("consul://consul:8500/replay").unwrap().to_string(),
+            "consul://consul:8500/replay"
+        );
+
+        for invalid in ["", "dns+srv://", "consul://agent/", "consul:///a/b", "consul:///engine?dc=east"] {
+            assert!(Target::parse(invalid).is_err(), "{} should be rejected", invalid);
+        }
+    }
+
+    #[test]
+    fn prefers_service_addresses_from_consul() {
+        let entries: Vec<ConsulEntry> = serde_json::from_str(
+            r#"[
+                {"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 8080}},
+                {"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "10.1.0.2", "Port": 8081}},
+                {"Node": {"Address": "10.0.0.3"}, "Service": {"Address": "fd00::3", "Port": 8082}}
+            ]"#,
+        )
+        .unwrap();
+        assert_eq!(
+            consul_endpoints(&entries),
+            vec!["http://10.0.0.1:8080", "http://10.1.0.2:8081", "http://[fd00::3]:8082"]
+        );
+        assert_eq!(endpoint_url("replay-0.replay.svc.", 8080), "http://replay-0.replay.svc:8080");
+    }
+
+    #[test]
+    fn connects_to_replicas_over_tls() {
+        let scheme = |uri: &str, tls: Option<&ClientTlsConfig>| {
+            endpoint(uri, tls).unwrap().uri().scheme_str().unwrap().to_string()
+        };
+        let tls = ClientTlsConfig::new();
+        assert_eq!(scheme("http://10.0.0.1:8080", None), "http");
+        assert_eq!(scheme("http://10.0.0.1:8080", Some(&tls)), "https");
+        assert_eq!(scheme("https://replay:8080", Some(&tls)), "https");
+    }
+
+    #[test]
+    fn diffs_replica_sets() {
+        let current: BTreeSet<String> = ["http://a:1", "http://b:1"].iter().map(|s| s.to_string()).collect();
+        let (added, removed) = diff(&current, &["http://b:1".into(), "http://c:1".into()]);
+        assert_eq!(added, vec!["http://c:1"]);
+        assert_eq!(removed, vec!["http://a:1"]);
+    }
+
+    #[test]
+    fn round_robins_http_requests() {
+        let (tx, endpoints) = watch::channel(vec!["http://a:1".to_string(), "http://b:1".to_string()]);
+        let http = HttpEndpoints {
+            endpoints,
+            next: Arc::new(AtomicUsize::new(0)),
+        };
+        assert_eq!(http.pick(), "http://a:1");
+        assert_eq!(http.pick(), "http://b:1");
+        assert_eq!(http.pick(), "http://a:1");
+
+        tx.send_replace(vec!["http://c:1".to_string()]);
+        assert_eq!(http.pick(), "http://c:1");
+    }
+}
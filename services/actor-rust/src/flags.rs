@@ -0,0 +1,96 @@
+//! Feature flags gating risky actor behaviours. Flags are configured with
+//! --feature-flags and, with an orchestrator and run configured, overridden by
+//! the flags the orchestrator resolves for the run.
+
+use anyhow::{anyhow, Result};
+use std::collections::{BTreeMap, HashMap};
+
+/// Gzip-compress StoreBatch calls to the replay service
+pub const COMPRESS_TRANSITIONS: &str = "compress_transitions";
+
+#[derive(Debug, Default)]
+pub struct FeatureFlags {
+    configured: HashMap<String, bool>,
+    remote: HashMap<String, bool>,
+}
+
+impl FeatureFlags {
+    /// Flags from name=true|false entries
+    pub fn from_config(entries: &[String]) -> Result<Self> {
+        Ok(Self {
+            configured: parse(entries)?,
+            remote: HashMap::new(),
+        })
+    }
+
+    pub fn enabled(&self, name: &str) -> bool {
+        self.remote
+            .get(name)
+            .or_else(|| self.configured.get(name))
+            .copied()
+            .unwrap_or(false)
+    }
+
+    /// Replaces the orchestrator's flags, returning whether any flag in effect changed
+    pub fn update(&mut self, remote: HashMap<String, bool>) -> bool {
+        let before = self.all();
+        self.remote = remote;
+        before != self.all()
+    }
+
+    /// Every flag in effect, ordered by name
+    pub fn all(&self) -> BTreeMap<String, bool> {
+        let mut all: BTreeMap<String, bool> = self.configured.clone().into_iter().collect();
+        all.extend(self.remote.clone());
+        all
+    }
+}
+
+fn parse(entries: &[String]) -> Result<HashMap<String, bool>> {
+    entries
+        .iter()
+        .map(|entry| {
+            let (name, value) = entry
+                .split_once('=')
+                .ok_or_else(|| anyhow!("Invalid feature flag '{}', expected name=true|false", entry))?;
+            let name = name.trim();
+            if name.is_empty() {
+                return Err(anyhow!("Invalid feature flag '{}', name is empty", entry));
+            }
+            let enabled = value
+                .trim()
+                .parse::<bool>()
+                .map_err(|_| anyhow!("Invalid feature flag '{}', expected name=true|false", entry))?;
+            Ok((name.to_string(), enabled))
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn orchestrator_overrides_configured_flags() {
+        let mut flags = FeatureFlags::from_config(&[
+            "compress_transitions=true".to_string(),
+            " other = false".to_string(),
+        ])
+        .unwrap();
+        assert!(flags.enabled(COMPRESS_TRANSITIONS));
+        assert!(!flags.enabled("other"));
+        assert!(!flags.enabled("unknown"));
+
+        let changed = flags.update(HashMap::from([(COMPRESS_TRANSITIONS.to_string(), false)]));
+        assert!(changed);
+        assert!(!flags.enabled(COMPRESS_TRANSITIONS));
+        assert!(!flags.update(HashMap::from([(COMPRESS_TRANSITIONS.to_string(), false)])));
+    }
+
+    #[test]
+    fn rejects_malformed_entries() {
+        for entry in ["compress_transitions", "=true", "compress_transitions=yes"] {
+            assert!(FeatureFlags::from_config(&[entry.to_string()]).is_err(), "{}", entry);
+        }
+    }
+}
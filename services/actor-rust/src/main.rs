@@ -6,7 +6,17 @@ use tracing::{info, error};
 
 mod actor;
 mod config;
+mod credentials;
+mod discovery;
+mod flags;
+mod orchestrator;
 mod policy;
+mod rpc_error;
+mod run_context;
+mod seeds;
+mod staleness;
+mod version;
+mod webhook;
 mod proto {
     pub mod engine {
         pub mod v1 {
@@ -34,9 +44,23 @@ async fn main() -> Result<()> {
     // Validate configuration
     config.validate()?;
 
-    info!("Starting actor {} for environment {}", config.actor_id, config.env_id);
+    info!(
+        "Starting actor {} for environment {} (version {}, commit {})",
+        config.actor_id,
+        config.env_id,
+        version::VERSION,
+        version::COMMIT
+    );
     info!("Engine: {}, Replay: {}", config.engine_addr, config.replay_addr);
 
+    if let Some(addr) = config.status_addr.clone() {
+        tokio::spawn(async move {
+            if let Err(e) = version::serve(&addr).await {
+                error!("Status server failed: {}", e);
+            }
+        });
+    }
+
     // Create actor instance
     let actor = Actor::new(config).await?;
     let actor = Arc::new(actor);
@@ -0,0 +1,94 @@
+use anyhow::{anyhow, Result};
+use serde::Deserialize;
+use std::collections::HashMap;
+use std::time::Duration;
+
+use crate::discovery::HttpEndpoints;
+
+/// Minimal client for the orchestrator HTTP API
+#[derive(Clone)]
+pub struct OrchestratorClient {
+    http: reqwest::Client,
+    endpoints: HttpEndpoints,
+    run_id: String,
+}
+
+#[derive(Debug, Deserialize)]
+struct RunResource {
+    checkpoint_version: i64,
+}
+
+#[derive(Debug, Deserialize)]
+struct RunContextResource {
+    experiment_id: String,
+}
+
+#[derive(Debug, Deserialize)]
+struct RunFlags {
+    flags: HashMap<String, bool>,
+}
+
+impl OrchestratorClient {
+    pub async fn new(addr: &str, run_id: &str, refresh: Duration) -> Result<Self> {
+        let http = reqwest::Client::builder()
+            .timeout(Duration::from_secs(10))
+            .build()
+            .map_err(|e| anyhow!("Failed to build orchestrator client: {}", e))?;
+        Ok(Self {
+            http,
+            endpoints: HttpEndpoints::new(addr, refresh).await?,
+            run_id: run_id.to_string(),
+        })
+    }
+
+    /// Latest checkpoint version the learner reported for this run
+    pub async fn latest_checkpoint_version(&self) -> Result<i64> {
+        let url = format!("{}/api/v1/runs/{}", self.endpoints.pick(), self.run_id);
+        let run = self
+            .http
+            .get(&url)
+            .send()
+            .await
+            .map_err(|e| anyhow!("Failed to fetch run {}: {}", self.run_id, e))?
+            .error_for_status()
+            .map_err(|e| anyhow!("Run lookup for {} failed: {}", self.run_id, e))?
+            .json::<RunResource>()
+            .await
+            .map_err(|e| anyhow!("Invalid run payload for {}: {}", self.run_id, e))?;
+        Ok(run.checkpoint_version)
+    }
+
+    /// Feature flags the orchestrator resolves for this run
+    pub async fn feature_flags(&self) -> Result<HashMap<String, bool>> {
+        let url = format!("{}/api/v1/runs/{}/flags", self.endpoints.pick(), self.run_id);
+        let flags = self
+            .http
+            .get(&url)
+            .send()
+            .await
+            .map_err(|e| anyhow!("Failed to fetch feature flags for {}: {}", self.run_id, e))?
+            .error_for_status()
+            .map_err(|e| anyhow!("Feature flag lookup for {} failed: {}", self.run_id, e))?
+            .json::<RunFlags>()
+            .await
+            .map_err(|e| anyhow!("Invalid feature flags payload for {}: {}", self.run_id, e))?;
+        Ok(flags.flags)
+    }
+
+    /// Experiment the orchestrator issued in this run's context
+    pub async fn experiment_id(&self) -> Result<String> {
+        let url = format!("{}/api/v1/runs/{}/context", self.endpoints.pick(), self.run_id);
+        let context = self
+            .http
+            .get(&url)
+            .send()
+            .await
+            .map_err(|e| anyhow!("Failed to fetch run context for {}: {}", self.run_id, e))?
+            .error_for_status()
+            .map_err(|e| anyhow!("Run context lookup for {} failed: {}", self.run_id, e))?
+            .json::<RunContextResource>()
+            .await
+            .map_err(|e| anyhow!("Invalid run context payload for {}: {}", self.run_id, e))?;
+        Ok(context.experiment_id)
+    }
+}
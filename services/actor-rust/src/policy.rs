@@ -1,12 +1,26 @@
 use anyhow::{anyhow, Result};
 use rand::prelude::*;
 use rand_chacha::ChaCha20Rng;
+use std::time::SystemTime;
 use crate::proto::engine::v1::Capabilities;
 
+/// Checkpoint a hot-reloadable policy was loaded from
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct PolicyVersion {
+    pub checkpoint_version: i64,
+    pub loaded_at: SystemTime,
+}
+
 /// Trait for action selection policies
 pub trait Policy: Send + Sync {
     /// Select an action given an observation
     fn select_action(&mut self, observation: &[u8]) -> Result<Vec<u8>>;
+
+    /// Checkpoint currently loaded, if the policy is backed by one.
+    /// Policies without weights (e.g. random) are never considered stale.
+    fn version(&self) -> Option<PolicyVersion> {
+        None
+    }
 }
 
 /// Random policy that selects actions uniformly at random
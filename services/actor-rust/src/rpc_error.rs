@@ -0,0 +1,158 @@
+use std::fmt;
+use std::time::{Duration, Instant};
+
+use tonic::{Code, Status};
+use tonic_types::StatusExt;
+
+/// ErrorInfo domain set by Cartridge gRPC services (see proto/README.md)
+pub const DOMAIN: &str = "cartridge";
+
+/// ErrorInfo metadata key saying whether the call can be retried unchanged
+pub const RETRYABLE_KEY: &str = "retryable";
+
+const BACKOFF_BASE: Duration = Duration::from_millis(500);
+const BACKOFF_MAX: Duration = Duration::from_secs(30);
+
+/// A failed gRPC call, read from the status' error details
+#[derive(Debug, Clone, PartialEq)]
+pub struct RpcError {
+    pub code: Code,
+    /// Stable error code such as NO_TRANSITIONS, None when the error did not
+    /// come from a Cartridge service
+    pub reason: Option<String>,
+    pub message: String,
+    pub retryable: bool,
+    /// How long the server asked clients to wait before retrying
+    pub retry_after: Option<Duration>,
+}
+
+impl RpcError {
+    pub fn from_status(status: &Status) -> Self {
+        let info = status
+            .get_details_error_info()
+            .filter(|info| info.domain == DOMAIN);
+        let retry_after = status
+            .get_details_retry_info()
+            .and_then(|info| info.retry_delay);
+
+        // Errors without details come from the transport or from other services,
+        // so fall back to the codes gRPC documents as transient
+        let retryable = match &info {
+            Some(info) => info.metadata.get(RETRYABLE_KEY).map_or(false, |v| v == "true"),
+            None => matches!(
+                status.code(),
+                Code::Unavailable | Code::ResourceExhausted | Code::Aborted | Code::DeadlineExceeded
+            ),
+        };
+
+        Self {
+            code: status.code(),
+            reason: info.map(|info| info.reason),
+            message: status.message().to_string(),
+            retryable,
+            retry_after,
+        }
+    }
+}
+
+impl fmt::Display for RpcError {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match &self.reason {
+            Some(reason) => write!(f, "{:?} ({}): {}", self.code, reason, self.message),
+            None => write!(f, "{:?}: {}", self.code, self.message),
+        }
+    }
+}
+
+/// Spaces out retries of a failing call, preferring the server's retry hint
+/// over exponential backoff
+#[derive(Debug, Clone, Default)]
+pub struct Backoff {
+    failures: u32,
+    until: Option<Instant>,
+}
+
+impl Backoff {
+    /// Time left before the next attempt, or None if it may go ahead now
+    pub fn remaining(&self, now: Instant) -> Option<Duration> {
+        self.until
+            .map(|until| until.saturating_duration_since(now))
+            .filter(|left| !left.is_zero())
+    }
+
+    /// Record a failed attempt and return how long to wait before the next one
+    pub fn failed(&mut self, retry_after: Option<Duration>, now: Instant) -> Duration {
+        let delay = retry_after
+            .unwrap_or_else(|| (BACKOFF_BASE * 2u32.pow(self.failures.min(6))).min(BACKOFF_MAX));
+        self.failures += 1;
+        self.until = Some(now + delay);
+        delay
+    }
+
+    pub fn succeeded(&mut self) {
+        *self = Self::default();
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::collections::HashMap;
+    use tonic_types::ErrorDetails;
+
+    fn cartridge_status(code: Code, reason: &str, retryable: bool, retry_after: Option<Duration>) -> Status {
+        let mut details = ErrorDetails::with_error_info(
+            reason,
+            DOMAIN,
+            HashMap::from([(RETRYABLE_KEY.to_string(), retryable.to_string())]),
+        );
+        details.set_retry_info(retry_after);
+        Status::with_error_details(code, "failed", details)
+    }
+
+    #[test]
+    fn reads_cartridge_error_details() {
+        let error = RpcError::from_status(&cartridge_status(
+            Code::FailedPrecondition,
+            "NO_TRANSITIONS",
+            true,
+            Some(Duration::from_secs(1)),
+        ));
+        assert_eq!(error.reason.as_deref(), Some("NO_TRANSITIONS"));
+        assert!(error.retryable);
+        assert_eq!(error.retry_after, Some(Duration::from_secs(1)));
+
+        // The retryable flag wins over the code
+        let error = RpcError::from_status(&cartridge_status(Code::Unavailable, "UNAVAILABLE", false, None));
+        assert!(!error.retryable);
+    }
+
+    #[test]
+    fn falls_back_to_status_code() {
+        assert!(RpcError::from_status(&Status::unavailable("connection refused")).retryable);
+        assert!(RpcError::from_status(&Status::deadline_exceeded("deadline")).retryable);
+        let error = RpcError::from_status(&Status::invalid_argument("bad"));
+        assert!(!error.retryable);
+        assert_eq!(error.reason, None);
+    }
+
+    #[test]
+    fn backoff_prefers_hint_and_resets() {
+        let now = Instant::now();
+        let mut backoff = Backoff::default();
+        assert_eq!(backoff.remaining(now), None);
+
+        assert_eq!(backoff.failed(None, now), Duration::from_millis(500));
+        assert_eq!(backoff.failed(None, now), Duration::from_secs(1));
+        assert_eq!(backoff.remaining(now), Some(Duration::from_secs(1)));
+        assert_eq!(backoff.failed(Some(Duration::from_secs(5)), now), Duration::from_secs(5));
+        for _ in 0..10 {
+            backoff.failed(None, now);
+        }
+        assert_eq!(backoff.failed(None, now), BACKOFF_MAX);
+        assert_eq!(backoff.remaining(now + BACKOFF_MAX), None);
+
+        backoff.succeeded();
+        assert_eq!(backoff.failed(None, now), Duration::from_millis(500));
+    }
+}
@@ -0,0 +1,110 @@
+use std::collections::HashMap;
+
+use anyhow::{anyhow, Result};
+use tonic::metadata::MetadataValue;
+use tonic::Request;
+
+/// gRPC metadata keys carrying the run context (see proto/README.md)
+pub const METADATA_RUN_ID: &str = "cartridge-run-id";
+pub const METADATA_EXPERIMENT_ID: &str = "cartridge-experiment-id";
+
+/// Transition metadata keys carrying the run context
+pub const RUN_ID_KEY: &str = "run_id";
+pub const EXPERIMENT_ID_KEY: &str = "experiment_id";
+
+/// The orchestrator run and experiment this actor collects experience for
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct RunContext {
+    run_id: Option<String>,
+    experiment_id: Option<String>,
+}
+
+impl RunContext {
+    pub fn new(run_id: Option<String>, experiment_id: Option<String>) -> Result<Self> {
+        let context = Self {
+            run_id: run_id.filter(|id| !id.is_empty()),
+            experiment_id: experiment_id.filter(|id| !id.is_empty()),
+        };
+        for (key, value) in context.metadata() {
+            MetadataValue::try_from(value)
+                .map_err(|_| anyhow!("Invalid {} '{}', must be printable ASCII", key, value))?;
+        }
+        Ok(context)
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.run_id.is_none() && self.experiment_id.is_none()
+    }
+
+    /// Add the run context to a transition's metadata, keeping IDs it already carries
+    pub fn stamp(&self, metadata: &mut HashMap<String, String>) {
+        if let Some(run_id) = &self.run_id {
+            metadata.entry(RUN_ID_KEY.to_string()).or_insert_with(|| run_id.clone());
+        }
+        if let Some(experiment_id) = &self.experiment_id {
+            metadata
+                .entry(EXPERIMENT_ID_KEY.to_string())
+                .or_insert_with(|| experiment_id.clone());
+        }
+    }
+
+    /// Send the run context as gRPC metadata on a request
+    pub fn apply<T>(&self, request: &mut Request<T>) {
+        for (key, value) in self.metadata() {
+            // Values were checked in new
+            if let Ok(value) = MetadataValue::try_from(value) {
+                request.metadata_mut().insert(key, value);
+            }
+        }
+    }
+
+    fn metadata(&self) -> Vec<(&'static str, &str)> {
+        let mut pairs = Vec::new();
+        if let Some(run_id) = &self.run_id {
+            pairs.push((METADATA_RUN_ID, run_id.as_str()));
+        }
+        if let Some(experiment_id) = &self.experiment_id {
+            pairs.push((METADATA_EXPERIMENT_ID, experiment_id.as_str()));
+        }
+        pairs
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn stamps_transitions_without_replacing_ids() {
+        let context = RunContext::new(Some("run-1".into()), Some("exp-1".into())).unwrap();
+
+        let mut metadata = HashMap::from([(RUN_ID_KEY.to_string(), "run-0".to_string())]);
+        context.stamp(&mut metadata);
+        assert_eq!(metadata[RUN_ID_KEY], "run-0");
+        assert_eq!(metadata[EXPERIMENT_ID_KEY], "exp-1");
+
+        let mut request = Request::new(());
+        context.apply(&mut request);
+        assert_eq!(request.metadata().get(METADATA_RUN_ID).unwrap(), "run-1");
+        assert_eq!(request.metadata().get(METADATA_EXPERIMENT_ID).unwrap(), "exp-1");
+    }
+
+    #[test]
+    fn empty_context_adds_nothing() {
+        let context = RunContext::new(Some(String::new()), None).unwrap();
+        assert!(context.is_empty());
+
+        let mut metadata = HashMap::new();
+        context.stamp(&mut metadata);
+        assert!(metadata.is_empty());
+
+        let mut request = Request::new(());
+        context.apply(&mut request);
+        assert!(request.metadata().is_empty());
+    }
+
+    #[test]
+    fn rejects_ids_that_cannot_be_sent_as_metadata() {
+        assert!(RunContext::new(Some("run\n1".into()), None).is_err());
+    }
+}
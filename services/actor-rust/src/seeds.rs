@@ -0,0 +1,172 @@
+use anyhow::{anyhow, Result};
+use rand::prelude::*;
+use rand_chacha::ChaCha20Rng;
+use std::time::{SystemTime, UNIX_EPOCH};
+
+use crate::config::Config;
+
+/// How seeds are drawn from a configured pool
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum SeedPoolMode {
+    /// Walk the pool in order, wrapping around at the end
+    Sequential,
+    /// Draw uniformly from the pool using an RNG derived from the actor id
+    Random,
+}
+
+impl SeedPoolMode {
+    pub fn parse(mode: &str) -> Result<Self> {
+        match mode {
+            "sequential" => Ok(SeedPoolMode::Sequential),
+            "random" => Ok(SeedPoolMode::Random),
+            other => Err(anyhow!("unknown seed pool mode: {}", other)),
+        }
+    }
+}
+
+/// Source of Reset seeds for episodes
+pub enum SeedSource {
+    /// Seed each episode from the wall clock (default behaviour)
+    Clock,
+    /// Draw seeds from a fixed pool so runs are comparable across actors
+    Pool {
+        seeds: Vec<u64>,
+        mode: SeedPoolMode,
+        cursor: usize,
+        rng: ChaCha20Rng,
+    },
+}
+
+impl SeedSource {
+    pub fn pool(seeds: Vec<u64>, mode: SeedPoolMode, actor_id: &str) -> Result<Self> {
+        if seeds.is_empty() {
+            return Err(anyhow!("seed pool must contain at least one seed"));
+        }
+        Ok(SeedSource::Pool {
+            seeds,
+            mode,
+            cursor: 0,
+            rng: ChaCha20Rng::seed_from_u64(fnv1a(actor_id.as_bytes())),
+        })
+    }
+
+    /// Build the seed source described by the configuration, fetching remote pools if needed
+    pub async fn from_config(config: &Config) -> Result<Self> {
+        let seeds = if !config.seed_pool.is_empty() {
+            config.seed_pool.clone()
+        } else if let Some(path) = &config.seed_pool_file {
+            let contents = tokio::fs::read_to_string(path)
+                .await
+                .map_err(|e| anyhow!("Failed to read seed pool file {}: {}", path, e))?;
+            parse_seed_lines(&contents)?
+        } else if let Some(url) = &config.seed_pool_url {
+            fetch_seed_pool(url).await?
+        } else {
+            return Ok(SeedSource::Clock);
+        };
+
+        let mode = SeedPoolMode::parse(&config.seed_pool_mode)?;
+        SeedSource::pool(seeds, mode, &config.actor_id)
+    }
+
+    pub fn len(&self) -> Option<usize> {
+        match self {
+            SeedSource::Clock => None,
+            SeedSource::Pool { seeds, .. } => Some(seeds.len()),
+        }
+    }
+
+    /// Return the seed for the next episode
+    pub fn next_seed(&mut self) -> Result<u64> {
+        match self {
+            SeedSource::Clock => Ok(SystemTime::now().duration_since(UNIX_EPOCH)?.as_nanos() as u64),
+            SeedSource::Pool {
+                seeds,
+                mode,
+                cursor,
+                rng,
+            } => {
+                let seed = match mode {
+                    SeedPoolMode::Sequential => {
+                        let seed = seeds[*cursor % seeds.len()];
+                        *cursor += 1;
+                        seed
+                    }
+                    SeedPoolMode::Random => seeds[rng.gen_range(0..seeds.len())],
+                };
+                Ok(seed)
+            }
+        }
+    }
+}
+
+fn parse_seed_lines(contents: &str) -> Result<Vec<u64>> {
+    contents
+        .lines()
+        .map(str::trim)
+        .filter(|line| !line.is_empty() && !line.starts_with('#'))
+        .map(|line| {
+            line.parse::<u64>()
+                .map_err(|e| anyhow!("invalid seed {:?}: {}", line, e))
+        })
+        .collect()
+}
+
+/// Fetch a seed list from a URL returning a JSON array of integers, e.g. an
+/// orchestrator-provided evaluation suite.
+async fn fetch_seed_pool(url: &str) -> Result<Vec<u64>> {
+    let response = reqwest::get(url)
+        .await
+        .map_err(|e| anyhow!("Failed to fetch seed pool from {}: {}", url, e))?
+        .error_for_status()
+        .map_err(|e| anyhow!("Seed pool request to {} failed: {}", url, e))?;
+    response
+        .json::<Vec<u64>>()
+        .await
+        .map_err(|e| anyhow!("Invalid seed pool payload from {}: {}", url, e))
+}
+
+fn fnv1a(bytes: &[u8]) -> u64 {
+    let mut hash: u64 = 0xcbf29ce484222325;
+    for byte in bytes {
+        hash ^= *byte as u64;
+        hash = hash.wrapping_mul(0x100000001b3);
+    }
+    hash
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn sequential_pool_cycles_in_order() {
+        let mut source = SeedSource::pool(vec![7, 8, 9], SeedPoolMode::Sequential, "actor-1").unwrap();
+        let drawn: Vec<u64> = (0..5).map(|_| source.next_seed().unwrap()).collect();
+        assert_eq!(drawn, vec![7, 8, 9, 7, 8]);
+    }
+
+    #[test]
+    fn random_pool_is_deterministic_per_actor() {
+        let seeds = vec![1, 2, 3, 4, 5, 6, 7, 8];
+        let mut a = SeedSource::pool(seeds.clone(), SeedPoolMode::Random, "actor-1").unwrap();
+        let mut b = SeedSource::pool(seeds.clone(), SeedPoolMode::Random, "actor-1").unwrap();
+        for _ in 0..16 {
+            let seed = a.next_seed().unwrap();
+            assert_eq!(seed, b.next_seed().unwrap());
+            assert!(seeds.contains(&seed));
+        }
+    }
+
+    #[test]
+    fn empty_pool_is_rejected() {
+        assert!(SeedSource::pool(vec![], SeedPoolMode::Sequential, "actor-1").is_err());
+    }
+
+    #[test]
+    fn seed_file_skips_comments_and_blank_lines() {
+        let seeds = parse_seed_lines("# eval suite\n1\n\n  42 \n").unwrap();
+        assert_eq!(seeds, vec![1, 42]);
+        assert!(parse_seed_lines("abc").is_err());
+    }
+}
@@ -0,0 +1,110 @@
+use std::time::{Duration, SystemTime};
+
+use crate::config::Config;
+use crate::policy::PolicyVersion;
+
+/// Pauses collection when the loaded policy drifts too far behind training
+#[derive(Debug, Clone, Default)]
+pub struct StalenessGuard {
+    max_age: Option<Duration>,
+    max_version_lag: Option<i64>,
+    latest_checkpoint: Option<i64>,
+}
+
+impl StalenessGuard {
+    pub fn new(max_age: Option<Duration>, max_version_lag: Option<i64>) -> Self {
+        Self {
+            max_age,
+            max_version_lag,
+            latest_checkpoint: None,
+        }
+    }
+
+    pub fn from_config(config: &Config) -> Self {
+        Self::new(
+            config.max_policy_age_secs.map(Duration::from_secs),
+            config.max_policy_version_lag,
+        )
+    }
+
+    pub fn is_enabled(&self) -> bool {
+        self.max_age.is_some() || self.max_version_lag.is_some()
+    }
+
+    /// Record the newest checkpoint version published for the run
+    pub fn observe_latest_checkpoint(&mut self, version: i64) {
+        self.latest_checkpoint = Some(self.latest_checkpoint.map_or(version, |v| v.max(version)));
+    }
+
+    /// Returns the reason collection should pause, or None if the policy is fresh enough
+    pub fn check(&self, policy: Option<PolicyVersion>, now: SystemTime) -> Option<String> {
+        let policy = policy?;
+
+        if let Some(max_age) = self.max_age {
+            let age = now.duration_since(policy.loaded_at).unwrap_or_default();
+            if age > max_age {
+                return Some(format!(
+                    "policy checkpoint {} loaded {}s ago exceeds max age of {}s",
+                    policy.checkpoint_version,
+                    age.as_secs(),
+                    max_age.as_secs()
+                ));
+            }
+        }
+
+        if let (Some(max_lag), Some(latest)) = (self.max_version_lag, self.latest_checkpoint) {
+            let lag = latest - policy.checkpoint_version;
+            if lag > max_lag {
+                return Some(format!(
+                    "policy checkpoint {} is {} versions behind latest checkpoint {} (max {})",
+                    policy.checkpoint_version, lag, latest, max_lag
+                ));
+            }
+        }
+
+        None
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn version(checkpoint_version: i64, loaded_at: SystemTime) -> Option<PolicyVersion> {
+        Some(PolicyVersion {
+            checkpoint_version,
+            loaded_at,
+        })
+    }
+
+    #[test]
+    fn unversioned_policies_are_never_stale() {
+        let mut guard = StalenessGuard::new(Some(Duration::from_secs(1)), Some(0));
+        guard.observe_latest_checkpoint(100);
+        assert!(guard.check(None, SystemTime::now()).is_none());
+    }
+
+    #[test]
+    fn pauses_when_policy_is_too_old() {
+        let guard = StalenessGuard::new(Some(Duration::from_secs(60)), None);
+        let now = SystemTime::now();
+        assert!(guard.check(version(3, now - Duration::from_secs(30)), now).is_none());
+        assert!(guard.check(version(3, now - Duration::from_secs(90)), now).is_some());
+    }
+
+    #[test]
+    fn pauses_when_policy_lags_latest_checkpoint() {
+        let mut guard = StalenessGuard::new(None, Some(2));
+        let now = SystemTime::now();
+        assert!(guard.check(version(3, now), now).is_none(), "unknown latest never pauses");
+
+        guard.observe_latest_checkpoint(5);
+        assert!(guard.check(version(3, now), now).is_none());
+
+        guard.observe_latest_checkpoint(6);
+        assert!(guard.check(version(3, now), now).is_some());
+
+        guard.observe_latest_checkpoint(4);
+        assert!(guard.check(version(3, now), now).is_some(), "latest never moves backwards");
+    }
+}
@@ -0,0 +1,111 @@
+//! Build information stamped by build.rs, served on GET /version.
+
+use anyhow::{anyhow, Result};
+use serde::Serialize;
+use tokio::io::{AsyncReadExt, AsyncWriteExt};
+use tokio::net::TcpListener;
+use tracing::{debug, info};
+
+pub const VERSION: &str = env!("ACTOR_VERSION");
+pub const COMMIT: &str = env!("ACTOR_GIT_COMMIT");
+pub const BUILD_TIME: &str = env!("ACTOR_BUILD_TIME");
+
+/// Version shown by --version, with the commit and build time
+pub const LONG_VERSION: &str = concat!(
+    env!("ACTOR_VERSION"),
+    " (commit ",
+    env!("ACTOR_GIT_COMMIT"),
+    ", built ",
+    env!("ACTOR_BUILD_TIME"),
+    ")"
+);
+
+/// Build the actor is running, in the shape the Go services report
+#[derive(Debug, Serialize)]
+pub struct BuildInfo {
+    pub service: &'static str,
+    pub version: &'static str,
+    #[serde(skip_serializing_if = "str::is_empty")]
+    pub commit: &'static str,
+    #[serde(skip_serializing_if = "str::is_empty")]
+    pub build_time: &'static str,
+}
+
+pub fn build_info() -> BuildInfo {
+    BuildInfo {
+        service: "actor",
+        version: VERSION,
+        commit: COMMIT,
+        build_time: BUILD_TIME,
+    }
+}
+
+/// Serves GET /version on addr until the task is dropped
+pub async fn serve(addr: &str) -> Result<()> {
+    let listener = TcpListener::bind(addr)
+        .await
+        .map_err(|e| anyhow!("Failed to bind status server to {}: {}", addr, e))?;
+    info!("Serving /version on {}", listener.local_addr()?);
+    loop {
+        let (mut stream, peer) = listener.accept().await?;
+        tokio::spawn(async move {
+            let mut request = [0u8; 1024];
+            let read = match stream.read(&mut request).await {
+                Ok(read) => read,
+                Err(e) => {
+                    debug!("Status request from {} failed: {}", peer, e);
+                    return;
+                }
+            };
+            let request_line = String::from_utf8_lossy(&request[..read]);
+            let response = respond(request_line.lines().next().unwrap_or_default());
+            if let Err(e) = stream.write_all(response.as_bytes()).await {
+                debug!("Status response to {} failed: {}", peer, e);
+            }
+        });
+    }
+}
+
+/// HTTP response to a request line such as "GET /version HTTP/1.1"
+fn respond(request_line: &str) -> String {
+    let mut parts = request_line.split_whitespace();
+    let (status, body) = match (parts.next(), parts.next()) {
+        (Some("GET"), Some("/version")) => (
+            "200 OK",
+            serde_json::to_string(&build_info()).unwrap_or_default(),
+        ),
+        (Some("GET"), _) => ("404 Not Found", r#"{"error":"not found"}"#.to_string()),
+        _ => (
+            "405 Method Not Allowed",
+            r#"{"error":"method not allowed"}"#.to_string(),
+        ),
+    };
+    format!(
+        "HTTP/1.1 {}\r\nContent-Type: application/json\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        status,
+        body.len(),
+        body
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn serves_build_info_on_version() {
+        let response = respond("GET /version HTTP/1.1");
+        assert!(response.starts_with("HTTP/1.1 200 OK\r\n"));
+        let body = response.split("\r\n\r\n").nth(1).unwrap();
+        let info: serde_json::Value = serde_json::from_str(body).unwrap();
+        assert_eq!(info["service"], "actor");
+        assert_eq!(info["version"], VERSION);
+    }
+
+    #[test]
+    fn rejects_other_requests() {
+        assert!(respond("GET /metrics HTTP/1.1").starts_with("HTTP/1.1 404"));
+        assert!(respond("POST /version HTTP/1.1").starts_with("HTTP/1.1 405"));
+        assert!(respond("").starts_with("HTTP/1.1 405"));
+    }
+}
@@ -0,0 +1,133 @@
+use anyhow::{anyhow, Result};
+use serde::Serialize;
+use std::time::Duration;
+use tracing::{debug, warn};
+
+/// Outcome of a single completed episode
+#[derive(Debug, Clone, Serialize)]
+pub struct EpisodeSummary {
+    pub episode_id: String,
+    pub seed: u64,
+    pub steps: u32,
+    pub total_reward: f32,
+    pub final_reward: f32,
+    pub duration_ms: u64,
+    pub completed_at: u64,
+}
+
+/// Payload posted to the webhook every N completed episodes
+#[derive(Debug, Serialize)]
+struct WebhookPayload<'a> {
+    actor_id: &'a str,
+    env_id: &'a str,
+    episode_count: usize,
+    mean_reward: f32,
+    episodes: &'a [EpisodeSummary],
+}
+
+/// Posts JSON episode summaries to a configured URL
+pub struct EpisodeWebhook {
+    http: reqwest::Client,
+    url: String,
+    every: usize,
+    actor_id: String,
+    env_id: String,
+    pending: Vec<EpisodeSummary>,
+}
+
+impl EpisodeWebhook {
+    pub fn new(url: &str, every: u32, actor_id: &str, env_id: &str) -> Result<Self> {
+        let http = reqwest::Client::builder()
+            .timeout(Duration::from_secs(10))
+            .build()
+            .map_err(|e| anyhow!("Failed to build webhook client: {}", e))?;
+        Ok(Self {
+            http,
+            url: url.to_string(),
+            every: every.max(1) as usize,
+            actor_id: actor_id.to_string(),
+            env_id: env_id.to_string(),
+            pending: Vec::new(),
+        })
+    }
+
+    /// Record a completed episode, returning the serialized batch once N episodes accumulated
+    pub fn record(&mut self, summary: EpisodeSummary) -> Option<Vec<u8>> {
+        self.pending.push(summary);
+        if self.pending.len() < self.every {
+            return None;
+        }
+        let episodes = std::mem::take(&mut self.pending);
+        self.encode(&episodes)
+    }
+
+    fn encode(&self, episodes: &[EpisodeSummary]) -> Option<Vec<u8>> {
+        let mean_reward =
+            episodes.iter().map(|e| e.total_reward).sum::<f32>() / episodes.len() as f32;
+        let payload = WebhookPayload {
+            actor_id: &self.actor_id,
+            env_id: &self.env_id,
+            episode_count: episodes.len(),
+            mean_reward,
+            episodes,
+        };
+        match serde_json::to_vec(&payload) {
+            Ok(body) => Some(body),
+            Err(e) => {
+                warn!("Failed to encode episode webhook payload: {}", e);
+                None
+            }
+        }
+    }
+
+    /// Deliver a payload in the background so slow endpoints never stall collection
+    pub fn send(&self, body: Vec<u8>) {
+        let http = self.http.clone();
+        let url = self.url.clone();
+        tokio::spawn(async move {
+            let result = http
+                .post(&url)
+                .header("Content-Type", "application/json")
+                .body(body)
+                .send()
+                .await
+                .and_then(|response| response.error_for_status());
+            match result {
+                Ok(_) => debug!("Delivered episode webhook to {}", url),
+                Err(e) => warn!("Episode webhook to {} failed: {}", url, e),
+            }
+        });
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn summary(id: &str, total_reward: f32) -> EpisodeSummary {
+        EpisodeSummary {
+            episode_id: id.to_string(),
+            seed: 1,
+            steps: 5,
+            total_reward,
+            final_reward: total_reward,
+            duration_ms: 10,
+            completed_at: 0,
+        }
+    }
+
+    #[test]
+    fn batches_every_n_episodes() {
+        let mut webhook = EpisodeWebhook::new("http://localhost/hook", 2, "actor-1", "tictactoe").unwrap();
+        assert!(webhook.record(summary("ep-1", 1.0)).is_none());
+
+        let body = webhook.record(summary("ep-2", 0.0)).expect("second episode should flush");
+        let payload: serde_json::Value = serde_json::from_slice(&body).unwrap();
+        assert_eq!(payload["actor_id"], "actor-1");
+        assert_eq!(payload["episode_count"], 2);
+        assert_eq!(payload["mean_reward"], 0.5);
+        assert_eq!(payload["episodes"][1]["episode_id"], "ep-2");
+
+        assert!(webhook.record(summary("ep-3", 1.0)).is_none(), "batch restarts after flush");
+    }
+}
@@ -10,14 +10,22 @@ from typing import Any, Iterable, Mapping, MutableMapping
 import yaml
 from pydantic import BaseModel, Field, ValidationError, field_validator, model_validator
 
+from .discovery import Target
+
 
 class ReplayConfig(BaseModel):
     """Configuration for talking to the replay buffer service."""
 
-    endpoint: str = Field(..., description="Target gRPC endpoint for the replay service")
+    endpoint: str = Field(
+        ...,
+        description="Target gRPC endpoint for the replay service (host:port, dns+srv:// or consul://)",
+    )
     tls_enabled: bool = Field(False, description="Whether to use TLS when connecting to replay")
     prefetch_depth: int = Field(4, ge=1, description="Number of batches to prefetch asynchronously")
     batch_size: int = Field(..., gt=0, description="Total transitions per sample request")
+    discovery_refresh_seconds: float = Field(
+        30.0, gt=0.0, description="How often to re-resolve a discovered endpoint"
+    )
 
 
 class AlgorithmConfig(BaseModel):
@@ -61,9 +69,17 @@ class WeightPublisherConfig(BaseModel):
 
 
 class ControlConfig(BaseModel):
-    orchestrator_endpoint: str = Field(..., description="HTTP endpoint for the orchestrator")
+    orchestrator_endpoint: str = Field(
+        ..., description="HTTP endpoint for the orchestrator (http://host:port, dns+srv:// or consul://)"
+    )
     run_id: str = Field(..., description="Unique identifier for the active run")
+    run_token: str | None = Field(
+        None, description="Token issued with the run; sent as X-Run-Token when set"
+    )
     heartbeat_interval_seconds: int = Field(30, ge=5)
+    discovery_refresh_seconds: float = Field(
+        30.0, gt=0.0, description="How often to re-resolve a discovered orchestrator endpoint"
+    )
 
 
 class LearnerConfig(BaseModel):
@@ -86,6 +102,12 @@ class LearnerConfig(BaseModel):
             )
         return algo
 
+    @model_validator(mode="after")
+    def _validate_endpoints(self) -> "LearnerConfig":
+        Target.parse(self.replay.endpoint)
+        Target.parse(self.control.orchestrator_endpoint)
+        return self
+
     @model_validator(mode="after")
     def _validate_training_constraints(self) -> "LearnerConfig":
         if self.algorithm.minibatch_size > self.training.rollout_size:
@@ -8,6 +8,7 @@ from dataclasses import dataclass
 import aiohttp
 
 from .config import ControlConfig
+from .discovery import Endpoints
 
 
 @dataclass(slots=True)
@@ -23,6 +24,11 @@ class ControlClient:
 
     def __init__(self, config: ControlConfig) -> None:
         self._config = config
+        self._endpoints = Endpoints(
+            config.orchestrator_endpoint,
+            refresh_seconds=config.discovery_refresh_seconds,
+            scheme="http",
+        )
         self._session: aiohttp.ClientSession | None = None
         self._lock = asyncio.Lock()
 
@@ -34,8 +40,11 @@ class ControlClient:
 
     async def send_heartbeat(self, payload: HeartbeatPayload) -> None:
         session = await self.ensure_session()
-        url = f"{self._config.orchestrator_endpoint}/runs/{self._config.run_id}/heartbeat"
-        async with session.post(url, json=payload.__dict__, timeout=10):
+        url = f"{await self._endpoints.pick()}/runs/{self._config.run_id}/heartbeat"
+        headers = {}
+        if self._config.run_token:
+            headers["X-Run-Token"] = self._config.run_token
+        async with session.post(url, json=payload.__dict__, headers=headers, timeout=10):
             pass
 
     async def close(self) -> None:
@@ -0,0 +1,162 @@
+"""Service discovery for the replay and orchestrator endpoints.
+
+Endpoints accept the same addresses as the actor:
+
+- ``host:port`` or ``http://host:port``: a single, static endpoint
+- ``dns+srv://<record>``: the targets of a DNS SRV record
+- ``consul://[agent]/<service>[?tag=<tag>]``: instances of a Consul service that pass
+  their health checks. The agent defaults to ``127.0.0.1:8500``.
+
+Discovered addresses are re-resolved periodically and requests rotate across every
+replica found.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import itertools
+import logging
+import time
+from dataclasses import dataclass
+from typing import Any
+from urllib.parse import parse_qsl
+
+import aiohttp
+import dns.asyncresolver
+
+DEFAULT_CONSUL_AGENT = "127.0.0.1:8500"
+
+_logger = logging.getLogger(__name__)
+
+
+@dataclass(frozen=True, slots=True)
+class Target:
+    """Where the replicas of a service are found."""
+
+    kind: str
+    name: str
+    agent: str | None = None
+    tag: str | None = None
+
+    @classmethod
+    def parse(cls, address: str) -> "Target":
+        if address.startswith("dns+srv://"):
+            record = address.removeprefix("dns+srv://").rstrip("/")
+            if not record or "/" in record:
+                raise ValueError(f"Invalid address '{address}', expected dns+srv://<record>")
+            return cls("srv", record)
+
+        if address.startswith("consul://"):
+            location, _, query = address.removeprefix("consul://").partition("?")
+            agent, _, service = location.partition("/") if "/" in location else ("", "", location)
+            service = service.rstrip("/")
+            if not service or "/" in service:
+                raise ValueError(f"Invalid address '{address}', expected consul://[agent]/<service>")
+            tag = None
+            for key, value in parse_qsl(query, keep_blank_values=True):
+                if key != "tag" or not value:
+                    raise ValueError(f"Invalid address '{address}', unsupported option '{key}'")
+                tag = value
+            return cls("consul", service, agent=agent or DEFAULT_CONSUL_AGENT, tag=tag)
+
+        if not address:
+            raise ValueError("Address cannot be empty")
+        return cls("static", address.rstrip("/"))
+
+    @property
+    def dynamic(self) -> bool:
+        return self.kind != "static"
+
+    async def resolve(self, session: aiohttp.ClientSession) -> list[tuple[str, int]]:
+        """Return the ``(host, port)`` of every replica currently behind the target."""
+
+        if self.kind == "srv":
+            answers = await dns.asyncresolver.resolve(self.name, "SRV")
+            return [(answer.target.to_text(omit_final_dot=True), answer.port) for answer in answers]
+
+        params = {"passing": "true"}
+        if self.tag:
+            params["tag"] = self.tag
+        url = f"http://{self.agent}/v1/health/service/{self.name}"
+        async with session.get(url, params=params, timeout=aiohttp.ClientTimeout(total=5)) as response:
+            response.raise_for_status()
+            return consul_instances(await response.json())
+
+
+def consul_instances(entries: list[dict[str, Any]]) -> list[tuple[str, int]]:
+    """Services registered without an address are reached at their node's."""
+
+    return [
+        (entry["Service"].get("Address") or entry["Node"]["Address"], entry["Service"]["Port"])
+        for entry in entries
+    ]
+
+
+def format_address(host: str, port: int, scheme: str | None) -> str:
+    if ":" in host:
+        host = f"[{host}]"
+    return f"{scheme}://{host}:{port}" if scheme else f"{host}:{port}"
+
+
+class Endpoints:
+    """Rotates requests across the replicas behind an address.
+
+    ``scheme`` prefixes discovered replicas, e.g. ``http`` for the orchestrator; gRPC
+    targets are left as ``host:port``. Static addresses are used exactly as written.
+    """
+
+    def __init__(self, address: str, *, refresh_seconds: float = 30.0, scheme: str | None = None) -> None:
+        self._target = Target.parse(address)
+        self._refresh_seconds = refresh_seconds
+        self._scheme = scheme
+        self._addresses: list[str] = [] if self._target.dynamic else [self._target.name]
+        self._resolved_at: float | None = None
+        self._cycle = itertools.cycle(self._addresses)
+        self._lock = asyncio.Lock()
+
+    @property
+    def addresses(self) -> list[str]:
+        return list(self._addresses)
+
+    async def pick(self) -> str:
+        """Return the replica to send the next request to, re-resolving when due."""
+
+        if self._target.dynamic and self._refresh_due():
+            async with self._lock:
+                if self._refresh_due():
+                    await self.refresh()
+        return next(self._cycle)
+
+    async def refresh(self) -> None:
+        """Re-resolve the target. On failure the replicas last found are kept."""
+
+        try:
+            async with aiohttp.ClientSession() as session:
+                instances = await self._target.resolve(session)
+            addresses = sorted({format_address(host, port, self._scheme) for host, port in instances})
+            if not addresses:
+                raise LookupError(f"no instances found for {self._target.name}")
+        except Exception as exc:
+            if not self._addresses:
+                raise
+            _logger.warning(
+                "Re-resolving %s failed, keeping %d known instances: %s",
+                self._target.name,
+                len(self._addresses),
+                exc,
+            )
+        else:
+            if addresses != self._addresses:
+                _logger.info("%s now resolves to %s", self._target.name, addresses)
+                self._set(addresses)
+        self._resolved_at = time.monotonic()
+
+    def _set(self, addresses: list[str]) -> None:
+        self._addresses = addresses
+        self._cycle = itertools.cycle(addresses)
+
+    def _refresh_due(self) -> bool:
+        return self._resolved_at is None or time.monotonic() - self._resolved_at >= self._refresh_seconds
+
+
+__all__ = ["DEFAULT_CONSUL_AGENT", "Endpoints", "Target", "consul_instances", "format_address"]
@@ -8,7 +8,7 @@ import logging
 from collections.abc import Awaitable, Callable
 
 import importlib
-from typing import TYPE_CHECKING
+from typing import TYPE_CHECKING, Any
 
 import grpc
 from tenacity import (
@@ -21,6 +21,7 @@ from tenacity import (
 
 from .config import ReplayConfig
 from .datamodel import TransitionBatch
+from .discovery import Endpoints
 from .metrics import MetricsRegistry
 from .replay import SampleResponseLike, sample_response_to_batch
 
@@ -48,8 +49,9 @@ class ReplayClient:
         self._prefetch_task: asyncio.Task[None] | None = None
         self._stopping = asyncio.Event()
         self._metrics = metrics
-        self._channel: grpc.aio.Channel | None = None
-        self._stub = None
+        self._endpoints = Endpoints(config.endpoint, refresh_seconds=config.discovery_refresh_seconds)
+        self._channels: dict[str, grpc.aio.Channel] = {}
+        self._stubs: dict[str, Any] = {}
         self._logger = logging.getLogger(__name__)
 
     async def __aenter__(self) -> "ReplayClient":
@@ -141,32 +143,39 @@ class ReplayClient:
         replay_pb2_grpc = importlib.import_module("learner.proto.replay.v1.replay_pb2_grpc")
         return replay_pb2, replay_pb2_grpc
 
-    async def _ensure_connection(self) -> None:
-        """Ensure gRPC channel and stub are initialized."""
-        if self._channel is None or self._stub is None:
+    async def _ensure_connection(self) -> tuple[str, Any]:
+        """Return the next replica and its stub, opening a channel to it if needed."""
+        address = await self._endpoints.pick()
+        for stale in set(self._channels) - set(self._endpoints.addresses):
+            await self._close_channel(stale)
+
+        if address not in self._stubs:
             replay_pb2, replay_pb2_grpc = self._load_replay_modules()
 
             if self._config.tls_enabled:
-                self._channel = grpc.aio.secure_channel(  # type: ignore[attr-defined]
-                    self._config.endpoint, grpc.ssl_channel_credentials()
+                channel = grpc.aio.secure_channel(  # type: ignore[attr-defined]
+                    address, grpc.ssl_channel_credentials()
                 )
             else:
-                self._channel = grpc.aio.insecure_channel(self._config.endpoint)  # type: ignore[attr-defined]
-
-            self._stub = replay_pb2_grpc.ReplayStub(self._channel)
-            self._logger.debug("gRPC connection established to %s", self._config.endpoint)
-
-    async def _close_channel(self) -> None:
-        """Close the gRPC channel if it exists."""
-        if self._channel is not None:
+                channel = grpc.aio.insecure_channel(address)  # type: ignore[attr-defined]
+
+            self._channels[address] = channel
+            self._stubs[address] = replay_pb2_grpc.ReplayStub(channel)
+            self._logger.debug("gRPC connection established to %s", address)
+        return address, self._stubs[address]
+
+    async def _close_channel(self, address: str | None = None) -> None:
+        """Close the gRPC channel to ``address``, or every channel if none is given."""
+        for target in [address] if address is not None else list(self._channels):
+            channel = self._channels.pop(target, None)
+            self._stubs.pop(target, None)
+            if channel is None:
+                continue
             try:
-                await self._channel.close()
-                self._logger.debug("gRPC channel closed")
+                await channel.close()
+                self._logger.debug("gRPC channel to %s closed", target)
             except Exception as e:
-                self._logger.warning("Error closing gRPC channel: %s", e)
-            finally:
-                self._channel = None
-                self._stub = None
+                self._logger.warning("Error closing gRPC channel to %s: %s", target, e)
 
     async def _grpc_sampler(self) -> SampleResponseLike:
         """Sample from replay buffer with retry logic."""
@@ -180,7 +189,7 @@ class ReplayClient:
             reraise=True,
         ):
             with attempt:
-                await self._ensure_connection()
+                address, stub = await self._ensure_connection()
 
                 request = replay_pb2.SampleRequest(
                     config=replay_pb2.SampleConfig(batch_size=self._config.batch_size)
@@ -190,7 +199,7 @@ class ReplayClient:
                     if self._metrics is not None:
                         self._metrics.samples_total.labels(status="attempt").inc()
 
-                    response = await self._stub.Sample(request)
+                    response = await stub.Sample(request)
 
                     if self._metrics is not None:
                         self._metrics.samples_total.labels(status="success").inc()
@@ -203,7 +212,7 @@ class ReplayClient:
                         self._metrics.samples_total.labels(status="error").inc()
 
                     # Close connection on RPC errors to force reconnection on retry
-                    await self._close_channel()
+                    await self._close_channel(address)
 
                     # Log different error types
                     if e.code() == grpc.StatusCode.UNAVAILABLE:
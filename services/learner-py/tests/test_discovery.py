@@ -0,0 +1,63 @@
+from __future__ import annotations
+
+import asyncio
+
+import pytest
+
+from learner.discovery import DEFAULT_CONSUL_AGENT, Endpoints, Target, consul_instances
+
+
+def test_parse_addresses() -> None:
+    assert Target.parse("localhost:8080") == Target("static", "localhost:8080")
+    assert Target.parse("dns+srv://_grpc._tcp.replay.svc") == Target("srv", "_grpc._tcp.replay.svc")
+    assert Target.parse("consul:///engine?tag=gpu") == Target(
+        "consul", "engine", agent=DEFAULT_CONSUL_AGENT, tag="gpu"
+    )
+    assert Target.parse("consul://consul:8500/replay").agent == "consul:8500"
+
+    for invalid in ["", "dns+srv://", "consul://agent/", "consul:///a/b", "consul:///engine?dc=east"]:
+        with pytest.raises(ValueError):
+            Target.parse(invalid)
+
+
+def test_consul_instances_prefer_service_address() -> None:
+    entries = [
+        {"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 8080}},
+        {"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "10.1.0.2", "Port": 8081}},
+    ]
+    assert consul_instances(entries) == [("10.0.0.1", 8080), ("10.1.0.2", 8081)]
+
+
+def test_endpoints_rotate_and_keep_last_replicas(monkeypatch: pytest.MonkeyPatch) -> None:
+    results: list[list[tuple[str, int]] | Exception] = [
+        [("replay-1", 8080), ("replay-0", 8080)],
+        RuntimeError("agent unavailable"),
+        [("replay-2", 8080)],
+    ]
+
+    async def resolve(self: Target, session: object) -> list[tuple[str, int]]:
+        result = results.pop(0)
+        if isinstance(result, Exception):
+            raise result
+        return result
+
+    monkeypatch.setattr(Target, "resolve", resolve)
+
+    async def scenario() -> None:
+        endpoints = Endpoints("consul:///replay", refresh_seconds=0.0, scheme="http")
+        picks = [await endpoints.pick(), await endpoints.pick()]
+        assert sorted(picks) == ["http://replay-0:8080", "http://replay-1:8080"]
+        assert endpoints.addresses == ["http://replay-0:8080", "http://replay-1:8080"]
+        assert await endpoints.pick() == "http://replay-2:8080"
+
+    asyncio.run(scenario())
+
+
+def test_static_endpoints_are_not_resolved(monkeypatch: pytest.MonkeyPatch) -> None:
+    async def resolve(self: Target, session: object) -> list[tuple[str, int]]:
+        raise AssertionError("static addresses should not be resolved")
+
+    monkeypatch.setattr(Target, "resolve", resolve)
+
+    endpoints = Endpoints("http://localhost:8000/")
+    assert asyncio.run(endpoints.pick()) == "http://localhost:8000"
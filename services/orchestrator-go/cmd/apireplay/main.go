@@ -0,0 +1,122 @@
+// Command apireplay replays traffic captured by the replay service and the
+// orchestrator (see telemetry/capture) against a new build, and reports per
+// endpoint how status codes and latency compare with what was recorded. It
+// exits non-zero when the build regresses, so it can gate a rollout.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/cartridge/telemetry/capture"
+)
+
+// errRegression is returned when the replayed build does worse than the capture.
+var errRegression = errors.New("regression detected")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// options are the command's flags.
+type options struct {
+	replayAddr      string
+	orchestratorURL string
+	apiKey          string
+	speed           float64
+	concurrency     int
+	timeout         time.Duration
+	maxLatencyRatio float64
+	maxMismatches   int
+	json            bool
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	var opts options
+	flags := flag.NewFlagSet("apireplay", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: apireplay [flags] capture-file...")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.replayAddr, "replay-addr", "", "replay service gRPC address to send captured gRPC calls to")
+	flags.StringVar(&opts.orchestratorURL, "orchestrator-url", "", "orchestrator base URL to send captured HTTP requests to")
+	flags.StringVar(&opts.apiKey, "api-key", os.Getenv("APIREPLAY_API_KEY"), "API key sent to the orchestrator as a bearer token (env APIREPLAY_API_KEY)")
+	flags.Float64Var(&opts.speed, "speed", 1, "replay rate relative to the capture; 0 sends every call as soon as a worker is free")
+	flags.IntVar(&opts.concurrency, "concurrency", 8, "maximum calls in flight")
+	flags.DurationVar(&opts.timeout, "timeout", 30*time.Second, "deadline for each call")
+	flags.Float64Var(&opts.maxLatencyRatio, "max-latency-ratio", 1.5, "fail when an endpoint's p95 latency exceeds the captured p95 by this factor; 0 disables")
+	flags.IntVar(&opts.maxMismatches, "max-mismatches", 0, "fail when more calls than this get a different status than was captured")
+	flags.BoolVar(&opts.json, "json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no capture files given")
+	}
+	if opts.speed < 0 {
+		return errors.New("-speed must not be negative")
+	}
+	if opts.concurrency < 1 {
+		return errors.New("-concurrency must be at least 1")
+	}
+
+	records, err := load(flags.Args())
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.New("the capture files hold no records")
+	}
+	target, err := newTarget(opts)
+	if err != nil {
+		return err
+	}
+	defer target.close()
+
+	results := replay(ctx, target, records, opts)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	report := newReport(results, opts)
+	if opts.json {
+		err = report.writeJSON(stdout)
+	} else {
+		err = report.writeTable(stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Regressed {
+		return errRegression
+	}
+	return nil
+}
+
+// load reads the capture files and merges their records in time order, so
+// captures from several services replay as one workload.
+func load(paths []string) ([]capture.Record, error) {
+	var records []capture.Record
+	for _, path := range paths {
+		recs, err := capture.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, recs...)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
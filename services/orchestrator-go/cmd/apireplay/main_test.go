@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	httpServer "github.com/cartridge/orchestrator/internal/http"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/replay/replaytest"
+	"github.com/cartridge/telemetry/capture"
+)
+
+func TestReplayCapturedTraffic(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(storage.NewMemoryStore(), events.NoopPublisher{}, logger)
+	if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: "run-a", ExperimentID: "exp-1", VersionID: "ver-1"}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	for _, state := range []types.RunState{types.RunStateProvisioning, types.RunStateRunning} {
+		if _, err := orch.TransitionRun(ctx, "run-a", service.TransitionInput{ToState: state, ChangedBy: "test"}); err != nil {
+			t.Fatalf("transition: %v", err)
+		}
+	}
+	replaySrv := replaytest.NewServer(100)
+	defer replaySrv.Close()
+
+	// Capture a heartbeat through the orchestrator as the server does.
+	path := filepath.Join(t.TempDir(), "orchestrator.jsonl")
+	recorder, err := capture.NewRecorder(path, 0)
+	if err != nil {
+		t.Fatalf("new recorder: %v", err)
+	}
+	isHeartbeat := func(r *http.Request) bool { return strings.HasSuffix(r.URL.Path, "/heartbeat") }
+	orchSrv := httptest.NewServer(recorder.HTTP(isHeartbeat)(httpServer.NewServer(orch, logger).Routes()))
+	defer orchSrv.Close()
+	body := `{"run_id":"run-a","status":"running","step":1}`
+	resp, err := http.Post(orchSrv.URL+"/api/v1/runs/run-a/heartbeat", "application/json", strings.NewReader(body))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("heartbeat: %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	// And a StoreBatch then Sample the replay service accepted.
+	now := time.Now()
+	batch := &replayv1.StoreBatchRequest{Transitions: []*replayv1.Transition{{
+		Id: "t1", EnvId: "tictactoe", EpisodeId: "ep1", State: []byte{1}, NextState: []byte{2}, Action: []byte{0},
+	}}}
+	sample := &replayv1.SampleRequest{Config: &replayv1.SampleConfig{BatchSize: 1}}
+	record(t, recorder, now, replayv1.Replay_StoreBatch_FullMethodName, batch, "OK")
+	record(t, recorder, now.Add(time.Millisecond), replayv1.Replay_Sample_FullMethodName, sample, "OK")
+	recorder.Close()
+
+	args := []string{"-replay-addr", replaySrv.Addr, "-orchestrator-url", orchSrv.URL, "-speed", "0", "-concurrency", "1", "-max-latency-ratio", "0"}
+	var out bytes.Buffer
+	if err := run(ctx, append(args, path), &out); err != nil {
+		t.Fatalf("replay: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{"POST /api/v1/runs/{id}/heartbeat", "/replay.v1.Replay/Sample", "PASS: 3 calls replayed, 0 with a different status"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in the report:\n%s", want, out.String())
+		}
+	}
+
+	// Against an empty buffer the captured Sample no longer succeeds.
+	empty := replaytest.NewServer(100)
+	defer empty.Close()
+	samplePath := filepath.Join(t.TempDir(), "replay.jsonl")
+	sampleRecorder, err := capture.NewRecorder(samplePath, 0)
+	if err != nil {
+		t.Fatalf("new recorder: %v", err)
+	}
+	record(t, sampleRecorder, now, replayv1.Replay_Sample_FullMethodName, sample, "OK")
+	sampleRecorder.Close()
+	out.Reset()
+	err = run(ctx, []string{"-replay-addr", empty.Addr, "-speed", "0", "-json", samplePath}, &out)
+	if !errors.Is(err, errRegression) {
+		t.Fatalf("expected a regression, got %v\n%s", err, out.String())
+	}
+	var got report
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if len(got.Endpoints) != 1 || got.Endpoints[0].StatusChanges["OK -> FailedPrecondition"] != 1 {
+		t.Fatalf("expected one OK -> FailedPrecondition change, got %+v", got)
+	}
+}
+
+func TestEndpointGroupsRoutes(t *testing.T) {
+	for path, want := range map[string]string{
+		"/api/v1/runs/run-a/heartbeat":       "POST /api/v1/runs/{id}/heartbeat",
+		"/api/v1/learners/l-1/heartbeat?x=1": "POST /api/v1/learners/{id}/heartbeat",
+		"/api/v1/heartbeats":                 "POST /api/v1/heartbeats",
+	} {
+		if got := endpoint(capture.Record{Protocol: capture.ProtocolHTTP, Method: http.MethodPost, Path: path}); got != want {
+			t.Errorf("endpoint(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func record(t *testing.T, recorder *capture.Recorder, at time.Time, method string, msg proto.Message, status string) {
+	t.Helper()
+	body, err := protojson.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	rec := capture.Record{Time: at, Protocol: capture.ProtocolGRPC, Method: method, Body: capture.Sanitize(body), Status: status, DurationMS: 1}
+	if err := recorder.Record(rec); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// Registers the replay service so its captured calls can be decoded.
+	_ "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/telemetry/capture"
+)
+
+// statusError is the status of a call that got no response at all, such as an
+// HTTP request that could not connect.
+const statusError = "ERROR"
+
+// result is the outcome of replaying one record.
+type result struct {
+	record   capture.Record
+	status   string
+	duration time.Duration
+	err      error
+}
+
+// target sends captured calls to the services under test.
+type target struct {
+	conn    *grpc.ClientConn
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func newTarget(opts options) (*target, error) {
+	t := &target{
+		baseURL: strings.TrimRight(opts.orchestratorURL, "/"),
+		apiKey:  opts.apiKey,
+		http:    &http.Client{},
+	}
+	if opts.replayAddr != "" {
+		conn, err := grpc.NewClient(opts.replayAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial replay service: %w", err)
+		}
+		t.conn = conn
+	}
+	return t, nil
+}
+
+func (t *target) close() {
+	if t.conn != nil {
+		t.conn.Close()
+	}
+}
+
+// send replays rec and returns the status it got, formatted as it was captured.
+func (t *target) send(ctx context.Context, rec capture.Record) (string, error) {
+	switch rec.Protocol {
+	case capture.ProtocolGRPC:
+		return t.sendGRPC(ctx, rec)
+	case capture.ProtocolHTTP:
+		return t.sendHTTP(ctx, rec)
+	}
+	return statusError, fmt.Errorf("unknown protocol %q", rec.Protocol)
+}
+
+func (t *target) sendGRPC(ctx context.Context, rec capture.Record) (string, error) {
+	if t.conn == nil {
+		return statusError, errors.New("gRPC record but no -replay-addr")
+	}
+	method, err := findMethod(rec.Method)
+	if err != nil {
+		return statusError, err
+	}
+	req := dynamicpb.NewMessage(method.Input())
+	if len(rec.Body) > 0 {
+		if err := protojson.Unmarshal(rec.Body, req); err != nil {
+			return statusError, fmt.Errorf("invalid %s request: %w", rec.Method, err)
+		}
+	}
+	resp := dynamicpb.NewMessage(method.Output())
+	err = t.conn.Invoke(ctx, rec.Method, req, resp)
+	return status.Code(err).String(), nil
+}
+
+func (t *target) sendHTTP(ctx context.Context, rec capture.Record) (string, error) {
+	if t.baseURL == "" {
+		return statusError, errors.New("HTTP record but no -orchestrator-url")
+	}
+	req, err := http.NewRequestWithContext(ctx, rec.Method, t.baseURL+rec.Path, bytes.NewReader(rec.Body))
+	if err != nil {
+		return statusError, err
+	}
+	if len(rec.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return statusError, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return strconv.Itoa(resp.StatusCode), nil
+}
+
+// findMethod resolves a full gRPC method name, such as
+// /replay.v1.Replay/Sample, among the registered services.
+func findMethod(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	service, name, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid gRPC method %q", fullMethod)
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("unknown gRPC service %q", service)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a gRPC service", service)
+	}
+	method := sd.Methods().ByName(protoreflect.Name(name))
+	if method == nil {
+		return nil, fmt.Errorf("unknown gRPC method %q", fullMethod)
+	}
+	return method, nil
+}
+
+// replay sends records with at most opts.concurrency in flight. With a
+// positive speed each is sent at its captured offset from the first, scaled by
+// speed; otherwise each is sent as soon as a worker is free. Results are in
+// the order of records; records not sent before ctx ends are left out.
+func replay(ctx context.Context, t *target, records []capture.Record, opts options) []result {
+	results := make([]result, len(records))
+	sent := make([]bool, len(records))
+	slots := make(chan struct{}, opts.concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	first := records[0].Time
+
+dispatch:
+	for i, rec := range records {
+		if opts.speed > 0 {
+			due := start.Add(time.Duration(float64(rec.Time.Sub(first)) / opts.speed))
+			timer := time.NewTimer(time.Until(due))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				break dispatch
+			}
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		sent[i] = true
+		wg.Add(1)
+		go func(i int, rec capture.Record) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			callCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+			defer cancel()
+			began := time.Now()
+			code, err := t.send(callCtx, rec)
+			results[i] = result{record: rec, status: code, duration: time.Since(began), err: err}
+		}(i, rec)
+	}
+	wg.Wait()
+
+	var done []result
+	for i, ok := range sent {
+		if ok {
+			done = append(done, results[i])
+		}
+	}
+	return done
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cartridge/telemetry/capture"
+)
+
+// latencyFloor is the smallest captured p95 latency is compared against, so
+// endpoints that answered in microseconds are not failed for scheduling noise.
+const latencyFloor = time.Millisecond
+
+// report compares the replay with the capture, endpoint by endpoint.
+type report struct {
+	Calls      int              `json:"calls"`
+	Mismatches int              `json:"mismatches"`
+	Regressed  bool             `json:"regressed"`
+	Endpoints  []endpointReport `json:"endpoints"`
+}
+
+// endpointReport covers the calls to one gRPC method or HTTP route.
+type endpointReport struct {
+	Endpoint string `json:"endpoint"`
+	Calls    int    `json:"calls"`
+	// Mismatches counts calls whose status differs from the captured one;
+	// StatusChanges breaks them down as "captured -> replayed".
+	Mismatches    int            `json:"mismatches"`
+	StatusChanges map[string]int `json:"status_changes,omitempty"`
+	// Error is the first failure to send a call, if any.
+	Error            string  `json:"error,omitempty"`
+	CapturedP50MS    float64 `json:"captured_p50_ms"`
+	CapturedP95MS    float64 `json:"captured_p95_ms"`
+	ReplayedP50MS    float64 `json:"replayed_p50_ms"`
+	ReplayedP95MS    float64 `json:"replayed_p95_ms"`
+	LatencyRegressed bool    `json:"latency_regressed"`
+}
+
+func newReport(results []result, opts options) report {
+	type samples struct {
+		endpoint endpointReport
+		captured []float64
+		replayed []float64
+	}
+	byEndpoint := make(map[string]*samples)
+	for _, res := range results {
+		name := endpoint(res.record)
+		s, ok := byEndpoint[name]
+		if !ok {
+			s = &samples{endpoint: endpointReport{Endpoint: name}}
+			byEndpoint[name] = s
+		}
+		s.endpoint.Calls++
+		if res.err != nil && s.endpoint.Error == "" {
+			s.endpoint.Error = res.err.Error()
+		}
+		if res.status != res.record.Status {
+			s.endpoint.Mismatches++
+			if s.endpoint.StatusChanges == nil {
+				s.endpoint.StatusChanges = make(map[string]int)
+			}
+			s.endpoint.StatusChanges[res.record.Status+" -> "+res.status]++
+		}
+		if res.err == nil {
+			s.captured = append(s.captured, res.record.DurationMS)
+			s.replayed = append(s.replayed, float64(res.duration)/float64(time.Millisecond))
+		}
+	}
+
+	r := report{Endpoints: make([]endpointReport, 0, len(byEndpoint))}
+	floor := float64(latencyFloor) / float64(time.Millisecond)
+	for _, s := range byEndpoint {
+		e := s.endpoint
+		e.CapturedP50MS, e.CapturedP95MS = percentile(s.captured, 0.5), percentile(s.captured, 0.95)
+		e.ReplayedP50MS, e.ReplayedP95MS = percentile(s.replayed, 0.5), percentile(s.replayed, 0.95)
+		if opts.maxLatencyRatio > 0 && len(s.replayed) > 0 {
+			e.LatencyRegressed = e.ReplayedP95MS > math.Max(e.CapturedP95MS, floor)*opts.maxLatencyRatio
+		}
+		r.Calls += e.Calls
+		r.Mismatches += e.Mismatches
+		r.Regressed = r.Regressed || e.LatencyRegressed
+		r.Endpoints = append(r.Endpoints, e)
+	}
+	sort.Slice(r.Endpoints, func(i, j int) bool { return r.Endpoints[i].Endpoint < r.Endpoints[j].Endpoint })
+	r.Regressed = r.Regressed || r.Mismatches > opts.maxMismatches
+	return r
+}
+
+// endpoint names the route a record was sent to. HTTP paths have their run and
+// learner IDs replaced with {id} so calls for different runs group together.
+func endpoint(rec capture.Record) string {
+	if rec.Protocol != capture.ProtocolHTTP {
+		return rec.Method
+	}
+	path := rec.Path
+	if u, err := url.Parse(rec.Path); err == nil {
+		path = u.Path
+	}
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i-1] == "runs" || segments[i-1] == "learners" {
+			segments[i] = "{id}"
+		}
+	}
+	return rec.Method + " " + strings.Join(segments, "/")
+}
+
+// percentile returns the nearest-rank q quantile of values, or 0 if there are none.
+func percentile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+func (r report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r report) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tCALLS\tMISMATCHES\tCAPTURED P50/P95\tREPLAYED P50/P95\tRESULT")
+	for _, e := range r.Endpoints {
+		verdict := "ok"
+		switch {
+		case e.LatencyRegressed:
+			verdict = "slower"
+		case e.Mismatches > 0:
+			verdict = "status changed"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f/%.1fms\t%.1f/%.1fms\t%s\n", e.Endpoint, e.Calls, e.Mismatches,
+			e.CapturedP50MS, e.CapturedP95MS, e.ReplayedP50MS, e.ReplayedP95MS, verdict)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, e := range r.Endpoints {
+		changes := make([]string, 0, len(e.StatusChanges))
+		for change, n := range e.StatusChanges {
+			changes = append(changes, fmt.Sprintf("%s (%d)", change, n))
+		}
+		sort.Strings(changes)
+		if len(changes) > 0 {
+			fmt.Fprintf(w, "%s: %s\n", e.Endpoint, strings.Join(changes, ", "))
+		}
+		if e.Error != "" {
+			fmt.Fprintf(w, "%s: %s\n", e.Endpoint, e.Error)
+		}
+	}
+	verdict := "PASS"
+	if r.Regressed {
+		verdict = "FAIL"
+	}
+	_, err := fmt.Fprintf(w, "%s: %d calls replayed, %d with a different status\n", verdict, r.Calls, r.Mismatches)
+	return err
+}
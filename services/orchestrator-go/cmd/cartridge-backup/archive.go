@@ -0,0 +1,187 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveFormat is bumped when the archive layout changes incompatibly.
+const archiveFormat = 1
+
+// manifestName is the archive's first entry.
+const manifestName = "manifest.json"
+
+// manifest describes what an archive holds.
+type manifest struct {
+	Format       int                   `json:"format"`
+	CreatedAt    time.Time             `json:"created_at"`
+	Orchestrator *orchestratorManifest `json:"orchestrator,omitempty"`
+	Replay       *replayManifest       `json:"replay,omitempty"`
+}
+
+// orchestratorManifest describes the orchestrator's database dump.
+type orchestratorManifest struct {
+	// SchemaVersion is the last migration applied to the database.
+	SchemaVersion int `json:"schema_version"`
+	// Tables are listed in the order they are restored: referenced tables
+	// before the tables referring to them.
+	Tables []tableManifest `json:"tables"`
+}
+
+type tableManifest struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// replayManifest describes the replay buffer export.
+type replayManifest struct {
+	// EnvID is the environment exported, empty for the whole buffer.
+	EnvID       string `json:"env_id,omitempty"`
+	Transitions int64  `json:"transitions"`
+}
+
+func tableEntry(table string) string { return "orchestrator/" + table + ".jsonl" }
+
+const transitionsEntry = "replay/transitions.jsonl"
+
+// archiveWriter spools entries to a temporary directory, as a tar header needs
+// each entry's size, until writeTo writes them out after the manifest.
+type archiveWriter struct {
+	dir     string
+	entries []string
+}
+
+func newArchiveWriter() (*archiveWriter, error) {
+	dir, err := os.MkdirTemp("", "cartridge-backup-")
+	if err != nil {
+		return nil, err
+	}
+	return &archiveWriter{dir: dir}, nil
+}
+
+// add writes an entry with the content fill produces.
+func (w *archiveWriter) add(name string, fill func(io.Writer) error) error {
+	path := filepath.Join(w.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := fill(file); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	w.entries = append(w.entries, name)
+	return nil
+}
+
+// writeTo writes the gzipped tar archive to out.
+func (w *archiveWriter) writeTo(out io.Writer, m manifest) error {
+	zw := gzip.NewWriter(out)
+	tw := tar.NewWriter(zw)
+	body, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(tw, manifestName, m.CreatedAt, int64(len(body)), bytes.NewReader(body)); err != nil {
+		return err
+	}
+	for _, name := range w.entries {
+		file, err := os.Open(filepath.Join(w.dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		if err == nil {
+			err = writeEntry(tw, name, m.CreatedAt, info.Size(), file)
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// close removes the spooled entries.
+func (w *archiveWriter) close() error {
+	return os.RemoveAll(w.dir)
+}
+
+func writeEntry(tw *tar.Writer, name string, modTime time.Time, size int64, content io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: size, ModTime: modTime}); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, content)
+	return err
+}
+
+// archiveReader reads an archive entry by entry, after its manifest.
+type archiveReader struct {
+	zr       *gzip.Reader
+	tr       *tar.Reader
+	manifest manifest
+}
+
+func openArchive(in io.Reader) (*archiveReader, error) {
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	r := &archiveReader{zr: zr, tr: tar.NewReader(zr)}
+	header, err := r.tr.Next()
+	if err != nil || header.Name != manifestName {
+		return nil, errors.New("not a backup archive: missing manifest")
+	}
+	if err := json.NewDecoder(r.tr).Decode(&r.manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if r.manifest.Format != archiveFormat {
+		return nil, fmt.Errorf("unsupported archive format %d, expected %d", r.manifest.Format, archiveFormat)
+	}
+	return r, nil
+}
+
+// next returns the next entry's name and content, or io.EOF after the last.
+func (r *archiveReader) next() (string, io.Reader, error) {
+	header, err := r.tr.Next()
+	if err != nil {
+		return "", nil, err
+	}
+	return header.Name, r.tr, nil
+}
+
+// expect returns the content of the next entry, which must be name.
+func (r *archiveReader) expect(name string) (io.Reader, error) {
+	got, content, err := r.next()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("archive ends before %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if got != name {
+		return nil, fmt.Errorf("expected %s in the archive, found %s", name, got)
+	}
+	return content, nil
+}
+
+func (r *archiveReader) close() error {
+	return r.zr.Close()
+}
@@ -0,0 +1,265 @@
+// Command cartridge-backup snapshots the orchestrator's PostgreSQL state and
+// the replay buffer into one archive, and restores both from it, for disaster
+// recovery and for cloning an environment.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/cartridge/orchestrator/internal/config"
+	replayv1 "github.com/cartridge/proto/replay/v1"
+)
+
+// maxReplayMessage bounds the Export responses the tool accepts.
+const maxReplayMessage = 64 << 20
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// options are the global flags shared by every subcommand.
+type options struct {
+	databaseURL      string
+	replayAddr       string
+	skipOrchestrator bool
+	skipReplay       bool
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:           "cartridge-backup",
+		Short:         "Back up and restore the orchestrator database and the replay buffer together",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.databaseURL, "database-url", "", "orchestrator PostgreSQL URL or DSN (default: the orchestrator's DB_* settings)")
+	flags.StringVar(&opts.replayAddr, "replay-addr", os.Getenv("REPLAY_ADDR"), "replay service gRPC address (env REPLAY_ADDR)")
+	flags.BoolVar(&opts.skipOrchestrator, "skip-orchestrator", false, "leave out the orchestrator database")
+	flags.BoolVar(&opts.skipReplay, "skip-replay", false, "leave out the replay buffer")
+
+	root.AddCommand(
+		newCreateCommand(opts),
+		newRestoreCommand(opts),
+		newInspectCommand(),
+	)
+	return root
+}
+
+func newCreateCommand(opts *options) *cobra.Command {
+	var output, envID string
+	cmd := &cobra.Command{
+		Use:   "create --output FILE",
+		Short: "Write a backup archive of the orchestrator database and the replay buffer",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.skipOrchestrator && opts.skipReplay {
+				return errors.New("nothing to back up with both --skip-orchestrator and --skip-replay")
+			}
+			ctx := cmd.Context()
+			archive, err := newArchiveWriter()
+			if err != nil {
+				return err
+			}
+			defer archive.close()
+
+			m := manifest{Format: archiveFormat, CreatedAt: time.Now().UTC()}
+			if !opts.skipOrchestrator {
+				db, err := opts.openDatabase()
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				if m.Orchestrator, err = dumpPostgres(ctx, db, archive); err != nil {
+					return fmt.Errorf("orchestrator database: %w", err)
+				}
+			}
+			if !opts.skipReplay {
+				client, closeReplay, err := opts.dialReplay()
+				if err != nil {
+					return err
+				}
+				defer closeReplay()
+				if m.Replay, err = dumpReplay(ctx, client, envID, archive); err != nil {
+					return fmt.Errorf("replay buffer: %w", err)
+				}
+			}
+			if err := writeArchive(output, archive, m); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "wrote %s: %s\n", output, summary(m))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "archive to write (required)")
+	cmd.Flags().StringVar(&envID, "env", "", "back up only this environment's transitions")
+	cmd.MarkFlagRequired("output")
+	return cmd
+}
+
+func newRestoreCommand(opts *options) *cobra.Command {
+	var replace bool
+	var batchSize int
+	cmd := &cobra.Command{
+		Use:   "restore FILE",
+		Short: "Restore the orchestrator database and the replay buffer from a backup archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if batchSize < 1 {
+				return errors.New("--batch-size must be at least 1")
+			}
+			ctx := cmd.Context()
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			archive, err := openArchive(file)
+			if err != nil {
+				return err
+			}
+			defer archive.close()
+			m := archive.manifest
+
+			if m.Orchestrator != nil {
+				if opts.skipOrchestrator {
+					// Entries are read in order, so pass over the tables.
+					for _, table := range m.Orchestrator.Tables {
+						if _, err := archive.expect(tableEntry(table.Name)); err != nil {
+							return err
+						}
+					}
+				} else {
+					db, err := opts.openDatabase()
+					if err != nil {
+						return err
+					}
+					defer db.Close()
+					if err := restorePostgres(ctx, db, archive, replace); err != nil {
+						return fmt.Errorf("orchestrator database: %w", err)
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "restored %d tables at schema version %d\n", len(m.Orchestrator.Tables), m.Orchestrator.SchemaVersion)
+				}
+			}
+			if m.Replay != nil && !opts.skipReplay {
+				client, closeReplay, err := opts.dialReplay()
+				if err != nil {
+					return err
+				}
+				defer closeReplay()
+				stored, err := restoreReplay(ctx, client, archive, batchSize, replace)
+				if err != nil {
+					return fmt.Errorf("replay buffer: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "restored %d of %d transitions\n", stored, m.Replay.Transitions)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&replace, "replace", false, "overwrite existing orchestrator tables and clear the replay buffer first")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 256, "transitions per StoreBatch call")
+	return cmd
+}
+
+func newInspectCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect FILE",
+		Short: "Print a backup archive's manifest",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			archive, err := openArchive(file)
+			if err != nil {
+				return err
+			}
+			defer archive.close()
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(archive.manifest)
+		},
+	}
+}
+
+// openDatabase connects to the orchestrator's database, configured as the
+// orchestrator is unless --database-url is given.
+func (o *options) openDatabase() (*sql.DB, error) {
+	dsn := o.databaseURL
+	if dsn == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the orchestrator's database settings: %w", err)
+		}
+		dsn = cfg.Database.ConnectionString()
+	}
+	return sql.Open("postgres", dsn)
+}
+
+func (o *options) dialReplay() (replayv1.ReplayClient, func() error, error) {
+	if o.replayAddr == "" {
+		return nil, nil, errors.New("no replay service address; set --replay-addr or pass --skip-replay")
+	}
+	conn, err := grpc.NewClient(o.replayAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxReplayMessage)),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to dial replay service: %w", err)
+	}
+	return replayv1.NewReplayClient(conn), conn.Close, nil
+}
+
+// writeArchive writes the archive next to path and renames it into place, so
+// a failed backup never leaves a partial archive under the requested name.
+func writeArchive(path string, archive *archiveWriter, m manifest) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := archive.writeTo(tmp, m); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func summary(m manifest) string {
+	var tables, rows int64
+	if m.Orchestrator != nil {
+		tables = int64(len(m.Orchestrator.Tables))
+		for _, table := range m.Orchestrator.Tables {
+			rows += table.Rows
+		}
+	}
+	var transitions int64
+	if m.Replay != nil {
+		transitions = m.Replay.Transitions
+	}
+	return fmt.Sprintf("%d rows in %d tables, %d transitions", rows, tables, transitions)
+}
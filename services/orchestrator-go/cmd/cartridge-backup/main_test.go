@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/replay/replaytest"
+)
+
+func TestBackupAndRestoreReplayBuffer(t *testing.T) {
+	ctx := context.Background()
+	source := replaytest.NewServer(100)
+	defer source.Close()
+	transitions := []*replayv1.Transition{
+		{Id: "t1", EnvId: "tictactoe", EpisodeId: "ep1", State: []byte{0}, Action: []byte{4}, Priority: 2, Timestamp: 1700000000, Metadata: map[string]string{"run_id": "run-a"}},
+		{Id: "t2", EnvId: "tictactoe", EpisodeId: "ep1", State: []byte{1}, Action: []byte{0}, Reward: 1, Done: true, Priority: 1, Timestamp: 1700000001},
+		{Id: "t3", EnvId: "gridworld", EpisodeId: "ep2", State: []byte{2}, Action: []byte{1}, Priority: 1, Timestamp: 1700000002},
+	}
+	if _, err := client(t, source.Addr).StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: transitions}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		root := newRootCommand()
+		var out bytes.Buffer
+		root.SetOut(&out)
+		root.SetArgs(append([]string{"--skip-orchestrator"}, args...))
+		err := root.ExecuteContext(ctx)
+		return out.String(), err
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	out, err := run("create", "--replay-addr", source.Addr, "-o", path)
+	if err != nil || !strings.Contains(out, "3 transitions") {
+		t.Fatalf("create: %v\n%s", err, out)
+	}
+	out, err = run("inspect", path)
+	if err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal([]byte(out), &m); err != nil {
+		t.Fatalf("decode manifest: %v\n%s", err, out)
+	}
+	if m.Orchestrator != nil || m.Replay == nil || m.Replay.Transitions != 3 {
+		t.Fatalf("unexpected manifest: %s", out)
+	}
+
+	target := replaytest.NewServer(100)
+	defer target.Close()
+	if out, err = run("restore", "--replay-addr", target.Addr, "--batch-size", "2", path); err != nil || !strings.Contains(out, "restored 3 of 3 transitions") {
+		t.Fatalf("restore: %v\n%s", err, out)
+	}
+	if got := export(t, target.Addr); !reflect.DeepEqual(ids(got), []string{"t1", "t2", "t3"}) || got[0].Priority != 2 || got[0].Metadata["run_id"] != "run-a" || !got[1].Done {
+		t.Fatalf("restored buffer differs: %v", got)
+	}
+
+	// A buffer with transitions is only overwritten on request.
+	if _, err = run("restore", "--replay-addr", target.Addr, path); err == nil || !strings.Contains(err.Error(), "--replace") {
+		t.Fatalf("expected restore into a full buffer to fail, got %v", err)
+	}
+	if _, err = run("restore", "--replay-addr", target.Addr, "--replace", path); err != nil {
+		t.Fatalf("restore --replace: %v", err)
+	}
+	if got := export(t, target.Addr); len(got) != 3 {
+		t.Fatalf("expected 3 transitions after --replace, got %d", len(got))
+	}
+}
+
+func TestRestoreOrder(t *testing.T) {
+	order, err := restoreOrder(
+		[]string{"runs", "run_commands", "evaluations", "run_checkpoints", "schedules", "learners"},
+		map[string][]string{
+			"runs":            {"schedules", "runs"},
+			"run_commands":    {"runs"},
+			"run_checkpoints": {"runs"},
+			"evaluations":     {"run_checkpoints", "runs"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("restore order: %v", err)
+	}
+	want := []string{"learners", "schedules", "runs", "run_checkpoints", "evaluations", "run_commands"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("restore order = %v, want %v", order, want)
+	}
+
+	if _, err := restoreOrder([]string{"a", "b"}, map[string][]string{"a": {"b"}, "b": {"a"}}); err == nil {
+		t.Fatalf("expected a cycle to be reported")
+	}
+}
+
+func client(t *testing.T, addr string) replayv1.ReplayClient {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return replayv1.NewReplayClient(conn)
+}
+
+func export(t *testing.T, addr string) []*replayv1.Transition {
+	t.Helper()
+	stream, err := client(t, addr).Export(context.Background(), &replayv1.ExportRequest{})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	var transitions []*replayv1.Transition
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return transitions
+		}
+		transitions = append(transitions, resp.Transitions...)
+	}
+}
+
+func ids(transitions []*replayv1.Transition) []string {
+	var out []string
+	for _, transition := range transitions {
+		out = append(out, transition.Id)
+	}
+	return out
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/cartridge/orchestrator/internal/migrations"
+)
+
+// restoreBatch is how many rows each INSERT restores.
+const restoreBatch = 500
+
+// querier is satisfied by *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// dumpPostgres writes every orchestrator table to the archive, one JSON row per
+// line, from a single snapshot so rows across tables are consistent.
+func dumpPostgres(ctx context.Context, db *sql.DB, archive *archiveWriter) (*orchestratorManifest, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m := &orchestratorManifest{}
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&m.SchemaVersion); err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	tables, err := listTables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		var rows int64
+		err := archive.add(tableEntry(table), func(w io.Writer) error {
+			var err error
+			rows, err = dumpTable(ctx, tx, table, w)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		m.Tables = append(m.Tables, tableManifest{Name: table, Rows: rows})
+	}
+	return m, nil
+}
+
+func dumpTable(ctx context.Context, q querier, table string, w io.Writer) (int64, error) {
+	rows, err := q.QueryContext(ctx, `SELECT row_to_json(t)::text FROM `+pq.QuoteIdentifier(table)+` t`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	bw := bufio.NewWriter(w)
+	var count int64
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return count, err
+		}
+		bw.WriteString(row)
+		bw.WriteByte('\n')
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	return count, bw.Flush()
+}
+
+// restorePostgres loads the archived tables into db in one transaction. The
+// schema is migrated to the archive's version first and to the latest after,
+// so an archive from an older release restores into a newer one. Unless
+// replace is set, the tables must be empty.
+func restorePostgres(ctx context.Context, db *sql.DB, archive *archiveReader, replace bool) error {
+	m := archive.manifest.Orchestrator
+	if _, err := migrations.UpTo(ctx, db, m.SchemaVersion); err != nil {
+		return err
+	}
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current != m.SchemaVersion {
+		return fmt.Errorf("database schema is at version %d, newer than the archive's %d; restore into an empty database", current, m.SchemaVersion)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	tables, err := listTables(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if replace {
+		quoted := make([]string, len(tables))
+		for i, table := range tables {
+			quoted[i] = pq.QuoteIdentifier(table)
+		}
+		if _, err := tx.ExecContext(ctx, `TRUNCATE `+strings.Join(quoted, ", ")+` RESTART IDENTITY CASCADE`); err != nil {
+			return fmt.Errorf("failed to clear tables: %w", err)
+		}
+	} else {
+		for _, table := range tables {
+			var nonEmpty bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+pq.QuoteIdentifier(table)+`)`).Scan(&nonEmpty); err != nil {
+				return err
+			}
+			if nonEmpty {
+				return fmt.Errorf("table %s is not empty; pass --replace to overwrite the database", table)
+			}
+		}
+	}
+	for _, table := range m.Tables {
+		content, err := archive.expect(tableEntry(table.Name))
+		if err != nil {
+			return err
+		}
+		if err := restoreTable(ctx, tx, table.Name, content); err != nil {
+			return fmt.Errorf("table %s: %w", table.Name, err)
+		}
+	}
+	if err := resetSequences(ctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	_, err = migrations.Up(ctx, db)
+	return err
+}
+
+// restoreTable inserts the rows in content, restoreBatch at a time.
+func restoreTable(ctx context.Context, tx *sql.Tx, table string, content io.Reader) error {
+	insert := `INSERT INTO ` + pq.QuoteIdentifier(table) + ` OVERRIDING SYSTEM VALUE
+		SELECT * FROM json_populate_recordset(NULL::` + pq.QuoteIdentifier(table) + `, $1)`
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	batch := make([]json.RawMessage, 0, restoreBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		rows, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		batch = batch[:0]
+		_, err = tx.ExecContext(ctx, insert, string(rows))
+		return err
+	}
+	for scanner.Scan() {
+		batch = append(batch, json.RawMessage(append([]byte(nil), scanner.Bytes()...)))
+		if len(batch) == restoreBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// resetSequences moves each serial column's sequence past the restored rows.
+func resetSequences(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND column_default LIKE 'nextval(%'`)
+	if err != nil {
+		return err
+	}
+	type column struct{ table, name string }
+	var serials []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.table, &c.name); err != nil {
+			rows.Close()
+			return err
+		}
+		serials = append(serials, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, c := range serials {
+		query := `SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(` + pq.QuoteIdentifier(c.name) + `), 0) + 1, false)
+			FROM ` + pq.QuoteIdentifier(c.table)
+		if _, err := tx.ExecContext(ctx, query, pq.QuoteIdentifier(c.table), c.name); err != nil {
+			return fmt.Errorf("failed to reset %s.%s sequence: %w", c.table, c.name, err)
+		}
+	}
+	return nil
+}
+
+// listTables returns the orchestrator's tables in restore order.
+func listTables(ctx context.Context, q querier) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT c.relname FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p') AND c.relname <> 'schema_migrations'`)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(ctx, `
+		SELECT c.relname, r.relname FROM pg_constraint k
+		JOIN pg_class c ON c.oid = k.conrelid
+		JOIN pg_class r ON r.oid = k.confrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE k.contype = 'f' AND n.nspname = current_schema()`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	references := make(map[string][]string)
+	for rows.Next() {
+		var table, referenced string
+		if err := rows.Scan(&table, &referenced); err != nil {
+			return nil, err
+		}
+		references[table] = append(references[table], referenced)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return restoreOrder(tables, references)
+}
+
+// restoreOrder sorts tables so each comes after the tables it references,
+// alphabetically where the foreign keys allow either order. A table's
+// references to itself are ignored.
+func restoreOrder(tables []string, references map[string][]string) ([]string, error) {
+	pending := append([]string(nil), tables...)
+	sort.Strings(pending)
+	placed := make(map[string]bool, len(tables))
+	known := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		known[table] = true
+	}
+	ordered := make([]string, 0, len(tables))
+	for len(pending) > 0 {
+		progressed := false
+		for i := 0; i < len(pending); i++ {
+			table := pending[i]
+			ready := true
+			for _, referenced := range references[table] {
+				if referenced != table && known[referenced] && !placed[referenced] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+			ordered = append(ordered, table)
+			placed[table] = true
+			pending = append(pending[:i], pending[i+1:]...)
+			progressed = true
+			break
+		}
+		if !progressed {
+			return nil, fmt.Errorf("foreign keys between %s form a cycle", strings.Join(pending, ", "))
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+)
+
+// clearAllBefore is a Unix time after every stored transition. Clear removes
+// nothing without a criterion, so --replace clears what was stored before it.
+const clearAllBefore = 1 << 40
+
+// dumpReplay writes the replay buffer's transitions for envID, or all of them,
+// to the archive, one protojson transition per line.
+func dumpReplay(ctx context.Context, client replayv1.ReplayClient, envID string, archive *archiveWriter) (*replayManifest, error) {
+	m := &replayManifest{EnvID: envID}
+	err := archive.add(transitionsEntry, func(w io.Writer) error {
+		stream, err := client.Export(ctx, &replayv1.ExportRequest{EnvId: envID})
+		if err != nil {
+			return err
+		}
+		bw := bufio.NewWriter(w)
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return bw.Flush()
+			}
+			if err != nil {
+				return fmt.Errorf("replay Export: %w", err)
+			}
+			for _, transition := range resp.Transitions {
+				line, err := protojson.Marshal(transition)
+				if err != nil {
+					return err
+				}
+				bw.Write(line)
+				bw.WriteByte('\n')
+				m.Transitions++
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// restoreReplay stores the archived transitions batchSize at a time. Unless
+// replace is set, the buffer must be empty; with it, the buffer is cleared
+// first.
+func restoreReplay(ctx context.Context, client replayv1.ReplayClient, archive *archiveReader, batchSize int, replace bool) (int64, error) {
+	if replace {
+		if _, err := client.Clear(ctx, &replayv1.ClearRequest{BeforeTimestamp: clearAllBefore}); err != nil {
+			return 0, fmt.Errorf("replay Clear: %w", err)
+		}
+	} else {
+		stats, err := client.GetStats(ctx, &replayv1.GetStatsRequest{})
+		if err != nil {
+			return 0, fmt.Errorf("replay GetStats: %w", err)
+		}
+		if stats.GetTotalTransitions() > 0 {
+			return 0, fmt.Errorf("replay buffer holds %d transitions; pass --replace to clear it", stats.GetTotalTransitions())
+		}
+	}
+	content, err := archive.expect(transitionsEntry)
+	if err != nil {
+		return 0, err
+	}
+
+	var stored int64
+	batch := make([]*replayv1.Transition, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		resp, err := client.StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: batch})
+		if err != nil {
+			return fmt.Errorf("replay StoreBatch: %w", err)
+		}
+		stored += int64(resp.GetStoredCount())
+		if resp.GetFailedCount() > 0 {
+			return fmt.Errorf("replay StoreBatch: %d transitions failed: %v", resp.GetFailedCount(), resp.GetErrorMessages())
+		}
+		batch = batch[:0]
+		return nil
+	}
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	for scanner.Scan() {
+		transition := &replayv1.Transition{}
+		if err := protojson.Unmarshal(scanner.Bytes(), transition); err != nil {
+			return stored, fmt.Errorf("invalid transition in archive: %w", err)
+		}
+		batch = append(batch, transition)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return stored, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stored, err
+	}
+	return stored, flush()
+}
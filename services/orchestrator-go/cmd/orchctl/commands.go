@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// commandFlags are shared by every command-issuing subcommand.
+type commandFlags struct {
+	actor string
+	ttl   time.Duration
+}
+
+func (f *commandFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.actor, "actor", os.Getenv("USER"), "operator recorded as the command's actor (ignored when authenticating with an API key)")
+	cmd.Flags().DurationVar(&f.ttl, "ttl", 0, "drop the command if it is not delivered within this duration")
+}
+
+func newTuneCommand(opts *options) *cobra.Command {
+	flags := &commandFlags{}
+	var (
+		payload                        types.TunePayload
+		learningRate, entropy, epsilon float64
+	)
+	cmd := &cobra.Command{
+		Use:   "tune RUN",
+		Short: "Adjust a running learner's hyperparameters",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("learning-rate") {
+				payload.LearningRate = &learningRate
+			}
+			if cmd.Flags().Changed("entropy-coef") {
+				payload.EntropyCoef = &entropy
+			}
+			if cmd.Flags().Changed("clip-epsilon") {
+				payload.ClipEpsilon = &epsilon
+			}
+			return issueCommand(cmd, opts, flags, args[0], types.CommandTypeTune, payload)
+		},
+	}
+	cmd.Flags().Float64Var(&learningRate, "learning-rate", 0, "new learning rate, in (0,1]")
+	cmd.Flags().Float64Var(&entropy, "entropy-coef", 0, "new entropy coefficient, in [0,0.1]")
+	cmd.Flags().Float64Var(&epsilon, "clip-epsilon", 0, "new PPO clip epsilon, in [0.05,0.3]")
+	cmd.Flags().StringVar(&payload.Notes, "notes", "", "free-form note recorded with the command")
+	flags.register(cmd)
+	return cmd
+}
+
+func newPauseCommand(opts *options) *cobra.Command {
+	return newEmptyCommand(opts, "pause", "Ask a learner to pause", types.CommandTypePause)
+}
+
+func newResumeCommand(opts *options) *cobra.Command {
+	return newEmptyCommand(opts, "resume", "Ask a paused learner to resume", types.CommandTypeResume)
+}
+
+func newEmptyCommand(opts *options, use, short string, commandType types.CommandType) *cobra.Command {
+	flags := &commandFlags{}
+	cmd := &cobra.Command{
+		Use:   use + " RUN",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return issueCommand(cmd, opts, flags, args[0], commandType, nil)
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func newTerminateCommand(opts *options) *cobra.Command {
+	flags := &commandFlags{}
+	var payload types.TerminatePayload
+	cmd := &cobra.Command{
+		Use:   "terminate RUN",
+		Short: "Ask a learner to stop for good",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return issueCommand(cmd, opts, flags, args[0], types.CommandTypeTerminate, payload)
+		},
+	}
+	cmd.Flags().StringVar(&payload.Reason, "reason", "", "why the run is being terminated (required)")
+	cmd.Flags().BoolVar(&payload.FinalCheckpoint, "final-checkpoint", false, "write a checkpoint before exiting")
+	flags.register(cmd)
+	return cmd
+}
+
+// issueCommand validates the command locally with the same rules the server
+// applies, so typos fail before anything is queued, then submits it.
+func issueCommand(cmd *cobra.Command, opts *options, flags *commandFlags, runID string, commandType types.CommandType, payload any) error {
+	command := types.RunCommand{
+		RunID:    runID,
+		Type:     commandType,
+		Actor:    types.CommandActor{Type: types.CommandActorOperator, ID: flags.actor},
+		IssuedAt: time.Now().UTC(),
+	}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		command.Payload = raw
+	}
+	if command.Actor.ID == "" {
+		return errors.New("--actor is required when $USER is unset")
+	}
+	if err := command.Validate(); err != nil {
+		return err
+	}
+	if flags.ttl < 0 {
+		return errors.New("--ttl must not be negative")
+	}
+
+	body := map[string]any{"type": command.Type, "issued_at": command.IssuedAt, "actor": command.Actor}
+	if command.Payload != nil {
+		body["payload"] = command.Payload
+	}
+	if flags.ttl > 0 {
+		body["ttl"] = flags.ttl.String()
+	}
+	var issued types.RunCommand
+	if err := opts.client().do(cmd.Context(), "POST", "/runs/"+url.PathEscape(runID)+"/commands", body, &issued); err != nil {
+		return err
+	}
+	if opts.output == "json" {
+		return printJSON(cmd.OutOrStdout(), issued)
+	}
+	_, err := fmt.Fprintf(cmd.OutOrStdout(), "Queued %s command %s for run %s\n", issued.Type, issued.ID, issued.RunID)
+	return err
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func newEventsCommand(opts *options) *cobra.Command {
+	events := &cobra.Command{Use: "events", Short: "Follow run events"}
+	events.AddCommand(newEventsTailCommand(opts))
+	return events
+}
+
+func newEventsTailCommand(opts *options) *cobra.Command {
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "tail RUN",
+		Short: "Print a run's state transitions, health changes and command deliveries as they happen",
+		Long: "Print a run's state transitions, health changes and command deliveries as they happen.\n" +
+			"Existing history is printed first. Stops once the run reaches a terminal state.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interval <= 0 {
+				return errors.New("--interval must be positive")
+			}
+			t := &tailer{client: opts.client(), runID: args[0], out: cmd.OutOrStdout(), json: opts.output == "json", commands: make(map[string]types.CommandStatus)}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				done, err := t.poll(cmd)
+				if err != nil || done {
+					return err
+				}
+				select {
+				case <-cmd.Context().Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "how often to poll the orchestrator")
+	return cmd
+}
+
+// runEvent is one line of tail output.
+type runEvent struct {
+	At      time.Time `json:"at"`
+	RunID   string    `json:"run_id"`
+	Kind    string    `json:"kind"` // transition, health or command
+	Message string    `json:"message"`
+}
+
+type transition struct {
+	FromState types.RunState `json:"from_state"`
+	ToState   types.RunState `json:"to_state"`
+	ChangedBy string         `json:"changed_by"`
+	Reason    string         `json:"reason"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// tailer diffs successive snapshots of a run's history. Transitions are append
+// only, so it tracks how many it has printed; commands and health are compared
+// against the last status seen.
+type tailer struct {
+	client      *client
+	runID       string
+	out         io.Writer
+	json        bool
+	transitions int
+	health      types.RunHealth
+	commands    map[string]types.CommandStatus
+}
+
+func (t *tailer) poll(cmd *cobra.Command) (done bool, err error) {
+	ctx := cmd.Context()
+	base := "/runs/" + url.PathEscape(t.runID)
+	var run types.Run
+	if err := t.client.do(ctx, "GET", base, nil, &run); err != nil {
+		return false, err
+	}
+	var history struct {
+		Transitions []transition `json:"transitions"`
+	}
+	if err := t.client.do(ctx, "GET", base+"/transitions", nil, &history); err != nil {
+		return false, err
+	}
+	commands, err := t.listCommands(cmd, base)
+	if err != nil {
+		return false, err
+	}
+
+	for _, tr := range history.Transitions[min(t.transitions, len(history.Transitions)):] {
+		message := fmt.Sprintf("%s -> %s by %s", orNone(string(tr.FromState)), tr.ToState, orNone(tr.ChangedBy))
+		if tr.Reason != "" {
+			message += ": " + tr.Reason
+		}
+		t.emit(runEvent{At: tr.CreatedAt, RunID: t.runID, Kind: "transition", Message: message})
+	}
+	t.transitions = len(history.Transitions)
+	if t.health != run.HealthStatus {
+		if t.health != "" {
+			t.emit(runEvent{At: run.UpdatedAt, RunID: t.runID, Kind: "health", Message: fmt.Sprintf("%s -> %s", t.health, run.HealthStatus)})
+		}
+		t.health = run.HealthStatus
+	}
+	for _, command := range commands {
+		status := command.Status()
+		if t.commands[command.ID] == status {
+			continue
+		}
+		t.commands[command.ID] = status
+		message := fmt.Sprintf("%s %s %s", command.Type, command.ID, status)
+		if command.Result != nil {
+			message += fmt.Sprintf(" (%s", command.Result.Status)
+			if command.Result.Message != "" {
+				message += ": " + command.Result.Message
+			}
+			message += ")"
+		}
+		t.emit(runEvent{At: commandEventTime(command), RunID: t.runID, Kind: "command", Message: message})
+	}
+	return run.State.IsTerminal(), nil
+}
+
+func (t *tailer) listCommands(cmd *cobra.Command, base string) ([]types.RunCommand, error) {
+	var commands []types.RunCommand
+	cursor := ""
+	for {
+		var page struct {
+			Commands   []types.RunCommand `json:"commands"`
+			NextCursor string             `json:"next_cursor"`
+		}
+		path := base + "/commands?limit=500"
+		if cursor != "" {
+			path += "&cursor=" + url.QueryEscape(cursor)
+		}
+		if err := t.client.do(cmd.Context(), "GET", path, nil, &page); err != nil {
+			return nil, err
+		}
+		commands = append(commands, page.Commands...)
+		if page.NextCursor == "" {
+			return commands, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (t *tailer) emit(event runEvent) {
+	if t.json {
+		raw, _ := json.Marshal(event)
+		fmt.Fprintln(t.out, string(raw))
+		return
+	}
+	fmt.Fprintf(t.out, "%s  %-10s  %s\n", event.At.Local().Format(time.DateTime), event.Kind, event.Message)
+}
+
+// commandEventTime picks the timestamp of the command's latest lifecycle step.
+func commandEventTime(command types.RunCommand) time.Time {
+	latest := command.IssuedAt
+	for _, at := range []*time.Time{command.DeliveredAt, command.AcknowledgedAt, command.ExpiredAt, command.DeadLetteredAt, command.DiscardedAt} {
+		if at != nil && at.After(latest) {
+			latest = *at
+		}
+	}
+	if command.Result != nil && command.Result.ReportedAt.After(latest) {
+		latest = command.Result.ReportedAt
+	}
+	return latest
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
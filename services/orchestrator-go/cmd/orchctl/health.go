@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func newHealthCommand(opts *options) *cobra.Command {
+	var labels []string
+	cmd := &cobra.Command{
+		Use:   "health [RUN...]",
+		Short: "Show heartbeat health of the given runs, or of every running and paused run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := opts.client()
+			var runs []types.Run
+			if len(args) > 0 {
+				for _, id := range args {
+					var run types.Run
+					if err := c.do(cmd.Context(), "GET", "/runs/"+url.PathEscape(id), nil, &run); err != nil {
+						return fmt.Errorf("run %s: %w", id, err)
+					}
+					runs = append(runs, run)
+				}
+			} else {
+				query := url.Values{}
+				for _, label := range labels {
+					query.Add("label", label)
+				}
+				all, err := listRuns(cmd, c, query, 0)
+				if err != nil {
+					return err
+				}
+				for _, run := range all {
+					if run.State == types.RunStateRunning || run.State == types.RunStatePaused {
+						runs = append(runs, run)
+					}
+				}
+			}
+			if opts.output == "json" {
+				return printJSON(cmd.OutOrStdout(), runs)
+			}
+			return printHealthTable(cmd.OutOrStdout(), runs, time.Now())
+		},
+	}
+	cmd.Flags().StringArrayVarP(&labels, "label", "l", nil, "only runs with this key=value label (repeatable)")
+	return cmd
+}
+
+// healthMarkers flags unhealthy runs so they stand out in a long table.
+var healthMarkers = map[types.RunHealth]string{
+	types.RunHealthHealthy:        "ok",
+	types.RunHealthHeartbeatStale: "STALE",
+	types.RunHealthUnresponsive:   "UNRESPONSIVE",
+}
+
+func printHealthTable(w io.Writer, runs []types.Run, now time.Time) error {
+	if len(runs) == 0 {
+		_, err := fmt.Fprintln(w, "No active runs.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tSTATE\tRUNTIME\tHEALTH\tLAST HEARTBEAT\tSTEP\tLOSS\tSAMPLES/S")
+	for _, run := range runs {
+		marker, ok := healthMarkers[run.HealthStatus]
+		if !ok {
+			marker = string(run.HealthStatus)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%.4g\t%.1f\n",
+			run.ID, run.State, run.RuntimeStatus, marker, since(run.LastHeartbeatAt, now),
+			run.CurrentStep, run.Loss, run.SamplesPerSecond)
+	}
+	return tw.Flush()
+}
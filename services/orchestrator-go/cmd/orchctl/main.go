@@ -0,0 +1,73 @@
+// Command orchctl is an operator CLI for the orchestrator HTTP API: it lists and
+// inspects runs, shows heartbeat health, tails run events and issues control
+// commands.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// options are the global flags shared by every subcommand.
+type options struct {
+	server string
+	apiKey string
+	output string
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:           "orchctl",
+		Short:         "Operate Cartridge training runs through the orchestrator API",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			switch opts.output {
+			case "table", "json":
+				return nil
+			}
+			return fmt.Errorf("--output must be table or json, got %q", opts.output)
+		},
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.server, "server", envOr("ORCHCTL_SERVER", "http://localhost:8080"), "orchestrator base URL (env ORCHCTL_SERVER)")
+	flags.StringVar(&opts.apiKey, "api-key", os.Getenv("ORCHCTL_API_KEY"), "API key sent as a bearer token (env ORCHCTL_API_KEY)")
+	flags.StringVarP(&opts.output, "output", "o", "table", "output format: table or json")
+
+	root.AddCommand(
+		newRunsCommand(opts),
+		newHealthCommand(opts),
+		newEventsCommand(opts),
+		newTuneCommand(opts),
+		newPauseCommand(opts),
+		newResumeCommand(opts),
+		newTerminateCommand(opts),
+	)
+	return root
+}
+
+func (o *options) client() *client {
+	return newClient(o.server, o.apiKey)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
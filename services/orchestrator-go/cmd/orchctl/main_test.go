@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	httpServer "github.com/cartridge/orchestrator/internal/http"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func TestOrchctlAgainstServer(t *testing.T) {
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(storage.NewMemoryStore(), events.NoopPublisher{}, logger)
+	srv := httptest.NewServer(httpServer.NewServer(orch, logger).Routes())
+	defer srv.Close()
+
+	ctx := context.Background()
+	for _, id := range []string{"run-a", "run-b"} {
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: id, ExperimentID: "exp-1", VersionID: "ver-1", Labels: map[string]string{"team": "rl"}}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	for _, state := range []types.RunState{types.RunStateProvisioning, types.RunStateRunning} {
+		if _, err := orch.TransitionRun(ctx, "run-a", service.TransitionInput{ToState: state, ChangedBy: "test"}); err != nil {
+			t.Fatalf("transition: %v", err)
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		root := newRootCommand()
+		var out bytes.Buffer
+		root.SetOut(&out)
+		root.SetArgs(append([]string{"--server", srv.URL}, args...))
+		err := root.ExecuteContext(ctx)
+		return out.String(), err
+	}
+
+	out, err := run("runs", "list", "--label", "team=rl")
+	if err != nil || !strings.Contains(out, "run-a") || !strings.Contains(out, "run-b") {
+		t.Fatalf("runs list: %v\n%s", err, out)
+	}
+	if out, err = run("health"); err != nil || !strings.Contains(out, "run-a") || strings.Contains(out, "run-b") {
+		t.Fatalf("expected only the running run in health output: %v\n%s", err, out)
+	}
+	if _, err = run("runs", "get", "missing"); err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Fatalf("expected a 404 error, got %v", err)
+	}
+
+	// Invalid payloads are rejected locally, before anything is queued.
+	if _, err = run("tune", "run-a", "--learning-rate", "2", "--actor", "ops"); err == nil || !strings.Contains(err.Error(), "learning_rate") {
+		t.Fatalf("expected a learning_rate validation error, got %v", err)
+	}
+	if _, err = run("terminate", "run-a", "--actor", "ops"); err == nil || !strings.Contains(err.Error(), "reason") {
+		t.Fatalf("expected terminate without a reason to fail, got %v", err)
+	}
+	if commands, _, _ := orch.ListCommands(ctx, "run-a", storage.CommandFilter{}); len(commands) != 0 {
+		t.Fatalf("expected no commands queued, got %d", len(commands))
+	}
+	if out, err = run("pause", "run-a", "--actor", "ops"); err != nil || !strings.Contains(out, "Queued pause command") {
+		t.Fatalf("pause: %v\n%s", err, out)
+	}
+	if out, err = run("tune", "run-a", "--learning-rate", "0.001", "--actor", "ops", "-o", "json"); err != nil || !strings.Contains(out, `"learning_rate": 0.001`) {
+		t.Fatalf("tune: %v\n%s", err, out)
+	}
+
+	// tail prints the history and returns once the run has ended.
+	if _, err := orch.TransitionRun(ctx, "run-a", service.TransitionInput{ToState: types.RunStateCompleted, ChangedBy: "ops", Reason: "done"}); err != nil {
+		t.Fatalf("terminate: %v", err)
+	}
+	out, err = run("events", "tail", "run-a", "--interval", "10ms")
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	for _, want := range []string{"- -> queued", "running -> completed by ops: done", "pause", "tune"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in tail output:\n%s", want, out)
+		}
+	}
+}
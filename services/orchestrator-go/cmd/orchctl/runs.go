@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func newRunsCommand(opts *options) *cobra.Command {
+	runs := &cobra.Command{Use: "runs", Short: "List and inspect runs"}
+	runs.AddCommand(newRunsListCommand(opts), newRunsGetCommand(opts))
+	return runs
+}
+
+func newRunsListCommand(opts *options) *cobra.Command {
+	var (
+		labels          []string
+		states          []string
+		text            string
+		includeArchived bool
+		limit           int
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List runs, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			query := url.Values{}
+			for _, label := range labels {
+				query.Add("label", label)
+			}
+			for _, state := range states {
+				query.Add("state", state)
+			}
+			if text != "" {
+				query.Set("q", text)
+			}
+			if includeArchived {
+				query.Set("include_archived", "true")
+			}
+			runs, err := listRuns(cmd, opts.client(), query, limit)
+			if err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(cmd.OutOrStdout(), runs)
+			}
+			return printRunTable(cmd.OutOrStdout(), runs)
+		},
+	}
+	cmd.Flags().StringArrayVarP(&labels, "label", "l", nil, "only runs with this key=value label (repeatable)")
+	cmd.Flags().StringArrayVarP(&states, "state", "s", nil, "only runs in this state (repeatable)")
+	cmd.Flags().StringVarP(&text, "query", "q", "", "only runs whose status message contains this text")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "include archived runs")
+	cmd.Flags().IntVar(&limit, "limit", 0, "maximum number of runs to show (0 for all)")
+	return cmd
+}
+
+// listRuns follows next_cursor until limit runs are collected or the listing ends.
+func listRuns(cmd *cobra.Command, c *client, query url.Values, limit int) ([]types.Run, error) {
+	var runs []types.Run
+	for {
+		if limit > 0 {
+			query.Set("limit", strconv.Itoa(min(limit-len(runs), 500)))
+		}
+		var page struct {
+			Runs       []types.Run `json:"runs"`
+			NextCursor string      `json:"next_cursor"`
+		}
+		if err := c.do(cmd.Context(), "GET", "/runs?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		runs = append(runs, page.Runs...)
+		if page.NextCursor == "" || (limit > 0 && len(runs) >= limit) {
+			return runs, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+func newRunsGetCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get RUN",
+		Short: "Show a run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var run types.Run
+			if err := opts.client().do(cmd.Context(), "GET", "/runs/"+url.PathEscape(args[0]), nil, &run); err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(cmd.OutOrStdout(), run)
+			}
+			return printRunDetail(cmd.OutOrStdout(), run)
+		},
+	}
+}
+
+func printRunTable(w io.Writer, runs []types.Run) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tEXPERIMENT\tSTATE\tHEALTH\tSTEP\tLOSS\tCREATED")
+	for _, run := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%.4g\t%s\n",
+			run.ID, run.ExperimentID, run.State, run.HealthStatus, run.CurrentStep, run.Loss,
+			run.CreatedAt.Local().Format(time.DateTime))
+	}
+	return tw.Flush()
+}
+
+func printRunDetail(w io.Writer, run types.Run) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	row := func(name string, value any) { fmt.Fprintf(tw, "%s:\t%v\n", name, value) }
+	row("ID", run.ID)
+	row("Experiment", run.ExperimentID)
+	row("Version", run.VersionID)
+	row("State", run.State)
+	if run.State == types.RunStateQueued && run.QueuePosition > 0 {
+		row("Queue position", run.QueuePosition)
+	}
+	if run.StatusMessage != "" {
+		row("Status", run.StatusMessage)
+	}
+	row("Runtime", run.RuntimeStatus)
+	row("Health", run.HealthStatus)
+	row("Last heartbeat", since(run.LastHeartbeatAt, time.Now()))
+	row("Step", run.CurrentStep)
+	row("Loss", run.Loss)
+	row("Samples/s", run.SamplesPerSecond)
+	row("Checkpoint", run.CheckpointVersion)
+	if run.LearnerID != "" {
+		row("Learner", run.LearnerID)
+	}
+	if len(run.Labels) > 0 {
+		row("Labels", formatLabels(run.Labels))
+	}
+	if len(run.DependsOn) > 0 {
+		row("Depends on", strings.Join(run.DependsOn, ", "))
+	}
+	if run.Archived() {
+		row("Archived", run.ArchivedAt.Local().Format(time.DateTime))
+	}
+	row("Created", fmt.Sprintf("%s by %s", run.CreatedAt.Local().Format(time.DateTime), run.CreatedBy))
+	return tw.Flush()
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// since renders how long ago at was, or "never".
+func since(at *time.Time, now time.Time) string {
+	if at == nil {
+		return "never"
+	}
+	return now.Sub(*at).Truncate(time.Second).String() + " ago"
+}
+
+func printJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
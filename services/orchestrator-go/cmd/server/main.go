@@ -2,60 +2,452 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 
+	"github.com/cartridge/chaos"
+	"github.com/cartridge/orchestrator/internal/admission"
+	"github.com/cartridge/orchestrator/internal/artifacts"
+	"github.com/cartridge/orchestrator/internal/auth"
+	"github.com/cartridge/orchestrator/internal/config"
+	"github.com/cartridge/orchestrator/internal/dispatcher"
 	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/health"
 	httpServer "github.com/cartridge/orchestrator/internal/http"
+	"github.com/cartridge/orchestrator/internal/launcher"
+	"github.com/cartridge/orchestrator/internal/leader"
+	"github.com/cartridge/orchestrator/internal/middleware"
+	"github.com/cartridge/orchestrator/internal/migrations"
+	"github.com/cartridge/orchestrator/internal/probe"
+	"github.com/cartridge/orchestrator/internal/replay"
+	"github.com/cartridge/orchestrator/internal/scheduler"
 	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/shutdown"
 	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/watchdog"
+	"github.com/cartridge/telemetry"
+	"github.com/cartridge/telemetry/capture"
+	"github.com/cartridge/telemetry/metrics"
+	"github.com/cartridge/telemetry/tracing"
 )
 
 func main() {
 	var addr string
+	var migrateOnly bool
 	flag.StringVar(&addr, "addr", ":8080", "HTTP listen address")
+	flag.BoolVar(&migrateOnly, "migrate-only", false, "apply database migrations and exit")
 	flag.Parse()
 
 	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
 
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load configuration")
+	}
+	telemetryCfg, err := telemetry.FromEnv("orchestrator")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load telemetry configuration")
+	}
+	faultConfig, err := chaos.FromEnv()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid fault injection configuration")
+	}
+	faults := chaos.New(faultConfig)
+	if faults != nil {
+		logger.Warn().Interface("faults", faultConfig).Msg("fault injection enabled")
+	}
+
+	if migrateOnly || cfg.Database.AutoMigrate {
+		if err := runMigrations(cfg.Database, logger); err != nil {
+			logger.Fatal().Err(err).Msg("database migration failed")
+		}
+		if migrateOnly {
+			return
+		}
+	}
+
 	store := storage.NewMemoryStore()
-	publisher := events.NoopPublisher{}
+	broker, closePublisher, err := newPublisher(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialise event publisher")
+	}
+	// Every event is kept in its run's event log for consumers to backfill.
+	publisher := events.Persisting(broker, store)
 	orch := service.NewOrchestrator(store, publisher, logger)
+	orch.WithCommandRedelivery(cfg.Commands.AckTimeout, cfg.Commands.MaxDeliveryAttempts)
+	orch.WithSerialCommandDelivery(cfg.Commands.Serial)
+	if bus, ok := broker.(events.CommandBus); ok && cfg.Commands.Push {
+		orch.WithCommandBus(bus)
+	}
+	orch.WithLearnerStaleAfter(cfg.Scheduler.LearnerStaleAfter)
+	orch.WithPreemption(service.PreemptionPolicy{
+		MinPriorityGap: cfg.Scheduler.PreemptionMinPriorityGap,
+		MaxPerPass:     cfg.Scheduler.PreemptionMaxPerPass,
+	})
+	orch.WithMetricsRetention(cfg.Health.MetricsRetention)
+	orch.WithArchivedMetricsRetention(cfg.Health.ArchivedMetricsRetention)
+	orch.WithCostRates(cfg.Cost.HourlyRates)
+	orch.WithDefaultMaxDuration(cfg.Scheduler.DefaultMaxDuration)
+	admitters, err := newAdmitters(cfg.Admission, orch, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialise admission webhook")
+	}
+	orch.WithAdmitters(admitters...)
+	if cfg.Artifacts.Bucket != "" {
+		presigner, err := artifacts.NewS3Presigner(artifacts.S3Config{
+			Endpoint:        cfg.Artifacts.Endpoint,
+			Region:          cfg.Artifacts.Region,
+			Bucket:          cfg.Artifacts.Bucket,
+			AccessKeyID:     cfg.Artifacts.AccessKeyID,
+			SecretAccessKey: cfg.Artifacts.SecretAccessKey,
+			PathStyle:       cfg.Artifacts.PathStyle,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to initialise artifact store")
+		}
+		orch.WithArtifactStore(presigner, cfg.Artifacts.URLExpiry)
+	}
+
+	var runTokens *auth.RunTokenSigner
+	if cfg.Auth.RunTokenSecret != "" {
+		runTokens, err = auth.NewRunTokenSigner(cfg.Auth.RunTokenSecret)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid RUN_TOKEN_SECRET")
+		}
+	}
+
+	var lockDB *sql.DB
+	var elector *leader.Elector
+	if cfg.Leader.Enabled {
+		// Replicas share one lock; only the holder runs the background loops.
+		lockDB, err = sql.Open("postgres", cfg.Database.ConnectionString())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to open leader election database")
+		}
+		identity, _ := os.Hostname()
+		elector = leader.NewElector(leader.NewPostgresLock(lockDB, cfg.Leader.LockName), cfg.Leader.RetryInterval, identity, *logger)
+	}
+
+	prober := probe.New(5 * time.Second)
+	if lockDB != nil {
+		prober.AddReadiness("database", probe.Ping(lockDB.PingContext))
+	}
+	if checker, ok := broker.(events.HealthChecker); ok {
+		prober.AddReadiness("events", probe.Ping(checker.Healthy))
+	}
+	// Loops only run on the leader; other replicas report them as standby.
+	var leading func() bool
+	if elector != nil {
+		leading = elector.IsLeader
+	}
+	track := func(name string, interval time.Duration, onTick func(func())) {
+		loop := probe.NewLoop(interval, leading)
+		onTick(loop.Tick)
+		prober.AddLiveness(name, loop.Check)
+	}
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	monitor := health.NewMonitor(orch, publisher, healthConfig(cfg.Health), *logger)
+	sched := scheduler.New(orch, cfg.Scheduler.Interval, *logger)
+	dispatch := dispatcher.New(orch, cfg.Scheduler.DispatchInterval, *logger)
+	guard := watchdog.New(orch, cfg.Scheduler.WatchdogInterval, *logger)
+	track("health_monitor", cfg.Health.CheckInterval, monitor.OnTick)
+	track("scheduler", cfg.Scheduler.Interval, sched.OnTick)
+	track("dispatcher", cfg.Scheduler.DispatchInterval, dispatch.OnTick)
+	track("watchdog", cfg.Scheduler.WatchdogInterval, guard.OnTick)
+	loops := []func(context.Context){monitor.Start, sched.Start, dispatch.Start, guard.Start}
+	if cfg.Launcher.Backend == "docker" {
+		dockerCfg := launcher.DockerConfig{
+			Host:            cfg.Launcher.DockerHost,
+			LearnerImage:    cfg.Launcher.LearnerImage,
+			ActorImage:      cfg.Launcher.ActorImage,
+			ActorReplicas:   cfg.Launcher.ActorReplicas,
+			Network:         cfg.Launcher.Network,
+			OrchestratorURL: cfg.Launcher.OrchestratorURL,
+			StopTimeout:     cfg.Launcher.StopTimeout,
+		}
+		if runTokens != nil {
+			dockerCfg.RunToken = runTokens.Issue
+		}
+		docker, err := launcher.NewDockerLauncher(dockerCfg)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to initialise docker launcher")
+		}
+		controller := launcher.NewController(orch, docker, cfg.Launcher.Interval, *logger)
+		track("launcher", cfg.Launcher.Interval, controller.OnTick)
+		loops = append(loops, controller.Start)
+	}
+	var replayClient *replay.Client
+	if cfg.Replay.Addr != "" {
+		var dialOpts []grpc.DialOption
+		if faults != nil {
+			dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(faults.UnaryClientInterceptor()))
+		}
+		replayClient, err = replay.Dial(cfg.Replay.Addr, dialOpts...)
+		if err != nil {
+			logger.Fatal().Err(err).Str("addr", cfg.Replay.Addr).Msg("failed to initialise replay client")
+		}
+		poller := replay.NewPoller(orch, replayClient, publisher, replay.Config{
+			Interval:   cfg.Replay.PollInterval,
+			StallAfter: cfg.Replay.StallAfter,
+			Capacity:   cfg.Replay.Capacity,
+		}, *logger)
+		track("replay_poller", cfg.Replay.PollInterval, poller.OnTick)
+		loops = append(loops, poller.Start)
+	}
+	runBackground := func(ctx context.Context) {
+		var wg sync.WaitGroup
+		for _, loop := range loops {
+			wg.Add(1)
+			go func(loop func(context.Context)) {
+				defer wg.Done()
+				loop(ctx)
+			}(loop)
+		}
+		wg.Wait()
+	}
+	backgroundDone := make(chan struct{})
+	go func() {
+		defer close(backgroundDone)
+		if elector != nil {
+			elector.Run(backgroundCtx, runBackground)
+		} else {
+			runBackground(backgroundCtx)
+		}
+	}()
 
 	h := httpServer.NewServer(orch, logger)
+	if cfg.Auth.Enabled {
+		staticKeys, err := auth.ParseStaticKeys(cfg.Auth.APIKeys)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid AUTH_API_KEYS")
+		}
+		keyring, err := auth.NewKeyring(staticKeys)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to build API keyring")
+		}
+		h.WithKeyring(keyring)
+		if cfg.Auth.OIDC.Issuer != "" {
+			discoverCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			verifier, err := auth.NewJWTVerifier(discoverCtx, auth.OIDCConfig{
+				Issuer:     cfg.Auth.OIDC.Issuer,
+				Audience:   cfg.Auth.OIDC.Audience,
+				JWKSURL:    cfg.Auth.OIDC.JWKSURL,
+				RolesClaim: cfg.Auth.OIDC.RolesClaim,
+				RunClaim:   cfg.Auth.OIDC.RunClaim,
+			})
+			cancel()
+			if err != nil {
+				logger.Fatal().Err(err).Str("issuer", cfg.Auth.OIDC.Issuer).Msg("failed to initialise OIDC verifier")
+			}
+			h.WithJWTVerifier(verifier)
+		}
+	} else {
+		logger.Warn().Msg("API authentication disabled; set AUTH_ENABLED with AUTH_API_KEYS or OIDC_ISSUER in production")
+	}
+	if runTokens != nil {
+		h.WithRunTokens(runTokens)
+	}
+	h.WithProbes(prober)
+	h.WithMetrics(metrics.NewRegistry())
+	h.WithFaults(faults)
+	shutdownTracing, err := tracing.Init(context.Background(), telemetryCfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialize tracing")
+	}
+	limiter := middleware.NewRateLimiter(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+	h.WithRateLimiter(limiter)
+	// SIGHUP and POST /api/v1/admin/reload re-read the configuration; only the
+	// heartbeat thresholds and rate limits change without a restart.
+	reloader := config.NewReloader(cfg, func(next *config.Config) {
+		monitor.SetThresholds(healthConfig(next.Health))
+		limiter.SetLimit(next.RateLimit.RequestsPerSecond, next.RateLimit.Burst)
+	}, *logger)
+	h.WithReloader(reloader.Reload)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloader.Reload()
+		}
+	}()
+	handler := h.Routes()
+	var recorder *capture.Recorder
+	if cfg.Capture.File != "" {
+		recorder, err = capture.NewRecorder(cfg.Capture.File, int64(cfg.Capture.MaxSize))
+		if err != nil {
+			logger.Fatal().Err(err).Str("file", cfg.Capture.File).Msg("failed to open capture file")
+		}
+		logger.Info().Str("file", cfg.Capture.File).Msg("capturing heartbeat traffic")
+		handler = recorder.HTTP(isHeartbeat)(handler)
+	}
 	srv := &http.Server{
 		Addr:              addr,
-		Handler:           h.Routes(),
+		Handler:           handler,
 		ReadHeaderTimeout: 10 * time.Second,
 		ReadTimeout:       30 * time.Second,
-		WriteTimeout:      30 * time.Second,
+		WriteTimeout:      90 * time.Second, // leaves room for 60s command long polls
 	}
 
-	done := make(chan struct{})
 	go func() {
 		logger.Info().Str("addr", addr).Msg("orchestrator HTTP server starting")
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Fatal().Err(err).Msg("http server failed")
 		}
-		close(done)
 	}()
 
+	// Components stop in order: no new requests, then no new background work,
+	// then the publisher flushes whatever events both produced.
+	coordinator := shutdown.New(*logger)
+	coordinator.Add("http", srv.Shutdown)
+	if recorder != nil {
+		coordinator.Add("traffic capture", func(context.Context) error {
+			if dropped := recorder.Dropped(); dropped > 0 {
+				logger.Warn().Int64("dropped", int64(dropped)).Msg("capture file filled up; later heartbeats were not recorded")
+			}
+			return recorder.Close()
+		})
+	}
+	coordinator.Add("background loops", func(ctx context.Context) error {
+		stopBackground()
+		select {
+		case <-backgroundDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	coordinator.Add("event publisher", shutdown.Wait(closePublisher))
+	if replayClient != nil {
+		coordinator.Add("replay client", func(context.Context) error { return replayClient.Close() })
+	}
+	if lockDB != nil {
+		coordinator.Add("database", func(context.Context) error { return lockDB.Close() })
+	}
+	coordinator.Add("tracing", shutdownTracing)
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 	<-sig
-	logger.Info().Msg("shutdown signal received")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
+	logger.Info().Dur("timeout", cfg.Server.ShutdownTimeout).Msg("shutdown signal received")
+	if err := coordinator.Shutdown(cfg.Server.ShutdownTimeout); err != nil {
 		logger.Error().Err(err).Msg("graceful shutdown failed")
+		os.Exit(1)
 	}
-	<-done
 	logger.Info().Msg("orchestrator stopped")
 }
+
+// newAdmitters builds the admission chain: the built-in policies first, so the
+// webhook only reviews runs they accept.
+func newAdmitters(cfg config.AdmissionConfig, orch *service.Orchestrator, logger *zerolog.Logger) ([]service.Admitter, error) {
+	var admitters []service.Admitter
+	if cfg.RunIDPattern != "" {
+		admitters = append(admitters, service.RunIDPattern(regexp.MustCompile(cfg.RunIDPattern)))
+	}
+	if len(cfg.AllowedEnvs) > 0 {
+		admitters = append(admitters, service.AllowedEnvs(cfg.AllowedEnvs))
+	}
+	if cfg.ExperimentBudget > 0 {
+		admitters = append(admitters, orch.ExperimentBudget(cfg.ExperimentBudget))
+	}
+	if cfg.WebhookURL != "" {
+		webhook, err := admission.NewWebhook(admission.WebhookOptions{
+			URL:      cfg.WebhookURL,
+			Secret:   cfg.WebhookSecret,
+			Timeout:  cfg.WebhookTimeout,
+			FailOpen: cfg.WebhookFailOpen,
+		}, *logger)
+		if err != nil {
+			return nil, err
+		}
+		admitters = append(admitters, webhook)
+	}
+	return admitters, nil
+}
+
+// isHeartbeat selects the run, batch and learner heartbeat requests for
+// traffic capture.
+func isHeartbeat(r *http.Request) bool {
+	return r.Method == http.MethodPost && (strings.HasSuffix(r.URL.Path, "/heartbeat") || r.URL.Path == "/api/v1/heartbeats")
+}
+
+func healthConfig(cfg config.HealthConfig) health.Config {
+	return health.Config{
+		CheckInterval:         cfg.CheckInterval,
+		HeartbeatStaleAfter:   cfg.HeartbeatStaleAfter,
+		HeartbeatUnresponsive: cfg.HeartbeatUnresponsive,
+		BaselineMultiplier:    cfg.HeartbeatBaselineMultiplier,
+		BaselineMinSamples:    cfg.HeartbeatBaselineMinSamples,
+		BaselineFloor:         cfg.HeartbeatBaselineFloor,
+	}
+}
+
+func newPublisher(cfg *config.Config, logger *zerolog.Logger) (events.Publisher, func(), error) {
+	switch cfg.Events.Backend {
+	case "nats":
+		publisher, err := events.NewNATSPublisher(events.NATSOptions{
+			URL:           cfg.NATS.URL,
+			Subject:       cfg.NATS.Subject,
+			Stream:        cfg.NATS.Stream,
+			OutboxSize:    cfg.NATS.OutboxSize,
+			ReconnectWait: cfg.NATS.ReconnectWait,
+		}, *logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		return publisher, publisher.Close, nil
+	case "redis":
+		publisher, err := events.NewRedisPublisher(events.RedisOptions{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+			Stream:   cfg.Redis.Stream,
+			MaxLen:   cfg.Redis.MaxLen,
+		}, *logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		return publisher, publisher.Close, nil
+	case "webhook":
+		publisher, err := events.NewWebhookPublisher(events.WebhookOptions{
+			URLs:         cfg.Webhook.URLs,
+			Secret:       cfg.Webhook.Secret,
+			MaxRetries:   cfg.Webhook.MaxRetries,
+			RetryBackoff: cfg.Webhook.RetryBackoff,
+			Timeout:      cfg.Webhook.Timeout,
+		}, *logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		return publisher, publisher.Close, nil
+	default:
+		return events.NoopPublisher{}, func() {}, nil
+	}
+}
+
+func runMigrations(cfg config.DatabaseConfig, logger *zerolog.Logger) error {
+	db, err := sql.Open("postgres", cfg.ConnectionString())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	applied, err := migrations.Up(ctx, db)
+	if err != nil {
+		return err
+	}
+	logger.Info().Int("applied", applied).Msg("database migrations complete")
+	return nil
+}
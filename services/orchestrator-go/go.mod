@@ -6,7 +6,26 @@ replace github.com/go-chi/chi/v5 => ./internal/thirdparty/chi
 
 replace github.com/rs/zerolog => ./internal/thirdparty/zerolog
 
+replace github.com/cartridge/proto => ../../proto
+
+replace github.com/cartridge/telemetry => ../../telemetry
+
+replace github.com/cartridge/replay => ../replay-go
+
+replace github.com/cartridge/chaos => ../../chaos
+
+replace github.com/cartridge/conf => ../../conf
+
+replace github.com/cartridge/sigv4 => ../../sigv4
+
 require (
+	github.com/cartridge/chaos v0.0.0-00010101000000-000000000000
+	github.com/cartridge/conf v0.0.0-00010101000000-000000000000
+	github.com/cartridge/proto v0.0.0-00010101000000-000000000000
+	github.com/cartridge/replay v0.0.0-00010101000000-000000000000
+	github.com/cartridge/sigv4 v0.0.0-00010101000000-000000000000
+	github.com/cartridge/telemetry v0.0.0-00010101000000-000000000000
 	github.com/go-chi/chi/v5 v5.0.10
 	github.com/rs/zerolog v1.31.0
+	github.com/spf13/cobra v1.8.0
 )
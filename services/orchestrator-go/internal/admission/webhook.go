@@ -0,0 +1,120 @@
+// Package admission calls out to external services that decide whether runs
+// may be created. In-process policies live in the service package.
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/correlation"
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// maxReviewResponse bounds the webhook response body that is read.
+const maxReviewResponse = 64 * 1024
+
+// WebhookOptions configures an admission webhook.
+type WebhookOptions struct {
+	URL string
+	// Secret signs requests like event webhooks (X-Cartridge-Signature); signing
+	// is skipped when empty.
+	Secret  string
+	Timeout time.Duration
+	// FailOpen admits runs when the webhook cannot be reached or answers with
+	// an error; by default they are refused.
+	FailOpen bool
+}
+
+// Review is the body POSTed to the webhook.
+type Review struct {
+	Run types.Run `json:"run"`
+}
+
+// Decision is the webhook's answer. Reason is returned to the caller when the
+// run is refused.
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Webhook is a service.Admitter that asks an external HTTP service to review
+// each run.
+type Webhook struct {
+	client *http.Client
+	opts   WebhookOptions
+	logger zerolog.Logger
+}
+
+// NewWebhook creates an admission webhook.
+func NewWebhook(opts WebhookOptions, logger zerolog.Logger) (*Webhook, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("admission webhook URL is required")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	return &Webhook{client: &http.Client{Timeout: opts.Timeout}, opts: opts, logger: logger}, nil
+}
+
+// Admit satisfies service.Admitter.
+func (w *Webhook) Admit(ctx context.Context, run types.Run) error {
+	decision, err := w.review(ctx, run)
+	if err != nil {
+		if w.opts.FailOpen {
+			w.logger.Warn().Err(err).Str("run_id", run.ID).Msg("admission webhook failed; admitting run")
+			return nil
+		}
+		return err
+	}
+	if !decision.Allowed {
+		reason := decision.Reason
+		if reason == "" {
+			reason = "refused by admission webhook"
+		}
+		return service.Deny("%s", reason)
+	}
+	return nil
+}
+
+func (w *Webhook) review(ctx context.Context, run types.Run) (Decision, error) {
+	body, err := json.Marshal(Review{Run: run})
+	if err != nil {
+		return Decision{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(events.WebhookTimestampHeader, timestamp)
+	if id := correlation.FromContext(ctx); id != "" {
+		req.Header.Set(correlation.Header, id)
+	}
+	if w.opts.Secret != "" {
+		req.Header.Set(events.WebhookSignatureHeader, events.SignWebhookPayload(w.opts.Secret, timestamp, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Decision{}, fmt.Errorf("admission webhook returned %d", resp.StatusCode)
+	}
+	var decision Decision
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReviewResponse)).Decode(&decision); err != nil {
+		return Decision{}, fmt.Errorf("decode admission webhook response: %w", err)
+	}
+	return decision, nil
+}
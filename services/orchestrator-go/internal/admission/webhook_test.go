@@ -0,0 +1,67 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func TestWebhookAdmit(t *testing.T) {
+	var reviewed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(events.WebhookSignatureHeader), events.SignWebhookPayload("s3cret", r.Header.Get(events.WebhookTimestampHeader), body); got != want {
+			t.Errorf("signature %q, want %q", got, want)
+		}
+		var review Review
+		json.Unmarshal(body, &review)
+		reviewed = append(reviewed, review.Run.ID)
+		switch review.Run.ID {
+		case "run-ok":
+			json.NewEncoder(w).Encode(Decision{Allowed: true})
+		case "run-bad-name":
+			json.NewEncoder(w).Encode(Decision{Allowed: false, Reason: "run ids must start with the team name"})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	webhook, err := NewWebhook(WebhookOptions{URL: server.URL, Secret: "s3cret"}, *zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("new webhook: %v", err)
+	}
+	if err := webhook.Admit(ctx, types.Run{ID: "run-ok"}); err != nil {
+		t.Fatalf("expected run-ok admitted, got %v", err)
+	}
+	err = webhook.Admit(ctx, types.Run{ID: "run-bad-name"})
+	if !errors.Is(err, service.ErrAdmissionDenied) || err.Error() != "run ids must start with the team name" {
+		t.Fatalf("expected the webhook's reason as a denial, got %v", err)
+	}
+	err = webhook.Admit(ctx, types.Run{ID: "run-broken"})
+	if err == nil || errors.Is(err, service.ErrAdmissionDenied) {
+		t.Fatalf("expected a failing webhook to be an error rather than a denial, got %v", err)
+	}
+
+	failOpen, _ := NewWebhook(WebhookOptions{URL: server.URL, Secret: "s3cret", FailOpen: true}, *zerolog.New(io.Discard))
+	if err := failOpen.Admit(ctx, types.Run{ID: "run-broken"}); err != nil {
+		t.Fatalf("expected a fail-open webhook to admit on error, got %v", err)
+	}
+	if err := failOpen.Admit(ctx, types.Run{ID: "run-bad-name"}); !errors.Is(err, service.ErrAdmissionDenied) {
+		t.Fatalf("expected fail-open to still honour denials, got %v", err)
+	}
+	if len(reviewed) != 5 {
+		t.Fatalf("expected five reviews, got %v", reviewed)
+	}
+}
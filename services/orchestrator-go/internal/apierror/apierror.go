@@ -0,0 +1,86 @@
+// Package apierror defines the JSON error envelope returned by the API and its
+// machine-readable codes:
+//
+//	{"code": "validation_failed", "message": "slots must be at least 1", "details": [...]}
+//
+// Clients should branch on code; message is for humans and may change.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Code is a stable, machine-readable error code.
+type Code string
+
+// Error codes. Each has a single HTTP status (see Status).
+const (
+	CodeValidation           Code = "validation_failed"
+	CodeUnauthenticated      Code = "unauthenticated"
+	CodeForbidden            Code = "forbidden"
+	CodeNotFound             Code = "not_found"
+	CodeStateConflict        Code = "state_conflict"
+	CodePreconditionFailed   Code = "precondition_failed"
+	CodeUnsupportedMediaType Code = "unsupported_media_type"
+	CodeRegression           Code = "regression"
+	CodeRateLimited          Code = "rate_limited"
+	CodeAdmissionDenied      Code = "admission_denied"
+	CodeInternal             Code = "internal"
+	CodeUnavailable          Code = "unavailable"
+)
+
+var statuses = map[Code]int{
+	CodeValidation:           http.StatusBadRequest,
+	CodeUnauthenticated:      http.StatusUnauthorized,
+	CodeForbidden:            http.StatusForbidden,
+	CodeNotFound:             http.StatusNotFound,
+	CodeStateConflict:        http.StatusConflict,
+	CodePreconditionFailed:   http.StatusPreconditionFailed,
+	CodeUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	CodeRegression:           http.StatusUnprocessableEntity,
+	CodeRateLimited:          http.StatusTooManyRequests,
+	CodeAdmissionDenied:      http.StatusForbidden,
+	CodeInternal:             http.StatusInternalServerError,
+	CodeUnavailable:          http.StatusServiceUnavailable,
+}
+
+// Status returns the HTTP status code reported with c.
+func (c Code) Status() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// ForStatus returns the code for errors that are only known by their HTTP
+// status, such as malformed request bodies rejected by a handler. Where several
+// codes share a status, the general one is returned.
+func ForStatus(status int) Code {
+	if status == http.StatusForbidden {
+		return CodeForbidden
+	}
+	for code, s := range statuses {
+		if s == status {
+			return code
+		}
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeValidation
+}
+
+// Envelope is the body of every error response.
+type Envelope struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Write sends an error envelope with code's status.
+func Write(w http.ResponseWriter, code Code, message string, details any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code.Status())
+	json.NewEncoder(w).Encode(Envelope{Code: code, Message: message, Details: details})
+}
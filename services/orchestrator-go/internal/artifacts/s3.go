@@ -0,0 +1,114 @@
+// Package artifacts issues pre-signed URLs for an S3-compatible object store so
+// learners upload and download large blobs directly, without proxying them
+// through the orchestrator. AWS S3, MinIO and Google Cloud Storage (through its
+// XML API with HMAC keys) all accept the Signature Version 4 query signing used
+// here.
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cartridge/sigv4"
+)
+
+// MaxExpiry is the longest validity SigV4 allows for a pre-signed URL.
+const MaxExpiry = sigv4.MaxExpiry
+
+// Presigner issues time-limited URLs for objects in a single bucket.
+type Presigner interface {
+	// PresignPut returns a URL accepting an HTTP PUT of the object body.
+	PresignPut(key string, expires time.Duration) (string, error)
+	// PresignGet returns a URL serving the object with HTTP GET.
+	PresignGet(key string, expires time.Duration) (string, error)
+	// URI returns the canonical s3://bucket/key location of an object.
+	URI(key string) string
+}
+
+// S3Config locates the bucket and the credentials used to sign URLs.
+type S3Config struct {
+	// Endpoint is the service base URL, e.g. https://s3.us-east-1.amazonaws.com,
+	// https://storage.googleapis.com or http://minio:9000.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key,
+	// which MinIO and most self-hosted stores require.
+	PathStyle bool
+}
+
+// S3Presigner signs URLs with AWS Signature Version 4.
+type S3Presigner struct {
+	cfg      S3Config
+	endpoint *url.URL
+	now      func() time.Time
+}
+
+// NewS3Presigner validates cfg and returns a presigner for its bucket.
+func NewS3Presigner(cfg S3Config) (*S3Presigner, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("artifacts: bucket and credentials are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("artifacts: invalid endpoint %q", cfg.Endpoint)
+	}
+	return &S3Presigner{cfg: cfg, endpoint: endpoint, now: time.Now}, nil
+}
+
+// WithNow allows tests to override the signing clock.
+func (p *S3Presigner) WithNow(now func() time.Time) {
+	p.now = now
+}
+
+// PresignPut implements Presigner.
+func (p *S3Presigner) PresignPut(key string, expires time.Duration) (string, error) {
+	return p.presign("PUT", key, expires)
+}
+
+// PresignGet implements Presigner.
+func (p *S3Presigner) PresignGet(key string, expires time.Duration) (string, error) {
+	return p.presign("GET", key, expires)
+}
+
+// URI implements Presigner.
+func (p *S3Presigner) URI(key string) string {
+	return "s3://" + p.cfg.Bucket + "/" + key
+}
+
+func (p *S3Presigner) presign(method, key string, expires time.Duration) (string, error) {
+	if key == "" {
+		return "", errors.New("artifacts: object key is required")
+	}
+	if expires < time.Second || expires > MaxExpiry {
+		return "", fmt.Errorf("artifacts: expiry must be between 1s and %s", MaxExpiry)
+	}
+
+	host, path := p.endpoint.Host, "/"+key
+	if p.cfg.PathStyle {
+		path = "/" + p.cfg.Bucket + path
+	} else {
+		host = p.cfg.Bucket + "." + host
+	}
+	if prefix := strings.TrimSuffix(p.endpoint.Path, "/"); prefix != "" {
+		path = prefix + path
+	}
+
+	u := &url.URL{Scheme: p.endpoint.Scheme, Host: host, Path: path}
+	return p.credentials().Presign(method, u, expires, p.now()), nil
+}
+
+func (p *S3Presigner) credentials() sigv4.Credentials {
+	return sigv4.Credentials{AccessKeyID: p.cfg.AccessKeyID, SecretAccessKey: p.cfg.SecretAccessKey, Region: p.cfg.Region}
+}
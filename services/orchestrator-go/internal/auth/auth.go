@@ -0,0 +1,259 @@
+// Package auth implements API key authentication and role checks for the
+// orchestrator HTTP API.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Role is the permission level granted to an API key.
+type Role string
+
+const (
+	// RoleReadOnly may only read run, experiment and version data.
+	RoleReadOnly Role = "read-only"
+	// RoleLearner may additionally heartbeat and consume/acknowledge commands.
+	RoleLearner Role = "learner"
+	// RoleOperator may call every endpoint, including key management.
+	RoleOperator Role = "operator"
+)
+
+var roleRank = map[Role]int{RoleReadOnly: 1, RoleLearner: 2, RoleOperator: 3}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// Allows reports whether a key with role r may call an endpoint requiring role required.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required] && roleRank[r] > 0
+}
+
+var (
+	// ErrInvalidKey indicates a missing, unknown, revoked or expired API key.
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrKeyNotFound indicates the referenced key ID does not exist.
+	ErrKeyNotFound = errors.New("api key not found")
+)
+
+// APIKey describes a key without its secret. Only a SHA-256 hash of the secret is kept.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Role      Role       `json:"role"`
+	Static    bool       `json:"static"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	hash      string
+}
+
+// Principal returns the identity requests authenticated with this key act as.
+func (k APIKey) Principal() Principal {
+	return Principal{Subject: k.Name, Role: k.Role, Method: MethodAPIKey}
+}
+
+func (k APIKey) active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// Keyring holds the API keys accepted by the server. Keys loaded from
+// configuration are static; keys created at runtime can be rotated by creating
+// a replacement and revoking the old key.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys map[string]APIKey // id -> key
+	now  func() time.Time
+}
+
+// NewKeyring constructs a Keyring seeded with static keys.
+func NewKeyring(static []StaticKey) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]APIKey), now: time.Now}
+	for _, s := range static {
+		if !s.Role.Valid() {
+			return nil, fmt.Errorf("api key %s: unknown role %q", s.Name, s.Role)
+		}
+		if s.Secret == "" {
+			return nil, fmt.Errorf("api key %s: secret is required", s.Name)
+		}
+		if _, exists := k.keys[s.Name]; exists {
+			return nil, fmt.Errorf("api key %s: duplicate name", s.Name)
+		}
+		k.keys[s.Name] = APIKey{
+			ID:        s.Name,
+			Name:      s.Name,
+			Role:      s.Role,
+			Static:    true,
+			CreatedAt: k.now(),
+			hash:      hashSecret(s.Secret),
+		}
+	}
+	return k, nil
+}
+
+// Authenticate returns the active key matching secret.
+func (k *Keyring) Authenticate(secret string) (APIKey, error) {
+	if secret == "" {
+		return APIKey{}, ErrInvalidKey
+	}
+	hash := hashSecret(secret)
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if subtle.ConstantTimeCompare([]byte(key.hash), []byte(hash)) == 1 {
+			if !key.active(now) {
+				return APIKey{}, ErrInvalidKey
+			}
+			return key, nil
+		}
+	}
+	return APIKey{}, ErrInvalidKey
+}
+
+// Create issues a new key, returning its metadata and the plaintext secret. The
+// secret cannot be recovered later. A zero ttl creates a key that never expires.
+func (k *Keyring) Create(name string, role Role, ttl time.Duration) (APIKey, string, error) {
+	if name == "" {
+		return APIKey{}, "", errors.New("name is required")
+	}
+	if !role.Valid() {
+		return APIKey{}, "", fmt.Errorf("unknown role %q", role)
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	now := k.now()
+	key := APIKey{ID: id, Name: name, Role: role, CreatedAt: now, hash: hashSecret(secret)}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		key.ExpiresAt = &expires
+	}
+	k.mu.Lock()
+	k.keys[id] = key
+	k.mu.Unlock()
+	return key, secret, nil
+}
+
+// Revoke disables a key immediately.
+func (k *Keyring) Revoke(id string) (APIKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return APIKey{}, ErrKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		now := k.now()
+		key.RevokedAt = &now
+		k.keys[id] = key
+	}
+	return key, nil
+}
+
+// List returns all keys, oldest first.
+func (k *Keyring) List() []APIKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]APIKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// StaticKey is a key supplied through configuration.
+type StaticKey struct {
+	Name   string
+	Role   Role
+	Secret string
+}
+
+// ParseStaticKeys parses a comma-separated list of name:role:secret entries.
+func ParseStaticKeys(spec string) ([]StaticKey, error) {
+	var keys []StaticKey
+	for i, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("api key entry %d: expected name:role:secret", i+1)
+		}
+		keys = append(keys, StaticKey{Name: parts[0], Role: Role(parts[1]), Secret: parts[2]})
+	}
+	return keys, nil
+}
+
+// Authentication methods recorded on a Principal.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Role    Role
+	// RunID, when set, restricts the principal to endpoints of that run.
+	RunID  string
+	Method string
+}
+
+// CanAccessRun reports whether the principal may act on runID.
+func (p Principal) CanAccessRun(runID string) bool {
+	return p.RunID == "" || runID == "" || p.RunID == runID
+}
+
+type principalKey struct{}
+
+// WithPrincipal stores the authenticated principal on the context.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the authenticated principal stored on the context, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRoleAllows(t *testing.T) {
+	if !RoleOperator.Allows(RoleLearner) || !RoleLearner.Allows(RoleReadOnly) {
+		t.Fatalf("expected higher roles to include lower ones")
+	}
+	if RoleReadOnly.Allows(RoleLearner) || RoleLearner.Allows(RoleOperator) {
+		t.Fatalf("expected lower roles to be rejected")
+	}
+	if Role("admin").Allows(RoleReadOnly) {
+		t.Fatalf("expected unknown role to be rejected")
+	}
+}
+
+func TestKeyringLifecycle(t *testing.T) {
+	static, err := ParseStaticKeys("ops:operator:s3cret, ci:read-only:ro")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	ring, err := NewKeyring(static)
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	if key, err := ring.Authenticate("s3cret"); err != nil || key.Role != RoleOperator {
+		t.Fatalf("expected static operator key, got %+v (%v)", key, err)
+	}
+	if _, err := ring.Authenticate("wrong"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected invalid key, got %v", err)
+	}
+
+	created, secret, err := ring.Create("learner-1", RoleLearner, time.Hour)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := ring.Authenticate(secret); err != nil {
+		t.Fatalf("expected new key to authenticate: %v", err)
+	}
+	if _, err := ring.Revoke(created.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := ring.Authenticate(secret); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected revoked key to be rejected, got %v", err)
+	}
+
+	_, expiring, _ := ring.Create("short", RoleReadOnly, time.Minute)
+	ring.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := ring.Authenticate(expiring); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected expired key to be rejected, got %v", err)
+	}
+
+	if _, err := ParseStaticKeys("missing-role"); err == nil {
+		t.Fatalf("expected malformed entry to be rejected")
+	}
+	if _, err := NewKeyring([]StaticKey{{Name: "x", Role: "admin", Secret: "y"}}); err == nil {
+		t.Fatalf("expected unknown role to be rejected")
+	}
+}
+
+func TestRunTokens(t *testing.T) {
+	if _, err := NewRunTokenSigner("short"); err == nil {
+		t.Fatalf("expected short secret to be rejected")
+	}
+	signer, err := NewRunTokenSigner("0123456789abcdef")
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	token := signer.Issue("run-a")
+	if err := signer.Verify("run-a", token); err != nil {
+		t.Fatalf("expected token to verify: %v", err)
+	}
+	if err := signer.Verify("run-b", token); !errors.Is(err, ErrInvalidRunToken) {
+		t.Fatalf("expected token for another run to be rejected, got %v", err)
+	}
+	if err := signer.Verify("run-a", ""); !errors.Is(err, ErrInvalidRunToken) {
+		t.Fatalf("expected missing token to be rejected, got %v", err)
+	}
+	other, _ := NewRunTokenSigner("fedcba9876543210")
+	if err := other.Verify("run-a", token); !errors.Is(err, ErrInvalidRunToken) {
+		t.Fatalf("expected token from another secret to be rejected, got %v", err)
+	}
+}
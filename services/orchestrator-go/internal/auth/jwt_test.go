@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWTVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	provider := httptest.NewServer(mux)
+	defer provider.Close()
+	issuer = provider.URL
+
+	verifier, err := NewJWTVerifier(context.Background(), OIDCConfig{Issuer: issuer, Audience: "orchestrator"})
+	if err != nil {
+		t.Fatalf("new verifier: %v", err)
+	}
+	sign := func(kid string, claims map[string]any) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+		body, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+		digest := sha256.Sum256([]byte(signed))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":   issuer,
+			"aud":   []string{"orchestrator"},
+			"sub":   "alice",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": []string{"read-only", "operator"},
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	principal, err := verifier.Verify(context.Background(), sign("k1", claims(nil)))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if principal.Subject != "alice" || principal.Role != RoleOperator || principal.Method != MethodJWT {
+		t.Fatalf("unexpected principal %+v", principal)
+	}
+
+	learner, err := verifier.Verify(context.Background(), sign("k1", claims(map[string]any{"roles": "learner", "run_id": "run-1"})))
+	if err != nil || learner.RunID != "run-1" || !learner.CanAccessRun("run-1") || learner.CanAccessRun("run-2") {
+		t.Fatalf("expected run-scoped learner, got %+v (%v)", learner, err)
+	}
+
+	rejected := map[string]string{
+		"expired":       sign("k1", claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"audience":      sign("k1", claims(map[string]any{"aud": "someone-else"})),
+		"issuer":        sign("k1", claims(map[string]any{"iss": "https://evil.example"})),
+		"no role":       sign("k1", claims(map[string]any{"roles": []string{"admin"}})),
+		"unbound learn": sign("k1", claims(map[string]any{"roles": "learner"})),
+		"unknown kid":   sign("k2", claims(nil)),
+		"tampered":      sign("k1", claims(nil))[:40] + "x" + sign("k1", claims(nil))[41:],
+	}
+	for name, token := range rejected {
+		if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidRunToken indicates a missing run token or one issued for another run.
+var ErrInvalidRunToken = errors.New("invalid run token")
+
+const runTokenPrefix = "rt1."
+
+// RunTokenSigner issues and checks per-run heartbeat tokens. A token is an HMAC
+// of the run ID, so it needs no storage and can be reissued for an existing
+// run; rotating the secret invalidates every outstanding token.
+type RunTokenSigner struct {
+	secret []byte
+}
+
+// NewRunTokenSigner creates a signer keyed by secret.
+func NewRunTokenSigner(secret string) (*RunTokenSigner, error) {
+	if len(secret) < 16 {
+		return nil, errors.New("run token secret must be at least 16 bytes")
+	}
+	return &RunTokenSigner{secret: []byte(secret)}, nil
+}
+
+// Issue returns the token learners present when acting on runID.
+func (s *RunTokenSigner) Issue(runID string) string {
+	return runTokenPrefix + base64.RawURLEncoding.EncodeToString(s.sign(runID))
+}
+
+// Verify checks that token was issued for runID.
+func (s *RunTokenSigner) Verify(runID, token string) error {
+	encoded, ok := strings.CutPrefix(token, runTokenPrefix)
+	if !ok || runID == "" {
+		return ErrInvalidRunToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || !hmac.Equal(mac, s.sign(runID)) {
+		return ErrInvalidRunToken
+	}
+	return nil
+}
+
+func (s *RunTokenSigner) sign(runID string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte("run:" + runID))
+	return h.Sum(nil)
+}
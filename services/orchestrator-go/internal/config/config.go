@@ -2,110 +2,325 @@ package config
 
 import (
 	"fmt"
-	"os"
-	"strconv"
+	"regexp"
 	"time"
+
+	"github.com/cartridge/conf"
 )
 
 // Config holds all orchestrator configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	NATS     NATSConfig
-	Health   HealthConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	NATS      NATSConfig
+	Redis     RedisConfig
+	Webhook   WebhookConfig
+	Events    EventsConfig
+	Health    HealthConfig
+	Commands  CommandsConfig
+	Scheduler SchedulerConfig
+	Artifacts ArtifactsConfig
+	Auth      AuthConfig
+	Leader    LeaderConfig
+	Launcher  LauncherConfig
+	Replay    ReplayConfig
+	Cost      CostConfig
+	RateLimit RateLimitConfig
+	Admission AdmissionConfig
+	Capture   CaptureConfig
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Port            int
-	Host            string
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	ShutdownTimeout time.Duration
+	Port            int           `env:"PORT" default:"8080"`
+	Host            string        `env:"HOST" default:"0.0.0.0"`
+	ReadTimeout     time.Duration `env:"READ_TIMEOUT" default:"30s"`
+	WriteTimeout    time.Duration `env:"WRITE_TIMEOUT" default:"30s"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s"`
 }
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `env:"DB_HOST" default:"localhost"`
+	Port     int    `env:"DB_PORT" default:"5432"`
+	User     string `env:"DB_USER" default:"postgres"`
+	Password string `env:"DB_PASSWORD"`
+	DBName   string `env:"DB_NAME" default:"cartridge"`
+	SSLMode  string `env:"DB_SSL_MODE" default:"disable"`
+
+	// AutoMigrate applies the embedded schema migrations on startup.
+	AutoMigrate bool `env:"DB_AUTO_MIGRATE"`
 }
 
 // NATSConfig holds NATS configuration
 type NATSConfig struct {
-	URL     string
-	Subject string
+	URL           string        `env:"NATS_URL" default:"nats://localhost:4222"`
+	Subject       string        `env:"NATS_SUBJECT" default:"run-status"`
+	Stream        string        `env:"NATS_STREAM" default:"RUN_EVENTS"`
+	OutboxSize    int           `env:"NATS_OUTBOX_SIZE" default:"10000"`
+	ReconnectWait time.Duration `env:"NATS_RECONNECT_WAIT" default:"2s"`
+}
+
+// RedisConfig holds Redis Streams configuration
+type RedisConfig struct {
+	Addr     string `env:"REDIS_ADDR" default:"localhost:6379"`
+	Password string `env:"REDIS_PASSWORD"`
+	DB       int    `env:"REDIS_DB"`
+	Stream   string `env:"REDIS_STREAM" default:"run-status"`
+	MaxLen   int64  `yaml:"stream_maxlen" env:"REDIS_STREAM_MAXLEN" default:"100000"`
+}
+
+// WebhookConfig holds webhook publisher configuration
+type WebhookConfig struct {
+	URLs         []string      `yaml:"urls" env:"WEBHOOK_URLS"`
+	Secret       string        `env:"WEBHOOK_SECRET"`
+	MaxRetries   int           `env:"WEBHOOK_MAX_RETRIES" default:"5"`
+	RetryBackoff time.Duration `env:"WEBHOOK_RETRY_BACKOFF" default:"1s"`
+	Timeout      time.Duration `env:"WEBHOOK_TIMEOUT" default:"10s"`
+}
+
+// EventsConfig selects the event publisher backend
+type EventsConfig struct {
+	// Backend is one of "noop", "nats", "redis", or "webhook".
+	Backend string `env:"EVENTS_BACKEND" default:"noop"`
 }
 
 // HealthConfig holds health monitoring configuration
 type HealthConfig struct {
-	CheckInterval         time.Duration
-	HeartbeatStaleAfter   time.Duration
-	HeartbeatUnresponsive time.Duration
+	CheckInterval         time.Duration `env:"HEALTH_CHECK_INTERVAL" default:"15s"`
+	HeartbeatStaleAfter   time.Duration `env:"HEARTBEAT_STALE_AFTER" default:"45s"`
+	HeartbeatUnresponsive time.Duration `env:"HEARTBEAT_UNRESPONSIVE" default:"135s"`
+	// MetricsRetention bounds the heartbeat metrics history; zero keeps it all.
+	MetricsRetention time.Duration `env:"METRICS_RETENTION" default:"7d"`
+	// ArchivedMetricsRetention purges an archived run's heartbeat history once it
+	// has been archived this long; zero never purges.
+	ArchivedMetricsRetention time.Duration `env:"ARCHIVED_METRICS_RETENTION"`
+	// HeartbeatBaselineMultiplier derives a run's stale threshold from its p95
+	// heartbeat gap once HeartbeatBaselineMinSamples gaps are on record, never
+	// below HeartbeatBaselineFloor; zero keeps the fixed thresholds.
+	HeartbeatBaselineMultiplier float64       `env:"HEARTBEAT_BASELINE_MULTIPLIER" default:"4"`
+	HeartbeatBaselineMinSamples int           `env:"HEARTBEAT_BASELINE_MIN_SAMPLES" default:"10"`
+	HeartbeatBaselineFloor      time.Duration `env:"HEARTBEAT_BASELINE_FLOOR" default:"15s"`
 }
 
-// Load loads configuration from environment variables
-func Load() (*Config, error) {
-	cfg := &Config{
-		Server: ServerConfig{
-			Port:            getEnvInt("PORT", 8080),
-			Host:            getEnvString("HOST", "0.0.0.0"),
-			ReadTimeout:     getEnvDuration("READ_TIMEOUT", 30*time.Second),
-			WriteTimeout:    getEnvDuration("WRITE_TIMEOUT", 30*time.Second),
-			ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
-		},
-		Database: DatabaseConfig{
-			Host:     getEnvString("DB_HOST", "localhost"),
-			Port:     getEnvInt("DB_PORT", 5432),
-			User:     getEnvString("DB_USER", "postgres"),
-			Password: getEnvString("DB_PASSWORD", ""),
-			DBName:   getEnvString("DB_NAME", "cartridge"),
-			SSLMode:  getEnvString("DB_SSL_MODE", "disable"),
-		},
-		NATS: NATSConfig{
-			URL:     getEnvString("NATS_URL", "nats://localhost:4222"),
-			Subject: getEnvString("NATS_SUBJECT", "run-status"),
-		},
-		Health: HealthConfig{
-			CheckInterval:         getEnvDuration("HEALTH_CHECK_INTERVAL", 15*time.Second),
-			HeartbeatStaleAfter:   getEnvDuration("HEARTBEAT_STALE_AFTER", 45*time.Second),
-			HeartbeatUnresponsive: getEnvDuration("HEARTBEAT_UNRESPONSIVE", 135*time.Second),
-		},
-	}
+// CommandsConfig holds control command delivery configuration
+type CommandsConfig struct {
+	// AckTimeout is how long a delivered command may stay unacknowledged before it
+	// is redelivered; zero disables redelivery.
+	AckTimeout          time.Duration `env:"COMMAND_ACK_TIMEOUT" default:"2m"`
+	MaxDeliveryAttempts int           `env:"COMMAND_MAX_DELIVERY_ATTEMPTS" default:"5"`
+	// Serial holds back a run's next command until the previous delivery is
+	// acknowledged, so that sequences such as tune then pause cannot reorder.
+	Serial bool `yaml:"serial_delivery" env:"COMMAND_SERIAL_DELIVERY" default:"true"`
+	// Push also publishes queued commands to each run's NATS command subject;
+	// learners that poll keep working.
+	Push bool `env:"COMMAND_PUSH"`
+}
 
-	return cfg, nil
+// SchedulerConfig holds cron schedule evaluation and run dispatch configuration
+type SchedulerConfig struct {
+	Interval         time.Duration `env:"SCHEDULER_INTERVAL" default:"15s"`
+	DispatchInterval time.Duration `env:"DISPATCH_INTERVAL" default:"5s"`
+	// LearnerStaleAfter excludes learners that stopped heartbeating from dispatch.
+	LearnerStaleAfter time.Duration `env:"LEARNER_STALE_AFTER" default:"1m"`
+	// PreemptionMinPriorityGap lets a queued run pause a running run at least this
+	// much lower in priority when no learner has room; zero disables preemption.
+	PreemptionMinPriorityGap int `env:"PREEMPTION_MIN_PRIORITY_GAP"`
+	PreemptionMaxPerPass     int `env:"PREEMPTION_MAX_PER_PASS" default:"1"`
+	// WatchdogInterval is how often running runs are checked against their max
+	// duration. DefaultMaxDuration caps runs created without one; zero leaves
+	// them unbounded.
+	WatchdogInterval   time.Duration `env:"WATCHDOG_INTERVAL" default:"1m"`
+	DefaultMaxDuration time.Duration `env:"RUN_DEFAULT_MAX_DURATION"`
 }
 
-// ConnectionString returns the database connection string
-func (d DatabaseConfig) ConnectionString() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		d.Host, d.Port, d.User, d.Password, d.DBName, d.SSLMode)
+// ArtifactsConfig holds the S3-compatible artifact store configuration
+type ArtifactsConfig struct {
+	// Bucket enables the artifact endpoints when set.
+	Bucket          string        `env:"ARTIFACT_BUCKET"`
+	Endpoint        string        `env:"ARTIFACT_ENDPOINT"`
+	Region          string        `env:"ARTIFACT_REGION" default:"us-east-1"`
+	AccessKeyID     string        `env:"ARTIFACT_ACCESS_KEY_ID"`
+	SecretAccessKey string        `env:"ARTIFACT_SECRET_ACCESS_KEY"`
+	PathStyle       bool          `env:"ARTIFACT_PATH_STYLE"`
+	URLExpiry       time.Duration `env:"ARTIFACT_URL_EXPIRY" default:"15m"`
 }
 
-func getEnvString(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+// LeaderConfig holds leader election configuration for running several replicas
+type LeaderConfig struct {
+	// Enabled gates the background loops on a PostgreSQL advisory lock so only
+	// one replica runs them; it uses the Database connection settings.
+	Enabled       bool          `env:"LEADER_ELECTION_ENABLED"`
+	LockName      string        `env:"LEADER_LOCK_NAME" default:"cartridge-orchestrator"`
+	RetryInterval time.Duration `env:"LEADER_RETRY_INTERVAL" default:"5s"`
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
+// AuthConfig holds API authentication configuration
+type AuthConfig struct {
+	Enabled bool `env:"AUTH_ENABLED"`
+	// APIKeys is a comma-separated list of name:role:secret entries.
+	APIKeys string `env:"AUTH_API_KEYS"`
+	OIDC    OIDCConfig
+	// RunTokenSecret, when set, signs per-run tokens that learners must present
+	// on heartbeat and command endpoints. It applies even with Enabled unset.
+	RunTokenSecret string `env:"RUN_TOKEN_SECRET"`
+}
+
+// OIDCConfig holds settings for validating JWTs issued by an OIDC provider
+type OIDCConfig struct {
+	Issuer     string `env:"OIDC_ISSUER"`
+	Audience   string `env:"OIDC_AUDIENCE"`
+	JWKSURL    string `yaml:"jwks_url" env:"OIDC_JWKS_URL"`
+	RolesClaim string `env:"OIDC_ROLES_CLAIM" default:"roles"`
+	RunClaim   string `env:"OIDC_RUN_CLAIM" default:"run_id"`
+}
+
+// Load loads configuration from its defaults, overridden by the YAML file named
+// by CONFIG_FILE when it is set, then by environment variables.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	if err := conf.Load(cfg); err != nil {
+		return nil, err
 	}
-	return defaultValue
+	return cfg, nil
 }
 
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+// Validate checks the settings that depend on one another. Errors name settings
+// by their path in the configuration file.
+func (c *Config) Validate() error {
+	var errs conf.Errors
+	switch c.Events.Backend {
+	case "noop", "nats", "redis", "webhook":
+	default:
+		errs.Add("events.backend", "unsupported backend %q", c.Events.Backend)
+	}
+	if c.Commands.MaxDeliveryAttempts < 1 {
+		errs.Add("commands.max_delivery_attempts", "must be at least 1")
+	}
+	if c.Commands.Push && (c.Events.Backend != "nats" || c.Commands.AckTimeout <= 0) {
+		errs.Add("commands.push", "requires the nats events backend and a positive commands.ack_timeout")
+	}
+	if c.Health.HeartbeatBaselineMultiplier < 0 {
+		errs.Add("health.heartbeat_baseline_multiplier", "must not be negative")
+	}
+	if c.RateLimit.RequestsPerSecond < 0 {
+		errs.Add("rate_limit.requests_per_second", "must not be negative")
+	}
+	if c.RateLimit.RequestsPerSecond > 0 && c.RateLimit.Burst < 1 {
+		errs.Add("rate_limit.burst", "must be at least 1")
+	}
+	if _, err := regexp.Compile(c.Admission.RunIDPattern); err != nil {
+		errs.Add("admission.run_id_pattern", "%v", err)
+	}
+	if c.Admission.ExperimentBudget < 0 {
+		errs.Add("admission.experiment_budget", "must not be negative")
+	}
+	for class, rate := range c.Cost.HourlyRates {
+		if rate < 0 {
+			errs.Add("cost.hourly_rates", "negative rate for class %q", class)
+		}
+	}
+	if c.Scheduler.WatchdogInterval <= 0 {
+		errs.Add("scheduler.watchdog_interval", "must be positive")
+	}
+	if c.Scheduler.DefaultMaxDuration != 0 && c.Scheduler.DefaultMaxDuration < time.Minute {
+		errs.Add("scheduler.default_max_duration", "must be at least 1m")
+	}
+	if c.Leader.Enabled && c.Leader.RetryInterval <= 0 {
+		errs.Add("leader.retry_interval", "must be positive")
+	}
+	switch c.Launcher.Backend {
+	case "":
+	case "docker":
+		if c.Launcher.LearnerImage == "" {
+			errs.Add("launcher.learner_image", "is required with the docker backend")
 		}
+	default:
+		errs.Add("launcher.backend", "unsupported backend %q", c.Launcher.Backend)
+	}
+	if c.Replay.Addr != "" && c.Replay.PollInterval <= 0 {
+		errs.Add("replay.poll_interval", "must be positive")
 	}
-	return defaultValue
+	if c.Replay.Addr != "" && c.Replay.StallAfter <= 0 {
+		errs.Add("replay.stall_after", "must be positive")
+	}
+	if c.Capture.MaxSize < 0 {
+		errs.Add("capture.max_size", "must not be negative")
+	}
+	if c.Auth.Enabled && c.Auth.APIKeys == "" && c.Auth.OIDC.Issuer == "" {
+		errs.Add("auth.enabled", "requires auth.api_keys or auth.oidc.issuer")
+	}
+	return errs.Err()
+}
+
+// LauncherConfig holds the run launcher configuration
+type LauncherConfig struct {
+	// Backend is "" (runs are launched externally) or "docker".
+	Backend         string        `env:"LAUNCHER_BACKEND"`
+	Interval        time.Duration `env:"LAUNCHER_INTERVAL" default:"5s"`
+	DockerHost      string        `env:"DOCKER_HOST" default:"unix:///var/run/docker.sock"`
+	LearnerImage    string        `env:"LAUNCHER_LEARNER_IMAGE"`
+	ActorImage      string        `env:"LAUNCHER_ACTOR_IMAGE"`
+	ActorReplicas   int           `env:"LAUNCHER_ACTOR_REPLICAS" default:"1"`
+	Network         string        `env:"LAUNCHER_NETWORK"`
+	OrchestratorURL string        `env:"LAUNCHER_ORCHESTRATOR_URL" default:"http://host.docker.internal:8080"`
+	StopTimeout     time.Duration `env:"LAUNCHER_STOP_TIMEOUT" default:"30s"`
+}
+
+// ReplayConfig holds replay service polling configuration
+type ReplayConfig struct {
+	// Addr is the replay service's gRPC address; empty disables polling.
+	Addr         string        `env:"REPLAY_ADDR"`
+	PollInterval time.Duration `env:"REPLAY_POLL_INTERVAL" default:"30s"`
+	// StallAfter alerts on a running run whose environment has produced no
+	// transitions for this long.
+	StallAfter time.Duration `env:"REPLAY_STALL_AFTER" default:"5m"`
+	// Capacity matches the replay service's max_size and is used to report
+	// buffer fill; zero leaves fill unreported.
+	Capacity uint64 `env:"REPLAY_CAPACITY" default:"100000"`
+}
+
+// CostConfig holds run accounting configuration
+type CostConfig struct {
+	// HourlyRates prices runs by their manifest's resources.class. In the
+	// environment it is COST_HOURLY_RATES, comma-separated class=rate pairs.
+	HourlyRates map[string]float64 `env:"COST_HOURLY_RATES"`
+}
+
+// RateLimitConfig holds per-client API rate limiting configuration
+type RateLimitConfig struct {
+	// RequestsPerSecond is each client's sustained request rate; zero disables
+	// rate limiting. Burst is how many requests a client may make at once.
+	RequestsPerSecond float64 `env:"RATE_LIMIT_RPS"`
+	Burst             int     `env:"RATE_LIMIT_BURST" default:"20"`
+}
+
+// AdmissionConfig holds the policies runs must pass before they are created
+type AdmissionConfig struct {
+	// RunIDPattern is a regular expression every run ID must match.
+	RunIDPattern string `env:"ADMISSION_RUN_ID_PATTERN"`
+	// AllowedEnvs restricts the manifest's game.env_id when non-empty.
+	AllowedEnvs []string `env:"ADMISSION_ALLOWED_ENVS"`
+	// ExperimentBudget refuses new runs once an experiment's runs have cost this
+	// much; zero disables the check.
+	ExperimentBudget float64 `env:"ADMISSION_EXPERIMENT_BUDGET"`
+	// WebhookURL, when set, is asked to review every run.
+	WebhookURL      string        `env:"ADMISSION_WEBHOOK_URL"`
+	WebhookSecret   string        `env:"ADMISSION_WEBHOOK_SECRET"`
+	WebhookTimeout  time.Duration `env:"ADMISSION_WEBHOOK_TIMEOUT" default:"5s"`
+	WebhookFailOpen bool          `env:"ADMISSION_WEBHOOK_FAIL_OPEN"`
+}
+
+// CaptureConfig holds heartbeat traffic capture configuration
+type CaptureConfig struct {
+	// File records sanitized heartbeat requests for cmd/apireplay; empty
+	// disables capture. Recording stops once the file reaches MaxSize.
+	File    string    `env:"CAPTURE_FILE"`
+	MaxSize conf.Size `env:"CAPTURE_MAX_SIZE" default:"1GiB"`
+}
+
+// ConnectionString returns the database connection string
+func (d DatabaseConfig) ConnectionString() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.DBName, d.SSLMode)
 }
\ No newline at end of file
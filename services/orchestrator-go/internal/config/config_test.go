@@ -0,0 +1,100 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orchestrator.yaml")
+	writeConfigFile(t, path, "# tuned for the staging cluster\nhealth:\n  heartbeat_stale_after: 30s\n  heartbeat_unresponsive: 2m\nrate_limit:\n  requests_per_second: 5\ncost:\n  hourly_rates: {gpu: 2.5}\n")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("HEARTBEAT_UNRESPONSIVE", "3m")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Health.HeartbeatStaleAfter != 30*time.Second || cfg.Health.HeartbeatUnresponsive != 3*time.Minute {
+		t.Fatalf("expected the environment to override the file, got %+v", cfg.Health)
+	}
+	if cfg.RateLimit.RequestsPerSecond != 5 || cfg.Cost.HourlyRates["gpu"] != 2.5 || cfg.Server.Port != 8080 {
+		t.Fatalf("expected file settings over the defaults, got %+v", cfg)
+	}
+
+	writeConfigFile(t, path, "health:\n  heartbeat_stale: 30s\n")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "health.heartbeat_stale: unknown setting") {
+		t.Fatalf("expected an unknown setting to be rejected, got %v", err)
+	}
+	writeConfigFile(t, path, "events:\n  backend: kafka\n")
+	t.Setenv("COMMAND_MAX_DELIVERY_ATTEMPTS", "0")
+	_, err = Load()
+	for _, want := range []string{
+		`events.backend: unsupported backend "kafka" (from file ` + path + ")",
+		"commands.max_delivery_attempts: must be at least 1 (from env COMMAND_MAX_DELIVERY_ATTEMPTS)",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+}
+
+func TestReloaderAppliesReloadableSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orchestrator.yaml")
+	writeConfigFile(t, path, "health: {heartbeat_stale_after: 45s}\nserver: {port: 8080}\ndatabase: {password: first}\n")
+	t.Setenv("CONFIG_FILE", path)
+	initial, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	var applied []*Config
+	reloader := NewReloader(initial, func(cfg *Config) { applied = append(applied, cfg) }, *zerolog.New(io.Discard))
+
+	writeConfigFile(t, path, "health: {heartbeat_stale_after: 20s}\nserver: {port: 9090}\ndatabase: {password: second}\nrate_limit: {requests_per_second: 10}\n")
+	changes, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	got := make(map[string]Change)
+	for _, change := range changes {
+		got[change.Setting] = change
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected four changed settings, got %+v", changes)
+	}
+	if stale := got["Health.HeartbeatStaleAfter"]; !stale.Applied || stale.Old != "45s" || stale.New != "20s" {
+		t.Fatalf("expected the stale threshold to be applied, got %+v", stale)
+	}
+	if !got["RateLimit.RequestsPerSecond"].Applied || got["Server.Port"].Applied {
+		t.Fatalf("expected only reloadable settings applied, got %+v", changes)
+	}
+	if password := got["Database.Password"]; password.Applied || password.Old != redacted || password.New != redacted {
+		t.Fatalf("expected the password change redacted, got %+v", password)
+	}
+	if len(applied) != 1 || applied[0].Health.HeartbeatStaleAfter != 20*time.Second || applied[0].Server.Port != 8080 {
+		t.Fatalf("expected the running port kept, got %+v", applied)
+	}
+
+	changes, err = reloader.Reload()
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("expected the restart-only settings reported again, got %+v %v", changes, err)
+	}
+
+	writeConfigFile(t, path, "rate_limit: {requests_per_second: -1}\n")
+	if _, err := reloader.Reload(); err == nil || len(applied) != 2 {
+		t.Fatalf("expected an invalid configuration to be rejected unapplied, got %v after %d applies", err, len(applied))
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Change is a setting whose value differs between two configurations. Applied
+// reports whether a reload put it into effect; other settings need a restart.
+type Change struct {
+	Setting string `json:"setting"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Applied bool   `json:"applied"`
+}
+
+// redacted replaces the values of secret settings in a diff.
+const redacted = "[redacted]"
+
+// Diff lists the settings that differ between before and after, named by their
+// field path such as Health.HeartbeatStaleAfter. Secrets are reported changed
+// without their values.
+func Diff(before, after *Config) []Change {
+	var changes []Change
+	diffValues("", reflect.ValueOf(*before), reflect.ValueOf(*after), &changes)
+	return changes
+}
+
+func diffValues(path string, before, after reflect.Value, changes *[]Change) {
+	if before.Kind() == reflect.Struct {
+		for i := 0; i < before.NumField(); i++ {
+			name := before.Type().Field(i).Name
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValues(name, before.Field(i), after.Field(i), changes)
+		}
+		return
+	}
+	if reflect.DeepEqual(before.Interface(), after.Interface()) {
+		return
+	}
+	change := Change{Setting: path, Old: fmt.Sprint(before.Interface()), New: fmt.Sprint(after.Interface())}
+	if secret(path) {
+		change.Old, change.New = redacted, redacted
+	}
+	*changes = append(*changes, change)
+}
+
+func secret(path string) bool {
+	for _, marker := range []string{"Password", "Secret", "APIKeys"} {
+		if strings.Contains(path, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// withReloadable returns c with the settings that can change at runtime taken
+// from next: the health monitor's heartbeat thresholds and the API rate limits.
+func (c Config) withReloadable(next *Config) Config {
+	c.Health.HeartbeatStaleAfter = next.Health.HeartbeatStaleAfter
+	c.Health.HeartbeatUnresponsive = next.Health.HeartbeatUnresponsive
+	c.Health.HeartbeatBaselineMultiplier = next.Health.HeartbeatBaselineMultiplier
+	c.Health.HeartbeatBaselineMinSamples = next.Health.HeartbeatBaselineMinSamples
+	c.Health.HeartbeatBaselineFloor = next.Health.HeartbeatBaselineFloor
+	c.RateLimit = next.RateLimit
+	return c
+}
+
+// Reloader re-reads the configuration on demand and applies the settings that
+// can change without a restart.
+type Reloader struct {
+	mu      sync.Mutex
+	current *Config
+	load    func() (*Config, error)
+	apply   func(*Config)
+	logger  zerolog.Logger
+}
+
+// NewReloader starts from current, the configuration the process was started
+// with. apply is handed each reloaded configuration and must put its reloadable
+// settings into effect.
+func NewReloader(current *Config, apply func(*Config), logger zerolog.Logger) *Reloader {
+	return &Reloader{current: current, load: Load, apply: apply, logger: logger}
+}
+
+// Reload loads the configuration again and applies it, returning every setting
+// that changed. A configuration that fails to load or validate is rejected and
+// the running one kept. Changes to settings that need a restart are reported,
+// and logged, but not applied; they are reported again on later reloads.
+func (r *Reloader) Reload() ([]Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next, err := r.load()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("configuration reload rejected")
+		return nil, err
+	}
+	applied := r.current.withReloadable(next)
+	pending := make(map[string]bool)
+	for _, change := range Diff(&applied, next) {
+		pending[change.Setting] = true
+	}
+	changes := Diff(r.current, next)
+	for i := range changes {
+		changes[i].Applied = !pending[changes[i].Setting]
+	}
+	r.apply(&applied)
+	r.current = &applied
+	for _, change := range changes {
+		event := r.logger.Info()
+		msg := "configuration setting reloaded"
+		if !change.Applied {
+			event = r.logger.Warn()
+			msg = "configuration setting changed; restart to apply"
+		}
+		event.Str("setting", change.Setting).Str("old", change.Old).Str("new", change.New).Msg(msg)
+	}
+	r.logger.Info().Int("changes", len(changes)).Msg("configuration reloaded")
+	return changes, nil
+}
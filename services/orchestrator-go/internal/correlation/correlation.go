@@ -0,0 +1,31 @@
+// Package correlation carries the ID that ties an API request to the logs,
+// audit entries and events it produces.
+package correlation
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header (and NATS message header) carrying the ID.
+const Header = "X-Correlation-ID"
+
+type contextKey struct{}
+
+// NewID returns a fresh correlation ID.
+func NewID() string {
+	return uuid.New().String()
+}
+
+// WithID stores id on the context.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the correlation ID stored on ctx, or "" when there is none,
+// e.g. for work started by a background loop.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
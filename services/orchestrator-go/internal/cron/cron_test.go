@@ -0,0 +1,49 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 17, 42, 0, time.UTC) // a Wednesday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * mon-fri", time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)}, // Friday or the 13th
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 6-7", time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", tc.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tc.want) {
+			t.Errorf("%s: next = %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+
+	never, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := never.Next(from); !got.IsZero() {
+		t.Errorf("expected impossible schedule to never fire, got %s", got)
+	}
+}
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "* * * smarch *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
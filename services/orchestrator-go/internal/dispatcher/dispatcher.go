@@ -0,0 +1,69 @@
+package dispatcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/service"
+)
+
+// Dispatcher periodically places queued runs onto learners with free slots.
+type Dispatcher struct {
+	orch     *service.Orchestrator
+	interval time.Duration
+	logger   zerolog.Logger
+	onTick   func()
+}
+
+// New creates a dispatcher that places queued runs every interval.
+func New(orch *service.Orchestrator, interval time.Duration, logger zerolog.Logger) *Dispatcher {
+	return &Dispatcher{orch: orch, interval: interval, logger: logger}
+}
+
+// OnTick registers fn to be called when the loop starts and after every pass,
+// letting liveness probes confirm it is making progress.
+func (d *Dispatcher) OnTick(fn func()) {
+	d.onTick = fn
+}
+
+// Start runs the dispatch loop until ctx is cancelled.
+func (d *Dispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	d.logger.Info().Dur("interval", d.interval).Msg("Starting run dispatcher")
+	d.beat()
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info().Msg("Run dispatcher stopped")
+			return
+		case <-ticker.C:
+			d.tick(ctx)
+			d.beat()
+		}
+	}
+}
+
+func (d *Dispatcher) beat() {
+	if d.onTick != nil {
+		d.onTick()
+	}
+}
+
+func (d *Dispatcher) tick(ctx context.Context) {
+	runs, err := d.orch.DispatchQueuedRuns(ctx)
+	if err != nil {
+		d.logger.Error().Err(err).Msg("Failed to dispatch queued runs")
+		return
+	}
+	for _, run := range runs {
+		d.logger.Info().
+			Str("run_id", run.ID).
+			Str("learner_id", run.LearnerID).
+			Int("priority", run.Priority).
+			Msg("Dispatched run")
+	}
+}
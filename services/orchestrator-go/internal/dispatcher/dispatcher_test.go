@@ -0,0 +1,244 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func TestDispatchHonoursPriorityAndCapacity(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+
+	base := time.Now()
+	for i, spec := range []struct {
+		id       string
+		priority int
+	}{{"run-low", 0}, {"run-high", 10}, {"run-mid", 5}} {
+		at := base.Add(time.Duration(i) * time.Second)
+		orch.WithNow(func() time.Time { return at })
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: spec.id, ExperimentID: "exp-1", VersionID: "ver-1", Priority: spec.priority}); err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+	}
+	orch.WithNow(time.Now)
+
+	if run, _ := orch.GetRun(ctx, "run-low"); run.QueuePosition != 3 {
+		t.Fatalf("expected lowest priority run third in queue, got %d", run.QueuePosition)
+	}
+
+	d := New(orch, time.Second, *logger)
+	d.tick(ctx)
+	if run, _ := orch.GetRun(ctx, "run-high"); run.State != types.RunStateQueued {
+		t.Fatalf("expected no dispatch without learners, got %s", run.State)
+	}
+
+	if _, err := orch.RegisterLearner(ctx, service.RegisterLearnerInput{ID: "learner-a", Slots: 2}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	d.tick(ctx)
+	for id, want := range map[string]types.RunState{"run-high": types.RunStateProvisioning, "run-mid": types.RunStateProvisioning, "run-low": types.RunStateQueued} {
+		run, _ := orch.GetRun(ctx, id)
+		if run.State != want {
+			t.Fatalf("%s: expected %s, got %s", id, want, run.State)
+		}
+		if want == types.RunStateProvisioning && run.LearnerID != "learner-a" {
+			t.Fatalf("%s: expected assignment to learner-a, got %q", id, run.LearnerID)
+		}
+	}
+	if run, _ := orch.GetRun(ctx, "run-low"); run.QueuePosition != 1 {
+		t.Fatalf("expected remaining run at head of queue, got %d", run.QueuePosition)
+	}
+
+	// Finishing a run frees its slot for the next queued run.
+	for _, action := range []types.RunAction{types.RunActionStart, types.RunActionComplete} {
+		if _, err := orch.PerformAction(ctx, "run-high", action, "tester", ""); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+	}
+	d.tick(ctx)
+	if run, _ := orch.GetRun(ctx, "run-low"); run.State != types.RunStateProvisioning {
+		t.Fatalf("expected run-low dispatched after a slot freed, got %s", run.State)
+	}
+	if err := orch.DeregisterLearner(ctx, "learner-a"); err == nil {
+		t.Fatalf("expected deregistering a busy learner to fail")
+	}
+}
+
+func TestDispatchMatchesCapabilities(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	orch.WithLearnerStaleAfter(time.Minute)
+
+	manifests := map[string]string{
+		"run-gpu":   `{"resources": {"gpus": 2}, "game": {"env_id": "generals"}}`,
+		"run-atari": `{"game": {"env_id": "atari"}}`,
+		"run-huge":  `{"resources": {"gpus": 8}}`,
+	}
+	for id, manifest := range manifests {
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: id, ExperimentID: "exp-1", VersionID: "ver-1", LaunchManifest: json.RawMessage(manifest)}); err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+	}
+	for _, input := range []service.RegisterLearnerInput{
+		{ID: "gpu-box", Slots: 4, Capabilities: types.LearnerCapabilities{GPUs: 2, Envs: []string{"generals"}}},
+		{ID: "cpu-box", Slots: 4, Capabilities: types.LearnerCapabilities{Envs: []string{"atari"}}},
+	} {
+		if _, err := orch.RegisterLearner(ctx, input); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+	}
+
+	New(orch, time.Second, *logger).tick(ctx)
+	for id, want := range map[string]string{"run-gpu": "gpu-box", "run-atari": "cpu-box", "run-huge": ""} {
+		run, _ := orch.GetRun(ctx, id)
+		if run.LearnerID != want {
+			t.Fatalf("%s: expected learner %q, got %q", id, want, run.LearnerID)
+		}
+	}
+	gpuBox, _ := orch.GetLearner(ctx, "gpu-box")
+	if gpuBox.UsedGPUs != 2 || gpuBox.ActiveRuns != 1 {
+		t.Fatalf("expected gpu-box usage to reflect its run, got %+v", gpuBox)
+	}
+
+	if _, err := orch.LearnerHeartbeat(ctx, "cpu-box", types.LearnerHeartbeat{LearnerLoad: types.LearnerLoad{GPUUtilization: 1.5}}); err == nil {
+		t.Fatalf("expected out-of-range load to be rejected")
+	}
+	if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: "run-late", ExperimentID: "exp-1", VersionID: "ver-1"}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	orch.WithNow(func() time.Time { return time.Now().Add(5 * time.Minute) })
+	New(orch, time.Second, *logger).tick(ctx)
+	if run, _ := orch.GetRun(ctx, "run-late"); run.State != types.RunStateQueued {
+		t.Fatalf("expected no dispatch to stale learners, got %s on %s", run.State, run.LearnerID)
+	}
+}
+
+func TestDispatchHoldsRunsForDependencies(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+
+	for _, spec := range []struct {
+		id        string
+		dependsOn []string
+	}{{"train", nil}, {"eval", []string{"train"}}, {"report", []string{"eval"}}, {"flaky", nil}, {"downstream", []string{"flaky", "train"}}} {
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: spec.id, ExperimentID: "exp-1", VersionID: "ver-1", DependsOn: spec.dependsOn}); err != nil {
+			t.Fatalf("create %s: %v", spec.id, err)
+		}
+	}
+	if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: "orphan", ExperimentID: "exp-1", VersionID: "ver-1", DependsOn: []string{"missing"}}); err == nil {
+		t.Fatalf("expected unknown dependency to be rejected")
+	}
+	if _, err := orch.RegisterLearner(ctx, service.RegisterLearnerInput{ID: "learner-a", Slots: 8}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	states := func(want map[string]types.RunState) {
+		t.Helper()
+		for id, state := range want {
+			if run, _ := orch.GetRun(ctx, id); run.State != state {
+				t.Fatalf("%s: expected %s, got %s", id, state, run.State)
+			}
+		}
+	}
+
+	d := New(orch, time.Second, *logger)
+	d.tick(ctx)
+	states(map[string]types.RunState{"train": types.RunStateProvisioning, "flaky": types.RunStateProvisioning, "eval": types.RunStateQueued, "report": types.RunStateQueued, "downstream": types.RunStateQueued})
+	if _, err := orch.PerformAction(ctx, "eval", types.RunActionProvision, "tester", ""); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected provisioning a blocked run to conflict, got %v", err)
+	}
+
+	for _, action := range []types.RunAction{types.RunActionStart, types.RunActionComplete} {
+		if _, err := orch.PerformAction(ctx, "train", action, "tester", ""); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+	}
+	if _, err := orch.PerformAction(ctx, "flaky", types.RunActionFail, "tester", ""); err != nil {
+		t.Fatalf("fail: %v", err)
+	}
+	d.tick(ctx)
+	states(map[string]types.RunState{"eval": types.RunStateProvisioning, "report": types.RunStateQueued, "downstream": types.RunStateFailed})
+	if run, _ := orch.GetRun(ctx, "downstream"); run.StatusMessage != "dependency flaky ended failed" {
+		t.Fatalf("unexpected failure reason %q", run.StatusMessage)
+	}
+	if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: "late", ExperimentID: "exp-1", VersionID: "ver-1", DependsOn: []string{"flaky"}}); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected depending on a failed run to conflict, got %v", err)
+	}
+}
+
+func TestDispatchPreemptsLowerPriorityRuns(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	orch.WithPreemption(service.PreemptionPolicy{MinPriorityGap: 5, MaxPerPass: 1})
+	d := New(orch, time.Second, *logger)
+
+	create := func(id string, priority int) {
+		t.Helper()
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: id, ExperimentID: "exp-1", VersionID: "ver-1", Priority: priority}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	if _, err := orch.RegisterLearner(ctx, service.RegisterLearnerInput{ID: "learner-a", Slots: 2}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	create("run-low", 0)
+	create("run-mid", 3)
+	d.tick(ctx)
+	for _, id := range []string{"run-low", "run-mid"} {
+		if _, err := orch.PerformAction(ctx, id, types.RunActionStart, "tester", ""); err != nil {
+			t.Fatalf("start %s: %v", id, err)
+		}
+	}
+
+	// run-next is not far enough above either running run to preempt it.
+	create("run-urgent", 10)
+	create("run-next", 7)
+	d.tick(ctx)
+	states := map[string]types.RunState{"run-urgent": types.RunStateProvisioning, "run-low": types.RunStatePaused, "run-mid": types.RunStateRunning, "run-next": types.RunStateQueued}
+	for id, want := range states {
+		if run, _ := orch.GetRun(ctx, id); run.State != want {
+			t.Fatalf("%s: expected %s, got %s", id, want, run.State)
+		}
+	}
+	low, _ := orch.GetRun(ctx, "run-low")
+	if low.PreemptedBy != "run-urgent" || low.LearnerID != "learner-a" {
+		t.Fatalf("expected run-low preempted by run-urgent, got %+v", low)
+	}
+	transitions, _ := orch.ListTransitions(ctx, "run-low")
+	if last := transitions[len(transitions)-1]; last.ChangedBy != "preemption" || last.ToState != types.RunStatePaused {
+		t.Fatalf("expected a preemption transition, got %+v", last)
+	}
+	if cmd, err := store.GetCommand(ctx, "run-low", "preempt-run-urgent"); err != nil || cmd.Type != types.CommandTypePause {
+		t.Fatalf("expected a pause command for the learner, got %+v (%v)", cmd, err)
+	}
+	if learner, _ := orch.GetLearner(ctx, "learner-a"); learner.ActiveRuns != 2 {
+		t.Fatalf("expected the preempted run to release its slot, got %d active", learner.ActiveRuns)
+	}
+
+	// Resuming reclaims the slot and clears the preemption marker.
+	resumed, err := orch.PerformAction(ctx, "run-low", types.RunActionResume, "tester", "")
+	if err != nil || resumed.PreemptedBy != "" {
+		t.Fatalf("resume: %+v (%v)", resumed, err)
+	}
+	if learner, _ := orch.GetLearner(ctx, "learner-a"); learner.ActiveRuns != 3 {
+		t.Fatalf("expected the resumed run to hold a slot again, got %d active", learner.ActiveRuns)
+	}
+}
@@ -0,0 +1,177 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cartridge/chaos"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func TestTransitionsAndCommandsFlow(t *testing.T) {
+	h := Start(t, Options{})
+	ctx := context.Background()
+	api := h.NewClient()
+
+	// An operator creates and starts a run training on the engine's environment.
+	var run types.Run
+	code, err := api.Do(ctx, http.MethodPost, "/runs", map[string]any{
+		"id":              "run-e2e",
+		"experiment_id":   "exp-e2e",
+		"version_id":      "ver-e2e",
+		"launch_manifest": map[string]any{"game": map[string]any{"env_id": h.Engine.EnvID}},
+	}, &run)
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("create run: %d %v", code, err)
+	}
+	for _, action := range []string{"provision", "start"} {
+		if code, err := api.Do(ctx, http.MethodPost, "/runs/run-e2e/"+action, nil, &run); err != nil || code != http.StatusOK {
+			t.Fatalf("%s run: %d %v", action, code, err)
+		}
+	}
+	if run.State != types.RunStateRunning {
+		t.Fatalf("expected a running run, got %s", run.State)
+	}
+
+	// Actor -> engine -> replay: every step the actor takes lands in replay.
+	stored, err := h.NewActor(t).Play(ctx, 3)
+	if err != nil {
+		t.Fatalf("actor: %v", err)
+	}
+	if want := 3 * 5; stored != want || h.Engine.Steps() != want {
+		t.Fatalf("expected %d transitions stored and engine steps, got %d and %d", want, stored, h.Engine.Steps())
+	}
+
+	// Replay -> orchestrator: the stats poller attributes them to the run.
+	Eventually(t, 5*time.Second, "replay stats on the run", func() bool {
+		var got struct {
+			Replay *types.ReplayStats `json:"replay"`
+		}
+		api.Do(ctx, http.MethodGet, "/runs/run-e2e/replay", nil, &got)
+		return got.Replay != nil && got.Replay.Transitions == uint64(stored)
+	})
+
+	// Learner -> orchestrator: heartbeats update the run.
+	if code, err := api.Do(ctx, http.MethodPost, "/runs/run-e2e/heartbeat", map[string]any{
+		"run_id": "run-e2e", "status": "running", "step": 40, "samples_per_sec": 250.0, "loss": 0.7,
+	}, nil); err != nil || code != http.StatusOK {
+		t.Fatalf("heartbeat: %d %v", code, err)
+	}
+	if code, err := api.Do(ctx, http.MethodGet, "/runs/run-e2e", nil, &run); err != nil || code != http.StatusOK {
+		t.Fatalf("get run: %d %v", code, err)
+	}
+	if run.CurrentStep != 40 || run.LastHeartbeatAt == nil {
+		t.Fatalf("expected the heartbeat recorded, got step %d at %v", run.CurrentStep, run.LastHeartbeatAt)
+	}
+
+	// Orchestrator -> learner: a command issued while the learner long-polls is
+	// delivered to it, then acknowledged with a result.
+	delivered := make(chan types.RunCommand, 1)
+	go func() {
+		var cmd types.RunCommand
+		if code, err := api.NextCommand(ctx, "run-e2e", 5*time.Second, &cmd); err != nil || code != http.StatusOK {
+			t.Errorf("next command: %d %v", code, err)
+		}
+		delivered <- cmd
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if code, err := api.Do(ctx, http.MethodPost, "/runs/run-e2e/commands", map[string]any{
+		"id":        "cmd-e2e",
+		"type":      "tune",
+		"issued_at": time.Now().UTC(),
+		"actor":     map[string]any{"type": "operator", "id": "e2e"},
+		"payload":   map[string]any{"learning_rate": 0.0003},
+	}, nil); err != nil || code != http.StatusAccepted {
+		t.Fatalf("create command: %d %v", code, err)
+	}
+	var cmd types.RunCommand
+	select {
+	case cmd = <-delivered:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the command to be delivered")
+	}
+	if cmd.ID != "cmd-e2e" || cmd.Type != types.CommandTypeTune {
+		t.Fatalf("expected cmd-e2e delivered, got %+v", cmd)
+	}
+	if code, err := api.Do(ctx, http.MethodPost, "/runs/run-e2e/commands/cmd-e2e/ack", map[string]any{
+		"status": "succeeded", "applied": map[string]any{"learning_rate": 0.0003},
+	}, &cmd); err != nil || code != http.StatusOK {
+		t.Fatalf("ack command: %d %v", code, err)
+	}
+	if cmd.Status() != types.CommandStatusAcked || cmd.Result == nil || cmd.Result.Status != types.CommandResultSucceeded {
+		t.Fatalf("expected an acked command with its result, got %s %+v", cmd.Status(), cmd.Result)
+	}
+}
+
+func TestInjectedFaults(t *testing.T) {
+	h := Start(t, Options{
+		HeartbeatStaleAfter: 200 * time.Millisecond,
+		CommandAckTimeout:   100 * time.Millisecond,
+		ReplayFaults:        chaos.Config{PartialRate: 1, Match: []string{"StoreBatch"}, Seed: 1},
+		APIFaults:           chaos.Config{DropRate: 1, Match: []string{"/heartbeat", "/ack"}},
+	})
+	ctx := context.Background()
+	api := h.NewClient()
+
+	if code, err := api.Do(ctx, http.MethodPost, "/runs", map[string]any{
+		"id":              "run-faults",
+		"experiment_id":   "exp-e2e",
+		"version_id":      "ver-e2e",
+		"launch_manifest": map[string]any{"game": map[string]any{"env_id": h.Engine.EnvID}},
+	}, nil); err != nil || code != http.StatusCreated {
+		t.Fatalf("create run: %d %v", code, err)
+	}
+	for _, action := range []string{"provision", "start"} {
+		if code, err := api.Do(ctx, http.MethodPost, "/runs/run-faults/"+action, nil, nil); err != nil || code != http.StatusOK {
+			t.Fatalf("%s run: %d %v", action, code, err)
+		}
+	}
+
+	// Partial StoreBatch failures: replay keeps only what it reports stored,
+	// and the run's replay stats agree with it rather than with the actor.
+	stored, err := h.NewActor(t).Play(ctx, 3)
+	if err != nil {
+		t.Fatalf("actor: %v", err)
+	}
+	if stored >= h.Engine.Steps() {
+		t.Fatalf("expected some of the %d transitions rejected, %d stored", h.Engine.Steps(), stored)
+	}
+	Eventually(t, 5*time.Second, "replay stats on the run", func() bool {
+		var got struct {
+			Replay *types.ReplayStats `json:"replay"`
+		}
+		api.Do(ctx, http.MethodGet, "/runs/run-faults/replay", nil, &got)
+		return got.Replay != nil && got.Replay.Transitions == uint64(stored)
+	})
+
+	// Dropped heartbeats: the learner sees a transport error, and the health
+	// monitor marks the silent run stale, then unresponsive.
+	if _, err := api.Do(ctx, http.MethodPost, "/runs/run-faults/heartbeat", map[string]any{"run_id": "run-faults", "status": "running", "step": 1}, nil); err == nil {
+		t.Fatal("expected the heartbeat to be dropped")
+	}
+	Eventually(t, 5*time.Second, "the run to go unresponsive", func() bool {
+		run, err := h.Store.GetRun(ctx, "run-faults")
+		return err == nil && run.LastHeartbeatAt == nil && run.HealthStatus == types.RunHealthUnresponsive
+	})
+
+	// Dropped acks: the command is redelivered once its ack timeout passes.
+	if code, err := api.Do(ctx, http.MethodPost, "/runs/run-faults/commands", map[string]any{
+		"id": "cmd-faults", "type": "pause", "actor": map[string]any{"type": "operator", "id": "e2e"},
+	}, nil); err != nil || code != http.StatusAccepted {
+		t.Fatalf("create command: %d %v", code, err)
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		var cmd types.RunCommand
+		if code, err := api.NextCommand(ctx, "run-faults", 5*time.Second, &cmd); err != nil || code != http.StatusOK {
+			t.Fatalf("delivery %d: %d %v", attempt, code, err)
+		}
+		if cmd.ID != "cmd-faults" || cmd.DeliveryAttempts != attempt {
+			t.Fatalf("expected delivery %d of cmd-faults, got %s attempt %d", attempt, cmd.ID, cmd.DeliveryAttempts)
+		}
+		if _, err := api.Do(ctx, http.MethodPost, "/runs/run-faults/commands/cmd-faults/ack", nil, nil); err == nil {
+			t.Fatal("expected the ack to be dropped")
+		}
+	}
+}
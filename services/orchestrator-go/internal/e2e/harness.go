@@ -0,0 +1,365 @@
+// Package e2e starts the Cartridge services in-process so tests can exercise
+// the paths between them: an actor playing a fake engine and storing its
+// transitions in the replay service, and a learner exchanging heartbeats and
+// commands with the orchestrator, which polls replay for each run's progress.
+//
+// The actor and learner are Go stand-ins for the Rust actor and the Python
+// learner. They speak the same protocols: the engine and replay gRPC contracts
+// and the orchestrator's HTTP API.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/cartridge/chaos"
+	enginev1 "github.com/cartridge/proto/engine/v1"
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/replay/replaytest"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/health"
+	httpServer "github.com/cartridge/orchestrator/internal/http"
+	"github.com/cartridge/orchestrator/internal/replay"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+)
+
+// ReplayCapacity is the size of the harness's replay buffer.
+const ReplayCapacity = 10000
+
+// Harness is a running set of services. Start stops them when the test ends.
+type Harness struct {
+	// OrchestratorURL is the base URL of the orchestrator's HTTP API.
+	OrchestratorURL string
+	// ReplayAddr and EngineAddr are the gRPC addresses of the replay service
+	// and the fake engine.
+	ReplayAddr string
+	EngineAddr string
+	// Store is the orchestrator's storage, for assertions the API does not
+	// expose.
+	Store *storage.MemoryStore
+	// Engine is the fake engine the actor plays.
+	Engine *FakeEngine
+}
+
+// Options tunes the harness.
+type Options struct {
+	// ReplayPollInterval is how often the orchestrator polls replay stats.
+	// Defaults to 20ms.
+	ReplayPollInterval time.Duration
+	// Horizon is the fake engine's episode length. Defaults to 5 steps.
+	Horizon uint32
+	// HeartbeatStaleAfter is how long a run may go without a heartbeat before
+	// the health monitor marks it stale, and half as long again unresponsive.
+	// Defaults to an hour, so runs stay healthy unless a test wants otherwise.
+	HeartbeatStaleAfter time.Duration
+	// CommandAckTimeout enables command redelivery after this long without an
+	// acknowledgement.
+	CommandAckTimeout time.Duration
+
+	// ReplayFaults are injected into the replay service, and APIFaults into
+	// the orchestrator's API.
+	ReplayFaults chaos.Config
+	APIFaults    chaos.Config
+}
+
+// Start runs the replay service, a fake engine and the orchestrator, with its
+// health monitor and replay stats poller, until t ends.
+func Start(t testing.TB, opts Options) *Harness {
+	t.Helper()
+	if opts.ReplayPollInterval == 0 {
+		opts.ReplayPollInterval = 20 * time.Millisecond
+	}
+	if opts.Horizon == 0 {
+		opts.Horizon = 5
+	}
+	if opts.HeartbeatStaleAfter == 0 {
+		opts.HeartbeatStaleAfter = time.Hour
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	t.Cleanup(func() {
+		cancel()
+		background.Wait()
+	})
+
+	replayServer := replaytest.NewServer(ReplayCapacity, replaytest.WithFaults(opts.ReplayFaults))
+	t.Cleanup(replayServer.Close)
+
+	engine := &FakeEngine{EnvID: "counter", Horizon: opts.Horizon}
+	engineAddr := serveGRPC(t, func(s *grpc.Server) { enginev1.RegisterEngineServer(s, engine) })
+
+	logger := zerolog.New(io.Discard)
+	store := storage.NewMemoryStore()
+	publisher := events.NoopPublisher{}
+	orch := service.NewOrchestrator(store, publisher, logger)
+	if opts.CommandAckTimeout > 0 {
+		orch.WithCommandRedelivery(opts.CommandAckTimeout, 5)
+	}
+	server := httpServer.NewServer(orch, logger)
+	server.WithFaults(chaos.New(opts.APIFaults))
+	api := httptest.NewServer(server.Routes())
+	t.Cleanup(api.Close)
+
+	monitor := health.NewMonitor(orch, publisher, health.Config{
+		CheckInterval:         10 * time.Millisecond,
+		HeartbeatStaleAfter:   opts.HeartbeatStaleAfter,
+		HeartbeatUnresponsive: opts.HeartbeatStaleAfter * 3 / 2,
+	}, *logger)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		monitor.Start(ctx)
+	}()
+
+	replayClient, err := replay.Dial(replayServer.Addr)
+	if err != nil {
+		t.Fatalf("e2e: dial replay: %v", err)
+	}
+	t.Cleanup(func() { replayClient.Close() })
+	poller := replay.NewPoller(orch, replayClient, publisher, replay.Config{
+		Interval:   opts.ReplayPollInterval,
+		StallAfter: time.Minute,
+		Capacity:   ReplayCapacity,
+	}, *logger)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		poller.Start(ctx)
+	}()
+
+	return &Harness{
+		OrchestratorURL: api.URL,
+		ReplayAddr:      replayServer.Addr,
+		EngineAddr:      engineAddr,
+		Store:           store,
+		Engine:          engine,
+	}
+}
+
+// serveGRPC serves the services register adds on a loopback port until t ends.
+func serveGRPC(t testing.TB, register func(*grpc.Server)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("e2e: listen: %v", err)
+	}
+	server := grpc.NewServer()
+	register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+// dial connects to a harness gRPC address, closing the connection when t ends.
+func dial(t testing.TB, addr string) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("e2e: dial %s: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// FakeEngine is an engine whose single-byte state counts the steps taken. Each
+// step rewards the action taken, and an episode ends after Horizon steps.
+type FakeEngine struct {
+	enginev1.UnimplementedEngineServer
+
+	EnvID   string
+	Horizon uint32
+
+	mu    sync.Mutex
+	steps int
+}
+
+// Steps returns the number of steps served.
+func (e *FakeEngine) Steps() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.steps
+}
+
+// GetCapabilities satisfies enginev1.EngineServer.
+func (e *FakeEngine) GetCapabilities(context.Context, *enginev1.EngineId) (*enginev1.Capabilities, error) {
+	return &enginev1.Capabilities{
+		Id: &enginev1.EngineId{EnvId: e.EnvID, BuildId: "e2e"},
+		Enc: &enginev1.Encoding{
+			State:         "counter:u8:v1",
+			Action:        "discrete:u8:v1",
+			Obs:           "counter:u8:v1",
+			SchemaVersion: 1,
+		},
+		MaxHorizon:  e.Horizon,
+		ActionSpace: &enginev1.Capabilities_DiscreteN{DiscreteN: 2},
+	}, nil
+}
+
+// Reset satisfies enginev1.EngineServer.
+func (e *FakeEngine) Reset(context.Context, *enginev1.ResetRequest) (*enginev1.ResetResponse, error) {
+	return &enginev1.ResetResponse{State: []byte{0}, Obs: []byte{0}}, nil
+}
+
+// Step satisfies enginev1.EngineServer.
+func (e *FakeEngine) Step(_ context.Context, req *enginev1.StepRequest) (*enginev1.StepResponse, error) {
+	if len(req.GetState()) != 1 || len(req.GetAction()) != 1 {
+		return nil, fmt.Errorf("state and action must be one byte each")
+	}
+	e.mu.Lock()
+	e.steps++
+	e.mu.Unlock()
+	next := req.GetState()[0] + 1
+	return &enginev1.StepResponse{
+		State:  []byte{next},
+		Obs:    []byte{next},
+		Reward: float32(req.GetAction()[0]),
+		Done:   uint32(next) >= e.Horizon,
+	}, nil
+}
+
+// Actor plays episodes against an engine and stores each one's transitions in
+// replay, as the actor service does.
+type Actor struct {
+	engine enginev1.EngineClient
+	replay replayv1.ReplayClient
+	envID  string
+}
+
+// NewActor returns an actor playing the harness's engine.
+func (h *Harness) NewActor(t testing.TB) *Actor {
+	t.Helper()
+	return &Actor{
+		engine: enginev1.NewEngineClient(dial(t, h.EngineAddr)),
+		replay: replayv1.NewReplayClient(dial(t, h.ReplayAddr)),
+		envID:  h.Engine.EnvID,
+	}
+}
+
+// Play plays episodes to completion, storing each as one batch, and returns
+// the number of transitions replay accepted.
+func (a *Actor) Play(ctx context.Context, episodes int) (int, error) {
+	caps, err := a.engine.GetCapabilities(ctx, &enginev1.EngineId{EnvId: a.envID})
+	if err != nil {
+		return 0, fmt.Errorf("get capabilities: %w", err)
+	}
+	stored := 0
+	for episode := 0; episode < episodes; episode++ {
+		episodeID := fmt.Sprintf("episode-%d", episode)
+		reset, err := a.engine.Reset(ctx, &enginev1.ResetRequest{Id: caps.GetId(), Seed: uint64(episode)})
+		if err != nil {
+			return stored, fmt.Errorf("reset: %w", err)
+		}
+		state, obs := reset.GetState(), reset.GetObs()
+		var batch []*replayv1.Transition
+		for step := uint32(0); step < caps.GetMaxHorizon(); step++ {
+			action := []byte{byte(step % caps.GetDiscreteN())}
+			next, err := a.engine.Step(ctx, &enginev1.StepRequest{Id: caps.GetId(), State: state, Action: action})
+			if err != nil {
+				return stored, fmt.Errorf("step: %w", err)
+			}
+			batch = append(batch, &replayv1.Transition{
+				EnvId:           a.envID,
+				EpisodeId:       episodeID,
+				StepNumber:      step,
+				State:           state,
+				Action:          action,
+				NextState:       next.GetState(),
+				Observation:     obs,
+				NextObservation: next.GetObs(),
+				Reward:          next.GetReward(),
+				Done:            next.GetDone(),
+				Priority:        1,
+			})
+			state, obs = next.GetState(), next.GetObs()
+			if next.GetDone() {
+				break
+			}
+		}
+		resp, err := a.replay.StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: batch})
+		if err != nil {
+			return stored, fmt.Errorf("store batch: %w", err)
+		}
+		stored += int(resp.GetStoredCount())
+	}
+	return stored, nil
+}
+
+// Client calls the orchestrator's HTTP API, as operators and learners do.
+type Client struct {
+	base string
+	http *http.Client
+}
+
+// NewClient returns a client for the harness's orchestrator.
+func (h *Harness) NewClient() *Client {
+	return &Client{base: h.OrchestratorURL + "/api/v1", http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Do sends body, if not nil, as JSON to path and decodes a JSON response into
+// out, if not nil. It returns the status code.
+func (c *Client) Do(ctx context.Context, method, path string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, err
+	}
+	if out != nil && res.StatusCode < 300 && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return res.StatusCode, fmt.Errorf("decode %s %s: %w", method, path, err)
+		}
+	}
+	return res.StatusCode, nil
+}
+
+// NextCommand long-polls for up to wait for the run's next command.
+func (c *Client) NextCommand(ctx context.Context, runID string, wait time.Duration, out any) (int, error) {
+	return c.Do(ctx, http.MethodGet, "/runs/"+url.PathEscape(runID)+"/commands/next?wait="+wait.String(), nil, out)
+}
+
+// Eventually polls cond every 10ms until it holds, failing t if it does not
+// within timeout.
+func Eventually(t testing.TB, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("e2e: timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
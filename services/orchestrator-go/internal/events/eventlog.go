@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// Event types recorded in the event log.
+const (
+	EventTypeRunStatus  = "run_status"
+	EventTypeCommand    = "command"
+	EventTypeAccounting = "accounting"
+	EventTypeAlert      = "alert"
+)
+
+// EventLog persists published events. storage.EventStore implements it.
+type EventLog interface {
+	AppendRunEvent(ctx context.Context, event types.RunEvent) (types.RunEvent, error)
+}
+
+// Persisting returns a Publisher that appends every event to log before handing
+// it to next. An event that cannot be persisted is still published; the error
+// is returned alongside any from next.
+func Persisting(next Publisher, log EventLog) Publisher {
+	return &persistingPublisher{next: next, log: log, now: time.Now}
+}
+
+type persistingPublisher struct {
+	next Publisher
+	log  EventLog
+	now  func() time.Time
+}
+
+// PublishRunStatus satisfies Publisher.
+func (p *persistingPublisher) PublishRunStatus(ctx context.Context, payload RunStatusEvent) error {
+	return errors.Join(p.persist(ctx, EventTypeRunStatus, payload.RunID, payload), p.next.PublishRunStatus(ctx, payload))
+}
+
+// PublishCommandEvent satisfies Publisher.
+func (p *persistingPublisher) PublishCommandEvent(ctx context.Context, payload CommandEvent) error {
+	return errors.Join(p.persist(ctx, EventTypeCommand, payload.RunID, payload), p.next.PublishCommandEvent(ctx, payload))
+}
+
+// PublishAccountingEvent satisfies Publisher.
+func (p *persistingPublisher) PublishAccountingEvent(ctx context.Context, payload AccountingEvent) error {
+	return errors.Join(p.persist(ctx, EventTypeAccounting, payload.RunID, payload), p.next.PublishAccountingEvent(ctx, payload))
+}
+
+// PublishAlertEvent satisfies Publisher.
+func (p *persistingPublisher) PublishAlertEvent(ctx context.Context, payload AlertEvent) error {
+	return errors.Join(p.persist(ctx, EventTypeAlert, payload.RunID, payload), p.next.PublishAlertEvent(ctx, payload))
+}
+
+func (p *persistingPublisher) persist(ctx context.Context, eventType, runID string, payload any) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", eventType, err)
+	}
+	event := types.RunEvent{RunID: runID, Type: eventType, PublishedAt: p.now().UTC(), Payload: raw}
+	if _, err := p.log.AppendRunEvent(ctx, event); err != nil {
+		return fmt.Errorf("persist %s event: %w", eventType, err)
+	}
+	return nil
+}
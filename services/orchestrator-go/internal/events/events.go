@@ -1,25 +1,59 @@
 package events
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
 
 // Publisher is implemented by downstream fan-out mechanisms.
 type Publisher interface {
 	PublishRunStatus(ctx context.Context, payload RunStatusEvent) error
 	PublishCommandEvent(ctx context.Context, payload CommandEvent) error
+	PublishAccountingEvent(ctx context.Context, payload AccountingEvent) error
+	PublishAlertEvent(ctx context.Context, payload AlertEvent) error
+}
+
+// HealthChecker is implemented by publishers that hold a broker connection and
+// can report whether it is usable.
+type HealthChecker interface {
+	Healthy(ctx context.Context) error
+}
+
+// CommandBus pushes queued commands to the learners of their run, alongside the
+// HTTP pull path. NATSPublisher implements it.
+type CommandBus interface {
+	// DeliverCommand publishes command and returns once the broker has accepted
+	// it; an error means the command was not delivered.
+	DeliverCommand(ctx context.Context, command types.RunCommand) error
 }
 
 // RunStatusEvent is emitted whenever run status/heartbeat fields change.
 type RunStatusEvent struct {
 	RunID            string  `json:"run_id"`
 	State            string  `json:"state"`
+	PreviousState    string  `json:"previous_state,omitempty"`
+	Reason           string  `json:"reason,omitempty"`
 	RuntimeStatus    string  `json:"runtime_status"`
 	HealthStatus     string  `json:"health_status"`
 	Step             int64   `json:"step"`
 	SamplesPerSecond float64 `json:"samples_per_sec"`
 	Loss             float64 `json:"loss"`
 	LastError        string  `json:"last_error,omitempty"`
+	// CorrelationID is the ID of the API request that caused the event; it is
+	// empty for events raised by background loops.
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
 
+// Reasons set on run status events raised by the replay stats poller. Stalls
+// are also published to a routing-key subject for alerting.
+const (
+	ReasonReplayStalled = "replay_stalled"
+	ReasonReplayResumed = "replay_resumed"
+)
+
 // CommandEvent tracks command lifecycle transitions.
 type CommandEvent struct {
 	RunID       string `json:"run_id"`
@@ -27,6 +61,52 @@ type CommandEvent struct {
 	Type        string `json:"type"`
 	Event       string `json:"event"`
 	Description string `json:"description,omitempty"`
+	// ResultStatus, Applied and Previous are set on "result" events reported by
+	// the learner.
+	ResultStatus  string          `json:"result_status,omitempty"`
+	Applied       json.RawMessage `json:"applied,omitempty"`
+	Previous      json.RawMessage `json:"previous,omitempty"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+}
+
+// AccountingEvent is emitted once when a run ends, with its final resource
+// accounting.
+type AccountingEvent struct {
+	RunID           string     `json:"run_id"`
+	ExperimentID    string     `json:"experiment_id"`
+	State           string     `json:"state"`
+	ResourceClass   string     `json:"resource_class,omitempty"`
+	GPUs            int        `json:"gpus"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	Steps           int64      `json:"steps"`
+	Samples         int64      `json:"samples"`
+	HourlyRate      float64    `json:"hourly_rate"`
+	Cost            float64    `json:"cost"`
+	CorrelationID   string     `json:"correlation_id,omitempty"`
+}
+
+// Alert statuses.
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// AlertEvent is emitted when a run's alert rule is breached and again when it
+// resolves. Firing alerts are also published to a routing key named after
+// their severity, so that paging can subscribe to breaches only.
+type AlertEvent struct {
+	RunID     string  `json:"run_id"`
+	RuleID    string  `json:"rule_id"`
+	Kind      string  `json:"kind"`
+	Severity  string  `json:"severity"`
+	Status    string  `json:"status"`
+	Threshold float64 `json:"threshold"`
+	Message   string  `json:"message,omitempty"`
+	Step      int64   `json:"step"`
+	// CorrelationID is the heartbeat request that raised or resolved the alert.
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
 
 // NoopPublisher logs nothing; useful for tests.
@@ -37,3 +117,9 @@ func (NoopPublisher) PublishRunStatus(context.Context, RunStatusEvent) error { r
 
 // PublishCommandEvent satisfies Publisher.
 func (NoopPublisher) PublishCommandEvent(context.Context, CommandEvent) error { return nil }
+
+// PublishAccountingEvent satisfies Publisher.
+func (NoopPublisher) PublishAccountingEvent(context.Context, AccountingEvent) error { return nil }
+
+// PublishAlertEvent satisfies Publisher.
+func (NoopPublisher) PublishAlertEvent(context.Context, AlertEvent) error { return nil }
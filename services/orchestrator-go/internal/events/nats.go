@@ -2,76 +2,151 @@ package events
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/correlation"
+	"github.com/cartridge/orchestrator/internal/types"
 )
 
-// NATSPublisher implements Publisher using NATS
+// NATSOptions configures the JetStream-backed publisher
+type NATSOptions struct {
+	URL     string
+	Subject string
+	// Stream is the JetStream stream capturing Subject and all of its
+	// routing-key subjects (Subject.>).
+	Stream string
+	// OutboxSize bounds how many events are buffered while the broker is unreachable.
+	OutboxSize int
+	// ReconnectWait is the initial reconnect and redelivery backoff.
+	ReconnectWait time.Duration
+	// MaxBackoff caps the exponential redelivery backoff.
+	MaxBackoff time.Duration
+	// PublishTimeout bounds how long to wait for a JetStream acknowledgement.
+	PublishTimeout time.Duration
+}
+
+func (o *NATSOptions) applyDefaults() {
+	if o.Stream == "" {
+		o.Stream = "RUN_EVENTS"
+	}
+	if o.OutboxSize <= 0 {
+		o.OutboxSize = 10000
+	}
+	if o.ReconnectWait <= 0 {
+		o.ReconnectWait = 2 * time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = time.Minute
+	}
+	if o.PublishTimeout <= 0 {
+		o.PublishTimeout = 5 * time.Second
+	}
+}
+
+// NATSPublisher implements Publisher using NATS JetStream. Events that cannot be
+// acknowledged by the broker are kept in a local outbox and redelivered in order
+// with exponential backoff once the connection recovers.
 type NATSPublisher struct {
-	conn    *nats.Conn
-	subject string
-	logger  zerolog.Logger
+	conn        *nats.Conn
+	js          jetstream.JetStream
+	opts        NATSOptions
+	logger      zerolog.Logger
+	outbox      *Outbox
+	streamReady atomic.Bool
+	wake        chan struct{}
+	done        chan struct{}
+	wg          sync.WaitGroup
 }
 
-// NewNATSPublisher creates a new NATS-backed publisher
-func NewNATSPublisher(natsURL, subject string, logger zerolog.Logger) (*NATSPublisher, error) {
-	conn, err := nats.Connect(natsURL)
+// NewNATSPublisher connects to NATS and starts the outbox flusher. The connection
+// is retried in the background, so the broker does not need to be up at startup.
+func NewNATSPublisher(opts NATSOptions, logger zerolog.Logger) (*NATSPublisher, error) {
+	opts.applyDefaults()
+	n := &NATSPublisher{
+		opts:   opts,
+		logger: logger,
+		outbox: NewOutbox(opts.OutboxSize),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	conn, err := nats.Connect(opts.URL,
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(opts.ReconnectWait),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			n.logger.Warn().Err(err).Msg("Disconnected from NATS")
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			n.logger.Info().Str("url", c.ConnectedUrl()).Msg("Reconnected to NATS")
+			n.signal()
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
+	n.conn = conn
+	n.js = js
 
-	return &NATSPublisher{
-		conn:    conn,
-		subject: subject,
-		logger:  logger,
-	}, nil
+	n.wg.Add(1)
+	go n.flushLoop()
+	return n, nil
 }
 
-// Close closes the NATS connection
+// Close stops the flusher, makes a final delivery attempt and closes the NATS connection
 func (n *NATSPublisher) Close() {
+	close(n.done)
+	n.wg.Wait()
+	if err := n.flush(); err != nil {
+		n.logger.Warn().Err(err).Int("pending", n.outbox.Len()).Msg("Closing NATS publisher with undelivered events")
+	}
 	if n.conn != nil {
 		n.conn.Close()
 	}
 }
 
-// PublishRunStatus publishes run status events to NATS
+// Healthy reports an error while the NATS connection is down. Events are still
+// accepted then, but only buffered in the outbox.
+func (n *NATSPublisher) Healthy(context.Context) error {
+	if status := n.conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats connection %s; %d events buffered", strings.ToLower(status.String()), n.outbox.Len())
+	}
+	return nil
+}
+
+// PublishRunStatus publishes run status events to the main subject and, for
+// alerting, to a routing-key subject derived from health and state.
 func (n *NATSPublisher) PublishRunStatus(ctx context.Context, event RunStatusEvent) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
 
-	// Publish to main subject
-	if err := n.conn.Publish(n.subject, data); err != nil {
-		n.logger.Error().Err(err).Str("subject", n.subject).Msg("Failed to publish run status")
-		return err
-	}
-
-	// Publish to specific routing keys for alerting
-	routingKey := ""
-	switch event.HealthStatus {
-	case "heartbeat_stale":
-		routingKey = n.subject + ".heartbeat_stale"
-	case "unresponsive":
-		routingKey = n.subject + ".unresponsive"
-	}
-
-	if event.State == "errored" || event.State == "failed" {
-		routingKey = n.subject + ".error"
-	}
-
-	if routingKey != "" {
-		if err := n.conn.Publish(routingKey, data); err != nil {
-			n.logger.Error().Err(err).Str("routing_key", routingKey).Msg("Failed to publish to routing key")
-		}
+	for _, subject := range runStatusSubjects(n.opts.Subject, event) {
+		n.publish(ctx, subject, data, event.CorrelationID)
 	}
 
 	n.logger.Debug().
 		Str("run_id", event.RunID).
 		Str("state", event.State).
-		Str("subject", n.subject).
+		Str("subject", n.opts.Subject).
 		Msg("Published run status event")
 
 	return nil
@@ -84,11 +159,8 @@ func (n *NATSPublisher) PublishCommandEvent(ctx context.Context, event CommandEv
 		return err
 	}
 
-	subject := n.subject + ".commands"
-	if err := n.conn.Publish(subject, data); err != nil {
-		n.logger.Error().Err(err).Str("subject", subject).Msg("Failed to publish command event")
-		return err
-	}
+	subject := n.opts.Subject + ".commands"
+	n.publish(ctx, subject, data, event.CorrelationID)
 
 	n.logger.Debug().
 		Str("run_id", event.RunID).
@@ -98,4 +170,226 @@ func (n *NATSPublisher) PublishCommandEvent(ctx context.Context, event CommandEv
 		Msg("Published command event")
 
 	return nil
+}
+
+// PublishAccountingEvent publishes run accounting events to NATS
+func (n *NATSPublisher) PublishAccountingEvent(ctx context.Context, event AccountingEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	subject := n.opts.Subject + ".accounting"
+	n.publish(ctx, subject, data, event.CorrelationID)
+
+	n.logger.Debug().
+		Str("run_id", event.RunID).
+		Float64("cost", event.Cost).
+		Str("subject", subject).
+		Msg("Published accounting event")
+
+	return nil
+}
+
+// PublishAlertEvent publishes alert events to NATS, routing firing alerts by severity
+func (n *NATSPublisher) PublishAlertEvent(ctx context.Context, event AlertEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, subject := range alertSubjects(n.opts.Subject, event) {
+		n.publish(ctx, subject, data, event.CorrelationID)
+	}
+
+	n.logger.Debug().
+		Str("run_id", event.RunID).
+		Str("rule_id", event.RuleID).
+		Str("severity", event.Severity).
+		Str("status", event.Status).
+		Msg("Published alert event")
+
+	return nil
+}
+
+// DeliverCommand publishes a command to its run's command subject. Unlike
+// events, commands bypass the outbox: one the broker does not acknowledge is
+// reported as undelivered so it stays queued for learners that poll.
+func (n *NATSPublisher) DeliverCommand(ctx context.Context, command types.RunCommand) error {
+	data, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	subject := CommandSubject(n.opts.Subject, command.RunID)
+	// One message ID per delivery attempt lets JetStream drop duplicate
+	// publishes of an attempt while still accepting redeliveries.
+	msgID := command.RunID + "/" + command.ID + "/" + strconv.Itoa(command.DeliveryAttempts)
+	if err := n.send(ctx, OutboxMessage{Subject: subject, Data: data, MsgID: msgID, CorrelationID: correlation.FromContext(ctx)}); err != nil {
+		return err
+	}
+
+	n.logger.Debug().
+		Str("run_id", command.RunID).
+		Str("command_id", command.ID).
+		Str("subject", subject).
+		Msg("Delivered command")
+
+	return nil
+}
+
+// CommandSubject returns the subject a run's commands are pushed to. Characters
+// NATS reserves in subject tokens are replaced in the run ID.
+func CommandSubject(base, runID string) string {
+	token := strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, runID)
+	return base + ".runs." + token + ".commands"
+}
+
+// runStatusSubjects returns the subjects a run status event is published to.
+func runStatusSubjects(base string, event RunStatusEvent) []string {
+	subjects := []string{base}
+
+	// Publish to specific routing keys for alerting
+	routingKey := ""
+	switch event.HealthStatus {
+	case "heartbeat_stale":
+		routingKey = base + ".heartbeat_stale"
+	case "unresponsive":
+		routingKey = base + ".unresponsive"
+	}
+	if event.Reason == ReasonReplayStalled {
+		routingKey = base + "." + ReasonReplayStalled
+	}
+
+	if event.State == "errored" || event.State == "failed" {
+		routingKey = base + ".error"
+	}
+
+	if routingKey != "" {
+		subjects = append(subjects, routingKey)
+	}
+	return subjects
+}
+
+// alertSubjects returns the subjects or streams an alert event is published to.
+func alertSubjects(base string, event AlertEvent) []string {
+	subjects := []string{base + ".alerts"}
+	if event.Status == AlertFiring {
+		subjects = append(subjects, base+".alerts."+event.Severity)
+	}
+	return subjects
+}
+
+// publish sends a message directly when nothing is queued, otherwise (or on
+// failure) it is appended to the outbox to preserve ordering.
+func (n *NATSPublisher) publish(ctx context.Context, subject string, data []byte, correlationID string) {
+	msg := OutboxMessage{Subject: subject, Data: data, MsgID: newMsgID(), CorrelationID: correlationID}
+	if n.outbox.Len() == 0 {
+		err := n.send(ctx, msg)
+		if err == nil {
+			return
+		}
+		n.logger.Warn().Err(err).Str("subject", subject).Msg("JetStream publish failed; buffering event")
+	}
+	if n.outbox.Push(msg) {
+		n.logger.Error().Str("subject", subject).Msg("Event outbox full; dropped oldest event")
+	}
+	n.signal()
+}
+
+func (n *NATSPublisher) send(ctx context.Context, msg OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, n.opts.PublishTimeout)
+	defer cancel()
+	if err := n.ensureStream(ctx); err != nil {
+		return err
+	}
+	out := nats.NewMsg(msg.Subject)
+	out.Data = msg.Data
+	if msg.CorrelationID != "" {
+		out.Header.Set(correlation.Header, msg.CorrelationID)
+	}
+	_, err := n.js.PublishMsg(ctx, out, jetstream.WithMsgID(msg.MsgID))
+	return err
+}
+
+func (n *NATSPublisher) ensureStream(ctx context.Context) error {
+	if n.streamReady.Load() {
+		return nil
+	}
+	_, err := n.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       n.opts.Stream,
+		Subjects:   []string{n.opts.Subject, n.opts.Subject + ".>"},
+		Storage:    jetstream.FileStorage,
+		Duplicates: 2 * time.Minute,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ensure stream %s: %w", n.opts.Stream, err)
+	}
+	n.streamReady.Store(true)
+	return nil
+}
+
+func (n *NATSPublisher) signal() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (n *NATSPublisher) flushLoop() {
+	defer n.wg.Done()
+	backoff := n.opts.ReconnectWait
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-n.wake:
+		case <-timer.C:
+		}
+		if err := n.flush(); err != nil {
+			backoff *= 2
+			if backoff > n.opts.MaxBackoff {
+				backoff = n.opts.MaxBackoff
+			}
+			n.logger.Warn().Err(err).Int("pending", n.outbox.Len()).Dur("retry_in", backoff).Msg("Failed to flush event outbox")
+		} else {
+			backoff = n.opts.ReconnectWait
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(backoff)
+	}
+}
+
+// flush delivers buffered events in order, stopping at the first failure.
+func (n *NATSPublisher) flush() error {
+	for {
+		msg, ok := n.outbox.Peek()
+		if !ok {
+			return nil
+		}
+		if err := n.send(context.Background(), msg); err != nil {
+			return err
+		}
+		n.outbox.Pop()
+	}
+}
+
+func newMsgID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("fallback-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
 }
\ No newline at end of file
@@ -0,0 +1,78 @@
+package events
+
+import "sync"
+
+// OutboxMessage is a serialized event waiting to be delivered.
+type OutboxMessage struct {
+	Subject string
+	Data    []byte
+	// MsgID lets the broker de-duplicate messages that are retried after an
+	// acknowledgement was lost.
+	MsgID string
+	// CorrelationID is sent as a message header when set.
+	CorrelationID string
+}
+
+// Outbox is a bounded FIFO of undelivered events. When full, the oldest
+// message is discarded so the most recent state always gets through.
+type Outbox struct {
+	mu       sync.Mutex
+	items    []OutboxMessage
+	capacity int
+	dropped  uint64
+}
+
+// NewOutbox creates an outbox holding at most capacity messages.
+func NewOutbox(capacity int) *Outbox {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &Outbox{capacity: capacity}
+}
+
+// Push appends a message, reporting whether an older message was dropped to make room.
+func (o *Outbox) Push(msg OutboxMessage) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	dropped := false
+	if len(o.items) >= o.capacity {
+		o.items = o.items[1:]
+		o.dropped++
+		dropped = true
+	}
+	o.items = append(o.items, msg)
+	return dropped
+}
+
+// Peek returns the oldest message without removing it.
+func (o *Outbox) Peek() (OutboxMessage, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.items) == 0 {
+		return OutboxMessage{}, false
+	}
+	return o.items[0], true
+}
+
+// Pop removes the oldest message.
+func (o *Outbox) Pop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.items) > 0 {
+		o.items = o.items[1:]
+	}
+}
+
+// Len returns the number of buffered messages.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.items)
+}
+
+// Dropped returns how many messages were discarded because the outbox was full.
+func (o *Outbox) Dropped() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.dropped
+}
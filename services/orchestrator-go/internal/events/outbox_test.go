@@ -0,0 +1,52 @@
+package events
+
+import "testing"
+
+func TestOutboxDropsOldestWhenFull(t *testing.T) {
+	outbox := NewOutbox(2)
+	for _, subject := range []string{"a", "b"} {
+		if outbox.Push(OutboxMessage{Subject: subject}) {
+			t.Fatalf("unexpected drop while below capacity")
+		}
+	}
+	if !outbox.Push(OutboxMessage{Subject: "c"}) {
+		t.Fatalf("expected oldest message to be dropped")
+	}
+	if outbox.Len() != 2 || outbox.Dropped() != 1 {
+		t.Fatalf("expected 2 buffered and 1 dropped, got %d and %d", outbox.Len(), outbox.Dropped())
+	}
+	msg, ok := outbox.Peek()
+	if !ok || msg.Subject != "b" {
+		t.Fatalf("expected b at head, got %+v", msg)
+	}
+	outbox.Pop()
+	if msg, _ := outbox.Peek(); msg.Subject != "c" {
+		t.Fatalf("expected c at head, got %+v", msg)
+	}
+}
+
+func TestRunStatusSubjects(t *testing.T) {
+	subjects := runStatusSubjects("run-status", RunStatusEvent{State: "running", HealthStatus: "unresponsive"})
+	if len(subjects) != 2 || subjects[1] != "run-status.unresponsive" {
+		t.Fatalf("unexpected subjects: %v", subjects)
+	}
+	subjects = runStatusSubjects("run-status", RunStatusEvent{State: "failed", HealthStatus: "heartbeat_stale"})
+	if len(subjects) != 2 || subjects[1] != "run-status.error" {
+		t.Fatalf("expected error routing to win, got %v", subjects)
+	}
+	subjects = runStatusSubjects("run-status", RunStatusEvent{State: "running", HealthStatus: "healthy", Reason: ReasonReplayStalled})
+	if len(subjects) != 2 || subjects[1] != "run-status.replay_stalled" {
+		t.Fatalf("expected replay stall routing, got %v", subjects)
+	}
+}
+
+func TestAlertSubjects(t *testing.T) {
+	subjects := alertSubjects("run-status", AlertEvent{Severity: "critical", Status: AlertFiring})
+	if len(subjects) != 2 || subjects[0] != "run-status.alerts" || subjects[1] != "run-status.alerts.critical" {
+		t.Fatalf("unexpected firing subjects: %v", subjects)
+	}
+	subjects = alertSubjects("run-status", AlertEvent{Severity: "critical", Status: AlertResolved})
+	if len(subjects) != 1 {
+		t.Fatalf("expected resolved alerts to skip the paging subject, got %v", subjects)
+	}
+}
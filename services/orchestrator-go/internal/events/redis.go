@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// RedisOptions configures the Redis Streams publisher
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+	// Stream is the base stream key. Routing keys and command events are
+	// appended as suffixes, mirroring the NATS subject layout.
+	Stream string
+	// MaxLen approximately caps each stream's length; zero disables trimming.
+	MaxLen int64
+}
+
+// RedisPublisher implements Publisher using Redis Streams (XADD)
+type RedisPublisher struct {
+	client *redis.Client
+	opts   RedisOptions
+	logger zerolog.Logger
+}
+
+// NewRedisPublisher connects to Redis and verifies the connection
+func NewRedisPublisher(opts RedisOptions, logger zerolog.Logger) (*RedisPublisher, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", opts.Addr, err)
+	}
+	return &RedisPublisher{client: client, opts: opts, logger: logger}, nil
+}
+
+// Close closes the Redis client
+func (r *RedisPublisher) Close() {
+	if err := r.client.Close(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to close redis client")
+	}
+}
+
+// Healthy pings the Redis server
+func (r *RedisPublisher) Healthy(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// PublishRunStatus appends run status events to the base stream and, for alerting,
+// to the routing-key stream derived from health and state.
+func (r *RedisPublisher) PublishRunStatus(ctx context.Context, event RunStatusEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for _, stream := range runStatusSubjects(r.opts.Stream, event) {
+		if err := r.add(ctx, stream, event.RunID, event.CorrelationID, data); err != nil {
+			return err
+		}
+	}
+
+	r.logger.Debug().
+		Str("run_id", event.RunID).
+		Str("state", event.State).
+		Str("stream", r.opts.Stream).
+		Msg("Published run status event")
+
+	return nil
+}
+
+// PublishCommandEvent appends command events to the commands stream
+func (r *RedisPublisher) PublishCommandEvent(ctx context.Context, event CommandEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	stream := r.opts.Stream + ".commands"
+	if err := r.add(ctx, stream, event.RunID, event.CorrelationID, data); err != nil {
+		return err
+	}
+
+	r.logger.Debug().
+		Str("run_id", event.RunID).
+		Str("command_id", event.CommandID).
+		Str("event", event.Event).
+		Str("stream", stream).
+		Msg("Published command event")
+
+	return nil
+}
+
+// PublishAccountingEvent appends run accounting events to the accounting stream
+func (r *RedisPublisher) PublishAccountingEvent(ctx context.Context, event AccountingEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	stream := r.opts.Stream + ".accounting"
+	if err := r.add(ctx, stream, event.RunID, event.CorrelationID, data); err != nil {
+		return err
+	}
+
+	r.logger.Debug().
+		Str("run_id", event.RunID).
+		Float64("cost", event.Cost).
+		Str("stream", stream).
+		Msg("Published accounting event")
+
+	return nil
+}
+
+// PublishAlertEvent appends alert events to the alerts stream and, while firing,
+// to the stream named after their severity.
+func (r *RedisPublisher) PublishAlertEvent(ctx context.Context, event AlertEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for _, stream := range alertSubjects(r.opts.Stream, event) {
+		if err := r.add(ctx, stream, event.RunID, event.CorrelationID, data); err != nil {
+			return err
+		}
+	}
+
+	r.logger.Debug().
+		Str("run_id", event.RunID).
+		Str("rule_id", event.RuleID).
+		Str("severity", event.Severity).
+		Str("status", event.Status).
+		Msg("Published alert event")
+
+	return nil
+}
+
+func (r *RedisPublisher) add(ctx context.Context, stream, runID, correlationID string, data []byte) error {
+	values := map[string]any{"run_id": runID, "payload": data}
+	if correlationID != "" {
+		values["correlation_id"] = correlationID
+	}
+	args := &redis.XAddArgs{
+		Stream: stream,
+		Values: values,
+	}
+	if r.opts.MaxLen > 0 {
+		args.MaxLen = r.opts.MaxLen
+		args.Approx = true
+	}
+	if err := r.client.XAdd(ctx, args).Err(); err != nil {
+		r.logger.Error().Err(err).Str("stream", stream).Msg("Failed to publish to redis stream")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/correlation"
+)
+
+const (
+	// WebhookSignatureHeader carries "sha256=<hex>" over "<timestamp>.<body>".
+	WebhookSignatureHeader = "X-Cartridge-Signature"
+	// WebhookTimestampHeader carries the unix timestamp included in the signature.
+	WebhookTimestampHeader = "X-Cartridge-Timestamp"
+	// WebhookEventHeader names the event type: run_status or command.
+	WebhookEventHeader = "X-Cartridge-Event"
+	// WebhookDeliveryHeader is a unique ID per event, stable across retries.
+	WebhookDeliveryHeader = "X-Cartridge-Delivery"
+)
+
+// WebhookOptions configures the webhook publisher
+type WebhookOptions struct {
+	URLs []string
+	// Secret signs payloads with HMAC-SHA256; signing is skipped when empty.
+	Secret       string
+	MaxRetries   int
+	RetryBackoff time.Duration
+	Timeout      time.Duration
+}
+
+// WebhookPublisher implements Publisher by POSTing event JSON to webhook URLs.
+// Deliveries happen in the background; events that still fail after MaxRetries
+// are written to the log as dead letters.
+type WebhookPublisher struct {
+	client *http.Client
+	opts   WebhookOptions
+	logger zerolog.Logger
+	wg     sync.WaitGroup
+}
+
+// NewWebhookPublisher creates a webhook publisher
+func NewWebhookPublisher(opts WebhookOptions, logger zerolog.Logger) (*WebhookPublisher, error) {
+	if len(opts.URLs) == 0 {
+		return nil, fmt.Errorf("at least one webhook URL is required")
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &WebhookPublisher{
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+		logger: logger,
+	}, nil
+}
+
+// Close waits for in-flight deliveries to finish
+func (w *WebhookPublisher) Close() {
+	w.wg.Wait()
+}
+
+// PublishRunStatus delivers run status events to every configured webhook
+func (w *WebhookPublisher) PublishRunStatus(_ context.Context, event RunStatusEvent) error {
+	return w.dispatch("run_status", event.RunID, event.CorrelationID, event)
+}
+
+// PublishCommandEvent delivers command events to every configured webhook
+func (w *WebhookPublisher) PublishCommandEvent(_ context.Context, event CommandEvent) error {
+	return w.dispatch("command", event.RunID, event.CorrelationID, event)
+}
+
+// PublishAccountingEvent delivers run accounting events to every configured webhook
+func (w *WebhookPublisher) PublishAccountingEvent(_ context.Context, event AccountingEvent) error {
+	return w.dispatch("accounting", event.RunID, event.CorrelationID, event)
+}
+
+// PublishAlertEvent delivers alert events to every configured webhook
+func (w *WebhookPublisher) PublishAlertEvent(_ context.Context, event AlertEvent) error {
+	return w.dispatch("alert", event.RunID, event.CorrelationID, event)
+}
+
+func (w *WebhookPublisher) dispatch(eventType, runID, correlationID string, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	delivery := newMsgID()
+	for _, url := range w.opts.URLs {
+		w.wg.Add(1)
+		go func(url string) {
+			defer w.wg.Done()
+			w.deliver(url, eventType, runID, correlationID, delivery, body)
+		}(url)
+	}
+	return nil
+}
+
+func (w *WebhookPublisher) deliver(url, eventType, runID, correlationID, delivery string, body []byte) {
+	backoff := w.opts.RetryBackoff
+	var lastErr error
+	for attempt := 0; attempt <= w.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		retry, err := w.post(url, eventType, correlationID, delivery, body)
+		if err == nil {
+			return
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	w.logger.Error().
+		Err(lastErr).
+		Str("dead_letter", "webhook").
+		Str("url", url).
+		Str("event_type", eventType).
+		Str("run_id", runID).
+		Str("delivery", delivery).
+		Str("correlation_id", correlationID).
+		Bytes("payload", body).
+		Msg("Webhook delivery failed; event dead-lettered")
+}
+
+// post sends a single delivery attempt, reporting whether a failure is retryable.
+func (w *WebhookPublisher) post(url, eventType, correlationID, delivery string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, eventType)
+	req.Header.Set(WebhookDeliveryHeader, delivery)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	if correlationID != "" {
+		req.Header.Set(correlation.Header, correlationID)
+	}
+	if w.opts.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(w.opts.Secret, timestamp, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+}
+
+// SignWebhookPayload computes the signature header value receivers should compare
+// against: "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWebhookPublisherSignsAndRetries(t *testing.T) {
+	var attempts atomic.Int32
+	var verified atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		expected := SignWebhookPayload("secret", r.Header.Get(WebhookTimestampHeader), body)
+		verified.Store(r.Header.Get(WebhookSignatureHeader) == expected && r.Header.Get(WebhookEventHeader) == "run_status" &&
+			r.Header.Get("X-Correlation-ID") == "corr-1")
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	publisher, err := NewWebhookPublisher(WebhookOptions{
+		URLs:         []string{srv.URL},
+		Secret:       "secret",
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	}, *zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	if err := publisher.PublishRunStatus(context.Background(), RunStatusEvent{RunID: "run-1", State: "running", CorrelationID: "corr-1"}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	publisher.Close()
+
+	if attempts.Load() != 2 {
+		t.Fatalf("expected one retry after a 503, got %d attempts", attempts.Load())
+	}
+	if !verified.Load() {
+		t.Fatalf("expected signed run_status delivery carrying the correlation ID")
+	}
+}
+
+func TestWebhookPublisherDoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	publisher, _ := NewWebhookPublisher(WebhookOptions{URLs: []string{srv.URL}, MaxRetries: 3, RetryBackoff: time.Millisecond}, *zerolog.New(io.Discard))
+	publisher.PublishCommandEvent(context.Background(), CommandEvent{RunID: "run-1", CommandID: "cmd-1"})
+	publisher.Close()
+
+	if attempts.Load() != 1 {
+		t.Fatalf("expected a single attempt for a 400, got %d", attempts.Load())
+	}
+}
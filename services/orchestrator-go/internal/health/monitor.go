@@ -2,6 +2,8 @@ package health
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -16,16 +18,30 @@ type Config struct {
 	CheckInterval         time.Duration
 	HeartbeatStaleAfter   time.Duration
 	HeartbeatUnresponsive time.Duration
+	// Once a run has BaselineMinSamples heartbeat gaps on record, it is stale
+	// after BaselineMultiplier times its p95 gap (but no sooner than
+	// BaselineFloor) instead of HeartbeatStaleAfter, and unresponsive after the
+	// same multiple of HeartbeatUnresponsive over HeartbeatStaleAfter. A zero
+	// multiplier always uses the fixed durations.
+	BaselineMultiplier float64
+	BaselineMinSamples int
+	BaselineFloor      time.Duration
 }
 
 // Monitor runs background health checks
 type Monitor struct {
 	orch      *service.Orchestrator
 	publisher events.Publisher
+	mu        sync.RWMutex // guards config, which SetThresholds may change
 	config    Config
 	logger    zerolog.Logger
+	now       func() time.Time
+	onTick    func()
 }
 
+// monitorActor is recorded as the initiator of health transitions.
+const monitorActor = "health-monitor"
+
 // NewMonitor creates a new health monitor
 func NewMonitor(orch *service.Orchestrator, publisher events.Publisher, config Config, logger zerolog.Logger) *Monitor {
 	return &Monitor{
@@ -33,19 +49,44 @@ func NewMonitor(orch *service.Orchestrator, publisher events.Publisher, config C
 		publisher: publisher,
 		config:    config,
 		logger:    logger,
+		now:       time.Now,
 	}
 }
 
+// OnTick registers fn to be called when the loop starts and after every pass,
+// letting liveness probes confirm it is making progress.
+func (m *Monitor) OnTick(fn func()) {
+	m.onTick = fn
+}
+
+// SetThresholds replaces the heartbeat thresholds while the monitor runs; they
+// apply from the next check. The check interval is left as it was.
+func (m *Monitor) SetThresholds(config Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	config.CheckInterval = m.config.CheckInterval
+	m.config = config
+}
+
+func (m *Monitor) settings() Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config
+}
+
 // Start begins the health monitoring loop
 func (m *Monitor) Start(ctx context.Context) {
-	ticker := time.NewTicker(m.config.CheckInterval)
+	config := m.settings()
+	ticker := time.NewTicker(config.CheckInterval)
 	defer ticker.Stop()
 
 	m.logger.Info().
-		Dur("check_interval", m.config.CheckInterval).
-		Dur("stale_after", m.config.HeartbeatStaleAfter).
-		Dur("unresponsive_after", m.config.HeartbeatUnresponsive).
+		Dur("check_interval", config.CheckInterval).
+		Dur("stale_after", config.HeartbeatStaleAfter).
+		Dur("unresponsive_after", config.HeartbeatUnresponsive).
+		Float64("baseline_multiplier", config.BaselineMultiplier).
 		Msg("Starting health monitor")
+	m.beat()
 
 	for {
 		select {
@@ -54,47 +95,139 @@ func (m *Monitor) Start(ctx context.Context) {
 			return
 		case <-ticker.C:
 			m.checkStaleHeartbeats(ctx)
+			m.redeliverCommands(ctx)
+			m.expireCommands(ctx)
+			m.pruneMetrics(ctx)
+			m.beat()
 		}
 	}
 }
 
-func (m *Monitor) checkStaleHeartbeats(ctx context.Context) {
-	// This would require adding a method to the service to list runs
-	// that need health checking. For now, we'll outline the logic:
+func (m *Monitor) beat() {
+	if m.onTick != nil {
+		m.onTick()
+	}
+}
 
-	now := time.Now()
-	staleThreshold := now.Add(-m.config.HeartbeatStaleAfter)
-	unresponsiveThreshold := now.Add(-m.config.HeartbeatUnresponsive)
+// monitoredStates are the lifecycle states in which a learner is expected to heartbeat.
+var monitoredStates = []types.RunState{types.RunStateRunning, types.RunStatePaused}
 
-	// TODO: Add ListRunsForHealthCheck to service layer
-	// runs, err := m.orch.ListRunsForHealthCheck(ctx, types.RunStateRunning)
+func (m *Monitor) checkStaleHeartbeats(ctx context.Context) {
+	now := m.now()
+	config := m.settings()
+
+	runs, err := m.orch.ListRunsForHealthCheck(ctx, monitoredStates...)
+	if err != nil {
+		m.logger.Error().Err(err).Msg("Failed to list runs for health check")
+		return
+	}
 
 	m.logger.Debug().
-		Time("stale_threshold", staleThreshold).
-		Time("unresponsive_threshold", unresponsiveThreshold).
+		Int("runs", len(runs)).
+		Time("now", now).
 		Msg("Checking run health")
 
-	// Example logic for what this would do:
-	// for _, run := range runs {
-	//     if run.LastHeartbeatAt != nil {
-	//         if run.LastHeartbeatAt.Before(unresponsiveThreshold) && run.HealthStatus != types.RunHealthUnresponsive {
-	//             m.markUnresponsive(ctx, run)
-	//         } else if run.LastHeartbeatAt.Before(staleThreshold) && run.HealthStatus == types.RunHealthHealthy {
-	//             m.markStale(ctx, run)
-	//         }
-	//     }
-	// }
+	for _, run := range runs {
+		lastSeen := lastSeenAt(run)
+		if lastSeen == nil {
+			continue
+		}
+		silent := now.Sub(*lastSeen)
+		staleAfter, unresponsiveAfter := config.thresholds(run)
+		if silent > unresponsiveAfter && run.HealthStatus != types.RunHealthUnresponsive {
+			m.markUnresponsive(ctx, run, *lastSeen, unresponsiveAfter)
+		} else if silent > staleAfter && run.HealthStatus == types.RunHealthHealthy {
+			m.markStale(ctx, run, *lastSeen)
+		}
+	}
+}
+
+// thresholds returns how long run may go without a heartbeat before it is
+// stale and unresponsive, scaled from its observed heartbeat cadence once
+// enough gaps are on record.
+func (c Config) thresholds(run types.Run) (staleAfter, unresponsiveAfter time.Duration) {
+	staleAfter, unresponsiveAfter = c.HeartbeatStaleAfter, c.HeartbeatUnresponsive
+	gaps := run.HeartbeatGaps
+	if c.BaselineMultiplier <= 0 || gaps == nil || gaps.Count < int64(max(c.BaselineMinSamples, 1)) {
+		return staleAfter, unresponsiveAfter
+	}
+	baseline := time.Duration(gaps.P95Seconds * c.BaselineMultiplier * float64(time.Second))
+	baseline = max(baseline, c.BaselineFloor)
+	ratio := float64(unresponsiveAfter) / float64(staleAfter)
+	return baseline, time.Duration(float64(baseline) * ratio)
+}
+
+// redeliverCommands requeues commands whose learner never acknowledged them.
+func (m *Monitor) redeliverCommands(ctx context.Context) {
+	reclaimed, err := m.orch.RedeliverCommands(ctx, "")
+	if err != nil {
+		m.logger.Error().Err(err).Msg("Failed to redeliver unacknowledged commands")
+		return
+	}
+	for _, cmd := range reclaimed {
+		event := m.logger.Info()
+		msg := "Requeued unacknowledged command"
+		if cmd.DeadLetteredAt != nil {
+			event = m.logger.Error()
+			msg = "Dead-lettered command after exhausting delivery attempts"
+		}
+		event.
+			Str("run_id", cmd.RunID).
+			Str("command_id", cmd.ID).
+			Int("delivery_attempts", cmd.DeliveryAttempts).
+			Msg(msg)
+	}
+}
+
+// expireCommands sweeps commands whose TTL lapsed before a learner fetched them.
+func (m *Monitor) expireCommands(ctx context.Context) {
+	expired, err := m.orch.ExpireCommands(ctx, "")
+	if err != nil {
+		m.logger.Error().Err(err).Msg("Failed to expire commands")
+		return
+	}
+	for _, cmd := range expired {
+		m.logger.Warn().
+			Str("run_id", cmd.RunID).
+			Str("command_id", cmd.ID).
+			Str("type", string(cmd.Type)).
+			Msg("Command expired before delivery")
+	}
 }
 
-func (m *Monitor) markStale(ctx context.Context, run types.Run) {
+// pruneMetrics drops heartbeat metrics older than the retention window.
+func (m *Monitor) pruneMetrics(ctx context.Context) {
+	removed, err := m.orch.PruneMetrics(ctx)
+	if err != nil {
+		m.logger.Error().Err(err).Msg("Failed to prune heartbeat metrics")
+		return
+	}
+	if removed > 0 {
+		m.logger.Debug().Int("points", removed).Msg("Pruned heartbeat metrics past retention")
+	}
+}
+
+// lastSeenAt returns the last heartbeat, falling back to the start time for runs that
+// have not reported yet.
+func lastSeenAt(run types.Run) *time.Time {
+	if run.LastHeartbeatAt != nil {
+		return run.LastHeartbeatAt
+	}
+	return run.StartedAt
+}
+
+func (m *Monitor) markStale(ctx context.Context, run types.Run, lastSeen time.Time) {
 	m.logger.Warn().
 		Str("run_id", run.ID).
-		Time("last_heartbeat", *run.LastHeartbeatAt).
+		Time("last_heartbeat", lastSeen).
 		Msg("Marking run as stale")
 
-	// Update run health status
-	run.HealthStatus = types.RunHealthHeartbeatStale
-	// Would need UpdateRunHealth method in service
+	runID := run.ID
+	run, err := m.orch.UpdateRunHealth(ctx, runID, types.RunHealthHeartbeatStale, monitorActor, "heartbeat stale")
+	if err != nil {
+		m.logger.Error().Err(err).Str("run_id", runID).Msg("Failed to persist stale health status")
+		return
+	}
 
 	// Publish stale event
 	event := events.RunStatusEvent{
@@ -111,15 +244,18 @@ func (m *Monitor) markStale(ctx context.Context, run types.Run) {
 	}
 }
 
-func (m *Monitor) markUnresponsive(ctx context.Context, run types.Run) {
+func (m *Monitor) markUnresponsive(ctx context.Context, run types.Run, lastSeen time.Time, after time.Duration) {
 	m.logger.Error().
 		Str("run_id", run.ID).
-		Time("last_heartbeat", *run.LastHeartbeatAt).
+		Time("last_heartbeat", lastSeen).
 		Msg("Marking run as unresponsive")
 
-	// Update run health status
-	run.HealthStatus = types.RunHealthUnresponsive
-	// Would need UpdateRunHealth method in service
+	runID := run.ID
+	run, err := m.orch.UpdateRunHealth(ctx, runID, types.RunHealthUnresponsive, monitorActor, "no heartbeat received")
+	if err != nil {
+		m.logger.Error().Err(err).Str("run_id", runID).Msg("Failed to persist unresponsive health status")
+		return
+	}
 
 	// Publish unresponsive event (triggers PagerDuty)
 	event := events.RunStatusEvent{
@@ -128,10 +264,10 @@ func (m *Monitor) markUnresponsive(ctx context.Context, run types.Run) {
 		RuntimeStatus: string(run.RuntimeStatus),
 		HealthStatus:  string(run.HealthStatus),
 		Step:          run.CurrentStep,
-		LastError:     "Run unresponsive - no heartbeat for over 2 minutes",
+		LastError:     fmt.Sprintf("Run unresponsive - no heartbeat for over %s", after.Round(time.Second)),
 	}
 
 	if err := m.publisher.PublishRunStatus(ctx, event); err != nil {
 		m.logger.Error().Err(err).Str("run_id", run.ID).Msg("Failed to publish unresponsive event")
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,124 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func TestCheckStaleHeartbeatsPersistsHealth(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+
+	for _, id := range []string{"run-stale", "run-dead"} {
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: id, ExperimentID: "exp-1", VersionID: "ver-1", LaunchManifest: json.RawMessage(`{}`)}); err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+		for _, action := range []types.RunAction{types.RunActionProvision, types.RunActionStart} {
+			if _, err := orch.PerformAction(ctx, id, action, "tester", ""); err != nil {
+				t.Fatalf("%s: %v", action, err)
+			}
+		}
+	}
+
+	base := time.Now()
+	lastHeartbeat := map[string]time.Time{"run-stale": base, "run-dead": base.Add(-time.Minute)}
+	for id, at := range lastHeartbeat {
+		at := at
+		orch.WithNow(func() time.Time { return at })
+		if _, err := orch.HandleHeartbeat(ctx, id, types.HeartbeatPayload{RunID: id, Status: types.RuntimeStatusRunning}); err != nil {
+			t.Fatalf("heartbeat: %v", err)
+		}
+	}
+
+	monitor := NewMonitor(orch, events.NoopPublisher{}, Config{
+		CheckInterval:         time.Second,
+		HeartbeatStaleAfter:   20 * time.Second,
+		HeartbeatUnresponsive: 50 * time.Second,
+	}, *logger)
+	monitor.now = func() time.Time { return base.Add(30 * time.Second) }
+	monitor.checkStaleHeartbeats(ctx)
+
+	stale, _ := orch.GetRun(ctx, "run-stale")
+	if stale.HealthStatus != types.RunHealthHeartbeatStale {
+		t.Fatalf("expected heartbeat_stale, got %s", stale.HealthStatus)
+	}
+	dead, _ := orch.GetRun(ctx, "run-dead")
+	if dead.HealthStatus != types.RunHealthUnresponsive {
+		t.Fatalf("expected unresponsive, got %s", dead.HealthStatus)
+	}
+	transitions, _ := orch.ListTransitions(ctx, "run-dead")
+	if last := transitions[len(transitions)-1]; last.ChangedBy != monitorActor {
+		t.Fatalf("expected health transition recorded by monitor, got %+v", last)
+	}
+}
+
+func TestCheckStaleHeartbeatsUsesObservedBaseline(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+
+	base := time.Now()
+	for _, id := range []string{"run-slow", "run-new"} {
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: id, ExperimentID: "exp-1", VersionID: "ver-1", LaunchManifest: json.RawMessage(`{}`)}); err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+		for _, action := range []types.RunAction{types.RunActionProvision, types.RunActionStart} {
+			if _, err := orch.PerformAction(ctx, id, action, "tester", ""); err != nil {
+				t.Fatalf("%s: %v", action, err)
+			}
+		}
+	}
+	// run-slow has a long record of heartbeating once a minute; run-new has one heartbeat.
+	heartbeat := func(id string, at time.Time) {
+		orch.WithNow(func() time.Time { return at })
+		if _, err := orch.HandleHeartbeat(ctx, id, types.HeartbeatPayload{RunID: id, Status: types.RuntimeStatusRunning}); err != nil {
+			t.Fatalf("heartbeat: %v", err)
+		}
+	}
+	for i := 10; i >= 0; i-- {
+		heartbeat("run-slow", base.Add(-time.Duration(i)*time.Minute))
+	}
+	heartbeat("run-new", base)
+
+	monitor := NewMonitor(orch, events.NoopPublisher{}, Config{
+		CheckInterval:         time.Second,
+		HeartbeatStaleAfter:   20 * time.Second,
+		HeartbeatUnresponsive: 60 * time.Second,
+		BaselineMultiplier:    2,
+		BaselineMinSamples:    10,
+		BaselineFloor:         10 * time.Second,
+	}, *logger)
+	check := func(after time.Duration) {
+		monitor.now = func() time.Time { return base.Add(after) }
+		monitor.checkStaleHeartbeats(ctx)
+	}
+
+	check(90 * time.Second)
+	if slow, _ := orch.GetRun(ctx, "run-slow"); slow.HealthStatus != types.RunHealthHealthy {
+		t.Fatalf("expected a run within twice its usual gap to stay healthy, got %s", slow.HealthStatus)
+	}
+	if fresh, _ := orch.GetRun(ctx, "run-new"); fresh.HealthStatus != types.RunHealthUnresponsive {
+		t.Fatalf("expected the fixed thresholds without a baseline, got %s", fresh.HealthStatus)
+	}
+	check(150 * time.Second)
+	if slow, _ := orch.GetRun(ctx, "run-slow"); slow.HealthStatus != types.RunHealthHeartbeatStale {
+		t.Fatalf("expected stale past twice the p95 gap, got %s", slow.HealthStatus)
+	}
+	check(370 * time.Second)
+	if slow, _ := orch.GetRun(ctx, "run-slow"); slow.HealthStatus != types.RunHealthUnresponsive {
+		t.Fatalf("expected unresponsive past the scaled threshold, got %s", slow.HealthStatus)
+	}
+}
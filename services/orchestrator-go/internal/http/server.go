@@ -2,47 +2,295 @@ package http
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 
+	"github.com/cartridge/chaos"
+	"github.com/cartridge/orchestrator/internal/apierror"
+	"github.com/cartridge/orchestrator/internal/auth"
+	"github.com/cartridge/orchestrator/internal/config"
+	"github.com/cartridge/orchestrator/internal/middleware"
+	"github.com/cartridge/orchestrator/internal/openapi"
+	"github.com/cartridge/orchestrator/internal/probe"
 	"github.com/cartridge/orchestrator/internal/service"
 	"github.com/cartridge/orchestrator/internal/storage"
 	"github.com/cartridge/orchestrator/internal/types"
+	"github.com/cartridge/telemetry/buildinfo"
+	"github.com/cartridge/telemetry/metrics"
+	telemetry "github.com/cartridge/telemetry/middleware"
 )
 
 const maxHeartbeatBody = 32 * 1024
 
+// Batch heartbeats are capped by item count and total body size.
+const (
+	maxHeartbeatBatch     = 256
+	maxHeartbeatBatchBody = 1 << 20
+)
+
+// Log pushes are capped the same way.
+const (
+	maxLogBatch     = 500
+	maxLogBatchBody = 1 << 20
+)
+
+// logKeepAlive is how often an idle log stream sends a comment so proxies
+// keep the connection open and the server notices departed clients.
+const logKeepAlive = 15 * time.Second
+
 // Server wires HTTP handlers to the orchestrator service.
 type Server struct {
-	orch   *service.Orchestrator
-	logger *zerolog.Logger
+	orch      *service.Orchestrator
+	logger    *zerolog.Logger
+	keyring   *auth.Keyring
+	jwt       *auth.JWTVerifier
+	runTokens *auth.RunTokenSigner
+	validator *openapi.Validator
+	probes    *probe.Prober
+	limiter   *middleware.RateLimiter
+	reload    func() ([]config.Change, error)
+	registry  *prometheus.Registry
+	httpStats *telemetry.HTTPMetrics
+	faults    *chaos.Injector
 }
 
 // NewServer constructs a Server instance.
 func NewServer(orch *service.Orchestrator, logger *zerolog.Logger) *Server {
-	return &Server{orch: orch, logger: logger}
+	return &Server{orch: orch, logger: logger, validator: openapi.MustNewValidator(), probes: probe.New(5 * time.Second)}
+}
+
+// WithKeyring requires API key authentication on every route. Without a keyring
+// the API is unauthenticated, which is only suitable for local development.
+func (s *Server) WithKeyring(keyring *auth.Keyring) {
+	s.keyring = keyring
+}
+
+// WithJWTVerifier additionally accepts OIDC-issued JWTs as bearer credentials.
+func (s *Server) WithJWTVerifier(verifier *auth.JWTVerifier) {
+	s.jwt = verifier
+}
+
+// WithRunTokens issues a token with every new run and requires learners to
+// present it (as X-Run-Token) on that run's heartbeat and command endpoints.
+func (s *Server) WithRunTokens(signer *auth.RunTokenSigner) {
+	s.runTokens = signer
+}
+
+// WithProbes sets the checks behind /healthz and /readyz. Without them both
+// endpoints only confirm that the process is serving HTTP.
+func (s *Server) WithProbes(probes *probe.Prober) {
+	s.probes = probes
+}
+
+// WithRateLimiter limits each client's request rate on the API routes.
+func (s *Server) WithRateLimiter(limiter *middleware.RateLimiter) {
+	s.limiter = limiter
+}
+
+// WithReloader serves POST /api/v1/admin/reload, which calls reload and reports
+// the settings it changed.
+func (s *Server) WithReloader(reload func() ([]config.Change, error)) {
+	s.reload = reload
+}
+
+// WithMetrics records every request's count and latency in registry, labelled
+// by documented route, and serves registry on GET /metrics.
+func (s *Server) WithMetrics(registry *prometheus.Registry) {
+	s.registry = registry
+	s.httpStats = telemetry.NewHTTPMetrics(registry)
+}
+
+// WithFaults injects faults into API requests, such as dropped heartbeats, so
+// integration tests can exercise learners' retries and the health monitor.
+// Probes and metrics are never faulted.
+func (s *Server) WithFaults(injector *chaos.Injector) {
+	s.faults = injector
+}
+
+func (s *Server) authEnabled() bool {
+	return s.keyring != nil || s.jwt != nil
 }
 
 // Routes builds the HTTP router for the orchestrator service.
 func (s *Server) Routes() http.Handler {
 	r := chi.NewRouter()
+	read, learner, operator := s.require(auth.RoleReadOnly), s.require(auth.RoleLearner), s.require(auth.RoleOperator)
 	r.Route("/api/v1", func(r chi.Router) {
-		r.Post("/runs", s.handleCreateRun)
-		r.Get("/runs/{runID}", s.handleGetRun)
-		r.Post("/runs/{runID}/heartbeat", s.handleHeartbeat)
-		r.Post("/runs/{runID}/commands", s.handleCreateCommand)
-		r.Get("/runs/{runID}/commands/next", s.handleNextCommand)
-		r.Post("/runs/{runID}/commands/{commandID}/ack", s.handleAckCommand)
+		r.Post("/runs", operator(s.handleCreateRun))
+		r.Get("/runs", read(s.handleListRuns))
+		r.Get("/runs/{runID}", read(s.handleGetRun))
+		r.Method(http.MethodPatch, "/runs/{runID}", operator(s.handleUpdateRun))
+		r.Get("/runs/{runID}/token", operator(s.handleGetRunToken))
+		r.Post("/runs/{runID}/clone", operator(s.handleCloneRun))
+		r.Post("/runs/{runID}/archive", operator(s.handleArchiveRun))
+		r.Post("/runs/{runID}/unarchive", operator(s.handleUnarchiveRun))
+		r.Post("/experiments", operator(s.handleCreateExperiment))
+		r.Get("/experiments", read(s.handleListExperiments))
+		r.Get("/experiments/{experimentID}", read(s.handleGetExperiment))
+		r.Post("/experiments/{experimentID}/archive", operator(s.handleArchiveExperiment))
+		r.Get("/experiments/{experimentID}/runs", read(s.handleListExperimentRuns))
+		r.Get("/experiments/{experimentID}/leaderboard", read(s.handleLeaderboard))
+		r.Get("/experiments/{experimentID}/stats", read(s.handleExperimentStats))
+		r.Get("/experiments/{experimentID}/cost", read(s.handleExperimentCost))
+		r.Post("/experiments/{experimentID}/versions", operator(s.handleCreateVersion))
+		r.Get("/experiments/{experimentID}/versions", read(s.handleListVersions))
+		r.Get("/versions/{versionID}", read(s.handleGetVersion))
+		r.Get("/versions/{versionID}/diff", read(s.handleDiffVersions))
+		r.Post("/learners", learner(s.handleRegisterLearner))
+		r.Get("/learners", read(s.handleListLearners))
+		r.Get("/learners/{learnerID}", read(s.handleGetLearner))
+		r.Get("/learners/{learnerID}/runs", read(s.handleListLearnerRuns))
+		r.Post("/learners/{learnerID}/heartbeat", learner(s.handleLearnerHeartbeat))
+		r.Post("/learners/{learnerID}/deregister", learner(s.handleDeregisterLearner))
+		r.Post("/schedules", operator(s.handleCreateSchedule))
+		r.Get("/schedules", read(s.handleListSchedules))
+		r.Get("/schedules/{scheduleID}", read(s.handleGetSchedule))
+		r.Post("/schedules/{scheduleID}/pause", operator(s.handleSetScheduleEnabled(false)))
+		r.Post("/schedules/{scheduleID}/enable", operator(s.handleSetScheduleEnabled(true)))
+		r.Post("/templates", operator(s.handleCreateTemplate))
+		r.Get("/templates", read(s.handleListTemplates))
+		r.Get("/templates/{templateID}", read(s.handleGetTemplate))
+		r.Post("/templates/{templateID}/render", read(s.handleRenderTemplate))
+		r.Post("/manifest-schemas", operator(s.handleRegisterManifestSchema))
+		r.Get("/manifest-schemas", read(s.handleListManifestSchemas))
+		r.Get("/manifest-schemas/{env}", read(s.handleGetManifestSchema))
+		r.Post("/flags", operator(s.handlePutFeatureFlag))
+		r.Get("/flags", read(s.handleListFeatureFlags))
+		r.Get("/flags/{name}", read(s.handleGetFeatureFlag))
+		r.Get("/runs/{runID}/transitions", read(s.handleListTransitions))
+		r.Get("/runs/{runID}/graph", read(s.handleRunGraph))
+		r.Get("/runs/{runID}/export", read(s.handleExportRun))
+		r.Post("/runs/{runID}/checkpoints", learner(s.handleRegisterCheckpoint))
+		r.Get("/runs/{runID}/checkpoints", read(s.handleListCheckpoints))
+		r.Get("/runs/{runID}/checkpoints/latest", read(s.handleLatestCheckpoint))
+		r.Get("/runs/{runID}/checkpoints/best", read(s.handleBestCheckpoint))
+		r.Get("/runs/{runID}/checkpoints/{version}", read(s.handleGetCheckpoint))
+		r.Post("/runs/{runID}/checkpoints/{version}/promote", operator(s.handlePromoteCheckpoint))
+		r.Post("/runs/{runID}/evaluations", learner(s.handleRecordEvaluation))
+		r.Get("/runs/{runID}/evaluations", read(s.handleListEvaluations))
+		r.Get("/runs/{runID}/metrics", read(s.handleRunMetrics))
+		r.Post("/runs/{runID}/annotations", operator(s.handleCreateAnnotation))
+		r.Get("/runs/{runID}/annotations", read(s.handleListAnnotations))
+		r.Post("/runs/{runID}/logs", learner(s.runScoped(s.handleAppendLogs)))
+		r.Get("/runs/{runID}/logs", read(s.handleRunLogs))
+		r.Get("/runs/{runID}/event-log", read(s.handleRunEventLog))
+		r.Get("/runs/{runID}/hyperparameters", read(s.handleHyperparameterTimeline))
+		r.Get("/runs/{runID}/replay", read(s.handleRunReplay))
+		r.Get("/runs/{runID}/cost", read(s.handleRunCost))
+		r.Get("/runs/{runID}/flags", read(s.handleRunFeatureFlags))
+		r.Get("/runs/{runID}/context", read(s.handleRunContext))
+		r.Post("/runs/{runID}/stopping-rules", operator(s.handleCreateStoppingRule))
+		r.Get("/runs/{runID}/stopping-rules", read(s.handleListStoppingRules))
+		r.Post("/runs/{runID}/stopping-rules/{ruleID}/disable", operator(s.handleDisableStoppingRule))
+		r.Post("/runs/{runID}/alert-rules", operator(s.handleCreateAlertRule))
+		r.Get("/runs/{runID}/alert-rules", read(s.handleListAlertRules))
+		r.Post("/runs/{runID}/alert-rules/{ruleID}/disable", operator(s.handleDisableAlertRule))
+		r.Post("/runs/{runID}/artifacts", learner(s.handleCreateArtifact))
+		r.Get("/runs/{runID}/artifacts", read(s.handleListArtifacts))
+		r.Get("/runs/{runID}/artifacts/{name}", read(s.handleGetArtifact))
+		r.Post("/runs/{runID}/artifacts/{name}/complete", learner(s.handleCompleteArtifact))
+		r.Get("/runs/{runID}/artifacts/{name}/download", read(s.handleDownloadArtifact))
+		r.Post("/runs/{runID}/provision", operator(s.handleRunAction(types.RunActionProvision)))
+		r.Post("/runs/{runID}/start", operator(s.handleRunAction(types.RunActionStart)))
+		r.Post("/runs/{runID}/pause", operator(s.handleRunAction(types.RunActionPause)))
+		r.Post("/runs/{runID}/resume", operator(s.handleRunAction(types.RunActionResume)))
+		r.Post("/runs/{runID}/terminate", operator(s.handleRunAction(types.RunActionTerminate)))
+		r.Post("/runs/{runID}/complete", operator(s.handleRunAction(types.RunActionComplete)))
+		r.Post("/runs/{runID}/fail", operator(s.handleRunAction(types.RunActionFail)))
+		r.Post("/runs/{runID}/heartbeat", learner(s.runScoped(s.handleHeartbeat)))
+		r.Post("/heartbeats", learner(s.handleHeartbeatBatch))
+		r.Post("/runs/{runID}/commands", operator(s.handleCreateCommand))
+		r.Get("/runs/{runID}/commands", read(s.handleListCommands))
+		r.Get("/runs/{runID}/commands/next", learner(s.runScoped(s.handleNextCommand)))
+		r.Post("/runs/{runID}/commands/{commandID}/ack", learner(s.runScoped(s.handleAckCommand)))
+		r.Post("/runs/{runID}/commands/{commandID}/result", learner(s.runScoped(s.handleCommandResult)))
+		r.Post("/runs/{runID}/commands/{commandID}/requeue", operator(s.handleRequeueCommand))
+		r.Post("/runs/{runID}/commands/{commandID}/discard", operator(s.handleDiscardCommand))
+		r.Get("/commands/dead-letters", read(s.handleListDeadLetters))
+		r.Get("/audit", operator(s.handleListAudit))
+		r.Get("/openapi.json", read(s.handleOpenAPI))
+		if s.reload != nil {
+			r.Post("/admin/reload", operator(s.handleReload))
+		}
+		if s.keyring != nil {
+			r.Get("/keys", operator(s.handleListKeys))
+			r.Post("/keys", operator(s.handleCreateKey))
+			r.Post("/keys/{keyID}/revoke", operator(s.handleRevokeKey))
+		}
 	})
-	return r
+	handler := middleware.ValidateRequest(s.validator)(r)
+	handler = middleware.Audit(s.orch, *s.logger)(handler)
+	if s.limiter != nil {
+		// Inside authentication, so clients are told apart by their credentials.
+		handler = s.limiter.Handler(handler)
+	}
+	if s.authEnabled() {
+		handler = middleware.Authenticate(s.keyring, s.jwt)(handler)
+	}
+	handler = s.faults.HTTP(handler)
+	// Probes, metrics and the build sit outside authentication so orchestrators
+	// such as Kubernetes, and Prometheus, can reach them without credentials.
+	root := http.NewServeMux()
+	root.Handle("/", handler)
+	root.HandleFunc("GET /healthz", s.handleHealthz)
+	root.HandleFunc("GET /readyz", s.handleReadyz)
+	root.Handle("GET /version", buildinfo.Handler("orchestrator"))
+	if s.registry == nil {
+		return middleware.CorrelationID(root)
+	}
+	root.Handle("GET /metrics", metrics.Handler(s.registry))
+	return telemetry.HTTP("orchestrator", s.httpStats, s.routeTemplate)(middleware.CorrelationID(root))
+}
+
+// routeTemplate labels a request's metrics and span with the route it matched.
+func (s *Server) routeTemplate(r *http.Request) string {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics", "/version":
+		return r.URL.Path
+	}
+	return s.validator.Route(r.URL.Path)
+}
+
+// require wraps a handler with a role check; it is a no-op when auth is disabled.
+func (s *Server) require(role auth.Role) func(http.HandlerFunc) http.HandlerFunc {
+	if !s.authEnabled() {
+		return func(h http.HandlerFunc) http.HandlerFunc { return h }
+	}
+	return middleware.RequireRole(role)
+}
+
+// runScoped enforces run tokens on a learner endpoint when they are enabled.
+func (s *Server) runScoped(h http.HandlerFunc) http.HandlerFunc {
+	if s.runTokens == nil {
+		return h
+	}
+	return middleware.RequireRunToken(s.runTokens)(h)
+}
+
+// runTokenValid reports whether one of tokens was issued for runID, or run
+// tokens are disabled.
+func (s *Server) runTokenValid(runID string, tokens []string) bool {
+	if s.runTokens == nil {
+		return true
+	}
+	for _, token := range tokens {
+		if s.runTokens.Verify(runID, token) == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Server) handleCreateRun(w http.ResponseWriter, r *http.Request) {
@@ -60,7 +308,56 @@ func (s *Server) handleCreateRun(w http.ResponseWriter, r *http.Request) {
 		s.respondError(w, err)
 		return
 	}
-	s.writeJSON(w, http.StatusCreated, run)
+	s.writeCreatedRun(w, run)
+}
+
+// handleCloneRun creates a new queued run from an existing run's manifest, e.g.
+// to retry a failed run with a tweaked configuration.
+func (s *Server) handleCloneRun(w http.ResponseWriter, r *http.Request) {
+	var payload service.CloneRunInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	run, err := s.orch.CloneRun(r.Context(), chi.URLParam(r, "runID"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeCreatedRun(w, run)
+}
+
+// writeCreatedRun responds with a newly created run, including its run token
+// when tokens are enabled.
+func (s *Server) writeCreatedRun(w http.ResponseWriter, run types.Run) {
+	if s.runTokens == nil {
+		s.writeJSON(w, http.StatusCreated, run)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, struct {
+		types.Run
+		RunToken string `json:"run_token"`
+	}{run, s.runTokens.Issue(run.ID)})
+}
+
+// handleGetRunToken reissues the token for an existing run, e.g. for a launcher
+// restarting its learner. Tokens are derived from the run ID, so this returns the
+// same token handed out at creation.
+func (s *Server) handleGetRunToken(w http.ResponseWriter, r *http.Request) {
+	if s.runTokens == nil {
+		s.writeError(w, http.StatusNotFound, "run tokens are not enabled")
+		return
+	}
+	run, err := s.orch.GetRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]string{"run_id": run.ID, "run_token": s.runTokens.Issue(run.ID)})
 }
 
 func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
@@ -70,9 +367,177 @@ func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
 		s.respondError(w, err)
 		return
 	}
+	if notModified(w, r, runsETag([]types.Run{run}, "")) {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, run)
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor, err := storage.DecodeCursor(query.Get("cursor"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := storage.RunFilter{
+		Text:            strings.TrimSpace(query.Get("q")),
+		IncludeArchived: query.Get("include_archived") == "true",
+		After:           cursor,
+		Limit:           limit,
+	}
+	for _, value := range query["state"] {
+		for _, state := range strings.Split(value, ",") {
+			if state = strings.TrimSpace(state); state != "" {
+				filter.States = append(filter.States, types.RunState(state))
+			}
+		}
+	}
+	for _, selector := range query["label"] {
+		key, value, ok := strings.Cut(selector, "=")
+		if !ok || key == "" {
+			s.writeError(w, http.StatusBadRequest, "label must be key=value")
+			return
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[key] = value
+	}
+	runs, next, err := s.orch.ListRuns(r.Context(), filter)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	response := map[string]any{"runs": runs}
+	var nextCursor string
+	if !next.IsZero() {
+		nextCursor = next.Encode()
+		response["next_cursor"] = nextCursor
+	}
+	if notModified(w, r, runsETag(runs, nextCursor)) {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleUpdateRun(w http.ResponseWriter, r *http.Request) {
+	var payload service.UpdateRunInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	for _, path := range strings.Split(r.URL.Query().Get("update_mask"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			payload.UpdateMask = append(payload.UpdateMask, path)
+		}
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		payload.IfMatch = func(run types.Run) bool {
+			return etagMatches(ifMatch, runsETag([]types.Run{run}, ""))
+		}
+	}
+	run, err := s.orch.UpdateRun(r.Context(), chi.URLParam(r, "runID"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	w.Header().Set("ETag", runsETag([]types.Run{run}, ""))
+	s.writeJSON(w, http.StatusOK, run)
+}
+
+func (s *Server) handleExportRun(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "tar" {
+		s.writeError(w, http.StatusBadRequest, "format must be json or tar")
+		return
+	}
+	bundle, err := s.orch.ExportRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	filename := "run-" + bundle.Run.ID
+	if format != "tar" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		s.writeJSON(w, http.StatusOK, bundle)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".tar"))
+	w.WriteHeader(http.StatusOK)
+	if err := bundle.WriteTar(w); err != nil {
+		s.logger.Error().Err(err).Str("run_id", bundle.Run.ID).Msg("failed to write run export")
+	}
+}
+
+func (s *Server) handleArchiveRun(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	run, err := s.orch.ArchiveRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, run)
+}
+
+func (s *Server) handleUnarchiveRun(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	run, err := s.orch.UnarchiveRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
 	s.writeJSON(w, http.StatusOK, run)
 }
 
+func (s *Server) handleListTransitions(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "runID")
+	transitions, err := s.orch.ListTransitions(r.Context(), runID)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"transitions": transitions})
+}
+
+func (s *Server) handleRunGraph(w http.ResponseWriter, r *http.Request) {
+	graph, err := s.orch.RunGraph(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, graph)
+}
+
+func (s *Server) handleRunAction(action types.RunAction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runID := chi.URLParam(r, "runID")
+		r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBody)
+		defer r.Body.Close()
+		var payload struct {
+			ChangedBy string `json:"changed_by"`
+			Reason    string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			s.writeError(w, http.StatusBadRequest, "invalid transition payload")
+			return
+		}
+		run, err := s.orch.PerformAction(r.Context(), runID, action, payload.ChangedBy, payload.Reason)
+		if err != nil {
+			s.respondError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, run)
+	}
+}
+
 func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 	if ct := r.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "application/json") {
 		s.writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
@@ -94,6 +559,69 @@ func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, run)
 }
 
+// heartbeatResult reports the outcome of one item of a batch heartbeat.
+type heartbeatResult struct {
+	Index        int             `json:"index"`
+	RunID        string          `json:"run_id"`
+	Status       int             `json:"status"`
+	Code         apierror.Code   `json:"code,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	State        types.RunState  `json:"state,omitempty"`
+	HealthStatus types.RunHealth `json:"health_status,omitempty"`
+}
+
+// handleHeartbeatBatch applies an array of heartbeats in order, each validated on
+// its own, so one learner can report many runs (or flush buffered updates for one
+// run, oldest first) in a single request. The response is 200 with a result per
+// item whenever the batch itself is well formed.
+func (s *Server) handleHeartbeatBatch(w http.ResponseWriter, r *http.Request) {
+	if ct := r.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "application/json") {
+		s.writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBatchBody)
+	defer r.Body.Close()
+	var payloads []types.HeartbeatPayload
+	if err := json.NewDecoder(r.Body).Decode(&payloads); err != nil {
+		s.writeError(w, http.StatusBadRequest, "heartbeat batch must be a JSON array of heartbeat payloads")
+		return
+	}
+	if len(payloads) == 0 || len(payloads) > maxHeartbeatBatch {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("heartbeat batch must hold 1-%d items", maxHeartbeatBatch))
+		return
+	}
+	principal, authenticated := auth.PrincipalFrom(r.Context())
+	tokens := middleware.RunTokens(r)
+	results := make([]heartbeatResult, len(payloads))
+	accepted := 0
+	for i, payload := range payloads {
+		result := heartbeatResult{Index: i, RunID: payload.RunID, Status: http.StatusOK}
+		switch {
+		case payload.RunID == "":
+			result.Status, result.Code, result.Error = http.StatusBadRequest, apierror.CodeValidation, "run_id is required"
+		case authenticated && !principal.CanAccessRun(payload.RunID):
+			result.Status, result.Code, result.Error = http.StatusForbidden, apierror.CodeForbidden, "credentials are scoped to run "+principal.RunID
+		case !s.runTokenValid(payload.RunID, tokens):
+			result.Status, result.Code, result.Error = http.StatusForbidden, apierror.CodeForbidden, "missing or invalid "+middleware.RunTokenHeader+" for run "+payload.RunID
+		default:
+			run, err := s.orch.HandleHeartbeat(r.Context(), payload.RunID, payload)
+			if err != nil {
+				result.Code, result.Error = s.describeError(err)
+				result.Status = result.Code.Status()
+				break
+			}
+			result.State, result.HealthStatus = run.State, run.HealthStatus
+			accepted++
+		}
+		results[i] = result
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"accepted": accepted,
+		"rejected": len(payloads) - accepted,
+		"results":  results,
+	})
+}
+
 func (s *Server) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
 	runID := chi.URLParam(r, "runID")
 	r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBody)
@@ -104,6 +632,9 @@ func (s *Server) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
 		IssuedAt time.Time          `json:"issued_at"`
 		Actor    types.CommandActor `json:"actor"`
 		Payload  json.RawMessage    `json:"payload"`
+		// ExpiresAt or TTL (a Go duration relative to issued_at) bound delivery.
+		ExpiresAt *time.Time `json:"expires_at"`
+		TTL       string     `json:"ttl"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		s.writeError(w, http.StatusBadRequest, "invalid command payload")
@@ -115,6 +646,19 @@ func (s *Server) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
 	if payload.IssuedAt.IsZero() {
 		payload.IssuedAt = time.Now().UTC()
 	}
+	if payload.TTL != "" {
+		ttl, err := time.ParseDuration(payload.TTL)
+		if err != nil || ttl <= 0 {
+			s.writeError(w, http.StatusBadRequest, "ttl must be a positive duration")
+			return
+		}
+		expiresAt := payload.IssuedAt.Add(ttl)
+		payload.ExpiresAt = &expiresAt
+	}
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		// Authenticated callers cannot impersonate another actor.
+		payload.Actor = types.CommandActor{Type: types.CommandActorOperator, ID: principal.Subject}
+	}
 	command := types.RunCommand{
 		ID:        payload.ID,
 		RunID:     runID,
@@ -122,6 +666,7 @@ func (s *Server) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
 		Payload:   payload.Payload,
 		Actor:     payload.Actor,
 		IssuedAt:  payload.IssuedAt,
+		ExpiresAt: payload.ExpiresAt,
 		CreatedAt: time.Now().UTC(),
 	}
 	command, err := s.orch.CreateCommand(r.Context(), command)
@@ -132,9 +677,113 @@ func (s *Server) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusAccepted, command)
 }
 
+func (s *Server) handleListCommands(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "runID")
+	query := r.URL.Query()
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor, err := storage.DecodeCursor(query.Get("cursor"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := storage.CommandFilter{After: cursor, Limit: limit}
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.Statuses = append(filter.Statuses, types.CommandStatus(status))
+			}
+		}
+	}
+	commands, next, err := s.orch.ListCommands(r.Context(), runID, filter)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	response := map[string]any{"commands": commands}
+	if !next.IsZero() {
+		response["next_cursor"] = next.Encode()
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// handleListDeadLetters lists commands awaiting an operator decision across all
+// runs, or one run's with ?run_id=.
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor, err := storage.DecodeCursor(query.Get("cursor"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	commands, next, err := s.orch.ListDeadLetters(r.Context(), query.Get("run_id"), storage.CommandFilter{After: cursor, Limit: limit})
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	response := map[string]any{"commands": commands}
+	if !next.IsZero() {
+		response["next_cursor"] = next.Encode()
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleRequeueCommand(w http.ResponseWriter, r *http.Request) {
+	var payload service.RequeueCommandInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	cmd, err := s.orch.RequeueCommand(r.Context(), chi.URLParam(r, "runID"), chi.URLParam(r, "commandID"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, cmd)
+}
+
+func (s *Server) handleDiscardCommand(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	cmd, err := s.orch.DiscardCommand(r.Context(), chi.URLParam(r, "runID"), chi.URLParam(r, "commandID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, cmd)
+}
+
+// maxCommandWait caps ?wait= on /commands/next so long polls finish well inside
+// the server's write timeout.
+const maxCommandWait = 60 * time.Second
+
 func (s *Server) handleNextCommand(w http.ResponseWriter, r *http.Request) {
 	runID := chi.URLParam(r, "runID")
-	cmd, err := s.orch.NextCommand(r.Context(), runID)
+	var (
+		cmd types.RunCommand
+		err error
+	)
+	if raw := r.URL.Query().Get("wait"); raw != "" {
+		wait, parseErr := time.ParseDuration(raw)
+		if parseErr != nil || wait < 0 {
+			s.writeError(w, http.StatusBadRequest, "wait must be a non-negative duration")
+			return
+		}
+		if wait > maxCommandWait {
+			wait = maxCommandWait
+		}
+		cmd, err = s.orch.WaitForCommand(r.Context(), runID, wait)
+	} else {
+		cmd, err = s.orch.NextCommand(r.Context(), runID)
+	}
 	if err != nil {
 		s.respondError(w, err)
 		return
@@ -145,8 +794,23 @@ func (s *Server) handleNextCommand(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleAckCommand(w http.ResponseWriter, r *http.Request) {
 	runID := chi.URLParam(r, "runID")
 	commandID := chi.URLParam(r, "commandID")
+	r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBody)
 	defer r.Body.Close()
-	cmd, err := s.orch.AckCommand(r.Context(), runID, commandID)
+	// The ack body optionally carries the execution result.
+	var result types.CommandResult
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "invalid ack payload")
+		return
+	}
+	var (
+		cmd types.RunCommand
+		err error
+	)
+	if result.Status != "" {
+		cmd, err = s.orch.ReportCommandResult(r.Context(), runID, commandID, result)
+	} else {
+		cmd, err = s.orch.AckCommand(r.Context(), runID, commandID)
+	}
 	if err != nil {
 		s.respondError(w, err)
 		return
@@ -154,21 +818,1198 @@ func (s *Server) handleAckCommand(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, cmd)
 }
 
-func (s *Server) respondError(w http.ResponseWriter, err error) {
-	switch {
-	case errors.Is(err, storage.ErrNotFound):
-		s.writeError(w, http.StatusNotFound, err.Error())
-	case errors.Is(err, storage.ErrConflict):
-		s.writeError(w, http.StatusConflict, err.Error())
-	case errors.Is(err, storage.ErrNoCommands):
-		s.writeJSON(w, http.StatusNoContent, map[string]string{"message": "no pending commands"})
-	default:
-		s.writeError(w, http.StatusUnprocessableEntity, err.Error())
+func (s *Server) handleCommandResult(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "runID")
+	commandID := chi.URLParam(r, "commandID")
+	r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBody)
+	defer r.Body.Close()
+	var result types.CommandResult
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid result payload")
+		return
+	}
+	cmd, err := s.orch.ReportCommandResult(r.Context(), runID, commandID, result)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, cmd)
+}
+
+func (s *Server) handleCreateExperiment(w http.ResponseWriter, r *http.Request) {
+	var payload service.CreateExperimentInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	experiment, err := s.orch.CreateExperiment(r.Context(), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, experiment)
+}
+
+func (s *Server) handleListExperiments(w http.ResponseWriter, r *http.Request) {
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	experiments, err := s.orch.ListExperiments(r.Context(), includeArchived)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"experiments": experiments})
+}
+
+func (s *Server) handleGetExperiment(w http.ResponseWriter, r *http.Request) {
+	experimentID := chi.URLParam(r, "experimentID")
+	summary, err := s.orch.GetExperiment(r.Context(), experimentID)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, summary)
+}
+
+func (s *Server) handleArchiveExperiment(w http.ResponseWriter, r *http.Request) {
+	experimentID := chi.URLParam(r, "experimentID")
+	defer r.Body.Close()
+	experiment, err := s.orch.ArchiveExperiment(r.Context(), experimentID)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, experiment)
+}
+
+func (s *Server) handleListExperimentRuns(w http.ResponseWriter, r *http.Request) {
+	experimentID := chi.URLParam(r, "experimentID")
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	runs, err := s.orch.ListExperimentRuns(r.Context(), experimentID, includeArchived)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	if notModified(w, r, runsETag(runs, "")) {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"runs": runs})
+}
+
+func (s *Server) handleCreateVersion(w http.ResponseWriter, r *http.Request) {
+	experimentID := chi.URLParam(r, "experimentID")
+	var payload service.CreateVersionInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	version, err := s.orch.CreateVersion(r.Context(), experimentID, payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, version)
+}
+
+func (s *Server) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	experimentID := chi.URLParam(r, "experimentID")
+	versions, err := s.orch.ListVersions(r.Context(), experimentID)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"versions": versions})
+}
+
+func (s *Server) handleGetVersion(w http.ResponseWriter, r *http.Request) {
+	versionID := chi.URLParam(r, "versionID")
+	version, err := s.orch.GetVersion(r.Context(), versionID)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, version)
+}
+
+func (s *Server) handleDiffVersions(w http.ResponseWriter, r *http.Request) {
+	versionID := chi.URLParam(r, "versionID")
+	against := r.URL.Query().Get("against")
+	if against == "" {
+		s.writeError(w, http.StatusBadRequest, "against query parameter is required")
+		return
+	}
+	changes, err := s.orch.DiffVersions(r.Context(), against, versionID)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"base": against, "target": versionID, "changes": changes})
+}
+
+func (s *Server) handleRegisterCheckpoint(w http.ResponseWriter, r *http.Request) {
+	var payload service.RegisterCheckpointInput
+	r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBody)
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid checkpoint payload")
+		return
+	}
+	checkpoint, err := s.orch.RegisterCheckpoint(r.Context(), chi.URLParam(r, "runID"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, checkpoint)
+}
+
+func (s *Server) handleListCheckpoints(w http.ResponseWriter, r *http.Request) {
+	checkpoints, err := s.orch.ListCheckpoints(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"checkpoints": checkpoints})
+}
+
+func (s *Server) handleLatestCheckpoint(w http.ResponseWriter, r *http.Request) {
+	checkpoint, err := s.orch.LatestCheckpoint(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, checkpoint)
+}
+
+func (s *Server) handleBestCheckpoint(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var minimize bool
+	switch query.Get("order") {
+	case "", "max":
+	case "min":
+		minimize = true
+	default:
+		s.writeError(w, http.StatusBadRequest, "order must be min or max")
+		return
+	}
+	checkpoint, err := s.orch.BestCheckpoint(r.Context(), chi.URLParam(r, "runID"), query.Get("metric"), minimize)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, checkpoint)
+}
+
+func (s *Server) handleGetCheckpoint(w http.ResponseWriter, r *http.Request) {
+	version, ok := s.checkpointVersion(w, r)
+	if !ok {
+		return
+	}
+	checkpoint, err := s.orch.GetCheckpoint(r.Context(), chi.URLParam(r, "runID"), version)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, checkpoint)
+}
+
+func (s *Server) handlePromoteCheckpoint(w http.ResponseWriter, r *http.Request) {
+	version, ok := s.checkpointVersion(w, r)
+	if !ok {
+		return
+	}
+	defer r.Body.Close()
+	var payload struct {
+		PromotedBy string `json:"promoted_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "invalid promote payload")
+		return
+	}
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		payload.PromotedBy = principal.Subject
+	}
+	checkpoint, err := s.orch.PromoteCheckpoint(r.Context(), chi.URLParam(r, "runID"), version, payload.PromotedBy)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, checkpoint)
+}
+
+// checkpointVersion parses the {version} path parameter, writing a 400 when invalid.
+func (s *Server) checkpointVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	version, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 64)
+	if err != nil || version <= 0 {
+		s.writeError(w, http.StatusBadRequest, "checkpoint version must be a positive integer")
+		return 0, false
+	}
+	return version, true
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Document())
+}
+
+// handleHealthz reports liveness: the background loops are making progress.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.writeProbe(w, s.probes.Live(r.Context()))
+}
+
+// handleReadyz reports readiness: liveness plus the database and event publisher.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	s.writeProbe(w, s.probes.Ready(r.Context()))
+}
+
+func (s *Server) writeProbe(w http.ResponseWriter, report probe.Report) {
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	s.writeJSON(w, status, report)
+}
+
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor, err := storage.DecodeCursor(query.Get("cursor"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := storage.AuditFilter{
+		Actor:      query.Get("actor"),
+		Method:     query.Get("method"),
+		PathPrefix: query.Get("path_prefix"),
+		After:      cursor,
+		Limit:      limit,
+	}
+	if raw := query.Get("min_status"); raw != "" {
+		if filter.MinStatus, err = strconv.Atoi(raw); err != nil {
+			s.writeError(w, http.StatusBadRequest, "min_status must be an integer")
+			return
+		}
+	}
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := query.Get(name); raw != "" {
+			at, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = at
+		}
+	}
+	entries, next, err := s.orch.ListAudit(r.Context(), filter)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	response := map[string]any{"entries": entries}
+	if !next.IsZero() {
+		response["next_cursor"] = next.Encode()
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleRecordEvaluation(w http.ResponseWriter, r *http.Request) {
+	var payload types.Evaluation
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid evaluation payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	payload.RunID = chi.URLParam(r, "runID")
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		payload.Evaluator = principal.Subject
+	}
+	evaluation, err := s.orch.RecordEvaluation(r.Context(), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, evaluation)
+}
+
+func (s *Server) handleListEvaluations(w http.ResponseWriter, r *http.Request) {
+	var version int64
+	if raw := r.URL.Query().Get("checkpoint_version"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed <= 0 {
+			s.writeError(w, http.StatusBadRequest, "checkpoint_version must be a positive integer")
+			return
+		}
+		version = parsed
+	}
+	evaluations, err := s.orch.ListEvaluations(r.Context(), chi.URLParam(r, "runID"), version)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"evaluations": evaluations})
+}
+
+func (s *Server) handleCreateStoppingRule(w http.ResponseWriter, r *http.Request) {
+	var payload service.CreateStoppingRuleInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid stopping rule payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	rule, err := s.orch.CreateStoppingRule(r.Context(), chi.URLParam(r, "runID"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, rule)
+}
+
+func (s *Server) handleListStoppingRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.orch.ListStoppingRules(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"stopping_rules": rules})
+}
+
+func (s *Server) handleDisableStoppingRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	rule, err := s.orch.DisableStoppingRule(r.Context(), chi.URLParam(r, "runID"), chi.URLParam(r, "ruleID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, rule)
+}
+
+func (s *Server) handleCreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var payload service.CreateAlertRuleInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid alert rule payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	rule, err := s.orch.CreateAlertRule(r.Context(), chi.URLParam(r, "runID"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, rule)
+}
+
+func (s *Server) handleListAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.orch.ListAlertRules(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"alert_rules": rules})
+}
+
+func (s *Server) handleDisableAlertRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	rule, err := s.orch.DisableAlertRule(r.Context(), chi.URLParam(r, "runID"), chi.URLParam(r, "ruleID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, rule)
+}
+
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	leaderboardQuery := service.LeaderboardQuery{Metric: query.Get("metric"), Suite: query.Get("suite")}
+	switch query.Get("order") {
+	case "", "max":
+	case "min":
+		leaderboardQuery.Minimize = true
+	default:
+		s.writeError(w, http.StatusBadRequest, "order must be min or max")
+		return
+	}
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	leaderboardQuery.Limit = limit
+	entries, err := s.orch.Leaderboard(r.Context(), chi.URLParam(r, "experimentID"), leaderboardQuery)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"leaderboard": entries})
+}
+
+func (s *Server) handleExperimentStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.orch.ExperimentStats(r.Context(), chi.URLParam(r, "experimentID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleExperimentCost(w http.ResponseWriter, r *http.Request) {
+	cost, err := s.orch.ExperimentCost(r.Context(), chi.URLParam(r, "experimentID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, cost)
+}
+
+func (s *Server) handleRunCost(w http.ResponseWriter, r *http.Request) {
+	cost, err := s.orch.RunCost(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, cost)
+}
+
+func (s *Server) handleCreateAnnotation(w http.ResponseWriter, r *http.Request) {
+	var payload service.CreateAnnotationInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid annotation payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		payload.Author = principal.Subject
+	}
+	annotation, err := s.orch.CreateAnnotation(r.Context(), chi.URLParam(r, "runID"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, annotation)
+}
+
+func (s *Server) handleListAnnotations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var from, to time.Time
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if raw := query.Get(name); raw != "" {
+			at, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = at
+		}
+	}
+	annotations, err := s.orch.ListAnnotations(r.Context(), chi.URLParam(r, "runID"), from, to)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"annotations": annotations})
+}
+
+func (s *Server) handleRunMetrics(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var metricsQuery service.MetricsQuery
+	for name, dst := range map[string]*time.Time{"from": &metricsQuery.From, "to": &metricsQuery.To} {
+		if raw := query.Get(name); raw != "" {
+			at, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = at
+		}
+	}
+	if raw := query.Get("resolution"); raw != "" {
+		resolution, err := time.ParseDuration(raw)
+		if err != nil || resolution < time.Second {
+			s.writeError(w, http.StatusBadRequest, "resolution must be a duration of at least 1s")
+			return
+		}
+		metricsQuery.Resolution = resolution
+	}
+	series, err := s.orch.RunMetrics(r.Context(), chi.URLParam(r, "runID"), metricsQuery)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, series)
+}
+
+func (s *Server) handleAppendLogs(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLogBatchBody)
+	defer r.Body.Close()
+	var payload struct {
+		Lines []types.LogLine `json:"lines"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid log payload")
+		return
+	}
+	if len(payload.Lines) > maxLogBatch {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d lines per request", maxLogBatch))
+		return
+	}
+	lines, err := s.orch.AppendRunLogs(r.Context(), chi.URLParam(r, "runID"), payload.Lines)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"appended": len(lines), "last_seq": lines[len(lines)-1].Seq})
+}
+
+// handleRunLogs pages through a run's logs, or with follow=true tails them as
+// server-sent events until the client disconnects or the run has ended and its
+// logs are drained. Each event carries the line's seq as its id, so reconnecting
+// clients resume from Last-Event-ID.
+func (s *Server) handleRunLogs(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "runID")
+	query := r.URL.Query()
+	filter := storage.LogFilter{MinLevel: types.LogLevel(query.Get("level"))}
+	after := query.Get("after")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		after = id
+	}
+	if after != "" {
+		seq, err := strconv.ParseInt(after, 10, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "after must be an integer")
+			return
+		}
+		filter.After = seq
+	}
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Limit = limit
+	lines, err := s.orch.RunLogs(r.Context(), runID, filter)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	if query.Get("follow") != "true" {
+		next := filter.After
+		if len(lines) > 0 {
+			next = lines[len(lines)-1].Seq
+		}
+		s.writeJSON(w, http.StatusOK, map[string]any{"run_id": runID, "lines": lines, "next_after": next})
+		return
+	}
+	s.streamLogs(w, r, runID, filter, lines)
+}
+
+func (s *Server) streamLogs(w http.ResponseWriter, r *http.Request, runID string, filter storage.LogFilter, lines []types.LogLine) {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout; keepalives detect dead clients.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.logger.Warn().Err(err).Str("run_id", runID).Msg("failed to clear write deadline for log stream")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	filter.Limit = maxPageSize
+	for {
+		for _, line := range lines {
+			data, err := json.Marshal(line)
+			if err != nil {
+				s.logger.Error().Err(err).Str("run_id", runID).Msg("failed to encode log line")
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", line.Seq, data); err != nil {
+				return
+			}
+			filter.After = line.Seq
+		}
+		if len(lines) == 0 {
+			run, err := s.orch.GetRun(ctx, runID)
+			if err != nil {
+				return
+			}
+			if run.State.IsTerminal() {
+				fmt.Fprintf(w, "event: end\ndata: {\"state\":%q}\n\n", run.State)
+				rc.Flush()
+				return
+			}
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		var err error
+		lines, err = s.orch.WaitForLogs(ctx, runID, filter, logKeepAlive)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleRunEventLog lets consumers that missed events on the broker backfill
+// them: since bounds the publish time and after pages by event ID.
+func (s *Server) handleRunEventLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := storage.EventFilter{Type: query.Get("type")}
+	if raw := query.Get("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		filter.Since = since
+	}
+	if raw := query.Get("after"); raw != "" {
+		after, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "after must be an integer")
+			return
+		}
+		filter.After = after
+	}
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Limit = limit
+	runID := chi.URLParam(r, "runID")
+	logged, err := s.orch.RunEventLog(r.Context(), runID, filter)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	next := filter.After
+	if len(logged) > 0 {
+		next = logged[len(logged)-1].ID
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"run_id": runID, "events": logged, "next_after": next})
+}
+
+func (s *Server) handleHyperparameterTimeline(w http.ResponseWriter, r *http.Request) {
+	timeline, err := s.orch.HyperparameterTimeline(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"timeline": timeline})
+}
+
+// handleRunReplay reports the run's replay buffer activity next to its health.
+// replay is null until the poller has seen the run.
+func (s *Server) handleRunReplay(w http.ResponseWriter, r *http.Request) {
+	run, err := s.orch.GetRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"run_id":            run.ID,
+		"state":             run.State,
+		"health_status":     run.HealthStatus,
+		"last_heartbeat_at": run.LastHeartbeatAt,
+		"replay":            run.Replay,
+	})
+}
+
+func (s *Server) handleCreateArtifact(w http.ResponseWriter, r *http.Request) {
+	var payload service.CreateArtifactInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid artifact payload")
+		return
+	}
+	upload, err := s.orch.CreateArtifactUpload(r.Context(), chi.URLParam(r, "runID"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, upload)
+}
+
+func (s *Server) handleListArtifacts(w http.ResponseWriter, r *http.Request) {
+	artifacts, err := s.orch.ListArtifacts(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"artifacts": artifacts})
+}
+
+func (s *Server) handleGetArtifact(w http.ResponseWriter, r *http.Request) {
+	artifact, err := s.orch.GetArtifact(r.Context(), chi.URLParam(r, "runID"), chi.URLParam(r, "name"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, artifact)
+}
+
+func (s *Server) handleCompleteArtifact(w http.ResponseWriter, r *http.Request) {
+	var payload service.CompleteArtifactInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "invalid artifact payload")
+		return
+	}
+	artifact, err := s.orch.CompleteArtifactUpload(r.Context(), chi.URLParam(r, "runID"), chi.URLParam(r, "name"), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, artifact)
+}
+
+func (s *Server) handleDownloadArtifact(w http.ResponseWriter, r *http.Request) {
+	download, err := s.orch.ArtifactDownload(r.Context(), chi.URLParam(r, "runID"), chi.URLParam(r, "name"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, download)
+}
+
+func (s *Server) handleRegisterLearner(w http.ResponseWriter, r *http.Request) {
+	var payload service.RegisterLearnerInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	learner, err := s.orch.RegisterLearner(r.Context(), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, learner)
+}
+
+func (s *Server) handleLearnerHeartbeat(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBody)
+	defer r.Body.Close()
+	var heartbeat types.LearnerHeartbeat
+	if err := json.NewDecoder(r.Body).Decode(&heartbeat); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "invalid learner heartbeat payload")
+		return
+	}
+	learner, err := s.orch.LearnerHeartbeat(r.Context(), chi.URLParam(r, "learnerID"), heartbeat)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, learner)
+}
+
+func (s *Server) handleListLearners(w http.ResponseWriter, r *http.Request) {
+	learners, err := s.orch.ListLearners(r.Context())
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"learners": learners})
+}
+
+func (s *Server) handleGetLearner(w http.ResponseWriter, r *http.Request) {
+	learner, err := s.orch.GetLearner(r.Context(), chi.URLParam(r, "learnerID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, learner)
+}
+
+func (s *Server) handleListLearnerRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.orch.ListLearnerRuns(r.Context(), chi.URLParam(r, "learnerID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"runs": runs})
+}
+
+func (s *Server) handleDeregisterLearner(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if err := s.orch.DeregisterLearner(r.Context(), chi.URLParam(r, "learnerID")); err != nil {
+		s.respondError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var payload service.CreateScheduleInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	schedule, err := s.orch.CreateSchedule(r.Context(), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, schedule)
+}
+
+func (s *Server) handleListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := s.orch.ListSchedules(r.Context())
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"schedules": schedules})
+}
+
+func (s *Server) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := s.orch.GetSchedule(r.Context(), chi.URLParam(r, "scheduleID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, schedule)
+}
+
+func (s *Server) handleSetScheduleEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		schedule, err := s.orch.SetScheduleEnabled(r.Context(), chi.URLParam(r, "scheduleID"), enabled)
+		if err != nil {
+			s.respondError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, schedule)
+	}
+}
+
+func (s *Server) handleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var payload service.CreateTemplateInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if payload.ID == "" {
+		payload.ID = generateID()
+	}
+	template, err := s.orch.CreateTemplate(r.Context(), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, template)
+}
+
+func (s *Server) handleRegisterManifestSchema(w http.ResponseWriter, r *http.Request) {
+	var payload service.RegisterManifestSchemaInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	schema, err := s.orch.RegisterManifestSchema(r.Context(), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, schema)
+}
+
+func (s *Server) handleListManifestSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas, err := s.orch.ListManifestSchemas(r.Context())
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"manifest_schemas": schemas})
+}
+
+func (s *Server) handleGetManifestSchema(w http.ResponseWriter, r *http.Request) {
+	schema, err := s.orch.GetManifestSchema(r.Context(), chi.URLParam(r, "env"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, schema)
+}
+
+func (s *Server) handlePutFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	var payload service.PutFeatureFlagInput
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	flag, err := s.orch.PutFeatureFlag(r.Context(), payload)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, flag)
+}
+
+func (s *Server) handleListFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := s.orch.ListFeatureFlags(r.Context())
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"flags": flags})
+}
+
+func (s *Server) handleGetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	flag, err := s.orch.GetFeatureFlag(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, flag)
+}
+
+func (s *Server) handleRunFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := s.orch.RunFeatureFlags(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, flags)
+}
+
+func (s *Server) handleRunContext(w http.ResponseWriter, r *http.Request) {
+	runContext, err := s.orch.RunContext(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, runContext)
+}
+
+func (s *Server) handleListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := s.orch.ListTemplates(r.Context())
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"templates": templates})
+}
+
+func (s *Server) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := s.orch.GetTemplate(r.Context(), chi.URLParam(r, "templateID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, template)
+}
+
+// handleRenderTemplate previews the manifest a run would get for the parameters.
+func (s *Server) handleRenderTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID := chi.URLParam(r, "templateID")
+	defer r.Body.Close()
+	var payload struct {
+		Parameters map[string]json.RawMessage `json:"parameters"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	manifest, err := s.orch.RenderTemplate(r.Context(), templateID, payload.Parameters)
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"template_id": templateID, "manifest": manifest})
+}
+
+// handleReload re-reads the configuration. A configuration that fails to load
+// is rejected and the running one kept.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	changes, err := s.reload()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "configuration reload rejected: "+err.Error())
+		return
+	}
+	if changes == nil {
+		changes = []config.Change{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"changes": changes})
+}
+
+func (s *Server) handleListKeys(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, map[string]any{"keys": s.keyring.List()})
+}
+
+func (s *Server) handleCreateKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var payload struct {
+		Name string    `json:"name"`
+		Role auth.Role `json:"role"`
+		TTL  string    `json:"ttl,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	var ttl time.Duration
+	if payload.TTL != "" {
+		parsed, err := time.ParseDuration(payload.TTL)
+		if err != nil || parsed < 0 {
+			s.writeError(w, http.StatusBadRequest, "ttl must be a positive duration")
+			return
+		}
+		ttl = parsed
+	}
+	key, secret, err := s.keyring.Create(payload.Name, payload.Role, ttl)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, map[string]any{"key": key, "secret": secret})
+}
+
+func (s *Server) handleRevokeKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	key, err := s.keyring.Revoke(chi.URLParam(r, "keyID"))
+	if err != nil {
+		s.respondError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, key)
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// parseLimit reads a page size query parameter, applying the default and cap.
+func parseLimit(raw string) (int, error) {
+	if raw == "" {
+		return defaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return limit, nil
+}
+
+func (s *Server) respondError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrNoCommands) {
+		s.writeJSON(w, http.StatusNoContent, map[string]string{"message": "no pending commands"})
+		return
+	}
+	code, message := s.describeError(err)
+	var details any
+	var manifestErr *service.ManifestError
+	if errors.As(err, &manifestErr) {
+		details = manifestErr.Fields
+	}
+	apierror.Write(w, code, message, details)
+}
+
+// describeError returns the error code and client-facing message for err.
+// Unclassified errors are logged and reported as internal without their text.
+func (s *Server) describeError(err error) (apierror.Code, string) {
+	code := errorCode(err)
+	if code == apierror.CodeInternal {
+		s.logger.Error().Err(err).Msg("request failed")
+		return code, "internal error"
+	}
+	return code, err.Error()
+}
+
+// errorCode maps service and storage errors onto API error codes.
+func errorCode(err error) apierror.Code {
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, auth.ErrKeyNotFound):
+		return apierror.CodeNotFound
+	case errors.Is(err, service.ErrRegression):
+		return apierror.CodeRegression
+	case errors.Is(err, storage.ErrConflict), errors.Is(err, types.ErrInvalidTransition):
+		return apierror.CodeStateConflict
+	case errors.Is(err, service.ErrPreconditionFailed):
+		return apierror.CodePreconditionFailed
+	case errors.Is(err, service.ErrValidation), errors.Is(err, storage.ErrInvalidCursor):
+		return apierror.CodeValidation
+	case errors.Is(err, service.ErrAdmissionDenied):
+		return apierror.CodeAdmissionDenied
+	case errors.Is(err, service.ErrArtifactsDisabled), errors.Is(err, service.ErrAdmissionUnavailable):
+		return apierror.CodeUnavailable
+	default:
+		return apierror.CodeInternal
+	}
+}
+
+// runsETag derives a weak validator for a run or page of runs. Every change to a
+// run bumps its UpdatedAt; queue positions shift as other runs are dispatched, and
+// the cursor covers runs appended beyond a full page.
+func runsETag(runs []types.Run, nextCursor string) string {
+	h := sha256.New()
+	for _, run := range runs {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", run.ID, run.UpdatedAt.UnixNano(), run.QueuePosition)
+	}
+	h.Write([]byte(nextCursor))
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag header and, when the request's If-None-Match already
+// names it, answers 304 Not Modified and reports true.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// etagMatches reports whether the comma-separated list of entity tags in header
+// names etag or is "*". Tags are compared weakly, since runsETag is weak.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
 	}
+	return false
 }
 
 func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
-	s.writeJSON(w, status, map[string]string{"error": message})
+	apierror.Write(w, apierror.ForStatus(status), message, nil)
 }
 
 func (s *Server) writeJSON(w http.ResponseWriter, status int, payload interface{}) {
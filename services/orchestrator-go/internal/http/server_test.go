@@ -1,19 +1,40 @@
 package http
 
 import (
+	"archive/tar"
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/rs/zerolog"
 
+	"github.com/cartridge/orchestrator/internal/apierror"
+	"github.com/cartridge/orchestrator/internal/artifacts"
+	"github.com/cartridge/orchestrator/internal/auth"
+	"github.com/cartridge/orchestrator/internal/config"
 	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/middleware"
+	"github.com/cartridge/orchestrator/internal/openapi"
+	"github.com/cartridge/orchestrator/internal/probe"
 	"github.com/cartridge/orchestrator/internal/service"
 	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+	"github.com/cartridge/telemetry/buildinfo"
+	"github.com/cartridge/telemetry/metrics"
 )
 
 func TestCreateRunAndHeartbeat(t *testing.T) {
@@ -99,4 +120,3456 @@ func TestCommandLifecycle(t *testing.T) {
 	if ackRes.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", ackRes.Code)
 	}
+
+	resultBody, _ := json.Marshal(map[string]any{"status": "succeeded", "applied": map[string]any{"paused_at_step": 1200}})
+	resultRes := httptest.NewRecorder()
+	server.Routes().ServeHTTP(resultRes, httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-2/commands/cmd-1/result", bytes.NewReader(resultBody)))
+	if resultRes.Code != http.StatusOK {
+		t.Fatalf("expected 200 reporting result, got %d: %s", resultRes.Code, resultRes.Body.String())
+	}
+	var reported types.RunCommand
+	if err := json.Unmarshal(resultRes.Body.Bytes(), &reported); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if reported.Result == nil || reported.Result.Status != types.CommandResultSucceeded || string(reported.Result.Applied) != `{"paused_at_step":1200}` {
+		t.Fatalf("expected stored result, got %+v", reported.Result)
+	}
+	dupRes := httptest.NewRecorder()
+	server.Routes().ServeHTTP(dupRes, httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-2/commands/cmd-1/result", bytes.NewReader(resultBody)))
+	if dupRes.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a second result, got %d", dupRes.Code)
+	}
+}
+
+func TestHyperparameterTimeline(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	now := time.Now().UTC()
+	orch.WithNow(func() time.Time { return now })
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	heartbeat := func(step int) {
+		t.Helper()
+		if res := call(http.MethodPost, "/api/v1/runs/run-hp/heartbeat", map[string]any{"run_id": "run-hp", "status": "running", "step": step}); res.Code != http.StatusAccepted && res.Code != http.StatusOK {
+			t.Fatalf("heartbeat: got %d: %s", res.Code, res.Body.String())
+		}
+	}
+	deliver := func(want string) {
+		t.Helper()
+		res := call(http.MethodGet, "/api/v1/runs/run-hp/commands/next", nil)
+		var cmd types.RunCommand
+		json.Unmarshal(res.Body.Bytes(), &cmd)
+		if res.Code != http.StatusOK || cmd.ID != want {
+			t.Fatalf("expected %s delivered, got %d %+v", want, res.Code, cmd)
+		}
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-hp", "experiment_id": "exp-1", "version_id": "ver-1"})
+	call(http.MethodPost, "/api/v1/runs/run-hp/provision", map[string]any{})
+	call(http.MethodPost, "/api/v1/runs/run-hp/start", map[string]any{})
+	heartbeat(100)
+	actor := map[string]any{"type": "operator", "id": "tester"}
+	for i, cmd := range []map[string]any{
+		{"id": "cmd-lr", "type": "tune", "payload": map[string]any{"learning_rate": 0.001}},
+		{"id": "cmd-pause", "type": "pause"},
+		{"id": "cmd-ent", "type": "tune", "payload": map[string]any{"entropy_coef": 0.05}},
+	} {
+		cmd["issued_at"], cmd["actor"] = now.Add(time.Duration(i)*time.Second), actor
+		if res := call(http.MethodPost, "/api/v1/runs/run-hp/commands", cmd); res.Code != http.StatusAccepted {
+			t.Fatalf("create %s: got %d: %s", cmd["id"], res.Code, res.Body.String())
+		}
+	}
+
+	deliver("cmd-lr")
+	res := call(http.MethodPost, "/api/v1/runs/run-hp/commands/cmd-lr/ack", map[string]any{
+		"status": "succeeded", "applied": map[string]any{"learning_rate": 0.001}, "previous": map[string]any{"learning_rate": 0.0003},
+	})
+	var acked types.RunCommand
+	json.Unmarshal(res.Body.Bytes(), &acked)
+	if res.Code != http.StatusOK || acked.Result == nil || acked.Result.Step != 100 || string(acked.Result.Previous) != `{"learning_rate":0.0003}` {
+		t.Fatalf("expected the result recorded at step 100 with previous values, got %d %+v", res.Code, acked.Result)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-hp/commands/cmd-lr/ack", map[string]any{"status": "succeeded", "previous": []int{1}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected a non-object previous to be rejected, got %d", res.Code)
+	}
+
+	now = now.Add(time.Minute)
+	heartbeat(250)
+	deliver("cmd-pause")
+	call(http.MethodPost, "/api/v1/runs/run-hp/commands/cmd-pause/result", map[string]any{"status": "succeeded"})
+	deliver("cmd-ent")
+	call(http.MethodPost, "/api/v1/runs/run-hp/commands/cmd-ent/result", map[string]any{"status": "failed", "message": "entropy schedule is fixed"})
+
+	res = call(http.MethodGet, "/api/v1/runs/run-hp/hyperparameters", nil)
+	var body struct {
+		Timeline []types.HyperparameterChange `json:"timeline"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &body)
+	if res.Code != http.StatusOK || len(body.Timeline) != 2 {
+		t.Fatalf("expected two tune entries, got %d %+v", res.Code, body.Timeline)
+	}
+	first, second := body.Timeline[0], body.Timeline[1]
+	if first.CommandID != "cmd-lr" || first.Step != 100 || string(first.Before) != `{"learning_rate":0.0003}` || string(first.After) != `{"learning_rate":0.001}` || string(first.Requested) != `{"learning_rate":0.001}` {
+		t.Fatalf("unexpected first entry %+v", first)
+	}
+	if second.CommandID != "cmd-ent" || second.Step != 250 || second.Status != types.CommandResultFailed || second.Message == "" || !second.AppliedAt.After(first.AppliedAt) {
+		t.Fatalf("unexpected second entry %+v", second)
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/missing/hyperparameters", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown run, got %d", res.Code)
+	}
+}
+
+func TestRunReplayStats(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(path string) (int, map[string]json.RawMessage) {
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]json.RawMessage
+		json.Unmarshal(res.Body.Bytes(), &body)
+		return res.Code, body
+	}
+
+	body, _ := json.Marshal(map[string]any{"id": "run-rb", "experiment_id": "exp-1", "version_id": "ver-1"})
+	routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+	if code, body := call("/api/v1/runs/run-rb/replay"); code != http.StatusOK || string(body["replay"]) != "null" || string(body["health_status"]) != `"healthy"` {
+		t.Fatalf("expected null replay stats before the first poll, got %d %v", code, body)
+	}
+
+	polled := types.ReplayStats{EnvID: "pong", Transitions: 500, Fill: 0.5, IngestRate: 12.5, Stalled: true, PolledAt: time.Now().UTC()}
+	if _, err := orch.RecordReplayStats(context.Background(), "run-rb", polled); err != nil {
+		t.Fatalf("record replay stats: %v", err)
+	}
+	code, resp := call("/api/v1/runs/run-rb/replay")
+	var got types.ReplayStats
+	json.Unmarshal(resp["replay"], &got)
+	if code != http.StatusOK || got.EnvID != "pong" || got.IngestRate != 12.5 || !got.Stalled {
+		t.Fatalf("unexpected replay stats %d %+v", code, got)
+	}
+	if code, _ := call("/api/v1/runs/missing/replay"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown run, got %d", code)
+	}
+}
+
+func TestRunLogs(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, payload any) (int, map[string]json.RawMessage) {
+		var reader io.Reader
+		if payload != nil {
+			body, _ := json.Marshal(payload)
+			reader = bytes.NewReader(body)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		var body map[string]json.RawMessage
+		json.Unmarshal(res.Body.Bytes(), &body)
+		return res.Code, body
+	}
+	push := func(lines ...map[string]any) (int, map[string]json.RawMessage) {
+		return call(http.MethodPost, "/api/v1/runs/run-logs/logs", map[string]any{"lines": lines})
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-logs", "experiment_id": "exp-1", "version_id": "ver-1"})
+	code, body := push(
+		map[string]any{"message": "starting learner", "source": "learner"},
+		map[string]any{"level": "error", "message": "actor-2 lost connection", "source": "actor-2", "fields": map[string]any{"retry": 3}},
+	)
+	if code != http.StatusOK || string(body["last_seq"]) != "2" {
+		t.Fatalf("expected two lines appended, got %d %v", code, body)
+	}
+	if code, _ := push(map[string]any{"level": "fatal", "message": "boom"}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown level, got %d", code)
+	}
+
+	var lines []types.LogLine
+	code, body = call(http.MethodGet, "/api/v1/runs/run-logs/logs?level=warn", nil)
+	json.Unmarshal(body["lines"], &lines)
+	if code != http.StatusOK || len(lines) != 1 || lines[0].Seq != 2 || lines[0].Fields["retry"] != float64(3) {
+		t.Fatalf("expected only the error line, got %d %+v", code, lines)
+	}
+	code, body = call(http.MethodGet, "/api/v1/runs/run-logs/logs?after=1", nil)
+	json.Unmarshal(body["lines"], &lines)
+	if code != http.StatusOK || len(lines) != 1 || string(body["next_after"]) != "2" {
+		t.Fatalf("expected paging after seq 1, got %d %v", code, body)
+	}
+
+	// Follow over a real connection: the backlog after Last-Event-ID, then lines
+	// as they are pushed, then an end event once the run is over.
+	server := httptest.NewServer(routes)
+	defer server.Close()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/runs/run-logs/logs?follow=true", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("follow: %v", err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+	stream := bufio.NewReader(res.Body)
+	next := func() (event, id, data string) {
+		t.Helper()
+		for {
+			line, err := stream.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && event != "":
+				return event, id, data
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+	if event, id, _ := next(); event != "log" || id != "2" {
+		t.Fatalf("expected the backlog to resume at seq 2, got %s %s", event, id)
+	}
+	push(map[string]any{"message": "checkpoint saved"})
+	if event, id, data := next(); event != "log" || id != "3" || !strings.Contains(data, "checkpoint saved") {
+		t.Fatalf("expected the pushed line, got %s %s %s", event, id, data)
+	}
+	push(map[string]any{"message": "shutting down"})
+	if event, id, _ := next(); event != "log" || id != "4" {
+		t.Fatalf("expected the next pushed line, got %s %s", event, id)
+	}
+	for _, action := range []string{"provision", "start", "fail"} {
+		call(http.MethodPost, "/api/v1/runs/run-logs/"+action, nil)
+	}
+	if event, _, data := next(); event != "end" || !strings.Contains(data, "failed") {
+		t.Fatalf("expected the stream to end with the run, got %s %s", event, data)
+	}
+
+	if code, _ := call(http.MethodGet, "/api/v1/runs/missing/logs?follow=true", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown run, got %d", code)
+	}
+}
+
+func TestRunEventLog(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.Persisting(events.NoopPublisher{}, store), logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, payload any) (int, map[string]json.RawMessage) {
+		var reader io.Reader
+		if payload != nil {
+			body, _ := json.Marshal(payload)
+			reader = bytes.NewReader(body)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		var body map[string]json.RawMessage
+		json.Unmarshal(res.Body.Bytes(), &body)
+		return res.Code, body
+	}
+	list := func(query string) (int, []types.RunEvent, string) {
+		t.Helper()
+		code, body := call(http.MethodGet, "/api/v1/runs/run-ev/event-log"+query, nil)
+		var logged []types.RunEvent
+		json.Unmarshal(body["events"], &logged)
+		return code, logged, string(body["next_after"])
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-ev", "experiment_id": "exp-1", "version_id": "ver-1"})
+	started := time.Now().UTC()
+	for _, action := range []string{"provision", "start"} {
+		call(http.MethodPost, "/api/v1/runs/run-ev/"+action, nil)
+	}
+	call(http.MethodPost, "/api/v1/runs/run-ev/commands", map[string]any{
+		"id":        "cmd-1",
+		"type":      "pause",
+		"issued_at": started,
+		"actor":     map[string]any{"type": "operator", "id": "tester"},
+	})
+
+	code, logged, next := list("")
+	if code != http.StatusOK || len(logged) != 3 || next != fmt.Sprint(logged[2].ID) {
+		t.Fatalf("expected two status events and a command event, got %d %+v", code, logged)
+	}
+	var status events.RunStatusEvent
+	json.Unmarshal(logged[1].Payload, &status)
+	if logged[1].Type != events.EventTypeRunStatus || status.State != "running" || status.PreviousState != "provisioning" {
+		t.Fatalf("unexpected status event %+v", logged[1])
+	}
+	if _, logged, _ := list("?type=command"); len(logged) != 1 || logged[0].Type != events.EventTypeCommand {
+		t.Fatalf("expected the command event only, got %+v", logged)
+	}
+	if _, page, next := list("?limit=2"); len(page) != 2 || next != fmt.Sprint(logged[1].ID) {
+		t.Fatalf("expected a first page of two, got %+v next %s", page, next)
+	}
+	if _, page, _ := list("?after=" + fmt.Sprint(logged[1].ID)); len(page) != 1 || page[0].ID != logged[2].ID {
+		t.Fatalf("expected the remaining event after the cursor, got %+v", page)
+	}
+	if _, page, _ := list("?since=" + started.Add(time.Hour).Format(time.RFC3339)); len(page) != 0 {
+		t.Fatalf("expected nothing published in the future, got %+v", page)
+	}
+	if code, _, _ := list("?since=yesterday"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed since, got %d", code)
+	}
+	if code, _ := call(http.MethodGet, "/api/v1/runs/missing/event-log", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown run, got %d", code)
+	}
+}
+
+func TestRunAnnotations(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, payload any) (int, map[string]json.RawMessage) {
+		var reader io.Reader
+		if payload != nil {
+			body, _ := json.Marshal(payload)
+			reader = bytes.NewReader(body)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		var body map[string]json.RawMessage
+		json.Unmarshal(res.Body.Bytes(), &body)
+		return res.Code, body
+	}
+	annotations := func(path string) []types.Annotation {
+		t.Helper()
+		code, body := call(http.MethodGet, path, nil)
+		if code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", path, code)
+		}
+		var listed []types.Annotation
+		json.Unmarshal(body["annotations"], &listed)
+		return listed
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-notes", "experiment_id": "exp-1", "version_id": "ver-1"})
+	for _, action := range []string{"provision", "start"} {
+		call(http.MethodPost, "/api/v1/runs/run-notes/"+action, nil)
+	}
+	call(http.MethodPost, "/api/v1/runs/run-notes/heartbeat", map[string]any{"run_id": "run-notes", "status": "running", "step": 1200, "loss": 0.5})
+
+	code, body := call(http.MethodPost, "/api/v1/runs/run-notes/annotations", map[string]any{"id": "note-now", "text": "changed lr here", "tags": []string{"lr"}})
+	var now types.Annotation
+	raw, _ := json.Marshal(body)
+	json.Unmarshal(raw, &now)
+	if code != http.StatusCreated || now.Step != 1200 || now.At.IsZero() {
+		t.Fatalf("expected the note at the current step, got %d %+v", code, now)
+	}
+	earlier := now.At.Add(-time.Hour)
+	call(http.MethodPost, "/api/v1/runs/run-notes/annotations", map[string]any{"id": "note-earlier", "text": "warmup done", "at": earlier, "step": 100})
+	if code, _ := call(http.MethodPost, "/api/v1/runs/run-notes/annotations", map[string]any{"id": "note-now", "text": "retry"}); code != http.StatusCreated {
+		t.Fatalf("expected resubmitting an annotation to succeed, got %d", code)
+	}
+	if code, _ := call(http.MethodPost, "/api/v1/runs/run-notes/annotations", map[string]any{"text": "  "}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty note, got %d", code)
+	}
+
+	listed := annotations("/api/v1/runs/run-notes/annotations")
+	if len(listed) != 2 || listed[0].ID != "note-earlier" || listed[1].Text != "changed lr here" {
+		t.Fatalf("expected both notes ordered by time, got %+v", listed)
+	}
+	window := "?from=" + earlier.Add(time.Minute).Format(time.RFC3339)
+	if listed := annotations("/api/v1/runs/run-notes/annotations" + window); len(listed) != 1 || listed[0].ID != "note-now" {
+		t.Fatalf("expected only the note inside the window, got %+v", listed)
+	}
+	if listed := annotations("/api/v1/runs/run-notes/metrics" + window); len(listed) != 1 || listed[0].ID != "note-now" {
+		t.Fatalf("expected the metric timeline to carry the note, got %+v", listed)
+	}
+	if code, _ := call(http.MethodPost, "/api/v1/runs/missing/annotations", map[string]any{"text": "lost"}); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown run, got %d", code)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	logger := zerolog.New(io.Discard)
+	server := NewServer(service.NewOrchestrator(storage.NewMemoryStore(), events.NoopPublisher{}, logger), logger)
+	limiter := middleware.NewRateLimiter(1, 2)
+	server.WithRateLimiter(limiter)
+	routes := server.Routes()
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/runs", nil)
+		req.RemoteAddr = remoteAddr
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+	for i := 0; i < 2; i++ {
+		if res := get("10.0.0.1:5000"); res.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst: expected 200, got %d", i, res.Code)
+		}
+	}
+	res := get("10.0.0.1:5001")
+	if res.Code != http.StatusTooManyRequests || res.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 429 with Retry-After once the burst is spent, got %d %q", res.Code, res.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(res.Body.String(), `"rate_limited"`) {
+		t.Fatalf("expected the rate_limited code, got %s", res.Body.String())
+	}
+	if res := get("10.0.0.2:5000"); res.Code != http.StatusOK {
+		t.Fatalf("expected another client to have its own allowance, got %d", res.Code)
+	}
+	limiter.SetLimit(0, 0)
+	if res := get("10.0.0.1:5000"); res.Code != http.StatusOK {
+		t.Fatalf("expected a zero rate to disable limiting, got %d", res.Code)
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(storage.NewMemoryStore(), events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+	server.WithReloader(func() ([]config.Change, error) {
+		return []config.Change{{Setting: "Health.HeartbeatStaleAfter", Old: "45s", New: "30s", Applied: true}}, nil
+	})
+	res := httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/admin/reload", nil))
+	var body struct {
+		Changes []config.Change `json:"changes"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &body)
+	if res.Code != http.StatusOK || len(body.Changes) != 1 || !body.Changes[0].Applied {
+		t.Fatalf("expected the applied change reported, got %d %s", res.Code, res.Body.String())
+	}
+
+	failing := NewServer(orch, logger)
+	failing.WithReloader(func() ([]config.Change, error) { return nil, errors.New("RATE_LIMIT_RPS must not be negative") })
+	res = httptest.NewRecorder()
+	failing.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/admin/reload", nil))
+	if res.Code != http.StatusBadRequest || !strings.Contains(res.Body.String(), "RATE_LIMIT_RPS") {
+		t.Fatalf("expected a rejected reload to report why, got %d %s", res.Code, res.Body.String())
+	}
+
+	res = httptest.NewRecorder()
+	NewServer(orch, logger).Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/admin/reload", nil))
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("expected no reload endpoint without a reloader, got %d", res.Code)
+	}
+}
+
+func TestRunAdmission(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	orch.WithCostRates(map[string]float64{"cpu": 36})
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	orch.WithNow(func() time.Time { return now })
+	hookDown := false
+	orch.WithAdmitters(
+		service.RunIDPattern(regexp.MustCompile(`^team-[a-z0-9-]+$`)),
+		service.AllowedEnvs([]string{"pong", "breakout"}),
+		orch.ExperimentBudget(30),
+		service.AdmitterFunc(func(context.Context, types.Run) error {
+			if hookDown {
+				return errors.New("dial tcp 10.0.0.9:443: connection refused")
+			}
+			return nil
+		}),
+	)
+	routes := NewServer(orch, logger).Routes()
+
+	create := func(id, env string) (int, apierror.Envelope) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{
+			"id": id, "experiment_id": "exp-1", "version_id": "ver-1",
+			"launch_manifest": map[string]any{"game": map[string]any{"env_id": env}, "resources": map[string]any{"class": "cpu"}},
+		})
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+		var envelope apierror.Envelope
+		json.Unmarshal(res.Body.Bytes(), &envelope)
+		return res.Code, envelope
+	}
+
+	if code, envelope := create("Adhoc_Run", "pong"); code != http.StatusForbidden || envelope.Code != apierror.CodeAdmissionDenied || !strings.Contains(envelope.Message, "does not match") {
+		t.Fatalf("expected the naming convention to refuse the run, got %d %+v", code, envelope)
+	}
+	if code, envelope := create("team-chess", "chess"); code != http.StatusForbidden || !strings.Contains(envelope.Message, `env "chess" is not allowed`) {
+		t.Fatalf("expected the env allowlist to refuse the run, got %d %+v", code, envelope)
+	}
+	if code, _ := create("team-1", "pong"); code != http.StatusCreated {
+		t.Fatalf("expected a conforming run to be admitted, got %d", code)
+	}
+	if _, err := orch.GetRun(context.Background(), "Adhoc_Run"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected refused runs not to be stored, got %v", err)
+	}
+
+	for _, action := range []types.RunAction{types.RunActionProvision, types.RunActionStart} {
+		if _, err := orch.PerformAction(context.Background(), "team-1", action, "tester", ""); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+	}
+	now = start.Add(time.Hour)
+	if code, envelope := create("team-2", "pong"); code != http.StatusForbidden || !strings.Contains(envelope.Message, "budget") {
+		t.Fatalf("expected the spent budget to refuse the run, got %d %+v", code, envelope)
+	}
+	if code, _ := create("team-1", "pong"); code != http.StatusCreated {
+		t.Fatalf("expected retrying an admitted run to stay idempotent, got %d", code)
+	}
+
+	// Back before any spend, so only the failing hook stands in the way.
+	now = start
+	hookDown = true
+	if code, envelope := create("team-3", "breakout"); code != http.StatusServiceUnavailable || strings.Contains(envelope.Message, "10.0.0.9") {
+		t.Fatalf("expected a failing hook to refuse the run as unavailable without its cause, got %d %+v", code, envelope)
+	}
+}
+
+func TestCommandExpiry(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+	routes := server.Routes()
+
+	body, _ := json.Marshal(map[string]any{"id": "run-ttl", "experiment_id": "exp-1", "version_id": "ver-1"})
+	routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+
+	issued := time.Now().UTC()
+	for id, ttl := range map[string]string{"cmd-short": "1m", "cmd-long": "1h"} {
+		cmdBody, _ := json.Marshal(map[string]any{
+			"id":        id,
+			"type":      "pause",
+			"issued_at": issued,
+			"ttl":       ttl,
+			"actor":     map[string]any{"type": "operator", "id": "tester"},
+		})
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-ttl/commands", bytes.NewReader(cmdBody)))
+		if res.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d: %s", res.Code, res.Body.String())
+		}
+	}
+
+	orch.WithNow(func() time.Time { return issued.Add(5 * time.Minute) })
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-ttl/commands/next", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	var next types.RunCommand
+	if err := json.Unmarshal(res.Body.Bytes(), &next); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if next.ID != "cmd-long" {
+		t.Fatalf("expected expired command to be skipped, got %s", next.ID)
+	}
+	expired, err := store.GetCommand(context.Background(), "run-ttl", "cmd-short")
+	if err != nil || expired.ExpiredAt == nil || expired.DeliveredAt != nil {
+		t.Fatalf("expected cmd-short to be marked expired, got %+v (%v)", expired, err)
+	}
+
+	bad, _ := json.Marshal(map[string]any{"type": "pause", "ttl": "-1s", "actor": map[string]any{"type": "operator", "id": "tester"}})
+	res = httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-ttl/commands", bytes.NewReader(bad)))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative ttl, got %d", res.Code)
+	}
+}
+
+func TestCommandRedelivery(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	orch.WithCommandRedelivery(time.Minute, 2)
+	routes := NewServer(orch, logger).Routes()
+
+	body, _ := json.Marshal(map[string]any{"id": "run-redeliver", "experiment_id": "exp-1", "version_id": "ver-1"})
+	routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+	cmdBody, _ := json.Marshal(map[string]any{"id": "cmd-lost", "type": "pause", "actor": map[string]any{"type": "operator", "id": "tester"}})
+	routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-redeliver/commands", bytes.NewReader(cmdBody)))
+
+	base := time.Now()
+	next := func(offset time.Duration) (int, types.RunCommand) {
+		orch.WithNow(func() time.Time { return base.Add(offset) })
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-redeliver/commands/next", nil))
+		var cmd types.RunCommand
+		json.Unmarshal(res.Body.Bytes(), &cmd)
+		return res.Code, cmd
+	}
+
+	if code, cmd := next(0); code != http.StatusOK || cmd.DeliveryAttempts != 1 {
+		t.Fatalf("expected first delivery, got %d %+v", code, cmd)
+	}
+	if code, _ := next(30 * time.Second); code != http.StatusNoContent {
+		t.Fatalf("expected no redelivery inside the ack window, got %d", code)
+	}
+	if code, cmd := next(2 * time.Minute); code != http.StatusOK || cmd.ID != "cmd-lost" || cmd.DeliveryAttempts != 2 {
+		t.Fatalf("expected redelivery after the ack window, got %d %+v", code, cmd)
+	}
+	if code, _ := next(4 * time.Minute); code != http.StatusNoContent {
+		t.Fatalf("expected no delivery once attempts are exhausted, got %d", code)
+	}
+	cmd, err := store.GetCommand(context.Background(), "run-redeliver", "cmd-lost")
+	if err != nil || cmd.Status() != types.CommandStatusDeadLettered {
+		t.Fatalf("expected command to be dead-lettered, got %+v (%v)", cmd, err)
+	}
+}
+
+// commandBus records pushed commands, rejecting them while down is set.
+type commandBus struct {
+	down   bool
+	pushed []types.RunCommand
+}
+
+func (b *commandBus) DeliverCommand(_ context.Context, command types.RunCommand) error {
+	if b.down {
+		return errors.New("broker unavailable")
+	}
+	b.pushed = append(b.pushed, command)
+	return nil
+}
+
+func TestCommandPush(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	orch.WithCommandRedelivery(time.Minute, 3)
+	bus := &commandBus{}
+	orch.WithCommandBus(bus)
+	routes := NewServer(orch, logger).Routes()
+	base := time.Now()
+	orch.WithNow(func() time.Time { return base })
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var raw []byte
+		if body != nil {
+			raw, _ = json.Marshal(body)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, bytes.NewReader(raw)))
+		return res
+	}
+	command := func(id string) map[string]any {
+		return map[string]any{"id": id, "type": "pause", "actor": map[string]any{"type": "operator", "id": "tester"}}
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-push", "experiment_id": "exp-1", "version_id": "ver-1"})
+	res := call(http.MethodPost, "/api/v1/runs/run-push/commands", command("cmd-pushed"))
+	var created types.RunCommand
+	json.Unmarshal(res.Body.Bytes(), &created)
+	if len(bus.pushed) != 1 || bus.pushed[0].ID != "cmd-pushed" || created.Status() != types.CommandStatusDelivered {
+		t.Fatalf("expected the command to be pushed and marked delivered, got %d pushed, status %s", len(bus.pushed), created.Status())
+	}
+	// A pushed command is not handed out again by the pull path.
+	if res := call(http.MethodGet, "/api/v1/runs/run-push/commands/next", nil); res.Code != http.StatusNoContent {
+		t.Fatalf("expected nothing to poll after a push, got %d", res.Code)
+	}
+
+	// Unacknowledged pushes are requeued and pushed again after the ack timeout.
+	orch.WithNow(func() time.Time { return base.Add(2 * time.Minute) })
+	if _, err := orch.RedeliverCommands(context.Background(), ""); err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	if len(bus.pushed) != 2 || bus.pushed[1].DeliveryAttempts != 2 {
+		t.Fatalf("expected a second push on redelivery, got %+v", bus.pushed)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-push/commands/cmd-pushed/ack", nil); res.Code != http.StatusOK {
+		t.Fatalf("ack: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	orch.WithNow(func() time.Time { return base.Add(4 * time.Minute) })
+	if reclaimed, _ := orch.RedeliverCommands(context.Background(), ""); len(reclaimed) != 0 || len(bus.pushed) != 2 {
+		t.Fatalf("expected an acknowledged command to stay delivered, reclaimed %d", len(reclaimed))
+	}
+
+	// While the bus is down, commands fall back to polling.
+	bus.down = true
+	call(http.MethodPost, "/api/v1/runs/run-push/commands", command("cmd-polled"))
+	res = call(http.MethodGet, "/api/v1/runs/run-push/commands/next", nil)
+	var polled types.RunCommand
+	json.Unmarshal(res.Body.Bytes(), &polled)
+	if res.Code != http.StatusOK || polled.ID != "cmd-polled" || polled.DeliveryAttempts != 1 {
+		t.Fatalf("expected the unpushed command to be polled, got %d %+v", res.Code, polled)
+	}
+
+	if got := events.CommandSubject("run-status", "run.push>1"); got != "run-status.runs.run_push_1.commands" {
+		t.Fatalf("unexpected command subject %q", got)
+	}
+}
+
+func TestCommandSequencing(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	orch.WithCommandRedelivery(time.Minute, 3)
+	orch.WithSerialCommandDelivery(true)
+	routes := NewServer(orch, logger).Routes()
+	base := time.Now().UTC().Truncate(time.Second)
+	orch.WithNow(func() time.Time { return base })
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var raw []byte
+		if body != nil {
+			raw, _ = json.Marshal(body)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, bytes.NewReader(raw)))
+		return res
+	}
+	next := func() (int, string) {
+		res := call(http.MethodGet, "/api/v1/runs/run-seq/commands/next", nil)
+		var cmd types.RunCommand
+		json.Unmarshal(res.Body.Bytes(), &cmd)
+		return res.Code, cmd.ID
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-seq", "experiment_id": "exp-1", "version_id": "ver-1"})
+	// Commands are delivered in issued_at order, whatever order they arrive in.
+	for _, spec := range []struct {
+		id     string
+		issued time.Time
+	}{
+		{"cmd-pause", base.Add(2 * time.Second)},
+		{"cmd-tune", base.Add(time.Second)},
+		{"cmd-resume", base.Add(3 * time.Second)},
+	} {
+		body := map[string]any{"id": spec.id, "type": "pause", "issued_at": spec.issued, "actor": map[string]any{"type": "operator", "id": "tester"}}
+		if res := call(http.MethodPost, "/api/v1/runs/run-seq/commands", body); res.Code != http.StatusAccepted {
+			t.Fatalf("create %s: expected 202, got %d: %s", spec.id, res.Code, res.Body.String())
+		}
+	}
+
+	if code, id := next(); code != http.StatusOK || id != "cmd-tune" {
+		t.Fatalf("expected cmd-tune first, got %d %q", code, id)
+	}
+	if code, _ := next(); code != http.StatusNoContent {
+		t.Fatalf("expected nothing while cmd-tune is unacknowledged, got %d", code)
+	}
+	// Acking a command still queued behind it neither skips it nor releases it.
+	if res := call(http.MethodPost, "/api/v1/runs/run-seq/commands/cmd-pause/ack", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 acking a queued command, got %d", res.Code)
+	}
+	if code, _ := next(); code != http.StatusNoContent {
+		t.Fatalf("expected nothing after acking a queued command, got %d", code)
+	}
+	call(http.MethodPost, "/api/v1/runs/run-seq/commands/cmd-tune/ack", nil)
+	if code, id := next(); code != http.StatusOK || id != "cmd-pause" {
+		t.Fatalf("expected cmd-pause after the ack, got %d %q", code, id)
+	}
+
+	// An unacknowledged command is redelivered before anything behind it.
+	orch.WithNow(func() time.Time { return base.Add(2 * time.Minute) })
+	if code, id := next(); code != http.StatusOK || id != "cmd-pause" {
+		t.Fatalf("expected cmd-pause to be redelivered, got %d %q", code, id)
+	}
+	result := map[string]any{"status": "succeeded"}
+	if res := call(http.MethodPost, "/api/v1/runs/run-seq/commands/cmd-pause/result", result); res.Code != http.StatusOK {
+		t.Fatalf("result: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if code, id := next(); code != http.StatusOK || id != "cmd-resume" {
+		t.Fatalf("expected a result to release cmd-resume, got %d %q", code, id)
+	}
+}
+
+func TestCommandDeadLetters(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	orch.WithCommandRedelivery(time.Minute, 1)
+	routes := NewServer(orch, logger).Routes()
+	base := time.Now().UTC()
+	orch.WithNow(func() time.Time { return base })
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	type page struct {
+		Commands   []types.RunCommand `json:"commands"`
+		NextCursor string             `json:"next_cursor"`
+	}
+	deadLetters := func(query string) page {
+		t.Helper()
+		res := call(http.MethodGet, "/api/v1/commands/dead-letters"+query, nil)
+		if res.Code != http.StatusOK {
+			t.Fatalf("dead letters: expected 200, got %d: %s", res.Code, res.Body.String())
+		}
+		var p page
+		json.Unmarshal(res.Body.Bytes(), &p)
+		return p
+	}
+
+	for _, id := range []string{"run-a", "run-b"} {
+		call(http.MethodPost, "/api/v1/runs", map[string]any{"id": id, "experiment_id": "exp-1", "version_id": "ver-1"})
+	}
+	actor := map[string]any{"type": "operator", "id": "tester"}
+	call(http.MethodPost, "/api/v1/runs/run-a/commands", map[string]any{"id": "cmd-lost", "type": "pause", "issued_at": base, "actor": actor})
+	for i, id := range []string{"cmd-ttl", "cmd-late"} {
+		call(http.MethodPost, "/api/v1/runs/run-b/commands", map[string]any{"id": id, "type": "pause", "issued_at": base.Add(time.Duration(i+1) * time.Second), "ttl": "1m", "actor": actor})
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/run-a/commands/next", nil); res.Code != http.StatusOK {
+		t.Fatalf("expected cmd-lost delivered, got %d", res.Code)
+	}
+	now := base.Add(5 * time.Minute)
+	orch.WithNow(func() time.Time { return now })
+	for _, id := range []string{"run-a", "run-b"} {
+		if res := call(http.MethodGet, "/api/v1/runs/"+id+"/commands/next", nil); res.Code != http.StatusNoContent {
+			t.Fatalf("%s: expected nothing deliverable, got %d", id, res.Code)
+		}
+	}
+
+	all := deadLetters("")
+	if len(all.Commands) != 3 || all.Commands[0].ID != "cmd-lost" || all.Commands[1].Status() != types.CommandStatusExpired {
+		t.Fatalf("expected dead letters across runs, got %+v", all.Commands)
+	}
+	first := deadLetters("?limit=2")
+	if len(first.Commands) != 2 || first.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	if rest := deadLetters("?limit=2&cursor=" + first.NextCursor); len(rest.Commands) != 1 || rest.Commands[0].ID != "cmd-late" {
+		t.Fatalf("unexpected second page %+v", rest)
+	}
+	if scoped := deadLetters("?run_id=run-a"); len(scoped.Commands) != 1 || scoped.Commands[0].ID != "cmd-lost" {
+		t.Fatalf("expected run-a's dead letter only, got %+v", scoped.Commands)
+	}
+
+	// A late ack doesn't take a command out of the dead-letter queue.
+	if res := call(http.MethodPost, "/api/v1/runs/run-a/commands/cmd-lost/ack", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 acking a dead-lettered command, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-a/commands/cmd-lost/result", map[string]any{"status": "succeeded"}); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 reporting a result for a dead-lettered command, got %d", res.Code)
+	}
+	res := call(http.MethodPost, "/api/v1/runs/run-a/commands/cmd-lost/requeue", nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("requeue: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var requeued types.RunCommand
+	json.Unmarshal(res.Body.Bytes(), &requeued)
+	if requeued.Status() != types.CommandStatusQueued || requeued.DeliveryAttempts != 0 || requeued.AcknowledgedAt != nil {
+		t.Fatalf("expected a fresh queued command, got %+v", requeued)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-a/commands/cmd-lost/ack", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 acking a requeued command before delivery, got %d", res.Code)
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/run-a/commands/next", nil); res.Code != http.StatusOK {
+		t.Fatalf("expected the requeued command delivered, got %d", res.Code)
+	}
+	res = call(http.MethodPost, "/api/v1/runs/run-a/commands/cmd-lost/ack", nil)
+	var acked types.RunCommand
+	json.Unmarshal(res.Body.Bytes(), &acked)
+	if res.Code != http.StatusOK || acked.Status() != types.CommandStatusAcked {
+		t.Fatalf("expected the redelivered command acked, got %d %+v", res.Code, acked)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-a/commands/cmd-lost/ack", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 acking twice, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-a/commands/cmd-lost/requeue", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 requeueing a delivered command, got %d", res.Code)
+	}
+
+	// An expired command needs a future expiry, or its stale one is cleared.
+	if res := call(http.MethodPost, "/api/v1/runs/run-b/commands/cmd-ttl/requeue", map[string]any{"expires_at": base}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a past expires_at, got %d", res.Code)
+	}
+	res = call(http.MethodPost, "/api/v1/runs/run-b/commands/cmd-ttl/discard", nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("discard: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var discarded types.RunCommand
+	json.Unmarshal(res.Body.Bytes(), &discarded)
+	if discarded.Status() != types.CommandStatusDiscarded {
+		t.Fatalf("expected discarded command, got %+v", discarded)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-b/commands/cmd-ttl/discard", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 discarding twice, got %d", res.Code)
+	}
+
+	call(http.MethodPost, "/api/v1/runs/run-b/terminate", nil)
+	if res := call(http.MethodPost, "/api/v1/runs/run-b/commands/cmd-late/requeue", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 requeueing onto a terminated run, got %d", res.Code)
+	}
+	if left := deadLetters(""); len(left.Commands) != 1 || left.Commands[0].ID != "cmd-late" {
+		t.Fatalf("expected only cmd-late left, got %+v", left.Commands)
+	}
+	if res := call(http.MethodGet, "/api/v1/commands/dead-letters?run_id=missing", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown run, got %d", res.Code)
+	}
+}
+
+func TestListCommands(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	body, _ := json.Marshal(map[string]any{"id": "run-list", "experiment_id": "exp-1", "version_id": "ver-1"})
+	routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+	issued := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		cmdBody, _ := json.Marshal(map[string]any{
+			"id":        fmt.Sprintf("cmd-%d", i),
+			"type":      "pause",
+			"issued_at": issued.Add(time.Duration(i) * time.Second),
+			"actor":     map[string]any{"type": "operator", "id": "tester"},
+		})
+		routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-list/commands", bytes.NewReader(cmdBody)))
+	}
+	routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-list/commands/next", nil))
+
+	type page struct {
+		Commands   []types.RunCommand `json:"commands"`
+		NextCursor string             `json:"next_cursor"`
+	}
+	list := func(query string) (int, page) {
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-list/commands"+query, nil))
+		var p page
+		json.Unmarshal(res.Body.Bytes(), &p)
+		return res.Code, p
+	}
+
+	code, first := list("?limit=2")
+	if code != http.StatusOK || len(first.Commands) != 2 || first.NextCursor == "" || first.Commands[0].ID != "cmd-0" {
+		t.Fatalf("unexpected first page %d %+v", code, first)
+	}
+	_, second := list("?limit=2&cursor=" + first.NextCursor)
+	if len(second.Commands) != 1 || second.Commands[0].ID != "cmd-2" || second.NextCursor != "" {
+		t.Fatalf("unexpected second page %+v", second)
+	}
+	_, queued := list("?status=queued")
+	if len(queued.Commands) != 2 {
+		t.Fatalf("expected 2 queued commands, got %d", len(queued.Commands))
+	}
+	_, delivered := list("?status=delivered,acked")
+	if len(delivered.Commands) != 1 || delivered.Commands[0].ID != "cmd-0" {
+		t.Fatalf("expected cmd-0 delivered, got %+v", delivered.Commands)
+	}
+	if code, _ := list("?status=lost"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown status, got %d", code)
+	}
+	if code, _ := list("?cursor=garbage"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad cursor, got %d", code)
+	}
+}
+
+func TestNextCommandLongPoll(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	body, _ := json.Marshal(map[string]any{"id": "run-poll", "experiment_id": "exp-1", "version_id": "ver-1"})
+	routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-poll/commands/next?wait=20ms", nil))
+	if res.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 after the wait elapsed, got %d", res.Code)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cmdBody, _ := json.Marshal(map[string]any{"id": "cmd-late", "type": "pause", "actor": map[string]any{"type": "operator", "id": "tester"}})
+		routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-poll/commands", bytes.NewReader(cmdBody)))
+	}()
+	start := time.Now()
+	res = httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-poll/commands/next?wait=5s", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200 once the command arrived, got %d", res.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("long poll returned after %s; expected wake-up on enqueue", elapsed)
+	}
+
+	res = httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-poll/commands/next?wait=soon", nil))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid wait, got %d", res.Code)
+	}
+}
+
+func TestRunLifecycleTransitions(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+
+	runPayload := map[string]any{
+		"id":              "run-3",
+		"experiment_id":   "exp-1",
+		"version_id":      "ver-1",
+		"launch_manifest": map[string]any{"foo": "bar"},
+		"created_by":      "tester",
+	}
+	body, _ := json.Marshal(runPayload)
+	server.Routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+
+	for _, action := range []string{"provision", "start", "pause", "resume"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-3/"+action, bytes.NewReader([]byte(`{"changed_by":"tester"}`)))
+		res := httptest.NewRecorder()
+		server.Routes().ServeHTTP(res, req)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", action, res.Code)
+		}
+	}
+
+	res := httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-3/provision", nil))
+	if res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for illegal transition, got %d", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-3/transitions", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	var listed struct {
+		Transitions []storage.RunTransition `json:"transitions"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("decode transitions: %v", err)
+	}
+	if len(listed.Transitions) < 4 {
+		t.Fatalf("expected at least 4 transitions, got %d", len(listed.Transitions))
+	}
+}
+
+func TestExperimentLifecycle(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+
+	expBody, _ := json.Marshal(map[string]any{"id": "exp-2", "name": "PPO baseline", "created_by": "tester"})
+	res := httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/experiments", bytes.NewReader(expBody)))
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.Code)
+	}
+	res = httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/experiments", bytes.NewReader(expBody)))
+	if res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate experiment, got %d", res.Code)
+	}
+
+	versionBody, _ := json.Marshal(map[string]any{"id": "ver-1", "manifest": map[string]any{"foo": "bar"}})
+	server.Routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/experiments/exp-2/versions", bytes.NewReader(versionBody)))
+
+	for _, id := range []string{"run-a", "run-b"} {
+		body, _ := json.Marshal(map[string]any{"id": id, "experiment_id": "exp-2", "version_id": "ver-1", "created_by": "tester"})
+		server.Routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+	}
+	server.Routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-b/provision", nil))
+
+	res = httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/experiments/exp-2", nil))
+	var summary types.ExperimentSummary
+	if err := json.Unmarshal(res.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("decode experiment: %v", err)
+	}
+	if summary.TotalRuns != 2 || summary.RunCounts[types.RunStateQueued] != 1 || summary.RunCounts[types.RunStateProvisioning] != 1 {
+		t.Fatalf("unexpected run aggregates: %+v", summary)
+	}
+
+	res = httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/experiments/exp-2/archive", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+	body, _ := json.Marshal(map[string]any{"id": "run-c", "experiment_id": "exp-2", "version_id": "ver-1"})
+	res = httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+	if res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for run against archived experiment, got %d", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/experiments", nil))
+	var listed struct {
+		Experiments []types.Experiment `json:"experiments"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("decode experiments: %v", err)
+	}
+	if len(listed.Experiments) != 0 {
+		t.Fatalf("expected archived experiment to be hidden, got %d", len(listed.Experiments))
+	}
+}
+
+func TestConfigVersions(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+
+	expBody, _ := json.Marshal(map[string]any{"id": "exp-3", "name": "PPO sweep"})
+	server.Routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/experiments", bytes.NewReader(expBody)))
+
+	manifests := map[string]map[string]any{
+		"ver-a": {"trainer": map[string]any{"lr": 0.0003}},
+		"ver-b": {"trainer": map[string]any{"lr": 0.001}},
+	}
+	for _, id := range []string{"ver-a", "ver-b"} {
+		body, _ := json.Marshal(map[string]any{"id": id, "manifest": manifests[id]})
+		res := httptest.NewRecorder()
+		server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/experiments/exp-3/versions", bytes.NewReader(body)))
+		if res.Code != http.StatusCreated {
+			t.Fatalf("%s: expected 201, got %d", id, res.Code)
+		}
+	}
+
+	res := httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/versions/ver-b/diff?against=ver-a", nil))
+	var diff struct {
+		Changes []types.ConfigChange `json:"changes"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &diff); err != nil {
+		t.Fatalf("decode diff: %v", err)
+	}
+	if len(diff.Changes) != 1 || diff.Changes[0].Path != "manifest.trainer.lr" {
+		t.Fatalf("unexpected diff: %+v", diff.Changes)
+	}
+
+	runBody, _ := json.Marshal(map[string]any{"id": "run-v", "experiment_id": "exp-3", "version_id": "ver-missing"})
+	res = httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(runBody)))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unregistered version, got %d", res.Code)
+	}
+
+	runBody, _ = json.Marshal(map[string]any{"id": "run-v", "experiment_id": "exp-3", "version_id": "ver-b"})
+	res = httptest.NewRecorder()
+	server.Routes().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(runBody)))
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.Code)
+	}
+	var run types.Run
+	if err := json.Unmarshal(res.Body.Bytes(), &run); err != nil {
+		t.Fatalf("decode run: %v", err)
+	}
+	if len(run.LaunchManifest) == 0 {
+		t.Fatalf("expected run to inherit the version manifest")
+	}
+}
+
+func TestEvaluationLeaderboard(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+
+	call(http.MethodPost, "/api/v1/experiments", map[string]any{"id": "exp-lb", "name": "league"})
+	call(http.MethodPost, "/api/v1/experiments/exp-lb/versions", map[string]any{"id": "ver-lb", "manifest": map[string]any{}})
+	for _, runID := range []string{"run-a", "run-b"} {
+		if res := call(http.MethodPost, "/api/v1/runs", map[string]any{"id": runID, "experiment_id": "exp-lb", "version_id": "ver-lb"}); res.Code != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d: %s", runID, res.Code, res.Body.String())
+		}
+	}
+	for _, ckpt := range []struct {
+		run     string
+		version int
+	}{{"run-a", 1}, {"run-a", 2}, {"run-b", 1}} {
+		call(http.MethodPost, "/api/v1/runs/"+ckpt.run+"/checkpoints", map[string]any{"version": ckpt.version, "storage_uri": fmt.Sprintf("s3://ckpts/%s/%d", ckpt.run, ckpt.version)})
+	}
+
+	reports := []struct {
+		run     string
+		id      string
+		version int
+		suite   string
+		winRate float64
+		eps     int
+	}{
+		{"run-a", "e1", 1, "vs-random", 0.4, 100},
+		{"run-a", "e2", 2, "vs-random", 0.7, 100},
+		{"run-a", "e3", 2, "vs-random", 0.5, 300},
+		{"run-b", "e4", 1, "vs-random", 0.6, 50},
+		{"run-a", "e5", 1, "self-play", 0.99, 10},
+	}
+	for _, report := range reports {
+		res := call(http.MethodPost, "/api/v1/runs/"+report.run+"/evaluations", map[string]any{
+			"id":                 report.id,
+			"checkpoint_version": report.version,
+			"suite":              report.suite,
+			"scores":             map[string]float64{"win_rate": report.winRate, "mean_return": report.winRate * 10},
+			"episodes":           report.eps,
+		})
+		if res.Code != http.StatusCreated {
+			t.Fatalf("report %s: expected 201, got %d: %s", report.id, res.Code, res.Body.String())
+		}
+	}
+	call(http.MethodPost, "/api/v1/runs/run-a/evaluations", map[string]any{"id": "e1", "checkpoint_version": 1, "suite": "vs-random", "scores": map[string]float64{"win_rate": 0.4}})
+	if res := call(http.MethodPost, "/api/v1/runs/run-b/evaluations", map[string]any{"checkpoint_version": 9, "suite": "vs-random", "scores": map[string]float64{"win_rate": 0.4}}); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unregistered checkpoint, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-b/evaluations", map[string]any{"checkpoint_version": 1, "suite": "vs-random", "scores": map[string]float64{"win_rate": 1.5}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for out-of-range win_rate, got %d", res.Code)
+	}
+
+	var evaluations struct {
+		Evaluations []types.Evaluation `json:"evaluations"`
+	}
+	_ = json.Unmarshal(call(http.MethodGet, "/api/v1/runs/run-a/evaluations?checkpoint_version=1", nil).Body.Bytes(), &evaluations)
+	if len(evaluations.Evaluations) != 2 {
+		t.Fatalf("expected 2 evaluations of run-a v1, got %d", len(evaluations.Evaluations))
+	}
+
+	var board struct {
+		Leaderboard []service.LeaderboardEntry `json:"leaderboard"`
+	}
+	res := call(http.MethodGet, "/api/v1/experiments/exp-lb/leaderboard?suite=vs-random", nil)
+	if err := json.Unmarshal(res.Body.Bytes(), &board); err != nil {
+		t.Fatalf("decode leaderboard: %v", err)
+	}
+	got := make([]string, 0, len(board.Leaderboard))
+	for _, entry := range board.Leaderboard {
+		got = append(got, fmt.Sprintf("%d:%s/%d", entry.Rank, entry.RunID, entry.CheckpointVersion))
+	}
+	if strings.Join(got, ",") != "1:run-b/1,2:run-a/2,3:run-a/1" {
+		t.Fatalf("unexpected ranking %v", got)
+	}
+	if second := board.Leaderboard[1]; second.Score != 0.55 || second.Evaluations != 2 || second.Episodes != 400 {
+		t.Fatalf("expected episode-weighted score 0.55 over 2 reports, got %+v", second)
+	}
+
+	_ = json.Unmarshal(call(http.MethodGet, "/api/v1/experiments/exp-lb/leaderboard?order=min&limit=1", nil).Body.Bytes(), &board)
+	if len(board.Leaderboard) != 1 || board.Leaderboard[0].RunID != "run-a" || board.Leaderboard[0].CheckpointVersion != 1 {
+		t.Fatalf("expected run-a/1 lowest across suites, got %+v", board.Leaderboard)
+	}
+	if res := call(http.MethodGet, "/api/v1/experiments/missing/leaderboard", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown experiment, got %d", res.Code)
+	}
+}
+
+func TestRunMetricsTimeseries(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	orch.WithNow(func() time.Time { return now })
+
+	body, _ := json.Marshal(map[string]any{"id": "run-metrics", "experiment_id": "exp-1", "version_id": "ver-1"})
+	routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+	for i := 0; i < 12; i++ {
+		now = start.Add(time.Duration(i) * 10 * time.Second)
+		hb, _ := json.Marshal(map[string]any{
+			"run_id":          "run-metrics",
+			"status":          "running",
+			"step":            i * 10,
+			"loss":            float64(i),
+			"samples_per_sec": 100.0,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-metrics/heartbeat", bytes.NewReader(hb))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		if res.Code != http.StatusOK {
+			t.Fatalf("heartbeat %d: expected 200, got %d", i, res.Code)
+		}
+	}
+
+	fetch := func(query string) service.MetricSeries {
+		t.Helper()
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-metrics/metrics"+query, nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("metrics%s: expected 200, got %d: %s", query, res.Code, res.Body.String())
+		}
+		var series service.MetricSeries
+		if err := json.Unmarshal(res.Body.Bytes(), &series); err != nil {
+			t.Fatalf("decode series: %v", err)
+		}
+		return series
+	}
+
+	if raw := fetch(""); len(raw.Points) != 12 || raw.ResolutionSeconds != 0 {
+		t.Fatalf("expected 12 raw points, got %d at %ds", len(raw.Points), raw.ResolutionSeconds)
+	}
+	series := fetch("?resolution=1m")
+	if len(series.Points) != 2 || series.ResolutionSeconds != 60 {
+		t.Fatalf("expected 2 one-minute buckets, got %+v", series)
+	}
+	if first := series.Points[0]; first.Samples != 6 || first.Loss != 2.5 || first.Step != 50 || !first.At.Equal(start) {
+		t.Fatalf("unexpected first bucket %+v", first)
+	}
+	window := fetch("?from=" + start.Add(30*time.Second).Format(time.RFC3339) + "&to=" + start.Add(time.Minute).Format(time.RFC3339))
+	if len(window.Points) != 3 {
+		t.Fatalf("expected 3 points in window, got %d", len(window.Points))
+	}
+
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-metrics/metrics?resolution=soon", nil))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid resolution, got %d", res.Code)
+	}
+
+	orch.WithMetricsRetention(time.Minute)
+	now = start.Add(2 * time.Minute)
+	if removed, err := orch.PruneMetrics(context.Background()); err != nil || removed != 6 {
+		t.Fatalf("expected 6 points pruned, got %d (%v)", removed, err)
+	}
+	if remaining := fetch(""); len(remaining.Points) != 6 {
+		t.Fatalf("expected 6 points after retention, got %d", len(remaining.Points))
+	}
+}
+
+func TestExperimentStats(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	orch.WithNow(func() time.Time { return now })
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	if res := call(http.MethodPost, "/api/v1/experiments", map[string]any{"id": "exp-stats", "name": "stats"}); res.Code != http.StatusCreated {
+		t.Fatalf("create experiment: expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	call(http.MethodPost, "/api/v1/experiments/exp-stats/versions", map[string]any{"id": "ver-stats", "manifest": map[string]any{}})
+	for _, id := range []string{"run-a", "run-b", "run-c"} {
+		if res := call(http.MethodPost, "/api/v1/runs", map[string]any{"id": id, "experiment_id": "exp-stats", "version_id": "ver-stats"}); res.Code != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d: %s", id, res.Code, res.Body.String())
+		}
+	}
+	heartbeat := func(runID string, step int, loss float64) {
+		t.Helper()
+		res := call(http.MethodPost, "/api/v1/runs/"+runID+"/heartbeat", map[string]any{
+			"run_id": runID, "status": "running", "step": step, "loss": loss, "samples_per_sec": 100.0,
+		})
+		if res.Code != http.StatusOK {
+			t.Fatalf("heartbeat %s: expected 200, got %d: %s", runID, res.Code, res.Body.String())
+		}
+	}
+	for i, loss := range []float64{0.9, 0.5, 0.7} {
+		now = start.Add(time.Duration(i) * 10 * time.Second)
+		heartbeat("run-a", (i+1)*100, loss)
+	}
+	heartbeat("run-b", 50, 0.6)
+	if res := call(http.MethodPost, "/api/v1/runs/run-c/terminate", nil); res.Code != http.StatusOK {
+		t.Fatalf("terminate: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+
+	res := call(http.MethodGet, "/api/v1/experiments/exp-stats/stats", nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var stats service.ExperimentStats
+	json.Unmarshal(res.Body.Bytes(), &stats)
+	if stats.Runs != 3 || stats.ByState[types.RunStateTerminated] != 1 {
+		t.Fatalf("unexpected state counts %+v", stats)
+	}
+	if stats.ByHealth[types.RunHealthHealthy] != 2 {
+		t.Fatalf("expected health of the two active runs only, got %v", stats.ByHealth)
+	}
+	if stats.BestLoss == nil || *stats.BestLoss != 0.5 || stats.BestLossRunID != "run-a" {
+		t.Fatalf("unexpected best loss %+v", stats)
+	}
+	// run-a reported 100 samples/s over two 10s intervals; run-b has a single heartbeat.
+	if stats.TotalSteps != 350 || stats.TotalSamples != 2000 {
+		t.Fatalf("expected 350 steps and 2000 samples, got %d and %d", stats.TotalSteps, stats.TotalSamples)
+	}
+
+	if res := call(http.MethodGet, "/api/v1/experiments/missing/stats", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing experiment, got %d", res.Code)
+	}
+}
+
+func TestRunCostAccounting(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	publisher := &recordingPublisher{}
+	orch := service.NewOrchestrator(store, publisher, logger)
+	orch.WithCostRates(map[string]float64{"a100": 3, "cpu": 0.5})
+	routes := NewServer(orch, logger).Routes()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	orch.WithNow(func() time.Time { return now })
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	call(http.MethodPost, "/api/v1/experiments", map[string]any{"id": "exp-cost", "name": "cost"})
+	call(http.MethodPost, "/api/v1/experiments/exp-cost/versions", map[string]any{"id": "ver-cost", "manifest": map[string]any{}})
+	for id, resources := range map[string]map[string]any{"run-gpu": {"class": "a100", "gpus": 2}, "run-cpu": {"class": "cpu"}} {
+		manifest := map[string]any{"resources": resources}
+		if res := call(http.MethodPost, "/api/v1/runs", map[string]any{"id": id, "experiment_id": "exp-cost", "version_id": "ver-cost", "launch_manifest": manifest}); res.Code != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d: %s", id, res.Code, res.Body.String())
+		}
+		call(http.MethodPost, "/api/v1/runs/"+id+"/provision", map[string]any{})
+		call(http.MethodPost, "/api/v1/runs/"+id+"/start", map[string]any{})
+	}
+	for i := 1; i <= 3; i++ {
+		now = start.Add(time.Duration(i) * 10 * time.Second)
+		if res := call(http.MethodPost, "/api/v1/runs/run-gpu/heartbeat", map[string]any{"run_id": "run-gpu", "status": "running", "step": i * 100, "samples_per_sec": 50.0}); res.Code != http.StatusOK {
+			t.Fatalf("heartbeat: expected 200, got %d: %s", res.Code, res.Body.String())
+		}
+	}
+
+	now = start.Add(time.Hour)
+	if res := call(http.MethodPost, "/api/v1/runs/run-gpu/complete", map[string]any{}); res.Code != http.StatusOK {
+		t.Fatalf("complete: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if len(publisher.accounting) != 1 {
+		t.Fatalf("expected one accounting event, got %+v", publisher.accounting)
+	}
+	// Two 10s intervals at 50 samples/s; the first heartbeat has no interval.
+	if event := publisher.accounting[0]; event.RunID != "run-gpu" || event.State != "completed" || event.DurationSeconds != 3600 || event.Cost != 3 || event.Steps != 300 || event.Samples != 1000 || event.GPUs != 2 {
+		t.Fatalf("unexpected accounting event %+v", event)
+	}
+
+	now = start.Add(2 * time.Hour)
+	var cost types.RunCost
+	res := call(http.MethodGet, "/api/v1/runs/run-cpu/cost", nil)
+	json.Unmarshal(res.Body.Bytes(), &cost)
+	if res.Code != http.StatusOK || cost.Final || cost.ResourceClass != "cpu" || cost.DurationSeconds != 7200 || cost.Cost != 1 {
+		t.Fatalf("expected the running run accounted up to now, got %d %+v", res.Code, cost)
+	}
+	res = call(http.MethodGet, "/api/v1/runs/run-gpu/cost", nil)
+	json.Unmarshal(res.Body.Bytes(), &cost)
+	if !cost.Final || cost.DurationSeconds != 3600 {
+		t.Fatalf("expected the completed run's accounting to stop at its end, got %+v", cost)
+	}
+
+	var total types.ExperimentCost
+	res = call(http.MethodGet, "/api/v1/experiments/exp-cost/cost", nil)
+	json.Unmarshal(res.Body.Bytes(), &total)
+	if res.Code != http.StatusOK || total.Runs != 2 || total.Cost != 4 || total.DurationSeconds != 10800 || total.Samples != 1000 {
+		t.Fatalf("unexpected experiment cost %d %+v", res.Code, total)
+	}
+	if gpu := total.ByResourceClass["a100"]; gpu.Runs != 1 || gpu.Cost != 3 {
+		t.Fatalf("unexpected a100 totals %+v", total.ByResourceClass)
+	}
+	if res := call(http.MethodGet, "/api/v1/experiments/missing/cost", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing experiment, got %d", res.Code)
+	}
+}
+
+func TestArtifactUploadFlow(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-art", "experiment_id": "exp-1", "version_id": "ver-1"})
+	upload := map[string]any{"name": "model-000100.pt", "kind": "checkpoint"}
+	if res := call(http.MethodPost, "/api/v1/runs/run-art/artifacts", upload); res.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without an artifact store, got %d", res.Code)
+	}
+
+	presigner, err := artifacts.NewS3Presigner(artifacts.S3Config{
+		Endpoint:        "http://minio:9000",
+		Bucket:          "cartridge",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatalf("presigner: %v", err)
+	}
+	orch.WithArtifactStore(presigner, 0)
+
+	res := call(http.MethodPost, "/api/v1/runs/run-art/artifacts", upload)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	var put service.ArtifactURL
+	if err := json.Unmarshal(res.Body.Bytes(), &put); err != nil {
+		t.Fatalf("decode upload: %v", err)
+	}
+	if put.Method != http.MethodPut || !strings.HasPrefix(put.URL, "http://minio:9000/cartridge/runs/run-art/checkpoint/model-000100.pt?") {
+		t.Fatalf("unexpected upload URL %s %s", put.Method, put.URL)
+	}
+	if put.Artifact.URI != "s3://cartridge/runs/run-art/checkpoint/model-000100.pt" {
+		t.Fatalf("unexpected artifact URI %s", put.Artifact.URI)
+	}
+
+	if res := call(http.MethodGet, "/api/v1/runs/run-art/artifacts/model-000100.pt/download", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 before upload completes, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-art/artifacts", map[string]any{"name": "../escape"}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid name, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-art/artifacts/model-000100.pt/complete", map[string]any{"size_bytes": 2048}); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 on complete, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-art/artifacts", upload); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 re-uploading a finished artifact, got %d", res.Code)
+	}
+
+	res = call(http.MethodGet, "/api/v1/runs/run-art/artifacts/model-000100.pt/download", nil)
+	var get service.ArtifactURL
+	if err := json.Unmarshal(res.Body.Bytes(), &get); err != nil || get.Method != http.MethodGet || get.Artifact.SizeBytes != 2048 {
+		t.Fatalf("unexpected download response %d: %s", res.Code, res.Body.String())
+	}
+
+	res = call(http.MethodGet, "/api/v1/runs/run-art/artifacts", nil)
+	var list struct {
+		Artifacts []types.Artifact `json:"artifacts"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &list); err != nil || len(list.Artifacts) != 1 {
+		t.Fatalf("expected one artifact, got %s", res.Body.String())
+	}
+}
+
+func TestCheckpointRegistry(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	decode := func(res *httptest.ResponseRecorder) types.Checkpoint {
+		var checkpoint types.Checkpoint
+		if err := json.Unmarshal(res.Body.Bytes(), &checkpoint); err != nil {
+			t.Fatalf("decode checkpoint: %v", err)
+		}
+		return checkpoint
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-ckpt", "experiment_id": "exp-1", "version_id": "ver-1"})
+	if res := call(http.MethodGet, "/api/v1/runs/run-ckpt/checkpoints/latest", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before any checkpoint, got %d", res.Code)
+	}
+	for version, ret := range map[int64]float64{1: 10, 2: 42, 3: 17} {
+		res := call(http.MethodPost, "/api/v1/runs/run-ckpt/checkpoints", map[string]any{
+			"version":     version,
+			"step":        version * 1000,
+			"metrics":     map[string]float64{"mean_return": ret},
+			"storage_uri": fmt.Sprintf("s3://ckpts/run-ckpt/%d.pt", version),
+		})
+		if res.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", res.Code, res.Body.String())
+		}
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-ckpt/checkpoints", map[string]any{"version": 2, "storage_uri": "s3://dup"}); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate version, got %d", res.Code)
+	}
+
+	if latest := decode(call(http.MethodGet, "/api/v1/runs/run-ckpt/checkpoints/latest", nil)); latest.Version != 3 {
+		t.Fatalf("expected latest version 3, got %d", latest.Version)
+	}
+	if best := decode(call(http.MethodGet, "/api/v1/runs/run-ckpt/checkpoints/best?metric=mean_return", nil)); best.Version != 2 {
+		t.Fatalf("expected best version 2, got %d", best.Version)
+	}
+	if best := decode(call(http.MethodGet, "/api/v1/runs/run-ckpt/checkpoints/best?metric=mean_return&order=min", nil)); best.Version != 1 {
+		t.Fatalf("expected min version 1, got %d", best.Version)
+	}
+	if run, _ := orch.GetRun(context.Background(), "run-ckpt"); run.CheckpointVersion != 3 {
+		t.Fatalf("expected run checkpoint_version 3, got %d", run.CheckpointVersion)
+	}
+
+	call(http.MethodPost, "/api/v1/runs/run-ckpt/checkpoints/1/promote", nil)
+	promoted := decode(call(http.MethodPost, "/api/v1/runs/run-ckpt/checkpoints/2/promote", map[string]any{"promoted_by": "alice"}))
+	if !promoted.Promoted || promoted.PromotedBy != "alice" {
+		t.Fatalf("expected promoted checkpoint, got %+v", promoted)
+	}
+	if first := decode(call(http.MethodGet, "/api/v1/runs/run-ckpt/checkpoints/1", nil)); first.Promoted {
+		t.Fatalf("expected earlier promotion to be cleared")
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/run-ckpt/checkpoints/zero", nil); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid version, got %d", res.Code)
+	}
+}
+
+func TestRunTemplates(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+
+	res := call(http.MethodPost, "/api/v1/templates", map[string]any{"id": "bad", "manifest": map[string]any{"env": "${env}"}})
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for undeclared placeholder, got %d", res.Code)
+	}
+	res = call(http.MethodPost, "/api/v1/templates", map[string]any{
+		"id":       "ppo",
+		"manifest": map[string]any{"game": map[string]any{"env_id": "${env}"}, "resources": map[string]any{"gpus": "${gpus}"}},
+		"parameters": []map[string]any{
+			{"name": "env"},
+			{"name": "gpus", "type": "integer", "default": 1},
+		},
+	})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call(http.MethodGet, "/api/v1/templates", nil); !strings.Contains(res.Body.String(), `"id":"ppo"`) {
+		t.Fatalf("expected template in listing, got %s", res.Body.String())
+	}
+
+	res = call(http.MethodPost, "/api/v1/templates/ppo/render", map[string]any{"parameters": map[string]any{"env": "pong"}})
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"manifest":{"game":{"env_id":"pong"},"resources":{"gpus":1}}`) {
+		t.Fatalf("unexpected render %d: %s", res.Code, res.Body.String())
+	}
+
+	res = call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-1", "experiment_id": "exp-1", "version_id": "ver-1", "template_id": "ppo", "parameters": map[string]any{"env": "breakout", "gpus": 4}})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	var run types.Run
+	json.Unmarshal(res.Body.Bytes(), &run)
+	if run.TemplateID != "ppo" || types.RequirementsFromManifest(run.LaunchManifest) != (types.RunRequirements{GPUs: 4, Env: "breakout"}) {
+		t.Fatalf("unexpected run %+v (manifest %s)", run, run.LaunchManifest)
+	}
+
+	for name, body := range map[string]map[string]any{
+		"missing parameter": {"experiment_id": "exp-1", "version_id": "ver-1", "template_id": "ppo"},
+		"mistyped":          {"experiment_id": "exp-1", "version_id": "ver-1", "template_id": "ppo", "parameters": map[string]any{"env": "pong", "gpus": "two"}},
+		"unknown template":  {"experiment_id": "exp-1", "version_id": "ver-1", "template_id": "nope"},
+		"with manifest":     {"experiment_id": "exp-1", "version_id": "ver-1", "template_id": "ppo", "launch_manifest": map[string]any{}},
+		"without template":  {"experiment_id": "exp-1", "version_id": "ver-1", "parameters": map[string]any{"env": "pong"}},
+	} {
+		if res := call(http.MethodPost, "/api/v1/runs", body); res.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", name, res.Code, res.Body.String())
+		}
+	}
+}
+
+func TestStoppingRules(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	orch.WithNow(func() time.Time { return now })
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+	heartbeat := func(step int, loss, sps float64) {
+		t.Helper()
+		res := call(http.MethodPost, "/api/v1/runs/run-es/heartbeat", map[string]any{"run_id": "run-es", "status": "running", "step": step, "loss": loss, "samples_per_sec": sps})
+		if res.Code != http.StatusOK {
+			t.Fatalf("heartbeat %d: expected 200, got %d: %s", step, res.Code, res.Body.String())
+		}
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-es", "experiment_id": "exp-1", "version_id": "ver-1"})
+	if res := call(http.MethodPost, "/api/v1/runs/run-es/stopping-rules", map[string]any{"kind": "loss_plateau", "action": "terminate"}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for plateau rule without heartbeats, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-es/stopping-rules", map[string]any{"id": "plateau", "kind": "loss_plateau", "action": "terminate", "heartbeats": 3}); res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-es/stopping-rules", map[string]any{"id": "slow", "kind": "throughput_below", "action": "pause", "threshold": 100, "window_seconds": 60}); res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-es/stopping-rules/slow/disable", nil); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+
+	// Heartbeats before the run starts are not evaluated.
+	for i := 0; i < 5; i++ {
+		heartbeat(i, 1, 10)
+	}
+	call(http.MethodPost, "/api/v1/runs/run-es/provision", nil)
+	call(http.MethodPost, "/api/v1/runs/run-es/start", nil)
+	for i, loss := range []float64{1.0, 0.8, 0.81, 0.8, 0.9} {
+		now = start.Add(time.Duration(i) * time.Minute)
+		heartbeat(10+i, loss, 10)
+	}
+
+	var listing struct {
+		StoppingRules []types.StoppingRule `json:"stopping_rules"`
+	}
+	json.Unmarshal(call(http.MethodGet, "/api/v1/runs/run-es/stopping-rules", nil).Body.Bytes(), &listing)
+	if len(listing.StoppingRules) != 2 {
+		t.Fatalf("expected 2 rules, got %+v", listing.StoppingRules)
+	}
+	plateau, slow := listing.StoppingRules[0], listing.StoppingRules[1]
+	if !plateau.Triggered() || plateau.CommandID == "" || plateau.Reason == "" {
+		t.Fatalf("expected plateau rule to fire, got %+v", plateau)
+	}
+	if slow.Enabled || slow.Triggered() {
+		t.Fatalf("expected disabled rule to stay idle, got %+v", slow)
+	}
+
+	command, err := orch.NextCommand(context.Background(), "run-es")
+	if err != nil {
+		t.Fatalf("next command: %v", err)
+	}
+	var payload types.TerminatePayload
+	json.Unmarshal(command.Payload, &payload)
+	if command.ID != plateau.CommandID || command.Type != types.CommandTypeTerminate || command.Actor.Type != types.CommandActorSystem || payload.Reason != plateau.Reason {
+		t.Fatalf("unexpected command %+v", command)
+	}
+	run, _ := orch.GetRun(context.Background(), "run-es")
+	if !strings.Contains(run.StatusMessage, plateau.Reason) {
+		t.Fatalf("expected status message to carry the reason, got %q", run.StatusMessage)
+	}
+
+	// A fired rule does not fire again.
+	heartbeat(20, 2, 10)
+	if _, err := orch.NextCommand(context.Background(), "run-es"); !errors.Is(err, storage.ErrNoCommands) {
+		t.Fatalf("expected no further commands, got %v", err)
+	}
+}
+
+func TestStoppingRulesFiringTogether(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	orch.WithNow(func() time.Time { return now })
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-both", "experiment_id": "exp-1", "version_id": "ver-1"})
+	for _, rule := range []map[string]any{
+		{"id": "plateau-pause", "kind": "loss_plateau", "action": "pause", "heartbeats": 2},
+		{"id": "plateau-stop", "kind": "loss_plateau", "action": "terminate", "heartbeats": 2},
+	} {
+		if res := call(http.MethodPost, "/api/v1/runs/run-both/stopping-rules", rule); res.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", res.Code, res.Body.String())
+		}
+	}
+	call(http.MethodPost, "/api/v1/runs/run-both/provision", nil)
+	call(http.MethodPost, "/api/v1/runs/run-both/start", nil)
+	for i, loss := range []float64{1.0, 1.0, 1.0, 1.0} {
+		now = start.Add(time.Duration(i) * time.Minute)
+		res := call(http.MethodPost, "/api/v1/runs/run-both/heartbeat", map[string]any{"run_id": "run-both", "status": "running", "step": i, "loss": loss, "samples_per_sec": 10})
+		if res.Code != http.StatusOK {
+			t.Fatalf("heartbeat %d: expected 200, got %d: %s", i, res.Code, res.Body.String())
+		}
+	}
+
+	var listing struct {
+		StoppingRules []types.StoppingRule `json:"stopping_rules"`
+	}
+	json.Unmarshal(call(http.MethodGet, "/api/v1/runs/run-both/stopping-rules", nil).Body.Bytes(), &listing)
+	if len(listing.StoppingRules) != 2 || !listing.StoppingRules[0].Triggered() || !listing.StoppingRules[1].Triggered() ||
+		!listing.StoppingRules[0].TriggeredAt.Equal(*listing.StoppingRules[1].TriggeredAt) {
+		t.Fatalf("expected both rules to fire on one heartbeat, got %+v", listing.StoppingRules)
+	}
+	// The status message records every rule that fired, not just the last.
+	run, _ := orch.GetRun(context.Background(), "run-both")
+	for _, rule := range listing.StoppingRules {
+		if !strings.Contains(run.StatusMessage, "stopping rule "+rule.ID+": "+rule.Reason) {
+			t.Fatalf("expected status message to name %s, got %q", rule.ID, run.StatusMessage)
+		}
+	}
+}
+
+func TestAlertRules(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	publisher := &recordingPublisher{}
+	orch := service.NewOrchestrator(store, publisher, logger)
+	routes := NewServer(orch, logger).Routes()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	orch.WithNow(func() time.Time { return now })
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	heartbeat := func(at time.Duration, loss, sps float64) {
+		t.Helper()
+		now = start.Add(at)
+		res := call(http.MethodPost, "/api/v1/runs/run-al/heartbeat", map[string]any{"run_id": "run-al", "status": "running", "step": int(at.Seconds()), "loss": loss, "samples_per_sec": sps})
+		if res.Code != http.StatusOK {
+			t.Fatalf("heartbeat at %s: expected 200, got %d: %s", at, res.Code, res.Body.String())
+		}
+	}
+	alerts := func() []string {
+		var got []string
+		for _, alert := range publisher.alerts {
+			got = append(got, alert.RuleID+":"+alert.Status)
+		}
+		return got
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-al", "experiment_id": "exp-1", "version_id": "ver-1"})
+	call(http.MethodPost, "/api/v1/runs/run-al/provision", nil)
+	call(http.MethodPost, "/api/v1/runs/run-al/start", nil)
+	for _, bad := range []map[string]any{
+		{"kind": "loss_spike", "severity": "page", "threshold": 50},
+		{"kind": "throughput_floor", "threshold": 0},
+		{"kind": "gpu_temperature", "threshold": 80},
+	} {
+		if res := call(http.MethodPost, "/api/v1/runs/run-al/alert-rules", bad); res.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", bad, res.Code)
+		}
+	}
+	for _, rule := range []map[string]any{
+		{"id": "spike", "kind": "loss_spike", "severity": "critical", "threshold": 50},
+		{"id": "floor", "kind": "throughput_floor", "threshold": 100},
+		{"id": "gap", "kind": "heartbeat_gap", "severity": "info", "threshold": 60},
+	} {
+		if res := call(http.MethodPost, "/api/v1/runs/run-al/alert-rules", rule); res.Code != http.StatusCreated {
+			t.Fatalf("create %v: expected 201, got %d: %s", rule["id"], res.Code, res.Body.String())
+		}
+	}
+
+	heartbeat(0, 1.0, 200)
+	heartbeat(30*time.Second, 1.6, 200)
+	heartbeat(60*time.Second, 1.5, 50)
+	heartbeat(180*time.Second, 1.4, 40)
+	heartbeat(190*time.Second, 1.3, 200)
+	want := "spike:firing,spike:resolved,floor:firing,gap:firing,floor:resolved,gap:resolved"
+	if got := strings.Join(alerts(), ","); got != want {
+		t.Fatalf("expected alerts %s, got %s", want, got)
+	}
+	if first := publisher.alerts[0]; first.Severity != "critical" || first.Step != 30 || !strings.Contains(first.Message, "more than 50%") {
+		t.Fatalf("unexpected spike alert %+v", first)
+	}
+
+	var listing struct {
+		AlertRules []types.AlertRule `json:"alert_rules"`
+	}
+	json.Unmarshal(call(http.MethodGet, "/api/v1/runs/run-al/alert-rules", nil).Body.Bytes(), &listing)
+	if len(listing.AlertRules) != 3 || listing.AlertRules[1].Severity != types.AlertSeverityWarning || listing.AlertRules[1].Breaches != 1 || listing.AlertRules[1].Firing() {
+		t.Fatalf("unexpected rules %+v", listing.AlertRules)
+	}
+
+	// Disabling a firing rule resolves its alert.
+	heartbeat(200*time.Second, 1.3, 10)
+	if res := call(http.MethodPost, "/api/v1/runs/run-al/alert-rules/floor/disable", nil); res.Code != http.StatusOK {
+		t.Fatalf("disable: expected 200, got %d", res.Code)
+	}
+	heartbeat(210*time.Second, 1.3, 10)
+	if got := alerts()[6:]; strings.Join(got, ",") != "floor:firing,floor:resolved" {
+		t.Fatalf("expected the disabled rule resolved and silent, got %v", got)
+	}
+}
+
+func TestRunGraph(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	create := func(id string, dependsOn ...string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"id": id, "experiment_id": "exp-1", "version_id": "ver-1", "depends_on": dependsOn})
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+		return res
+	}
+	for _, spec := range [][]string{{"prep"}, {"train", "prep"}, {"baseline"}, {"eval", "train", "baseline"}, {"sibling", "prep"}, {"report", "eval"}} {
+		if res := create(spec[0], spec[1:]...); res.Code != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d: %s", spec[0], res.Code, res.Body.String())
+		}
+	}
+	if res := create("self", "self"); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a self dependency, got %d", res.Code)
+	}
+
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/train/graph", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var graph types.RunGraph
+	json.Unmarshal(res.Body.Bytes(), &graph)
+	var order []string
+	for _, node := range graph.Nodes {
+		order = append(order, node.ID)
+	}
+	// Ancestors and descendants of train, prerequisites first; sibling is unrelated.
+	// baseline enters through eval but is not a descendant of train, so it is omitted.
+	if got := strings.Join(order, ","); got != "prep,train,eval,report" {
+		t.Fatalf("unexpected nodes %s", got)
+	}
+	if len(graph.Edges) != 3 || graph.Edges[0] != (types.RunGraphEdge{From: "prep", To: "train"}) {
+		t.Fatalf("unexpected edges %+v", graph.Edges)
+	}
+	if eval := graph.Nodes[2]; strings.Join(eval.WaitingOn, ",") != "train,baseline" {
+		t.Fatalf("expected eval to wait on train and baseline, got %+v", eval)
+	}
+}
+
+func TestRunLabelSearch(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	search := func(query string) string {
+		t.Helper()
+		res := call(http.MethodGet, "/api/v1/runs?"+query, nil)
+		if res.Code != http.StatusOK {
+			t.Fatalf("search %q: expected 200, got %d: %s", query, res.Code, res.Body.String())
+		}
+		var page struct {
+			Runs []types.Run `json:"runs"`
+		}
+		json.Unmarshal(res.Body.Bytes(), &page)
+		var ids []string
+		for _, run := range page.Runs {
+			ids = append(ids, run.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	for _, spec := range []struct {
+		id     string
+		labels map[string]string
+	}{
+		{"run-a", map[string]string{"team": "rl", "env": "tictactoe"}},
+		{"run-b", map[string]string{"team": "rl", "env": "connect4"}},
+		{"run-c", map[string]string{"team": "vision"}},
+	} {
+		res := call(http.MethodPost, "/api/v1/runs", map[string]any{"id": spec.id, "experiment_id": "exp-1", "version_id": "ver-1", "labels": spec.labels})
+		if res.Code != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d: %s", spec.id, res.Code, res.Body.String())
+		}
+		time.Sleep(time.Millisecond) // distinct creation times keep the order stable
+	}
+	if res := call(http.MethodPost, "/api/v1/runs", map[string]any{"experiment_id": "exp-1", "version_id": "ver-1", "labels": map[string]string{"-bad": "x"}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid label key, got %d", res.Code)
+	}
+
+	if got := search("label=team%3Drl"); got != "run-a,run-b" {
+		t.Fatalf("team=rl matched %q", got)
+	}
+	if got := search("label=team%3Drl&label=env%3Dtictactoe"); got != "run-a" {
+		t.Fatalf("team=rl,env=tictactoe matched %q", got)
+	}
+	if res := call(http.MethodGet, "/api/v1/runs?label=team", nil); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a label without a value, got %d", res.Code)
+	}
+
+	run, _ := store.GetRun(context.Background(), "run-b")
+	run.StatusMessage = "Diverged: NaN loss"
+	store.UpdateRun(context.Background(), run)
+	if got := search("q=nan"); got != "run-b" {
+		t.Fatalf("free-text search matched %q", got)
+	}
+
+	// Relabelling moves the run between index entries; null deletes a label.
+	res := call(http.MethodPatch, "/api/v1/runs/run-a", map[string]any{"labels": map[string]any{"team": "vision", "env": nil}})
+	if res.Code != http.StatusOK {
+		t.Fatalf("patch: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var patched types.Run
+	json.Unmarshal(res.Body.Bytes(), &patched)
+	if len(patched.Labels) != 1 || patched.Labels["team"] != "vision" {
+		t.Fatalf("unexpected labels after patch %v", patched.Labels)
+	}
+	if got := search("label=team%3Drl"); got != "run-b" {
+		t.Fatalf("team=rl after relabel matched %q", got)
+	}
+	if got := search("label=team%3Dvision&limit=1"); got != "run-a" {
+		t.Fatalf("team=vision page matched %q", got)
+	}
+	if res := call(http.MethodPatch, "/api/v1/runs/missing", map[string]any{"labels": map[string]any{"team": "rl"}}); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 patching a missing run, got %d", res.Code)
+	}
+}
+
+func TestRunArchival(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	orch.WithNow(func() time.Time { return now })
+	orch.WithArchivedMetricsRetention(24 * time.Hour)
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	listed := func(path string) int {
+		t.Helper()
+		res := call(http.MethodGet, path, nil)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, res.Code, res.Body.String())
+		}
+		var page struct {
+			Runs []types.Run `json:"runs"`
+		}
+		json.Unmarshal(res.Body.Bytes(), &page)
+		return len(page.Runs)
+	}
+
+	call(http.MethodPost, "/api/v1/experiments", map[string]any{"id": "exp-arch", "name": "archive"})
+	call(http.MethodPost, "/api/v1/experiments/exp-arch/versions", map[string]any{"id": "ver-arch", "manifest": map[string]any{}})
+	for _, id := range []string{"run-old", "run-new"} {
+		if res := call(http.MethodPost, "/api/v1/runs", map[string]any{"id": id, "experiment_id": "exp-arch", "version_id": "ver-arch"}); res.Code != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d: %s", id, res.Code, res.Body.String())
+		}
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-old/archive", nil); res.Code != http.StatusConflict {
+		t.Fatalf("expected 409 archiving a queued run, got %d", res.Code)
+	}
+	for _, action := range []string{"provision", "start"} {
+		call(http.MethodPost, "/api/v1/runs/run-old/"+action, nil)
+	}
+	call(http.MethodPost, "/api/v1/runs/run-old/heartbeat", map[string]any{"run_id": "run-old", "status": "running", "step": 10})
+
+	res := call(http.MethodPost, "/api/v1/runs/run-old/archive", nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("archive: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if listed("/api/v1/runs") != 1 || listed("/api/v1/runs?include_archived=true") != 2 {
+		t.Fatal("expected the archived run only when include_archived=true")
+	}
+	if listed("/api/v1/experiments/exp-arch/runs") != 1 || listed("/api/v1/experiments/exp-arch/runs?include_archived=true") != 2 {
+		t.Fatal("expected experiment runs to hide the archived run by default")
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/run-old", nil); res.Code != http.StatusOK {
+		t.Fatalf("archived runs stay fetchable, got %d", res.Code)
+	}
+	if monitored, _ := orch.ListRunsForHealthCheck(context.Background(), types.RunStateRunning); len(monitored) != 0 {
+		t.Fatalf("expected archived runs to be excluded from health checks, got %d", len(monitored))
+	}
+
+	// Heartbeat history survives until the run has been archived for the retention window.
+	if removed, _ := orch.PruneMetrics(context.Background()); removed != 0 {
+		t.Fatalf("expected no purge inside the retention window, removed %d", removed)
+	}
+	now = start.Add(25 * time.Hour)
+	if removed, err := orch.PruneMetrics(context.Background()); err != nil || removed != 1 {
+		t.Fatalf("expected the archived run's heartbeat purged, got %d (%v)", removed, err)
+	}
+
+	if res := call(http.MethodPost, "/api/v1/runs/run-old/unarchive", nil); res.Code != http.StatusOK {
+		t.Fatalf("unarchive: expected 200, got %d", res.Code)
+	}
+	if listed("/api/v1/runs") != 2 {
+		t.Fatal("expected the unarchived run back in default listings")
+	}
+}
+
+func TestRunClone(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+
+	call(http.MethodPost, "/api/v1/experiments", map[string]any{"id": "exp-clone", "name": "clone"})
+	call(http.MethodPost, "/api/v1/experiments/exp-clone/versions", map[string]any{"id": "ver-clone", "manifest": map[string]any{}})
+	if res := call(http.MethodPost, "/api/v1/runs", map[string]any{
+		"id": "run-src", "experiment_id": "exp-clone", "version_id": "ver-clone", "priority": 3,
+		"launch_manifest": map[string]any{"lr": 0.1, "env": map[string]any{"seed": 1, "debug": true}},
+		"labels":          map[string]string{"team": "rl"},
+	}); res.Code != http.StatusCreated {
+		t.Fatalf("create source: expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	for _, action := range []string{"provision", "start", "fail"} {
+		call(http.MethodPost, "/api/v1/runs/run-src/"+action, nil)
+	}
+
+	res := call(http.MethodPost, "/api/v1/runs/run-src/clone", map[string]any{
+		"id":             "run-retry",
+		"manifest_patch": map[string]any{"lr": 0.05, "env": map[string]any{"debug": nil}},
+	})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("clone: expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	var clone types.Run
+	json.Unmarshal(res.Body.Bytes(), &clone)
+	if clone.State != types.RunStateQueued || clone.ClonedFrom != "run-src" || clone.ExperimentID != "exp-clone" || clone.VersionID != "ver-clone" {
+		t.Fatalf("unexpected clone: %+v", clone)
+	}
+	if clone.Priority != 3 || clone.Labels["team"] != "rl" {
+		t.Fatalf("expected priority and labels carried over, got %d %v", clone.Priority, clone.Labels)
+	}
+	var manifest map[string]any
+	json.Unmarshal(clone.LaunchManifest, &manifest)
+	env, _ := manifest["env"].(map[string]any)
+	if manifest["lr"] != 0.05 || env["seed"] != float64(1) || env["debug"] != nil {
+		t.Fatalf("expected patched manifest, got %s", clone.LaunchManifest)
+	}
+
+	// An empty body clones the source as-is under a generated ID.
+	res = call(http.MethodPost, "/api/v1/runs/run-src/clone", nil)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("clone without body: expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	json.Unmarshal(res.Body.Bytes(), &clone)
+	if clone.ID == "" || clone.ID == "run-src" || clone.ClonedFrom != "run-src" {
+		t.Fatalf("unexpected clone: %+v", clone)
+	}
+
+	if res := call(http.MethodPost, "/api/v1/runs/run-src/clone", map[string]any{"labels": map[string]string{"-bad": "x"}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid labels, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/missing/clone", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 cloning an unknown run, got %d", res.Code)
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-x", "experiment_id": "exp-1", "version_id": "ver-1"})
+	call(http.MethodPost, "/api/v1/runs/run-x/provision", nil)
+	call(http.MethodPost, "/api/v1/runs/run-x/heartbeat", map[string]any{"run_id": "run-x", "status": "running", "step": 5, "loss": 1.5})
+	if res := call(http.MethodPost, "/api/v1/runs/run-x/commands", map[string]any{
+		"id": "cmd-1", "type": "pause", "issued_at": time.Now().UTC(), "actor": map[string]any{"type": "operator", "id": "tester"},
+	}); res.Code != http.StatusAccepted {
+		t.Fatalf("command: expected 202, got %d: %s", res.Code, res.Body.String())
+	}
+
+	res := call(http.MethodGet, "/api/v1/runs/run-x/export", nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if disposition := res.Header().Get("Content-Disposition"); !strings.Contains(disposition, "run-run-x.json") {
+		t.Fatalf("unexpected Content-Disposition %q", disposition)
+	}
+	var bundle service.RunBundle
+	json.Unmarshal(res.Body.Bytes(), &bundle)
+	if bundle.Run.ID != "run-x" || len(bundle.Transitions) != 2 || len(bundle.Commands) != 1 || len(bundle.Metrics) != 1 {
+		t.Fatalf("unexpected bundle: %d transitions, %d commands, %d metrics", len(bundle.Transitions), len(bundle.Commands), len(bundle.Metrics))
+	}
+
+	res = call(http.MethodGet, "/api/v1/runs/run-x/export?format=tar", nil)
+	if res.Code != http.StatusOK || res.Header().Get("Content-Type") != "application/x-tar" {
+		t.Fatalf("expected a tar export, got %d %q", res.Code, res.Header().Get("Content-Type"))
+	}
+	archive := tar.NewReader(res.Body)
+	var names []string
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	if got := strings.Join(names, ","); got != "run-x/run.json,run-x/transitions.json,run-x/commands.json,run-x/metrics.json" {
+		t.Fatalf("unexpected tar entries %s", got)
+	}
+
+	if res := call(http.MethodGet, "/api/v1/runs/run-x/export?format=zip", nil); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", res.Code)
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/missing/export", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing run, got %d", res.Code)
+	}
+}
+
+func TestManifestSchemas(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	envSchema := map[string]any{
+		"type":     "object",
+		"required": []string{"trainer"},
+		"properties": map[string]any{
+			"trainer": map[string]any{"type": "object", "properties": map[string]any{"batch_size": map[string]any{"type": "integer", "minimum": 1}}},
+		},
+	}
+	res := call(http.MethodPost, "/api/v1/manifest-schemas", map[string]any{"env": "tictactoe", "schema": envSchema})
+	if res.Code != http.StatusOK {
+		t.Fatalf("register: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	res = call(http.MethodPost, "/api/v1/manifest-schemas", map[string]any{"env": "tictactoe", "schema": envSchema})
+	var registered types.ManifestSchema
+	json.Unmarshal(res.Body.Bytes(), &registered)
+	if registered.Revision != 2 {
+		t.Fatalf("expected re-registration to bump the revision, got %d", registered.Revision)
+	}
+	if res := call(http.MethodPost, "/api/v1/manifest-schemas", map[string]any{"env": "go", "schema": map[string]any{"$ref": "#/$defs/nope"}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an uncompilable schema, got %d", res.Code)
+	}
+
+	createRun := func(id string, manifest map[string]any) *httptest.ResponseRecorder {
+		return call(http.MethodPost, "/api/v1/runs", map[string]any{"id": id, "experiment_id": "exp-1", "version_id": "ver-1", "launch_manifest": manifest})
+	}
+	res = createRun("run-bad", map[string]any{"game": map[string]any{"env_id": "tictactoe"}, "trainer": map[string]any{"batch_size": 0}})
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a manifest violating the env schema, got %d: %s", res.Code, res.Body.String())
+	}
+	var failure struct {
+		Details []openapi.FieldError `json:"details"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &failure)
+	if len(failure.Details) != 1 || failure.Details[0].Field != "trainer.batch_size" {
+		t.Fatalf("expected a trainer.batch_size field error, got %s", res.Body.String())
+	}
+	if _, err := store.GetRun(context.Background(), "run-bad"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatal("rejected runs must not be queued")
+	}
+	if res := createRun("run-ok", map[string]any{"game": map[string]any{"env_id": "tictactoe"}, "trainer": map[string]any{"batch_size": 32}}); res.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for a valid manifest, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := createRun("run-other-env", map[string]any{"game": map[string]any{"env_id": "connect4"}}); res.Code != http.StatusCreated {
+		t.Fatalf("expected environments without a schema to be unchecked, got %d", res.Code)
+	}
+
+	// Version schemas apply to every run of the version, including its own manifest.
+	call(http.MethodPost, "/api/v1/experiments", map[string]any{"id": "exp-schema", "name": "schema"})
+	versionSchema := map[string]any{"type": "object", "required": []string{"seed"}, "properties": map[string]any{"seed": map[string]any{"type": "integer"}}}
+	if res := call(http.MethodPost, "/api/v1/experiments/exp-schema/versions", map[string]any{"id": "ver-bad", "manifest": map[string]any{}, "manifest_schema": versionSchema}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a version manifest violating its own schema, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/experiments/exp-schema/versions", map[string]any{"id": "ver-schema", "manifest": map[string]any{"seed": 1}, "manifest_schema": versionSchema}); res.Code != http.StatusCreated {
+		t.Fatalf("create version: expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call(http.MethodPost, "/api/v1/runs", map[string]any{"experiment_id": "exp-schema", "version_id": "ver-schema", "launch_manifest": map[string]any{"seed": "one"}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a run manifest violating the version schema, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs", map[string]any{"experiment_id": "exp-schema", "version_id": "ver-schema"}); res.Code != http.StatusCreated {
+		t.Fatalf("expected the version's own manifest to pass, got %d: %s", res.Code, res.Body.String())
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	for _, id := range []string{"run-a", "run-b", "run-c"} {
+		experiment := "exp-1"
+		if id == "run-c" {
+			experiment = "exp-2"
+		}
+		if res := call(http.MethodPost, "/api/v1/runs", map[string]any{"id": id, "experiment_id": experiment, "version_id": "ver-1"}); res.Code != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d: %s", id, res.Code, res.Body.String())
+		}
+	}
+
+	flag := map[string]any{"name": "prioritized_sampling_v2", "experiments": map[string]bool{"exp-1": true}, "runs": map[string]bool{"run-b": false}}
+	if res := call(http.MethodPost, "/api/v1/flags", flag); res.Code != http.StatusOK {
+		t.Fatalf("put: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	flag["description"] = "sample through the sum tree"
+	res := call(http.MethodPost, "/api/v1/flags", flag)
+	var stored types.FeatureFlag
+	json.Unmarshal(res.Body.Bytes(), &stored)
+	if stored.Revision != 2 || stored.Description != "sample through the sum tree" {
+		t.Fatalf("expected the replacement to bump the revision, got %+v", stored)
+	}
+	if res := call(http.MethodPost, "/api/v1/flags", map[string]any{"name": "compress_transitions", "enabled": true}); res.Code != http.StatusOK {
+		t.Fatalf("put: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call(http.MethodPost, "/api/v1/flags", map[string]any{"name": "Bad-Name"}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid name, got %d", res.Code)
+	}
+	if res := call(http.MethodGet, "/api/v1/flags/missing", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown flag, got %d", res.Code)
+	}
+
+	var listed struct {
+		Flags []types.FeatureFlag `json:"flags"`
+	}
+	json.Unmarshal(call(http.MethodGet, "/api/v1/flags", nil).Body.Bytes(), &listed)
+	if len(listed.Flags) != 2 || listed.Flags[0].Name != "compress_transitions" {
+		t.Fatalf("expected both flags ordered by name, got %+v", listed.Flags)
+	}
+
+	expected := map[string]map[string]bool{
+		"run-a": {"prioritized_sampling_v2": true, "compress_transitions": true},
+		"run-b": {"prioritized_sampling_v2": false, "compress_transitions": true},
+		"run-c": {"prioritized_sampling_v2": false, "compress_transitions": true},
+	}
+	for runID, want := range expected {
+		res := call(http.MethodGet, "/api/v1/runs/"+runID+"/flags", nil)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s flags: expected 200, got %d: %s", runID, res.Code, res.Body.String())
+		}
+		var resolved service.RunFeatureFlags
+		json.Unmarshal(res.Body.Bytes(), &resolved)
+		if !maps.Equal(resolved.Flags, want) {
+			t.Fatalf("%s: expected %v, got %v", runID, want, resolved.Flags)
+		}
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/missing/flags", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown run, got %d", res.Code)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	body, _ := json.Marshal(map[string]any{
+		"id":              "run-ctx",
+		"experiment_id":   "exp-1",
+		"version_id":      "ver-1",
+		"launch_manifest": map[string]any{"game": map[string]any{"env_id": "tictactoe"}},
+	})
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+	if res.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+
+	res = httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/run-ctx/context", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("context: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var issued service.RunContext
+	json.Unmarshal(res.Body.Bytes(), &issued)
+	want := service.RunContext{RunID: "run-ctx", ExperimentID: "exp-1", VersionID: "ver-1", EnvID: "tictactoe"}
+	if issued != want {
+		t.Fatalf("expected %+v, got %+v", want, issued)
+	}
+
+	res = httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs/missing/context", nil))
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown run, got %d", res.Code)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+
+	if res := call(http.MethodPost, "/api/v1/schedules", map[string]any{"id": "bad", "cron": "every day", "experiment_id": "exp-1", "version_id": "ver-1"}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid cron, got %d", res.Code)
+	}
+	res := call(http.MethodPost, "/api/v1/schedules", map[string]any{"id": "hourly", "cron": "@hourly", "experiment_id": "exp-1", "version_id": "ver-1"})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", res.Code, res.Body.String())
+	}
+	var schedule types.Schedule
+	json.Unmarshal(res.Body.Bytes(), &schedule)
+	if !schedule.Enabled || schedule.OverlapPolicy != types.OverlapSkip || schedule.NextRunAt == nil {
+		t.Fatalf("unexpected schedule %+v", schedule)
+	}
+	res = call(http.MethodPost, "/api/v1/schedules/hourly/pause", nil)
+	json.Unmarshal(res.Body.Bytes(), &schedule)
+	if res.Code != http.StatusOK || schedule.Enabled {
+		t.Fatalf("expected paused schedule, got %d %+v", res.Code, schedule)
+	}
+	res = call(http.MethodPost, "/api/v1/schedules/hourly/enable", nil)
+	json.Unmarshal(res.Body.Bytes(), &schedule)
+	if res.Code != http.StatusOK || !schedule.Enabled {
+		t.Fatalf("expected enabled schedule, got %d %+v", res.Code, schedule)
+	}
+	if res := call(http.MethodGet, "/api/v1/schedules/missing", nil); res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", res.Code)
+	}
+}
+
+func TestAPIKeyRoles(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+	keyring, err := auth.NewKeyring([]auth.StaticKey{
+		{Name: "ops", Role: auth.RoleOperator, Secret: "ops-secret"},
+		{Name: "viewer", Role: auth.RoleReadOnly, Secret: "viewer-secret"},
+	})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	server.WithKeyring(keyring)
+	routes := server.Routes()
+
+	call := func(method, path, key string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+
+	runBody, _ := json.Marshal(map[string]any{"id": "run-auth", "experiment_id": "exp-1", "version_id": "ver-1"})
+	if res := call(http.MethodPost, "/api/v1/runs", "", runBody); res.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without key, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs", "viewer-secret", runBody); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for read-only key, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs", "ops-secret", runBody); res.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for operator key, got %d", res.Code)
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/run-auth", "viewer-secret", nil); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 for read-only GET, got %d", res.Code)
+	}
+
+	keyBody, _ := json.Marshal(map[string]any{"name": "learner-1", "role": "learner"})
+	res := call(http.MethodPost, "/api/v1/keys", "ops-secret", keyBody)
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating key, got %d", res.Code)
+	}
+	var created struct {
+		Key    auth.APIKey `json:"key"`
+		Secret string      `json:"secret"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode key: %v", err)
+	}
+	hbBody, _ := json.Marshal(map[string]any{"run_id": "run-auth", "status": "running", "step": 1})
+	if res := call(http.MethodPost, "/api/v1/runs/run-auth/heartbeat", created.Secret, hbBody); res.Code != http.StatusOK {
+		t.Fatalf("expected learner heartbeat to succeed, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/keys/"+created.Key.ID+"/revoke", "ops-secret", nil); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 revoking key, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-auth/heartbeat", created.Secret, hbBody); res.Code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked key to be rejected, got %d", res.Code)
+	}
+
+	cmdBody, _ := json.Marshal(map[string]any{"type": "pause", "actor": map[string]string{"type": "system", "id": "spoofed"}})
+	res = call(http.MethodPost, "/api/v1/runs/run-auth/commands", "ops-secret", cmdBody)
+	if res.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 creating command, got %d", res.Code)
+	}
+	var command types.RunCommand
+	if err := json.Unmarshal(res.Body.Bytes(), &command); err != nil {
+		t.Fatalf("decode command: %v", err)
+	}
+	if command.Actor.Type != types.CommandActorOperator || command.Actor.ID != "ops" {
+		t.Fatalf("expected actor recorded from credentials, got %+v", command.Actor)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+	keyring, err := auth.NewKeyring([]auth.StaticKey{
+		{Name: "ops", Role: auth.RoleOperator, Secret: "ops-secret"},
+		{Name: "viewer", Role: auth.RoleReadOnly, Secret: "viewer-secret"},
+	})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	server.WithKeyring(keyring)
+	routes := server.Routes()
+
+	call := func(method, path, key string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+	listAudit := func(query string) (entries []types.AuditEntry, next string) {
+		t.Helper()
+		res := call(http.MethodGet, "/api/v1/audit"+query, "ops-secret", nil)
+		if res.Code != http.StatusOK {
+			t.Fatalf("audit%s: expected 200, got %d: %s", query, res.Code, res.Body.String())
+		}
+		var page struct {
+			Entries    []types.AuditEntry `json:"entries"`
+			NextCursor string             `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(res.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decode audit: %v", err)
+		}
+		return page.Entries, page.NextCursor
+	}
+
+	runBody, _ := json.Marshal(map[string]any{"id": "run-audit", "experiment_id": "exp-1", "version_id": "ver-1"})
+	call(http.MethodPost, "/api/v1/runs", "ops-secret", runBody)
+	call(http.MethodPost, "/api/v1/runs/run-audit/pause", "viewer-secret", nil)
+	call(http.MethodGet, "/api/v1/runs/run-audit", "viewer-secret", nil)
+	call(http.MethodPost, "/api/v1/runs/run-audit/provision", "ops-secret", nil)
+
+	entries, _ := listAudit("")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audited mutations, got %d", len(entries))
+	}
+	digest := sha256.Sum256(runBody)
+	first := entries[0]
+	if first.Actor != "ops" || first.Role != string(auth.RoleOperator) || first.AuthMethod != auth.MethodAPIKey ||
+		first.Method != http.MethodPost || first.Path != "/api/v1/runs" || first.Status != http.StatusCreated ||
+		first.PayloadSHA256 != hex.EncodeToString(digest[:]) || first.PayloadBytes != int64(len(runBody)) {
+		t.Fatalf("unexpected audit entry %+v", first)
+	}
+
+	denied, _ := listAudit("?min_status=400")
+	if len(denied) != 1 || denied[0].Actor != "viewer" || denied[0].Status != http.StatusForbidden {
+		t.Fatalf("expected the viewer's rejected pause, got %+v", denied)
+	}
+	if runEntries, _ := listAudit("?actor=ops&path_prefix=/api/v1/runs/run-audit"); len(runEntries) != 1 {
+		t.Fatalf("expected 1 operator entry for the run, got %d", len(runEntries))
+	}
+
+	page, next := listAudit("?limit=2")
+	if len(page) != 2 || next == "" {
+		t.Fatalf("expected a 2-entry page with a cursor, got %d entries", len(page))
+	}
+	rest, next := listAudit("?limit=2&cursor=" + next)
+	if len(rest) != 1 || next != "" || rest[0].Path != "/api/v1/runs/run-audit/provision" {
+		t.Fatalf("unexpected second page %+v", rest)
+	}
+	if res := call(http.MethodGet, "/api/v1/audit", "viewer-secret", nil); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for read-only audit access, got %d", res.Code)
+	}
+}
+
+func TestHeartbeatDrivenTransitions(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	publisher := &recordingPublisher{}
+	orch := service.NewOrchestrator(store, publisher, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	step := int64(0)
+	heartbeat := func(runID string, status types.RuntimeStatus, notes string) types.Run {
+		t.Helper()
+		step++
+		res := call(http.MethodPost, "/api/v1/runs/"+runID+"/heartbeat", map[string]any{"run_id": runID, "status": status, "step": step, "notes": notes})
+		if res.Code != http.StatusOK {
+			t.Fatalf("heartbeat: expected 200, got %d: %s", res.Code, res.Body.String())
+		}
+		run, _ := store.GetRun(context.Background(), runID)
+		return run
+	}
+	transitions := func(runID string) []storage.RunTransition {
+		history, _ := store.ListTransitions(context.Background(), runID)
+		return history
+	}
+
+	for _, id := range []string{"run-hb", "run-queued"} {
+		call(http.MethodPost, "/api/v1/runs", map[string]any{"id": id, "experiment_id": "exp-1", "version_id": "ver-1"})
+	}
+	for _, action := range []string{"provision", "start"} {
+		call(http.MethodPost, "/api/v1/runs/run-hb/"+action, nil)
+	}
+	if run := heartbeat("run-hb", types.RuntimeStatusRunning, ""); run.State != types.RunStateRunning {
+		t.Fatalf("expected running heartbeats to leave the state alone, got %s", run.State)
+	}
+
+	recorded := len(transitions("run-hb"))
+	run := heartbeat("run-hb", types.RuntimeStatusErrored, "CUDA out of memory")
+	if run.State != types.RunStateErrored || run.StatusMessage != "learner reported errored: CUDA out of memory" {
+		t.Fatalf("expected errored run, got %s (%q)", run.State, run.StatusMessage)
+	}
+	history := transitions("run-hb")
+	if len(history) != recorded+1 {
+		t.Fatalf("expected one new transition, got %d", len(history)-recorded)
+	}
+	if last := history[len(history)-1]; last.FromState != types.RunStateRunning || last.ToState != types.RunStateErrored || last.ChangedBy != "heartbeat" {
+		t.Fatalf("unexpected transition %+v", last)
+	}
+	publisher.mu.Lock()
+	event := publisher.statuses[len(publisher.statuses)-1]
+	publisher.mu.Unlock()
+	if event.State != "errored" || event.PreviousState != "running" || event.Reason == "" {
+		t.Fatalf("expected a status event for the transition, got %+v", event)
+	}
+
+	// Repeated reports do not append duplicate transitions.
+	heartbeat("run-hb", types.RuntimeStatusErrored, "")
+	if len(transitions("run-hb")) != recorded+1 {
+		t.Fatal("expected no transition for a repeated errored heartbeat")
+	}
+	if run := heartbeat("run-hb", types.RuntimeStatusTerminating, ""); run.State != types.RunStateTerminating {
+		t.Fatalf("expected errored -> terminating, got %s", run.State)
+	}
+
+	// Reports the state machine does not allow only update the runtime status.
+	if run := heartbeat("run-queued", types.RuntimeStatusErrored, ""); run.State != types.RunStateQueued || run.RuntimeStatus != types.RuntimeStatusErrored {
+		t.Fatalf("expected queued run to keep its state, got %s/%s", run.State, run.RuntimeStatus)
+	}
+}
+
+// recordingPublisher captures published events.
+type recordingPublisher struct {
+	mu         sync.Mutex
+	statuses   []events.RunStatusEvent
+	commands   []events.CommandEvent
+	accounting []events.AccountingEvent
+	alerts     []events.AlertEvent
+}
+
+func (p *recordingPublisher) PublishRunStatus(_ context.Context, event events.RunStatusEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses = append(p.statuses, event)
+	return nil
+}
+
+func (p *recordingPublisher) PublishCommandEvent(_ context.Context, event events.CommandEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commands = append(p.commands, event)
+	return nil
+}
+
+func (p *recordingPublisher) PublishAccountingEvent(_ context.Context, event events.AccountingEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.accounting = append(p.accounting, event)
+	return nil
+}
+
+func (p *recordingPublisher) PublishAlertEvent(_ context.Context, event events.AlertEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.alerts = append(p.alerts, event)
+	return nil
+}
+
+func TestCorrelationIDPropagation(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	publisher := &recordingPublisher{}
+	orch := service.NewOrchestrator(store, publisher, logger)
+	routes := NewServer(orch, logger).Routes()
+	call := func(method, path, correlationID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if correlationID != "" {
+			req.Header.Set("X-Correlation-ID", correlationID)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+
+	if res := call(http.MethodPost, "/api/v1/runs", "", `{"id":"run-c","experiment_id":"exp-1","version_id":"ver-1"}`); res.Header().Get("X-Correlation-ID") == "" {
+		t.Fatalf("expected a generated correlation ID on the response")
+	}
+	res := call(http.MethodPost, "/api/v1/runs/run-c/provision", "req-provision", "")
+	if res.Code != http.StatusOK || res.Header().Get("X-Correlation-ID") != "req-provision" {
+		t.Fatalf("expected the caller's correlation ID echoed, got %d %q", res.Code, res.Header().Get("X-Correlation-ID"))
+	}
+	command := `{"id":"cmd-c","type":"pause","issued_at":"2024-01-01T00:00:00Z","actor":{"type":"operator","id":"ops"},"payload":{}}`
+	if res := call(http.MethodPost, "/api/v1/runs/run-c/commands", "req-command", command); res.Code != http.StatusAccepted {
+		t.Fatalf("create command: expected 202, got %d: %s", res.Code, res.Body.String())
+	}
+
+	if len(publisher.statuses) != 1 || publisher.statuses[0].CorrelationID != "req-provision" {
+		t.Fatalf("expected the transition event to carry req-provision, got %+v", publisher.statuses)
+	}
+	if len(publisher.commands) != 1 || publisher.commands[0].CorrelationID != "req-command" {
+		t.Fatalf("expected the command event to carry req-command, got %+v", publisher.commands)
+	}
+	entries, _, _ := orch.ListAudit(context.Background(), storage.AuditFilter{PathPrefix: "/api/v1/runs/run-c/provision"})
+	if len(entries) != 1 || entries[0].CorrelationID != "req-provision" {
+		t.Fatalf("expected the audit entry to carry req-provision, got %+v", entries)
+	}
+}
+
+// failingStore fails run lookups the way an unreachable database would.
+type failingStore struct {
+	*storage.MemoryStore
+}
+
+func (failingStore) GetRun(context.Context, string) (types.Run, error) {
+	return types.Run{}, errors.New("connection refused")
+}
+
+func TestListRunsByState(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	base := time.Now().UTC()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+	type page struct {
+		Runs       []types.Run `json:"runs"`
+		NextCursor string      `json:"next_cursor"`
+	}
+	list := func(query string) page {
+		t.Helper()
+		res := call(http.MethodGet, "/api/v1/runs"+query, nil)
+		if res.Code != http.StatusOK {
+			t.Fatalf("list %s: expected 200, got %d: %s", query, res.Code, res.Body.String())
+		}
+		var p page
+		json.Unmarshal(res.Body.Bytes(), &p)
+		return p
+	}
+	ids := func(runs []types.Run) string {
+		var out []string
+		for _, run := range runs {
+			out = append(out, run.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	for i, id := range []string{"run-a", "run-b", "run-c", "run-d"} {
+		at := base.Add(time.Duration(i) * time.Second)
+		orch.WithNow(func() time.Time { return at })
+		call(http.MethodPost, "/api/v1/runs", map[string]any{"id": id, "experiment_id": "exp-1", "version_id": "ver-1"})
+	}
+	for _, id := range []string{"run-a", "run-c", "run-d"} {
+		call(http.MethodPost, "/api/v1/runs/"+id+"/provision", map[string]any{})
+	}
+	call(http.MethodPost, "/api/v1/runs/run-d/fail", map[string]any{"reason": "boom"})
+
+	first := list("?state=provisioning&limit=1")
+	if ids(first.Runs) != "run-a" || first.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	rest := list("?state=provisioning&limit=1&cursor=" + first.NextCursor)
+	if ids(rest.Runs) != "run-c" || rest.NextCursor != "" {
+		t.Fatalf("unexpected second page %+v", rest)
+	}
+	if got := ids(list("?state=queued,failed").Runs); got != "run-b,run-d" {
+		t.Fatalf("expected comma-separated states to combine, got %s", got)
+	}
+	if got := ids(list("?state=queued&state=provisioning").Runs); got != "run-a,run-b,run-c" {
+		t.Fatalf("expected repeated states to combine, got %s", got)
+	}
+	if got := list("?state=running").Runs; len(got) != 0 {
+		t.Fatalf("expected no running runs, got %+v", got)
+	}
+}
+
+func TestRunETags(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	call := func(method, path, ifNoneMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+
+	call(http.MethodPost, "/api/v1/experiments", "", `{"id":"exp-etag","name":"etag"}`)
+	call(http.MethodPost, "/api/v1/experiments/exp-etag/versions", "", `{"id":"ver-etag","manifest":{}}`)
+	call(http.MethodPost, "/api/v1/runs", "", `{"id":"run-etag","experiment_id":"exp-etag","version_id":"ver-etag"}`)
+	for _, path := range []string{"/api/v1/runs/run-etag", "/api/v1/runs", "/api/v1/experiments/exp-etag/runs"} {
+		first := call(http.MethodGet, path, "", "")
+		etag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: expected 200 with an ETag, got %d %q", path, first.Code, etag)
+		}
+		cached := call(http.MethodGet, path, `"other", `+etag, "")
+		if cached.Code != http.StatusNotModified || cached.Body.Len() != 0 || cached.Header().Get("ETag") != etag {
+			t.Fatalf("%s: expected an empty 304 for a matching If-None-Match, got %d", path, cached.Code)
+		}
+	}
+
+	before := call(http.MethodGet, "/api/v1/runs/run-etag", "", "").Header().Get("ETag")
+	listBefore := call(http.MethodGet, "/api/v1/runs", "", "").Header().Get("ETag")
+	call(http.MethodPost, "/api/v1/runs/run-etag/provision", "", "")
+	if res := call(http.MethodGet, "/api/v1/runs/run-etag", before, ""); res.Code != http.StatusOK || res.Header().Get("ETag") == before {
+		t.Fatalf("expected a changed run to return 200 with a new ETag, got %d", res.Code)
+	}
+	if res := call(http.MethodGet, "/api/v1/runs", listBefore, ""); res.Code != http.StatusOK {
+		t.Fatalf("expected the run listing to change after a transition, got %d", res.Code)
+	}
+	call(http.MethodPost, "/api/v1/runs", "", `{"id":"run-etag-2","experiment_id":"exp-etag","version_id":"ver-etag"}`)
+	listBefore = call(http.MethodGet, "/api/v1/runs", "", "").Header().Get("ETag")
+	call(http.MethodPost, "/api/v1/runs", "", `{"id":"run-etag-3","experiment_id":"exp-etag","version_id":"ver-etag"}`)
+	if res := call(http.MethodGet, "/api/v1/runs", listBefore, ""); res.Code != http.StatusOK {
+		t.Fatalf("expected a new run to change the listing, got %d", res.Code)
+	}
+}
+
+func TestUpdateRun(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	orch.WithNow(func() time.Time { now = now.Add(time.Second); return now })
+	call := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+	patch := func(query, ifMatch, body string, want int) types.Run {
+		t.Helper()
+		res := call(http.MethodPatch, "/api/v1/runs/run-u"+query, ifMatch, body)
+		if res.Code != want {
+			t.Fatalf("patch%s %s: expected %d, got %d: %s", query, body, want, res.Code, res.Body.String())
+		}
+		var run types.Run
+		json.Unmarshal(res.Body.Bytes(), &run)
+		return run
+	}
+
+	call(http.MethodPost, "/api/v1/experiments", "", `{"id":"exp-u","name":"update"}`)
+	call(http.MethodPost, "/api/v1/experiments/exp-u/versions", "", `{"id":"ver-u","manifest":{}}`)
+	call(http.MethodPost, "/api/v1/runs", "", `{"id":"run-u","experiment_id":"exp-u","version_id":"ver-u","priority":1,"labels":{"team":"rl","env":"go"}}`)
+
+	// Without a mask, only the fields present change.
+	run := patch("", "", `{"priority":5,"status_message":"warming up","overrides":{"lr":0.1}}`, http.StatusOK)
+	if run.Priority != 5 || run.StatusMessage != "warming up" || string(run.Overrides) != `{"lr":0.1}` || run.Labels["team"] != "rl" {
+		t.Fatalf("unexpected run after merge patch %+v", run)
+	}
+
+	// A mask replaces exactly the named fields, clearing those left out.
+	run = patch("?update_mask=status_message,labels.env,overrides", "", `{"priority":9,"labels":{"team":"x"}}`, http.StatusOK)
+	if run.Priority != 5 || run.StatusMessage != "" || run.Overrides != nil || len(run.Labels) != 1 || run.Labels["team"] != "rl" {
+		t.Fatalf("unexpected run after masked patch %+v", run)
+	}
+	run = patch("?update_mask=labels", "", `{"labels":{"owner":"ana"}}`, http.StatusOK)
+	if len(run.Labels) != 1 || run.Labels["owner"] != "ana" {
+		t.Fatalf("expected labels to be replaced, got %v", run.Labels)
+	}
+	patch("?update_mask=state", "", `{}`, http.StatusBadRequest)
+	patch("", "", `{"overrides":[1]}`, http.StatusBadRequest)
+
+	// If-Match guards against lost updates.
+	res := call(http.MethodGet, "/api/v1/runs/run-u", "", "")
+	etag := res.Header().Get("ETag")
+	res = call(http.MethodPatch, "/api/v1/runs/run-u", etag, `{"priority":7}`)
+	if res.Code != http.StatusOK || res.Header().Get("ETag") == etag || res.Header().Get("ETag") == "" {
+		t.Fatalf("expected a matching If-Match to apply with a new ETag, got %d %q", res.Code, res.Header().Get("ETag"))
+	}
+	fresh := res.Header().Get("ETag")
+	res = call(http.MethodPatch, "/api/v1/runs/run-u", etag, `{"priority":8}`)
+	var envelope apierror.Envelope
+	json.Unmarshal(res.Body.Bytes(), &envelope)
+	if res.Code != http.StatusPreconditionFailed || envelope.Code != apierror.CodePreconditionFailed {
+		t.Fatalf("expected 412 precondition_failed for a stale If-Match, got %d %s", res.Code, res.Body.String())
+	}
+	if run, _ := store.GetRun(context.Background(), "run-u"); run.Priority != 7 {
+		t.Fatalf("stale update was applied: priority %d", run.Priority)
+	}
+	patch("", fresh, `{"priority":8}`, http.StatusOK)
+
+	// Overrides are fixed once the run leaves the queue.
+	call(http.MethodPost, "/api/v1/runs/run-u/provision", "", "")
+	patch("", "", `{"overrides":{"lr":0.2}}`, http.StatusConflict)
+	if run := patch("", "", `{"status_message":"provisioning"}`, http.StatusOK); run.StatusMessage != "provisioning" {
+		t.Fatalf("expected status_message to remain mutable, got %q", run.StatusMessage)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+	call := func(routes http.Handler, method, path, body string) (int, apierror.Envelope) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		var envelope apierror.Envelope
+		json.Unmarshal(res.Body.Bytes(), &envelope)
+		return res.Code, envelope
+	}
+
+	call(routes, http.MethodPost, "/api/v1/runs", `{"id":"run-e","experiment_id":"exp-1","version_id":"ver-1"}`)
+	call(routes, http.MethodPost, "/api/v1/runs", `{"id":"run-q","experiment_id":"exp-1","version_id":"ver-1"}`)
+	call(routes, http.MethodPost, "/api/v1/runs/run-e/provision", "")
+	call(routes, http.MethodPost, "/api/v1/runs/run-e/start", "")
+	call(routes, http.MethodPost, "/api/v1/runs/run-e/heartbeat", `{"run_id":"run-e","status":"running","step":10}`)
+	cases := []struct {
+		name, method, path, body string
+		status                   int
+		code                     apierror.Code
+	}{
+		{"unknown run", http.MethodGet, "/api/v1/runs/missing", "", http.StatusNotFound, apierror.CodeNotFound},
+		{"archive queued run", http.MethodPost, "/api/v1/runs/run-q/archive", "", http.StatusConflict, apierror.CodeStateConflict},
+		{"invalid transition", http.MethodPost, "/api/v1/runs/run-e/resume", "", http.StatusConflict, apierror.CodeStateConflict},
+		{"invalid label", http.MethodPatch, "/api/v1/runs/run-e", `{"labels":{"-bad":"x"}}`, http.StatusBadRequest, apierror.CodeValidation},
+		{"step regression", http.MethodPost, "/api/v1/runs/run-e/heartbeat", `{"run_id":"run-e","status":"running","step":5}`, http.StatusUnprocessableEntity, apierror.CodeRegression},
+		{"bad cursor", http.MethodGet, "/api/v1/runs?cursor=%25", "", http.StatusBadRequest, apierror.CodeValidation},
+	}
+	for _, tc := range cases {
+		status, envelope := call(routes, tc.method, tc.path, tc.body)
+		if status != tc.status || envelope.Code != tc.code || envelope.Message == "" {
+			t.Fatalf("%s: expected %d %s, got %d %+v", tc.name, tc.status, tc.code, status, envelope)
+		}
+	}
+
+	failing := NewServer(service.NewOrchestrator(failingStore{store}, events.NoopPublisher{}, logger), logger).Routes()
+	status, envelope := call(failing, http.MethodGet, "/api/v1/runs/run-e", "")
+	if status != http.StatusInternalServerError || envelope.Code != apierror.CodeInternal || strings.Contains(envelope.Message, "connection refused") {
+		t.Fatalf("expected an opaque internal error, got %d %+v", status, envelope)
+	}
+}
+
+func TestHeartbeatBatch(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	for _, runID := range []string{"run-b1", "run-b2"} {
+		body, _ := json.Marshal(map[string]any{"id": runID, "experiment_id": "exp-1", "version_id": "ver-1"})
+		routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/runs", bytes.NewReader(body)))
+	}
+	batch, _ := json.Marshal([]map[string]any{
+		{"run_id": "run-b1", "status": "running", "step": 10, "loss": 0.9},
+		{"run_id": "run-b1", "status": "running", "step": 20, "loss": 0.8},
+		{"run_id": "run-b2", "status": "running", "step": 5},
+		{"run_id": "run-b1", "status": "running", "step": 15},
+		{"run_id": "missing", "status": "running", "step": 1},
+		{"status": "running"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/heartbeats", bytes.NewReader(batch))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var response struct {
+		Accepted int `json:"accepted"`
+		Rejected int `json:"rejected"`
+		Results  []struct {
+			Index  int    `json:"index"`
+			RunID  string `json:"run_id"`
+			Status int    `json:"status"`
+			Error  string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode batch response: %v", err)
+	}
+	if response.Accepted != 3 || response.Rejected != 3 {
+		t.Fatalf("expected 3 accepted and 3 rejected, got %+v", response)
+	}
+	wantStatus := []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusUnprocessableEntity, http.StatusNotFound, http.StatusBadRequest}
+	for i, result := range response.Results {
+		if result.Index != i || result.Status != wantStatus[i] {
+			t.Fatalf("item %d: expected status %d, got %+v", i, wantStatus[i], result)
+		}
+	}
+	if run, _ := orch.GetRun(context.Background(), "run-b1"); run.CurrentStep != 20 {
+		t.Fatalf("expected run-b1 at step 20 after the regressing item was rejected, got %d", run.CurrentStep)
+	}
+
+	res = httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/heartbeats", strings.NewReader(`{"run_id":"run-b1"}`)))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-array body, got %d", res.Code)
+	}
+}
+
+func TestRunTokens(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+	signer, err := auth.NewRunTokenSigner("test-run-token-secret")
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	server.WithRunTokens(signer)
+	routes := server.Routes()
+	call := func(method, path, body string, tokens ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, token := range tokens {
+			req.Header.Add("X-Run-Token", token)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+
+	tokens := map[string]string{}
+	for _, runID := range []string{"run-t1", "run-t2"} {
+		res := call(http.MethodPost, "/api/v1/runs", `{"id":"`+runID+`","experiment_id":"exp-1","version_id":"ver-1"}`)
+		if res.Code != http.StatusCreated {
+			t.Fatalf("create %s: expected 201, got %d: %s", runID, res.Code, res.Body.String())
+		}
+		var created struct {
+			ID       string `json:"id"`
+			RunToken string `json:"run_token"`
+		}
+		json.Unmarshal(res.Body.Bytes(), &created)
+		if created.ID != runID || created.RunToken == "" {
+			t.Fatalf("expected run and token in create response, got %s", res.Body.String())
+		}
+		tokens[runID] = created.RunToken
+	}
+
+	heartbeat := `{"run_id":"run-t1","status":"running","step":1}`
+	if res := call(http.MethodPost, "/api/v1/runs/run-t1/heartbeat", heartbeat); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without a run token, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-t1/heartbeat", heartbeat, tokens["run-t2"]); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 with another run's token, got %d", res.Code)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-t1/heartbeat", heartbeat, tokens["run-t1"]); res.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the run's token, got %d: %s", res.Code, res.Body.String())
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/run-t1/commands/next", "", tokens["run-t2"]); res.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 polling commands with another run's token, got %d", res.Code)
+	}
+	if res := call(http.MethodGet, "/api/v1/runs/run-t1/commands/next", "", tokens["run-t1"]); res.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 polling commands with the run's token, got %d", res.Code)
+	}
+
+	batch := `[{"run_id":"run-t1","status":"running","step":2},{"run_id":"run-t2","status":"running","step":1}]`
+	res := call(http.MethodPost, "/api/v1/heartbeats", batch, tokens["run-t1"])
+	var response struct {
+		Accepted int `json:"accepted"`
+		Results  []struct {
+			Status int `json:"status"`
+		} `json:"results"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &response)
+	if response.Accepted != 1 || response.Results[1].Status != http.StatusForbidden {
+		t.Fatalf("expected only run-t1 accepted, got %s", res.Body.String())
+	}
+	res = call(http.MethodPost, "/api/v1/heartbeats", strings.ReplaceAll(batch, `"step":2`, `"step":3`), tokens["run-t1"]+", "+tokens["run-t2"])
+	json.Unmarshal(res.Body.Bytes(), &response)
+	if response.Accepted != 2 {
+		t.Fatalf("expected both runs accepted with both tokens, got %s", res.Body.String())
+	}
+
+	res = call(http.MethodGet, "/api/v1/runs/run-t2/token", "")
+	var reissued struct {
+		RunToken string `json:"run_token"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &reissued)
+	if res.Code != http.StatusOK || reissued.RunToken != tokens["run-t2"] {
+		t.Fatalf("expected reissued token to match, got %d: %s", res.Code, res.Body.String())
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/learners", strings.NewReader(`{"slots":"two","capabilities":{"envs":"pong"}}`)))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", res.Code, res.Body.String())
+	}
+	var body struct {
+		Code    string               `json:"code"`
+		Message string               `json:"message"`
+		Details []openapi.FieldError `json:"details"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	want := []openapi.FieldError{
+		{Field: "capabilities.envs", Message: "must be an array"},
+		{Field: "id", Message: "is required"},
+		{Field: "slots", Message: "must be an integer"},
+	}
+	if body.Code != "validation_failed" || body.Message == "" || fmt.Sprint(body.Details) != fmt.Sprint(want) {
+		t.Fatalf("unexpected validation error %+v", body)
+	}
+	if _, err := orch.GetLearner(context.Background(), ""); err == nil {
+		t.Fatalf("expected the invalid request not to reach the handler")
+	}
+
+	res = httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"openapi": "3.0.3"`) {
+		t.Fatalf("expected the OpenAPI document, got %d", res.Code)
+	}
+}
+
+// TestOpenAPICoversRoutes fails when the document describes an operation the
+// router does not serve, keeping the two in step.
+func TestProbeEndpoints(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+	keyring, err := auth.NewKeyring([]auth.StaticKey{{Name: "ops", Role: auth.RoleOperator, Secret: "ops-secret"}})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	server.WithKeyring(keyring)
+	prober := probe.New(time.Second)
+	loop := probe.NewLoop(time.Minute, nil)
+	prober.AddLiveness("dispatcher", loop.Check)
+	var brokerErr error
+	prober.AddReadiness("events", probe.Ping(func(context.Context) error { return brokerErr }))
+	server.WithProbes(prober)
+	routes := server.Routes()
+
+	// Probes are reachable without credentials even though the API requires them.
+	call := func(path string) (int, probe.Report) {
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		var report probe.Report
+		json.Unmarshal(res.Body.Bytes(), &report)
+		return res.Code, report
+	}
+
+	if code, report := call("/healthz"); code != http.StatusServiceUnavailable || report.Components["dispatcher"].Status != probe.StatusDown {
+		t.Fatalf("expected healthz to fail before the loop starts, got %d %+v", code, report)
+	}
+	loop.Tick()
+	code, report := call("/healthz")
+	if code != http.StatusOK || report.Status != probe.StatusOK || report.Components["dispatcher"].LastTick == nil {
+		t.Fatalf("expected healthz ok once the loop ticks, got %d %+v", code, report)
+	}
+	if _, ok := report.Components["events"]; ok {
+		t.Fatal("expected liveness to exclude readiness checks")
+	}
+	if code, report := call("/readyz"); code != http.StatusOK || len(report.Components) != 2 {
+		t.Fatalf("expected readyz ok with both components, got %d %+v", code, report)
+	}
+
+	brokerErr = errors.New("nats connection reconnecting; 3 events buffered")
+	code, report = call("/readyz")
+	if code != http.StatusServiceUnavailable || report.Components["events"].Error != brokerErr.Error() {
+		t.Fatalf("expected readyz to fail on the publisher, got %d %+v", code, report)
+	}
+	if code, _ := call("/healthz"); code != http.StatusOK {
+		t.Fatalf("expected a publisher outage not to fail healthz, got %d", code)
+	}
+
+	res := httptest.NewRecorder()
+	routes.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/runs", nil))
+	if res.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the API to still require credentials, got %d", res.Code)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+	keyring, err := auth.NewKeyring([]auth.StaticKey{{Name: "ops", Role: auth.RoleOperator, Secret: "ops-secret"}})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	server.WithKeyring(keyring)
+	server.WithMetrics(metrics.NewRegistry())
+	routes := server.Routes()
+
+	call := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, req)
+		return res
+	}
+
+	call("/api/v1/runs/run-a", "ops-secret")
+	call("/api/v1/runs/run-b", "ops-secret")
+	call("/api/v1/runs", "")
+	call("/api/v1/no/such/route", "ops-secret")
+
+	// Metrics are scraped without credentials, and paths are reduced to the
+	// documented route so run IDs do not become label values.
+	res := call("/metrics", "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected metrics without credentials, got %d", res.Code)
+	}
+	body := res.Body.String()
+	for _, want := range []string{
+		`http_requests_total{code="404",method="GET",route="/api/v1/runs/{runID}"} 2`,
+		`http_requests_total{code="401",method="GET",route="/api/v1/runs"} 1`,
+		`http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %s", want)
+		}
+	}
+	if strings.Contains(body, "run-a") {
+		t.Error("expected run IDs to stay out of metric labels")
+	}
+}
+
+func TestVersionAndReportedBuilds(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	routes := NewServer(orch, logger).Routes()
+
+	call := func(method, path string, body any) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			reader = bytes.NewReader(raw)
+		}
+		res := httptest.NewRecorder()
+		routes.ServeHTTP(res, httptest.NewRequest(method, path, reader))
+		return res
+	}
+
+	res := call(http.MethodGet, "/version", nil)
+	var own buildinfo.Info
+	if err := json.Unmarshal(res.Body.Bytes(), &own); err != nil || res.Code != http.StatusOK || own.Service != "orchestrator" || own.Version == "" {
+		t.Fatalf("expected the orchestrator's build, got %d: %s", res.Code, res.Body.String())
+	}
+
+	v1 := map[string]any{"version": "v1.4.0", "commit": "abc123"}
+	if res := call(http.MethodPost, "/api/v1/learners", map[string]any{"id": "learner-a", "slots": 1, "build": v1}); res.Code != http.StatusOK {
+		t.Fatalf("register: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if learner, _ := store.GetLearner(context.Background(), "learner-a"); learner.Build == nil || learner.Build.Version != "v1.4.0" || learner.Build.Commit != "abc123" {
+		t.Fatalf("expected the registered build recorded, got %+v", learner.Build)
+	}
+	call(http.MethodPost, "/api/v1/learners/learner-a/heartbeat", map[string]any{"gpu_utilization": 0.5, "build": map[string]any{"version": "v1.5.0"}})
+	if learner, _ := store.GetLearner(context.Background(), "learner-a"); learner.Build == nil || learner.Build.Version != "v1.5.0" || learner.Load == nil {
+		t.Fatalf("expected the heartbeat to update the build and load, got %+v", learner)
+	}
+	call(http.MethodPost, "/api/v1/learners/learner-a/heartbeat", map[string]any{"gpu_utilization": 0.25})
+	if learner, _ := store.GetLearner(context.Background(), "learner-a"); learner.Build == nil || learner.Build.Version != "v1.5.0" {
+		t.Fatalf("expected a heartbeat without a build to keep the last one, got %+v", learner.Build)
+	}
+
+	call(http.MethodPost, "/api/v1/runs", map[string]any{"id": "run-build", "experiment_id": "exp-1", "version_id": "ver-1"})
+	for _, action := range []string{"provision", "start"} {
+		call(http.MethodPost, "/api/v1/runs/run-build/"+action, nil)
+	}
+	res = call(http.MethodPost, "/api/v1/runs/run-build/heartbeat", map[string]any{"run_id": "run-build", "status": "running", "step": 1, "build": v1})
+	if res.Code != http.StatusOK {
+		t.Fatalf("heartbeat: expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if run, _ := store.GetRun(context.Background(), "run-build"); run.LearnerBuild == nil || run.LearnerBuild.Version != "v1.4.0" {
+		t.Fatalf("expected the run to record its learner's build, got %+v", run.LearnerBuild)
+	}
+	if res := call(http.MethodPost, "/api/v1/runs/run-build/heartbeat", map[string]any{"run_id": "run-build", "status": "running", "step": 2, "build": map[string]any{"commit": "abc123"}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected a build without a version to be rejected, got %d", res.Code)
+	}
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+	server := NewServer(orch, logger)
+	keyring, err := auth.NewKeyring([]auth.StaticKey{{Name: "ops", Role: auth.RoleOperator, Secret: "ops-secret"}})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	server.WithKeyring(keyring)
+	server.WithReloader(func() ([]config.Change, error) { return nil, nil })
+	routes := server.Routes()
+
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Document(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	for path, item := range doc.Paths {
+		concrete := path
+		for _, param := range []string{"{runID}", "{experimentID}", "{versionID}", "{learnerID}", "{scheduleID}", "{templateID}", "{ruleID}", "{commandID}", "{name}", "{keyID}", "{env}"} {
+			concrete = strings.ReplaceAll(concrete, param, "x")
+		}
+		concrete = strings.ReplaceAll(concrete, "{version}", "1")
+		if strings.Contains(concrete, "{") {
+			t.Fatalf("unhandled path parameter in %s", path)
+		}
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			req := httptest.NewRequest(strings.ToUpper(method), "/api/v1"+concrete, nil)
+			req.Header.Set("Authorization", "Bearer ops-secret")
+			res := httptest.NewRecorder()
+			routes.ServeHTTP(res, req)
+			if res.Code == http.StatusNotFound && strings.HasPrefix(res.Body.String(), "404 page not found") {
+				t.Errorf("%s %s is documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
 }
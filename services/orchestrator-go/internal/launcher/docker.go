@@ -0,0 +1,303 @@
+package launcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+const (
+	// dockerAPIVersion is the oldest Engine API version whose endpoints we use.
+	dockerAPIVersion = "v1.41"
+
+	labelRunID = "cartridge.run_id"
+	labelRole  = "cartridge.role"
+)
+
+// DockerConfig describes the containers started for each run.
+type DockerConfig struct {
+	// Host is the Docker daemon address: unix:///path/to/docker.sock,
+	// tcp://host:port or http://host:port.
+	Host string
+	// LearnerImage is required; ActorImage is optional and starts ActorReplicas
+	// actor containers alongside the learner when set.
+	LearnerImage  string
+	ActorImage    string
+	ActorReplicas int
+	// Network is the Docker network the containers join; empty uses the default.
+	Network string
+	// OrchestratorURL is how containers reach this orchestrator.
+	OrchestratorURL string
+	// StopTimeout is how long containers get to exit after SIGTERM.
+	StopTimeout time.Duration
+	// RunToken issues the token learners present for a run; nil when run tokens
+	// are disabled.
+	RunToken func(runID string) string
+}
+
+// DockerLauncher runs each run as containers on a single Docker host, for
+// local development. Containers are labelled with the run ID so they can be
+// found again after an orchestrator restart.
+type DockerLauncher struct {
+	cfg     DockerConfig
+	client  *http.Client
+	baseURL string
+}
+
+// NewDockerLauncher creates a launcher talking to the Docker Engine API at cfg.Host.
+func NewDockerLauncher(cfg DockerConfig) (*DockerLauncher, error) {
+	if cfg.LearnerImage == "" {
+		return nil, fmt.Errorf("docker launcher: learner image is required")
+	}
+	u, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("docker launcher: invalid host %q: %w", cfg.Host, err)
+	}
+	transport := &http.Transport{}
+	var baseURL string
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		baseURL = "http://docker"
+	case "tcp", "http":
+		baseURL = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("docker launcher: unsupported host scheme %q", u.Scheme)
+	}
+	return &DockerLauncher{
+		cfg:     cfg,
+		client:  &http.Client{Transport: transport, Timeout: cfg.StopTimeout + 30*time.Second},
+		baseURL: baseURL + "/" + dockerAPIVersion,
+	}, nil
+}
+
+// dockerContainer is the subset of the container list response we read.
+type dockerContainer struct {
+	ID     string            `json:"Id"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// containerSpec is the body of a container create request.
+type containerSpec struct {
+	Image      string            `json:"Image"`
+	Env        []string          `json:"Env"`
+	Labels     map[string]string `json:"Labels"`
+	HostConfig hostConfig        `json:"HostConfig"`
+}
+
+type hostConfig struct {
+	NetworkMode    string          `json:"NetworkMode,omitempty"`
+	DeviceRequests []deviceRequest `json:"DeviceRequests,omitempty"`
+}
+
+type deviceRequest struct {
+	Count        int        `json:"Count"`
+	Capabilities [][]string `json:"Capabilities"`
+}
+
+// Launch creates and starts the learner container and any actor containers. If
+// any fails, those already created are removed so the next attempt starts clean.
+func (d *DockerLauncher) Launch(ctx context.Context, run types.Run) error {
+	existing, err := d.list(ctx, labelRunID+"="+run.ID)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+	env := d.env(run)
+	learner := d.spec(run, "learner", d.cfg.LearnerImage, env)
+	if gpus := types.RequirementsFromManifest(run.LaunchManifest).GPUs; gpus > 0 {
+		learner.HostConfig.DeviceRequests = []deviceRequest{{Count: gpus, Capabilities: [][]string{{"gpu"}}}}
+	}
+	// The learner starts first so actors find it when they connect.
+	names := []string{"learner"}
+	specs := []containerSpec{learner}
+	if d.cfg.ActorImage != "" {
+		for i := 0; i < d.cfg.ActorReplicas; i++ {
+			names = append(names, "actor-"+strconv.Itoa(i))
+			specs = append(specs, d.spec(run, "actor", d.cfg.ActorImage, env))
+		}
+	}
+	for i, spec := range specs {
+		if err := d.start(ctx, "cartridge-"+run.ID+"-"+names[i], spec); err != nil {
+			if stopErr := d.Stop(ctx, run.ID); stopErr != nil {
+				return fmt.Errorf("%w (cleanup: %v)", err, stopErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop stops and removes every container labelled with runID.
+func (d *DockerLauncher) Stop(ctx context.Context, runID string) error {
+	containers, err := d.list(ctx, labelRunID+"="+runID)
+	if err != nil {
+		return err
+	}
+	timeout := strconv.Itoa(int(d.cfg.StopTimeout.Seconds()))
+	for _, c := range containers {
+		// 304 means the container had already stopped.
+		if err := d.do(ctx, http.MethodPost, "/containers/"+c.ID+"/stop?t="+timeout, nil, nil, http.StatusNotModified, http.StatusNotFound); err != nil {
+			return err
+		}
+		if err := d.do(ctx, http.MethodDelete, "/containers/"+c.ID+"?force=true", nil, nil, http.StatusNotFound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Active returns the IDs of runs with at least one container, running or not.
+func (d *DockerLauncher) Active(ctx context.Context) ([]string, error) {
+	containers, err := d.list(ctx, labelRunID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var runIDs []string
+	for _, c := range containers {
+		if id := c.Labels[labelRunID]; id != "" && !seen[id] {
+			seen[id] = true
+			runIDs = append(runIDs, id)
+		}
+	}
+	sort.Strings(runIDs)
+	return runIDs, nil
+}
+
+// env builds the container environment: the run's identity and the
+// orchestrator address, then the manifest's "env" object. Manifest values
+// cannot replace the CARTRIDGE_ variables.
+func (d *DockerLauncher) env(run types.Run) []string {
+	vars := map[string]string{}
+	var manifest struct {
+		Env  map[string]any `json:"env"`
+		Game struct {
+			EnvID string `json:"env_id"`
+		} `json:"game"`
+	}
+	if len(run.LaunchManifest) > 0 {
+		_ = json.Unmarshal(run.LaunchManifest, &manifest)
+	}
+	for key, value := range manifest.Env {
+		switch v := value.(type) {
+		case string:
+			vars[key] = v
+		case nil:
+		default:
+			raw, _ := json.Marshal(v)
+			vars[key] = string(raw)
+		}
+	}
+	vars["CARTRIDGE_RUN_ID"] = run.ID
+	vars["CARTRIDGE_EXPERIMENT_ID"] = run.ExperimentID
+	vars["CARTRIDGE_VERSION_ID"] = run.VersionID
+	vars["CARTRIDGE_ORCHESTRATOR_URL"] = d.cfg.OrchestratorURL
+	vars["CARTRIDGE_LAUNCH_MANIFEST"] = string(run.LaunchManifest)
+	if d.cfg.RunToken != nil {
+		vars["CARTRIDGE_RUN_TOKEN"] = d.cfg.RunToken(run.ID)
+	}
+	// Actors read their settings from ACTOR_* variables.
+	vars["ACTOR_RUN_ID"] = run.ID
+	vars["ACTOR_EXPERIMENT_ID"] = run.ExperimentID
+	vars["ACTOR_ORCHESTRATOR_ADDR"] = d.cfg.OrchestratorURL
+	if manifest.Game.EnvID != "" {
+		vars["ACTOR_ENV_ID"] = manifest.Game.EnvID
+	}
+	env := make([]string, 0, len(vars))
+	for key, value := range vars {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+func (d *DockerLauncher) spec(run types.Run, role, image string, env []string) containerSpec {
+	return containerSpec{
+		Image:      image,
+		Env:        env,
+		Labels:     map[string]string{labelRunID: run.ID, labelRole: role},
+		HostConfig: hostConfig{NetworkMode: d.cfg.Network},
+	}
+}
+
+func (d *DockerLauncher) start(ctx context.Context, name string, spec containerSpec) error {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := d.do(ctx, http.MethodPost, "/containers/create?name="+url.QueryEscape(name), spec, &created); err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	if err := d.do(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil, nil, http.StatusNotModified); err != nil {
+		return fmt.Errorf("start %s: %w", name, err)
+	}
+	return nil
+}
+
+func (d *DockerLauncher) list(ctx context.Context, label string) ([]dockerContainer, error) {
+	filters, _ := json.Marshal(map[string][]string{"label": {label}})
+	var containers []dockerContainer
+	err := d.do(ctx, http.MethodGet, "/containers/json?all=true&filters="+url.QueryEscape(string(filters)), nil, &containers)
+	return containers, err
+}
+
+// do sends a Docker API request, decoding a JSON response into out when set.
+// Any 2xx status succeeds, as do the extra statuses listed in ok.
+func (d *DockerLauncher) do(ctx context.Context, method, path string, body, out any, ok ...int) error {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("docker %s %s: %w", method, path, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		if out != nil {
+			return json.NewDecoder(res.Body).Decode(out)
+		}
+		return nil
+	}
+	for _, status := range ok {
+		if res.StatusCode == status {
+			return nil
+		}
+	}
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if json.Unmarshal(raw, &apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(raw))
+	}
+	return fmt.Errorf("docker %s %s: %d %s", method, strings.SplitN(path, "?", 2)[0], res.StatusCode, apiErr.Message)
+}
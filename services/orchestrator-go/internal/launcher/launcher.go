@@ -0,0 +1,151 @@
+// Package launcher starts and stops the workloads behind a run. A Controller
+// reconciles run states against a Launcher: runs entering provisioning are
+// launched and moved to running, and the workloads of runs that are
+// terminating or have ended are stopped.
+package launcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// launcherActor is recorded as the initiator of launcher transitions.
+const launcherActor = "launcher"
+
+// Launcher runs the workloads for a run on some backend.
+type Launcher interface {
+	// Launch starts the run's workloads. It is a no-op for a run that has
+	// already been launched.
+	Launch(ctx context.Context, run types.Run) error
+	// Stop stops and removes the run's workloads, if any.
+	Stop(ctx context.Context, runID string) error
+	// Active lists the IDs of runs that currently have workloads.
+	Active(ctx context.Context) ([]string, error)
+}
+
+// Controller periodically reconciles run states with a Launcher.
+type Controller struct {
+	orch     *service.Orchestrator
+	launcher Launcher
+	interval time.Duration
+	logger   zerolog.Logger
+	onTick   func()
+}
+
+// NewController creates a controller that reconciles every interval.
+func NewController(orch *service.Orchestrator, launcher Launcher, interval time.Duration, logger zerolog.Logger) *Controller {
+	return &Controller{orch: orch, launcher: launcher, interval: interval, logger: logger}
+}
+
+// OnTick registers fn to be called when the loop starts and after every pass,
+// letting liveness probes confirm it is making progress.
+func (c *Controller) OnTick(fn func()) {
+	c.onTick = fn
+}
+
+// Start runs the reconcile loop until ctx is cancelled.
+func (c *Controller) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.logger.Info().Dur("interval", c.interval).Msg("Starting run launcher")
+	c.beat()
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info().Msg("Run launcher stopped")
+			return
+		case <-ticker.C:
+			c.tick(ctx)
+			c.beat()
+		}
+	}
+}
+
+func (c *Controller) beat() {
+	if c.onTick != nil {
+		c.onTick()
+	}
+}
+
+func (c *Controller) tick(ctx context.Context) {
+	c.launchProvisioning(ctx)
+	c.stopTerminating(ctx)
+	c.stopEnded(ctx)
+}
+
+// launchProvisioning starts the workloads of provisioning runs and marks them
+// running, or failed if the launch is rejected.
+func (c *Controller) launchProvisioning(ctx context.Context) {
+	runs, err := c.orch.ListRunsForHealthCheck(ctx, types.RunStateProvisioning)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to list provisioning runs")
+		return
+	}
+	for _, run := range runs {
+		if err := c.launcher.Launch(ctx, run); err != nil {
+			c.logger.Error().Err(err).Str("run_id", run.ID).Msg("Failed to launch run")
+			c.perform(ctx, run.ID, types.RunActionFail, fmt.Sprintf("launch failed: %v", err))
+			continue
+		}
+		c.logger.Info().Str("run_id", run.ID).Msg("Launched run")
+		c.perform(ctx, run.ID, types.RunActionStart, "workloads started")
+	}
+}
+
+// stopTerminating stops the workloads of terminating runs and completes their
+// termination once they are gone.
+func (c *Controller) stopTerminating(ctx context.Context) {
+	runs, err := c.orch.ListRunsForHealthCheck(ctx, types.RunStateTerminating)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to list terminating runs")
+		return
+	}
+	for _, run := range runs {
+		if err := c.launcher.Stop(ctx, run.ID); err != nil {
+			c.logger.Error().Err(err).Str("run_id", run.ID).Msg("Failed to stop run")
+			continue
+		}
+		c.logger.Info().Str("run_id", run.ID).Msg("Stopped run")
+		c.perform(ctx, run.ID, types.RunActionTerminate, "workloads stopped")
+	}
+}
+
+// stopEnded removes workloads left behind by runs that completed, failed or
+// were terminated directly, and by runs the registry no longer knows.
+func (c *Controller) stopEnded(ctx context.Context) {
+	active, err := c.launcher.Active(ctx)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to list launched runs")
+		return
+	}
+	for _, runID := range active {
+		run, err := c.orch.GetRun(ctx, runID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			c.logger.Error().Err(err).Str("run_id", runID).Msg("Failed to load launched run")
+			continue
+		}
+		if err == nil && !run.State.IsTerminal() {
+			continue
+		}
+		if err := c.launcher.Stop(ctx, runID); err != nil {
+			c.logger.Error().Err(err).Str("run_id", runID).Msg("Failed to stop ended run")
+			continue
+		}
+		c.logger.Info().Str("run_id", runID).Msg("Removed workloads of ended run")
+	}
+}
+
+func (c *Controller) perform(ctx context.Context, runID string, action types.RunAction, reason string) {
+	if _, err := c.orch.PerformAction(ctx, runID, action, launcherActor, reason); err != nil {
+		c.logger.Error().Err(err).Str("run_id", runID).Str("action", string(action)).Msg("Failed to record launcher transition")
+	}
+}
@@ -0,0 +1,186 @@
+package launcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+type fakeContainer struct {
+	name    string
+	spec    containerSpec
+	running bool
+}
+
+// fakeDocker implements the Docker Engine API endpoints the launcher uses.
+type fakeDocker struct {
+	mu         sync.Mutex
+	containers map[string]*fakeContainer
+	nextID     int
+	failName   string
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/"+dockerAPIVersion)
+	switch {
+	case r.Method == http.MethodGet && path == "/containers/json":
+		var filters map[string][]string
+		json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+		list := []dockerContainer{}
+		for id, c := range f.containers {
+			if matchesLabels(c.spec.Labels, filters["label"]) {
+				list = append(list, dockerContainer{ID: id, Labels: c.spec.Labels})
+			}
+		}
+		json.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodPost && path == "/containers/create":
+		name := r.URL.Query().Get("name")
+		if name == f.failName {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"No such image"}`)
+			return
+		}
+		var spec containerSpec
+		json.NewDecoder(r.Body).Decode(&spec)
+		f.nextID++
+		id := fmt.Sprintf("c%d", f.nextID)
+		f.containers[id] = &fakeContainer{name: name, spec: spec}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id":%q}`, id)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/start"):
+		f.containers[strings.Split(path, "/")[2]].running = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/stop"):
+		f.containers[strings.Split(path, "/")[2]].running = false
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(f.containers, strings.Split(path, "/")[2])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func matchesLabels(labels map[string]string, filters []string) bool {
+	for _, filter := range filters {
+		key, value, exact := strings.Cut(filter, "=")
+		got, ok := labels[key]
+		if !ok || exact && got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// byName returns the containers for runID keyed by container name.
+func (f *fakeDocker) byName(runID string) map[string]*fakeContainer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	named := make(map[string]*fakeContainer)
+	for _, c := range f.containers {
+		if c.spec.Labels[labelRunID] == runID {
+			named[c.name] = c
+		}
+	}
+	return named
+}
+
+func TestDockerLauncherReconcilesRuns(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(storage.NewMemoryStore(), events.NoopPublisher{}, logger)
+	docker := &fakeDocker{containers: map[string]*fakeContainer{}, failName: "cartridge-run-bad-actor-1"}
+	srv := httptest.NewServer(docker)
+	defer srv.Close()
+
+	dl, err := NewDockerLauncher(DockerConfig{
+		Host:            "tcp://" + srv.Listener.Addr().String(),
+		LearnerImage:    "cartridge/learner:dev",
+		ActorImage:      "cartridge/actor:dev",
+		ActorReplicas:   2,
+		Network:         "cartridge",
+		OrchestratorURL: "http://orchestrator:8080",
+		StopTimeout:     5 * time.Second,
+		RunToken:        func(runID string) string { return "token-" + runID },
+	})
+	if err != nil {
+		t.Fatalf("new launcher: %v", err)
+	}
+	controller := NewController(orch, dl, time.Second, *logger)
+
+	manifest := json.RawMessage(`{"env": {"SEED": "7", "LR": 0.1, "CARTRIDGE_RUN_ID": "spoofed"}, "game": {"env_id": "tictactoe"}, "resources": {"gpus": 1}}`)
+	for _, id := range []string{"run-ok", "run-bad"} {
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: id, ExperimentID: "exp-1", VersionID: "ver-1", LaunchManifest: manifest}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+		if _, err := orch.PerformAction(ctx, id, types.RunActionProvision, "tester", ""); err != nil {
+			t.Fatalf("provision %s: %v", id, err)
+		}
+	}
+	controller.tick(ctx)
+
+	run, _ := orch.GetRun(ctx, "run-ok")
+	if run.State != types.RunStateRunning {
+		t.Fatalf("expected launched run to be running, got %s", run.State)
+	}
+	containers := docker.byName("run-ok")
+	if len(containers) != 3 {
+		t.Fatalf("expected learner and two actors, got %d containers", len(containers))
+	}
+	learner := containers["cartridge-run-ok-learner"]
+	if learner == nil || !learner.running || learner.spec.Image != "cartridge/learner:dev" || learner.spec.HostConfig.NetworkMode != "cartridge" {
+		t.Fatalf("unexpected learner container %+v", learner)
+	}
+	if len(learner.spec.HostConfig.DeviceRequests) != 1 || learner.spec.HostConfig.DeviceRequests[0].Count != 1 {
+		t.Fatalf("expected the manifest's GPU request, got %+v", learner.spec.HostConfig.DeviceRequests)
+	}
+	env := strings.Join(learner.spec.Env, "\n")
+	for _, want := range []string{"SEED=7", "LR=0.1", "CARTRIDGE_RUN_ID=run-ok", "ACTOR_EXPERIMENT_ID=exp-1", "CARTRIDGE_RUN_TOKEN=token-run-ok", "CARTRIDGE_ORCHESTRATOR_URL=http://orchestrator:8080", "ACTOR_ENV_ID=tictactoe"} {
+		if !strings.Contains(env, want+"\n") && !strings.HasSuffix(env, want) {
+			t.Fatalf("expected %s in learner env, got:\n%s", want, env)
+		}
+	}
+	if actor := containers["cartridge-run-ok-actor-1"]; actor == nil || actor.spec.Labels[labelRole] != "actor" || len(actor.spec.HostConfig.DeviceRequests) != 0 {
+		t.Fatalf("unexpected actor container %+v", actor)
+	}
+
+	// A failed launch fails the run and removes the containers it created.
+	bad, _ := orch.GetRun(ctx, "run-bad")
+	if bad.State != types.RunStateFailed || !strings.Contains(bad.StatusMessage, "No such image") {
+		t.Fatalf("expected run-bad failed with the Docker error, got %s (%q)", bad.State, bad.StatusMessage)
+	}
+	if left := docker.byName("run-bad"); len(left) != 0 {
+		t.Fatalf("expected partial launch cleaned up, got %d containers", len(left))
+	}
+
+	if _, err := orch.PerformAction(ctx, "run-ok", types.RunActionTerminate, "tester", ""); err != nil {
+		t.Fatalf("terminate: %v", err)
+	}
+	docker.mu.Lock()
+	docker.containers["orphan"] = &fakeContainer{name: "cartridge-ghost-learner", spec: containerSpec{Labels: map[string]string{labelRunID: "ghost"}}, running: true}
+	docker.mu.Unlock()
+	controller.tick(ctx)
+
+	if run, _ := orch.GetRun(ctx, "run-ok"); run.State != types.RunStateTerminated {
+		t.Fatalf("expected terminate to complete once containers stopped, got %s", run.State)
+	}
+	if active, _ := dl.Active(ctx); len(active) != 0 {
+		t.Fatalf("expected every container removed, still have %v", active)
+	}
+}
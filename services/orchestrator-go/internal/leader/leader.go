@@ -0,0 +1,110 @@
+// Package leader elects a single replica to run the orchestrator's singleton
+// background loops. Replicas campaign for a shared Lock; the holder leads until
+// it can no longer confirm the lock, after which another replica takes over on
+// its next attempt.
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// releaseTimeout bounds how long a departing leader waits to give up the lock.
+const releaseTimeout = 5 * time.Second
+
+// Lock is a cluster-wide mutual exclusion primitive.
+type Lock interface {
+	// TryAcquire takes the lock if it is free, without blocking.
+	TryAcquire(ctx context.Context) (bool, error)
+	// Check returns an error once the lock is no longer held.
+	Check(ctx context.Context) error
+	// Release gives the lock up.
+	Release(ctx context.Context) error
+}
+
+// Elector campaigns for a Lock and runs work while it holds it.
+type Elector struct {
+	lock     Lock
+	interval time.Duration
+	identity string
+	logger   zerolog.Logger
+	leading  atomic.Bool
+}
+
+// NewElector creates an elector that retries acquisition, and confirms a held
+// lock, every interval. identity names this replica in logs.
+func NewElector(lock Lock, interval time.Duration, identity string, logger zerolog.Logger) *Elector {
+	return &Elector{lock: lock, interval: interval, identity: identity, logger: logger}
+}
+
+// IsLeader reports whether this replica currently holds the lock.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run campaigns until ctx is cancelled. Whenever this replica is elected, lead
+// runs with a context that is cancelled as soon as leadership is lost; Run waits
+// for lead to return before releasing the lock and campaigning again.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	e.logger.Info().Str("identity", e.identity).Dur("interval", e.interval).Msg("Campaigning for leadership")
+	for {
+		acquired, err := e.lock.TryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			e.logger.Error().Err(err).Str("identity", e.identity).Msg("Failed to acquire leader lock")
+		}
+		if acquired {
+			e.lead(ctx, ticker, lead)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) lead(ctx context.Context, ticker *time.Ticker, lead func(ctx context.Context)) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	e.leading.Store(true)
+	e.logger.Info().Str("identity", e.identity).Msg("Elected leader")
+	go func() {
+		defer close(done)
+		lead(leaderCtx)
+	}()
+
+watch:
+	for {
+		select {
+		case <-ctx.Done():
+			break watch
+		case <-done:
+			break watch
+		case <-ticker.C:
+			if err := e.lock.Check(ctx); err != nil {
+				if ctx.Err() == nil {
+					e.logger.Error().Err(err).Str("identity", e.identity).Msg("Lost leadership")
+				}
+				break watch
+			}
+		}
+	}
+	cancel()
+	<-done
+	e.leading.Store(false)
+
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancelRelease()
+	if err := e.lock.Release(releaseCtx); err != nil {
+		e.logger.Error().Err(err).Str("identity", e.identity).Msg("Failed to release leader lock")
+		return
+	}
+	e.logger.Info().Str("identity", e.identity).Msg("Stepped down as leader")
+}
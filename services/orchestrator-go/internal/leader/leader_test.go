@@ -0,0 +1,113 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// sharedLock is an in-process lock contended for by several fakeLock handles.
+type sharedLock struct {
+	mu    sync.Mutex
+	owner *fakeLock
+}
+
+type fakeLock struct {
+	shared *sharedLock
+}
+
+func (f *fakeLock) TryAcquire(context.Context) (bool, error) {
+	f.shared.mu.Lock()
+	defer f.shared.mu.Unlock()
+	if f.shared.owner == nil {
+		f.shared.owner = f
+	}
+	return f.shared.owner == f, nil
+}
+
+func (f *fakeLock) Check(context.Context) error {
+	f.shared.mu.Lock()
+	defer f.shared.mu.Unlock()
+	if f.shared.owner != f {
+		return errors.New("lock lost")
+	}
+	return nil
+}
+
+func (f *fakeLock) Release(context.Context) error {
+	f.shared.mu.Lock()
+	defer f.shared.mu.Unlock()
+	if f.shared.owner == f {
+		f.shared.owner = nil
+	}
+	return nil
+}
+
+// revoke simulates the lock backend dropping the holder, e.g. a dead session.
+func (s *sharedLock) revoke() {
+	s.mu.Lock()
+	s.owner = nil
+	s.mu.Unlock()
+}
+
+func TestElectorFailover(t *testing.T) {
+	shared := &sharedLock{}
+	logger := zerolog.New(io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leading := make(chan string, 4)
+	stopped := make(chan string, 4)
+	start := func(name string) *Elector {
+		elector := NewElector(&fakeLock{shared: shared}, 5*time.Millisecond, name, *logger)
+		go elector.Run(ctx, func(leaderCtx context.Context) {
+			leading <- name
+			<-leaderCtx.Done()
+			stopped <- name
+		})
+		return elector
+	}
+	electors := map[string]*Elector{"a": start("a")}
+	first := receive(t, leading)
+	electors["b"] = start("b")
+
+	// The standby must not lead while the first replica holds the lock.
+	select {
+	case name := <-leading:
+		t.Fatalf("%s elected while %s still leads", name, first)
+	case <-time.After(30 * time.Millisecond):
+	}
+	if !electors[first].IsLeader() || electors["b"].IsLeader() {
+		t.Fatalf("expected only %s to lead", first)
+	}
+
+	shared.revoke()
+	if name := receive(t, stopped); name != first {
+		t.Fatalf("expected %s to step down, got %s", first, name)
+	}
+	second := receive(t, leading)
+	if !electors[second].IsLeader() {
+		t.Fatalf("expected %s to report leadership", second)
+	}
+
+	cancel()
+	if name := receive(t, stopped); name != second {
+		t.Fatalf("expected %s to stop on shutdown, got %s", second, name)
+	}
+}
+
+func receive(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case name := <-ch:
+		return name
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for leadership change")
+		return ""
+	}
+}
@@ -0,0 +1,104 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+// errLockLost reports that the session holding the advisory lock went away.
+var errLockLost = errors.New("leader: advisory lock no longer held")
+
+// PostgresLock is a Lock backed by a session-level PostgreSQL advisory lock.
+// The lock lives on one dedicated connection, so it is dropped by the server as
+// soon as that session ends, letting a standby replica take over.
+type PostgresLock struct {
+	db  *sql.DB
+	key int64
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// NewPostgresLock creates a lock keyed by name; replicas sharing a name and a
+// database contend for the same lock.
+func NewPostgresLock(db *sql.DB, name string) *PostgresLock {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return &PostgresLock{db: db, key: int64(h.Sum64())}
+}
+
+// TryAcquire takes the advisory lock on a dedicated connection.
+func (p *PostgresLock) TryAcquire(ctx context.Context) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		return true, nil
+	}
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, p.key).Scan(&acquired); err != nil {
+		discard(conn)
+		return false, err
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+	p.conn = conn
+	return true, nil
+}
+
+// Check confirms the holding session is alive and still owns the lock.
+func (p *PostgresLock) Check(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		return errLockLost
+	}
+	// pg_locks splits a bigint advisory key into classid (high) and objid (low).
+	var held bool
+	err := p.conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND pid = pg_backend_pid() AND granted
+			  AND classid = $1 AND objid = $2 AND objsubid = 1)`,
+		int64(uint64(p.key)>>32), int64(uint32(p.key))).Scan(&held)
+	if err == nil && !held {
+		err = errLockLost
+	}
+	if err != nil {
+		discard(p.conn)
+		p.conn = nil
+	}
+	return err
+}
+
+// Release unlocks and returns the connection to the pool. If unlocking fails
+// the connection is discarded instead, which ends the session and the lock.
+func (p *PostgresLock) Release(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	conn := p.conn
+	p.conn = nil
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, p.key); err != nil {
+		discard(conn)
+		return err
+	}
+	return conn.Close()
+}
+
+// discard closes the underlying driver connection rather than pooling it.
+func discard(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
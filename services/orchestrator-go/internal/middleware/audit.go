@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/auth"
+	"github.com/cartridge/orchestrator/internal/correlation"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// anonymousActor identifies callers when authentication is disabled.
+const anonymousActor = "anonymous"
+
+// AuditRecorder persists audit entries.
+type AuditRecorder interface {
+	RecordAudit(ctx context.Context, entry types.AuditEntry) error
+}
+
+// Audit records every mutating request (anything but GET, HEAD and OPTIONS) once
+// the handler has responded. The payload digest covers the request body as read
+// by the handler, so large bodies are never buffered. It must run inside
+// Authenticate to attribute calls to a principal, and inside CorrelationID.
+func Audit(recorder AuditRecorder, logger zerolog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			body := &digestReader{ReadCloser: r.Body, hash: sha256.New()}
+			r.Body = body
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			entry := types.AuditEntry{
+				ID:            uuid.New().String(),
+				Actor:         anonymousActor,
+				Method:        r.Method,
+				Path:          r.URL.Path,
+				Query:         r.URL.RawQuery,
+				PayloadSHA256: hex.EncodeToString(body.hash.Sum(nil)),
+				PayloadBytes:  body.n,
+				Status:        rec.status,
+				RemoteAddr:    r.RemoteAddr,
+				CorrelationID: correlation.FromContext(r.Context()),
+				DurationMS:    time.Since(start).Milliseconds(),
+				At:            time.Now().UTC(),
+			}
+			if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+				entry.Actor = principal.Subject
+				entry.Role = string(principal.Role)
+				entry.AuthMethod = principal.Method
+			}
+			// The request context may already be cancelled; the record must still land.
+			if err := recorder.RecordAudit(context.WithoutCancel(r.Context()), entry); err != nil {
+				logger.Error().Err(err).
+					Str("actor", entry.Actor).
+					Str("method", entry.Method).
+					Str("path", entry.Path).
+					Msg("failed to record audit entry")
+			}
+		})
+	}
+}
+
+// digestReader hashes request body bytes as the handler consumes them.
+type digestReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	n    int64
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.ReadCloser.Read(p)
+	d.hash.Write(p[:n])
+	d.n += int64(n)
+	return n, err
+}
+
+// statusRecorder captures the response status written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cartridge/orchestrator/internal/apierror"
+	"github.com/cartridge/orchestrator/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// APIKeyHeader is accepted as an alternative to "Authorization: Bearer <key>".
+const APIKeyHeader = "X-API-Key"
+
+// RunTokenHeader carries the per-run token issued when a run is created.
+const RunTokenHeader = "X-Run-Token"
+
+// Authenticate rejects requests without valid credentials and stores the
+// resulting principal on the request context for RequireRole. Bearer tokens that
+// look like JWTs are checked against verifier when one is configured; everything
+// else is treated as an API key. Either argument may be nil.
+func Authenticate(keyring *auth.Keyring, verifier *auth.JWTVerifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticate(r, keyring, verifier)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="orchestrator"`)
+				writeJSONError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireRole rejects authenticated requests whose role does not include required,
+// and requests from run-scoped principals that target a different run.
+func RequireRole(required auth.Role) func(next http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFrom(r.Context())
+			if !ok || !principal.Role.Allows(required) {
+				writeJSONError(w, http.StatusForbidden, "role "+string(principal.Role)+" cannot access this endpoint")
+				return
+			}
+			if !principal.CanAccessRun(chi.URLParam(r, "runID")) {
+				writeJSONError(w, http.StatusForbidden, "credentials are scoped to run "+principal.RunID)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// RequireRunToken rejects requests for a run that do not present a token issued
+// for that run. It is independent of authentication: a learner holding valid
+// credentials still cannot act on a run it was not launched for.
+func RequireRunToken(signer *auth.RunTokenSigner) func(next http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			runID := chi.URLParam(r, "runID")
+			if err := signer.Verify(runID, r.Header.Get(RunTokenHeader)); err != nil {
+				writeJSONError(w, http.StatusForbidden, "missing or invalid "+RunTokenHeader+" for run "+runID)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// RunTokens returns every token presented on r. Batch requests spanning several
+// runs repeat the header, or comma-separate its value, once per run.
+func RunTokens(r *http.Request) []string {
+	var tokens []string
+	for _, value := range r.Header.Values(RunTokenHeader) {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+func authenticate(r *http.Request, keyring *auth.Keyring, verifier *auth.JWTVerifier) (auth.Principal, error) {
+	token := apiKeyFromRequest(r)
+	if verifier != nil && strings.Count(token, ".") == 2 {
+		return verifier.Verify(r.Context(), token)
+	}
+	if keyring == nil {
+		return auth.Principal{}, auth.ErrInvalidKey
+	}
+	key, err := keyring.Authenticate(token)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	return key.Principal(), nil
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return r.Header.Get(APIKeyHeader)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	apierror.Write(w, apierror.ForStatus(status), message, nil)
+}
@@ -1,11 +1,17 @@
 package middleware
 
 import (
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/auth"
+	"github.com/cartridge/orchestrator/internal/correlation"
 )
 
 // LogEntry interface matches chi's LogEntry
@@ -19,11 +25,15 @@ type LogFormatter interface {
 	NewLogEntry(r *http.Request) LogEntry
 }
 
-// RequestLogger creates a zerolog-based request logger middleware
+// RequestLogger creates a zerolog-based request logger middleware. Requests that
+// did not pass through CorrelationID get an ID on their context here.
 func RequestLogger(logger zerolog.Logger) func(next http.Handler) http.Handler {
 	formatter := &RequestLoggerFormatter{logger}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if correlation.FromContext(r.Context()) == "" {
+				r = r.WithContext(correlation.WithID(r.Context(), requestCorrelationID(r)))
+			}
 			entry := formatter.NewLogEntry(r)
 			ww := &responseWriter{ResponseWriter: w, entry: entry, start: time.Now()}
 			next.ServeHTTP(ww, r)
@@ -38,14 +48,11 @@ type RequestLoggerFormatter struct {
 }
 
 func (l *RequestLoggerFormatter) NewLogEntry(r *http.Request) LogEntry {
-	correlationID := r.Header.Get("X-Correlation-ID")
+	correlationID := correlation.FromContext(r.Context())
 	if correlationID == "" {
-		correlationID = uuid.New().String()
+		correlationID = requestCorrelationID(r)
 	}
 
-	// Add correlation ID to request context for downstream handlers
-	r.Header.Set("X-Correlation-ID", correlationID)
-
 	entry := &RequestLoggerEntry{
 		Logger:        l.Logger,
 		CorrelationID: correlationID,
@@ -127,30 +134,116 @@ func (w *responseWriter) Write(data []byte) (int, error) {
 	return n, err
 }
 
-// CorrelationID adds a correlation ID to requests if not present
+// CorrelationID stores the caller's X-Correlation-ID, or a new one, on the request
+// context (see correlation.FromContext) and echoes it in the response headers.
 func CorrelationID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		correlationID := r.Header.Get("X-Correlation-ID")
-		if correlationID == "" {
-			correlationID = uuid.New().String()
-			r.Header.Set("X-Correlation-ID", correlationID)
-		}
+		correlationID := requestCorrelationID(r)
+		w.Header().Set(correlation.Header, correlationID)
+		next.ServeHTTP(w, r.WithContext(correlation.WithID(r.Context(), correlationID)))
+	})
+}
+
+// maxCorrelationIDLength bounds caller-supplied IDs, which end up in logs and events.
+const maxCorrelationIDLength = 128
+
+// requestCorrelationID returns the caller's correlation ID, or a new one when it
+// is missing or too long.
+func requestCorrelationID(r *http.Request) string {
+	if id := r.Header.Get(correlation.Header); id != "" && len(id) <= maxCorrelationIDLength {
+		return id
+	}
+	return correlation.NewID()
+}
+
+// maxRateLimitClients bounds the buckets a RateLimiter tracks; past it, buckets
+// that have refilled are dropped since they are indistinguishable from new ones.
+const maxRateLimitClients = 10000
+
+// RateLimiter limits each client to a sustained request rate with a burst
+// allowance, answering 429 with Retry-After once a client's bucket is empty.
+// Clients are keyed by their authenticated subject, or their remote address
+// when unauthenticated. The limits can be changed while serving.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// NewRateLimiter allows each client requestsPerSecond requests per second and
+// bursts of up to burst; a zero rate disables limiting.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{rate: requestsPerSecond, burst: burst, buckets: make(map[string]*tokenBucket), now: time.Now}
+}
 
-		// Add to response headers
-		w.Header().Set("X-Correlation-ID", correlationID)
+// SetLimit changes the limits. Clients keep their current allowance, capped at
+// the new burst.
+func (l *RateLimiter) SetLimit(requestsPerSecond float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate, l.burst = requestsPerSecond, burst
+}
 
+// Handler enforces the limits in front of next.
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait := l.take(rateLimitKey(r)); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-// RateLimiter creates a simple rate limiting middleware
-func RateLimiter(requestsPerSecond int) func(next http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Simple rate limiting implementation
-			// In production, would use a more sophisticated rate limiter
-			// like golang.org/x/time/rate or redis-based limiting
-			next.ServeHTTP(w, r)
-		})
+// take spends a token from key's bucket, returning zero, or how long until one
+// is available when the bucket is empty.
+func (l *RateLimiter) take(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitClients {
+			l.evictFull(now)
+		}
+		bucket = &tokenBucket{tokens: float64(l.burst), at: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(float64(l.burst), bucket.tokens+now.Sub(bucket.at).Seconds()*l.rate)
+	bucket.at = now
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return 0
+}
+
+func (l *RateLimiter) evictFull(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.at).Seconds()*l.rate >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func rateLimitKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		return "subject:" + principal.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
 	}
+	return "addr:" + host
 }
\ No newline at end of file
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/cartridge/orchestrator/internal/apierror"
+	"github.com/cartridge/orchestrator/internal/openapi"
+)
+
+// maxValidatedBody bounds how much of a request body is buffered for schema
+// validation. Larger bodies skip validation and are left to the handler's limits.
+const maxValidatedBody = 1 << 20
+
+// ValidateRequest rejects JSON request bodies that do not match the OpenAPI
+// document with a 400 listing every violation:
+//
+//	{"code": "validation_failed", "message": "request body does not match the API schema", "details": [{"field": "slots", "message": "must be at least 1"}]}
+func ValidateRequest(validator *openapi.Validator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !validator.HasBody(r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			buffered, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "failed to read request body")
+				return
+			}
+			r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(buffered), r.Body), Closer: r.Body}
+			if len(buffered) > maxValidatedBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if errs := validator.ValidateBody(r.Method, r.URL.Path, buffered); len(errs) > 0 {
+				apierror.Write(w, apierror.CodeValidation, "request body does not match the API schema", errs)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
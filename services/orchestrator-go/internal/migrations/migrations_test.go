@@ -0,0 +1,21 @@
+package migrations
+
+import "testing"
+
+func TestLoadOrdersEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatalf("expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.SQL == "" {
+			t.Fatalf("migration %s is empty", m.Name)
+		}
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Fatalf("migrations out of order: %s after %s", m.Name, migrations[i-1].Name)
+		}
+	}
+}
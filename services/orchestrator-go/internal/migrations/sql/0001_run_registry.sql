@@ -0,0 +1,54 @@
+-- Core run registry tables. IDs are caller-supplied strings to match the API.
+CREATE TABLE IF NOT EXISTS runs (
+  id text PRIMARY KEY,
+  experiment_id text NOT NULL,
+  version_id text NOT NULL,
+  state text NOT NULL DEFAULT 'queued',
+  status_message text NOT NULL DEFAULT '',
+  priority integer NOT NULL DEFAULT 0,
+  launch_manifest jsonb,
+  overrides jsonb,
+  last_heartbeat_at timestamptz,
+  runtime_status text NOT NULL DEFAULT 'running',
+  health_status text NOT NULL DEFAULT 'healthy',
+  current_step bigint NOT NULL DEFAULT 0,
+  samples_per_sec double precision NOT NULL DEFAULT 0,
+  loss double precision NOT NULL DEFAULT 0,
+  checkpoint_version bigint NOT NULL DEFAULT 0,
+  started_at timestamptz,
+  ended_at timestamptz,
+  created_by text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS runs_experiment_created_idx ON runs (experiment_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS runs_state_idx ON runs (state);
+CREATE INDEX IF NOT EXISTS runs_active_heartbeat_idx ON runs (last_heartbeat_at)
+  WHERE state IN ('running', 'paused');
+
+CREATE TABLE IF NOT EXISTS run_transitions (
+  id bigserial PRIMARY KEY,
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  from_state text NOT NULL DEFAULT '',
+  to_state text NOT NULL,
+  changed_by text NOT NULL DEFAULT '',
+  reason text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS run_transitions_run_idx ON run_transitions (run_id, created_at);
+
+CREATE TABLE IF NOT EXISTS run_commands (
+  id text NOT NULL,
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  type text NOT NULL,
+  payload jsonb NOT NULL DEFAULT '{}'::jsonb,
+  actor_type text NOT NULL,
+  actor_id text NOT NULL,
+  issued_at timestamptz NOT NULL DEFAULT now(),
+  delivered_at timestamptz,
+  acknowledged_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (run_id, id)
+);
+CREATE INDEX IF NOT EXISTS run_commands_pending_idx ON run_commands (run_id, issued_at)
+  WHERE delivered_at IS NULL;
@@ -0,0 +1,6 @@
+-- Commands may carry a delivery deadline; the sweeper stamps expired_at once it passes.
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS expired_at timestamptz;
+DROP INDEX IF EXISTS run_commands_pending_idx;
+CREATE INDEX IF NOT EXISTS run_commands_pending_idx ON run_commands (run_id, issued_at)
+  WHERE delivered_at IS NULL AND expired_at IS NULL;
@@ -0,0 +1,5 @@
+-- Learner-reported execution outcome for a command.
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS result_status text;
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS result_message text NOT NULL DEFAULT '';
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS result_applied jsonb;
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS result_reported_at timestamptz;
@@ -0,0 +1,5 @@
+-- Unacknowledged deliveries are retried; commands that exhaust their attempts are dead-lettered.
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS delivery_attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS dead_lettered_at timestamptz;
+CREATE INDEX IF NOT EXISTS run_commands_unacked_idx ON run_commands (delivered_at)
+  WHERE acknowledged_at IS NULL AND dead_lettered_at IS NULL;
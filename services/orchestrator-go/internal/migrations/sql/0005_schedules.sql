@@ -0,0 +1,23 @@
+-- Cron schedules that launch runs from a template manifest.
+CREATE TABLE IF NOT EXISTS schedules (
+  id text PRIMARY KEY,
+  name text NOT NULL DEFAULT '',
+  cron text NOT NULL,
+  experiment_id text NOT NULL,
+  version_id text NOT NULL,
+  launch_manifest jsonb,
+  overrides jsonb,
+  priority integer NOT NULL DEFAULT 0,
+  overlap_policy text NOT NULL DEFAULT 'skip',
+  enabled boolean NOT NULL DEFAULT true,
+  next_run_at timestamptz,
+  last_run_at timestamptz,
+  last_run_id text,
+  created_by text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS schedules_due_idx ON schedules (next_run_at) WHERE enabled;
+
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS schedule_id text REFERENCES schedules(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS runs_schedule_idx ON runs (schedule_id, created_at DESC) WHERE schedule_id IS NOT NULL;
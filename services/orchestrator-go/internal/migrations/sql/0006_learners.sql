@@ -0,0 +1,14 @@
+-- Registered learner processes and the runs the dispatcher assigned to them.
+CREATE TABLE IF NOT EXISTS learners (
+  id text PRIMARY KEY,
+  name text NOT NULL DEFAULT '',
+  slots integer NOT NULL CHECK (slots > 0),
+  registered_at timestamptz NOT NULL DEFAULT now(),
+  last_seen_at timestamptz NOT NULL DEFAULT now()
+);
+
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS learner_id text;
+CREATE INDEX IF NOT EXISTS runs_learner_active_idx ON runs (learner_id)
+  WHERE state IN ('provisioning', 'running', 'paused', 'terminating');
+CREATE INDEX IF NOT EXISTS runs_queue_idx ON runs (priority DESC, created_at)
+  WHERE state = 'queued';
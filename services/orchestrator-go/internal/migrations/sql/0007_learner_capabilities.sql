@@ -0,0 +1,5 @@
+-- Learner capabilities used for placement and the load they last reported.
+ALTER TABLE learners ADD COLUMN IF NOT EXISTS gpus integer NOT NULL DEFAULT 0;
+ALTER TABLE learners ADD COLUMN IF NOT EXISTS max_batch_size integer NOT NULL DEFAULT 0;
+ALTER TABLE learners ADD COLUMN IF NOT EXISTS envs text[] NOT NULL DEFAULT '{}';
+ALTER TABLE learners ADD COLUMN IF NOT EXISTS load jsonb;
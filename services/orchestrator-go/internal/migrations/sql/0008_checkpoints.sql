@@ -0,0 +1,14 @@
+-- Model checkpoints registered per run; at most one is promoted per run.
+CREATE TABLE IF NOT EXISTS run_checkpoints (
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  version bigint NOT NULL CHECK (version > 0),
+  step bigint NOT NULL DEFAULT 0,
+  metrics jsonb NOT NULL DEFAULT '{}'::jsonb,
+  storage_uri text NOT NULL,
+  promoted boolean NOT NULL DEFAULT false,
+  promoted_at timestamptz,
+  promoted_by text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (run_id, version)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS run_checkpoints_promoted_idx ON run_checkpoints (run_id) WHERE promoted;
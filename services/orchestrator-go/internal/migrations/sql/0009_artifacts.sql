@@ -0,0 +1,14 @@
+-- Metadata for artifacts uploaded to the object store through pre-signed URLs.
+CREATE TABLE IF NOT EXISTS run_artifacts (
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  kind text NOT NULL CHECK (kind IN ('checkpoint', 'log', 'other')),
+  object_key text NOT NULL,
+  uri text NOT NULL,
+  content_type text NOT NULL DEFAULT '',
+  size_bytes bigint NOT NULL DEFAULT 0,
+  sha256 text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  uploaded_at timestamptz,
+  PRIMARY KEY (run_id, name)
+);
@@ -0,0 +1,11 @@
+-- Heartbeat telemetry history backing the metrics timeseries endpoint. Rows past
+-- METRICS_RETENTION are deleted by the orchestrator.
+CREATE TABLE IF NOT EXISTS run_metrics (
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  recorded_at timestamptz NOT NULL,
+  step bigint NOT NULL,
+  loss double precision NOT NULL,
+  samples_per_sec double precision NOT NULL
+);
+CREATE INDEX IF NOT EXISTS run_metrics_run_time_idx ON run_metrics (run_id, recorded_at);
+CREATE INDEX IF NOT EXISTS run_metrics_recorded_at_idx ON run_metrics (recorded_at);
@@ -0,0 +1,15 @@
+-- Scores reported by evaluation jobs against registered checkpoints.
+CREATE TABLE IF NOT EXISTS evaluations (
+  run_id text NOT NULL,
+  id text NOT NULL,
+  checkpoint_version bigint NOT NULL,
+  suite text NOT NULL,
+  scores jsonb NOT NULL,
+  episodes integer NOT NULL DEFAULT 0 CHECK (episodes >= 0),
+  evaluator text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (run_id, id),
+  FOREIGN KEY (run_id, checkpoint_version) REFERENCES run_checkpoints(run_id, version) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS evaluations_checkpoint_idx ON evaluations (run_id, checkpoint_version);
+CREATE INDEX IF NOT EXISTS evaluations_suite_idx ON evaluations (suite);
@@ -0,0 +1,19 @@
+-- Append-only record of every mutating API call.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id text PRIMARY KEY,
+  actor text NOT NULL,
+  role text NOT NULL DEFAULT '',
+  auth_method text NOT NULL DEFAULT '',
+  method text NOT NULL,
+  path text NOT NULL,
+  query text NOT NULL DEFAULT '',
+  payload_sha256 text NOT NULL,
+  payload_bytes bigint NOT NULL DEFAULT 0,
+  status integer NOT NULL,
+  remote_addr text NOT NULL DEFAULT '',
+  correlation_id text NOT NULL DEFAULT '',
+  duration_ms bigint NOT NULL DEFAULT 0,
+  at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS audit_log_at_idx ON audit_log (at, id);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor, at);
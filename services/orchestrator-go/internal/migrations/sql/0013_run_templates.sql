@@ -0,0 +1,13 @@
+-- Reusable launch manifests with ${name} placeholders.
+CREATE TABLE IF NOT EXISTS run_templates (
+  id text PRIMARY KEY,
+  name text NOT NULL DEFAULT '',
+  description text NOT NULL DEFAULT '',
+  manifest jsonb NOT NULL,
+  parameters jsonb NOT NULL DEFAULT '[]',
+  created_by text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS template_id text REFERENCES run_templates(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS runs_template_idx ON runs (template_id, created_at DESC) WHERE template_id IS NOT NULL;
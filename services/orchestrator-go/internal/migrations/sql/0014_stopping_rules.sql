@@ -0,0 +1,22 @@
+-- Early-stopping rules evaluated on each heartbeat, with their running state.
+CREATE TABLE IF NOT EXISTS stopping_rules (
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  id text NOT NULL,
+  kind text NOT NULL,
+  action text NOT NULL,
+  heartbeats integer NOT NULL DEFAULT 0,
+  min_delta double precision NOT NULL DEFAULT 0,
+  threshold double precision NOT NULL DEFAULT 0,
+  window_seconds bigint NOT NULL DEFAULT 0,
+  enabled boolean NOT NULL DEFAULT true,
+  best_loss double precision,
+  streak integer NOT NULL DEFAULT 0,
+  below_since timestamptz,
+  triggered_at timestamptz,
+  reason text NOT NULL DEFAULT '',
+  command_id text NOT NULL DEFAULT '',
+  created_by text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (run_id, id)
+);
+CREATE INDEX IF NOT EXISTS stopping_rules_active_idx ON stopping_rules (run_id) WHERE enabled AND triggered_at IS NULL;
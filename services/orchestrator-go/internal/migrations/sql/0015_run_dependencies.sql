@@ -0,0 +1,3 @@
+-- Prerequisite runs that must complete before a run is dispatched.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS depends_on text[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS runs_depends_on_idx ON runs USING gin (depends_on);
@@ -0,0 +1,3 @@
+-- Free-form key/value run labels, indexed for containment (@>) label searches.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS labels jsonb NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS runs_labels_idx ON runs USING gin (labels jsonb_path_ops);
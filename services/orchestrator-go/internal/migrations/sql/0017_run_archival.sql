@@ -0,0 +1,3 @@
+-- Soft-deleted runs stay in the registry but are hidden from default listings.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS archived_at timestamptz;
+CREATE INDEX IF NOT EXISTS runs_archived_at_idx ON runs (archived_at) WHERE archived_at IS NOT NULL;
@@ -0,0 +1,9 @@
+-- JSON Schemas that launch manifests must satisfy, one per environment (game.env_id).
+CREATE TABLE IF NOT EXISTS manifest_schemas (
+  env text PRIMARY KEY,
+  schema jsonb NOT NULL,
+  description text NOT NULL DEFAULT '',
+  revision integer NOT NULL DEFAULT 1,
+  updated_by text NOT NULL DEFAULT '',
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
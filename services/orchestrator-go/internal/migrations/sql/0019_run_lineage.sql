@@ -0,0 +1,3 @@
+-- Runs created by cloning another run record their source for lineage.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS cloned_from text;
+CREATE INDEX IF NOT EXISTS runs_cloned_from_idx ON runs (cloned_from) WHERE cloned_from IS NOT NULL;
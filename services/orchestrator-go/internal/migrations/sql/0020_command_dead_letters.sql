@@ -0,0 +1,4 @@
+-- Dead-lettered and expired commands wait for an operator to requeue or discard them.
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS discarded_at timestamptz;
+CREATE INDEX IF NOT EXISTS run_commands_dead_letters_idx ON run_commands (issued_at, id)
+  WHERE discarded_at IS NULL AND (dead_lettered_at IS NOT NULL OR expired_at IS NOT NULL);
@@ -0,0 +1,2 @@
+-- Runs paused by priority preemption record the run that displaced them.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS preempted_by text;
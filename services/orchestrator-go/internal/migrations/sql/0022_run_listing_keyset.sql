@@ -0,0 +1,4 @@
+-- Keyset pagination for run listings orders by (created_at, id) with bytewise
+-- ID comparison so cursors match the memory store.
+CREATE INDEX IF NOT EXISTS runs_created_id_idx ON runs (created_at, id COLLATE "C");
+CREATE INDEX IF NOT EXISTS runs_state_created_id_idx ON runs (state, created_at, id COLLATE "C");
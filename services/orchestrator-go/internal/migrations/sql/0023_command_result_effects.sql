@@ -0,0 +1,4 @@
+-- Values a command replaced and the run step at which its result was reported,
+-- for the hyperparameter timeline.
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS result_previous jsonb;
+ALTER TABLE run_commands ADD COLUMN IF NOT EXISTS result_step bigint;
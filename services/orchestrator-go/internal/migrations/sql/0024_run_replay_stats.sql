@@ -0,0 +1,2 @@
+-- Replay buffer activity last polled from the replay service for each run.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS replay jsonb;
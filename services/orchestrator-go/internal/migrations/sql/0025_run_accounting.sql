@@ -0,0 +1,2 @@
+-- Samples trained so far, integrated from heartbeat throughput, for run accounting.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS samples_processed bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- Per-run alert thresholds evaluated on each heartbeat, with their firing state.
+CREATE TABLE IF NOT EXISTS alert_rules (
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  id text NOT NULL,
+  kind text NOT NULL,
+  severity text NOT NULL,
+  threshold double precision NOT NULL,
+  enabled boolean NOT NULL DEFAULT true,
+  last_loss double precision,
+  firing_since timestamptz,
+  breaches integer NOT NULL DEFAULT 0,
+  last_message text NOT NULL DEFAULT '',
+  created_by text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (run_id, id)
+);
+CREATE INDEX IF NOT EXISTS alert_rules_enabled_idx ON alert_rules (run_id) WHERE enabled;
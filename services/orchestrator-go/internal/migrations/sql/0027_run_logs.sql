@@ -0,0 +1,12 @@
+-- Structured log lines pushed by learners and actors. seq increases per run;
+-- the orchestrator keeps only the latest lines of each run.
+CREATE TABLE IF NOT EXISTS run_logs (
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  seq bigint NOT NULL,
+  logged_at timestamptz NOT NULL,
+  level text NOT NULL,
+  source text NOT NULL DEFAULT '',
+  message text NOT NULL,
+  fields jsonb NOT NULL DEFAULT '{}'::jsonb,
+  PRIMARY KEY (run_id, seq)
+);
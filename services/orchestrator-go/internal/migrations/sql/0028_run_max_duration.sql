@@ -0,0 +1,4 @@
+-- Max-duration limits enforced by the run watchdog.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS max_duration_seconds bigint NOT NULL DEFAULT 0;
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS max_duration_action text NOT NULL DEFAULT '';
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS max_duration_exceeded_at timestamptz;
@@ -0,0 +1,11 @@
+-- Every published event, kept so consumers can backfill what they missed while
+-- disconnected from the broker. The orchestrator keeps only the latest events
+-- of each run.
+CREATE TABLE IF NOT EXISTS run_events (
+  id bigserial PRIMARY KEY,
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  type text NOT NULL,
+  published_at timestamptz NOT NULL,
+  payload jsonb NOT NULL
+);
+CREATE INDEX IF NOT EXISTS run_events_run_id_idx ON run_events (run_id, id);
@@ -0,0 +1,2 @@
+-- Inter-heartbeat gap statistics that baseline the health monitor's thresholds.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS heartbeat_gaps jsonb;
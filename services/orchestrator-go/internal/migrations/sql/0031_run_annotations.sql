@@ -0,0 +1,13 @@
+-- Operator notes pinned to points on a run's timeline.
+CREATE TABLE IF NOT EXISTS run_annotations (
+  run_id text NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+  id text NOT NULL,
+  annotated_at timestamptz NOT NULL,
+  step bigint NOT NULL DEFAULT 0,
+  text text NOT NULL,
+  tags text[] NOT NULL DEFAULT '{}',
+  author text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (run_id, id)
+);
+CREATE INDEX IF NOT EXISTS run_annotations_run_at_idx ON run_annotations (run_id, annotated_at);
@@ -0,0 +1,2 @@
+-- Build the run's learner last reported on a heartbeat.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS learner_build jsonb;
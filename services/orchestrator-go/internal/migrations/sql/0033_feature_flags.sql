@@ -0,0 +1,12 @@
+-- Feature flags gating actor and replay behaviours, with per-experiment and
+-- per-run overrides (ID -> enabled).
+CREATE TABLE IF NOT EXISTS feature_flags (
+  name text PRIMARY KEY,
+  description text NOT NULL DEFAULT '',
+  enabled boolean NOT NULL DEFAULT false,
+  experiments jsonb NOT NULL DEFAULT '{}',
+  runs jsonb NOT NULL DEFAULT '{}',
+  revision integer NOT NULL DEFAULT 1,
+  updated_by text NOT NULL DEFAULT '',
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,472 @@
+// Package openapi embeds the orchestrator's OpenAPI 3 document and validates
+// request bodies against it. The validator understands the JSON Schema subset
+// the document uses: type, nullable, enum, required, properties,
+// additionalProperties, items, minimum/maximum, minLength/maxLength,
+// minItems/maxItems, pattern, format date-time and local $refs.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Document returns the raw OpenAPI document.
+func Document() []byte {
+	return document
+}
+
+// FieldError describes one schema violation. Field is a dot path into the body
+// ("capabilities.gpus", "[2].run_id"); it is empty for the body as a whole.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Schema is the subset of an OpenAPI schema object the validator enforces.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Pattern              string             `json:"pattern"`
+
+	additional *Schema // compiled from AdditionalProperties when it is a schema
+	closed     bool    // additionalProperties: false
+	pattern    *regexp.Regexp
+}
+
+type operation struct {
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type route struct {
+	template string
+	segments []string // "{param}" segments match any value
+	literals int
+	bodies   map[string]*body // by HTTP method
+}
+
+type body struct {
+	schema   *Schema
+	required bool
+}
+
+// Validator checks request bodies against the document.
+type Validator struct {
+	basePath  string
+	routes    []route
+	schemas   map[string]*Schema
+	refPrefix string // $refs name schemas as refPrefix + key in schemas
+}
+
+// NewValidator compiles the embedded document.
+func NewValidator() (*Validator, error) {
+	var doc struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]*Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: parse document: %w", err)
+	}
+	v := &Validator{schemas: doc.Components.Schemas, refPrefix: "#/components/schemas/"}
+	if len(doc.Servers) > 0 {
+		v.basePath = strings.TrimSuffix(doc.Servers[0].URL, "/")
+	}
+	for name, schema := range v.schemas {
+		if err := v.compile(schema); err != nil {
+			return nil, fmt.Errorf("openapi: schema %s: %w", name, err)
+		}
+	}
+	for path, item := range doc.Paths {
+		rt := route{template: path, segments: strings.Split(strings.Trim(path, "/"), "/"), bodies: make(map[string]*body)}
+		for _, segment := range rt.segments {
+			if !strings.HasPrefix(segment, "{") {
+				rt.literals++
+			}
+		}
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", method, path, err)
+			}
+			if op.RequestBody == nil {
+				continue
+			}
+			media, ok := op.RequestBody.Content["application/json"]
+			if !ok || media.Schema == nil {
+				continue
+			}
+			if err := v.compile(media.Schema); err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", method, path, err)
+			}
+			rt.bodies[strings.ToUpper(method)] = &body{schema: media.Schema, required: op.RequestBody.Required}
+		}
+		v.routes = append(v.routes, rt)
+	}
+	// Prefer the most specific template, mirroring the router's static-first order.
+	sort.Slice(v.routes, func(i, j int) bool { return v.routes[i].literals > v.routes[j].literals })
+	return v, nil
+}
+
+// MustNewValidator is NewValidator for the embedded document, which the package
+// tests keep valid; it panics on failure.
+func MustNewValidator() *Validator {
+	v, err := NewValidator()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// compile resolves references and prepares patterns and additionalProperties.
+func (v *Validator) compile(s *Schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		if _, err := v.resolve(s); err != nil {
+			return err
+		}
+		return nil
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	switch raw := bytes.TrimSpace(s.AdditionalProperties); {
+	case len(raw) == 0, bytes.Equal(raw, []byte("true")):
+	case bytes.Equal(raw, []byte("false")):
+		s.closed = true
+	default:
+		if s.additional == nil {
+			s.additional = &Schema{}
+			if err := json.Unmarshal(raw, s.additional); err != nil {
+				return err
+			}
+		}
+		if err := v.compile(s.additional); err != nil {
+			return err
+		}
+	}
+	for _, prop := range s.Properties {
+		if err := v.compile(prop); err != nil {
+			return err
+		}
+	}
+	return v.compile(s.Items)
+}
+
+func (v *Validator) resolve(s *Schema) (*Schema, error) {
+	for depth := 0; s.Ref != ""; depth++ {
+		name, ok := strings.CutPrefix(s.Ref, v.refPrefix)
+		target, found := v.schemas[name]
+		if !ok || !found || depth > 16 {
+			return nil, fmt.Errorf("unresolvable $ref %q", s.Ref)
+		}
+		s = target
+	}
+	return s, nil
+}
+
+// HasBody reports whether the document describes a JSON body for the request.
+func (v *Validator) HasBody(method, path string) bool {
+	_, ok := v.lookup(method, path)
+	return ok
+}
+
+// ValidateBody checks raw against the body schema of method and path. Requests
+// the document does not describe pass unchecked.
+func (v *Validator) ValidateBody(method, path string, raw []byte) []FieldError {
+	b, ok := v.lookup(method, path)
+	if !ok {
+		return nil
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if b.required {
+			return []FieldError{{Message: "request body is required"}}
+		}
+		return nil
+	}
+	return v.validateDocument(b.schema, raw, "body")
+}
+
+// validateDocument decodes raw and checks it against s, sorting errors by field.
+func (v *Validator) validateDocument(s *Schema, raw []byte, what string) []FieldError {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []FieldError{{Message: what + " is not valid JSON: " + err.Error()}}
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return []FieldError{{Message: what + " must hold a single JSON value"}}
+	}
+	var errs []FieldError
+	v.validate(s, value, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// DocumentSchema is a standalone JSON Schema, such as a launch manifest schema,
+// checked with the same keyword subset as request bodies. Its $refs resolve
+// against the schema's own "$defs".
+type DocumentSchema struct {
+	validator *Validator
+	root      *Schema
+}
+
+// CompileSchema parses and prepares a standalone JSON Schema.
+func CompileSchema(raw []byte) (*DocumentSchema, error) {
+	var root Schema
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("schema is not a JSON Schema object: %w", err)
+	}
+	var defs struct {
+		Defs map[string]*Schema `json:"$defs"`
+	}
+	if err := json.Unmarshal(raw, &defs); err != nil {
+		return nil, fmt.Errorf("schema $defs: %w", err)
+	}
+	v := &Validator{schemas: defs.Defs, refPrefix: "#/$defs/"}
+	for name, schema := range v.schemas {
+		if err := v.compile(schema); err != nil {
+			return nil, fmt.Errorf("$defs %s: %w", name, err)
+		}
+	}
+	if err := v.compile(&root); err != nil {
+		return nil, err
+	}
+	return &DocumentSchema{validator: v, root: &root}, nil
+}
+
+// Validate checks a JSON document against the schema, returning every violation.
+func (d *DocumentSchema) Validate(raw []byte) []FieldError {
+	return d.validator.validateDocument(d.root, raw, "document")
+}
+
+// Route returns the documented path template that path matches, including the
+// server's base path, such as "/api/v1/runs/{runID}", or "" if it matches none.
+func (v *Validator) Route(path string) string {
+	rt, ok := v.match(path)
+	if !ok {
+		return ""
+	}
+	return v.basePath + rt.template
+}
+
+func (v *Validator) lookup(method, path string) (*body, bool) {
+	rt, ok := v.match(path)
+	if !ok {
+		return nil, false
+	}
+	b, ok := rt.bodies[method]
+	return b, ok
+}
+
+func (v *Validator) match(path string) (*route, bool) {
+	path, ok := strings.CutPrefix(path, v.basePath)
+	if !ok {
+		return nil, false
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, rt := range v.routes {
+		if len(rt.segments) != len(segments) {
+			continue
+		}
+		matched := true
+		for j, segment := range rt.segments {
+			if !strings.HasPrefix(segment, "{") && segment != segments[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &v.routes[i], true
+		}
+	}
+	return nil, false
+}
+
+func (v *Validator) validate(s *Schema, value any, field string, errs *[]FieldError) {
+	s, err := v.resolve(s)
+	if err != nil {
+		*errs = append(*errs, FieldError{Field: field, Message: err.Error()})
+		return
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if value == nil {
+		if s.Type != "" && !s.Nullable {
+			fail("must not be null")
+		}
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		fail("must be one of %s", formatEnum(s.Enum))
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, present := obj[name]; !present {
+				*errs = append(*errs, FieldError{Field: join(field, name), Message: "is required"})
+			}
+		}
+		for name, child := range obj {
+			if prop, known := s.Properties[name]; known {
+				v.validate(prop, child, join(field, name), errs)
+			} else if s.additional != nil {
+				v.validate(s.additional, child, join(field, name), errs)
+			} else if s.closed {
+				*errs = append(*errs, FieldError{Field: join(field, name), Message: "is not a recognised field"})
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			fail("must hold at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			fail("must hold at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				v.validate(s.Items, item, fmt.Sprintf("%s[%d]", field, i), errs)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		length := len([]rune(str))
+		if s.MinLength != nil && length < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			fail("must match %s", s.Pattern)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				fail("must be an RFC 3339 timestamp")
+			}
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			fail("must be %s", article(s.Type))
+			return
+		}
+		f, err := num.Float64()
+		if err != nil || math.IsInf(f, 0) {
+			fail("must be a finite %s", s.Type)
+			return
+		}
+		if s.Type == "integer" && (strings.ContainsAny(num.String(), ".eE") || f != math.Trunc(f)) {
+			fail("must be an integer")
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+	}
+}
+
+func article(typ string) string {
+	if typ == "integer" {
+		return "an integer"
+	}
+	return "a " + typ
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, candidate := range enum {
+		if fmt.Sprint(candidate) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []any) string {
+	values := make([]string, 0, len(enum))
+	for _, value := range enum {
+		if s, ok := value.(string); ok && s == "" {
+			continue // an empty string enum member means "omitted"
+		}
+		values = append(values, fmt.Sprintf("%q", value))
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDocumentCompiles(t *testing.T) {
+	var doc map[string]any
+	if err := json.Unmarshal(Document(), &doc); err != nil {
+		t.Fatalf("document is not JSON: %v", err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Fatalf("unexpected openapi version %v", doc["openapi"])
+	}
+	if _, err := NewValidator(); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	v := MustNewValidator()
+	for path, want := range map[string]string{
+		"/api/v1/runs":                          "/api/v1/runs",
+		"/api/v1/runs/r1":                       "/api/v1/runs/{runID}",
+		"/api/v1/runs/r1/checkpoints/3/promote": "/api/v1/runs/{runID}/checkpoints/{version}/promote",
+		"/api/v1/nowhere":                       "",
+		"/healthz":                              "",
+	} {
+		if got := v.Route(path); got != want {
+			t.Errorf("Route(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestValidateBody(t *testing.T) {
+	v := MustNewValidator()
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   []FieldError
+	}{
+		{"valid learner", "POST", "/api/v1/learners", `{"id":"l1","slots":2,"capabilities":{"gpus":4}}`, nil},
+		{"missing required", "POST", "/api/v1/learners", `{"name":"l1"}`, []FieldError{{"id", "is required"}, {"slots", "is required"}}},
+		{"nested type", "POST", "/api/v1/learners", `{"id":"l1","slots":1,"capabilities":{"gpus":"four"}}`, []FieldError{{"capabilities.gpus", "must be an integer"}}},
+		{"fractional integer", "POST", "/api/v1/learners", `{"id":"l1","slots":1.5}`, []FieldError{{"slots", "must be an integer"}}},
+		{"minimum", "POST", "/api/v1/learners", `{"id":"l1","slots":0}`, []FieldError{{"slots", "must be at least 1"}}},
+		{"enum via ref", "POST", "/api/v1/runs/r1/heartbeat", `{"run_id":"r1","status":"sleeping"}`, []FieldError{{"status", `must be one of "running", "paused", "terminating", "errored"`}}},
+		{"date-time", "POST", "/api/v1/runs/r1/commands", `{"type":"pause","expires_at":"tomorrow"}`, []FieldError{{"expires_at", "must be an RFC 3339 timestamp"}}},
+		{"map values", "POST", "/api/v1/runs/r1/checkpoints", `{"version":1,"storage_uri":"s3://b/k","metrics":{"loss":"low"}}`, []FieldError{{"metrics.loss", "must be a number"}}},
+		{"array items", "POST", "/api/v1/heartbeats", `[{"run_id":"r1"},{"run_id":7}]`, []FieldError{{"[1].run_id", "must be a string"}}},
+		{"array bounds", "POST", "/api/v1/heartbeats", `[]`, []FieldError{{"", "must hold at least 1 items"}}},
+		{"optional body", "POST", "/api/v1/runs/r1/pause", ``, nil},
+		{"required body", "POST", "/api/v1/runs", ``, []FieldError{{"", "request body is required"}}},
+		{"nullable", "POST", "/api/v1/runs", `{"experiment_id":"e","version_id":"v","launch_manifest":null}`, nil},
+		{"not null", "POST", "/api/v1/runs", `{"experiment_id":null,"version_id":"v"}`, []FieldError{{"experiment_id", "must not be null"}}},
+		{"trailing data", "POST", "/api/v1/runs", `{"experiment_id":"e","version_id":"v"} {}`, []FieldError{{"", "body must hold a single JSON value"}}},
+		{"static segment wins", "POST", "/api/v1/runs/r1/checkpoints/3/promote", `{"promoted_by":5}`, []FieldError{{"promoted_by", "must be a string"}}},
+		{"undocumented route", "POST", "/api/v1/nowhere", `not json`, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := v.ValidateBody(tc.method, tc.path, []byte(tc.body))
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileSchema(t *testing.T) {
+	schema, err := CompileSchema([]byte(`{
+		"type": "object",
+		"required": ["game", "trainer"],
+		"properties": {
+			"game": {"type": "object", "properties": {"env_id": {"type": "string", "enum": ["tictactoe", "connect4"]}}},
+			"trainer": {"$ref": "#/$defs/trainer"}
+		},
+		"$defs": {"trainer": {"type": "object", "properties": {"batch_size": {"type": "integer", "minimum": 1}}}}
+	}`))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if errs := schema.Validate([]byte(`{"game":{"env_id":"tictactoe"},"trainer":{"batch_size":64}}`)); len(errs) != 0 {
+		t.Fatalf("expected a valid document, got %v", errs)
+	}
+	errs := schema.Validate([]byte(`{"game":{"env_id":"chess"},"trainer":{"batch_size":0}}`))
+	want := []FieldError{{"game.env_id", `must be one of "tictactoe", "connect4"`}, {"trainer.batch_size", "must be at least 1"}}
+	if len(errs) != len(want) || errs[0] != want[0] || errs[1] != want[1] {
+		t.Fatalf("got %v, want %v", errs, want)
+	}
+	if _, err := CompileSchema([]byte(`{"properties": {"x": {"$ref": "#/$defs/missing"}}}`)); err == nil {
+		t.Fatal("expected an unresolvable $ref to fail compilation")
+	}
+}
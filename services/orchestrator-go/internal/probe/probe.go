@@ -0,0 +1,160 @@
+// Package probe reports component health for the orchestrator's /healthz and
+// /readyz endpoints. Liveness checks cover the process itself, such as the
+// background loops; readiness additionally covers the dependencies it needs to
+// serve traffic, such as the database and the event publisher.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Component statuses.
+const (
+	StatusOK   = "ok"
+	StatusDown = "down"
+	// StatusStandby marks a loop that is idle because another replica leads.
+	StatusStandby = "standby"
+)
+
+// stallIntervals is how many intervals a loop may go without ticking before it
+// is considered stalled.
+const stallIntervals = 3
+
+// Component is the result of a single check.
+type Component struct {
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	LastTick *time.Time `json:"last_tick,omitempty"`
+}
+
+// Report aggregates component results. Status is "ok" unless a component is down.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Healthy reports whether no component is down.
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// Check reports the status of one component.
+type Check func(ctx context.Context) Component
+
+// Ping adapts a connectivity check: the component is down when ping fails.
+func Ping(ping func(ctx context.Context) error) Check {
+	return func(ctx context.Context) Component {
+		if err := ping(ctx); err != nil {
+			return Component{Status: StatusDown, Error: err.Error()}
+		}
+		return Component{Status: StatusOK}
+	}
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Prober runs registered checks concurrently, each bounded by a timeout.
+type Prober struct {
+	timeout   time.Duration
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+// New creates a prober whose checks are cancelled after timeout.
+func New(timeout time.Duration) *Prober {
+	return &Prober{timeout: timeout}
+}
+
+// AddLiveness registers a check included in both the liveness and readiness reports.
+func (p *Prober) AddLiveness(name string, check Check) {
+	p.liveness = append(p.liveness, namedCheck{name: name, check: check})
+}
+
+// AddReadiness registers a check included only in the readiness report.
+func (p *Prober) AddReadiness(name string, check Check) {
+	p.readiness = append(p.readiness, namedCheck{name: name, check: check})
+}
+
+// Live runs the liveness checks.
+func (p *Prober) Live(ctx context.Context) Report {
+	return p.run(ctx, p.liveness)
+}
+
+// Ready runs the liveness and readiness checks.
+func (p *Prober) Ready(ctx context.Context) Report {
+	checks := append(append([]namedCheck{}, p.liveness...), p.readiness...)
+	return p.run(ctx, checks)
+}
+
+func (p *Prober) run(ctx context.Context, checks []namedCheck) Report {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+			result := c.check(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[c.name] = result
+			if result.Status == StatusDown {
+				report.Status = StatusDown
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// Loop tracks the ticks of a background loop. A loop that has not ticked for
+// several intervals is reported down, as is one that has never started.
+type Loop struct {
+	interval time.Duration
+	active   func() bool
+	now      func() time.Time
+	last     atomic.Int64
+}
+
+// NewLoop tracks a loop ticking every interval. active reports whether the loop
+// should be running on this replica; nil means always.
+func NewLoop(interval time.Duration, active func() bool) *Loop {
+	return &Loop{interval: interval, active: active, now: time.Now}
+}
+
+// Tick records that the loop is making progress.
+func (l *Loop) Tick() {
+	l.last.Store(l.now().UnixNano())
+}
+
+// Check reports whether the loop has ticked recently.
+func (l *Loop) Check(context.Context) Component {
+	var result Component
+	if last := l.last.Load(); last != 0 {
+		at := time.Unix(0, last).UTC()
+		result.LastTick = &at
+	}
+	switch {
+	case l.active != nil && !l.active():
+		result.Status = StatusStandby
+	case result.LastTick == nil:
+		result.Status = StatusDown
+		result.Error = "loop has not started"
+	case l.now().Sub(*result.LastTick) > stallIntervals*l.interval:
+		result.Status = StatusDown
+		result.Error = fmt.Sprintf("no tick for %s (interval %s)", l.now().Sub(*result.LastTick).Round(time.Second), l.interval)
+	default:
+		result.Status = StatusOK
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoopCheck(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	leading := true
+	loop := NewLoop(10*time.Second, func() bool { return leading })
+	loop.now = func() time.Time { return now }
+
+	if got := loop.Check(ctx); got.Status != StatusDown || got.Error == "" {
+		t.Fatalf("expected a loop that never ticked to be down, got %+v", got)
+	}
+	loop.Tick()
+	now = now.Add(25 * time.Second)
+	if got := loop.Check(ctx); got.Status != StatusOK || got.LastTick == nil {
+		t.Fatalf("expected a recently ticked loop to be ok, got %+v", got)
+	}
+	now = now.Add(10 * time.Second)
+	if got := loop.Check(ctx); got.Status != StatusDown {
+		t.Fatalf("expected a loop silent for 35s at a 10s interval to be down, got %+v", got)
+	}
+	leading = false
+	if got := loop.Check(ctx); got.Status != StatusStandby {
+		t.Fatalf("expected a loop on a follower to be standby, got %+v", got)
+	}
+}
+
+func TestProberReports(t *testing.T) {
+	ctx := context.Background()
+	p := New(time.Second)
+	p.AddLiveness("loop", func(context.Context) Component { return Component{Status: StatusStandby} })
+	var dbErr error
+	p.AddReadiness("database", Ping(func(context.Context) error { return dbErr }))
+
+	live := p.Live(ctx)
+	if !live.Healthy() || len(live.Components) != 1 {
+		t.Fatalf("expected liveness to cover only the loop and pass, got %+v", live)
+	}
+	if ready := p.Ready(ctx); !ready.Healthy() || len(ready.Components) != 2 {
+		t.Fatalf("expected readiness to pass with both components, got %+v", ready)
+	}
+
+	dbErr = errors.New("connection refused")
+	ready := p.Ready(ctx)
+	if ready.Healthy() || ready.Components["database"].Error != "connection refused" {
+		t.Fatalf("expected readiness to fail on the database, got %+v", ready)
+	}
+	if !p.Live(ctx).Healthy() {
+		t.Fatal("expected a database outage not to fail liveness")
+	}
+}
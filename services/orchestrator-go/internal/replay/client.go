@@ -0,0 +1,76 @@
+// Package replay polls the replay service for the buffer activity of running
+// runs, recording fill and ingest rate on each run and raising an alert when
+// an environment stops producing transitions.
+package replay
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/telemetry/middleware"
+)
+
+// Stats mirrors the replay service's StatsResponse.
+type Stats struct {
+	TotalTransitions uint64
+	TotalEpisodes    uint64
+	TransitionsByEnv map[string]uint64
+	// TransitionsByRun counts transitions by the run_id they are stamped with.
+	TransitionsByRun map[string]uint64
+	// OldestTimestamp and NewestTimestamp are Unix seconds, zero when the buffer is empty.
+	OldestTimestamp uint64
+	NewestTimestamp uint64
+	StorageBytes    uint64
+}
+
+// Client calls the replay service's GetStats RPC.
+type Client struct {
+	conn   *grpc.ClientConn
+	replay replayv1.ReplayClient
+}
+
+// Dial creates a client for the replay service at addr, with opts added to the
+// connection's. The connection is established lazily on the first call, and
+// calls are traced.
+func Dial(addr string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append(append(middleware.GRPCClientOptions(), grpc.WithTransportCredentials(insecure.NewCredentials())), opts...)
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial replay service: %w", err)
+	}
+	return newClient(conn), nil
+}
+
+func newClient(conn *grpc.ClientConn) *Client {
+	return &Client{conn: conn, replay: replayv1.NewReplayClient(conn)}
+}
+
+// Stats returns the buffer statistics, with TransitionsByEnv limited to envID.
+func (c *Client) Stats(ctx context.Context, envID string) (Stats, error) {
+	response, err := c.replay.GetStats(ctx, &replayv1.GetStatsRequest{EnvId: envID})
+	if err != nil {
+		return Stats{}, fmt.Errorf("replay GetStats: %w", err)
+	}
+	stats := Stats{
+		TotalTransitions: response.GetTotalTransitions(),
+		TotalEpisodes:    response.GetTotalEpisodes(),
+		TransitionsByEnv: response.GetTransitionsByEnv(),
+		TransitionsByRun: response.GetTransitionsByRun(),
+		OldestTimestamp:  response.GetOldestTimestamp(),
+		NewestTimestamp:  response.GetNewestTimestamp(),
+		StorageBytes:     response.GetStorageBytes(),
+	}
+	if stats.TransitionsByEnv == nil {
+		stats.TransitionsByEnv = map[string]uint64{}
+	}
+	return stats, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
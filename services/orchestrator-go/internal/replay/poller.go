@@ -0,0 +1,214 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// StatsSource returns replay buffer statistics for an environment. *Client
+// implements it.
+type StatsSource interface {
+	Stats(ctx context.Context, envID string) (Stats, error)
+}
+
+// Config holds replay polling configuration.
+type Config struct {
+	Interval time.Duration
+	// StallAfter is how long a running run may go without new transitions
+	// before collection is reported stalled.
+	StallAfter time.Duration
+	// Capacity is the replay buffer's maximum number of transitions, used to
+	// report fill; zero leaves fill unreported.
+	Capacity uint64
+}
+
+// Poller periodically records replay stats on running runs.
+type Poller struct {
+	orch      *service.Orchestrator
+	source    StatsSource
+	publisher events.Publisher
+	config    Config
+	logger    zerolog.Logger
+	now       func() time.Time
+	onTick    func()
+}
+
+// NewPoller creates a poller that queries source every config.Interval.
+func NewPoller(orch *service.Orchestrator, source StatsSource, publisher events.Publisher, config Config, logger zerolog.Logger) *Poller {
+	return &Poller{
+		orch:      orch,
+		source:    source,
+		publisher: publisher,
+		config:    config,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// OnTick registers fn to be called when the loop starts and after every pass,
+// letting liveness probes confirm it is making progress.
+func (p *Poller) OnTick(fn func()) {
+	p.onTick = fn
+}
+
+// Start runs the polling loop until ctx is cancelled.
+func (p *Poller) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	p.logger.Info().
+		Dur("interval", p.config.Interval).
+		Dur("stall_after", p.config.StallAfter).
+		Msg("Starting replay stats poller")
+	p.beat()
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info().Msg("Replay stats poller stopped")
+			return
+		case <-ticker.C:
+			p.tick(ctx)
+			p.beat()
+		}
+	}
+}
+
+func (p *Poller) beat() {
+	if p.onTick != nil {
+		p.onTick()
+	}
+}
+
+func (p *Poller) tick(ctx context.Context) {
+	runs, err := p.orch.ListRunsForHealthCheck(ctx, types.RunStateRunning)
+	if err != nil {
+		p.logger.Error().Err(err).Msg("Failed to list runs for replay stats")
+		return
+	}
+
+	// Runs sharing an environment share one query per pass.
+	polled := make(map[string]*Stats)
+	for _, run := range runs {
+		env := types.RequirementsFromManifest(run.LaunchManifest).Env
+		if env == "" {
+			continue
+		}
+		stats, ok := polled[env]
+		if !ok {
+			fetched, err := p.source.Stats(ctx, env)
+			if err != nil {
+				p.logger.Warn().Err(err).Str("env_id", env).Msg("Failed to fetch replay stats")
+			} else {
+				stats = &fetched
+			}
+			polled[env] = stats
+		}
+		if stats == nil {
+			continue
+		}
+		p.record(ctx, run, env, *stats)
+	}
+}
+
+// record derives the run's replay stats from a fresh poll and the previous one,
+// alerting when collection stalls or recovers.
+func (p *Poller) record(ctx context.Context, run types.Run, env string, polled Stats) {
+	now := p.now().UTC()
+	current := types.ReplayStats{
+		EnvID:          env,
+		Transitions:    polled.TransitionsByEnv[env],
+		RunTransitions: polled.TransitionsByRun[run.ID],
+		Episodes:       polled.TotalEpisodes,
+		StorageBytes:   polled.StorageBytes,
+		PolledAt:       now,
+	}
+	if p.config.Capacity > 0 {
+		current.Fill = min(1, float64(polled.TotalTransitions)/float64(p.config.Capacity))
+	}
+	if polled.NewestTimestamp > 0 {
+		newest := time.Unix(int64(polled.NewestTimestamp), 0).UTC()
+		current.NewestTransitionAt = &newest
+	}
+
+	previous := run.Replay
+	if previous != nil && previous.EnvID == env {
+		current.LastIngestAt = previous.LastIngestAt
+		switch {
+		case ingested(current) > ingested(*previous):
+			if elapsed := now.Sub(previous.PolledAt).Seconds(); elapsed > 0 {
+				current.IngestRate = float64(ingested(current)-ingested(*previous)) / elapsed
+			}
+			current.LastIngestAt = &now
+		case current.Fill >= 1 && newer(current.NewestTransitionAt, previous.NewestTransitionAt):
+			// A full buffer evicts as it ingests, so the count cannot grow.
+			current.LastIngestAt = &now
+		}
+	}
+	since := current.LastIngestAt
+	if since == nil {
+		since = run.StartedAt
+	}
+	current.Stalled = since != nil && now.Sub(*since) >= p.config.StallAfter
+
+	updated, err := p.orch.RecordReplayStats(ctx, run.ID, current)
+	if err != nil {
+		p.logger.Error().Err(err).Str("run_id", run.ID).Msg("Failed to record replay stats")
+		return
+	}
+
+	wasStalled := previous != nil && previous.Stalled
+	switch {
+	case current.Stalled && !wasStalled:
+		p.logger.Warn().
+			Str("run_id", run.ID).
+			Str("env_id", env).
+			Time("since", *since).
+			Msg("Replay collection stalled")
+		p.publish(ctx, updated, events.ReasonReplayStalled,
+			fmt.Sprintf("No replay transitions for env %s since %s", env, since.Format(time.RFC3339)))
+	case !current.Stalled && wasStalled:
+		p.logger.Info().
+			Str("run_id", run.ID).
+			Str("env_id", env).
+			Msg("Replay collection resumed")
+		p.publish(ctx, updated, events.ReasonReplayResumed, "")
+	}
+}
+
+func (p *Poller) publish(ctx context.Context, run types.Run, reason, lastError string) {
+	event := events.RunStatusEvent{
+		RunID:            run.ID,
+		State:            string(run.State),
+		Reason:           reason,
+		RuntimeStatus:    string(run.RuntimeStatus),
+		HealthStatus:     string(run.HealthStatus),
+		Step:             run.CurrentStep,
+		SamplesPerSecond: run.SamplesPerSecond,
+		Loss:             run.Loss,
+		LastError:        lastError,
+	}
+	if err := p.publisher.PublishRunStatus(ctx, event); err != nil {
+		p.logger.Error().Err(err).Str("run_id", run.ID).Msg("Failed to publish replay event")
+	}
+}
+
+// ingested is the count ingest is tracked on: the run's own transitions once
+// its actors stamp their run context, so runs sharing an environment do not
+// mask each other's stalls, and the environment's otherwise.
+func ingested(stats types.ReplayStats) uint64 {
+	if stats.RunTransitions > 0 {
+		return stats.RunTransitions
+	}
+	return stats.Transitions
+}
+
+func newer(a, b *time.Time) bool {
+	return a != nil && (b == nil || a.After(*b))
+}
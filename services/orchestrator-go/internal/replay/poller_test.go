@@ -0,0 +1,177 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+	replayv1 "github.com/cartridge/proto/replay/v1"
+)
+
+type fakeSource map[string]Stats
+
+func (f fakeSource) Stats(_ context.Context, envID string) (Stats, error) {
+	return f[envID], nil
+}
+
+type statusRecorder struct {
+	events.NoopPublisher
+	statuses []events.RunStatusEvent
+}
+
+func (r *statusRecorder) PublishRunStatus(_ context.Context, event events.RunStatusEvent) error {
+	r.statuses = append(r.statuses, event)
+	return nil
+}
+
+func TestPollerRecordsIngestAndAlertsOnStall(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(storage.NewMemoryStore(), events.NoopPublisher{}, logger)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	orch.WithNow(func() time.Time { return start })
+	for id, manifest := range map[string]string{"run-pong": `{"game":{"env_id":"pong"}}`, "run-bare": `{}`} {
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: id, ExperimentID: "exp-1", VersionID: "ver-1", LaunchManifest: json.RawMessage(manifest)}); err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+		for _, action := range []types.RunAction{types.RunActionProvision, types.RunActionStart} {
+			if _, err := orch.PerformAction(ctx, id, action, "tester", ""); err != nil {
+				t.Fatalf("%s: %v", action, err)
+			}
+		}
+	}
+
+	source := fakeSource{"pong": {TotalTransitions: 100, TransitionsByEnv: map[string]uint64{"pong": 100}}}
+	publisher := &statusRecorder{}
+	poller := NewPoller(orch, source, publisher, Config{Interval: 10 * time.Second, StallAfter: time.Minute, Capacity: 1000}, *logger)
+	now := start
+	poller.now = func() time.Time { return now }
+	poll := func(after time.Duration, transitions uint64) types.ReplayStats {
+		t.Helper()
+		now = now.Add(after)
+		source["pong"] = Stats{TotalTransitions: transitions, TransitionsByEnv: map[string]uint64{"pong": transitions}}
+		poller.tick(ctx)
+		run, _ := orch.GetRun(ctx, "run-pong")
+		if run.Replay == nil {
+			t.Fatal("expected replay stats recorded on the run")
+		}
+		return *run.Replay
+	}
+
+	if got := poll(10*time.Second, 100); got.Fill != 0.1 || got.IngestRate != 0 || got.Stalled {
+		t.Fatalf("unexpected first poll: %+v", got)
+	}
+	if got := poll(10*time.Second, 300); got.IngestRate != 20 || got.LastIngestAt == nil {
+		t.Fatalf("expected 20 transitions/s, got %+v", got)
+	}
+	if got := poll(50*time.Second, 300); got.Stalled || got.IngestRate != 0 {
+		t.Fatalf("expected no stall within the window, got %+v", got)
+	}
+	if got := poll(20*time.Second, 300); !got.Stalled {
+		t.Fatalf("expected a stall after 70s without transitions, got %+v", got)
+	}
+	poll(10*time.Second, 300)
+	if len(publisher.statuses) != 1 || publisher.statuses[0].Reason != events.ReasonReplayStalled || publisher.statuses[0].RunID != "run-pong" {
+		t.Fatalf("expected a single stall alert, got %+v", publisher.statuses)
+	}
+	if got := poll(10*time.Second, 310); got.Stalled {
+		t.Fatalf("expected collection to resume, got %+v", got)
+	}
+	if len(publisher.statuses) != 2 || publisher.statuses[1].Reason != events.ReasonReplayResumed {
+		t.Fatalf("expected a resume event, got %+v", publisher.statuses)
+	}
+	if bare, _ := orch.GetRun(ctx, "run-bare"); bare.Replay != nil {
+		t.Fatalf("expected runs without an env_id to be skipped, got %+v", bare.Replay)
+	}
+}
+
+func TestPollerTracksRunsSharingAnEnvironment(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(storage.NewMemoryStore(), events.NoopPublisher{}, logger)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	orch.WithNow(func() time.Time { return start })
+	for _, id := range []string{"run-a", "run-b"} {
+		if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: id, ExperimentID: "exp-1", VersionID: "ver-1", LaunchManifest: json.RawMessage(`{"game":{"env_id":"pong"}}`)}); err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+		for _, action := range []types.RunAction{types.RunActionProvision, types.RunActionStart} {
+			if _, err := orch.PerformAction(ctx, id, action, "tester", ""); err != nil {
+				t.Fatalf("%s: %v", action, err)
+			}
+		}
+	}
+
+	source := fakeSource{}
+	poller := NewPoller(orch, source, &statusRecorder{}, Config{Interval: 10 * time.Second, StallAfter: time.Minute}, *logger)
+	now := start
+	poller.now = func() time.Time { return now }
+	// Only run-a's actors produce; the environment's count keeps growing.
+	for i, byRun := range []map[string]uint64{{"run-a": 10, "run-b": 5}, {"run-a": 40, "run-b": 5}, {"run-a": 70, "run-b": 5}} {
+		now = now.Add(40 * time.Second)
+		total := byRun["run-a"] + byRun["run-b"]
+		source["pong"] = Stats{TotalTransitions: total, TransitionsByEnv: map[string]uint64{"pong": total}, TransitionsByRun: byRun}
+		poller.tick(ctx)
+		if i == 0 {
+			continue
+		}
+		a, _ := orch.GetRun(ctx, "run-a")
+		if a.Replay.RunTransitions != byRun["run-a"] || a.Replay.IngestRate != 0.75 || a.Replay.Stalled {
+			t.Fatalf("poll %d: expected run-a ingesting 0.75/s, got %+v", i, a.Replay)
+		}
+	}
+	b, _ := orch.GetRun(ctx, "run-b")
+	if b.Replay.IngestRate != 0 || !b.Replay.Stalled {
+		t.Fatalf("expected run-b stalled despite the environment ingesting, got %+v", b.Replay)
+	}
+}
+
+type statsServer struct {
+	replayv1.UnimplementedReplayServer
+}
+
+func (statsServer) GetStats(_ context.Context, req *replayv1.GetStatsRequest) (*replayv1.StatsResponse, error) {
+	return &replayv1.StatsResponse{
+		TotalTransitions: 50,
+		TransitionsByEnv: map[string]uint64{req.GetEnvId(): 42},
+		NewestTimestamp:  1704110400,
+	}, nil
+}
+
+func TestClientStats(t *testing.T) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	replayv1.RegisterReplayServer(server, statsServer{})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///replay",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	client := newClient(conn)
+	defer client.Close()
+
+	stats, err := client.Stats(context.Background(), "pong")
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if stats.TotalTransitions != 50 || stats.TransitionsByEnv["pong"] != 42 || stats.NewestTimestamp != 1704110400 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
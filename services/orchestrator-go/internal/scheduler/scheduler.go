@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/service"
+)
+
+// Scheduler periodically launches runs for due cron schedules.
+type Scheduler struct {
+	orch     *service.Orchestrator
+	interval time.Duration
+	logger   zerolog.Logger
+	onTick   func()
+}
+
+// New creates a scheduler that checks for due schedules every interval.
+func New(orch *service.Orchestrator, interval time.Duration, logger zerolog.Logger) *Scheduler {
+	return &Scheduler{orch: orch, interval: interval, logger: logger}
+}
+
+// OnTick registers fn to be called when the loop starts and after every pass,
+// letting liveness probes confirm it is making progress.
+func (s *Scheduler) OnTick(fn func()) {
+	s.onTick = fn
+}
+
+// Start runs the scheduling loop until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info().Dur("interval", s.interval).Msg("Starting run scheduler")
+	s.beat()
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info().Msg("Run scheduler stopped")
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+			s.beat()
+		}
+	}
+}
+
+func (s *Scheduler) beat() {
+	if s.onTick != nil {
+		s.onTick()
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	runs, err := s.orch.RunDueSchedules(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to run due schedules")
+		return
+	}
+	for _, run := range runs {
+		s.logger.Info().
+			Str("schedule_id", run.ScheduleID).
+			Str("run_id", run.ID).
+			Msg("Launched scheduled run")
+	}
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func TestSchedulerOverlapPolicies(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	orch := service.NewOrchestrator(store, events.NoopPublisher{}, logger)
+
+	now := time.Date(2024, time.March, 1, 8, 0, 30, 0, time.UTC)
+	orch.WithNow(func() time.Time { return now })
+	for _, policy := range []types.OverlapPolicy{types.OverlapSkip, types.OverlapQueue} {
+		if _, err := orch.CreateSchedule(ctx, service.CreateScheduleInput{
+			ID:            "nightly-" + string(policy),
+			Cron:          "*/10 * * * *",
+			ExperimentID:  "exp-1",
+			VersionID:     "ver-1",
+			OverlapPolicy: policy,
+		}); err != nil {
+			t.Fatalf("create schedule: %v", err)
+		}
+	}
+	sched := New(orch, time.Minute, *logger)
+
+	// Nothing is due before the first activation at 08:10.
+	sched.tick(ctx)
+	if runs, _ := store.ListRunsByState(ctx, types.RunStateQueued); len(runs) != 0 {
+		t.Fatalf("expected no runs before first activation, got %d", len(runs))
+	}
+
+	now = now.Add(10 * time.Minute)
+	sched.tick(ctx)
+	runs, _ := store.ListRunsByState(ctx, types.RunStateQueued)
+	if len(runs) != 2 {
+		t.Fatalf("expected one run per schedule, got %d", len(runs))
+	}
+	for _, run := range runs {
+		if run.ScheduleID == "" || run.CreatedBy != "scheduler" {
+			t.Fatalf("expected run linked to its schedule, got %+v", run)
+		}
+	}
+
+	// Both previous runs are still queued: skip drops the activation, queue does not.
+	now = now.Add(10 * time.Minute)
+	sched.tick(ctx)
+	skip, _ := orch.GetSchedule(ctx, "nightly-skip")
+	queue, _ := orch.GetSchedule(ctx, "nightly-queue")
+	if skip.LastRunID != "nightly-skip-"+strconv.FormatInt(time.Date(2024, time.March, 1, 8, 10, 0, 0, time.UTC).Unix(), 10) {
+		t.Fatalf("expected skip schedule to keep its first run, got %s", skip.LastRunID)
+	}
+	if runs, _ := store.ListRunsByState(ctx, types.RunStateQueued); len(runs) != 3 {
+		t.Fatalf("expected queue policy to add a run, got %d queued", len(runs))
+	}
+	if queue.NextRunAt == nil || !queue.NextRunAt.Equal(time.Date(2024, time.March, 1, 8, 30, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected next activation %v", queue.NextRunAt)
+	}
+
+	paused, err := orch.SetScheduleEnabled(ctx, "nightly-queue", false)
+	if err != nil || paused.Enabled || paused.NextRunAt != nil {
+		t.Fatalf("expected paused schedule, got %+v (%v)", paused, err)
+	}
+	now = now.Add(time.Hour)
+	sched.tick(ctx)
+	if runs, _ := store.ListRunsByState(ctx, types.RunStateQueued); len(runs) != 3 {
+		t.Fatalf("expected no new runs while paused or overlapping, got %d queued", len(runs))
+	}
+	resumed, err := orch.SetScheduleEnabled(ctx, "nightly-queue", true)
+	if err != nil || resumed.NextRunAt == nil || !resumed.NextRunAt.After(now) {
+		t.Fatalf("expected re-enabled schedule to resume from now, got %+v (%v)", resumed, err)
+	}
+}
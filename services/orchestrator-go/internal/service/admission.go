@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// Admitter is a policy hook consulted before a run is accepted, such as a
+// naming convention or a budget check. Admit returns nil to accept the run and
+// an error made with Deny to refuse it; any other error means the hook could
+// not decide, and the run is refused as unavailable.
+type Admitter interface {
+	Admit(ctx context.Context, run types.Run) error
+}
+
+// AdmitterFunc adapts a function to Admitter.
+type AdmitterFunc func(ctx context.Context, run types.Run) error
+
+// Admit calls f.
+func (f AdmitterFunc) Admit(ctx context.Context, run types.Run) error {
+	return f(ctx, run)
+}
+
+// Deny refuses admission with a reason reported to the caller.
+func Deny(format string, args ...any) error {
+	return &kindError{kind: ErrAdmissionDenied, err: fmt.Errorf(format, args...)}
+}
+
+// WithAdmitters appends hooks to the admission chain. Every run created, by the
+// API, a schedule or a clone, must pass each hook in order once it has been
+// validated; the first refusal rejects it.
+func (o *Orchestrator) WithAdmitters(admitters ...Admitter) {
+	o.admitters = append(o.admitters, admitters...)
+}
+
+// admit runs the admission chain. A retried create for a run that already
+// exists is not judged again, so creation stays idempotent.
+func (o *Orchestrator) admit(ctx context.Context, run types.Run) error {
+	if len(o.admitters) == 0 {
+		return nil
+	}
+	if _, err := o.store.GetRun(ctx, run.ID); err == nil {
+		return nil
+	}
+	for _, admitter := range o.admitters {
+		err := admitter.Admit(ctx, run)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, ErrAdmissionDenied):
+			o.logger.Info().Err(err).Str("run_id", run.ID).Str("experiment_id", run.ExperimentID).Msg("run denied admission")
+			return err
+		default:
+			// The cause is logged rather than returned; it may name internal hosts.
+			o.logger.Error().Err(err).Str("run_id", run.ID).Msg("admission hook failed")
+			return &kindError{kind: ErrAdmissionUnavailable, err: errors.New("admission check failed; try again later")}
+		}
+	}
+	return nil
+}
+
+// RunIDPattern admits only runs whose ID matches pattern.
+func RunIDPattern(pattern *regexp.Regexp) Admitter {
+	return AdmitterFunc(func(_ context.Context, run types.Run) error {
+		if !pattern.MatchString(run.ID) {
+			return Deny("run id %q does not match %s", run.ID, pattern)
+		}
+		return nil
+	})
+}
+
+// AllowedEnvs admits only runs whose manifest's game.env_id is one of envs.
+func AllowedEnvs(envs []string) Admitter {
+	return AdmitterFunc(func(_ context.Context, run types.Run) error {
+		env := types.RequirementsFromManifest(run.LaunchManifest).Env
+		if env == "" {
+			return Deny("launch manifest must set game.env_id")
+		}
+		if !slices.Contains(envs, env) {
+			return Deny("env %q is not allowed", env)
+		}
+		return nil
+	})
+}
+
+// ExperimentBudget admits runs only while the cost of their experiment's runs,
+// as priced by WithCostRates, is below limit. Archived runs count too.
+func (o *Orchestrator) ExperimentBudget(limit float64) Admitter {
+	return AdmitterFunc(func(ctx context.Context, run types.Run) error {
+		runs, err := o.store.ListRunsByExperiment(ctx, run.ExperimentID)
+		if err != nil {
+			return err
+		}
+		now := o.now()
+		var spent float64
+		for _, existing := range runs {
+			spent += o.costOf(existing, now).Cost
+		}
+		if spent >= limit {
+			return Deny("experiment %s has spent %.2f of its %.2f budget", run.ExperimentID, spent, limit)
+		}
+		return nil
+	})
+}
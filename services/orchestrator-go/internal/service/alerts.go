@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/correlation"
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// CreateAlertRuleInput captures the payload required to attach an alert rule.
+type CreateAlertRuleInput struct {
+	ID        string              `json:"id"`
+	Kind      types.AlertRuleKind `json:"kind"`
+	Severity  types.AlertSeverity `json:"severity,omitempty"`
+	Threshold float64             `json:"threshold"`
+	CreatedBy string              `json:"created_by"`
+}
+
+// CreateAlertRule attaches an alert rule to a run that has not ended. Severity
+// defaults to warning.
+func (o *Orchestrator) CreateAlertRule(ctx context.Context, runID string, input CreateAlertRuleInput) (types.AlertRule, error) {
+	if input.ID == "" {
+		return types.AlertRule{}, invalidf("id is required")
+	}
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.AlertRule{}, err
+	}
+	if run.State.IsTerminal() {
+		return types.AlertRule{}, fmt.Errorf("%w: run %s is %s", storage.ErrConflict, runID, run.State)
+	}
+	rule := types.AlertRule{
+		ID:        input.ID,
+		RunID:     runID,
+		Kind:      input.Kind,
+		Severity:  input.Severity,
+		Threshold: input.Threshold,
+		Enabled:   true,
+		CreatedBy: input.CreatedBy,
+		CreatedAt: o.now(),
+	}
+	if rule.Severity == "" {
+		rule.Severity = types.AlertSeverityWarning
+	}
+	if err := rule.Validate(); err != nil {
+		return types.AlertRule{}, invalid(err)
+	}
+	if err := o.store.CreateAlertRule(ctx, rule); err != nil {
+		return types.AlertRule{}, err
+	}
+	return rule, nil
+}
+
+// ListAlertRules returns a run's alert rules with their firing state.
+func (o *Orchestrator) ListAlertRules(ctx context.Context, runID string) ([]types.AlertRule, error) {
+	return o.store.ListAlertRules(ctx, runID)
+}
+
+// DisableAlertRule stops evaluating a rule; disabling is idempotent. A rule
+// that was firing is resolved.
+func (o *Orchestrator) DisableAlertRule(ctx context.Context, runID, ruleID string) (types.AlertRule, error) {
+	rule, err := o.store.GetAlertRule(ctx, runID, ruleID)
+	if err != nil || !rule.Enabled {
+		return rule, err
+	}
+	wasFiring := rule.Firing()
+	rule.Enabled = false
+	rule.FiringSince = nil
+	if err := o.store.UpdateAlertRule(ctx, rule); err != nil {
+		return types.AlertRule{}, err
+	}
+	if wasFiring {
+		o.publishAlert(ctx, rule, events.AlertResolved, "rule disabled", 0)
+	}
+	return rule, nil
+}
+
+// evaluateAlertRules checks a heartbeat against the run's enabled alert rules,
+// raising an alert for each newly breached rule and resolving those back
+// within their threshold. gap is the time since the previous heartbeat, zero
+// for the first.
+func (o *Orchestrator) evaluateAlertRules(ctx context.Context, run types.Run, gap time.Duration) {
+	if run.State != types.RunStateRunning || run.RuntimeStatus != types.RuntimeStatusRunning {
+		return
+	}
+	rules, err := o.store.ListAlertRules(ctx, run.ID)
+	if err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to load alert rules")
+		return
+	}
+	now := o.now()
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		rule, breach := rule.Observe(run.Loss, run.SamplesPerSecond, gap)
+		var status string
+		switch {
+		case breach != "" && !rule.Firing():
+			rule.FiringSince = &now
+			rule.Breaches++
+			rule.LastMessage = breach
+			status = events.AlertFiring
+		case breach == "" && rule.Firing():
+			rule.FiringSince = nil
+			status = events.AlertResolved
+		}
+		if err := o.store.UpdateAlertRule(ctx, rule); err != nil {
+			o.logger.Error().Err(err).Str("run_id", run.ID).Str("rule_id", rule.ID).Msg("failed to save alert rule state")
+			continue
+		}
+		if status != "" {
+			o.publishAlert(ctx, rule, status, breach, run.CurrentStep)
+		}
+	}
+}
+
+func (o *Orchestrator) publishAlert(ctx context.Context, rule types.AlertRule, status, message string, step int64) {
+	event := events.AlertEvent{
+		RunID:         rule.RunID,
+		RuleID:        rule.ID,
+		Kind:          string(rule.Kind),
+		Severity:      string(rule.Severity),
+		Status:        status,
+		Threshold:     rule.Threshold,
+		Message:       message,
+		Step:          step,
+		CorrelationID: correlation.FromContext(ctx),
+	}
+	if err := o.events.PublishAlertEvent(ctx, event); err != nil {
+		o.logger.Error().Err(err).Str("run_id", rule.RunID).Str("rule_id", rule.ID).Msg("failed to publish alert event")
+	}
+	o.logger.Info().
+		Str("run_id", rule.RunID).
+		Str("rule_id", rule.ID).
+		Str("severity", string(rule.Severity)).
+		Str("status", status).
+		Str("message", message).
+		Msg("alert rule " + status)
+}
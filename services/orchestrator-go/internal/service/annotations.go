@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// CreateAnnotationInput captures an operator's note on a run. At and Step
+// default to now and the run's current step.
+type CreateAnnotationInput struct {
+	ID     string     `json:"id"`
+	At     *time.Time `json:"at,omitempty"`
+	Step   *int64     `json:"step,omitempty"`
+	Text   string     `json:"text"`
+	Tags   []string   `json:"tags,omitempty"`
+	Author string     `json:"author,omitempty"`
+}
+
+// CreateAnnotation pins a note to the run's timeline. Notes may be added after
+// the run ends, and are idempotent by ID: resubmitting returns the stored note.
+func (o *Orchestrator) CreateAnnotation(ctx context.Context, runID string, input CreateAnnotationInput) (types.Annotation, error) {
+	if input.ID == "" {
+		return types.Annotation{}, invalidf("id is required")
+	}
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.Annotation{}, err
+	}
+	now := o.now()
+	annotation := types.Annotation{
+		ID:        input.ID,
+		RunID:     runID,
+		At:        now,
+		Step:      run.CurrentStep,
+		Text:      input.Text,
+		Tags:      input.Tags,
+		Author:    input.Author,
+		CreatedAt: now,
+	}
+	if input.At != nil {
+		annotation.At = input.At.UTC()
+	}
+	if input.Step != nil {
+		annotation.Step = *input.Step
+	}
+	if err := annotation.Validate(); err != nil {
+		return types.Annotation{}, invalid(err)
+	}
+	if err := o.store.CreateAnnotation(ctx, annotation); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return o.store.GetAnnotation(ctx, runID, annotation.ID)
+		}
+		return types.Annotation{}, err
+	}
+	return annotation, nil
+}
+
+// ListAnnotations returns the run's notes with from <= At < to, ordered by At.
+// Zero bounds leave the window open.
+func (o *Orchestrator) ListAnnotations(ctx context.Context, runID string, from, to time.Time) ([]types.Annotation, error) {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, invalidf("from must be before to")
+	}
+	return o.store.ListAnnotations(ctx, runID, from, to)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// WithArchivedMetricsRetention purges the heartbeat history of runs once they
+// have been archived for d; zero keeps it under the regular retention only.
+func (o *Orchestrator) WithArchivedMetricsRetention(d time.Duration) {
+	o.archivedMetricsRetention = d
+}
+
+// ArchiveRun hides a run from default listings and from health monitoring. The
+// record itself is kept. Queued runs must be dispatched or terminated first so
+// that the dispatcher never launches a hidden run.
+func (o *Orchestrator) ArchiveRun(ctx context.Context, runID string) (types.Run, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.Run{}, err
+	}
+	if run.Archived() {
+		return run, nil
+	}
+	if run.State == types.RunStateQueued {
+		return types.Run{}, fmt.Errorf("%w: run %s is still queued", storage.ErrConflict, runID)
+	}
+	now := o.now()
+	run.ArchivedAt = &now
+	run.UpdatedAt = now
+	if err := o.store.UpdateRun(ctx, run); err != nil {
+		return types.Run{}, err
+	}
+	return run, nil
+}
+
+// UnarchiveRun restores an archived run to default listings.
+func (o *Orchestrator) UnarchiveRun(ctx context.Context, runID string) (types.Run, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.Run{}, err
+	}
+	if !run.Archived() {
+		return run, nil
+	}
+	run.ArchivedAt = nil
+	run.UpdatedAt = o.now()
+	if err := o.store.UpdateRun(ctx, run); err != nil {
+		return types.Run{}, err
+	}
+	return run, nil
+}
+
+// PurgeArchivedMetrics drops the heartbeat history of runs archived longer than
+// the archived-metrics retention, returning the number of points removed.
+func (o *Orchestrator) PurgeArchivedMetrics(ctx context.Context) (int, error) {
+	if o.archivedMetricsRetention <= 0 {
+		return 0, nil
+	}
+	filter := storage.RunFilter{ArchivedBefore: o.now().Add(-o.archivedMetricsRetention)}
+	removed := 0
+	for {
+		runs, next, err := o.store.ListRuns(ctx, filter)
+		if err != nil {
+			return removed, err
+		}
+		for _, run := range runs {
+			n, err := o.store.DeleteMetricPoints(ctx, run.ID)
+			if err != nil {
+				return removed, err
+			}
+			removed += n
+		}
+		if next.IsZero() {
+			return removed, nil
+		}
+		filter.After = next
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/artifacts"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// ErrArtifactsDisabled is returned by artifact operations when no artifact
+// backend is configured.
+var ErrArtifactsDisabled = errors.New("artifact store is not configured")
+
+const defaultArtifactURLExpiry = 15 * time.Minute
+
+var artifactNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,254}$`)
+
+// CreateArtifactInput describes an artifact a client is about to upload.
+type CreateArtifactInput struct {
+	Name        string             `json:"name"`
+	Kind        types.ArtifactKind `json:"kind"`
+	ContentType string             `json:"content_type,omitempty"`
+}
+
+// CompleteArtifactInput confirms an upload finished.
+type CompleteArtifactInput struct {
+	SizeBytes int64  `json:"size_bytes"`
+	SHA256    string `json:"sha256,omitempty"`
+}
+
+// ArtifactURL pairs an artifact with a pre-signed URL for moving its bytes.
+type ArtifactURL struct {
+	Artifact  types.Artifact `json:"artifact"`
+	Method    string         `json:"method"`
+	URL       string         `json:"url"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+// WithArtifactStore enables artifact uploads and downloads through presigner,
+// issuing URLs valid for urlExpiry (15 minutes when zero).
+func (o *Orchestrator) WithArtifactStore(presigner artifacts.Presigner, urlExpiry time.Duration) {
+	if urlExpiry <= 0 {
+		urlExpiry = defaultArtifactURLExpiry
+	}
+	o.artifacts = presigner
+	o.artifactURLExpiry = urlExpiry
+}
+
+// CreateArtifactUpload records an artifact for a run and returns a pre-signed
+// PUT URL. Asking again for an artifact that has not finished uploading issues
+// a fresh URL, so clients can retry an interrupted upload.
+func (o *Orchestrator) CreateArtifactUpload(ctx context.Context, runID string, input CreateArtifactInput) (ArtifactURL, error) {
+	if o.artifacts == nil {
+		return ArtifactURL{}, ErrArtifactsDisabled
+	}
+	if !artifactNamePattern.MatchString(input.Name) {
+		return ArtifactURL{}, invalidf("name must be 1-255 characters of letters, digits, '.', '_' or '-'")
+	}
+	if input.Kind == "" {
+		input.Kind = types.ArtifactOther
+	}
+	if !input.Kind.Valid() {
+		return ArtifactURL{}, invalidf("unknown artifact kind %q", input.Kind)
+	}
+
+	artifact, err := o.store.GetArtifact(ctx, runID, input.Name)
+	switch {
+	case err == nil:
+		if artifact.Uploaded() {
+			return ArtifactURL{}, fmt.Errorf("%w: artifact %s already uploaded", storage.ErrConflict, input.Name)
+		}
+		if artifact.Kind != input.Kind {
+			return ArtifactURL{}, fmt.Errorf("%w: artifact %s is a %s", storage.ErrConflict, input.Name, artifact.Kind)
+		}
+	case errors.Is(err, storage.ErrNotFound):
+		key := path.Join("runs", runID, string(input.Kind), input.Name)
+		artifact = types.Artifact{
+			RunID:       runID,
+			Name:        input.Name,
+			Kind:        input.Kind,
+			Key:         key,
+			URI:         o.artifacts.URI(key),
+			ContentType: input.ContentType,
+			CreatedAt:   o.now(),
+		}
+		if err := o.store.CreateArtifact(ctx, artifact); err != nil {
+			return ArtifactURL{}, err
+		}
+	default:
+		return ArtifactURL{}, err
+	}
+	return o.presignArtifact(artifact, "PUT")
+}
+
+// CompleteArtifactUpload marks an artifact uploaded so it becomes downloadable.
+func (o *Orchestrator) CompleteArtifactUpload(ctx context.Context, runID, name string, input CompleteArtifactInput) (types.Artifact, error) {
+	if input.SizeBytes < 0 {
+		return types.Artifact{}, invalidf("size_bytes must not be negative")
+	}
+	artifact, err := o.store.GetArtifact(ctx, runID, name)
+	if err != nil {
+		return types.Artifact{}, err
+	}
+	if artifact.Uploaded() {
+		return artifact, nil
+	}
+	now := o.now()
+	artifact.UploadedAt = &now
+	artifact.SizeBytes = input.SizeBytes
+	artifact.SHA256 = input.SHA256
+	if err := o.store.SaveArtifact(ctx, artifact); err != nil {
+		return types.Artifact{}, err
+	}
+	o.logger.Info().Str("run_id", runID).Str("artifact", name).Str("uri", artifact.URI).Msg("artifact uploaded")
+	return artifact, nil
+}
+
+// ArtifactDownload returns a pre-signed GET URL for an uploaded artifact.
+func (o *Orchestrator) ArtifactDownload(ctx context.Context, runID, name string) (ArtifactURL, error) {
+	if o.artifacts == nil {
+		return ArtifactURL{}, ErrArtifactsDisabled
+	}
+	artifact, err := o.store.GetArtifact(ctx, runID, name)
+	if err != nil {
+		return ArtifactURL{}, err
+	}
+	if !artifact.Uploaded() {
+		return ArtifactURL{}, fmt.Errorf("%w: artifact %s has not finished uploading", storage.ErrConflict, name)
+	}
+	return o.presignArtifact(artifact, "GET")
+}
+
+// GetArtifact fetches an artifact's metadata.
+func (o *Orchestrator) GetArtifact(ctx context.Context, runID, name string) (types.Artifact, error) {
+	return o.store.GetArtifact(ctx, runID, name)
+}
+
+// ListArtifacts returns a run's artifacts ordered by name.
+func (o *Orchestrator) ListArtifacts(ctx context.Context, runID string) ([]types.Artifact, error) {
+	return o.store.ListArtifacts(ctx, runID)
+}
+
+func (o *Orchestrator) presignArtifact(artifact types.Artifact, method string) (ArtifactURL, error) {
+	expiresAt := o.now().Add(o.artifactURLExpiry)
+	var (
+		url string
+		err error
+	)
+	if method == "PUT" {
+		url, err = o.artifacts.PresignPut(artifact.Key, o.artifactURLExpiry)
+	} else {
+		url, err = o.artifacts.PresignGet(artifact.Key, o.artifactURLExpiry)
+	}
+	if err != nil {
+		return ArtifactURL{}, err
+	}
+	return ArtifactURL{Artifact: artifact, Method: method, URL: url, ExpiresAt: expiresAt}, nil
+}
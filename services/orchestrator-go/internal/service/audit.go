@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// RecordAudit appends an entry to the API audit log.
+func (o *Orchestrator) RecordAudit(ctx context.Context, entry types.AuditEntry) error {
+	return o.store.AppendAudit(ctx, entry)
+}
+
+// ListAudit returns audit entries oldest first with a cursor for the next page.
+func (o *Orchestrator) ListAudit(ctx context.Context, filter storage.AuditFilter) ([]types.AuditEntry, storage.Cursor, error) {
+	return o.store.ListAudit(ctx, filter)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// RegisterCheckpointInput captures a checkpoint reported by a learner.
+type RegisterCheckpointInput struct {
+	Version    int64              `json:"version"`
+	Step       int64              `json:"step"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"`
+	StorageURI string             `json:"storage_uri"`
+}
+
+// RegisterCheckpoint records a checkpoint for a run and advances the run's
+// checkpoint_version when it is the newest one.
+func (o *Orchestrator) RegisterCheckpoint(ctx context.Context, runID string, input RegisterCheckpointInput) (types.Checkpoint, error) {
+	if input.Version <= 0 {
+		return types.Checkpoint{}, invalidf("version must be positive")
+	}
+	if input.Step < 0 {
+		return types.Checkpoint{}, invalidf("step must not be negative")
+	}
+	if input.StorageURI == "" {
+		return types.Checkpoint{}, invalidf("storage_uri is required")
+	}
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.Checkpoint{}, err
+	}
+	now := o.now()
+	checkpoint := types.Checkpoint{
+		RunID:      runID,
+		Version:    input.Version,
+		Step:       input.Step,
+		Metrics:    input.Metrics,
+		StorageURI: input.StorageURI,
+		CreatedAt:  now,
+	}
+	if err := o.store.CreateCheckpoint(ctx, checkpoint); err != nil {
+		return types.Checkpoint{}, err
+	}
+	if checkpoint.Version > run.CheckpointVersion {
+		run.CheckpointVersion = checkpoint.Version
+		run.UpdatedAt = now
+		if err := o.store.UpdateRun(ctx, run); err != nil {
+			o.logger.Error().Err(err).Str("run_id", runID).Msg("failed to advance checkpoint version")
+		}
+	}
+	return checkpoint, nil
+}
+
+// ListCheckpoints returns a run's checkpoints in ascending version order.
+func (o *Orchestrator) ListCheckpoints(ctx context.Context, runID string) ([]types.Checkpoint, error) {
+	return o.store.ListCheckpoints(ctx, runID)
+}
+
+// GetCheckpoint fetches a checkpoint by run and version.
+func (o *Orchestrator) GetCheckpoint(ctx context.Context, runID string, version int64) (types.Checkpoint, error) {
+	return o.store.GetCheckpoint(ctx, runID, version)
+}
+
+// LatestCheckpoint returns the run's highest-version checkpoint.
+func (o *Orchestrator) LatestCheckpoint(ctx context.Context, runID string) (types.Checkpoint, error) {
+	checkpoints, err := o.store.ListCheckpoints(ctx, runID)
+	if err != nil {
+		return types.Checkpoint{}, err
+	}
+	if len(checkpoints) == 0 {
+		return types.Checkpoint{}, fmt.Errorf("%w: run %s has no checkpoints", storage.ErrNotFound, runID)
+	}
+	return checkpoints[len(checkpoints)-1], nil
+}
+
+// BestCheckpoint returns the checkpoint with the highest (or, when minimize is set,
+// lowest) value of metric. Checkpoints without the metric are ignored; ties go to
+// the newer version.
+func (o *Orchestrator) BestCheckpoint(ctx context.Context, runID, metric string, minimize bool) (types.Checkpoint, error) {
+	if metric == "" {
+		return types.Checkpoint{}, invalidf("metric is required")
+	}
+	checkpoints, err := o.store.ListCheckpoints(ctx, runID)
+	if err != nil {
+		return types.Checkpoint{}, err
+	}
+	var (
+		best  types.Checkpoint
+		found bool
+	)
+	for _, checkpoint := range checkpoints {
+		value, ok := checkpoint.Metrics[metric]
+		if !ok {
+			continue
+		}
+		current := best.Metrics[metric]
+		if !found || (minimize && value <= current) || (!minimize && value >= current) {
+			best, found = checkpoint, true
+		}
+	}
+	if !found {
+		return types.Checkpoint{}, fmt.Errorf("%w: no checkpoint of run %s reports %q", storage.ErrNotFound, runID, metric)
+	}
+	return best, nil
+}
+
+// PromoteCheckpoint marks a checkpoint as the run's promoted model, replacing any
+// previously promoted checkpoint.
+func (o *Orchestrator) PromoteCheckpoint(ctx context.Context, runID string, version int64, promotedBy string) (types.Checkpoint, error) {
+	checkpoint, err := o.store.PromoteCheckpoint(ctx, runID, version, promotedBy, o.now())
+	if err != nil {
+		return types.Checkpoint{}, err
+	}
+	o.logger.Info().Str("run_id", runID).Int64("version", version).Str("promoted_by", promotedBy).Msg("checkpoint promoted")
+	return checkpoint, nil
+}
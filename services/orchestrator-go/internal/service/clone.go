@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// CloneRunInput describes a run to create from an existing one. Fields left
+// empty are carried over from the source run.
+type CloneRunInput struct {
+	ID string `json:"id"`
+
+	// ManifestPatch is a JSON merge patch (RFC 7386) applied to the source
+	// run's launch manifest: keys set to null are removed, objects are merged
+	// recursively and any other value replaces the original.
+	ManifestPatch json.RawMessage `json:"manifest_patch,omitempty"`
+
+	Overrides json.RawMessage   `json:"overrides,omitempty"`
+	Priority  *int              `json:"priority,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	CreatedBy string            `json:"created_by"`
+}
+
+// CloneRun creates a new queued run from sourceID's experiment, version and
+// launch manifest, recording the source as its lineage. The clone goes through
+// the same checks as CreateRun, so it is rejected if the experiment has since
+// been archived or the patched manifest no longer validates. The max duration
+// is carried over; dependencies and schedule ownership are not.
+func (o *Orchestrator) CloneRun(ctx context.Context, sourceID string, input CloneRunInput) (types.Run, error) {
+	source, err := o.store.GetRun(ctx, sourceID)
+	if err != nil {
+		return types.Run{}, err
+	}
+	manifest := source.LaunchManifest
+	if len(input.ManifestPatch) > 0 {
+		manifest, err = mergePatch(manifest, input.ManifestPatch)
+		if err != nil {
+			return types.Run{}, invalidf("manifest_patch: %v", err)
+		}
+	}
+	create := CreateRunInput{
+		ID:             input.ID,
+		ExperimentID:   source.ExperimentID,
+		VersionID:      source.VersionID,
+		LaunchManifest: manifest,
+		Overrides:      source.Overrides,
+		Priority:       source.Priority,
+		CreatedBy:      input.CreatedBy,
+		Labels:         source.Labels,
+		ClonedFrom:     source.ID,
+	}
+	if source.MaxDurationSeconds > 0 {
+		create.MaxDuration = (time.Duration(source.MaxDurationSeconds) * time.Second).String()
+		create.MaxDurationAction = source.MaxDurationAction
+	}
+	if len(input.Overrides) > 0 {
+		create.Overrides = input.Overrides
+	}
+	if input.Priority != nil {
+		create.Priority = *input.Priority
+	}
+	if input.Labels != nil {
+		create.Labels = input.Labels
+	}
+	return o.CreateRun(ctx, create)
+}
+
+// mergePatch applies an RFC 7386 JSON merge patch to doc.
+func mergePatch(doc, patch json.RawMessage) (json.RawMessage, error) {
+	var target, p any
+	if len(bytes.TrimSpace(doc)) > 0 {
+		if err := json.Unmarshal(doc, &target); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergeValue(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cartridge/orchestrator/internal/correlation"
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// WithCommandBus pushes each queued command to bus as well as leaving it for the
+// HTTP pull path. A pushed command counts as delivered, so it must still be
+// acknowledged: one that is not is requeued after the ack timeout and offered
+// on both paths again. Learners must therefore skip command IDs they have
+// already executed. Push delivery relies on redelivery being enabled.
+func (o *Orchestrator) WithCommandBus(bus events.CommandBus) {
+	o.commandBus = bus
+}
+
+// pushCommands delivers a run's pending commands over the command bus, if one
+// is set, in the order the pull path would, and returns those pushed. Each is
+// marked delivered before it is published so that a concurrent poll does not
+// hand it out too; one the bus rejects is returned to the queue and stops the
+// push, keeping later commands behind it.
+func (o *Orchestrator) pushCommands(ctx context.Context, runID string) []types.RunCommand {
+	if o.commandBus == nil {
+		return nil
+	}
+	var pushed []types.RunCommand
+	for {
+		cmd, err := o.store.DeliverNextCommand(ctx, runID, o.now(), o.serialCommands)
+		if errors.Is(err, storage.ErrNoCommands) {
+			return pushed
+		}
+		if err != nil {
+			o.logger.Error().Err(err).Str("run_id", runID).Msg("failed to claim command for push")
+			return pushed
+		}
+		if err := o.commandBus.DeliverCommand(ctx, cmd); err != nil {
+			o.logger.Warn().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to push command; leaving it for polling")
+			cmd.DeliveredAt = nil
+			cmd.DeliveryAttempts--
+			if err := o.store.SaveCommand(ctx, cmd); err != nil {
+				o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to requeue undelivered command")
+			}
+			o.signals.notify(cmd.RunID)
+			return pushed
+		}
+		if err := o.events.PublishCommandEvent(ctx, events.CommandEvent{
+			RunID:         cmd.RunID,
+			CommandID:     cmd.ID,
+			Type:          string(cmd.Type),
+			Event:         "delivered",
+			Description:   "pushed to the command bus",
+			CorrelationID: correlation.FromContext(ctx),
+		}); err != nil {
+			o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to publish delivery event")
+		}
+		pushed = append(pushed, cmd)
+	}
+}
+
+// commandSettled is called once a delivered command is acknowledged. Under
+// serial delivery that releases the run's next command, so waiting pollers are
+// woken and the command bus is offered it.
+func (o *Orchestrator) commandSettled(ctx context.Context, runID string) {
+	if !o.serialCommands {
+		return
+	}
+	o.signals.notify(runID)
+	o.pushCommands(ctx, runID)
+}
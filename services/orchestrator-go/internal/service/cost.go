@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/correlation"
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// WithCostRates prices run accounting at the given hourly rate per manifest
+// resources.class. Runs of classes without a rate cost nothing.
+func (o *Orchestrator) WithCostRates(rates map[string]float64) {
+	o.costRates = rates
+}
+
+// RunCost returns the run's resource accounting so far.
+func (o *Orchestrator) RunCost(ctx context.Context, runID string) (types.RunCost, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.RunCost{}, err
+	}
+	return o.costOf(run, o.now()), nil
+}
+
+// ExperimentCost totals the accounting of the experiment's runs, including
+// archived ones since their resources were spent all the same.
+func (o *Orchestrator) ExperimentCost(ctx context.Context, experimentID string) (types.ExperimentCost, error) {
+	runs, err := o.ListExperimentRuns(ctx, experimentID, true)
+	if err != nil {
+		return types.ExperimentCost{}, err
+	}
+	now := o.now()
+	total := types.ExperimentCost{
+		ExperimentID:    experimentID,
+		Runs:            len(runs),
+		ByResourceClass: make(map[string]types.ResourceClassCost),
+	}
+	for _, run := range runs {
+		cost := o.costOf(run, now)
+		total.DurationSeconds += cost.DurationSeconds
+		total.Steps += cost.Steps
+		total.Samples += cost.Samples
+		total.Cost += cost.Cost
+		class := total.ByResourceClass[cost.ResourceClass]
+		class.Runs++
+		class.DurationSeconds += cost.DurationSeconds
+		class.Cost += cost.Cost
+		total.ByResourceClass[cost.ResourceClass] = class
+	}
+	return total, nil
+}
+
+func (o *Orchestrator) costOf(run types.Run, now time.Time) types.RunCost {
+	req := types.RequirementsFromManifest(run.LaunchManifest)
+	cost := types.RunCost{
+		RunID:         run.ID,
+		ExperimentID:  run.ExperimentID,
+		State:         run.State,
+		ResourceClass: req.ResourceClass,
+		GPUs:          req.GPUs,
+		StartedAt:     run.StartedAt,
+		EndedAt:       run.EndedAt,
+		Steps:         run.CurrentStep,
+		Samples:       run.SamplesProcessed,
+		HourlyRate:    o.costRates[req.ResourceClass],
+		Final:         run.State.IsTerminal(),
+	}
+	if run.StartedAt != nil {
+		end := now
+		if run.EndedAt != nil {
+			end = *run.EndedAt
+		}
+		if end.After(*run.StartedAt) {
+			cost.DurationSeconds = end.Sub(*run.StartedAt).Seconds()
+		}
+	}
+	cost.Cost = cost.DurationSeconds / time.Hour.Seconds() * cost.HourlyRate
+	return cost
+}
+
+// publishAccounting emits the final accounting of a run that just ended.
+func (o *Orchestrator) publishAccounting(ctx context.Context, run types.Run) {
+	cost := o.costOf(run, o.now())
+	event := events.AccountingEvent{
+		RunID:           cost.RunID,
+		ExperimentID:    cost.ExperimentID,
+		State:           string(cost.State),
+		ResourceClass:   cost.ResourceClass,
+		GPUs:            cost.GPUs,
+		StartedAt:       cost.StartedAt,
+		EndedAt:         cost.EndedAt,
+		DurationSeconds: cost.DurationSeconds,
+		Steps:           cost.Steps,
+		Samples:         cost.Samples,
+		HourlyRate:      cost.HourlyRate,
+		Cost:            cost.Cost,
+		CorrelationID:   correlation.FromContext(ctx),
+	}
+	if err := o.events.PublishAccountingEvent(ctx, event); err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to publish accounting event")
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/correlation"
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// RequeueCommandInput describes how a dead-lettered command is retried.
+type RequeueCommandInput struct {
+	// ExpiresAt sets a new TTL. When omitted, an expiry that has already passed
+	// is cleared so the command does not expire again immediately.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ListDeadLetters returns a page of commands that expired or exhausted their
+// delivery attempts and have not been requeued or discarded, oldest first. An
+// empty runID lists dead letters across every run.
+func (o *Orchestrator) ListDeadLetters(ctx context.Context, runID string, filter storage.CommandFilter) ([]types.RunCommand, storage.Cursor, error) {
+	filter.Statuses = types.DeadLetterStatuses
+	return o.store.ListCommands(ctx, runID, filter)
+}
+
+// RequeueCommand returns a dead-lettered command to the pending queue with a
+// fresh delivery budget. Commands for runs that have already ended cannot be
+// requeued since no learner will fetch them.
+func (o *Orchestrator) RequeueCommand(ctx context.Context, runID, commandID string, input RequeueCommandInput) (types.RunCommand, error) {
+	cmd, err := o.deadLetter(ctx, runID, commandID)
+	if err != nil {
+		return types.RunCommand{}, err
+	}
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.RunCommand{}, err
+	}
+	if run.State.IsTerminal() {
+		return types.RunCommand{}, fmt.Errorf("%w: run %s is %s", storage.ErrConflict, runID, run.State)
+	}
+	now := o.now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return types.RunCommand{}, invalidf("expires_at must be in the future")
+	}
+	attempts := cmd.DeliveryAttempts
+	cmd.DeliveredAt = nil
+	cmd.AcknowledgedAt = nil
+	cmd.ExpiredAt = nil
+	cmd.DeadLetteredAt = nil
+	cmd.DeliveryAttempts = 0
+	switch {
+	case input.ExpiresAt != nil:
+		cmd.ExpiresAt = input.ExpiresAt
+	case cmd.ExpiresAt != nil && !cmd.ExpiresAt.After(now):
+		cmd.ExpiresAt = nil
+	}
+	if err := o.store.SaveCommand(ctx, cmd); err != nil {
+		return types.RunCommand{}, err
+	}
+	o.signals.notify(cmd.RunID)
+	if err := o.events.PublishCommandEvent(ctx, events.CommandEvent{
+		RunID:         cmd.RunID,
+		CommandID:     cmd.ID,
+		Type:          string(cmd.Type),
+		Event:         "requeued",
+		Description:   fmt.Sprintf("requeued from the dead-letter queue after %d delivery attempts", attempts),
+		CorrelationID: correlation.FromContext(ctx),
+	}); err != nil {
+		o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to publish requeue event")
+	}
+	for _, pushed := range o.pushCommands(ctx, cmd.RunID) {
+		if pushed.ID == cmd.ID {
+			return pushed, nil
+		}
+	}
+	return cmd, nil
+}
+
+// DiscardCommand removes a command from the dead-letter queue without retrying
+// it. The record is kept for auditing.
+func (o *Orchestrator) DiscardCommand(ctx context.Context, runID, commandID string) (types.RunCommand, error) {
+	cmd, err := o.deadLetter(ctx, runID, commandID)
+	if err != nil {
+		return types.RunCommand{}, err
+	}
+	now := o.now()
+	cmd.DiscardedAt = &now
+	if err := o.store.SaveCommand(ctx, cmd); err != nil {
+		return types.RunCommand{}, err
+	}
+	if err := o.events.PublishCommandEvent(ctx, events.CommandEvent{
+		RunID:         cmd.RunID,
+		CommandID:     cmd.ID,
+		Type:          string(cmd.Type),
+		Event:         "discarded",
+		CorrelationID: correlation.FromContext(ctx),
+	}); err != nil {
+		o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to publish discard event")
+	}
+	return cmd, nil
+}
+
+// deadLetter loads a command and checks that it is in the dead-letter queue.
+func (o *Orchestrator) deadLetter(ctx context.Context, runID, commandID string) (types.RunCommand, error) {
+	cmd, err := o.store.GetCommand(ctx, runID, commandID)
+	if err != nil {
+		return types.RunCommand{}, err
+	}
+	if !cmd.DeadLettered() {
+		return types.RunCommand{}, fmt.Errorf("%w: command %s is %s, not dead-lettered", storage.ErrConflict, commandID, cmd.Status())
+	}
+	return cmd, nil
+}
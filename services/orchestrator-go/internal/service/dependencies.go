@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// maxRunDependencies bounds how many prerequisites a single run may declare.
+const maxRunDependencies = 64
+
+// checkRunDependencies validates a new run's prerequisites. They must already
+// exist, so dependency graphs are acyclic by construction, and none may have
+// ended without completing.
+func (o *Orchestrator) checkRunDependencies(ctx context.Context, input *CreateRunInput) error {
+	if len(input.DependsOn) > maxRunDependencies {
+		return invalidf("a run may depend on at most %d runs", maxRunDependencies)
+	}
+	seen := make(map[string]bool, len(input.DependsOn))
+	deduped := input.DependsOn[:0]
+	for _, id := range input.DependsOn {
+		switch {
+		case id == "":
+			return invalidf("depends_on entries must not be empty")
+		case id == input.ID:
+			return invalidf("a run cannot depend on itself")
+		case seen[id]:
+			continue
+		}
+		seen[id] = true
+		dependency, err := o.store.GetRun(ctx, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return invalidf("dependency %s does not exist", id)
+		}
+		if err != nil {
+			return err
+		}
+		if dependency.State.IsTerminal() && dependency.State != types.RunStateCompleted {
+			return fmt.Errorf("%w: dependency %s ended %s", storage.ErrConflict, id, dependency.State)
+		}
+		deduped = append(deduped, id)
+	}
+	input.DependsOn = deduped
+	return nil
+}
+
+// dependencyStatus reports which of a run's prerequisites have not completed
+// yet, and the first one that ended unsuccessfully, if any.
+func (o *Orchestrator) dependencyStatus(ctx context.Context, run types.Run) (waiting []string, failed *types.Run, err error) {
+	for _, id := range run.DependsOn {
+		dependency, err := o.store.GetRun(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case dependency.State == types.RunStateCompleted:
+		case dependency.State.IsTerminal():
+			if failed == nil {
+				failed = &dependency
+			}
+		default:
+			waiting = append(waiting, id)
+		}
+	}
+	return waiting, failed, nil
+}
+
+// requireDependenciesMet rejects provisioning a run before its prerequisites complete.
+func (o *Orchestrator) requireDependenciesMet(ctx context.Context, run types.Run) error {
+	waiting, failed, err := o.dependencyStatus(ctx, run)
+	switch {
+	case err != nil:
+		return err
+	case failed != nil:
+		return fmt.Errorf("%w: dependency %s ended %s", storage.ErrConflict, failed.ID, failed.State)
+	case len(waiting) > 0:
+		return fmt.Errorf("%w: run %s is waiting on %s", storage.ErrConflict, run.ID, strings.Join(waiting, ", "))
+	}
+	return nil
+}
+
+// holdForDependencies reports whether the dispatcher must leave a queued run
+// alone. A run whose prerequisite ended without completing can never start, so
+// it is failed instead.
+func (o *Orchestrator) holdForDependencies(ctx context.Context, run types.Run) bool {
+	if len(run.DependsOn) == 0 {
+		return false
+	}
+	waiting, failed, err := o.dependencyStatus(ctx, run)
+	if err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to check run dependencies")
+		return true
+	}
+	if failed != nil {
+		if _, err := o.transition(ctx, run, TransitionInput{
+			ToState:   types.RunStateFailed,
+			ChangedBy: dispatchActor,
+			Reason:    fmt.Sprintf("dependency %s ended %s", failed.ID, failed.State),
+		}); err != nil {
+			o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to fail run with unmet dependency")
+		}
+		return true
+	}
+	return len(waiting) > 0
+}
+
+// RunGraph returns the dependency DAG around a run: every transitive
+// prerequisite and dependent, ordered so prerequisites precede their dependents.
+func (o *Orchestrator) RunGraph(ctx context.Context, runID string) (types.RunGraph, error) {
+	root, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.RunGraph{}, err
+	}
+	runs := map[string]types.Run{root.ID: root}
+	// Walk prerequisites and dependents separately so siblings are not pulled in.
+	for _, upstream := range []bool{true, false} {
+		frontier := []types.Run{root}
+		for len(frontier) > 0 {
+			run := frontier[0]
+			frontier = frontier[1:]
+			var next []types.Run
+			if upstream {
+				for _, id := range run.DependsOn {
+					dependency, err := o.store.GetRun(ctx, id)
+					if err != nil {
+						return types.RunGraph{}, err
+					}
+					next = append(next, dependency)
+				}
+			} else if next, err = o.store.ListDependentRuns(ctx, run.ID); err != nil {
+				return types.RunGraph{}, err
+			}
+			for _, candidate := range next {
+				if _, seen := runs[candidate.ID]; !seen {
+					runs[candidate.ID] = candidate
+					frontier = append(frontier, candidate)
+				}
+			}
+		}
+	}
+
+	graph := types.RunGraph{RunID: runID, Nodes: make([]types.RunGraphNode, 0, len(runs)), Edges: make([]types.RunGraphEdge, 0)}
+	placed := make(map[string]bool, len(runs))
+	var place func(run types.Run) error
+	place = func(run types.Run) error {
+		if placed[run.ID] {
+			return nil
+		}
+		placed[run.ID] = true
+		for _, id := range run.DependsOn {
+			if dependency, ok := runs[id]; ok {
+				if err := place(dependency); err != nil {
+					return err
+				}
+				graph.Edges = append(graph.Edges, types.RunGraphEdge{From: id, To: run.ID})
+			}
+		}
+		node := types.RunGraphNode{ID: run.ID, ExperimentID: run.ExperimentID, State: run.State, DependsOn: run.DependsOn}
+		if run.State == types.RunStateQueued {
+			waiting, _, err := o.dependencyStatus(ctx, run)
+			if err != nil {
+				return err
+			}
+			node.WaitingOn = waiting
+		}
+		graph.Nodes = append(graph.Nodes, node)
+		return nil
+	}
+	ordered := make([]types.Run, 0, len(runs))
+	for _, run := range runs {
+		ordered = append(ordered, run)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if !ordered[i].CreatedAt.Equal(ordered[j].CreatedAt) {
+			return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+	for _, run := range ordered {
+		if err := place(run); err != nil {
+			return types.RunGraph{}, err
+		}
+	}
+	return graph, nil
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// dispatchActor is recorded as the initiator of dispatcher transitions.
+const dispatchActor = "dispatcher"
+
+// learnerHeldStates are the run states that occupy a learner slot.
+var learnerHeldStates = []types.RunState{
+	types.RunStateProvisioning,
+	types.RunStateRunning,
+	types.RunStatePaused,
+	types.RunStateTerminating,
+}
+
+// RegisterLearnerInput captures a learner's registration.
+type RegisterLearnerInput struct {
+	ID           string                    `json:"id"`
+	Name         string                    `json:"name"`
+	Slots        int                       `json:"slots"`
+	Capabilities types.LearnerCapabilities `json:"capabilities"`
+	Build        *types.BuildInfo          `json:"build,omitempty"`
+}
+
+// RegisterLearner registers a learner, or refreshes an existing registration.
+func (o *Orchestrator) RegisterLearner(ctx context.Context, input RegisterLearnerInput) (types.Learner, error) {
+	if input.ID == "" {
+		return types.Learner{}, invalidf("id is required")
+	}
+	if input.Slots < 1 {
+		return types.Learner{}, invalidf("slots must be at least 1")
+	}
+	if input.Capabilities.GPUs < 0 || input.Capabilities.MaxBatchSize < 0 {
+		return types.Learner{}, invalidf("capabilities must not be negative")
+	}
+	if input.Build != nil {
+		if err := input.Build.Validate(); err != nil {
+			return types.Learner{}, invalid(err)
+		}
+	}
+	now := o.now()
+	learner, err := o.store.GetLearner(ctx, input.ID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		learner = types.Learner{ID: input.ID, RegisteredAt: now}
+	case err != nil:
+		return types.Learner{}, err
+	}
+	learner.Name = input.Name
+	learner.Slots = input.Slots
+	learner.Capabilities = input.Capabilities
+	learner.Build = input.Build
+	learner.LastSeenAt = now
+	if err := o.store.SaveLearner(ctx, learner); err != nil {
+		return types.Learner{}, err
+	}
+	return o.withActiveRuns(ctx, learner)
+}
+
+// LearnerHeartbeat records a learner's current load, and the build it runs if
+// reported, and marks it alive.
+func (o *Orchestrator) LearnerHeartbeat(ctx context.Context, learnerID string, heartbeat types.LearnerHeartbeat) (types.Learner, error) {
+	load := heartbeat.LearnerLoad
+	if err := load.Validate(); err != nil {
+		return types.Learner{}, invalid(err)
+	}
+	if heartbeat.Build != nil {
+		if err := heartbeat.Build.Validate(); err != nil {
+			return types.Learner{}, invalid(err)
+		}
+	}
+	learner, err := o.store.GetLearner(ctx, learnerID)
+	if err != nil {
+		return types.Learner{}, err
+	}
+	now := o.now()
+	load.ReportedAt = now
+	learner.Load = &load
+	if heartbeat.Build != nil {
+		learner.Build = heartbeat.Build
+	}
+	learner.LastSeenAt = now
+	if err := o.store.SaveLearner(ctx, learner); err != nil {
+		return types.Learner{}, err
+	}
+	return o.withActiveRuns(ctx, learner)
+}
+
+// GetLearner returns a learner along with its current slot usage.
+func (o *Orchestrator) GetLearner(ctx context.Context, learnerID string) (types.Learner, error) {
+	learner, err := o.store.GetLearner(ctx, learnerID)
+	if err != nil {
+		return types.Learner{}, err
+	}
+	return o.withActiveRuns(ctx, learner)
+}
+
+// ListLearners returns registered learners along with their slot usage.
+func (o *Orchestrator) ListLearners(ctx context.Context) ([]types.Learner, error) {
+	learners, err := o.store.ListLearners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	active, err := o.activeRunsByLearner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range learners {
+		applyUsage(&learners[i], active[learners[i].ID])
+	}
+	return learners, nil
+}
+
+// DeregisterLearner removes a learner that no longer holds any runs.
+func (o *Orchestrator) DeregisterLearner(ctx context.Context, learnerID string) error {
+	learner, err := o.GetLearner(ctx, learnerID)
+	if err != nil {
+		return err
+	}
+	if learner.ActiveRuns > 0 {
+		return fmt.Errorf("%w: learner %s still holds %d active runs", storage.ErrConflict, learnerID, learner.ActiveRuns)
+	}
+	return o.store.DeleteLearner(ctx, learnerID)
+}
+
+// ListLearnerRuns returns the runs currently assigned to a learner.
+func (o *Orchestrator) ListLearnerRuns(ctx context.Context, learnerID string) ([]types.Run, error) {
+	if _, err := o.store.GetLearner(ctx, learnerID); err != nil {
+		return nil, err
+	}
+	active, err := o.activeRunsByLearner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return active[learnerID], nil
+}
+
+// DispatchQueuedRuns assigns queued runs to live learners that can host them,
+// highest priority first (oldest first within a priority), and moves them to
+// provisioning. A run no learner can currently host, or whose dependencies have
+// not completed, stays queued without blocking smaller runs behind it, unless
+// preemption is enabled and pausing a lower-priority running run makes room.
+func (o *Orchestrator) DispatchQueuedRuns(ctx context.Context) ([]types.Run, error) {
+	queued, err := o.queuedInDispatchOrder(ctx)
+	if err != nil || len(queued) == 0 {
+		return nil, err
+	}
+	learners, err := o.ListLearners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if o.learnerStaleAfter > 0 {
+		cutoff := o.now().Add(-o.learnerStaleAfter)
+		live := learners[:0]
+		for _, learner := range learners {
+			if learner.LastSeenAt.After(cutoff) {
+				live = append(live, learner)
+			}
+		}
+		learners = live
+	}
+	var (
+		dispatched []types.Run
+		candidates []types.Run
+		preempted  int
+	)
+	if o.preemption.MinPriorityGap > 0 {
+		if candidates, err = o.preemptionCandidates(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for _, run := range queued {
+		if o.holdForDependencies(ctx, run) {
+			continue
+		}
+		req := types.RequirementsFromManifest(run.LaunchManifest)
+		learner := pickLearner(learners, req)
+		if learner == nil && len(candidates) > 0 && (o.preemption.MaxPerPass == 0 || preempted < o.preemption.MaxPerPass) {
+			if learner = o.preemptFor(ctx, run, req, learners, &candidates); learner != nil {
+				preempted++
+			}
+		}
+		if learner == nil {
+			continue
+		}
+		run.LearnerID = learner.ID
+		run, err = o.transition(ctx, run, TransitionInput{
+			ToState:   types.RunStateProvisioning,
+			ChangedBy: dispatchActor,
+			Reason:    "assigned to learner " + learner.ID,
+		})
+		if err != nil {
+			o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to dispatch run")
+			continue
+		}
+		learner.ActiveRuns++
+		learner.UsedGPUs += req.GPUs
+		dispatched = append(dispatched, run)
+	}
+	return dispatched, nil
+}
+
+// pickLearner returns the least loaded learner that can host req, or nil.
+func pickLearner(learners []types.Learner, req types.RunRequirements) *types.Learner {
+	var best *types.Learner
+	for i := range learners {
+		candidate := &learners[i]
+		if !candidate.CanHost(req) {
+			continue
+		}
+		if best == nil || lessLoaded(candidate, best) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// lessLoaded prefers more free slots, then lower reported GPU utilisation.
+func lessLoaded(a, b *types.Learner) bool {
+	if a.FreeSlots() != b.FreeSlots() {
+		return a.FreeSlots() > b.FreeSlots()
+	}
+	return gpuUtilization(a) < gpuUtilization(b)
+}
+
+func gpuUtilization(l *types.Learner) float64 {
+	if l.Load == nil {
+		return 0
+	}
+	return l.Load.GPUUtilization
+}
+
+// queuedInDispatchOrder returns queued runs in the order the dispatcher will place them.
+func (o *Orchestrator) queuedInDispatchOrder(ctx context.Context) ([]types.Run, error) {
+	queued, err := o.store.ListRunsByState(ctx, types.RunStateQueued)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		return queued[i].Priority > queued[j].Priority
+	})
+	return queued, nil
+}
+
+// queuePosition returns the 1-based dispatch position of a queued run.
+func (o *Orchestrator) queuePosition(ctx context.Context, runID string) (int, error) {
+	queued, err := o.queuedInDispatchOrder(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, run := range queued {
+		if run.ID == runID {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (o *Orchestrator) withActiveRuns(ctx context.Context, learner types.Learner) (types.Learner, error) {
+	active, err := o.activeRunsByLearner(ctx)
+	if err != nil {
+		return types.Learner{}, err
+	}
+	applyUsage(&learner, active[learner.ID])
+	return learner, nil
+}
+
+func (o *Orchestrator) activeRunsByLearner(ctx context.Context) (map[string][]types.Run, error) {
+	runs, err := o.store.ListRunsByState(ctx, learnerHeldStates...)
+	if err != nil {
+		return nil, err
+	}
+	byLearner := make(map[string][]types.Run)
+	for _, run := range runs {
+		if run.LearnerID != "" && run.PreemptedBy == "" {
+			byLearner[run.LearnerID] = append(byLearner[run.LearnerID], run)
+		}
+	}
+	return byLearner, nil
+}
+
+// applyUsage fills the slot and GPU usage derived from a learner's active runs.
+func applyUsage(learner *types.Learner, runs []types.Run) {
+	learner.ActiveRuns = len(runs)
+	learner.UsedGPUs = 0
+	for _, run := range runs {
+		learner.UsedGPUs += types.RequirementsFromManifest(run.LaunchManifest).GPUs
+	}
+}
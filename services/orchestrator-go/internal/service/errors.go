@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// Error kinds returned by the service, tested with errors.Is. The API maps each
+// onto a status code and a machine-readable error code.
+var (
+	// ErrValidation marks input that is malformed or violates a constraint.
+	ErrValidation = errors.New("validation failed")
+	// ErrRegression marks heartbeat counters that moved backwards.
+	ErrRegression = types.ErrRegression
+	// ErrStateConflict marks requests that conflict with the current state of a
+	// resource, such as a duplicate ID. types.ErrInvalidTransition is reported
+	// the same way.
+	ErrStateConflict = storage.ErrConflict
+	// ErrNotFound marks references to resources that do not exist.
+	ErrNotFound = storage.ErrNotFound
+	// ErrPreconditionFailed marks conditional requests whose resource changed
+	// after the version the caller based them on.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrAdmissionDenied marks runs an admission hook refused.
+	ErrAdmissionDenied = errors.New("admission denied")
+	// ErrAdmissionUnavailable marks runs that could not be admitted because an
+	// admission hook failed to reach a decision.
+	ErrAdmissionUnavailable = errors.New("admission unavailable")
+)
+
+// kindError tags err with an error kind without changing its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// invalid marks err as a validation failure.
+func invalid(err error) error {
+	if err == nil || errors.Is(err, ErrValidation) {
+		return err
+	}
+	return &kindError{kind: ErrValidation, err: err}
+}
+
+// invalidf formats a validation failure.
+func invalidf(format string, args ...any) error {
+	return invalid(fmt.Errorf(format, args...))
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// defaultLeaderboardMetric ranks checkpoints when the caller does not pick a score.
+const defaultLeaderboardMetric = "win_rate"
+
+// LeaderboardQuery selects how an experiment's checkpoints are ranked.
+type LeaderboardQuery struct {
+	Metric   string
+	Suite    string
+	Minimize bool
+	Limit    int
+}
+
+// LeaderboardEntry is one ranked checkpoint. Score averages the metric over every
+// matching evaluation, weighted by episodes (reports without episodes count once).
+type LeaderboardEntry struct {
+	Rank              int     `json:"rank"`
+	RunID             string  `json:"run_id"`
+	CheckpointVersion int64   `json:"checkpoint_version"`
+	Score             float64 `json:"score"`
+	Evaluations       int     `json:"evaluations"`
+	Episodes          int     `json:"episodes"`
+	StorageURI        string  `json:"storage_uri"`
+	Promoted          bool    `json:"promoted"`
+}
+
+// RecordEvaluation stores an evaluation report for a registered checkpoint.
+// Reports are idempotent by ID: resubmitting returns the stored report.
+func (o *Orchestrator) RecordEvaluation(ctx context.Context, evaluation types.Evaluation) (types.Evaluation, error) {
+	if evaluation.ID == "" {
+		return types.Evaluation{}, invalidf("id is required")
+	}
+	if err := evaluation.Validate(); err != nil {
+		return types.Evaluation{}, invalid(err)
+	}
+	if _, err := o.store.GetCheckpoint(ctx, evaluation.RunID, evaluation.CheckpointVersion); err != nil {
+		return types.Evaluation{}, err
+	}
+	evaluation.CreatedAt = o.now()
+	if err := o.store.CreateEvaluation(ctx, evaluation); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return o.store.GetEvaluation(ctx, evaluation.RunID, evaluation.ID)
+		}
+		return types.Evaluation{}, err
+	}
+	o.logger.Info().
+		Str("run_id", evaluation.RunID).
+		Int64("checkpoint_version", evaluation.CheckpointVersion).
+		Str("suite", evaluation.Suite).
+		Msg("evaluation recorded")
+	return evaluation, nil
+}
+
+// ListEvaluations returns a run's evaluation reports oldest first, optionally
+// restricted to one checkpoint version (zero lists all).
+func (o *Orchestrator) ListEvaluations(ctx context.Context, runID string, checkpointVersion int64) ([]types.Evaluation, error) {
+	evaluations, err := o.store.ListEvaluations(ctx, runID)
+	if err != nil || checkpointVersion == 0 {
+		return evaluations, err
+	}
+	filtered := evaluations[:0]
+	for _, evaluation := range evaluations {
+		if evaluation.CheckpointVersion == checkpointVersion {
+			filtered = append(filtered, evaluation)
+		}
+	}
+	return filtered, nil
+}
+
+// Leaderboard ranks the evaluated checkpoints of every run in an experiment by
+// query.Metric, best first. Ties go to the newer checkpoint.
+func (o *Orchestrator) Leaderboard(ctx context.Context, experimentID string, query LeaderboardQuery) ([]LeaderboardEntry, error) {
+	if query.Metric == "" {
+		query.Metric = defaultLeaderboardMetric
+	}
+	runs, err := o.ListExperimentRuns(ctx, experimentID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		runID   string
+		version int64
+	}
+	type tally struct {
+		sum         float64
+		weight      int
+		episodes    int
+		evaluations int
+	}
+	tallies := make(map[key]*tally)
+	for _, run := range runs {
+		evaluations, err := o.store.ListEvaluations(ctx, run.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, evaluation := range evaluations {
+			value, ok := evaluation.Scores[query.Metric]
+			if !ok || (query.Suite != "" && evaluation.Suite != query.Suite) {
+				continue
+			}
+			k := key{evaluation.RunID, evaluation.CheckpointVersion}
+			t, ok := tallies[k]
+			if !ok {
+				t = &tally{}
+				tallies[k] = t
+			}
+			weight := evaluation.Episodes
+			if weight == 0 {
+				weight = 1
+			}
+			t.sum += value * float64(weight)
+			t.weight += weight
+			t.episodes += evaluation.Episodes
+			t.evaluations++
+		}
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(tallies))
+	for k, t := range tallies {
+		checkpoint, err := o.store.GetCheckpoint(ctx, k.runID, k.version)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, LeaderboardEntry{
+			RunID:             k.runID,
+			CheckpointVersion: k.version,
+			Score:             t.sum / float64(t.weight),
+			Evaluations:       t.evaluations,
+			Episodes:          t.episodes,
+			StorageURI:        checkpoint.StorageURI,
+			Promoted:          checkpoint.Promoted,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Score != b.Score {
+			return (a.Score < b.Score) == query.Minimize
+		}
+		if a.CheckpointVersion != b.CheckpointVersion {
+			return a.CheckpointVersion > b.CheckpointVersion
+		}
+		return a.RunID < b.RunID
+	})
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries, nil
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// RunEventLog returns the events published for a run that match filter, oldest
+// first. Events are recorded by the publisher returned from events.Persisting.
+func (o *Orchestrator) RunEventLog(ctx context.Context, runID string, filter storage.EventFilter) ([]types.RunEvent, error) {
+	if filter.After < 0 {
+		return nil, invalidf("after must not be negative")
+	}
+	switch filter.Type {
+	case "", events.EventTypeRunStatus, events.EventTypeCommand, events.EventTypeAccounting, events.EventTypeAlert:
+	default:
+		return nil, invalidf("invalid event type %q", filter.Type)
+	}
+	return o.store.ListRunEvents(ctx, runID, filter)
+}
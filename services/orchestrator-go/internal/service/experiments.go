@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// CreateExperimentInput captures the payload required to create an experiment.
+type CreateExperimentInput struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	Description      string          `json:"description,omitempty"`
+	Config           json.RawMessage `json:"config,omitempty"`
+	AllowedOverrides []string        `json:"allowed_overrides,omitempty"`
+	CreatedBy        string          `json:"created_by"`
+}
+
+// CreateExperiment persists a new experiment template.
+func (o *Orchestrator) CreateExperiment(ctx context.Context, input CreateExperimentInput) (types.Experiment, error) {
+	if input.ID == "" || input.Name == "" {
+		return types.Experiment{}, invalidf("id and name are required")
+	}
+	now := o.now()
+	experiment := types.Experiment{
+		ID:               input.ID,
+		Name:             input.Name,
+		Description:      input.Description,
+		Config:           input.Config,
+		AllowedOverrides: input.AllowedOverrides,
+		CreatedBy:        input.CreatedBy,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := o.store.CreateExperiment(ctx, experiment); err != nil {
+		return types.Experiment{}, err
+	}
+	return experiment, nil
+}
+
+// GetExperiment returns an experiment along with run counts by state.
+func (o *Orchestrator) GetExperiment(ctx context.Context, experimentID string) (types.ExperimentSummary, error) {
+	experiment, err := o.store.GetExperiment(ctx, experimentID)
+	if err != nil {
+		return types.ExperimentSummary{}, err
+	}
+	runs, err := o.store.ListRunsByExperiment(ctx, experimentID)
+	if err != nil {
+		return types.ExperimentSummary{}, err
+	}
+	summary := types.ExperimentSummary{
+		Experiment: experiment,
+		TotalRuns:  len(runs),
+		RunCounts:  make(map[types.RunState]int),
+	}
+	for _, run := range runs {
+		summary.RunCounts[run.State]++
+	}
+	return summary, nil
+}
+
+// ListExperiments returns experiments, hiding archived ones unless requested.
+func (o *Orchestrator) ListExperiments(ctx context.Context, includeArchived bool) ([]types.Experiment, error) {
+	return o.store.ListExperiments(ctx, includeArchived)
+}
+
+// ArchiveExperiment hides an experiment from default listings and blocks new runs against it.
+func (o *Orchestrator) ArchiveExperiment(ctx context.Context, experimentID string) (types.Experiment, error) {
+	experiment, err := o.store.GetExperiment(ctx, experimentID)
+	if err != nil {
+		return types.Experiment{}, err
+	}
+	if experiment.Archived() {
+		return experiment, nil
+	}
+	now := o.now()
+	experiment.ArchivedAt = &now
+	experiment.UpdatedAt = now
+	if err := o.store.UpdateExperiment(ctx, experiment); err != nil {
+		return types.Experiment{}, err
+	}
+	return experiment, nil
+}
+
+// ListExperimentRuns returns the runs launched from an experiment, hiding archived
+// ones unless requested.
+func (o *Orchestrator) ListExperimentRuns(ctx context.Context, experimentID string, includeArchived bool) ([]types.Run, error) {
+	if _, err := o.store.GetExperiment(ctx, experimentID); err != nil {
+		return nil, err
+	}
+	runs, err := o.store.ListRunsByExperiment(ctx, experimentID)
+	if err != nil || includeArchived {
+		return runs, err
+	}
+	visible := runs[:0]
+	for _, run := range runs {
+		if !run.Archived() {
+			visible = append(visible, run)
+		}
+	}
+	return visible, nil
+}
+
+// checkExperimentAcceptsRuns rejects new runs against archived experiments. Runs may still
+// reference experiments that were never registered.
+func (o *Orchestrator) checkExperimentAcceptsRuns(ctx context.Context, experimentID string) error {
+	experiment, err := o.store.GetExperiment(ctx, experimentID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if experiment.Archived() {
+		return fmt.Errorf("%w: experiment %s is archived", storage.ErrConflict, experimentID)
+	}
+	return nil
+}
+
+// ExperimentStats rolls up the runs of an experiment. Health counts only cover
+// runs that have not ended. TotalSamples is estimated from each run's heartbeat
+// history by integrating samples_per_sec between consecutive heartbeats.
+type ExperimentStats struct {
+	ExperimentID  string                  `json:"experiment_id"`
+	Runs          int                     `json:"runs"`
+	ByState       map[types.RunState]int  `json:"by_state"`
+	ByHealth      map[types.RunHealth]int `json:"by_health"`
+	BestLoss      *float64                `json:"best_loss,omitempty"`
+	BestLossRunID string                  `json:"best_loss_run_id,omitempty"`
+	TotalSteps    int64                   `json:"total_steps"`
+	TotalSamples  int64                   `json:"total_samples"`
+}
+
+// ExperimentStats aggregates the experiment's runs by state and health along with
+// the lowest loss any run reported and the steps and samples trained in total.
+func (o *Orchestrator) ExperimentStats(ctx context.Context, experimentID string) (ExperimentStats, error) {
+	runs, err := o.ListExperimentRuns(ctx, experimentID, true)
+	if err != nil {
+		return ExperimentStats{}, err
+	}
+	stats := ExperimentStats{
+		ExperimentID: experimentID,
+		Runs:         len(runs),
+		ByState:      make(map[types.RunState]int),
+		ByHealth:     make(map[types.RunHealth]int),
+	}
+	for _, run := range runs {
+		stats.ByState[run.State]++
+		if !run.State.IsTerminal() {
+			stats.ByHealth[run.HealthStatus]++
+		}
+		stats.TotalSteps += run.CurrentStep
+		if run.LastHeartbeatAt == nil {
+			continue
+		}
+		points, err := o.store.ListMetricPoints(ctx, run.ID, time.Time{}, time.Time{})
+		if err != nil {
+			return ExperimentStats{}, err
+		}
+		// The latest heartbeat's loss counts even when older history was pruned.
+		stats.observeLoss(run.ID, run.Loss)
+		var samples float64
+		for i, point := range points {
+			stats.observeLoss(run.ID, point.Loss)
+			if i > 0 {
+				samples += point.SamplesPerSecond * point.At.Sub(points[i-1].At).Seconds()
+			}
+		}
+		stats.TotalSamples += int64(math.Round(samples))
+	}
+	return stats, nil
+}
+
+func (s *ExperimentStats) observeLoss(runID string, loss float64) {
+	if math.IsNaN(loss) || math.IsInf(loss, 0) {
+		return
+	}
+	if s.BestLoss == nil || loss < *s.BestLoss {
+		s.BestLoss = &loss
+		s.BestLossRunID = runID
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// runBundleFormat versions the export layout so importers can reject bundles
+// they do not understand.
+const runBundleFormat = 1
+
+// RunBundle is a self-contained export of a run's record and history.
+type RunBundle struct {
+	Format      int                     `json:"format"`
+	ExportedAt  time.Time               `json:"exported_at"`
+	Run         types.Run               `json:"run"`
+	Transitions []storage.RunTransition `json:"transitions"`
+	Commands    []types.RunCommand      `json:"commands"`
+	Metrics     []types.MetricPoint     `json:"metrics"`
+}
+
+// ExportRun collects a run's record, transitions, commands and full heartbeat
+// metrics history into one bundle.
+func (o *Orchestrator) ExportRun(ctx context.Context, runID string) (RunBundle, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return RunBundle{}, err
+	}
+	transitions, err := o.store.ListTransitions(ctx, runID)
+	if err != nil {
+		return RunBundle{}, err
+	}
+	commands, _, err := o.store.ListCommands(ctx, runID, storage.CommandFilter{})
+	if err != nil {
+		return RunBundle{}, err
+	}
+	metrics, err := o.store.ListMetricPoints(ctx, runID, time.Time{}, time.Time{})
+	if err != nil {
+		return RunBundle{}, err
+	}
+	bundle := RunBundle{
+		Format:      runBundleFormat,
+		ExportedAt:  o.now(),
+		Run:         run,
+		Transitions: transitions,
+		Commands:    commands,
+		Metrics:     metrics,
+	}
+	if bundle.Transitions == nil {
+		bundle.Transitions = []storage.RunTransition{}
+	}
+	if bundle.Commands == nil {
+		bundle.Commands = []types.RunCommand{}
+	}
+	if bundle.Metrics == nil {
+		bundle.Metrics = []types.MetricPoint{}
+	}
+	return bundle, nil
+}
+
+// WriteTar writes the bundle as a tar archive with one JSON file per section:
+// run.json (which also carries format and exported_at), transitions.json,
+// commands.json and metrics.json.
+func (b RunBundle) WriteTar(w io.Writer) error {
+	archive := tar.NewWriter(w)
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"run.json", struct {
+			Format     int       `json:"format"`
+			ExportedAt time.Time `json:"exported_at"`
+			Run        types.Run `json:"run"`
+		}{b.Format, b.ExportedAt, b.Run}},
+		{"transitions.json", b.Transitions},
+		{"commands.json", b.Commands},
+		{"metrics.json", b.Metrics},
+	}
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.content, "", "  ")
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:    b.Run.ID + "/" + file.name,
+			Mode:    0o644,
+			Size:    int64(len(content)),
+			ModTime: b.ExportedAt,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+var featureFlagNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// PutFeatureFlagInput creates or replaces a feature flag.
+type PutFeatureFlagInput struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Enabled     bool            `json:"enabled"`
+	Experiments map[string]bool `json:"experiments,omitempty"`
+	Runs        map[string]bool `json:"runs,omitempty"`
+	UpdatedBy   string          `json:"updated_by"`
+}
+
+// RunFeatureFlags is every feature flag resolved for one run.
+type RunFeatureFlags struct {
+	RunID        string          `json:"run_id"`
+	ExperimentID string          `json:"experiment_id"`
+	Flags        map[string]bool `json:"flags"`
+}
+
+// PutFeatureFlag creates or replaces a flag. Its overrides replace the previous
+// ones entirely, and actors and replay services pick the change up on their
+// next refresh.
+func (o *Orchestrator) PutFeatureFlag(ctx context.Context, input PutFeatureFlagInput) (types.FeatureFlag, error) {
+	if !featureFlagNamePattern.MatchString(input.Name) {
+		return types.FeatureFlag{}, invalidf("name must be 1-64 lowercase letters, digits or underscores, starting with a letter")
+	}
+	if _, ok := input.Experiments[""]; ok {
+		return types.FeatureFlag{}, invalidf("experiments cannot override an empty experiment ID")
+	}
+	if _, ok := input.Runs[""]; ok {
+		return types.FeatureFlag{}, invalidf("runs cannot override an empty run ID")
+	}
+	return o.store.PutFeatureFlag(ctx, types.FeatureFlag{
+		Name:        input.Name,
+		Description: input.Description,
+		Enabled:     input.Enabled,
+		Experiments: input.Experiments,
+		Runs:        input.Runs,
+		UpdatedBy:   input.UpdatedBy,
+		UpdatedAt:   o.now(),
+	})
+}
+
+// GetFeatureFlag returns the flag called name.
+func (o *Orchestrator) GetFeatureFlag(ctx context.Context, name string) (types.FeatureFlag, error) {
+	return o.store.GetFeatureFlag(ctx, name)
+}
+
+// ListFeatureFlags returns every flag.
+func (o *Orchestrator) ListFeatureFlags(ctx context.Context) ([]types.FeatureFlag, error) {
+	return o.store.ListFeatureFlags(ctx)
+}
+
+// RunFeatureFlags resolves every flag for a run, applying its run and
+// experiment overrides.
+func (o *Orchestrator) RunFeatureFlags(ctx context.Context, runID string) (RunFeatureFlags, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return RunFeatureFlags{}, err
+	}
+	flags, err := o.store.ListFeatureFlags(ctx)
+	if err != nil {
+		return RunFeatureFlags{}, err
+	}
+	resolved := RunFeatureFlags{RunID: run.ID, ExperimentID: run.ExperimentID, Flags: make(map[string]bool, len(flags))}
+	for _, flag := range flags {
+		resolved.Flags[flag.Name] = flag.EnabledFor(run)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// HyperparameterTimeline returns the run's tune commands that a learner has
+// reported a result for, in the order they were applied. Each entry carries the
+// step at which it took effect so it can be lined up with the run's metrics.
+func (o *Orchestrator) HyperparameterTimeline(ctx context.Context, runID string) ([]types.HyperparameterChange, error) {
+	commands, _, err := o.store.ListCommands(ctx, runID, storage.CommandFilter{})
+	if err != nil {
+		return nil, err
+	}
+	timeline := []types.HyperparameterChange{}
+	for _, cmd := range commands {
+		if cmd.Type != types.CommandTypeTune || cmd.Result == nil {
+			continue
+		}
+		timeline = append(timeline, types.HyperparameterChange{
+			CommandID: cmd.ID,
+			Actor:     cmd.Actor,
+			IssuedAt:  cmd.IssuedAt,
+			AppliedAt: cmd.Result.ReportedAt,
+			Step:      cmd.Result.Step,
+			Status:    cmd.Result.Status,
+			Requested: cmd.Payload,
+			Before:    cmd.Result.Previous,
+			After:     cmd.Result.Applied,
+			Message:   cmd.Result.Message,
+		})
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].AppliedAt.Before(timeline[j].AppliedAt)
+	})
+	return timeline, nil
+}
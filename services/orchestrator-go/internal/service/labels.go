@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// ListRuns returns a page of runs matching filter, oldest first.
+func (o *Orchestrator) ListRuns(ctx context.Context, filter storage.RunFilter) ([]types.Run, storage.Cursor, error) {
+	return o.store.ListRuns(ctx, filter)
+}
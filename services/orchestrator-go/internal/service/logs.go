@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// AppendRunLogs stores log lines pushed for a run and wakes its followers.
+// Lines default to level info and the time they were received. Lines may still
+// arrive after the run ends, since a failing learner's last words matter most.
+func (o *Orchestrator) AppendRunLogs(ctx context.Context, runID string, lines []types.LogLine) ([]types.LogLine, error) {
+	if len(lines) == 0 {
+		return nil, invalidf("lines must not be empty")
+	}
+	now := o.now()
+	for i := range lines {
+		if lines[i].Level == "" {
+			lines[i].Level = types.LogLevelInfo
+		}
+		if lines[i].At.IsZero() {
+			lines[i].At = now
+		}
+		if err := lines[i].Validate(); err != nil {
+			return nil, invalidf("lines[%d]: %v", i, err)
+		}
+	}
+	appended, err := o.store.AppendLogs(ctx, runID, lines)
+	if err != nil {
+		return nil, err
+	}
+	o.logSignals.notify(runID)
+	return appended, nil
+}
+
+// RunLogs returns the run's retained log lines matching filter, oldest first.
+func (o *Orchestrator) RunLogs(ctx context.Context, runID string, filter storage.LogFilter) ([]types.LogLine, error) {
+	if filter.After < 0 {
+		return nil, invalidf("after must not be negative")
+	}
+	if filter.MinLevel != "" && !filter.MinLevel.Valid() {
+		return nil, invalidf("invalid level %q", filter.MinLevel)
+	}
+	return o.store.ListLogs(ctx, runID, filter)
+}
+
+// WaitForLogs behaves like RunLogs but, when nothing matches, blocks for up to
+// wait until lines are appended or the run ends. It may return no lines, and
+// propagates ctx cancellation when the client goes away.
+func (o *Orchestrator) WaitForLogs(ctx context.Context, runID string, filter storage.LogFilter, wait time.Duration) ([]types.LogLine, error) {
+	// Subscribe before reading so lines appended in between are not missed.
+	signal := o.logSignals.wait(runID)
+	lines, err := o.RunLogs(ctx, runID, filter)
+	if err != nil || len(lines) > 0 {
+		return lines, err
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-signal:
+		return o.RunLogs(ctx, runID, filter)
+	case <-timer.C:
+		return lines, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
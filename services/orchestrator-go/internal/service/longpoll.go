@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// runSignals wakes long-polling clients waiting on something new for a run:
+// a pending command for learners, or log lines for followers.
+type runSignals struct {
+	mu      sync.Mutex
+	waiting map[string]chan struct{}
+}
+
+func newRunSignals() *runSignals {
+	return &runSignals{waiting: make(map[string]chan struct{})}
+}
+
+// wait returns a channel closed on the next notify for runID.
+func (s *runSignals) wait(runID string) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch, ok := s.waiting[runID]
+	if !ok {
+		ch = make(chan struct{})
+		s.waiting[runID] = ch
+	}
+	return ch
+}
+
+func (s *runSignals) notify(runID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch, ok := s.waiting[runID]; ok {
+		close(ch)
+		delete(s.waiting, runID)
+	}
+}
+
+// WaitForCommand behaves like NextCommand but, when nothing is pending, blocks for
+// up to wait until a command is queued for the run. It returns storage.ErrNoCommands
+// if the wait elapses and propagates ctx cancellation when the client goes away.
+func (o *Orchestrator) WaitForCommand(ctx context.Context, runID string, wait time.Duration) (types.RunCommand, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		// Subscribe before checking so a command queued in between is not missed.
+		signal := o.signals.wait(runID)
+		cmd, err := o.NextCommand(ctx, runID)
+		if !errors.Is(err, storage.ErrNoCommands) {
+			return cmd, err
+		}
+		select {
+		case <-signal:
+		case <-timer.C:
+			return types.RunCommand{}, storage.ErrNoCommands
+		case <-ctx.Done():
+			return types.RunCommand{}, ctx.Err()
+		}
+	}
+}
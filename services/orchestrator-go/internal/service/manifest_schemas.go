@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cartridge/orchestrator/internal/openapi"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// ManifestError reports the fields of a launch manifest that violate a schema.
+type ManifestError struct {
+	// Schema names the violated schema: "version <id>" or "env <env>".
+	Schema string
+	Fields []openapi.FieldError
+}
+
+func (e *ManifestError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		if field.Field == "" {
+			parts = append(parts, field.Message)
+			continue
+		}
+		parts = append(parts, field.Field+" "+field.Message)
+	}
+	return fmt.Sprintf("launch manifest does not match the %s schema: %s", e.Schema, strings.Join(parts, "; "))
+}
+
+// Unwrap marks manifest violations as validation failures.
+func (e *ManifestError) Unwrap() error { return ErrValidation }
+
+// RegisterManifestSchemaInput registers or replaces an environment's schema.
+type RegisterManifestSchemaInput struct {
+	Env         string          `json:"env"`
+	Schema      json.RawMessage `json:"schema"`
+	Description string          `json:"description,omitempty"`
+	UpdatedBy   string          `json:"updated_by"`
+}
+
+// RegisterManifestSchema stores the schema that launch manifests for input.Env
+// must satisfy from now on. Existing runs are not revalidated.
+func (o *Orchestrator) RegisterManifestSchema(ctx context.Context, input RegisterManifestSchemaInput) (types.ManifestSchema, error) {
+	if input.Env == "" {
+		return types.ManifestSchema{}, invalidf("env is required")
+	}
+	if _, err := compileManifestSchema(input.Schema); err != nil {
+		return types.ManifestSchema{}, err
+	}
+	return o.store.PutManifestSchema(ctx, types.ManifestSchema{
+		Env:         input.Env,
+		Schema:      input.Schema,
+		Description: input.Description,
+		UpdatedBy:   input.UpdatedBy,
+		UpdatedAt:   o.now(),
+	})
+}
+
+// GetManifestSchema returns the schema registered for env.
+func (o *Orchestrator) GetManifestSchema(ctx context.Context, env string) (types.ManifestSchema, error) {
+	return o.store.GetManifestSchema(ctx, env)
+}
+
+// ListManifestSchemas returns every registered environment schema.
+func (o *Orchestrator) ListManifestSchemas(ctx context.Context) ([]types.ManifestSchema, error) {
+	return o.store.ListManifestSchemas(ctx)
+}
+
+func compileManifestSchema(raw json.RawMessage) (*openapi.DocumentSchema, error) {
+	if !isJSONObject(raw) {
+		return nil, invalidf("schema must be a JSON object")
+	}
+	schema, err := openapi.CompileSchema(raw)
+	if err != nil {
+		return nil, invalidf("invalid schema: %w", err)
+	}
+	return schema, nil
+}
+
+// checkManifest validates manifest against a compiled schema.
+func checkManifest(schema *openapi.DocumentSchema, name string, manifest json.RawMessage) error {
+	if len(manifest) == 0 {
+		manifest = json.RawMessage("{}")
+	}
+	if fields := schema.Validate(manifest); len(fields) > 0 {
+		return &ManifestError{Schema: name, Fields: fields}
+	}
+	return nil
+}
+
+// validateLaunchManifest checks a run's resolved launch manifest against its
+// version's schema and the schema registered for its game.env_id, if any.
+func (o *Orchestrator) validateLaunchManifest(ctx context.Context, input CreateRunInput) error {
+	version, err := o.store.GetVersion(ctx, input.VersionID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+	case err != nil:
+		return err
+	case len(version.ManifestSchema) > 0 && version.ExperimentID == input.ExperimentID:
+		schema, err := compileManifestSchema(version.ManifestSchema)
+		if err != nil {
+			return err
+		}
+		if err := checkManifest(schema, "version "+version.ID, input.LaunchManifest); err != nil {
+			return err
+		}
+	}
+
+	env := types.RequirementsFromManifest(input.LaunchManifest).Env
+	if env == "" {
+		return nil
+	}
+	registered, err := o.store.GetManifestSchema(ctx, env)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	schema, err := compileManifestSchema(registered.Schema)
+	if err != nil {
+		return err
+	}
+	return checkManifest(schema, "env "+env, input.LaunchManifest)
+}
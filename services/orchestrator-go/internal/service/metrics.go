@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// maxMetricBuckets caps the points returned when the caller leaves the resolution
+// to the server; the resolution grows until the window fits.
+const maxMetricBuckets = 500
+
+// MetricsQuery selects a window of a run's metrics history. Zero From/To leave
+// the window open; zero Resolution picks one that keeps the series within
+// maxMetricBuckets points.
+type MetricsQuery struct {
+	From       time.Time
+	To         time.Time
+	Resolution time.Duration
+}
+
+// MetricSeries is a downsampled heartbeat timeseries.
+type MetricSeries struct {
+	RunID string `json:"run_id"`
+	// ResolutionSeconds is the bucket width; zero means raw heartbeats.
+	ResolutionSeconds int64               `json:"resolution_seconds"`
+	Points            []types.MetricPoint `json:"points"`
+	// Annotations are the operator notes placed within the window, so they can
+	// be drawn over the points.
+	Annotations []types.Annotation `json:"annotations"`
+	// HeartbeatGaps is the run's current heartbeat gap summary, regardless of
+	// the window.
+	HeartbeatGaps *types.HeartbeatGapStats `json:"heartbeat_gaps,omitempty"`
+}
+
+// WithMetricsRetention drops heartbeat metrics older than d on each prune; zero
+// keeps history until the per-run buffer fills.
+func (o *Orchestrator) WithMetricsRetention(d time.Duration) {
+	o.metricsRetention = d
+}
+
+// recordMetricPoint appends the heartbeat telemetry to the run's history.
+func (o *Orchestrator) recordMetricPoint(ctx context.Context, run types.Run, at time.Time) {
+	point := types.MetricPoint{
+		At:               at,
+		Step:             run.CurrentStep,
+		Loss:             run.Loss,
+		SamplesPerSecond: run.SamplesPerSecond,
+	}
+	if err := o.store.AppendMetricPoint(ctx, run.ID, point); err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to record heartbeat metrics")
+	}
+}
+
+// RunMetrics returns a run's heartbeat metrics within the query window, averaged
+// into buckets aligned to the resolution, along with the annotations in it. Each
+// bucket reports the latest step seen.
+func (o *Orchestrator) RunMetrics(ctx context.Context, runID string, query MetricsQuery) (MetricSeries, error) {
+	if query.Resolution < 0 {
+		return MetricSeries{}, invalidf("resolution must not be negative")
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return MetricSeries{}, invalidf("from must be before to")
+	}
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return MetricSeries{}, err
+	}
+	points, err := o.store.ListMetricPoints(ctx, runID, query.From, query.To)
+	if err != nil {
+		return MetricSeries{}, err
+	}
+	annotations, err := o.store.ListAnnotations(ctx, runID, query.From, query.To)
+	if err != nil {
+		return MetricSeries{}, err
+	}
+	resolution := query.Resolution
+	if resolution == 0 && len(points) > maxMetricBuckets {
+		span := points[len(points)-1].At.Sub(points[0].At)
+		resolution = (span/maxMetricBuckets + time.Second).Truncate(time.Second)
+	}
+	series := MetricSeries{RunID: runID, Points: points, Annotations: annotations, HeartbeatGaps: run.HeartbeatGaps}
+	if resolution > 0 {
+		series.ResolutionSeconds = int64(resolution / time.Second)
+		series.Points = downsample(points, resolution)
+	}
+	return series, nil
+}
+
+// PruneMetrics enforces the metrics retention windows, returning the number of
+// points removed.
+func (o *Orchestrator) PruneMetrics(ctx context.Context) (int, error) {
+	removed, err := o.PurgeArchivedMetrics(ctx)
+	if err != nil || o.metricsRetention <= 0 {
+		return removed, err
+	}
+	pruned, err := o.store.PruneMetricPoints(ctx, o.now().Add(-o.metricsRetention))
+	return removed + pruned, err
+}
+
+// downsample folds time-ordered points into fixed-width buckets.
+func downsample(points []types.MetricPoint, resolution time.Duration) []types.MetricPoint {
+	buckets := make([]types.MetricPoint, 0)
+	for _, point := range points {
+		start := point.At.Truncate(resolution)
+		if n := len(buckets); n == 0 || !buckets[n-1].At.Equal(start) {
+			buckets = append(buckets, types.MetricPoint{At: start})
+		}
+		bucket := &buckets[len(buckets)-1]
+		bucket.Samples++
+		weight := 1 / float64(bucket.Samples)
+		bucket.Loss += (point.Loss - bucket.Loss) * weight
+		bucket.SamplesPerSecond += (point.SamplesPerSecond - bucket.SamplesPerSecond) * weight
+		if point.Step > bucket.Step {
+			bucket.Step = point.Step
+		}
+	}
+	return buckets
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/rs/zerolog"
 
+	"github.com/cartridge/orchestrator/internal/artifacts"
+	"github.com/cartridge/orchestrator/internal/correlation"
 	"github.com/cartridge/orchestrator/internal/events"
 	"github.com/cartridge/orchestrator/internal/storage"
 	"github.com/cartridge/orchestrator/internal/types"
@@ -22,26 +25,107 @@ type CreateRunInput struct {
 	Overrides      json.RawMessage `json:"overrides,omitempty"`
 	Priority       int             `json:"priority"`
 	CreatedBy      string          `json:"created_by"`
+	ScheduleID     string          `json:"-"` // set when launched by a cron schedule
+
+	// TemplateID renders the launch manifest from a run template, substituting
+	// Parameters for its placeholders.
+	TemplateID string                     `json:"template_id,omitempty"`
+	Parameters map[string]json.RawMessage `json:"parameters,omitempty"`
+
+	// DependsOn lists runs that must complete before this run is dispatched.
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Labels are free-form key/value tags runs can be searched by.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// MaxDuration (a Go duration such as "12h") bounds how long the run may stay
+	// running; MaxDurationAction is terminate (the default) or pause.
+	MaxDuration       string            `json:"max_duration,omitempty"`
+	MaxDurationAction types.CommandType `json:"max_duration_action,omitempty"`
+
+	ClonedFrom string `json:"-"` // set by CloneRun
 }
 
 // Orchestrator implements the orchestrator workflows on top of storage.
 type Orchestrator struct {
-	store  storage.RunStore
-	events events.Publisher
-	logger *zerolog.Logger
-	now    func() time.Time
+	store   storage.RunStore
+	events  events.Publisher
+	logger  *zerolog.Logger
+	now     func() time.Time
+	signals *runSignals // pending commands
+
+	logSignals *runSignals // appended log lines
+
+	// Unacked deliveries older than ackTimeout are retried up to maxDeliveryAttempts
+	// times; zero ackTimeout disables redelivery.
+	ackTimeout          time.Duration
+	maxDeliveryAttempts int
+
+	// learnerStaleAfter excludes learners that have not registered or heartbeated
+	// recently from dispatch; zero keeps every registered learner eligible.
+	learnerStaleAfter time.Duration
+
+	// preemption pauses low-priority running runs for higher-priority queued ones.
+	preemption PreemptionPolicy
+
+	// artifacts signs upload/download URLs; nil disables the artifact endpoints.
+	artifacts         artifacts.Presigner
+	artifactURLExpiry time.Duration
+
+	metricsRetention         time.Duration
+	archivedMetricsRetention time.Duration
+
+	// costRates are hourly rates per manifest resources.class.
+	costRates map[string]float64
+
+	// defaultMaxDuration applies to runs created without a max_duration.
+	defaultMaxDuration time.Duration
+
+	// admitters must all accept a run before it is created.
+	admitters []Admitter
+
+	// commandBus, when set, pushes queued commands to learners.
+	commandBus events.CommandBus
+
+	// serialCommands holds back a run's next command until the previous
+	// delivery is acknowledged.
+	serialCommands bool
 }
 
 // NewOrchestrator constructs an Orchestrator instance.
 func NewOrchestrator(store storage.RunStore, publisher events.Publisher, logger *zerolog.Logger) *Orchestrator {
 	return &Orchestrator{
-		store:  store,
-		events: publisher,
-		logger: logger,
-		now:    time.Now,
+		store:   store,
+		events:  publisher,
+		logger:  logger,
+		now:     time.Now,
+		signals: newRunSignals(),
+
+		logSignals: newRunSignals(),
 	}
 }
 
+// WithCommandRedelivery requeues commands that are not acknowledged within
+// ackTimeout of delivery, dead-lettering them after maxAttempts deliveries.
+func (o *Orchestrator) WithCommandRedelivery(ackTimeout time.Duration, maxAttempts int) {
+	o.ackTimeout = ackTimeout
+	o.maxDeliveryAttempts = maxAttempts
+}
+
+// WithSerialCommandDelivery delivers a run's commands one at a time: the next
+// is held back until the learner acknowledges the previous one, or it is
+// requeued or dead-lettered. Without it, commands are still handed out in
+// IssuedAt order, but an unacknowledged command redelivered after the ack
+// timeout can arrive after later ones.
+func (o *Orchestrator) WithSerialCommandDelivery(serial bool) {
+	o.serialCommands = serial
+}
+
+// WithLearnerStaleAfter stops dispatching to learners silent for longer than d.
+func (o *Orchestrator) WithLearnerStaleAfter(d time.Duration) {
+	o.learnerStaleAfter = d
+}
+
 // WithNow allows tests to override the time source.
 func (o *Orchestrator) WithNow(now func() time.Time) {
 	o.now = now
@@ -50,7 +134,29 @@ func (o *Orchestrator) WithNow(now func() time.Time) {
 // CreateRun persists a new run and an initial transition entry.
 func (o *Orchestrator) CreateRun(ctx context.Context, input CreateRunInput) (types.Run, error) {
 	if input.ID == "" || input.ExperimentID == "" || input.VersionID == "" {
-		return types.Run{}, errors.New("id, experiment_id, and version_id are required")
+		return types.Run{}, invalidf("id, experiment_id, and version_id are required")
+	}
+	if err := types.ValidateLabels(input.Labels); err != nil {
+		return types.Run{}, invalid(err)
+	}
+	maxDuration, maxDurationAction, err := o.resolveMaxDuration(input)
+	if err != nil {
+		return types.Run{}, err
+	}
+	if err := o.checkExperimentAcceptsRuns(ctx, input.ExperimentID); err != nil {
+		return types.Run{}, err
+	}
+	if err := o.materializeRunTemplate(ctx, &input); err != nil {
+		return types.Run{}, err
+	}
+	if err := o.checkRunDependencies(ctx, &input); err != nil {
+		return types.Run{}, err
+	}
+	if err := o.resolveRunVersion(ctx, &input); err != nil {
+		return types.Run{}, err
+	}
+	if err := o.validateLaunchManifest(ctx, input); err != nil {
+		return types.Run{}, err
 	}
 	now := o.now()
 	run := types.Run{
@@ -61,6 +167,11 @@ func (o *Orchestrator) CreateRun(ctx context.Context, input CreateRunInput) (typ
 		LaunchManifest:   input.LaunchManifest,
 		Overrides:        input.Overrides,
 		Priority:         input.Priority,
+		ScheduleID:       input.ScheduleID,
+		TemplateID:       input.TemplateID,
+		DependsOn:        input.DependsOn,
+		Labels:           input.Labels,
+		ClonedFrom:       input.ClonedFrom,
 		RuntimeStatus:    types.RuntimeStatusRunning,
 		HealthStatus:     types.RunHealthHealthy,
 		CurrentStep:      0,
@@ -70,6 +181,13 @@ func (o *Orchestrator) CreateRun(ctx context.Context, input CreateRunInput) (typ
 		CreatedAt:        now,
 		UpdatedAt:        now,
 	}
+	if maxDuration > 0 {
+		run.MaxDurationSeconds = int64(maxDuration / time.Second)
+		run.MaxDurationAction = maxDurationAction
+	}
+	if err := o.admit(ctx, run); err != nil {
+		return types.Run{}, err
+	}
 	if err := o.store.CreateRun(ctx, run); err != nil {
 		if errors.Is(err, storage.ErrConflict) {
 			o.logger.Warn().Str("run_id", input.ID).Msg("run already exists")
@@ -91,39 +209,241 @@ func (o *Orchestrator) CreateRun(ctx context.Context, input CreateRunInput) (typ
 	return run, nil
 }
 
-// GetRun returns run metadata.
+// TransitionInput describes a requested lifecycle change.
+type TransitionInput struct {
+	ToState   types.RunState `json:"to_state"`
+	ChangedBy string         `json:"changed_by"`
+	Reason    string         `json:"reason"`
+}
+
+// TransitionRun moves a run to the requested state if the state machine allows it.
+func (o *Orchestrator) TransitionRun(ctx context.Context, runID string, input TransitionInput) (types.Run, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.Run{}, err
+	}
+	return o.transition(ctx, run, input)
+}
+
+// PerformAction resolves a lifecycle action against the run's current state and applies it.
+func (o *Orchestrator) PerformAction(ctx context.Context, runID string, action types.RunAction, changedBy, reason string) (types.Run, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.Run{}, err
+	}
+	next, err := run.State.ActionTarget(action)
+	if err != nil {
+		return types.Run{}, err
+	}
+	return o.transition(ctx, run, TransitionInput{ToState: next, ChangedBy: changedBy, Reason: reason})
+}
+
+// transition persists a lifecycle change, records it, and publishes a run status event.
+func (o *Orchestrator) transition(ctx context.Context, run types.Run, input TransitionInput) (types.Run, error) {
+	from := run.State
+	if from == types.RunStateQueued && input.ToState == types.RunStateProvisioning {
+		if err := o.requireDependenciesMet(ctx, run); err != nil {
+			return types.Run{}, err
+		}
+	}
+	now := o.now()
+	run, err := run.ApplyTransition(input.ToState, input.Reason, now)
+	if err != nil {
+		return types.Run{}, err
+	}
+	if err := o.store.UpdateRun(ctx, run); err != nil {
+		return types.Run{}, err
+	}
+	transition := storage.RunTransition{
+		RunID:     run.ID,
+		FromState: from,
+		ToState:   run.State,
+		ChangedBy: input.ChangedBy,
+		Reason:    input.Reason,
+		CreatedAt: now,
+	}
+	if err := o.store.AppendTransition(ctx, transition); err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to record transition")
+	}
+	event := events.RunStatusEvent{
+		RunID:            run.ID,
+		State:            string(run.State),
+		PreviousState:    string(from),
+		Reason:           input.Reason,
+		RuntimeStatus:    string(run.RuntimeStatus),
+		HealthStatus:     string(run.HealthStatus),
+		Step:             run.CurrentStep,
+		SamplesPerSecond: run.SamplesPerSecond,
+		Loss:             run.Loss,
+		CorrelationID:    correlation.FromContext(ctx),
+	}
+	if err := o.events.PublishRunStatus(ctx, event); err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to publish run status event")
+	}
+	if run.State.IsTerminal() && !from.IsTerminal() {
+		o.runEnded(ctx, run)
+	}
+	return run, nil
+}
+
+// runEnded does the bookkeeping due once when a run first reaches a terminal
+// state.
+func (o *Orchestrator) runEnded(ctx context.Context, run types.Run) {
+	o.publishAccounting(ctx, run)
+	// Log followers stop once an ended run's logs are drained.
+	o.logSignals.notify(run.ID)
+}
+
+// ListTransitions returns the lifecycle history of a run.
+func (o *Orchestrator) ListTransitions(ctx context.Context, runID string) ([]storage.RunTransition, error) {
+	return o.store.ListTransitions(ctx, runID)
+}
+
+// GetRun returns run metadata, including the dispatch position of queued runs.
 func (o *Orchestrator) GetRun(ctx context.Context, runID string) (types.Run, error) {
-	return o.store.GetRun(ctx, runID)
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil || run.State != types.RunStateQueued {
+		return run, err
+	}
+	run.QueuePosition, err = o.queuePosition(ctx, runID)
+	return run, err
 }
 
-// HandleHeartbeat processes a learner heartbeat and updates run state.
+// runScanPageSize is the page size background loops use to walk run listings.
+const runScanPageSize = 500
+
+// ListRunsForHealthCheck returns the runs in the given states whose heartbeats should be monitored.
+// Archived runs are skipped. Runs are read a page at a time so no single store
+// query scans the whole registry.
+func (o *Orchestrator) ListRunsForHealthCheck(ctx context.Context, states ...types.RunState) ([]types.Run, error) {
+	if len(states) == 0 {
+		return nil, nil
+	}
+	filter := storage.RunFilter{States: states, Limit: runScanPageSize}
+	var monitored []types.Run
+	for {
+		runs, next, err := o.store.ListRuns(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		monitored = append(monitored, runs...)
+		if next.IsZero() {
+			return monitored, nil
+		}
+		filter.After = next
+	}
+}
+
+// UpdateRunHealth persists an orchestrator-derived health change and records it in the
+// run's transition log.
+func (o *Orchestrator) UpdateRunHealth(ctx context.Context, runID string, health types.RunHealth, changedBy, reason string) (types.Run, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.Run{}, err
+	}
+	if run.HealthStatus == health {
+		return run, nil
+	}
+	previous := run.HealthStatus
+	now := o.now()
+	run.HealthStatus = health
+	run.UpdatedAt = now
+	if err := o.store.UpdateRun(ctx, run); err != nil {
+		return types.Run{}, err
+	}
+	transition := storage.RunTransition{
+		RunID:     run.ID,
+		FromState: run.State,
+		ToState:   run.State,
+		ChangedBy: changedBy,
+		Reason:    fmt.Sprintf("health %s -> %s: %s", previous, health, reason),
+		CreatedAt: now,
+	}
+	if err := o.store.AppendTransition(ctx, transition); err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to record transition")
+	}
+	return run, nil
+}
+
+// heartbeatActor is recorded as the author of transitions driven by learner heartbeats.
+const heartbeatActor = "heartbeat"
+
+// HandleHeartbeat processes a learner heartbeat and updates run state. An errored
+// or terminating runtime status also moves the run's lifecycle state, recording
+// the transition and reporting the previous state on the status event.
 func (o *Orchestrator) HandleHeartbeat(ctx context.Context, runID string, payload types.HeartbeatPayload) (types.Run, error) {
 	run, err := o.store.GetRun(ctx, runID)
 	if err != nil {
 		return types.Run{}, err
 	}
 	if err := payload.Validate(runID, run.CurrentStep, run.CheckpointVersion); err != nil {
-		return types.Run{}, err
+		if errors.Is(err, ErrRegression) {
+			return types.Run{}, err
+		}
+		return types.Run{}, invalid(err)
 	}
 	now := o.now()
+	from := run.State
+	var gap time.Duration
+	if run.LastHeartbeatAt != nil {
+		gap = now.Sub(*run.LastHeartbeatAt)
+	}
 	run = run.MergeHeartbeat(payload, now)
 	run.HealthStatus = types.RunHealthHealthy
 	run.UpdatedAt = now
+	var reason string
+	if next, ok := run.HeartbeatTarget(payload.Status); ok {
+		reason = "learner reported " + string(payload.Status)
+		if payload.Notes != "" {
+			reason += ": " + payload.Notes
+		}
+		if run, err = run.ApplyTransition(next, reason, now); err != nil {
+			return types.Run{}, err
+		}
+	}
+	fired := o.evaluateStoppingRules(ctx, run)
+	if len(fired) > 0 {
+		run.StatusMessage = stoppingMessage(fired)
+	}
 	if err := o.store.UpdateRun(ctx, run); err != nil {
 		return types.Run{}, err
 	}
+	if run.State != from {
+		transition := storage.RunTransition{
+			RunID:     run.ID,
+			FromState: from,
+			ToState:   run.State,
+			ChangedBy: heartbeatActor,
+			Reason:    reason,
+			CreatedAt: now,
+		}
+		if err := o.store.AppendTransition(ctx, transition); err != nil {
+			o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to record transition")
+		}
+	}
+	o.recordMetricPoint(ctx, run, now)
+	o.fireStoppingRules(ctx, fired)
+	o.evaluateAlertRules(ctx, run, gap)
 	event := events.RunStatusEvent{
 		RunID:            run.ID,
 		State:            string(run.State),
+		Reason:           reason,
 		RuntimeStatus:    string(run.RuntimeStatus),
 		HealthStatus:     string(run.HealthStatus),
 		Step:             run.CurrentStep,
 		SamplesPerSecond: run.SamplesPerSecond,
 		Loss:             run.Loss,
+		CorrelationID:    correlation.FromContext(ctx),
+	}
+	if run.State != from {
+		event.PreviousState = string(from)
 	}
 	if err := o.events.PublishRunStatus(ctx, event); err != nil {
 		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to publish run status event")
 	}
+	if run.State.IsTerminal() && !from.IsTerminal() {
+		o.runEnded(ctx, run)
+	}
 	return run, nil
 }
 
@@ -133,7 +453,7 @@ func (o *Orchestrator) CreateCommand(ctx context.Context, command types.RunComma
 		return types.RunCommand{}, err
 	}
 	if err := command.Validate(); err != nil {
-		return types.RunCommand{}, err
+		return types.RunCommand{}, invalid(err)
 	}
 	if err := o.store.AppendCommand(ctx, command); err != nil {
 		if errors.Is(err, storage.ErrConflict) {
@@ -141,57 +461,201 @@ func (o *Orchestrator) CreateCommand(ctx context.Context, command types.RunComma
 		}
 		return types.RunCommand{}, err
 	}
+	o.signals.notify(command.RunID)
 	if err := o.events.PublishCommandEvent(ctx, events.CommandEvent{
-		RunID:     command.RunID,
-		CommandID: command.ID,
-		Type:      string(command.Type),
-		Event:     "queued",
+		RunID:         command.RunID,
+		CommandID:     command.ID,
+		Type:          string(command.Type),
+		Event:         "queued",
+		CorrelationID: correlation.FromContext(ctx),
 	}); err != nil {
 		o.logger.Error().Err(err).Str("run_id", command.RunID).Str("command_id", command.ID).Msg("failed to publish command event")
 	}
+	for _, pushed := range o.pushCommands(ctx, command.RunID) {
+		if pushed.ID == command.ID {
+			return pushed, nil
+		}
+	}
 	return command, nil
 }
 
-// NextCommand returns the oldest undelivered command and marks it delivered.
+// NextCommand returns the oldest undelivered, unexpired command and marks it
+// delivered. Under serial delivery it returns storage.ErrNoCommands while an
+// earlier command awaits acknowledgement.
 func (o *Orchestrator) NextCommand(ctx context.Context, runID string) (types.RunCommand, error) {
-	cmd, err := o.store.NextPendingCommand(ctx, runID)
-	if err != nil {
+	if _, err := o.RedeliverCommands(ctx, runID); err != nil {
 		return types.RunCommand{}, err
 	}
-	now := o.now()
-	cmd.DeliveredAt = &now
-	if err := o.store.SaveCommand(ctx, cmd); err != nil {
+	if _, err := o.ExpireCommands(ctx, runID); err != nil {
+		return types.RunCommand{}, err
+	}
+	cmd, err := o.store.DeliverNextCommand(ctx, runID, o.now(), o.serialCommands)
+	if err != nil {
 		return types.RunCommand{}, err
 	}
 	if err := o.events.PublishCommandEvent(ctx, events.CommandEvent{
-		RunID:     cmd.RunID,
-		CommandID: cmd.ID,
-		Type:      string(cmd.Type),
-		Event:     "delivered",
+		RunID:         cmd.RunID,
+		CommandID:     cmd.ID,
+		Type:          string(cmd.Type),
+		Event:         "delivered",
+		CorrelationID: correlation.FromContext(ctx),
 	}); err != nil {
 		o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to publish delivery event")
 	}
 	return cmd, nil
 }
 
-// AckCommand marks a command as acknowledged by the learner.
+// ListCommands returns a page of a run's commands and the cursor for the next page.
+func (o *Orchestrator) ListCommands(ctx context.Context, runID string, filter storage.CommandFilter) ([]types.RunCommand, storage.Cursor, error) {
+	for _, status := range filter.Statuses {
+		if !status.Valid() {
+			return nil, storage.Cursor{}, invalidf("unknown command status %q", status)
+		}
+	}
+	return o.store.ListCommands(ctx, runID, filter)
+}
+
+// RedeliverCommands returns delivered-but-unacknowledged commands past the ack
+// timeout to the pending queue so a learner that crashed after fetching them gets
+// them again, and dead-letters those that exhausted their delivery attempts.
+// Requeued commands are pushed to the command bus again. An empty runID sweeps
+// every run.
+func (o *Orchestrator) RedeliverCommands(ctx context.Context, runID string) ([]types.RunCommand, error) {
+	if o.ackTimeout <= 0 {
+		return nil, nil
+	}
+	now := o.now()
+	reclaimed, err := o.store.ReclaimCommands(ctx, runID, now.Add(-o.ackTimeout), o.maxDeliveryAttempts, now)
+	if err != nil {
+		return nil, err
+	}
+	pushRuns := make(map[string]bool)
+	for _, cmd := range reclaimed {
+		event := events.CommandEvent{
+			RunID:         cmd.RunID,
+			CommandID:     cmd.ID,
+			Type:          string(cmd.Type),
+			Event:         "requeued",
+			Description:   fmt.Sprintf("not acknowledged within %s (attempt %d)", o.ackTimeout, cmd.DeliveryAttempts),
+			CorrelationID: correlation.FromContext(ctx),
+		}
+		if cmd.DeadLetteredAt != nil {
+			event.Event = "dead_lettered"
+			event.Description = fmt.Sprintf("not acknowledged after %d delivery attempts", cmd.DeliveryAttempts)
+		}
+		// Either way the command is no longer in flight, which may release the
+		// run's next command under serial delivery.
+		o.signals.notify(cmd.RunID)
+		pushRuns[cmd.RunID] = true
+		if err := o.events.PublishCommandEvent(ctx, event); err != nil {
+			o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to publish redelivery event")
+		}
+	}
+	for runID := range pushRuns {
+		o.pushCommands(ctx, runID)
+	}
+	return reclaimed, nil
+}
+
+// ExpireCommands marks undelivered commands past their expires_at as expired and
+// publishes an "expired" event for each so operators learn the learner never saw
+// them. An empty runID sweeps every run.
+func (o *Orchestrator) ExpireCommands(ctx context.Context, runID string) ([]types.RunCommand, error) {
+	expired, err := o.store.ExpireCommands(ctx, runID, o.now())
+	if err != nil {
+		return nil, err
+	}
+	for _, cmd := range expired {
+		if err := o.events.PublishCommandEvent(ctx, events.CommandEvent{
+			RunID:         cmd.RunID,
+			CommandID:     cmd.ID,
+			Type:          string(cmd.Type),
+			Event:         "expired",
+			Description:   fmt.Sprintf("expired at %s before delivery", cmd.ExpiresAt.Format(time.RFC3339)),
+			CorrelationID: correlation.FromContext(ctx),
+		}); err != nil {
+			o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to publish expiry event")
+		}
+	}
+	return expired, nil
+}
+
+// AckCommand marks a command as acknowledged by the learner. Only a command
+// delivered and not yet acknowledged can be: one still queued, expired or
+// dead-lettered never reached the learner through the queue.
 func (o *Orchestrator) AckCommand(ctx context.Context, runID, commandID string) (types.RunCommand, error) {
 	cmd, err := o.store.GetCommand(ctx, runID, commandID)
 	if err != nil {
 		return types.RunCommand{}, err
 	}
+	if status := cmd.Status(); status != types.CommandStatusDelivered {
+		return types.RunCommand{}, fmt.Errorf("%w: command %s is %s, not delivered", storage.ErrConflict, commandID, status)
+	}
 	now := o.now()
 	cmd.AcknowledgedAt = &now
 	if err := o.store.SaveCommand(ctx, cmd); err != nil {
 		return types.RunCommand{}, err
 	}
 	if err := o.events.PublishCommandEvent(ctx, events.CommandEvent{
-		RunID:     cmd.RunID,
-		CommandID: cmd.ID,
-		Type:      string(cmd.Type),
-		Event:     "acknowledged",
+		RunID:         cmd.RunID,
+		CommandID:     cmd.ID,
+		Type:          string(cmd.Type),
+		Event:         "acknowledged",
+		CorrelationID: correlation.FromContext(ctx),
 	}); err != nil {
 		o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to publish ack event")
 	}
+	o.commandSettled(ctx, cmd.RunID)
+	return cmd, nil
+}
+
+// ReportCommandResult records the learner's outcome for a delivered command,
+// acknowledging it if the learner skipped the explicit ack. A command since
+// expired, dead-lettered, discarded or requeued takes no result.
+func (o *Orchestrator) ReportCommandResult(ctx context.Context, runID, commandID string, result types.CommandResult) (types.RunCommand, error) {
+	if err := result.Validate(); err != nil {
+		return types.RunCommand{}, invalid(err)
+	}
+	cmd, err := o.store.GetCommand(ctx, runID, commandID)
+	if err != nil {
+		return types.RunCommand{}, err
+	}
+	if status := cmd.Status(); status != types.CommandStatusDelivered && status != types.CommandStatusAcked {
+		return types.RunCommand{}, fmt.Errorf("%w: command %s is %s, not delivered", storage.ErrConflict, commandID, status)
+	}
+	if cmd.Result != nil {
+		return types.RunCommand{}, fmt.Errorf("%w: command %s already has a result", storage.ErrConflict, commandID)
+	}
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.RunCommand{}, err
+	}
+	now := o.now()
+	result.ReportedAt = now
+	result.Step = run.CurrentStep
+	cmd.Result = &result
+	settled := cmd.AcknowledgedAt == nil
+	if settled {
+		cmd.AcknowledgedAt = &now
+	}
+	if err := o.store.SaveCommand(ctx, cmd); err != nil {
+		return types.RunCommand{}, err
+	}
+	if err := o.events.PublishCommandEvent(ctx, events.CommandEvent{
+		RunID:         cmd.RunID,
+		CommandID:     cmd.ID,
+		Type:          string(cmd.Type),
+		Event:         "result",
+		Description:   result.Message,
+		ResultStatus:  string(result.Status),
+		Applied:       result.Applied,
+		Previous:      result.Previous,
+		CorrelationID: correlation.FromContext(ctx),
+	}); err != nil {
+		o.logger.Error().Err(err).Str("run_id", cmd.RunID).Str("command_id", cmd.ID).Msg("failed to publish result event")
+	}
+	if settled {
+		o.commandSettled(ctx, cmd.RunID)
+	}
 	return cmd, nil
 }
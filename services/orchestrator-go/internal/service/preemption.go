@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// preemptionActor is recorded as the initiator of preemption pauses.
+const preemptionActor = "preemption"
+
+// PreemptionPolicy controls when the dispatcher pauses running runs to make room
+// for higher-priority queued runs that no learner can currently host.
+type PreemptionPolicy struct {
+	// MinPriorityGap is how far a queued run's priority must exceed a running
+	// run's for it to preempt that run; zero disables preemption.
+	MinPriorityGap int
+	// MaxPerPass caps preemptions per dispatch pass; zero means no cap.
+	MaxPerPass int
+}
+
+// WithPreemption enables priority-based preemption under policy.
+func (o *Orchestrator) WithPreemption(policy PreemptionPolicy) {
+	o.preemption = policy
+}
+
+// preemptionCandidates returns the running runs that may be preempted, lowest
+// priority first and, within a priority, the most recently started first so the
+// least progress is interrupted.
+func (o *Orchestrator) preemptionCandidates(ctx context.Context) ([]types.Run, error) {
+	running, err := o.store.ListRunsByState(ctx, types.RunStateRunning)
+	if err != nil {
+		return nil, err
+	}
+	candidates := running[:0]
+	for _, run := range running {
+		if run.LearnerID != "" && !run.Archived() {
+			candidates = append(candidates, run)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority < candidates[j].Priority
+		}
+		return startedAt(candidates[i]).After(startedAt(candidates[j]))
+	})
+	return candidates, nil
+}
+
+// preemptFor pauses the lowest-priority candidate whose learner could then host
+// run and returns that learner with the freed capacity applied. It returns nil,
+// leaving candidates untouched, when no candidate is far enough below run's
+// priority or freeing one would not make room.
+func (o *Orchestrator) preemptFor(ctx context.Context, run types.Run, req types.RunRequirements, learners []types.Learner, candidates *[]types.Run) *types.Learner {
+	for i, victim := range *candidates {
+		if run.Priority-victim.Priority < o.preemption.MinPriorityGap {
+			return nil
+		}
+		learner := findLearner(learners, victim.LearnerID)
+		if learner == nil {
+			continue
+		}
+		freed := *learner
+		freed.ActiveRuns--
+		freed.UsedGPUs -= types.RequirementsFromManifest(victim.LaunchManifest).GPUs
+		if !freed.CanHost(req) {
+			continue
+		}
+		if err := o.preempt(ctx, victim, run); err != nil {
+			o.logger.Error().Err(err).Str("run_id", victim.ID).Str("preempted_by", run.ID).Msg("failed to preempt run")
+			continue
+		}
+		*learner = freed
+		*candidates = append((*candidates)[:i:i], (*candidates)[i+1:]...)
+		return learner
+	}
+	return nil
+}
+
+// preempt pauses victim in favour of by and asks its learner to pause. The
+// paused run keeps its learner assignment for reference but no longer holds a
+// slot; resuming it reclaims one.
+func (o *Orchestrator) preempt(ctx context.Context, victim, by types.Run) error {
+	victim.PreemptedBy = by.ID
+	victim, err := o.transition(ctx, victim, TransitionInput{
+		ToState:   types.RunStatePaused,
+		ChangedBy: preemptionActor,
+		Reason:    fmt.Sprintf("preempted by run %s (priority %d > %d)", by.ID, by.Priority, victim.Priority),
+	})
+	if err != nil {
+		return err
+	}
+	now := o.now()
+	if _, err := o.CreateCommand(ctx, types.RunCommand{
+		ID:        "preempt-" + by.ID,
+		RunID:     victim.ID,
+		Type:      types.CommandTypePause,
+		Actor:     types.CommandActor{Type: types.CommandActorSystem, ID: preemptionActor},
+		IssuedAt:  now,
+		CreatedAt: now,
+	}); err != nil {
+		o.logger.Error().Err(err).Str("run_id", victim.ID).Msg("failed to issue preemption pause command")
+	}
+	o.logger.Info().
+		Str("run_id", victim.ID).
+		Str("preempted_by", by.ID).
+		Str("learner_id", victim.LearnerID).
+		Msg("preempted run")
+	return nil
+}
+
+func findLearner(learners []types.Learner, id string) *types.Learner {
+	for i := range learners {
+		if learners[i].ID == id {
+			return &learners[i]
+		}
+	}
+	return nil
+}
+
+func startedAt(run types.Run) time.Time {
+	if run.StartedAt != nil {
+		return *run.StartedAt
+	}
+	return run.CreatedAt
+}
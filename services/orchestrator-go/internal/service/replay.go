@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// RecordReplayStats stores the replay buffer stats most recently polled for a run.
+func (o *Orchestrator) RecordReplayStats(ctx context.Context, runID string, stats types.ReplayStats) (types.Run, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.Run{}, err
+	}
+	run.Replay = &stats
+	run.UpdatedAt = o.now()
+	if err := o.store.UpdateRun(ctx, run); err != nil {
+		return types.Run{}, err
+	}
+	return run, nil
+}
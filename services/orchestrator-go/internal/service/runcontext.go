@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// RunContext is the identity a run's processes propagate to the services they
+// call: actors send it as gRPC metadata and stamp it onto their transitions,
+// so replay can index experience by run (see proto/README.md).
+type RunContext struct {
+	RunID        string `json:"run_id"`
+	ExperimentID string `json:"experiment_id"`
+	VersionID    string `json:"version_id"`
+	// EnvID is the game the launch manifest names, empty when it names none.
+	EnvID string `json:"env_id,omitempty"`
+}
+
+// RunContext issues the context for a run.
+func (o *Orchestrator) RunContext(ctx context.Context, runID string) (RunContext, error) {
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return RunContext{}, err
+	}
+	return RunContext{
+		RunID:        run.ID,
+		ExperimentID: run.ExperimentID,
+		VersionID:    run.VersionID,
+		EnvID:        types.RequirementsFromManifest(run.LaunchManifest).Env,
+	}, nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/cron"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// scheduleActor is recorded as the creator of runs launched by schedules.
+const scheduleActor = "scheduler"
+
+// CreateScheduleInput captures the payload required to register a schedule.
+type CreateScheduleInput struct {
+	ID             string              `json:"id"`
+	Name           string              `json:"name"`
+	Cron           string              `json:"cron"`
+	ExperimentID   string              `json:"experiment_id"`
+	VersionID      string              `json:"version_id"`
+	LaunchManifest json.RawMessage     `json:"launch_manifest,omitempty"`
+	Overrides      json.RawMessage     `json:"overrides,omitempty"`
+	Priority       int                 `json:"priority"`
+	OverlapPolicy  types.OverlapPolicy `json:"overlap_policy"`
+	Disabled       bool                `json:"disabled"`
+	CreatedBy      string              `json:"created_by"`
+}
+
+// CreateSchedule validates the cron expression and registers the schedule.
+func (o *Orchestrator) CreateSchedule(ctx context.Context, input CreateScheduleInput) (types.Schedule, error) {
+	if input.ID == "" || input.Cron == "" || input.ExperimentID == "" || input.VersionID == "" {
+		return types.Schedule{}, invalidf("id, cron, experiment_id, and version_id are required")
+	}
+	expr, err := cron.Parse(input.Cron)
+	if err != nil {
+		return types.Schedule{}, invalid(err)
+	}
+	switch input.OverlapPolicy {
+	case "":
+		input.OverlapPolicy = types.OverlapSkip
+	case types.OverlapSkip, types.OverlapQueue:
+	default:
+		return types.Schedule{}, invalidf("unsupported overlap_policy %q", input.OverlapPolicy)
+	}
+	if err := o.checkExperimentAcceptsRuns(ctx, input.ExperimentID); err != nil {
+		return types.Schedule{}, err
+	}
+	now := o.now()
+	schedule := types.Schedule{
+		ID:             input.ID,
+		Name:           input.Name,
+		Cron:           input.Cron,
+		ExperimentID:   input.ExperimentID,
+		VersionID:      input.VersionID,
+		LaunchManifest: input.LaunchManifest,
+		Overrides:      input.Overrides,
+		Priority:       input.Priority,
+		OverlapPolicy:  input.OverlapPolicy,
+		Enabled:        !input.Disabled,
+		CreatedBy:      input.CreatedBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if schedule.Enabled {
+		schedule.NextRunAt = nextActivation(expr, now)
+	}
+	if err := o.store.CreateSchedule(ctx, schedule); err != nil {
+		return types.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+// GetSchedule fetches a schedule by ID.
+func (o *Orchestrator) GetSchedule(ctx context.Context, scheduleID string) (types.Schedule, error) {
+	return o.store.GetSchedule(ctx, scheduleID)
+}
+
+// ListSchedules returns all registered schedules.
+func (o *Orchestrator) ListSchedules(ctx context.Context) ([]types.Schedule, error) {
+	return o.store.ListSchedules(ctx)
+}
+
+// SetScheduleEnabled pauses or re-enables a schedule. Re-enabling computes the next
+// activation from now, so activations missed while paused are not backfilled.
+func (o *Orchestrator) SetScheduleEnabled(ctx context.Context, scheduleID string, enabled bool) (types.Schedule, error) {
+	schedule, err := o.store.GetSchedule(ctx, scheduleID)
+	if err != nil {
+		return types.Schedule{}, err
+	}
+	if schedule.Enabled == enabled {
+		return schedule, nil
+	}
+	now := o.now()
+	schedule.Enabled = enabled
+	schedule.NextRunAt = nil
+	if enabled {
+		expr, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			return types.Schedule{}, err
+		}
+		schedule.NextRunAt = nextActivation(expr, now)
+	}
+	schedule.UpdatedAt = now
+	if err := o.store.UpdateSchedule(ctx, schedule); err != nil {
+		return types.Schedule{}, err
+	}
+	return schedule, nil
+}
+
+// RunDueSchedules launches a run for every schedule whose activation time has
+// passed and advances it to its next activation. A schedule that missed several
+// activations (e.g. while the orchestrator was down) fires once.
+func (o *Orchestrator) RunDueSchedules(ctx context.Context) ([]types.Run, error) {
+	now := o.now()
+	due, err := o.store.ListDueSchedules(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	var launched []types.Run
+	for _, schedule := range due {
+		run, fired, err := o.fireSchedule(ctx, schedule, now)
+		if err != nil {
+			o.logger.Error().Err(err).Str("schedule_id", schedule.ID).Msg("failed to launch scheduled run")
+		}
+		if fired {
+			launched = append(launched, run)
+		}
+	}
+	return launched, nil
+}
+
+func (o *Orchestrator) fireSchedule(ctx context.Context, schedule types.Schedule, now time.Time) (types.Run, bool, error) {
+	expr, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return types.Run{}, false, err
+	}
+	activation := *schedule.NextRunAt
+	schedule.NextRunAt = nextActivation(expr, now)
+	schedule.UpdatedAt = now
+
+	var (
+		run      types.Run
+		fired    bool
+		fireErr  error
+		skipping bool
+	)
+	if schedule.OverlapPolicy == types.OverlapSkip && schedule.LastRunID != "" {
+		previous, err := o.store.GetRun(ctx, schedule.LastRunID)
+		skipping = err == nil && !previous.State.IsTerminal()
+	}
+	if skipping {
+		o.logger.Info().Str("schedule_id", schedule.ID).Str("active_run_id", schedule.LastRunID).Msg("skipping schedule activation; previous run still active")
+	} else {
+		// Deriving the ID from the activation time makes a retried activation idempotent.
+		run, fireErr = o.CreateRun(ctx, CreateRunInput{
+			ID:             fmt.Sprintf("%s-%d", schedule.ID, activation.Unix()),
+			ExperimentID:   schedule.ExperimentID,
+			VersionID:      schedule.VersionID,
+			LaunchManifest: schedule.LaunchManifest,
+			Overrides:      schedule.Overrides,
+			Priority:       schedule.Priority,
+			CreatedBy:      scheduleActor,
+			ScheduleID:     schedule.ID,
+		})
+		if fireErr == nil {
+			fired = true
+			schedule.LastRunAt = &now
+			schedule.LastRunID = run.ID
+		}
+	}
+	if err := o.store.UpdateSchedule(ctx, schedule); err != nil {
+		return run, fired, err
+	}
+	return run, fired, fireErr
+}
+
+func nextActivation(expr cron.Schedule, after time.Time) *time.Time {
+	next := expr.Next(after)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// stoppingActor is recorded as the issuer of commands sent by stopping rules.
+const stoppingActor = "early-stopping"
+
+// CreateStoppingRuleInput captures the payload required to attach a stopping rule.
+type CreateStoppingRuleInput struct {
+	ID            string                 `json:"id"`
+	Kind          types.StoppingRuleKind `json:"kind"`
+	Action        types.CommandType      `json:"action"`
+	Heartbeats    int                    `json:"heartbeats,omitempty"`
+	MinDelta      float64                `json:"min_delta,omitempty"`
+	Threshold     float64                `json:"threshold,omitempty"`
+	WindowSeconds int64                  `json:"window_seconds,omitempty"`
+	CreatedBy     string                 `json:"created_by"`
+}
+
+// CreateStoppingRule attaches an early-stopping rule to a run that has not ended.
+func (o *Orchestrator) CreateStoppingRule(ctx context.Context, runID string, input CreateStoppingRuleInput) (types.StoppingRule, error) {
+	if input.ID == "" {
+		return types.StoppingRule{}, invalidf("id is required")
+	}
+	run, err := o.store.GetRun(ctx, runID)
+	if err != nil {
+		return types.StoppingRule{}, err
+	}
+	if run.State.IsTerminal() {
+		return types.StoppingRule{}, fmt.Errorf("%w: run %s is %s", storage.ErrConflict, runID, run.State)
+	}
+	rule := types.StoppingRule{
+		ID:            input.ID,
+		RunID:         runID,
+		Kind:          input.Kind,
+		Action:        input.Action,
+		Heartbeats:    input.Heartbeats,
+		MinDelta:      input.MinDelta,
+		Threshold:     input.Threshold,
+		WindowSeconds: input.WindowSeconds,
+		Enabled:       true,
+		CreatedBy:     input.CreatedBy,
+		CreatedAt:     o.now(),
+	}
+	if err := rule.Validate(); err != nil {
+		return types.StoppingRule{}, invalid(err)
+	}
+	if err := o.store.CreateStoppingRule(ctx, rule); err != nil {
+		return types.StoppingRule{}, err
+	}
+	return rule, nil
+}
+
+// ListStoppingRules returns a run's stopping rules with their evaluation state.
+func (o *Orchestrator) ListStoppingRules(ctx context.Context, runID string) ([]types.StoppingRule, error) {
+	return o.store.ListStoppingRules(ctx, runID)
+}
+
+// DisableStoppingRule stops evaluating a rule; disabling is idempotent.
+func (o *Orchestrator) DisableStoppingRule(ctx context.Context, runID, ruleID string) (types.StoppingRule, error) {
+	rule, err := o.store.GetStoppingRule(ctx, runID, ruleID)
+	if err != nil || !rule.Enabled {
+		return rule, err
+	}
+	rule.Enabled = false
+	if err := o.store.UpdateStoppingRule(ctx, rule); err != nil {
+		return types.StoppingRule{}, err
+	}
+	return rule, nil
+}
+
+// evaluateStoppingRules feeds a heartbeat to the run's enabled rules and returns
+// those that fired, each stamped with its reason. Fired rules are persisted by
+// fireStoppingRules once the run update has landed.
+func (o *Orchestrator) evaluateStoppingRules(ctx context.Context, run types.Run) []types.StoppingRule {
+	if run.State != types.RunStateRunning || run.RuntimeStatus != types.RuntimeStatusRunning {
+		return nil
+	}
+	rules, err := o.store.ListStoppingRules(ctx, run.ID)
+	if err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to load stopping rules")
+		return nil
+	}
+	now := o.now()
+	var fired []types.StoppingRule
+	for _, rule := range rules {
+		if !rule.Enabled || rule.Triggered() {
+			continue
+		}
+		rule, reason := rule.Observe(run.Loss, run.SamplesPerSecond, now)
+		if reason != "" {
+			rule.TriggeredAt = &now
+			rule.Reason = reason
+			fired = append(fired, rule)
+			continue
+		}
+		if err := o.store.UpdateStoppingRule(ctx, rule); err != nil {
+			o.logger.Error().Err(err).Str("run_id", run.ID).Str("rule_id", rule.ID).Msg("failed to save stopping rule state")
+		}
+	}
+	return fired
+}
+
+// stoppingMessage is the run status message naming every rule that fired on
+// one heartbeat and why.
+func stoppingMessage(fired []types.StoppingRule) string {
+	messages := make([]string, len(fired))
+	for i, rule := range fired {
+		messages[i] = fmt.Sprintf("stopping rule %s: %s", rule.ID, rule.Reason)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// fireStoppingRules issues each fired rule's command and records it on the rule.
+func (o *Orchestrator) fireStoppingRules(ctx context.Context, fired []types.StoppingRule) {
+	for _, rule := range fired {
+		command := types.RunCommand{
+			ID:        "stopping-" + rule.ID,
+			RunID:     rule.RunID,
+			Type:      rule.Action,
+			Actor:     types.CommandActor{Type: types.CommandActorSystem, ID: stoppingActor},
+			IssuedAt:  *rule.TriggeredAt,
+			CreatedAt: *rule.TriggeredAt,
+		}
+		if rule.Action == types.CommandTypeTerminate {
+			command.Payload, _ = json.Marshal(types.TerminatePayload{Reason: rule.Reason, FinalCheckpoint: true})
+		}
+		command, err := o.CreateCommand(ctx, command)
+		if err != nil {
+			o.logger.Error().Err(err).Str("run_id", rule.RunID).Str("rule_id", rule.ID).Msg("failed to issue stopping command")
+			continue
+		}
+		rule.CommandID = command.ID
+		if err := o.store.UpdateStoppingRule(ctx, rule); err != nil {
+			o.logger.Error().Err(err).Str("run_id", rule.RunID).Str("rule_id", rule.ID).Msg("failed to record fired stopping rule")
+		}
+		o.logger.Info().
+			Str("run_id", rule.RunID).
+			Str("rule_id", rule.ID).
+			Str("action", string(rule.Action)).
+			Str("reason", rule.Reason).
+			Msg("stopping rule fired")
+	}
+}
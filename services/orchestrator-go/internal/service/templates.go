@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// CreateTemplateInput captures the payload required to register a run template.
+type CreateTemplateInput struct {
+	ID          string                    `json:"id"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description,omitempty"`
+	Manifest    json.RawMessage           `json:"manifest"`
+	Parameters  []types.TemplateParameter `json:"parameters,omitempty"`
+	CreatedBy   string                    `json:"created_by"`
+}
+
+// CreateTemplate validates the placeholders and parameters and stores the template.
+func (o *Orchestrator) CreateTemplate(ctx context.Context, input CreateTemplateInput) (types.RunTemplate, error) {
+	if input.ID == "" {
+		return types.RunTemplate{}, invalidf("id is required")
+	}
+	template := types.RunTemplate{
+		ID:          input.ID,
+		Name:        input.Name,
+		Description: input.Description,
+		Manifest:    input.Manifest,
+		Parameters:  input.Parameters,
+		CreatedBy:   input.CreatedBy,
+		CreatedAt:   o.now(),
+	}
+	for i := range template.Parameters {
+		if template.Parameters[i].Type == "" {
+			template.Parameters[i].Type = types.TemplateParameterString
+		}
+	}
+	if err := template.Validate(); err != nil {
+		return types.RunTemplate{}, invalid(err)
+	}
+	if err := o.store.CreateTemplate(ctx, template); err != nil {
+		return types.RunTemplate{}, err
+	}
+	return template, nil
+}
+
+// GetTemplate fetches a run template by ID.
+func (o *Orchestrator) GetTemplate(ctx context.Context, templateID string) (types.RunTemplate, error) {
+	return o.store.GetTemplate(ctx, templateID)
+}
+
+// ListTemplates returns all registered run templates.
+func (o *Orchestrator) ListTemplates(ctx context.Context) ([]types.RunTemplate, error) {
+	return o.store.ListTemplates(ctx)
+}
+
+// RenderTemplate materializes a template's manifest without creating a run.
+func (o *Orchestrator) RenderTemplate(ctx context.Context, templateID string, params map[string]json.RawMessage) (json.RawMessage, error) {
+	template, err := o.store.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := template.Materialize(params)
+	if err != nil {
+		return nil, invalid(err)
+	}
+	return manifest, nil
+}
+
+// materializeRunTemplate replaces a run's template reference with the rendered
+// manifest. A run names either a template or an explicit launch manifest.
+func (o *Orchestrator) materializeRunTemplate(ctx context.Context, input *CreateRunInput) error {
+	if input.TemplateID == "" {
+		if len(input.Parameters) > 0 {
+			return invalidf("parameters require a template_id")
+		}
+		return nil
+	}
+	if len(input.LaunchManifest) > 0 {
+		return invalidf("launch_manifest and template_id are mutually exclusive")
+	}
+	template, err := o.store.GetTemplate(ctx, input.TemplateID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return invalidf("template %s does not exist", input.TemplateID)
+	}
+	if err != nil {
+		return err
+	}
+	manifest, err := template.Materialize(input.Parameters)
+	if err != nil {
+		return invalidf("template %s: %w", template.ID, err)
+	}
+	input.LaunchManifest = manifest
+	return nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// maxUpdateAttempts bounds how often UpdateRun reapplies an unconditional update
+// that raced another write to the run, such as a heartbeat.
+const maxUpdateAttempts = 3
+
+// UpdateRunInput is a partial update of a run's mutable fields.
+//
+// Without an UpdateMask the fields present are applied: Labels is merged into
+// the run's labels, a nil value removing that label, and null Overrides clears
+// them. An UpdateMask instead names exactly the fields to replace, and a named
+// field missing from the input is cleared; "labels.<key>" names a single label.
+type UpdateRunInput struct {
+	Priority      *int               `json:"priority,omitempty"`
+	StatusMessage *string            `json:"status_message,omitempty"`
+	Labels        map[string]*string `json:"labels,omitempty"`
+	Overrides     json.RawMessage    `json:"overrides,omitempty"`
+
+	UpdateMask []string `json:"-"`
+	// IfMatch, when set, must accept the run as currently stored or the update
+	// fails with ErrPreconditionFailed.
+	IfMatch func(types.Run) bool `json:"-"`
+}
+
+// UpdateRun applies a partial update to a run. Overrides only take effect at
+// launch, so they can change only while the run is queued. The update is written
+// only if the run has not changed since it was read: a conditional update then
+// fails with ErrPreconditionFailed, while an unconditional one is reapplied.
+func (o *Orchestrator) UpdateRun(ctx context.Context, runID string, input UpdateRunInput) (types.Run, error) {
+	for attempt := 1; ; attempt++ {
+		run, err := o.GetRun(ctx, runID)
+		if err != nil {
+			return types.Run{}, err
+		}
+		if input.IfMatch != nil && !input.IfMatch(run) {
+			return types.Run{}, fmt.Errorf("%w: run %s has been modified", ErrPreconditionFailed, runID)
+		}
+		updated, err := input.apply(run)
+		if err != nil {
+			return types.Run{}, err
+		}
+		if sameRunMetadata(run, updated) {
+			return run, nil
+		}
+		if !bytes.Equal(run.Overrides, updated.Overrides) && run.State != types.RunStateQueued {
+			return types.Run{}, fmt.Errorf("%w: overrides can only change while run %s is queued", storage.ErrConflict, runID)
+		}
+		updated.UpdatedAt = o.now()
+		err = o.store.CompareAndSwapRun(ctx, updated, run.UpdatedAt)
+		switch {
+		case err == nil:
+			return updated, nil
+		case !errors.Is(err, storage.ErrConflict):
+			return types.Run{}, err
+		case input.IfMatch != nil:
+			return types.Run{}, fmt.Errorf("%w: run %s has been modified", ErrPreconditionFailed, runID)
+		case attempt == maxUpdateAttempts:
+			return types.Run{}, fmt.Errorf("%w: run %s is being modified concurrently", storage.ErrConflict, runID)
+		}
+	}
+}
+
+// apply returns run with the update applied.
+func (input UpdateRunInput) apply(run types.Run) (types.Run, error) {
+	labels := maps.Clone(run.Labels)
+	if labels == nil {
+		labels = make(map[string]string, len(input.Labels))
+	}
+	var err error
+	if len(input.UpdateMask) == 0 {
+		if input.Priority != nil {
+			run.Priority = *input.Priority
+		}
+		if input.StatusMessage != nil {
+			run.StatusMessage = *input.StatusMessage
+		}
+		for key, value := range input.Labels {
+			setLabel(labels, key, value)
+		}
+		if input.Overrides != nil {
+			if run.Overrides, err = normalizeOverrides(input.Overrides); err != nil {
+				return types.Run{}, err
+			}
+		}
+	}
+	for _, path := range input.UpdateMask {
+		field, key, nested := strings.Cut(path, ".")
+		switch {
+		case path == "priority":
+			run.Priority = 0
+			if input.Priority != nil {
+				run.Priority = *input.Priority
+			}
+		case path == "status_message":
+			run.StatusMessage = ""
+			if input.StatusMessage != nil {
+				run.StatusMessage = *input.StatusMessage
+			}
+		case path == "overrides":
+			if run.Overrides, err = normalizeOverrides(input.Overrides); err != nil {
+				return types.Run{}, err
+			}
+		case path == "labels":
+			clear(labels)
+			for key, value := range input.Labels {
+				setLabel(labels, key, value)
+			}
+		case field == "labels" && nested && key != "":
+			setLabel(labels, key, input.Labels[key])
+		default:
+			return types.Run{}, invalidf("update_mask: unknown field %q", path)
+		}
+	}
+	if err := types.ValidateLabels(labels); err != nil {
+		return types.Run{}, invalid(err)
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+	run.Labels = labels
+	return run, nil
+}
+
+// setLabel sets labels[key] to value, removing it when value is nil.
+func setLabel(labels map[string]string, key string, value *string) {
+	if value == nil {
+		delete(labels, key)
+		return
+	}
+	labels[key] = *value
+}
+
+// normalizeOverrides checks that raw is a JSON object, treating null or nothing
+// as no overrides.
+func normalizeOverrides(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if !isJSONObject(raw) {
+		return nil, invalidf("overrides must be a JSON object")
+	}
+	return raw, nil
+}
+
+// sameRunMetadata reports whether a and b agree on every field UpdateRun sets.
+func sameRunMetadata(a, b types.Run) bool {
+	return a.Priority == b.Priority &&
+		a.StatusMessage == b.StatusMessage &&
+		maps.Equal(a.Labels, b.Labels) &&
+		bytes.Equal(a.Overrides, b.Overrides)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// CreateVersionInput captures the payload required to register a config version.
+type CreateVersionInput struct {
+	ID              string          `json:"id"`
+	Manifest        json.RawMessage `json:"manifest"`
+	Hyperparameters json.RawMessage `json:"hyperparameters,omitempty"`
+	// ManifestSchema, a JSON Schema, constrains the launch manifests of the
+	// version's runs; the version's own manifest must satisfy it too.
+	ManifestSchema json.RawMessage `json:"manifest_schema,omitempty"`
+	Description    string          `json:"description,omitempty"`
+	CreatedBy      string          `json:"created_by"`
+}
+
+// CreateVersion validates and stores an immutable config version for an experiment.
+func (o *Orchestrator) CreateVersion(ctx context.Context, experimentID string, input CreateVersionInput) (types.ConfigVersion, error) {
+	if input.ID == "" {
+		return types.ConfigVersion{}, invalidf("id is required")
+	}
+	if !isJSONObject(input.Manifest) {
+		return types.ConfigVersion{}, invalidf("manifest must be a JSON object")
+	}
+	if len(input.Hyperparameters) > 0 && !isJSONObject(input.Hyperparameters) {
+		return types.ConfigVersion{}, invalidf("hyperparameters must be a JSON object")
+	}
+	if len(input.ManifestSchema) > 0 {
+		schema, err := compileManifestSchema(input.ManifestSchema)
+		if err != nil {
+			return types.ConfigVersion{}, err
+		}
+		if err := checkManifest(schema, "version "+input.ID, input.Manifest); err != nil {
+			return types.ConfigVersion{}, err
+		}
+	}
+	experiment, err := o.store.GetExperiment(ctx, experimentID)
+	if err != nil {
+		return types.ConfigVersion{}, err
+	}
+	if experiment.Archived() {
+		return types.ConfigVersion{}, fmt.Errorf("%w: experiment %s is archived", storage.ErrConflict, experimentID)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, input.Manifest); err != nil {
+		return types.ConfigVersion{}, invalidf("invalid manifest: %w", err)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	version := types.ConfigVersion{
+		ID:              input.ID,
+		ExperimentID:    experimentID,
+		Manifest:        input.Manifest,
+		Hyperparameters: input.Hyperparameters,
+		ManifestHash:    hex.EncodeToString(sum[:]),
+		ManifestSchema:  input.ManifestSchema,
+		Description:     input.Description,
+		CreatedBy:       input.CreatedBy,
+		CreatedAt:       o.now(),
+	}
+	return o.store.CreateVersion(ctx, version)
+}
+
+// GetVersion returns a config version.
+func (o *Orchestrator) GetVersion(ctx context.Context, versionID string) (types.ConfigVersion, error) {
+	return o.store.GetVersion(ctx, versionID)
+}
+
+// ListVersions returns the config versions registered for an experiment.
+func (o *Orchestrator) ListVersions(ctx context.Context, experimentID string) ([]types.ConfigVersion, error) {
+	if _, err := o.store.GetExperiment(ctx, experimentID); err != nil {
+		return nil, err
+	}
+	return o.store.ListVersions(ctx, experimentID)
+}
+
+// DiffVersions compares the manifest and hyperparameters of two versions. Paths are
+// prefixed with "manifest." or "hyperparameters.".
+func (o *Orchestrator) DiffVersions(ctx context.Context, baseID, targetID string) ([]types.ConfigChange, error) {
+	base, err := o.store.GetVersion(ctx, baseID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := o.store.GetVersion(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	baseDoc, err := versionDocument(base)
+	if err != nil {
+		return nil, err
+	}
+	targetDoc, err := versionDocument(target)
+	if err != nil {
+		return nil, err
+	}
+	return types.DiffConfigs(baseDoc, targetDoc)
+}
+
+// resolveRunVersion checks a run's version against the registry. Runs for registered
+// experiments must reference one of that experiment's versions; the version manifest is
+// used when the run does not supply its own.
+func (o *Orchestrator) resolveRunVersion(ctx context.Context, input *CreateRunInput) error {
+	if _, err := o.store.GetExperiment(ctx, input.ExperimentID); errors.Is(err, storage.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	version, err := o.store.GetVersion(ctx, input.VersionID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return invalidf("version %s is not registered for experiment %s", input.VersionID, input.ExperimentID)
+	}
+	if err != nil {
+		return err
+	}
+	if version.ExperimentID != input.ExperimentID {
+		return invalidf("version %s belongs to experiment %s", version.ID, version.ExperimentID)
+	}
+	if len(input.LaunchManifest) == 0 {
+		input.LaunchManifest = version.Manifest
+	}
+	return nil
+}
+
+func versionDocument(version types.ConfigVersion) (json.RawMessage, error) {
+	doc := map[string]json.RawMessage{"manifest": version.Manifest}
+	if len(version.Hyperparameters) > 0 {
+		doc["hyperparameters"] = version.Hyperparameters
+	}
+	return json.Marshal(doc)
+}
+
+func isJSONObject(raw json.RawMessage) bool {
+	var obj map[string]json.RawMessage
+	return len(raw) > 0 && json.Unmarshal(raw, &obj) == nil && obj != nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// watchdogActor is recorded as the initiator of max-duration enforcement.
+const watchdogActor = "watchdog"
+
+// maxDurationReason is the transition and terminate command reason.
+const maxDurationReason = "max duration exceeded"
+
+// WithDefaultMaxDuration caps runs created without a max_duration at d, so that
+// forgotten runs are stopped too; zero leaves them unbounded.
+func (o *Orchestrator) WithDefaultMaxDuration(d time.Duration) {
+	o.defaultMaxDuration = d
+}
+
+// resolveMaxDuration parses the requested max duration, falling back to the
+// default, and the action to take once it is exceeded.
+func (o *Orchestrator) resolveMaxDuration(input CreateRunInput) (time.Duration, types.CommandType, error) {
+	maxDuration := o.defaultMaxDuration
+	if input.MaxDuration != "" {
+		parsed, err := time.ParseDuration(input.MaxDuration)
+		if err != nil || parsed < time.Minute {
+			return 0, "", invalidf("max_duration must be a duration of at least 1m")
+		}
+		maxDuration = parsed
+	}
+	action := input.MaxDurationAction
+	switch action {
+	case "":
+		action = types.CommandTypeTerminate
+	case types.CommandTypeTerminate, types.CommandTypePause:
+	default:
+		return 0, "", invalidf("max_duration_action must be terminate or pause")
+	}
+	return maxDuration, action, nil
+}
+
+// EnforceMaxDurations stops each running run that has run longer than its max
+// duration, returning the runs acted on. A run is terminated, draining through
+// terminating, or paused according to its max_duration_action, and its learner
+// is sent the matching command. The watchdog acts on a run only once, so a run
+// an operator resumes afterwards keeps running.
+func (o *Orchestrator) EnforceMaxDurations(ctx context.Context) ([]types.Run, error) {
+	running, err := o.store.ListRunsByState(ctx, types.RunStateRunning)
+	if err != nil {
+		return nil, err
+	}
+	now := o.now()
+	var stopped []types.Run
+	for _, run := range running {
+		if !run.ExceedsMaxDuration(now) {
+			continue
+		}
+		updated, err := o.enforceMaxDuration(ctx, run, now)
+		if err != nil {
+			o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to enforce max duration")
+			continue
+		}
+		stopped = append(stopped, updated)
+	}
+	return stopped, nil
+}
+
+func (o *Orchestrator) enforceMaxDuration(ctx context.Context, run types.Run, now time.Time) (types.Run, error) {
+	action := run.MaxDurationAction
+	if action == "" {
+		action = types.CommandTypeTerminate
+	}
+	runAction := types.RunActionTerminate
+	if action == types.CommandTypePause {
+		runAction = types.RunActionPause
+	}
+	next, err := run.State.ActionTarget(runAction)
+	if err != nil {
+		return run, err
+	}
+	run.MaxDurationExceededAt = &now
+	run, err = o.transition(ctx, run, TransitionInput{ToState: next, ChangedBy: watchdogActor, Reason: maxDurationReason})
+	if err != nil {
+		return run, err
+	}
+	command := types.RunCommand{
+		ID:        "watchdog-" + run.ID,
+		RunID:     run.ID,
+		Type:      action,
+		Actor:     types.CommandActor{Type: types.CommandActorSystem, ID: watchdogActor},
+		IssuedAt:  now,
+		CreatedAt: now,
+	}
+	if action == types.CommandTypeTerminate {
+		command.Payload, _ = json.Marshal(types.TerminatePayload{Reason: maxDurationReason, FinalCheckpoint: true})
+	}
+	if _, err := o.CreateCommand(ctx, command); err != nil {
+		o.logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to issue max duration command")
+	}
+	o.logger.Info().
+		Str("run_id", run.ID).
+		Str("action", string(action)).
+		Int64("max_duration_seconds", run.MaxDurationSeconds).
+		Msg("run exceeded its max duration")
+	return run, nil
+}
@@ -0,0 +1,81 @@
+// Package shutdown stops the orchestrator's components in order under a shared
+// deadline, so that, for example, background loops stop producing events before
+// the publishers flush them.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// StopFunc stops a component, returning early with an error if ctx expires.
+type StopFunc func(ctx context.Context) error
+
+type component struct {
+	name string
+	stop StopFunc
+}
+
+// Coordinator stops registered components in registration order.
+type Coordinator struct {
+	components []component
+	logger     zerolog.Logger
+}
+
+// New creates an empty coordinator.
+func New(logger zerolog.Logger) *Coordinator {
+	return &Coordinator{logger: logger}
+}
+
+// Add registers a component to stop after those already registered.
+func (c *Coordinator) Add(name string, stop StopFunc) {
+	c.components = append(c.components, component{name: name, stop: stop})
+}
+
+// Shutdown stops every component within timeout. A component that fails or
+// overruns the deadline is logged and does not prevent the rest from being
+// stopped; the returned error names each one.
+func (c *Coordinator) Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	var failed []string
+	for _, comp := range c.components {
+		started := time.Now()
+		if err := comp.stop(ctx); err != nil {
+			c.logger.Error().Err(err).Str("component", comp.name).Dur("elapsed", time.Since(started)).Msg("Component failed to stop")
+			errs = append(errs, fmt.Errorf("%s: %w", comp.name, err))
+			failed = append(failed, comp.name)
+			continue
+		}
+		c.logger.Info().Str("component", comp.name).Dur("elapsed", time.Since(started)).Msg("Component stopped")
+	}
+	if len(failed) > 0 {
+		c.logger.Error().Str("failed", strings.Join(failed, ", ")).Msg("Shutdown incomplete")
+	}
+	return errors.Join(errs...)
+}
+
+// Wait adapts a blocking stop function without a context. If ctx expires first
+// the function is left running and ctx's error is returned.
+func Wait(stop func()) StopFunc {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			stop()
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestShutdownStopsEveryComponentInOrder(t *testing.T) {
+	c := New(*zerolog.New(io.Discard))
+	var order []string
+	record := func(name string, err error) StopFunc {
+		return func(context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	c.Add("http", record("http", nil))
+	c.Add("loops", record("loops", errors.New("boom")))
+	block := make(chan struct{})
+	defer close(block)
+	c.Add("publisher", Wait(func() { <-block }))
+	c.Add("database", record("database", nil))
+
+	err := c.Shutdown(50 * time.Millisecond)
+	if got := strings.Join(order, ","); got != "http,loops,database" {
+		t.Fatalf("expected components stopped in order despite failures, got %s", got)
+	}
+	if err == nil || !strings.Contains(err.Error(), "loops: boom") || !strings.Contains(err.Error(), "publisher: context deadline exceeded") {
+		t.Fatalf("expected the failed and overrunning components named, got %v", err)
+	}
+
+	ok := New(*zerolog.New(io.Discard))
+	ok.Add("publisher", Wait(func() {}))
+	if err := ok.Shutdown(time.Second); err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+}
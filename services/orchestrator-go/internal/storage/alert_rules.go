@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// AlertRuleStore persists per-run alert rules and their evaluation state.
+type AlertRuleStore interface {
+	// CreateAlertRule inserts a rule; a duplicate ID within the run returns ErrConflict.
+	CreateAlertRule(ctx context.Context, rule types.AlertRule) error
+	GetAlertRule(ctx context.Context, runID, ruleID string) (types.AlertRule, error)
+	UpdateAlertRule(ctx context.Context, rule types.AlertRule) error
+	// ListAlertRules returns a run's rules in creation order.
+	ListAlertRules(ctx context.Context, runID string) ([]types.AlertRule, error)
+}
+
+// CreateAlertRule attaches a rule to its run.
+func (m *MemoryStore) CreateAlertRule(_ context.Context, rule types.AlertRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[rule.RunID]; !exists {
+		return ErrNotFound
+	}
+	for _, existing := range m.alertRules[rule.RunID] {
+		if existing.ID == rule.ID {
+			return ErrConflict
+		}
+	}
+	m.alertRules[rule.RunID] = append(m.alertRules[rule.RunID], rule)
+	return nil
+}
+
+// GetAlertRule fetches a rule by run and ID.
+func (m *MemoryStore) GetAlertRule(_ context.Context, runID, ruleID string) (types.AlertRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, rule := range m.alertRules[runID] {
+		if rule.ID == ruleID {
+			return rule, nil
+		}
+	}
+	return types.AlertRule{}, ErrNotFound
+}
+
+// UpdateAlertRule replaces the stored rule.
+func (m *MemoryStore) UpdateAlertRule(_ context.Context, rule types.AlertRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rules := m.alertRules[rule.RunID]
+	for i := range rules {
+		if rules[i].ID == rule.ID {
+			rules[i] = rule
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// ListAlertRules returns a copy of a run's rules.
+func (m *MemoryStore) ListAlertRules(_ context.Context, runID string) ([]types.AlertRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	return append([]types.AlertRule{}, m.alertRules[runID]...), nil
+}
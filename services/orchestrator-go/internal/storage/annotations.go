@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// AnnotationStore persists the notes operators attach to runs.
+type AnnotationStore interface {
+	// CreateAnnotation inserts a note; a duplicate ID within the run returns ErrConflict.
+	CreateAnnotation(ctx context.Context, annotation types.Annotation) error
+	GetAnnotation(ctx context.Context, runID, annotationID string) (types.Annotation, error)
+	// ListAnnotations returns a run's notes with from <= At < to, ordered by At.
+	// A zero from or to leaves that side of the window open, as for metrics.
+	ListAnnotations(ctx context.Context, runID string, from, to time.Time) ([]types.Annotation, error)
+}
+
+// CreateAnnotation adds a note to its run, keeping the run's notes ordered by At.
+func (m *MemoryStore) CreateAnnotation(_ context.Context, annotation types.Annotation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[annotation.RunID]; !exists {
+		return ErrNotFound
+	}
+	stored := m.annotations[annotation.RunID]
+	for _, existing := range stored {
+		if existing.ID == annotation.ID {
+			return ErrConflict
+		}
+	}
+	i := sort.Search(len(stored), func(i int) bool { return stored[i].At.After(annotation.At) })
+	stored = append(stored, types.Annotation{})
+	copy(stored[i+1:], stored[i:])
+	stored[i] = annotation
+	m.annotations[annotation.RunID] = stored
+	return nil
+}
+
+// GetAnnotation fetches a note by run and ID.
+func (m *MemoryStore) GetAnnotation(_ context.Context, runID, annotationID string) (types.Annotation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, annotation := range m.annotations[runID] {
+		if annotation.ID == annotationID {
+			return annotation, nil
+		}
+	}
+	return types.Annotation{}, ErrNotFound
+}
+
+// ListAnnotations returns a copy of the run's notes within the window.
+func (m *MemoryStore) ListAnnotations(_ context.Context, runID string, from, to time.Time) ([]types.Annotation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	annotations := []types.Annotation{}
+	for _, annotation := range m.annotations[runID] {
+		if (!from.IsZero() && annotation.At.Before(from)) || (!to.IsZero() && !annotation.At.Before(to)) {
+			continue
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations, nil
+}
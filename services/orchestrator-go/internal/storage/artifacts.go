@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// ArtifactStore persists artifact metadata; the blobs live in the artifact backend.
+type ArtifactStore interface {
+	CreateArtifact(ctx context.Context, artifact types.Artifact) error
+	GetArtifact(ctx context.Context, runID, name string) (types.Artifact, error)
+	SaveArtifact(ctx context.Context, artifact types.Artifact) error
+	// ListArtifacts returns a run's artifacts ordered by name.
+	ListArtifacts(ctx context.Context, runID string) ([]types.Artifact, error)
+}
+
+// CreateArtifact inserts an artifact, enforcing unique names per run.
+func (m *MemoryStore) CreateArtifact(_ context.Context, artifact types.Artifact) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[artifact.RunID]; !exists {
+		return ErrNotFound
+	}
+	runArtifacts, ok := m.artifacts[artifact.RunID]
+	if !ok {
+		runArtifacts = make(map[string]types.Artifact)
+		m.artifacts[artifact.RunID] = runArtifacts
+	}
+	if _, exists := runArtifacts[artifact.Name]; exists {
+		return ErrConflict
+	}
+	runArtifacts[artifact.Name] = artifact
+	return nil
+}
+
+// GetArtifact fetches an artifact by run and name.
+func (m *MemoryStore) GetArtifact(_ context.Context, runID, name string) (types.Artifact, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	artifact, ok := m.artifacts[runID][name]
+	if !ok {
+		return types.Artifact{}, ErrNotFound
+	}
+	return artifact, nil
+}
+
+// SaveArtifact overwrites an existing artifact.
+func (m *MemoryStore) SaveArtifact(_ context.Context, artifact types.Artifact) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.artifacts[artifact.RunID][artifact.Name]; !ok {
+		return ErrNotFound
+	}
+	m.artifacts[artifact.RunID][artifact.Name] = artifact
+	return nil
+}
+
+// ListArtifacts returns a run's artifacts sorted by name.
+func (m *MemoryStore) ListArtifacts(_ context.Context, runID string) ([]types.Artifact, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	artifacts := make([]types.Artifact, 0, len(m.artifacts[runID]))
+	for _, artifact := range m.artifacts[runID] {
+		artifacts = append(artifacts, artifact)
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Name < artifacts[j].Name })
+	return artifacts, nil
+}
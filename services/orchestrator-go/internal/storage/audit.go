@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// MaxAuditEntries bounds the in-memory audit log; the oldest entries are dropped
+// once it is full. Production deployments keep the full history in Postgres.
+const MaxAuditEntries = 100000
+
+// AuditFilter narrows an audit log listing. Zero fields match everything.
+type AuditFilter struct {
+	Actor      string
+	Method     string
+	PathPrefix string
+	// MinStatus keeps entries whose response status is at least this value, e.g.
+	// 400 to find rejected calls.
+	MinStatus int
+	Since     time.Time
+	Until     time.Time
+	After     Cursor
+	Limit     int
+}
+
+func (f AuditFilter) matches(entry types.AuditEntry) bool {
+	switch {
+	case f.Actor != "" && entry.Actor != f.Actor,
+		f.Method != "" && !strings.EqualFold(entry.Method, f.Method),
+		f.PathPrefix != "" && !strings.HasPrefix(entry.Path, f.PathPrefix),
+		entry.Status < f.MinStatus,
+		!f.Since.IsZero() && entry.At.Before(f.Since),
+		!f.Until.IsZero() && !entry.At.Before(f.Until):
+		return false
+	}
+	return f.After.after(entry.At, entry.ID)
+}
+
+// AuditStore persists the API audit log.
+type AuditStore interface {
+	AppendAudit(ctx context.Context, entry types.AuditEntry) error
+	// ListAudit returns matching entries oldest first and, when more remain, the
+	// cursor to resume from.
+	ListAudit(ctx context.Context, filter AuditFilter) ([]types.AuditEntry, Cursor, error)
+}
+
+// AppendAudit records an entry, evicting the oldest once the log is full.
+func (m *MemoryStore) AppendAudit(_ context.Context, entry types.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audit = append(m.audit, entry)
+	if overflow := len(m.audit) - MaxAuditEntries; overflow > 0 {
+		m.audit = append(m.audit[:0:0], m.audit[overflow:]...)
+	}
+	return nil
+}
+
+// ListAudit returns matching entries ordered by time, then ID.
+func (m *MemoryStore) ListAudit(_ context.Context, filter AuditFilter) ([]types.AuditEntry, Cursor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var matched []types.AuditEntry
+	for _, entry := range m.audit {
+		if filter.matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].At.Equal(matched[j].At) {
+			return matched[i].At.Before(matched[j].At)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	if filter.Limit <= 0 || len(matched) <= filter.Limit {
+		return matched, Cursor{}, nil
+	}
+	page := matched[:filter.Limit]
+	last := page[len(page)-1]
+	return page, Cursor{At: last.At, ID: last.ID}, nil
+}
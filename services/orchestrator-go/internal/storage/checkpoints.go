@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// CheckpointStore persists the checkpoints registered for each run.
+type CheckpointStore interface {
+	CreateCheckpoint(ctx context.Context, checkpoint types.Checkpoint) error
+	GetCheckpoint(ctx context.Context, runID string, version int64) (types.Checkpoint, error)
+	// ListCheckpoints returns a run's checkpoints in ascending version order.
+	ListCheckpoints(ctx context.Context, runID string) ([]types.Checkpoint, error)
+	// PromoteCheckpoint marks a checkpoint promoted, clearing any previously promoted
+	// checkpoint of the same run.
+	PromoteCheckpoint(ctx context.Context, runID string, version int64, promotedBy string, now time.Time) (types.Checkpoint, error)
+}
+
+// CreateCheckpoint inserts a checkpoint, enforcing one per run and version.
+func (m *MemoryStore) CreateCheckpoint(_ context.Context, checkpoint types.Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[checkpoint.RunID]; !exists {
+		return ErrNotFound
+	}
+	runCheckpoints, ok := m.checkpoints[checkpoint.RunID]
+	if !ok {
+		runCheckpoints = make(map[int64]types.Checkpoint)
+		m.checkpoints[checkpoint.RunID] = runCheckpoints
+	}
+	if _, exists := runCheckpoints[checkpoint.Version]; exists {
+		return ErrConflict
+	}
+	runCheckpoints[checkpoint.Version] = checkpoint
+	return nil
+}
+
+// GetCheckpoint fetches a checkpoint by run and version.
+func (m *MemoryStore) GetCheckpoint(_ context.Context, runID string, version int64) (types.Checkpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	checkpoint, ok := m.checkpoints[runID][version]
+	if !ok {
+		return types.Checkpoint{}, ErrNotFound
+	}
+	return checkpoint, nil
+}
+
+// ListCheckpoints returns a run's checkpoints oldest version first.
+func (m *MemoryStore) ListCheckpoints(_ context.Context, runID string) ([]types.Checkpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	checkpoints := make([]types.Checkpoint, 0, len(m.checkpoints[runID]))
+	for _, checkpoint := range m.checkpoints[runID] {
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Version < checkpoints[j].Version })
+	return checkpoints, nil
+}
+
+// PromoteCheckpoint marks a single checkpoint of the run as promoted.
+func (m *MemoryStore) PromoteCheckpoint(_ context.Context, runID string, version int64, promotedBy string, now time.Time) (types.Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	runCheckpoints := m.checkpoints[runID]
+	target, ok := runCheckpoints[version]
+	if !ok {
+		return types.Checkpoint{}, ErrNotFound
+	}
+	for v, checkpoint := range runCheckpoints {
+		if checkpoint.Promoted && v != version {
+			checkpoint.Promoted = false
+			runCheckpoints[v] = checkpoint
+		}
+	}
+	if !target.Promoted {
+		at := now
+		target.Promoted = true
+		target.PromotedAt = &at
+		target.PromotedBy = promotedBy
+		runCheckpoints[version] = target
+	}
+	return target, nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// CommandFilter narrows a ListCommands page.
+type CommandFilter struct {
+	// Statuses restricts results to commands in any of the given statuses; empty means all.
+	Statuses []types.CommandStatus
+	After    Cursor
+	Limit    int
+}
+
+func (f CommandFilter) matches(cmd types.RunCommand) bool {
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	status := cmd.Status()
+	for _, want := range f.Statuses {
+		if status == want {
+			return true
+		}
+	}
+	return false
+}
+
+// ListCommands returns a page of a run's commands ordered by issue time, plus the
+// cursor for the next page (zero when there are no more). An empty runID lists
+// commands across every run.
+func (m *MemoryStore) ListCommands(_ context.Context, runID string, filter CommandFilter) ([]types.RunCommand, Cursor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; runID != "" && !exists {
+		return nil, Cursor{}, ErrNotFound
+	}
+	var matched []types.RunCommand
+	for id, runCommands := range m.commands {
+		if runID != "" && id != runID {
+			continue
+		}
+		for _, cmd := range runCommands {
+			if filter.matches(cmd) && filter.After.after(cmd.IssuedAt, cmd.ID) {
+				matched = append(matched, cmd)
+			}
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].IssuedAt.Equal(matched[j].IssuedAt) {
+			return matched[i].IssuedAt.Before(matched[j].IssuedAt)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	if filter.Limit <= 0 || len(matched) <= filter.Limit {
+		return matched, Cursor{}, nil
+	}
+	page := matched[:filter.Limit]
+	last := page[len(page)-1]
+	return page, Cursor{At: last.IssuedAt, ID: last.ID}, nil
+}
+
+// ReclaimCommands requeues or dead-letters delivered commands that were never acked.
+func (m *MemoryStore) ReclaimCommands(_ context.Context, runID string, deliveredBefore time.Time, maxAttempts int, now time.Time) ([]types.RunCommand, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var reclaimed []types.RunCommand
+	for id, runCommands := range m.commands {
+		if runID != "" && id != runID {
+			continue
+		}
+		for cmdID, cmd := range runCommands {
+			if cmd.Status() != types.CommandStatusDelivered || !cmd.DeliveredAt.Before(deliveredBefore) {
+				continue
+			}
+			if cmd.DeliveryAttempts >= maxAttempts {
+				at := now
+				cmd.DeadLetteredAt = &at
+			} else {
+				cmd.DeliveredAt = nil
+			}
+			runCommands[cmdID] = cmd
+			reclaimed = append(reclaimed, cmd)
+		}
+	}
+	sort.Slice(reclaimed, func(i, j int) bool {
+		return reclaimed[i].IssuedAt.Before(reclaimed[j].IssuedAt)
+	})
+	return reclaimed, nil
+}
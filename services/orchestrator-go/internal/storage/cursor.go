@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor indicates a pagination cursor that was not produced by this store.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a keyset position: the sort timestamp and ID of the last item returned.
+type Cursor struct {
+	At time.Time
+	ID string
+}
+
+// Encode returns the opaque string form handed to API clients.
+func (c Cursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.At.UTC().Format(time.RFC3339Nano) + "|" + c.ID))
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode. An empty string yields
+// the zero cursor, meaning "from the start".
+func DecodeCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return Cursor{At: t, ID: id}, nil
+}
+
+// IsZero reports whether the cursor points at the start of a listing.
+func (c Cursor) IsZero() bool {
+	return c.ID == ""
+}
+
+// after reports whether (at, id) sorts strictly after the cursor in ascending order.
+func (c Cursor) after(at time.Time, id string) bool {
+	if c.IsZero() {
+		return true
+	}
+	if !at.Equal(c.At) {
+		return at.After(c.At)
+	}
+	return id > c.ID
+}
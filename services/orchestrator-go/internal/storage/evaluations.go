@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// EvaluationStore persists evaluation reports.
+type EvaluationStore interface {
+	// CreateEvaluation inserts a report; a duplicate ID within the run returns ErrConflict.
+	CreateEvaluation(ctx context.Context, evaluation types.Evaluation) error
+	GetEvaluation(ctx context.Context, runID, evaluationID string) (types.Evaluation, error)
+	// ListEvaluations returns a run's reports oldest first.
+	ListEvaluations(ctx context.Context, runID string) ([]types.Evaluation, error)
+}
+
+// CreateEvaluation appends an evaluation report to its run.
+func (m *MemoryStore) CreateEvaluation(_ context.Context, evaluation types.Evaluation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[evaluation.RunID]; !exists {
+		return ErrNotFound
+	}
+	for _, existing := range m.evaluations[evaluation.RunID] {
+		if existing.ID == evaluation.ID {
+			return ErrConflict
+		}
+	}
+	m.evaluations[evaluation.RunID] = append(m.evaluations[evaluation.RunID], evaluation)
+	return nil
+}
+
+// GetEvaluation fetches a report by run and ID.
+func (m *MemoryStore) GetEvaluation(_ context.Context, runID, evaluationID string) (types.Evaluation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, evaluation := range m.evaluations[runID] {
+		if evaluation.ID == evaluationID {
+			return evaluation, nil
+		}
+	}
+	return types.Evaluation{}, ErrNotFound
+}
+
+// ListEvaluations returns a copy of a run's reports.
+func (m *MemoryStore) ListEvaluations(_ context.Context, runID string) ([]types.Evaluation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	return append([]types.Evaluation{}, m.evaluations[runID]...), nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// MaxEventsPerRun bounds the event log retained for a run; once full, the
+// oldest events are dropped as new ones are published.
+const MaxEventsPerRun = 10000
+
+// EventFilter selects events from a run's event log.
+type EventFilter struct {
+	// After skips events with ID <= After.
+	After int64
+	// Since skips events published before it; zero leaves it open.
+	Since time.Time
+	// Type keeps only events of that type; empty keeps all.
+	Type string
+	// Limit caps the events returned; zero means no limit.
+	Limit int
+}
+
+// EventStore persists the events published for each run.
+type EventStore interface {
+	// AppendRunEvent assigns the event the next ID, stores it and returns it.
+	AppendRunEvent(ctx context.Context, event types.RunEvent) (types.RunEvent, error)
+	// ListRunEvents returns the run's events matching filter, oldest first.
+	ListRunEvents(ctx context.Context, runID string, filter EventFilter) ([]types.RunEvent, error)
+}
+
+// AppendRunEvent records an event, evicting the run's oldest once its log is full.
+func (m *MemoryStore) AppendRunEvent(_ context.Context, event types.RunEvent) (types.RunEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventSeq++
+	event.ID = m.eventSeq
+	stored := append(m.events[event.RunID], event)
+	if overflow := len(stored) - MaxEventsPerRun; overflow > 0 {
+		stored = append(stored[:0:0], stored[overflow:]...)
+	}
+	m.events[event.RunID] = stored
+	return event, nil
+}
+
+// ListRunEvents returns the retained events after filter.After.
+func (m *MemoryStore) ListRunEvents(_ context.Context, runID string, filter EventFilter) ([]types.RunEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	stored := m.events[runID]
+	start := sort.Search(len(stored), func(i int) bool { return stored[i].ID > filter.After })
+	events := []types.RunEvent{}
+	for _, event := range stored[start:] {
+		if event.PublishedAt.Before(filter.Since) || (filter.Type != "" && event.Type != filter.Type) {
+			continue
+		}
+		events = append(events, event)
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+	}
+	return events, nil
+}
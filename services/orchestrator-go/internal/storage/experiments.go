@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// ExperimentStore persists experiment templates and the run lookups keyed by them.
+type ExperimentStore interface {
+	CreateExperiment(ctx context.Context, experiment types.Experiment) error
+	GetExperiment(ctx context.Context, id string) (types.Experiment, error)
+	UpdateExperiment(ctx context.Context, experiment types.Experiment) error
+	ListExperiments(ctx context.Context, includeArchived bool) ([]types.Experiment, error)
+	ListRunsByExperiment(ctx context.Context, experimentID string) ([]types.Run, error)
+}
+
+// CreateExperiment inserts a new experiment, enforcing uniqueness.
+func (m *MemoryStore) CreateExperiment(_ context.Context, experiment types.Experiment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.experiments[experiment.ID]; exists {
+		return ErrConflict
+	}
+	m.experiments[experiment.ID] = experiment
+	return nil
+}
+
+// GetExperiment fetches an experiment by ID.
+func (m *MemoryStore) GetExperiment(_ context.Context, id string) (types.Experiment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	experiment, ok := m.experiments[id]
+	if !ok {
+		return types.Experiment{}, ErrNotFound
+	}
+	return experiment, nil
+}
+
+// UpdateExperiment replaces the stored experiment.
+func (m *MemoryStore) UpdateExperiment(_ context.Context, experiment types.Experiment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.experiments[experiment.ID]; !ok {
+		return ErrNotFound
+	}
+	m.experiments[experiment.ID] = experiment
+	return nil
+}
+
+// ListExperiments returns experiments newest first, skipping archived ones unless requested.
+func (m *MemoryStore) ListExperiments(_ context.Context, includeArchived bool) ([]types.Experiment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	experiments := make([]types.Experiment, 0, len(m.experiments))
+	for _, experiment := range m.experiments {
+		if experiment.Archived() && !includeArchived {
+			continue
+		}
+		experiments = append(experiments, experiment)
+	}
+	sort.Slice(experiments, func(i, j int) bool {
+		return experiments[i].CreatedAt.After(experiments[j].CreatedAt)
+	})
+	return experiments, nil
+}
+
+// ListRunsByExperiment returns the runs launched from an experiment, newest first.
+func (m *MemoryStore) ListRunsByExperiment(_ context.Context, experimentID string) ([]types.Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var runs []types.Run
+	for _, run := range m.runs {
+		if run.ExperimentID == experimentID {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
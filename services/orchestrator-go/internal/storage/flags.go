@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// FeatureFlagStore persists feature flags and their rollout overrides.
+type FeatureFlagStore interface {
+	// PutFeatureFlag creates or replaces flag.Name, stamping the next revision,
+	// and returns what was stored.
+	PutFeatureFlag(ctx context.Context, flag types.FeatureFlag) (types.FeatureFlag, error)
+	GetFeatureFlag(ctx context.Context, name string) (types.FeatureFlag, error)
+	ListFeatureFlags(ctx context.Context) ([]types.FeatureFlag, error)
+}
+
+// PutFeatureFlag upserts a flag.
+func (m *MemoryStore) PutFeatureFlag(_ context.Context, flag types.FeatureFlag) (types.FeatureFlag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	flag.Revision = m.featureFlags[flag.Name].Revision + 1
+	flag.Experiments = maps.Clone(flag.Experiments)
+	flag.Runs = maps.Clone(flag.Runs)
+	m.featureFlags[flag.Name] = flag
+	return flag, nil
+}
+
+// GetFeatureFlag fetches a flag by name.
+func (m *MemoryStore) GetFeatureFlag(_ context.Context, name string) (types.FeatureFlag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	flag, ok := m.featureFlags[name]
+	if !ok {
+		return types.FeatureFlag{}, ErrNotFound
+	}
+	return flag, nil
+}
+
+// ListFeatureFlags returns every flag ordered by name.
+func (m *MemoryStore) ListFeatureFlags(_ context.Context) ([]types.FeatureFlag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	flags := make([]types.FeatureFlag, 0, len(m.featureFlags))
+	for _, flag := range m.featureFlags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags, nil
+}
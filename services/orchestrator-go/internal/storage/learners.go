@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// LearnerStore persists registered learner processes.
+type LearnerStore interface {
+	// SaveLearner inserts or replaces a learner registration.
+	SaveLearner(ctx context.Context, learner types.Learner) error
+	GetLearner(ctx context.Context, id string) (types.Learner, error)
+	ListLearners(ctx context.Context) ([]types.Learner, error)
+	DeleteLearner(ctx context.Context, id string) error
+}
+
+// SaveLearner upserts a learner registration.
+func (m *MemoryStore) SaveLearner(_ context.Context, learner types.Learner) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.learners[learner.ID] = learner
+	return nil
+}
+
+// GetLearner fetches a learner by ID.
+func (m *MemoryStore) GetLearner(_ context.Context, id string) (types.Learner, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	learner, ok := m.learners[id]
+	if !ok {
+		return types.Learner{}, ErrNotFound
+	}
+	return learner, nil
+}
+
+// ListLearners returns all learners ordered by registration time.
+func (m *MemoryStore) ListLearners(_ context.Context) ([]types.Learner, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	learners := make([]types.Learner, 0, len(m.learners))
+	for _, learner := range m.learners {
+		learners = append(learners, learner)
+	}
+	sort.Slice(learners, func(i, j int) bool {
+		if !learners[i].RegisteredAt.Equal(learners[j].RegisteredAt) {
+			return learners[i].RegisteredAt.Before(learners[j].RegisteredAt)
+		}
+		return learners[i].ID < learners[j].ID
+	})
+	return learners, nil
+}
+
+// DeleteLearner removes a learner registration.
+func (m *MemoryStore) DeleteLearner(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.learners[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.learners, id)
+	return nil
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// MaxLogLinesPerRun bounds the log lines retained for a run; once full, the
+// oldest lines are dropped as new ones arrive.
+const MaxLogLinesPerRun = 5000
+
+// LogFilter selects a run's log lines.
+type LogFilter struct {
+	// After skips lines with Seq <= After.
+	After int64
+	// MinLevel drops lines less severe than it; empty keeps every level.
+	MinLevel types.LogLevel
+	// Limit caps the lines returned; zero means no limit.
+	Limit int
+}
+
+// LogStore persists the log lines pushed for each run.
+type LogStore interface {
+	// AppendLogs assigns the lines consecutive sequence numbers following the
+	// run's last line, stores them and returns them with Seq set.
+	AppendLogs(ctx context.Context, runID string, lines []types.LogLine) ([]types.LogLine, error)
+	// ListLogs returns the lines matching filter, oldest first.
+	ListLogs(ctx context.Context, runID string, filter LogFilter) ([]types.LogLine, error)
+}
+
+// AppendLogs records the lines, evicting the oldest once the run's buffer is full.
+func (m *MemoryStore) AppendLogs(_ context.Context, runID string, lines []types.LogLine) ([]types.LogLine, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	stored := m.logs[runID]
+	var seq int64
+	if len(stored) > 0 {
+		seq = stored[len(stored)-1].Seq
+	}
+	appended := make([]types.LogLine, len(lines))
+	for i, line := range lines {
+		seq++
+		line.Seq = seq
+		appended[i] = line
+	}
+	stored = append(stored, appended...)
+	if overflow := len(stored) - MaxLogLinesPerRun; overflow > 0 {
+		stored = append(stored[:0:0], stored[overflow:]...)
+	}
+	m.logs[runID] = stored
+	return appended, nil
+}
+
+// ListLogs returns the retained lines after filter.After.
+func (m *MemoryStore) ListLogs(_ context.Context, runID string, filter LogFilter) ([]types.LogLine, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	stored := m.logs[runID]
+	start := sort.Search(len(stored), func(i int) bool { return stored[i].Seq > filter.After })
+	lines := []types.LogLine{}
+	for _, line := range stored[start:] {
+		if filter.MinLevel != "" && !line.Level.AtLeast(filter.MinLevel) {
+			continue
+		}
+		lines = append(lines, line)
+		if filter.Limit > 0 && len(lines) == filter.Limit {
+			break
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// ManifestSchemaStore persists the launch manifest schema of each environment.
+type ManifestSchemaStore interface {
+	// PutManifestSchema registers or replaces the schema for schema.Env, stamping
+	// the next revision, and returns what was stored.
+	PutManifestSchema(ctx context.Context, schema types.ManifestSchema) (types.ManifestSchema, error)
+	GetManifestSchema(ctx context.Context, env string) (types.ManifestSchema, error)
+	ListManifestSchemas(ctx context.Context) ([]types.ManifestSchema, error)
+}
+
+// PutManifestSchema upserts an environment's schema.
+func (m *MemoryStore) PutManifestSchema(_ context.Context, schema types.ManifestSchema) (types.ManifestSchema, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	schema.Revision = m.manifestSchemas[schema.Env].Revision + 1
+	m.manifestSchemas[schema.Env] = schema
+	return schema, nil
+}
+
+// GetManifestSchema fetches the schema registered for env.
+func (m *MemoryStore) GetManifestSchema(_ context.Context, env string) (types.ManifestSchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	schema, ok := m.manifestSchemas[env]
+	if !ok {
+		return types.ManifestSchema{}, ErrNotFound
+	}
+	return schema, nil
+}
+
+// ListManifestSchemas returns all registered schemas ordered by environment.
+func (m *MemoryStore) ListManifestSchemas(_ context.Context) ([]types.ManifestSchema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	schemas := make([]types.ManifestSchema, 0, len(m.manifestSchemas))
+	for _, schema := range m.manifestSchemas {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Env < schemas[j].Env })
+	return schemas, nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// MaxMetricPointsPerRun bounds the in-memory metrics history of a run; once full,
+// the oldest points are dropped as new heartbeats arrive.
+const MaxMetricPointsPerRun = 20000
+
+// MetricsStore persists the heartbeat metrics history of each run.
+type MetricsStore interface {
+	AppendMetricPoint(ctx context.Context, runID string, point types.MetricPoint) error
+	// ListMetricPoints returns a run's points with from <= At < to, oldest first.
+	// A zero from or to leaves that side of the window open.
+	ListMetricPoints(ctx context.Context, runID string, from, to time.Time) ([]types.MetricPoint, error)
+	// PruneMetricPoints deletes points recorded before cutoff across all runs and
+	// returns how many were removed.
+	PruneMetricPoints(ctx context.Context, cutoff time.Time) (int, error)
+	// DeleteMetricPoints drops a run's whole history and returns how many points
+	// were removed.
+	DeleteMetricPoints(ctx context.Context, runID string) (int, error)
+}
+
+// AppendMetricPoint records a point, evicting the oldest once the run's buffer is full.
+func (m *MemoryStore) AppendMetricPoint(_ context.Context, runID string, point types.MetricPoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[runID]; !exists {
+		return ErrNotFound
+	}
+	points := append(m.metrics[runID], point)
+	if overflow := len(points) - MaxMetricPointsPerRun; overflow > 0 {
+		points = append(points[:0:0], points[overflow:]...)
+	}
+	m.metrics[runID] = points
+	return nil
+}
+
+// ListMetricPoints returns the points inside the window.
+func (m *MemoryStore) ListMetricPoints(_ context.Context, runID string, from, to time.Time) ([]types.MetricPoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	points := m.metrics[runID]
+	start := 0
+	if !from.IsZero() {
+		start = sort.Search(len(points), func(i int) bool { return !points[i].At.Before(from) })
+	}
+	end := len(points)
+	if !to.IsZero() {
+		end = sort.Search(len(points), func(i int) bool { return !points[i].At.Before(to) })
+	}
+	if start >= end {
+		return []types.MetricPoint{}, nil
+	}
+	return append([]types.MetricPoint(nil), points[start:end]...), nil
+}
+
+// PruneMetricPoints drops points older than cutoff.
+func (m *MemoryStore) PruneMetricPoints(_ context.Context, cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := 0
+	for runID, points := range m.metrics {
+		keep := sort.Search(len(points), func(i int) bool { return !points[i].At.Before(cutoff) })
+		if keep == 0 {
+			continue
+		}
+		removed += keep
+		if keep == len(points) {
+			delete(m.metrics, runID)
+			continue
+		}
+		m.metrics[runID] = append([]types.MetricPoint(nil), points[keep:]...)
+	}
+	return removed, nil
+}
+
+// DeleteMetricPoints drops every point recorded for the run.
+func (m *MemoryStore) DeleteMetricPoints(_ context.Context, runID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[runID]; !exists {
+		return 0, ErrNotFound
+	}
+	removed := len(m.metrics[runID])
+	delete(m.metrics, runID)
+	return removed, nil
+}
@@ -5,11 +5,28 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 
-	_ "github.com/lib/pq"
 	"github.com/cartridge/orchestrator/internal/types"
 )
 
+// runColumns is the column list scanned by scanRun.
+const runColumns = `id, experiment_id, version_id, state, status_message, priority,
+			   launch_manifest, overrides, last_heartbeat_at, runtime_status,
+			   health_status, current_step, samples_per_sec, loss, checkpoint_version,
+			   started_at, ended_at, created_by, created_at, updated_at, labels, archived_at, replay, samples_processed,
+			   max_duration_seconds, max_duration_action, max_duration_exceeded_at, heartbeat_gaps,
+			   learner_build, depends_on, cloned_from, preempted_by, learner_id,
+			   schedule_id, template_id`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 // PostgresStore implements RunStore backed by PostgreSQL
 type PostgresStore struct {
 	db *sql.DB
@@ -25,14 +42,24 @@ func (p *PostgresStore) CreateRun(ctx context.Context, run types.Run) error {
 		INSERT INTO runs (id, experiment_id, version_id, state, status_message, priority,
 						 launch_manifest, overrides, runtime_status, health_status,
 						 current_step, samples_per_sec, loss, checkpoint_version,
-						 created_by, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`
+						 created_by, created_at, updated_at, labels,
+						 max_duration_seconds, max_duration_action, depends_on, cloned_from, preempted_by,
+						 learner_id, schedule_id, template_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21,
+				NULLIF($22, ''), NULLIF($23, ''), NULLIF($24, ''), NULLIF($25, ''),
+				NULLIF($26, ''))`
 
-	_, err := p.db.ExecContext(ctx, query,
+	labels, err := marshalLabels(run.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, query,
 		run.ID, run.ExperimentID, run.VersionID, run.State, run.StatusMessage,
 		run.Priority, run.LaunchManifest, run.Overrides, run.RuntimeStatus,
 		run.HealthStatus, run.CurrentStep, run.SamplesPerSecond, run.Loss,
-		run.CheckpointVersion, run.CreatedBy, run.CreatedAt, run.UpdatedAt)
+		run.CheckpointVersion, run.CreatedBy, run.CreatedAt, run.UpdatedAt, labels,
+		run.MaxDurationSeconds, run.MaxDurationAction, dependsOn(run.DependsOn), run.ClonedFrom, run.PreemptedBy,
+		run.LearnerID, run.ScheduleID, run.TemplateID)
 
 	if err != nil {
 		// Check for unique constraint violation
@@ -46,50 +73,223 @@ func (p *PostgresStore) CreateRun(ctx context.Context, run types.Run) error {
 }
 
 func (p *PostgresStore) GetRun(ctx context.Context, id string) (types.Run, error) {
-	query := `
-		SELECT id, experiment_id, version_id, state, status_message, priority,
-			   launch_manifest, overrides, last_heartbeat_at, runtime_status,
-			   health_status, current_step, samples_per_sec, loss, checkpoint_version,
-			   started_at, ended_at, created_by, created_at, updated_at
-		FROM runs WHERE id = $1`
+	query := `SELECT ` + runColumns + ` FROM runs WHERE id = $1`
+
+	run, err := scanRun(p.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return types.Run{}, ErrNotFound
+	}
+	if err != nil {
+		return types.Run{}, fmt.Errorf("failed to get run: %w", err)
+	}
+
+	return run, nil
+}
+
+// ListRuns returns a page of runs matching filter ordered by creation time and
+// ID. Pages are found by keyset rather than offset, so deep pages cost the same
+// as the first one; IDs compare bytewise to match the memory store's cursors.
+func (p *PostgresStore) ListRuns(ctx context.Context, filter RunFilter) ([]types.Run, Cursor, error) {
+	var where []string
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if len(filter.States) > 0 {
+		states := make([]string, len(filter.States))
+		for i, state := range filter.States {
+			states[i] = string(state)
+		}
+		where = append(where, "state = ANY("+arg(pq.Array(states))+")")
+	}
+	switch {
+	case !filter.ArchivedBefore.IsZero():
+		where = append(where, "archived_at < "+arg(filter.ArchivedBefore))
+	case !filter.IncludeArchived:
+		where = append(where, "archived_at IS NULL")
+	}
+	if len(filter.Labels) > 0 {
+		labels, err := marshalLabels(filter.Labels)
+		if err != nil {
+			return nil, Cursor{}, err
+		}
+		where = append(where, "labels @> "+arg(labels)+"::jsonb")
+	}
+	if filter.Text != "" {
+		where = append(where, "strpos(lower(status_message), lower("+arg(filter.Text)+")) > 0")
+	}
+	if !filter.After.IsZero() {
+		where = append(where, `(created_at, id COLLATE "C") > (`+arg(filter.After.At)+", "+arg(filter.After.ID)+")")
+	}
 
+	query := `SELECT ` + runColumns + ` FROM runs`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at, id COLLATE "C"`
+	if filter.Limit > 0 {
+		// One extra row tells us whether another page follows.
+		query += " LIMIT " + arg(filter.Limit+1)
+	}
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Cursor{}, fmt.Errorf("failed to list runs: %w", err)
+	}
+	defer rows.Close()
+	var runs []types.Run
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, Cursor{}, fmt.Errorf("failed to scan run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, Cursor{}, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	if filter.Limit <= 0 || len(runs) <= filter.Limit {
+		return runs, Cursor{}, nil
+	}
+	runs = runs[:filter.Limit]
+	last := runs[len(runs)-1]
+	return runs, Cursor{At: last.CreatedAt, ID: last.ID}, nil
+}
+
+func scanRun(row rowScanner) (types.Run, error) {
 	var run types.Run
-	var launchManifest, overrides []byte
+	var launchManifest, overrides, labels, replay, gaps, build []byte
+	var clonedFrom, preemptedBy, learnerID, scheduleID, templateID sql.NullString
 
-	err := p.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&run.ID, &run.ExperimentID, &run.VersionID, &run.State, &run.StatusMessage,
 		&run.Priority, &launchManifest, &overrides, &run.LastHeartbeatAt,
 		&run.RuntimeStatus, &run.HealthStatus, &run.CurrentStep,
 		&run.SamplesPerSecond, &run.Loss, &run.CheckpointVersion,
-		&run.StartedAt, &run.EndedAt, &run.CreatedBy, &run.CreatedAt, &run.UpdatedAt)
-
-	if err == sql.ErrNoRows {
-		return types.Run{}, ErrNotFound
-	}
+		&run.StartedAt, &run.EndedAt, &run.CreatedBy, &run.CreatedAt, &run.UpdatedAt,
+		&labels, &run.ArchivedAt, &replay, &run.SamplesProcessed,
+		&run.MaxDurationSeconds, &run.MaxDurationAction, &run.MaxDurationExceededAt, &gaps,
+		&build, pq.Array(&run.DependsOn), &clonedFrom, &preemptedBy, &learnerID,
+		&scheduleID, &templateID)
 	if err != nil {
-		return types.Run{}, fmt.Errorf("failed to get run: %w", err)
+		return types.Run{}, err
 	}
 
 	run.LaunchManifest = json.RawMessage(launchManifest)
 	run.Overrides = json.RawMessage(overrides)
+	if len(labels) > 0 {
+		if err := json.Unmarshal(labels, &run.Labels); err != nil {
+			return types.Run{}, fmt.Errorf("invalid labels for run %s: %w", run.ID, err)
+		}
+		if len(run.Labels) == 0 {
+			run.Labels = nil
+		}
+	}
+	if len(run.DependsOn) == 0 {
+		run.DependsOn = nil
+	}
+	run.ClonedFrom = clonedFrom.String
+	run.PreemptedBy = preemptedBy.String
+	run.LearnerID = learnerID.String
+	run.ScheduleID = scheduleID.String
+	run.TemplateID = templateID.String
+	if len(replay) > 0 {
+		run.Replay = &types.ReplayStats{}
+		if err := json.Unmarshal(replay, run.Replay); err != nil {
+			return types.Run{}, fmt.Errorf("invalid replay stats for run %s: %w", run.ID, err)
+		}
+	}
+	if len(gaps) > 0 {
+		run.HeartbeatGaps = &types.HeartbeatGapStats{}
+		if err := json.Unmarshal(gaps, run.HeartbeatGaps); err != nil {
+			return types.Run{}, fmt.Errorf("invalid heartbeat gaps for run %s: %w", run.ID, err)
+		}
+	}
+	if len(build) > 0 {
+		run.LearnerBuild = &types.BuildInfo{}
+		if err := json.Unmarshal(build, run.LearnerBuild); err != nil {
+			return types.Run{}, fmt.Errorf("invalid learner build for run %s: %w", run.ID, err)
+		}
+	}
 
 	return run, nil
 }
 
+// marshalLabels encodes labels for the jsonb labels column, which is never null.
+func marshalLabels(labels map[string]string) (string, error) {
+	if len(labels) == 0 {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(labels)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode labels: %w", err)
+	}
+	return string(raw), nil
+}
+
+// dependsOn encodes run dependencies for the text[] depends_on column, which
+// is never null.
+func dependsOn(ids []string) pq.StringArray {
+	if ids == nil {
+		return pq.StringArray{}
+	}
+	return ids
+}
+
 func (p *PostgresStore) UpdateRun(ctx context.Context, run types.Run) error {
+	return p.updateRun(ctx, run, nil)
+}
+
+// CompareAndSwapRun replaces the stored run only while its updated_at still
+// equals updatedAt.
+func (p *PostgresStore) CompareAndSwapRun(ctx context.Context, run types.Run, updatedAt time.Time) error {
+	return p.updateRun(ctx, run, &updatedAt)
+}
+
+func (p *PostgresStore) updateRun(ctx context.Context, run types.Run, updatedAt *time.Time) error {
 	query := `
 		UPDATE runs SET
 			state = $2, status_message = $3, last_heartbeat_at = $4,
 			runtime_status = $5, health_status = $6, current_step = $7,
 			samples_per_sec = $8, loss = $9, checkpoint_version = $10,
-			started_at = $11, ended_at = $12, updated_at = $13
-		WHERE id = $1`
+			started_at = $11, ended_at = $12, updated_at = $13,
+			labels = $14, archived_at = $15, replay = $16, samples_processed = $17,
+			max_duration_exceeded_at = $18, heartbeat_gaps = $19,
+			priority = $20, overrides = $21, learner_build = $22, depends_on = $23,
+			cloned_from = NULLIF($24, ''), preempted_by = NULLIF($25, ''), learner_id = NULLIF($26, ''),
+			schedule_id = NULLIF($27, ''), template_id = NULLIF($28, '')
+		WHERE id = $1 AND ($29::timestamptz IS NULL OR updated_at = $29)`
 
+	labels, err := marshalLabels(run.Labels)
+	if err != nil {
+		return err
+	}
+	var replay []byte
+	if run.Replay != nil {
+		if replay, err = json.Marshal(run.Replay); err != nil {
+			return fmt.Errorf("failed to encode replay stats: %w", err)
+		}
+	}
+	var gaps []byte
+	if run.HeartbeatGaps != nil {
+		if gaps, err = json.Marshal(run.HeartbeatGaps); err != nil {
+			return fmt.Errorf("failed to encode heartbeat gaps: %w", err)
+		}
+	}
+	var build []byte
+	if run.LearnerBuild != nil {
+		if build, err = json.Marshal(run.LearnerBuild); err != nil {
+			return fmt.Errorf("failed to encode learner build: %w", err)
+		}
+	}
 	result, err := p.db.ExecContext(ctx, query,
 		run.ID, run.State, run.StatusMessage, run.LastHeartbeatAt,
 		run.RuntimeStatus, run.HealthStatus, run.CurrentStep,
 		run.SamplesPerSecond, run.Loss, run.CheckpointVersion,
-		run.StartedAt, run.EndedAt, run.UpdatedAt)
+		run.StartedAt, run.EndedAt, run.UpdatedAt, labels, run.ArchivedAt, replay, run.SamplesProcessed,
+		run.MaxDurationExceededAt, gaps, run.Priority, run.Overrides, build, dependsOn(run.DependsOn), run.ClonedFrom, run.PreemptedBy, run.LearnerID, run.ScheduleID, run.TemplateID, updatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to update run: %w", err)
@@ -101,6 +301,12 @@ func (p *PostgresStore) UpdateRun(ctx context.Context, run types.Run) error {
 	}
 
 	if rowsAffected == 0 {
+		if updatedAt != nil {
+			if _, err := p.GetRun(ctx, run.ID); err != nil {
+				return err
+			}
+			return ErrConflict
+		}
 		return ErrNotFound
 	}
 
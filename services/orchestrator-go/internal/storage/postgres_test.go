@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+func TestPostgresStoreRunColumns(t *testing.T) {
+	db, conn := openRecordingDB(t)
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	run := types.Run{
+		ID:           "run-1",
+		ExperimentID: "exp-1",
+		VersionID:    "ver-1",
+		State:        types.RunStateRunning,
+		LearnerID:    "learner-1",
+		ScheduleID:   "schedule-1",
+		TemplateID:   "template-1",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	// Nullable text columns, stored as NULL when empty.
+	fields := map[string]func(*types.Run) *string{
+		"learner_id":  func(r *types.Run) *string { return &r.LearnerID },
+		"schedule_id": func(r *types.Run) *string { return &r.ScheduleID },
+		"template_id": func(r *types.Run) *string { return &r.TemplateID },
+	}
+
+	if err := store.CreateRun(ctx, run); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	inserted := insertedColumns(t, conn.query, conn.args)
+	for column, field := range fields {
+		if want := *field(&run); inserted[column] != want {
+			t.Fatalf("expected %s %q to be inserted, got %v", column, want, inserted[column])
+		}
+	}
+
+	for _, field := range fields {
+		*field(&run) = ""
+	}
+	if err := store.UpdateRun(ctx, run); err != nil {
+		t.Fatalf("update run: %v", err)
+	}
+	updated := updatedColumns(t, conn.query, conn.args)
+	row := columnRow{}
+	for column := range fields {
+		if got, ok := updated[column]; !ok || got != "" {
+			t.Fatalf("expected %s to be cleared, got %v", column, got)
+		}
+		if !strings.Contains(conn.query, column+" = NULLIF(") {
+			t.Fatalf("expected an empty %s to be stored as NULL: %s", column, conn.query)
+		}
+		row[column] = column + "-scanned"
+	}
+
+	scanned, err := scanRun(row)
+	if err != nil {
+		t.Fatalf("scan run: %v", err)
+	}
+	for column, field := range fields {
+		if got := *field(&scanned); got != column+"-scanned" {
+			t.Fatalf("expected %s to be scanned, got %q", column, got)
+		}
+	}
+	if scanned, _ = scanRun(columnRow{}); scanned.LearnerID != "" || scanned.ScheduleID != "" || scanned.TemplateID != "" {
+		t.Fatalf("expected NULL columns to scan empty, got %+v", scanned)
+	}
+}
+
+var (
+	insertPattern = regexp.MustCompile(`(?s)INSERT INTO \w+ \((.*?)\)\s*VALUES \((.*)\)`)
+	paramPattern  = regexp.MustCompile(`\$(\d+)`)
+	setPattern    = regexp.MustCompile(`(\w+) = (?:NULLIF\()?\$(\d+)`)
+)
+
+// insertedColumns maps the columns of an INSERT to the arguments bound to them.
+func insertedColumns(t *testing.T, query string, args []driver.NamedValue) map[string]any {
+	t.Helper()
+	match := insertPattern.FindStringSubmatch(query)
+	if match == nil {
+		t.Fatalf("not an INSERT: %s", query)
+	}
+	columns := strings.Split(match[1], ",")
+	params := paramPattern.FindAllStringSubmatch(match[2], -1)
+	if len(columns) != len(params) {
+		t.Fatalf("%d columns but %d values: %s", len(columns), len(params), query)
+	}
+	values := make(map[string]any, len(columns))
+	for i, column := range columns {
+		values[strings.TrimSpace(column)] = arg(t, args, params[i][1])
+	}
+	return values
+}
+
+// updatedColumns maps the columns an UPDATE sets to the arguments bound to them.
+func updatedColumns(t *testing.T, query string, args []driver.NamedValue) map[string]any {
+	t.Helper()
+	values := make(map[string]any)
+	for _, match := range setPattern.FindAllStringSubmatch(query, -1) {
+		values[match[1]] = arg(t, args, match[2])
+	}
+	return values
+}
+
+func arg(t *testing.T, args []driver.NamedValue, param string) any {
+	t.Helper()
+	n, _ := strconv.Atoi(param)
+	if n < 1 || n > len(args) {
+		t.Fatalf("parameter $%d out of range of %d arguments", n, len(args))
+	}
+	return args[n-1].Value
+}
+
+// columnRow is a rowScanner returning the named runColumns as text and
+// leaving every other destination at its zero value, as for NULL columns.
+type columnRow map[string]string
+
+func (r columnRow) Scan(dest ...any) error {
+	columns := strings.Split(runColumns, ",")
+	if len(columns) != len(dest) {
+		return errors.New("runColumns and scan destinations differ in length")
+	}
+	for i, column := range columns {
+		value, ok := r[strings.TrimSpace(column)]
+		if !ok {
+			continue
+		}
+		scanner, ok := dest[i].(sql.Scanner)
+		if !ok {
+			return errors.New("column " + column + " is not scanned as nullable text")
+		}
+		if err := scanner.Scan(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordingConn is a database/sql connection that records the last statement
+// executed and reports one row affected.
+type recordingConn struct {
+	query string
+	args  []driver.NamedValue
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.query, c.args = query, args
+	return driver.RowsAffected(1), nil
+}
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("recordingConn: prepared statements are not supported")
+}
+
+func (c *recordingConn) Close() error { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("recordingConn: transactions are not supported")
+}
+
+type recordingConnector struct{ conn *recordingConn }
+
+func (c recordingConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c recordingConnector) Driver() driver.Driver                        { return nil }
+
+func openRecordingDB(t *testing.T) (*sql.DB, *recordingConn) {
+	t.Helper()
+	conn := &recordingConn{}
+	db := sql.OpenDB(recordingConnector{conn: conn})
+	t.Cleanup(func() { db.Close() })
+	return db, conn
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// RunFilter narrows a ListRuns page.
+type RunFilter struct {
+	// States restricts results to runs in any of the given states; empty means all.
+	States []types.RunState
+	// Labels restricts results to runs carrying every given key=value pair.
+	Labels map[string]string
+	// Text restricts results to runs whose status message contains it, ignoring case.
+	Text string
+	// IncludeArchived also returns archived runs, which are hidden by default.
+	IncludeArchived bool
+	// ArchivedBefore, when set, restricts results to runs archived before it.
+	ArchivedBefore time.Time
+	// After resumes a listing from a previous page's cursor, keyed on the run's
+	// creation time and ID.
+	After Cursor
+	// Limit caps the page size; zero returns every matching run.
+	Limit int
+}
+
+func (f RunFilter) matches(run types.Run) bool {
+	if len(f.States) > 0 && !slices.Contains(f.States, run.State) {
+		return false
+	}
+	if !f.ArchivedBefore.IsZero() {
+		if !run.Archived() || !run.ArchivedAt.Before(f.ArchivedBefore) {
+			return false
+		}
+	} else if run.Archived() && !f.IncludeArchived {
+		return false
+	}
+	for key, value := range f.Labels {
+		if got, ok := run.Labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return f.Text == "" || strings.Contains(strings.ToLower(run.StatusMessage), strings.ToLower(f.Text))
+}
+
+// ListRuns returns a page of runs matching filter ordered by creation time, plus
+// the cursor for the next page (zero when there are no more). Label filters are
+// answered from the label index rather than a scan of every run.
+func (m *MemoryStore) ListRuns(_ context.Context, filter RunFilter) ([]types.Run, Cursor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var matched []types.Run
+	consider := func(run types.Run) {
+		if filter.matches(run) && filter.After.after(run.CreatedAt, run.ID) {
+			matched = append(matched, run)
+		}
+	}
+	if candidates, indexed := m.labelCandidates(filter.Labels); indexed {
+		for id := range candidates {
+			consider(m.runs[id])
+		}
+	} else {
+		for _, run := range m.runs {
+			consider(run)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	if filter.Limit <= 0 || len(matched) <= filter.Limit {
+		return matched, Cursor{}, nil
+	}
+	page := matched[:filter.Limit]
+	last := page[len(page)-1]
+	return page, Cursor{At: last.CreatedAt, ID: last.ID}, nil
+}
+
+// labelCandidates returns the smallest indexed run set among the requested
+// labels; indexed is false when no labels were requested.
+func (m *MemoryStore) labelCandidates(labels map[string]string) (candidates map[string]bool, indexed bool) {
+	for key, value := range labels {
+		ids := m.labels[key][value]
+		if !indexed || len(ids) < len(candidates) {
+			candidates, indexed = ids, true
+		}
+	}
+	return candidates, indexed
+}
+
+func (m *MemoryStore) indexLabels(runID string, labels map[string]string) {
+	for key, value := range labels {
+		values, ok := m.labels[key]
+		if !ok {
+			values = make(map[string]map[string]bool)
+			m.labels[key] = values
+		}
+		if values[value] == nil {
+			values[value] = make(map[string]bool)
+		}
+		values[value][runID] = true
+	}
+}
+
+func (m *MemoryStore) unindexLabels(runID string, labels map[string]string) {
+	for key, value := range labels {
+		delete(m.labels[key][value], runID)
+		if len(m.labels[key][value]) == 0 {
+			delete(m.labels[key], value)
+		}
+		if len(m.labels[key]) == 0 {
+			delete(m.labels, key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// ScheduleStore persists cron schedules that launch runs.
+type ScheduleStore interface {
+	CreateSchedule(ctx context.Context, schedule types.Schedule) error
+	GetSchedule(ctx context.Context, id string) (types.Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule types.Schedule) error
+	ListSchedules(ctx context.Context) ([]types.Schedule, error)
+	// ListDueSchedules returns enabled schedules whose next activation is at or before now.
+	ListDueSchedules(ctx context.Context, now time.Time) ([]types.Schedule, error)
+}
+
+// CreateSchedule inserts a new schedule, enforcing uniqueness.
+func (m *MemoryStore) CreateSchedule(_ context.Context, schedule types.Schedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.schedules[schedule.ID]; exists {
+		return ErrConflict
+	}
+	m.schedules[schedule.ID] = schedule
+	return nil
+}
+
+// GetSchedule fetches a schedule by ID.
+func (m *MemoryStore) GetSchedule(_ context.Context, id string) (types.Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return types.Schedule{}, ErrNotFound
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule replaces the stored schedule.
+func (m *MemoryStore) UpdateSchedule(_ context.Context, schedule types.Schedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.schedules[schedule.ID]; !ok {
+		return ErrNotFound
+	}
+	m.schedules[schedule.ID] = schedule
+	return nil
+}
+
+// ListSchedules returns all schedules ordered by ID.
+func (m *MemoryStore) ListSchedules(_ context.Context) ([]types.Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	schedules := make([]types.Schedule, 0, len(m.schedules))
+	for _, schedule := range m.schedules {
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+	return schedules, nil
+}
+
+// ListDueSchedules returns enabled schedules due at now, earliest first.
+func (m *MemoryStore) ListDueSchedules(_ context.Context, now time.Time) ([]types.Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var due []types.Schedule
+	for _, schedule := range m.schedules {
+		if schedule.Enabled && schedule.NextRunAt != nil && !schedule.NextRunAt.After(now) {
+			due = append(due, schedule)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextRunAt.Before(*due[j].NextRunAt) })
+	return due, nil
+}
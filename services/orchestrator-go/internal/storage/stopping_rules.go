@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// StoppingRuleStore persists early-stopping rules and their evaluation state.
+type StoppingRuleStore interface {
+	// CreateStoppingRule inserts a rule; a duplicate ID within the run returns ErrConflict.
+	CreateStoppingRule(ctx context.Context, rule types.StoppingRule) error
+	GetStoppingRule(ctx context.Context, runID, ruleID string) (types.StoppingRule, error)
+	UpdateStoppingRule(ctx context.Context, rule types.StoppingRule) error
+	// ListStoppingRules returns a run's rules in creation order.
+	ListStoppingRules(ctx context.Context, runID string) ([]types.StoppingRule, error)
+}
+
+// CreateStoppingRule attaches a rule to its run.
+func (m *MemoryStore) CreateStoppingRule(_ context.Context, rule types.StoppingRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[rule.RunID]; !exists {
+		return ErrNotFound
+	}
+	for _, existing := range m.stoppingRules[rule.RunID] {
+		if existing.ID == rule.ID {
+			return ErrConflict
+		}
+	}
+	m.stoppingRules[rule.RunID] = append(m.stoppingRules[rule.RunID], rule)
+	return nil
+}
+
+// GetStoppingRule fetches a rule by run and ID.
+func (m *MemoryStore) GetStoppingRule(_ context.Context, runID, ruleID string) (types.StoppingRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, rule := range m.stoppingRules[runID] {
+		if rule.ID == ruleID {
+			return rule, nil
+		}
+	}
+	return types.StoppingRule{}, ErrNotFound
+}
+
+// UpdateStoppingRule replaces the stored rule.
+func (m *MemoryStore) UpdateStoppingRule(_ context.Context, rule types.StoppingRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rules := m.stoppingRules[rule.RunID]
+	for i := range rules {
+		if rules[i].ID == rule.ID {
+			rules[i] = rule
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// ListStoppingRules returns a copy of a run's rules.
+func (m *MemoryStore) ListStoppingRules(_ context.Context, runID string) ([]types.StoppingRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, exists := m.runs[runID]; !exists {
+		return nil, ErrNotFound
+	}
+	return append([]types.StoppingRule{}, m.stoppingRules[runID]...), nil
+}
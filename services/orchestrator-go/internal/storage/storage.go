@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"maps"
 	"sort"
 	"sync"
 	"time"
@@ -21,14 +22,55 @@ var (
 
 // RunStore captures the persistence operations the orchestrator relies on.
 type RunStore interface {
+	ExperimentStore
+	VersionStore
+	ScheduleStore
+	TemplateStore
+	ManifestSchemaStore
+	FeatureFlagStore
+	LearnerStore
+	CheckpointStore
+	ArtifactStore
+	MetricsStore
+	EvaluationStore
+	AnnotationStore
+	StoppingRuleStore
+	AlertRuleStore
+	LogStore
+	EventStore
+	AuditStore
 	CreateRun(ctx context.Context, run types.Run) error
 	GetRun(ctx context.Context, id string) (types.Run, error)
 	UpdateRun(ctx context.Context, run types.Run) error
+	// CompareAndSwapRun replaces the stored run only while its UpdatedAt still
+	// equals updatedAt, returning ErrConflict when another write got there first.
+	CompareAndSwapRun(ctx context.Context, run types.Run, updatedAt time.Time) error
+	ListRunsByState(ctx context.Context, states ...types.RunState) ([]types.Run, error)
+	// ListRuns returns a page of runs matching filter, oldest first, plus the
+	// cursor for the next page (zero when there are no more).
+	ListRuns(ctx context.Context, filter RunFilter) ([]types.Run, Cursor, error)
+	// ListDependentRuns returns the runs that list runID in DependsOn, oldest first.
+	ListDependentRuns(ctx context.Context, runID string) ([]types.Run, error)
 	AppendTransition(ctx context.Context, transition RunTransition) error
+	ListTransitions(ctx context.Context, runID string) ([]RunTransition, error)
 	AppendCommand(ctx context.Context, command types.RunCommand) error
 	GetCommand(ctx context.Context, runID, commandID string) (types.RunCommand, error)
-	NextPendingCommand(ctx context.Context, runID string) (types.RunCommand, error)
+	// DeliverNextCommand atomically marks the run's next pending command
+	// delivered at now, counting the attempt, and returns it. When serial is set,
+	// nothing is delivered while an earlier delivery awaits acknowledgement.
+	// ErrNoCommands reports that nothing can be delivered.
+	DeliverNextCommand(ctx context.Context, runID string, now time.Time, serial bool) (types.RunCommand, error)
 	SaveCommand(ctx context.Context, command types.RunCommand) error
+	// ExpireCommands stamps ExpiredAt on undelivered commands whose ExpiresAt has
+	// passed and returns them. An empty runID sweeps every run.
+	ExpireCommands(ctx context.Context, runID string, now time.Time) ([]types.RunCommand, error)
+	// ListCommands returns a page of commands matching filter, oldest first. An
+	// empty runID lists commands across every run.
+	ListCommands(ctx context.Context, runID string, filter CommandFilter) ([]types.RunCommand, Cursor, error)
+	// ReclaimCommands returns commands delivered before deliveredBefore but never
+	// acknowledged to the pending queue, or dead-letters them once they have been
+	// delivered maxAttempts times. It returns the affected commands.
+	ReclaimCommands(ctx context.Context, runID string, deliveredBefore time.Time, maxAttempts int, now time.Time) ([]types.RunCommand, error)
 }
 
 // RunTransition records a state change for auditing.
@@ -43,18 +85,54 @@ type RunTransition struct {
 
 // MemoryStore is an in-memory RunStore for development/testing.
 type MemoryStore struct {
-	mu          sync.RWMutex
-	runs        map[string]types.Run
-	commands    map[string]map[string]types.RunCommand // runID -> commandID -> command
-	transitions map[string][]RunTransition
+	mu              sync.RWMutex
+	runs            map[string]types.Run
+	commands        map[string]map[string]types.RunCommand // runID -> commandID -> command
+	transitions     map[string][]RunTransition
+	experiments     map[string]types.Experiment
+	versions        map[string]types.ConfigVersion
+	schedules       map[string]types.Schedule
+	templates       map[string]types.RunTemplate
+	manifestSchemas map[string]types.ManifestSchema // env -> schema
+	featureFlags    map[string]types.FeatureFlag    // name -> flag
+	learners        map[string]types.Learner
+	checkpoints     map[string]map[int64]types.Checkpoint // runID -> version -> checkpoint
+	artifacts       map[string]map[string]types.Artifact  // runID -> name -> artifact
+	metrics         map[string][]types.MetricPoint        // runID -> points, oldest first
+	evaluations     map[string][]types.Evaluation         // runID -> evaluations, oldest first
+	annotations     map[string][]types.Annotation         // runID -> notes ordered by At
+	stoppingRules   map[string][]types.StoppingRule       // runID -> rules in creation order
+	alertRules      map[string][]types.AlertRule          // runID -> rules in creation order
+	logs            map[string][]types.LogLine            // runID -> lines, oldest first
+	events          map[string][]types.RunEvent           // runID -> published events, oldest first
+	eventSeq        int64                                 // last assigned event ID
+	labels          map[string]map[string]map[string]bool // label key -> value -> run IDs
+	audit           []types.AuditEntry                    // oldest first
 }
 
 // NewMemoryStore constructs a MemoryStore.
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		runs:        make(map[string]types.Run),
-		commands:    make(map[string]map[string]types.RunCommand),
-		transitions: make(map[string][]RunTransition),
+		runs:            make(map[string]types.Run),
+		commands:        make(map[string]map[string]types.RunCommand),
+		transitions:     make(map[string][]RunTransition),
+		experiments:     make(map[string]types.Experiment),
+		versions:        make(map[string]types.ConfigVersion),
+		schedules:       make(map[string]types.Schedule),
+		templates:       make(map[string]types.RunTemplate),
+		manifestSchemas: make(map[string]types.ManifestSchema),
+		featureFlags:    make(map[string]types.FeatureFlag),
+		learners:        make(map[string]types.Learner),
+		checkpoints:     make(map[string]map[int64]types.Checkpoint),
+		artifacts:       make(map[string]map[string]types.Artifact),
+		metrics:         make(map[string][]types.MetricPoint),
+		evaluations:     make(map[string][]types.Evaluation),
+		annotations:     make(map[string][]types.Annotation),
+		stoppingRules:   make(map[string][]types.StoppingRule),
+		alertRules:      make(map[string][]types.AlertRule),
+		logs:            make(map[string][]types.LogLine),
+		events:          make(map[string][]types.RunEvent),
+		labels:          make(map[string]map[string]map[string]bool),
 	}
 }
 
@@ -65,7 +143,9 @@ func (m *MemoryStore) CreateRun(_ context.Context, run types.Run) error {
 	if _, exists := m.runs[run.ID]; exists {
 		return ErrConflict
 	}
+	run.Labels = maps.Clone(run.Labels)
 	m.runs[run.ID] = run
+	m.indexLabels(run.ID, run.Labels)
 	return nil
 }
 
@@ -84,13 +164,74 @@ func (m *MemoryStore) GetRun(_ context.Context, id string) (types.Run, error) {
 func (m *MemoryStore) UpdateRun(_ context.Context, run types.Run) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if _, ok := m.runs[run.ID]; !ok {
+	previous, ok := m.runs[run.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	m.unindexLabels(run.ID, previous.Labels)
+	run.Labels = maps.Clone(run.Labels)
+	m.runs[run.ID] = run
+	m.indexLabels(run.ID, run.Labels)
+	return nil
+}
+
+// CompareAndSwapRun replaces the stored run if it has not been updated since
+// updatedAt.
+func (m *MemoryStore) CompareAndSwapRun(_ context.Context, run types.Run, updatedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous, ok := m.runs[run.ID]
+	if !ok {
 		return ErrNotFound
 	}
+	if !previous.UpdatedAt.Equal(updatedAt) {
+		return ErrConflict
+	}
+	m.unindexLabels(run.ID, previous.Labels)
+	run.Labels = maps.Clone(run.Labels)
 	m.runs[run.ID] = run
+	m.indexLabels(run.ID, run.Labels)
 	return nil
 }
 
+// ListRunsByState returns all runs currently in one of the given states.
+func (m *MemoryStore) ListRunsByState(_ context.Context, states ...types.RunState) ([]types.Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var runs []types.Run
+	for _, run := range m.runs {
+		for _, state := range states {
+			if run.State == state {
+				runs = append(runs, run)
+				break
+			}
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
+// ListDependentRuns returns the runs that declare runID as a prerequisite.
+func (m *MemoryStore) ListDependentRuns(_ context.Context, runID string) ([]types.Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var runs []types.Run
+	for _, run := range m.runs {
+		for _, dependency := range run.DependsOn {
+			if dependency == runID {
+				runs = append(runs, run)
+				break
+			}
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
 // AppendTransition adds a state transition entry.
 func (m *MemoryStore) AppendTransition(_ context.Context, transition RunTransition) error {
 	m.mu.Lock()
@@ -99,6 +240,16 @@ func (m *MemoryStore) AppendTransition(_ context.Context, transition RunTransiti
 	return nil
 }
 
+// ListTransitions returns a run's transitions in the order they were recorded.
+func (m *MemoryStore) ListTransitions(_ context.Context, runID string) ([]RunTransition, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.runs[runID]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]RunTransition(nil), m.transitions[runID]...), nil
+}
+
 // AppendCommand inserts a command if not already present.
 func (m *MemoryStore) AppendCommand(_ context.Context, command types.RunCommand) error {
 	m.mu.Lock()
@@ -143,28 +294,53 @@ func (m *MemoryStore) GetCommand(_ context.Context, runID, commandID string) (ty
 	return cmd, nil
 }
 
-// NextPendingCommand returns the oldest undelivered command for a run.
-func (m *MemoryStore) NextPendingCommand(_ context.Context, runID string) (types.RunCommand, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// DeliverNextCommand delivers the run's oldest pending command.
+func (m *MemoryStore) DeliverNextCommand(_ context.Context, runID string, now time.Time, serial bool) (types.RunCommand, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if _, exists := m.runs[runID]; !exists {
 		return types.RunCommand{}, ErrNotFound
 	}
-	runCommands, ok := m.commands[runID]
-	if !ok {
-		return types.RunCommand{}, ErrNoCommands
-	}
-	var pending []types.RunCommand
-	for _, cmd := range runCommands {
-		if cmd.DeliveredAt == nil {
-			pending = append(pending, cmd)
+	var next types.RunCommand
+	found := false
+	for _, cmd := range m.commands[runID] {
+		if serial && cmd.Status() == types.CommandStatusDelivered {
+			return types.RunCommand{}, ErrNoCommands
+		}
+		if cmd.Pending() && (!found || cmd.DeliveredBefore(next)) {
+			next, found = cmd, true
 		}
 	}
-	if len(pending) == 0 {
+	if !found {
 		return types.RunCommand{}, ErrNoCommands
 	}
-	sort.Slice(pending, func(i, j int) bool {
-		return pending[i].IssuedAt.Before(pending[j].IssuedAt)
+	next.DeliveredAt = &now
+	next.DeliveryAttempts++
+	m.commands[runID][next.ID] = next
+	return next, nil
+}
+
+// ExpireCommands marks undelivered commands past their expiry as expired.
+func (m *MemoryStore) ExpireCommands(_ context.Context, runID string, now time.Time) ([]types.RunCommand, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var expired []types.RunCommand
+	for id, runCommands := range m.commands {
+		if runID != "" && id != runID {
+			continue
+		}
+		for cmdID, cmd := range runCommands {
+			if cmd.ExpiredAt != nil || !cmd.ExpiredBy(now) {
+				continue
+			}
+			at := now
+			cmd.ExpiredAt = &at
+			runCommands[cmdID] = cmd
+			expired = append(expired, cmd)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].IssuedAt.Before(expired[j].IssuedAt)
 	})
-	return pending[0], nil
+	return expired, nil
 }
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// TemplateStore persists reusable run templates.
+type TemplateStore interface {
+	CreateTemplate(ctx context.Context, template types.RunTemplate) error
+	GetTemplate(ctx context.Context, id string) (types.RunTemplate, error)
+	ListTemplates(ctx context.Context) ([]types.RunTemplate, error)
+}
+
+// CreateTemplate inserts a new template, enforcing uniqueness.
+func (m *MemoryStore) CreateTemplate(_ context.Context, template types.RunTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.templates[template.ID]; exists {
+		return ErrConflict
+	}
+	m.templates[template.ID] = template
+	return nil
+}
+
+// GetTemplate fetches a template by ID.
+func (m *MemoryStore) GetTemplate(_ context.Context, id string) (types.RunTemplate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	template, ok := m.templates[id]
+	if !ok {
+		return types.RunTemplate{}, ErrNotFound
+	}
+	return template, nil
+}
+
+// ListTemplates returns all templates ordered by ID.
+func (m *MemoryStore) ListTemplates(_ context.Context) ([]types.RunTemplate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	templates := make([]types.RunTemplate, 0, len(m.templates))
+	for _, template := range m.templates {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+// VersionStore persists immutable config versions registered against experiments.
+type VersionStore interface {
+	// CreateVersion stores a new version, assigning the next version number for its experiment.
+	CreateVersion(ctx context.Context, version types.ConfigVersion) (types.ConfigVersion, error)
+	GetVersion(ctx context.Context, id string) (types.ConfigVersion, error)
+	ListVersions(ctx context.Context, experimentID string) ([]types.ConfigVersion, error)
+}
+
+// CreateVersion inserts a version, numbering it after the experiment's latest version.
+func (m *MemoryStore) CreateVersion(_ context.Context, version types.ConfigVersion) (types.ConfigVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.versions[version.ID]; exists {
+		return types.ConfigVersion{}, ErrConflict
+	}
+	next := 1
+	for _, existing := range m.versions {
+		if existing.ExperimentID == version.ExperimentID && existing.Version >= next {
+			next = existing.Version + 1
+		}
+	}
+	version.Version = next
+	m.versions[version.ID] = version
+	return version, nil
+}
+
+// GetVersion fetches a version by ID.
+func (m *MemoryStore) GetVersion(_ context.Context, id string) (types.ConfigVersion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	version, ok := m.versions[id]
+	if !ok {
+		return types.ConfigVersion{}, ErrNotFound
+	}
+	return version, nil
+}
+
+// ListVersions returns an experiment's versions, newest first.
+func (m *MemoryStore) ListVersions(_ context.Context, experimentID string) ([]types.ConfigVersion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var versions []types.ConfigVersion
+	for _, version := range m.versions {
+		if version.ExperimentID == experimentID {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+	return versions, nil
+}
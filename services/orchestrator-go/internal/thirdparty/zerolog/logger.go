@@ -57,6 +57,7 @@ const (
 	FatalLevel
 )
 
+func (l *Logger) Debug() *Event { return l.log("debug") }
 func (l *Logger) Info() *Event  { return l.log("info") }
 func (l *Logger) Warn() *Event  { return l.log("warn") }
 func (l *Logger) Error() *Event { return l.log("error") }
@@ -64,6 +65,8 @@ func (l *Logger) Fatal() *Event { return l.log("fatal") }
 
 func (l *Logger) WithLevel(level Level) *Event {
 	switch level {
+	case DebugLevel:
+		return l.Debug()
 	case InfoLevel:
 		return l.Info()
 	case WarnLevel:
@@ -92,11 +95,21 @@ func (e *Event) Int64(key string, value int64) *Event {
 	return e
 }
 
+func (e *Event) Float64(key string, value float64) *Event {
+	e.fields[key] = value
+	return e
+}
+
 func (e *Event) Dur(key string, value time.Duration) *Event {
 	e.fields[key] = value.String()
 	return e
 }
 
+func (e *Event) Time(key string, value time.Time) *Event {
+	e.fields[key] = value.UTC().Format(time.RFC3339)
+	return e
+}
+
 func (e *Event) Interface(key string, value interface{}) *Event {
 	e.fields[key] = value
 	return e
@@ -0,0 +1,195 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// CommandPayloadValidator checks the payload of one command type. It receives
+// the raw payload, which is empty when the client sent none.
+type CommandPayloadValidator func(payload json.RawMessage) error
+
+var (
+	commandTypesMu sync.RWMutex
+	commandTypes   = map[CommandType]CommandPayloadValidator{}
+)
+
+// RegisterCommandType makes t a deliverable command type whose payloads are
+// checked by validate. Registering a type twice is an error so that two
+// packages cannot silently disagree about a payload's shape.
+func RegisterCommandType(t CommandType, validate CommandPayloadValidator) error {
+	if t == "" {
+		return errors.New("command type must not be empty")
+	}
+	if validate == nil {
+		return fmt.Errorf("command type %q needs a payload validator", t)
+	}
+	commandTypesMu.Lock()
+	defer commandTypesMu.Unlock()
+	if _, exists := commandTypes[t]; exists {
+		return fmt.Errorf("command type %q is already registered", t)
+	}
+	commandTypes[t] = validate
+	return nil
+}
+
+// MustRegisterCommandType is RegisterCommandType for use in init functions.
+func MustRegisterCommandType(t CommandType, validate CommandPayloadValidator) {
+	if err := RegisterCommandType(t, validate); err != nil {
+		panic(err)
+	}
+}
+
+// CommandTypes returns the registered command types in name order.
+func CommandTypes() []CommandType {
+	commandTypesMu.RLock()
+	defer commandTypesMu.RUnlock()
+	registered := make([]CommandType, 0, len(commandTypes))
+	for t := range commandTypes {
+		registered = append(registered, t)
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i] < registered[j] })
+	return registered
+}
+
+// ValidatePayload checks payload against the validator registered for t.
+func (t CommandType) ValidatePayload(payload json.RawMessage) error {
+	commandTypesMu.RLock()
+	validate, ok := commandTypes[t]
+	commandTypesMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unsupported command type %q", t)
+	}
+	return validate(payload)
+}
+
+const (
+	// CommandTypeCheckpointNow asks the learner to write a checkpoint immediately.
+	CommandTypeCheckpointNow CommandType = "checkpoint_now"
+	// CommandTypeEvalNow asks the learner to evaluate a checkpoint immediately.
+	CommandTypeEvalNow CommandType = "eval_now"
+	// CommandTypeResizeActors changes how many actors feed the learner.
+	CommandTypeResizeActors CommandType = "resize_actors"
+)
+
+// MaxActorReplicas bounds resize_actors requests.
+const MaxActorReplicas = 1024
+
+// CheckpointNowPayload is the optional payload of checkpoint_now commands.
+type CheckpointNowPayload struct {
+	// Tag is recorded with the resulting checkpoint, e.g. "pre-tune".
+	Tag string `json:"tag,omitempty"`
+}
+
+// EvalNowPayload is the optional payload of eval_now commands.
+type EvalNowPayload struct {
+	// CheckpointVersion selects the checkpoint to evaluate; zero means the latest.
+	CheckpointVersion int64  `json:"checkpoint_version,omitempty"`
+	Suite             string `json:"suite,omitempty"`
+	Episodes          int    `json:"episodes,omitempty"`
+}
+
+// ResizeActorsPayload is the payload of resize_actors commands.
+type ResizeActorsPayload struct {
+	Replicas int `json:"replicas"`
+}
+
+func init() {
+	MustRegisterCommandType(CommandTypeTune, validateTunePayload)
+	MustRegisterCommandType(CommandTypePause, emptyPayload(CommandTypePause))
+	MustRegisterCommandType(CommandTypeResume, emptyPayload(CommandTypeResume))
+	MustRegisterCommandType(CommandTypeTerminate, validateTerminatePayload)
+	MustRegisterCommandType(CommandTypeCheckpointNow, validateCheckpointNowPayload)
+	MustRegisterCommandType(CommandTypeEvalNow, validateEvalNowPayload)
+	MustRegisterCommandType(CommandTypeResizeActors, validateResizeActorsPayload)
+}
+
+// decodePayload unmarshals an optional payload strictly, rejecting unknown fields.
+func decodePayload(t CommandType, payload json.RawMessage, into any) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(into); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", t, err)
+	}
+	return nil
+}
+
+func emptyPayload(t CommandType) CommandPayloadValidator {
+	return func(payload json.RawMessage) error {
+		if len(payload) > 0 && string(payload) != "{}" {
+			return fmt.Errorf("%s payload must be empty", t)
+		}
+		return nil
+	}
+}
+
+func validateTunePayload(raw json.RawMessage) error {
+	var payload TunePayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return fmt.Errorf("invalid tune payload: %w", err)
+	}
+	if payload.LearningRate == nil && payload.EntropyCoef == nil && payload.ClipEpsilon == nil {
+		return errors.New("tune payload requires at least one tunable field")
+	}
+	if payload.LearningRate != nil {
+		if *payload.LearningRate <= 0 || *payload.LearningRate > 1 {
+			return errors.New("learning_rate must be in (0,1]")
+		}
+	}
+	if payload.EntropyCoef != nil {
+		if *payload.EntropyCoef < 0 || *payload.EntropyCoef > 0.1 {
+			return errors.New("entropy_coef must be within [0,0.1]")
+		}
+	}
+	if payload.ClipEpsilon != nil {
+		if *payload.ClipEpsilon < 0.05 || *payload.ClipEpsilon > 0.3 {
+			return errors.New("clip_epsilon must be within [0.05,0.3]")
+		}
+	}
+	return nil
+}
+
+func validateTerminatePayload(raw json.RawMessage) error {
+	var payload TerminatePayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return fmt.Errorf("invalid terminate payload: %w", err)
+	}
+	if payload.Reason == "" {
+		return errors.New("terminate payload requires reason")
+	}
+	return nil
+}
+
+func validateCheckpointNowPayload(raw json.RawMessage) error {
+	var payload CheckpointNowPayload
+	return decodePayload(CommandTypeCheckpointNow, raw, &payload)
+}
+
+func validateEvalNowPayload(raw json.RawMessage) error {
+	var payload EvalNowPayload
+	if err := decodePayload(CommandTypeEvalNow, raw, &payload); err != nil {
+		return err
+	}
+	if payload.CheckpointVersion < 0 || payload.Episodes < 0 {
+		return errors.New("checkpoint_version and episodes must not be negative")
+	}
+	return nil
+}
+
+func validateResizeActorsPayload(raw json.RawMessage) error {
+	var payload ResizeActorsPayload
+	if err := decodePayload(CommandTypeResizeActors, raw, &payload); err != nil {
+		return err
+	}
+	if payload.Replicas < 1 || payload.Replicas > MaxActorReplicas {
+		return fmt.Errorf("replicas must be within [1,%d]", MaxActorReplicas)
+	}
+	return nil
+}
@@ -4,6 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -22,6 +27,95 @@ const (
 	RunStateTerminated   RunState = "terminated"
 )
 
+// ErrInvalidTransition indicates a lifecycle change the state machine does not permit.
+var ErrInvalidTransition = errors.New("invalid state transition")
+
+// ErrRegression indicates a heartbeat whose step or checkpoint moved backwards.
+var ErrRegression = errors.New("regression")
+
+// runStateTransitions is the central table of legal lifecycle moves.
+var runStateTransitions = map[RunState][]RunState{
+	RunStateQueued:       {RunStateProvisioning, RunStateTerminated, RunStateFailed},
+	RunStateProvisioning: {RunStateRunning, RunStateTerminating, RunStateTerminated, RunStateFailed, RunStateErrored},
+	RunStateRunning:      {RunStatePaused, RunStateTerminating, RunStateCompleted, RunStateFailed, RunStateErrored},
+	RunStatePaused:       {RunStateRunning, RunStateTerminating, RunStateFailed, RunStateErrored},
+	RunStateTerminating:  {RunStateTerminated, RunStateFailed, RunStateErrored},
+	RunStateErrored:      {RunStateRunning, RunStateTerminating, RunStateTerminated, RunStateFailed},
+}
+
+// IsTerminal reports whether no further transitions are possible.
+func (s RunState) IsTerminal() bool {
+	switch s {
+	case RunStateCompleted, RunStateFailed, RunStateTerminated:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether moving from s to next is a legal lifecycle change.
+func (s RunState) CanTransitionTo(next RunState) bool {
+	for _, allowed := range runStateTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns ErrInvalidTransition when s cannot move to next.
+func (s RunState) ValidateTransition(next RunState) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, s, next)
+	}
+	return nil
+}
+
+// RunAction names a lifecycle request exposed through the API.
+type RunAction string
+
+const (
+	RunActionProvision RunAction = "provision"
+	RunActionStart     RunAction = "start"
+	RunActionPause     RunAction = "pause"
+	RunActionResume    RunAction = "resume"
+	RunActionTerminate RunAction = "terminate"
+	RunActionComplete  RunAction = "complete"
+	RunActionFail      RunAction = "fail"
+)
+
+// ActionTarget resolves the state an action moves a run in state s into.
+func (s RunState) ActionTarget(action RunAction) (RunState, error) {
+	var next RunState
+	switch action {
+	case RunActionProvision:
+		next = RunStateProvisioning
+	case RunActionStart:
+		next = RunStateRunning
+	case RunActionPause:
+		next = RunStatePaused
+	case RunActionResume:
+		if s != RunStatePaused {
+			return "", fmt.Errorf("%w: resume requires a paused run, got %s", ErrInvalidTransition, s)
+		}
+		next = RunStateRunning
+	case RunActionTerminate:
+		// Queued or already winding-down runs stop immediately; active runs drain first.
+		switch s {
+		case RunStateQueued, RunStateTerminating, RunStateErrored:
+			next = RunStateTerminated
+		default:
+			next = RunStateTerminating
+		}
+	case RunActionComplete:
+		next = RunStateCompleted
+	case RunActionFail:
+		next = RunStateFailed
+	default:
+		return "", fmt.Errorf("unsupported run action %q", action)
+	}
+	return next, s.ValidateTransition(next)
+}
+
 // RuntimeStatus mirrors learner-reported state coming from heartbeats.
 type RuntimeStatus string
 
@@ -41,7 +135,8 @@ const (
 	RunHealthUnresponsive   RunHealth = "unresponsive"
 )
 
-// CommandType captures the control commands the orchestrator can deliver.
+// CommandType captures the control commands the orchestrator can deliver. The
+// built-in types are below; more can be added with RegisterCommandType.
 type CommandType string
 
 const (
@@ -81,39 +176,1334 @@ type TerminatePayload struct {
 
 // RunCommand is the canonical representation stored in the registry.
 type RunCommand struct {
-	ID             string          `json:"id"`
-	RunID          string          `json:"run_id"`
-	Type           CommandType     `json:"type"`
-	Payload        json.RawMessage `json:"payload"`
-	Actor          CommandActor    `json:"actor"`
-	IssuedAt       time.Time       `json:"issued_at"`
-	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
-	AcknowledgedAt *time.Time      `json:"acknowledged_at,omitempty"`
-	CreatedAt      time.Time       `json:"created_at"`
+	ID               string          `json:"id"`
+	RunID            string          `json:"run_id"`
+	Type             CommandType     `json:"type"`
+	Payload          json.RawMessage `json:"payload"`
+	Actor            CommandActor    `json:"actor"`
+	IssuedAt         time.Time       `json:"issued_at"`
+	DeliveredAt      *time.Time      `json:"delivered_at,omitempty"`
+	AcknowledgedAt   *time.Time      `json:"acknowledged_at,omitempty"`
+	ExpiresAt        *time.Time      `json:"expires_at,omitempty"`
+	ExpiredAt        *time.Time      `json:"expired_at,omitempty"`
+	DeliveryAttempts int             `json:"delivery_attempts"`
+	DeadLetteredAt   *time.Time      `json:"dead_lettered_at,omitempty"`
+	DiscardedAt      *time.Time      `json:"discarded_at,omitempty"`
+	Result           *CommandResult  `json:"result,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
+
+// CommandResultStatus is the learner-reported outcome of executing a command.
+type CommandResultStatus string
+
+const (
+	CommandResultSucceeded CommandResultStatus = "succeeded"
+	CommandResultFailed    CommandResultStatus = "failed"
+)
+
+// CommandResult captures how a learner applied a command, e.g. the learning rate
+// it actually switched to after clamping.
+type CommandResult struct {
+	Status  CommandResultStatus `json:"status"`
+	Message string              `json:"message,omitempty"`
+	Applied json.RawMessage     `json:"applied,omitempty"`
+	// Previous holds the values Applied replaced, e.g. the learning rate in
+	// effect before a tune.
+	Previous json.RawMessage `json:"previous,omitempty"`
+	// Step is the run's training step when the result was reported.
+	Step       int64     `json:"step,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+// Validate ensures the result carries a known status and an object of applied values.
+func (r CommandResult) Validate() error {
+	switch r.Status {
+	case CommandResultSucceeded, CommandResultFailed:
+	default:
+		return fmt.Errorf("invalid result status %q", r.Status)
+	}
+	if !isJSONObject(r.Applied) {
+		return errors.New("applied must be a JSON object")
+	}
+	if !isJSONObject(r.Previous) {
+		return errors.New("previous must be a JSON object")
+	}
+	return nil
+}
+
+// isJSONObject reports whether raw is empty or a JSON object.
+func isJSONObject(raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return true
+	}
+	var object map[string]any
+	return json.Unmarshal(raw, &object) == nil
+}
+
+// HyperparameterChange is one entry in a run's hyperparameter timeline: a tune
+// command with the values the learner reported before and after applying it.
+type HyperparameterChange struct {
+	CommandID string              `json:"command_id"`
+	Actor     CommandActor        `json:"actor"`
+	IssuedAt  time.Time           `json:"issued_at"`
+	AppliedAt time.Time           `json:"applied_at"`
+	Step      int64               `json:"step"`
+	Status    CommandResultStatus `json:"status"`
+	Requested json.RawMessage     `json:"requested"`
+	Before    json.RawMessage     `json:"before,omitempty"`
+	After     json.RawMessage     `json:"after,omitempty"`
+	Message   string              `json:"message,omitempty"`
+}
+
+// CommandStatus is the delivery state derived from a command's timestamps.
+type CommandStatus string
+
+const (
+	CommandStatusQueued    CommandStatus = "queued"
+	CommandStatusDelivered CommandStatus = "delivered"
+	CommandStatusAcked     CommandStatus = "acked"
+	CommandStatusExpired   CommandStatus = "expired"
+	// CommandStatusDeadLettered marks commands that exhausted their delivery attempts.
+	CommandStatusDeadLettered CommandStatus = "dead_lettered"
+	// CommandStatusDiscarded marks dead letters an operator chose not to retry.
+	CommandStatusDiscarded CommandStatus = "discarded"
+)
+
+// Valid reports whether s is a known command status.
+func (s CommandStatus) Valid() bool {
+	switch s {
+	case CommandStatusQueued, CommandStatusDelivered, CommandStatusAcked, CommandStatusExpired, CommandStatusDeadLettered, CommandStatusDiscarded:
+		return true
+	}
+	return false
+}
+
+// Status derives the command's position in the delivery lifecycle.
+func (c RunCommand) Status() CommandStatus {
+	switch {
+	case c.DiscardedAt != nil:
+		return CommandStatusDiscarded
+	case c.DeadLetteredAt != nil:
+		return CommandStatusDeadLettered
+	case c.ExpiredAt != nil:
+		return CommandStatusExpired
+	case c.AcknowledgedAt != nil:
+		return CommandStatusAcked
+	case c.DeliveredAt != nil:
+		return CommandStatusDelivered
+	default:
+		return CommandStatusQueued
+	}
+}
+
+// DeadLetterStatuses are the statuses of commands that will never reach a
+// learner without operator intervention.
+var DeadLetterStatuses = []CommandStatus{CommandStatusExpired, CommandStatusDeadLettered}
+
+// DeadLettered reports whether the command expired or exhausted its delivery
+// attempts and is waiting for an operator to requeue or discard it.
+func (c RunCommand) DeadLettered() bool {
+	status := c.Status()
+	return status == CommandStatusExpired || status == CommandStatusDeadLettered
+}
+
+// Pending reports whether the command is waiting to be delivered.
+func (c RunCommand) Pending() bool {
+	return c.Status() == CommandStatusQueued
+}
+
+// DeliveredBefore reports whether c is due for delivery ahead of other: commands
+// go out in IssuedAt order, with ties broken by CreatedAt and then ID.
+func (c RunCommand) DeliveredBefore(other RunCommand) bool {
+	if !c.IssuedAt.Equal(other.IssuedAt) {
+		return c.IssuedAt.Before(other.IssuedAt)
+	}
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.Before(other.CreatedAt)
+	}
+	return c.ID < other.ID
+}
+
+// ExpiredBy reports whether a pending command has outlived its expires_at at now.
+func (c RunCommand) ExpiredBy(now time.Time) bool {
+	return c.Pending() && c.ExpiresAt != nil && !now.Before(*c.ExpiresAt)
 }
 
 // Run captures canonical run metadata.
 type Run struct {
-	ID                string          `json:"id"`
-	ExperimentID      string          `json:"experiment_id"`
-	VersionID         string          `json:"version_id"`
-	State             RunState        `json:"state"`
-	StatusMessage     string          `json:"status_message,omitempty"`
-	Priority          int             `json:"priority"`
-	LaunchManifest    json.RawMessage `json:"launch_manifest"`
-	Overrides         json.RawMessage `json:"overrides,omitempty"`
-	LastHeartbeatAt   *time.Time      `json:"last_heartbeat_at,omitempty"`
-	RuntimeStatus     RuntimeStatus   `json:"runtime_status"`
-	HealthStatus      RunHealth       `json:"health_status"`
-	CurrentStep       int64           `json:"current_step"`
-	SamplesPerSecond  float64         `json:"samples_per_sec"`
-	Loss              float64         `json:"loss"`
-	CheckpointVersion int64           `json:"checkpoint_version"`
-	StartedAt         *time.Time      `json:"started_at,omitempty"`
-	EndedAt           *time.Time      `json:"ended_at,omitempty"`
-	CreatedBy         string          `json:"created_by"`
-	CreatedAt         time.Time       `json:"created_at"`
-	UpdatedAt         time.Time       `json:"updated_at"`
+	ID               string          `json:"id"`
+	ExperimentID     string          `json:"experiment_id"`
+	VersionID        string          `json:"version_id"`
+	State            RunState        `json:"state"`
+	StatusMessage    string          `json:"status_message,omitempty"`
+	Priority         int             `json:"priority"`
+	LaunchManifest   json.RawMessage `json:"launch_manifest"`
+	Overrides        json.RawMessage `json:"overrides,omitempty"`
+	LastHeartbeatAt  *time.Time      `json:"last_heartbeat_at,omitempty"`
+	RuntimeStatus    RuntimeStatus   `json:"runtime_status"`
+	HealthStatus     RunHealth       `json:"health_status"`
+	CurrentStep      int64           `json:"current_step"`
+	SamplesPerSecond float64         `json:"samples_per_sec"`
+	// SamplesProcessed estimates the samples trained so far by integrating
+	// samples_per_sec between heartbeats.
+	SamplesProcessed  int64             `json:"samples_processed"`
+	Loss              float64           `json:"loss"`
+	CheckpointVersion int64             `json:"checkpoint_version"`
+	ScheduleID        string            `json:"schedule_id,omitempty"`
+	TemplateID        string            `json:"template_id,omitempty"`
+	DependsOn         []string          `json:"depends_on,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	ClonedFrom        string            `json:"cloned_from,omitempty"`
+	LearnerID         string            `json:"learner_id,omitempty"`
+	PreemptedBy       string            `json:"preempted_by,omitempty"`
+	QueuePosition     int               `json:"queue_position,omitempty"`
+	// MaxDurationSeconds bounds how long the run may stay running before the
+	// watchdog applies MaxDurationAction (terminate or pause); zero means no
+	// limit. MaxDurationExceededAt records when it did, as it acts only once.
+	MaxDurationSeconds    int64        `json:"max_duration_seconds,omitempty"`
+	MaxDurationAction     CommandType  `json:"max_duration_action,omitempty"`
+	MaxDurationExceededAt *time.Time   `json:"max_duration_exceeded_at,omitempty"`
+	StartedAt             *time.Time   `json:"started_at,omitempty"`
+	EndedAt               *time.Time   `json:"ended_at,omitempty"`
+	ArchivedAt            *time.Time   `json:"archived_at,omitempty"`
+	Replay                *ReplayStats `json:"replay,omitempty"`
+	// HeartbeatGaps summarises the intervals between the run's heartbeats while
+	// it was running or paused.
+	HeartbeatGaps *HeartbeatGapStats `json:"heartbeat_gaps,omitempty"`
+	// LearnerBuild is the build the run's learner last reported on a heartbeat.
+	LearnerBuild *BuildInfo `json:"learner_build,omitempty"`
+	CreatedBy    string     `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ReplayStats is a run's replay buffer activity as last polled from the replay
+// service for the environment named by its launch manifest.
+type ReplayStats struct {
+	EnvID string `json:"env_id"`
+	// Transitions is the number of the environment's transitions in the buffer.
+	Transitions uint64 `json:"transitions"`
+	// RunTransitions is the number of the run's own transitions, counted by
+	// the run_id its actors stamp on them; zero when they stamp none.
+	RunTransitions uint64 `json:"run_transitions"`
+	// Episodes and StorageBytes describe the whole buffer, which may be shared
+	// by several environments.
+	Episodes     uint64 `json:"episodes"`
+	StorageBytes uint64 `json:"storage_bytes"`
+	// Fill is the share of the buffer's capacity in use, or zero when the
+	// capacity is not configured.
+	Fill float64 `json:"fill"`
+	// IngestRate is the run's new transitions per second since the previous
+	// poll, or the environment's when the run's are not counted. It reads zero once a full buffer is evicting as fast as it
+	// ingests.
+	IngestRate         float64    `json:"ingest_rate"`
+	NewestTransitionAt *time.Time `json:"newest_transition_at,omitempty"`
+	// LastIngestAt is when the poller last saw new transitions arrive.
+	LastIngestAt *time.Time `json:"last_ingest_at,omitempty"`
+	// Stalled is set once no transitions have arrived for the stall window.
+	Stalled  bool      `json:"stalled"`
+	PolledAt time.Time `json:"polled_at"`
+}
+
+// HeartbeatGapWindow is how many of a run's latest heartbeat gaps the gap
+// percentiles are taken over.
+const HeartbeatGapWindow = 32
+
+// HeartbeatGapStats summarises the intervals between a run's heartbeats.
+// Percentiles cover the latest HeartbeatGapWindow gaps, listed oldest first in
+// RecentSeconds; Count and MaxSeconds cover every gap observed.
+type HeartbeatGapStats struct {
+	Count         int64     `json:"count"`
+	LastSeconds   float64   `json:"last_seconds"`
+	P50Seconds    float64   `json:"p50_seconds"`
+	P95Seconds    float64   `json:"p95_seconds"`
+	MaxSeconds    float64   `json:"max_seconds"`
+	RecentSeconds []float64 `json:"recent_seconds"`
+}
+
+// Observe folds a new gap into the statistics and returns the updated copy.
+func (s HeartbeatGapStats) Observe(gap time.Duration) HeartbeatGapStats {
+	seconds := gap.Seconds()
+	recent := append(append([]float64(nil), s.RecentSeconds...), seconds)
+	if overflow := len(recent) - HeartbeatGapWindow; overflow > 0 {
+		recent = recent[overflow:]
+	}
+	sorted := append([]float64(nil), recent...)
+	sort.Float64s(sorted)
+	s.Count++
+	s.LastSeconds = seconds
+	s.P50Seconds = nearestRank(sorted, 0.50)
+	s.P95Seconds = nearestRank(sorted, 0.95)
+	s.MaxSeconds = max(s.MaxSeconds, seconds)
+	s.RecentSeconds = recent
+	return s
+}
+
+// nearestRank returns the p-th percentile of sorted values.
+func nearestRank(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// Archived reports whether the run has been hidden from default listings.
+func (r Run) Archived() bool {
+	return r.ArchivedAt != nil
+}
+
+// ExceedsMaxDuration reports whether a running run has been running for longer
+// than its max duration and the watchdog has not yet acted on it.
+func (r Run) ExceedsMaxDuration(now time.Time) bool {
+	if r.State != RunStateRunning || r.MaxDurationSeconds <= 0 || r.MaxDurationExceededAt != nil || r.StartedAt == nil {
+		return false
+	}
+	return now.Sub(*r.StartedAt) > time.Duration(r.MaxDurationSeconds)*time.Second
+}
+
+// RunGraphNode is a run in a dependency graph. WaitingOn lists the prerequisites
+// a queued run is still waiting to see completed.
+type RunGraphNode struct {
+	ID           string   `json:"id"`
+	ExperimentID string   `json:"experiment_id"`
+	State        RunState `json:"state"`
+	DependsOn    []string `json:"depends_on,omitempty"`
+	WaitingOn    []string `json:"waiting_on,omitempty"`
+}
+
+// RunGraphEdge points from a prerequisite run to the run that depends on it.
+type RunGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RunGraph is the dependency DAG around a run: its transitive prerequisites and
+// dependents, with nodes in topological order.
+type RunGraph struct {
+	RunID string         `json:"run_id"`
+	Nodes []RunGraphNode `json:"nodes"`
+	Edges []RunGraphEdge `json:"edges"`
+}
+
+// Label limits keep run labels cheap to index and safe to echo in query strings.
+const (
+	MaxRunLabels        = 64
+	maxLabelKeyLength   = 63
+	maxLabelValueLength = 256
+)
+
+var labelKey = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.\-/]*[A-Za-z0-9])?$`)
+
+// ValidateLabels checks run labels: keys are alphanumeric with interior "-",
+// "_", "." or "/" and at most 63 characters; values may be any text up to 256
+// characters.
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > MaxRunLabels {
+		return fmt.Errorf("a run may carry at most %d labels", MaxRunLabels)
+	}
+	for key, value := range labels {
+		if len(key) > maxLabelKeyLength || !labelKey.MatchString(key) {
+			return fmt.Errorf("invalid label key %q", key)
+		}
+		if len(value) > maxLabelValueLength {
+			return fmt.Errorf("invalid value for label %q", key)
+		}
+	}
+	return nil
+}
+
+// Experiment is the template runs are launched from.
+type Experiment struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	Description      string          `json:"description,omitempty"`
+	Config           json.RawMessage `json:"config,omitempty"`
+	AllowedOverrides []string        `json:"allowed_overrides,omitempty"`
+	CreatedBy        string          `json:"created_by"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	ArchivedAt       *time.Time      `json:"archived_at,omitempty"`
+}
+
+// Archived reports whether the experiment has been hidden from default listings.
+func (e Experiment) Archived() bool {
+	return e.ArchivedAt != nil
+}
+
+// ExperimentSummary pairs an experiment with aggregates over its runs.
+type ExperimentSummary struct {
+	Experiment
+	TotalRuns int              `json:"total_runs"`
+	RunCounts map[RunState]int `json:"run_counts"`
+}
+
+// ConfigVersion is an immutable launch manifest registered against an experiment.
+type ConfigVersion struct {
+	ID              string          `json:"id"`
+	ExperimentID    string          `json:"experiment_id"`
+	Version         int             `json:"version"`
+	Manifest        json.RawMessage `json:"manifest"`
+	Hyperparameters json.RawMessage `json:"hyperparameters,omitempty"`
+	ManifestHash    string          `json:"manifest_hash"`
+	// ManifestSchema is a JSON Schema every launch manifest of the version's runs
+	// must satisfy.
+	ManifestSchema json.RawMessage `json:"manifest_schema,omitempty"`
+	Description    string          `json:"description,omitempty"`
+	CreatedBy      string          `json:"created_by"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// ManifestSchema is a JSON Schema registered for an environment. Launch
+// manifests whose game.env_id names the environment must satisfy it.
+type ManifestSchema struct {
+	Env         string          `json:"env"`
+	Schema      json.RawMessage `json:"schema"`
+	Description string          `json:"description,omitempty"`
+	// Revision counts registrations for the environment, starting at 1.
+	Revision  int       `json:"revision"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeatureFlag switches a risky actor or replay behaviour on or off without a
+// redeploy. Overrides roll it out to chosen experiments or runs: a run sees
+// its own override first, then its experiment's, then Enabled.
+type FeatureFlag struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Enabled     bool            `json:"enabled"`
+	Experiments map[string]bool `json:"experiments,omitempty"`
+	Runs        map[string]bool `json:"runs,omitempty"`
+	// Revision counts updates to the flag, starting at 1.
+	Revision  int       `json:"revision"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EnabledFor reports whether the flag is on for run.
+func (f FeatureFlag) EnabledFor(run Run) bool {
+	if enabled, ok := f.Runs[run.ID]; ok {
+		return enabled
+	}
+	if enabled, ok := f.Experiments[run.ExperimentID]; ok {
+		return enabled
+	}
+	return f.Enabled
+}
+
+// Learner is a registered learner process that the dispatcher assigns runs to.
+type Learner struct {
+	ID           string              `json:"id"`
+	Name         string              `json:"name,omitempty"`
+	Slots        int                 `json:"slots"`
+	Capabilities LearnerCapabilities `json:"capabilities"`
+	Load         *LearnerLoad        `json:"load,omitempty"`
+	Build        *BuildInfo          `json:"build,omitempty"`
+	ActiveRuns   int                 `json:"active_runs"`
+	UsedGPUs     int                 `json:"used_gpus"`
+	RegisteredAt time.Time           `json:"registered_at"`
+	LastSeenAt   time.Time           `json:"last_seen_at"`
+}
+
+// LearnerCapabilities describes what a learner can host.
+type LearnerCapabilities struct {
+	GPUs         int `json:"gpus"`
+	MaxBatchSize int `json:"max_batch_size,omitempty"`
+	// Envs lists the supported environment IDs; empty means any.
+	Envs []string `json:"envs,omitempty"`
+}
+
+// LearnerLoad is the utilisation a learner last reported, each in [0,1].
+type LearnerLoad struct {
+	GPUUtilization    float64   `json:"gpu_utilization"`
+	CPUUtilization    float64   `json:"cpu_utilization"`
+	MemoryUtilization float64   `json:"memory_utilization"`
+	ReportedAt        time.Time `json:"reported_at"`
+}
+
+// Validate ensures utilisation figures are fractions.
+func (l LearnerLoad) Validate() error {
+	for name, v := range map[string]float64{"gpu_utilization": l.GPUUtilization, "cpu_utilization": l.CPUUtilization, "memory_utilization": l.MemoryUtilization} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("%s must be within [0,1]", name)
+		}
+	}
+	return nil
+}
+
+// LearnerHeartbeat is a learner's report of its load and, optionally, the
+// build it is running.
+type LearnerHeartbeat struct {
+	LearnerLoad
+	Build *BuildInfo `json:"build,omitempty"`
+}
+
+// BuildInfo identifies the build a component reports running, as served on
+// its /version endpoint.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+}
+
+// maxBuildField bounds each BuildInfo field.
+const maxBuildField = 128
+
+// Validate requires a version and bounds each field's length.
+func (b BuildInfo) Validate() error {
+	if b.Version == "" {
+		return errors.New("build.version is required")
+	}
+	for name, v := range map[string]string{"version": b.Version, "commit": b.Commit, "build_time": b.BuildTime} {
+		if len(v) > maxBuildField {
+			return fmt.Errorf("build.%s must be at most %d characters", name, maxBuildField)
+		}
+	}
+	return nil
+}
+
+// FreeSlots returns how many more runs the learner can take.
+func (l Learner) FreeSlots() int {
+	if free := l.Slots - l.ActiveRuns; free > 0 {
+		return free
+	}
+	return 0
+}
+
+// CanHost reports whether the learner has the capabilities and spare GPUs for req.
+func (l Learner) CanHost(req RunRequirements) bool {
+	if l.FreeSlots() == 0 || l.Capabilities.GPUs-l.UsedGPUs < req.GPUs {
+		return false
+	}
+	if req.BatchSize > 0 && l.Capabilities.MaxBatchSize > 0 && req.BatchSize > l.Capabilities.MaxBatchSize {
+		return false
+	}
+	if req.Env == "" || len(l.Capabilities.Envs) == 0 {
+		return true
+	}
+	for _, env := range l.Capabilities.Envs {
+		if env == req.Env {
+			return true
+		}
+	}
+	return false
+}
+
+// RunRequirements are the placement constraints read from a launch manifest.
+// ResourceClass does not constrain placement; it prices the run's accounting.
+type RunRequirements struct {
+	GPUs          int
+	BatchSize     int
+	Env           string
+	ResourceClass string
+}
+
+// RequirementsFromManifest reads resources.gpus, resources.class,
+// trainer.batch_size and game.env_id from a launch manifest. Missing or
+// malformed fields impose no constraint.
+func RequirementsFromManifest(manifest json.RawMessage) RunRequirements {
+	var doc struct {
+		Resources struct {
+			GPUs  int    `json:"gpus"`
+			Class string `json:"class"`
+		} `json:"resources"`
+		Trainer struct {
+			BatchSize int `json:"batch_size"`
+		} `json:"trainer"`
+		Game struct {
+			EnvID string `json:"env_id"`
+		} `json:"game"`
+	}
+	if len(manifest) > 0 {
+		_ = json.Unmarshal(manifest, &doc)
+	}
+	return RunRequirements{
+		GPUs:          doc.Resources.GPUs,
+		BatchSize:     doc.Trainer.BatchSize,
+		Env:           doc.Game.EnvID,
+		ResourceClass: doc.Resources.Class,
+	}
+}
+
+// RunCost is the resource accounting of a run. Duration runs from the run's
+// start to its end, or to now while it is still going; Cost prices it at the
+// hourly rate configured for the manifest's resources.class.
+type RunCost struct {
+	RunID           string     `json:"run_id"`
+	ExperimentID    string     `json:"experiment_id"`
+	State           RunState   `json:"state"`
+	ResourceClass   string     `json:"resource_class,omitempty"`
+	GPUs            int        `json:"gpus"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	Steps           int64      `json:"steps"`
+	Samples         int64      `json:"samples"`
+	HourlyRate      float64    `json:"hourly_rate"`
+	Cost            float64    `json:"cost"`
+	// Final is set once the run has ended and its accounting no longer changes.
+	Final bool `json:"final"`
+}
+
+// ResourceClassCost totals the runs of one resource class.
+type ResourceClassCost struct {
+	Runs            int     `json:"runs"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Cost            float64 `json:"cost"`
+}
+
+// ExperimentCost totals the accounting of an experiment's runs. Runs without a
+// resources.class are grouped under the empty class.
+type ExperimentCost struct {
+	ExperimentID    string                       `json:"experiment_id"`
+	Runs            int                          `json:"runs"`
+	DurationSeconds float64                      `json:"duration_seconds"`
+	Steps           int64                        `json:"steps"`
+	Samples         int64                        `json:"samples"`
+	Cost            float64                      `json:"cost"`
+	ByResourceClass map[string]ResourceClassCost `json:"by_resource_class"`
+}
+
+// Checkpoint is a model snapshot a learner registered for a run.
+type Checkpoint struct {
+	RunID      string             `json:"run_id"`
+	Version    int64              `json:"version"`
+	Step       int64              `json:"step"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"`
+	StorageURI string             `json:"storage_uri"`
+	Promoted   bool               `json:"promoted"`
+	PromotedAt *time.Time         `json:"promoted_at,omitempty"`
+	PromotedBy string             `json:"promoted_by,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// ArtifactKind classifies an uploaded artifact.
+type ArtifactKind string
+
+const (
+	ArtifactCheckpoint ArtifactKind = "checkpoint"
+	ArtifactLog        ArtifactKind = "log"
+	ArtifactOther      ArtifactKind = "other"
+)
+
+// Valid reports whether k is a known artifact kind.
+func (k ArtifactKind) Valid() bool {
+	switch k {
+	case ArtifactCheckpoint, ArtifactLog, ArtifactOther:
+		return true
+	}
+	return false
+}
+
+// Artifact records a blob a run stores in the artifact backend. The orchestrator
+// only tracks metadata; bytes move directly between clients and the bucket.
+type Artifact struct {
+	RunID       string       `json:"run_id"`
+	Name        string       `json:"name"`
+	Kind        ArtifactKind `json:"kind"`
+	Key         string       `json:"key"`
+	URI         string       `json:"uri"`
+	ContentType string       `json:"content_type,omitempty"`
+	SizeBytes   int64        `json:"size_bytes,omitempty"`
+	SHA256      string       `json:"sha256,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UploadedAt  *time.Time   `json:"uploaded_at,omitempty"`
+}
+
+// Uploaded reports whether the client confirmed the upload finished.
+func (a Artifact) Uploaded() bool {
+	return a.UploadedAt != nil
+}
+
+// AuditEntry records one mutating API call: who made it, what it targeted, a
+// digest of the request body and the response status.
+type AuditEntry struct {
+	ID            string    `json:"id"`
+	Actor         string    `json:"actor"`
+	Role          string    `json:"role,omitempty"`
+	AuthMethod    string    `json:"auth_method,omitempty"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Query         string    `json:"query,omitempty"`
+	PayloadSHA256 string    `json:"payload_sha256"`
+	PayloadBytes  int64     `json:"payload_bytes"`
+	Status        int       `json:"status"`
+	RemoteAddr    string    `json:"remote_addr,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	DurationMS    int64     `json:"duration_ms"`
+	At            time.Time `json:"at"`
+}
+
+// Evaluation is a score report from an evaluation job against one checkpoint.
+// Suite names the evaluation protocol (opponent pool, seeds, env variant) so only
+// comparable scores are ranked together.
+type Evaluation struct {
+	ID                string             `json:"id"`
+	RunID             string             `json:"run_id"`
+	CheckpointVersion int64              `json:"checkpoint_version"`
+	Suite             string             `json:"suite"`
+	Scores            map[string]float64 `json:"scores"`
+	Episodes          int                `json:"episodes,omitempty"`
+	Evaluator         string             `json:"evaluator,omitempty"`
+	CreatedAt         time.Time          `json:"created_at"`
+}
+
+// Validate checks the report carries usable scores.
+func (e Evaluation) Validate() error {
+	if e.CheckpointVersion <= 0 {
+		return errors.New("checkpoint_version must be positive")
+	}
+	if e.Suite == "" {
+		return errors.New("suite is required")
+	}
+	if len(e.Scores) == 0 {
+		return errors.New("scores must not be empty")
+	}
+	for name, value := range e.Scores {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("score %q must be finite", name)
+		}
+	}
+	if rate, ok := e.Scores["win_rate"]; ok && (rate < 0 || rate > 1) {
+		return errors.New("win_rate must be between 0 and 1")
+	}
+	if e.Episodes < 0 {
+		return errors.New("episodes must not be negative")
+	}
+	return nil
+}
+
+// MetricPoint is the training telemetry carried by a single heartbeat, or the
+// aggregate of several heartbeats when a series is downsampled.
+type MetricPoint struct {
+	At               time.Time `json:"at"`
+	Step             int64     `json:"step"`
+	Loss             float64   `json:"loss"`
+	SamplesPerSecond float64   `json:"samples_per_sec"`
+	// Samples counts the heartbeats folded into a downsampled point.
+	Samples int `json:"samples,omitempty"`
+}
+
+// LogLevel is the severity of a run log line.
+type LogLevel string
+
+const (
+	LogLevelDebug LogLevel = "debug"
+	LogLevelInfo  LogLevel = "info"
+	LogLevelWarn  LogLevel = "warn"
+	LogLevelError LogLevel = "error"
+)
+
+var logLevelRanks = map[LogLevel]int{LogLevelDebug: 0, LogLevelInfo: 1, LogLevelWarn: 2, LogLevelError: 3}
+
+// Valid reports whether l is a known level.
+func (l LogLevel) Valid() bool {
+	_, ok := logLevelRanks[l]
+	return ok
+}
+
+// AtLeast reports whether l is as severe as min.
+func (l LogLevel) AtLeast(min LogLevel) bool {
+	return logLevelRanks[l] >= logLevelRanks[min]
+}
+
+// MaxLogMessageBytes bounds the message of a single log line.
+const MaxLogMessageBytes = 8 * 1024
+
+// LogLine is a structured log line pushed by a run's learner or actors. Seq is
+// assigned by the orchestrator and increases monotonically within the run.
+type LogLine struct {
+	Seq   int64     `json:"seq"`
+	At    time.Time `json:"at"`
+	Level LogLevel  `json:"level"`
+	// Source names the emitting process, e.g. "learner" or "actor-3".
+	Source  string         `json:"source,omitempty"`
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// Validate checks a pushed log line.
+func (l LogLine) Validate() error {
+	if !l.Level.Valid() {
+		return fmt.Errorf("invalid level %q", l.Level)
+	}
+	if l.Message == "" {
+		return errors.New("message is required")
+	}
+	if len(l.Message) > MaxLogMessageBytes {
+		return fmt.Errorf("message exceeds %d bytes", MaxLogMessageBytes)
+	}
+	return nil
+}
+
+// RunEvent is a published event kept in a run's event log, so consumers that
+// were disconnected from the broker can backfill what they missed. ID orders
+// events across all runs.
+type RunEvent struct {
+	ID    int64  `json:"id"`
+	RunID string `json:"run_id"`
+	// Type is the kind of event: run_status, command, accounting or alert.
+	Type        string          `json:"type"`
+	PublishedAt time.Time       `json:"published_at"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// MaxAnnotationTextBytes bounds the text of a single annotation.
+const MaxAnnotationTextBytes = 4 * 1024
+
+// Annotation is an operator's note pinned to a point in a run's timeline, such
+// as "lowered lr to 1e-4", so the context behind a change in the metrics is
+// kept next to them.
+type Annotation struct {
+	ID    string `json:"id"`
+	RunID string `json:"run_id"`
+	// At places the note on the metric timeline; Step is the run's step then.
+	At        time.Time `json:"at"`
+	Step      int64     `json:"step"`
+	Text      string    `json:"text"`
+	Tags      []string  `json:"tags,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the annotation carries a note.
+func (a Annotation) Validate() error {
+	if strings.TrimSpace(a.Text) == "" {
+		return errors.New("text is required")
+	}
+	if len(a.Text) > MaxAnnotationTextBytes {
+		return fmt.Errorf("text exceeds %d bytes", MaxAnnotationTextBytes)
+	}
+	if a.Step < 0 {
+		return errors.New("step must not be negative")
+	}
+	for _, tag := range a.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return errors.New("tags must not be empty")
+		}
+	}
+	return nil
+}
+
+// OverlapPolicy controls what a schedule does when its previous run is still active.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the activation while the previous run is active.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue creates the run anyway; it waits in the queue behind the active one.
+	OverlapQueue OverlapPolicy = "queue"
+)
+
+// Schedule creates runs from a template manifest on a cron cadence.
+type Schedule struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Cron           string          `json:"cron"`
+	ExperimentID   string          `json:"experiment_id"`
+	VersionID      string          `json:"version_id"`
+	LaunchManifest json.RawMessage `json:"launch_manifest,omitempty"`
+	Overrides      json.RawMessage `json:"overrides,omitempty"`
+	Priority       int             `json:"priority"`
+	OverlapPolicy  OverlapPolicy   `json:"overlap_policy"`
+	Enabled        bool            `json:"enabled"`
+	NextRunAt      *time.Time      `json:"next_run_at,omitempty"`
+	LastRunAt      *time.Time      `json:"last_run_at,omitempty"`
+	LastRunID      string          `json:"last_run_id,omitempty"`
+	CreatedBy      string          `json:"created_by"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// TemplateParameterType is the JSON type a template parameter accepts.
+type TemplateParameterType string
+
+const (
+	TemplateParameterString  TemplateParameterType = "string"
+	TemplateParameterInteger TemplateParameterType = "integer"
+	TemplateParameterNumber  TemplateParameterType = "number"
+	TemplateParameterBoolean TemplateParameterType = "boolean"
+)
+
+// TemplateParameter declares a placeholder a run template accepts. Parameters
+// without a default must be supplied when a run is created from the template.
+type TemplateParameter struct {
+	Name        string                `json:"name"`
+	Type        TemplateParameterType `json:"type"`
+	Default     json.RawMessage       `json:"default,omitempty"`
+	Description string                `json:"description,omitempty"`
+}
+
+// RunTemplate is a reusable launch manifest with ${name} placeholders. A string
+// that is exactly one placeholder is replaced by the typed parameter value;
+// placeholders inside longer strings are substituted as text.
+type RunTemplate struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Manifest    json.RawMessage     `json:"manifest"`
+	Parameters  []TemplateParameter `json:"parameters,omitempty"`
+	CreatedBy   string              `json:"created_by"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+var (
+	templatePlaceholder   = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	templateParameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Validate checks the parameter declarations and that every placeholder in the
+// manifest refers to a declared parameter.
+func (t RunTemplate) Validate() error {
+	manifest, err := decodeTemplateManifest(t.Manifest)
+	if err != nil {
+		return err
+	}
+	declared := make(map[string]bool, len(t.Parameters))
+	for _, param := range t.Parameters {
+		if !templateParameterName.MatchString(param.Name) {
+			return fmt.Errorf("invalid parameter name %q", param.Name)
+		}
+		if declared[param.Name] {
+			return fmt.Errorf("parameter %s is declared twice", param.Name)
+		}
+		declared[param.Name] = true
+		if param.kind() == "" {
+			return fmt.Errorf("parameter %s has unsupported type %q", param.Name, param.Type)
+		}
+		if len(param.Default) > 0 {
+			if _, err := param.decode(param.Default); err != nil {
+				return fmt.Errorf("parameter %s default: %w", param.Name, err)
+			}
+		}
+	}
+	var undeclared []string
+	walkTemplateStrings(manifest, func(s string) {
+		for _, match := range templatePlaceholder.FindAllStringSubmatch(s, -1) {
+			if !declared[match[1]] {
+				undeclared = append(undeclared, match[1])
+				declared[match[1]] = true
+			}
+		}
+	})
+	if len(undeclared) > 0 {
+		return fmt.Errorf("manifest references undeclared parameters: %s", strings.Join(undeclared, ", "))
+	}
+	return nil
+}
+
+// Materialize substitutes params, falling back to parameter defaults, into the
+// manifest. Unknown, missing or mistyped parameters are rejected.
+func (t RunTemplate) Materialize(params map[string]json.RawMessage) (json.RawMessage, error) {
+	manifest, err := decodeTemplateManifest(t.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any, len(t.Parameters))
+	var missing []string
+	for _, param := range t.Parameters {
+		raw, ok := params[param.Name]
+		if !ok {
+			if len(param.Default) == 0 {
+				missing = append(missing, param.Name)
+				continue
+			}
+			raw = param.Default
+		}
+		value, err := param.decode(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		values[param.Name] = value
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing template parameters: %s", strings.Join(missing, ", "))
+	}
+	unknown := make([]string, 0)
+	for name := range params {
+		if _, ok := values[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown template parameters: %s", strings.Join(unknown, ", "))
+	}
+	return json.Marshal(substituteTemplate(manifest, values))
+}
+
+func (p TemplateParameter) kind() TemplateParameterType {
+	switch p.Type {
+	case "":
+		return TemplateParameterString
+	case TemplateParameterString, TemplateParameterInteger, TemplateParameterNumber, TemplateParameterBoolean:
+		return p.Type
+	}
+	return ""
+}
+
+// decode parses raw as a value of the parameter's type.
+func (p TemplateParameter) decode(raw json.RawMessage) (any, error) {
+	kind := p.kind()
+	if kind == "" {
+		return nil, fmt.Errorf("parameter %s has unsupported type %q", p.Name, p.Type)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("must be a %s", kind)
+	}
+	switch v := value.(type) {
+	case string:
+		if kind == TemplateParameterString {
+			return v, nil
+		}
+	case bool:
+		if kind == TemplateParameterBoolean {
+			return v, nil
+		}
+	case json.Number:
+		if kind == TemplateParameterNumber {
+			return v, nil
+		}
+		if _, err := v.Int64(); err == nil && kind == TemplateParameterInteger {
+			return v, nil
+		}
+	}
+	if kind == TemplateParameterInteger {
+		return nil, errors.New("must be an integer")
+	}
+	return nil, fmt.Errorf("must be a %s", kind)
+}
+
+func decodeTemplateManifest(raw json.RawMessage) (map[string]any, error) {
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	var manifest map[string]any
+	if err := decoder.Decode(&manifest); err != nil || manifest == nil {
+		return nil, errors.New("manifest must be a JSON object")
+	}
+	return manifest, nil
+}
+
+func walkTemplateStrings(value any, visit func(string)) {
+	switch v := value.(type) {
+	case string:
+		visit(v)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkTemplateStrings(v[key], visit)
+		}
+	case []any:
+		for _, item := range v {
+			walkTemplateStrings(item, visit)
+		}
+	}
+}
+
+func substituteTemplate(value any, values map[string]any) any {
+	switch v := value.(type) {
+	case string:
+		if match := templatePlaceholder.FindStringSubmatch(v); match != nil && match[0] == v {
+			return values[match[1]]
+		}
+		return templatePlaceholder.ReplaceAllStringFunc(v, func(placeholder string) string {
+			return fmt.Sprint(values[placeholder[2:len(placeholder)-1]])
+		})
+	case map[string]any:
+		for key, item := range v {
+			v[key] = substituteTemplate(item, values)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = substituteTemplate(item, values)
+		}
+	}
+	return value
+}
+
+// StoppingRuleKind selects the condition an early-stopping rule watches.
+type StoppingRuleKind string
+
+const (
+	// StoppingRuleLossPlateau fires once loss has not improved on its best by more
+	// than MinDelta for Heartbeats consecutive heartbeats.
+	StoppingRuleLossPlateau StoppingRuleKind = "loss_plateau"
+	// StoppingRuleLossAbove fires once loss exceeds Threshold for Heartbeats
+	// consecutive heartbeats (default 1), e.g. when training diverges.
+	StoppingRuleLossAbove StoppingRuleKind = "loss_above"
+	// StoppingRuleThroughputBelow fires once samples_per_sec has stayed below
+	// Threshold for WindowSeconds.
+	StoppingRuleThroughputBelow StoppingRuleKind = "throughput_below"
+)
+
+// StoppingRule is an early-stopping condition attached to a run. The
+// orchestrator evaluates it on every heartbeat while the run is running and,
+// when it fires, issues Action (pause or terminate) once.
+type StoppingRule struct {
+	ID            string           `json:"id"`
+	RunID         string           `json:"run_id"`
+	Kind          StoppingRuleKind `json:"kind"`
+	Action        CommandType      `json:"action"`
+	Heartbeats    int              `json:"heartbeats,omitempty"`
+	MinDelta      float64          `json:"min_delta,omitempty"`
+	Threshold     float64          `json:"threshold,omitempty"`
+	WindowSeconds int64            `json:"window_seconds,omitempty"`
+	Enabled       bool             `json:"enabled"`
+	// BestLoss, Streak and BelowSince carry evaluation state between heartbeats.
+	BestLoss    *float64   `json:"best_loss,omitempty"`
+	Streak      int        `json:"streak"`
+	BelowSince  *time.Time `json:"below_since,omitempty"`
+	TriggeredAt *time.Time `json:"triggered_at,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	CommandID   string     `json:"command_id,omitempty"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Validate checks the rule's kind, action and thresholds.
+func (r StoppingRule) Validate() error {
+	switch r.Action {
+	case CommandTypePause, CommandTypeTerminate:
+	default:
+		return fmt.Errorf("action must be pause or terminate, got %q", r.Action)
+	}
+	if r.Heartbeats < 0 || r.WindowSeconds < 0 || r.MinDelta < 0 {
+		return errors.New("heartbeats, min_delta and window_seconds must not be negative")
+	}
+	if math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0) || math.IsNaN(r.MinDelta) || math.IsInf(r.MinDelta, 0) {
+		return errors.New("threshold and min_delta must be finite")
+	}
+	switch r.Kind {
+	case StoppingRuleLossPlateau:
+		if r.Heartbeats == 0 {
+			return errors.New("loss_plateau requires heartbeats")
+		}
+	case StoppingRuleLossAbove:
+	case StoppingRuleThroughputBelow:
+		if r.Threshold <= 0 || r.WindowSeconds == 0 {
+			return errors.New("throughput_below requires a positive threshold and window_seconds")
+		}
+	default:
+		return fmt.Errorf("unsupported rule kind %q", r.Kind)
+	}
+	return nil
+}
+
+// Triggered reports whether the rule has already fired.
+func (r StoppingRule) Triggered() bool {
+	return r.TriggeredAt != nil
+}
+
+// Observe folds a heartbeat's loss and throughput into the rule state. It
+// returns the updated rule and, when the rule fires, the reason.
+func (r StoppingRule) Observe(loss, samplesPerSecond float64, at time.Time) (StoppingRule, string) {
+	switch r.Kind {
+	case StoppingRuleLossPlateau:
+		if r.BestLoss == nil || loss < *r.BestLoss-r.MinDelta {
+			r.BestLoss = &loss
+			r.Streak = 0
+			return r, ""
+		}
+		r.Streak++
+		if r.Streak >= r.Heartbeats {
+			return r, fmt.Sprintf("loss has not improved on %g by more than %g for %d heartbeats", *r.BestLoss, r.MinDelta, r.Streak)
+		}
+	case StoppingRuleLossAbove:
+		if !(loss > r.Threshold) && !math.IsNaN(loss) {
+			r.Streak = 0
+			return r, ""
+		}
+		r.Streak++
+		if r.Streak >= max(r.Heartbeats, 1) {
+			return r, fmt.Sprintf("loss %g exceeded %g for %d heartbeats", loss, r.Threshold, r.Streak)
+		}
+	case StoppingRuleThroughputBelow:
+		if samplesPerSecond >= r.Threshold {
+			r.BelowSince = nil
+			return r, ""
+		}
+		if r.BelowSince == nil {
+			r.BelowSince = &at
+		}
+		if below := at.Sub(*r.BelowSince); below >= time.Duration(r.WindowSeconds)*time.Second {
+			return r, fmt.Sprintf("samples_per_sec %g below %g for %s", samplesPerSecond, r.Threshold, below.Round(time.Second))
+		}
+	}
+	return r, ""
+}
+
+// AlertRuleKind selects the condition an alert rule watches.
+type AlertRuleKind string
+
+const (
+	// AlertRuleLossSpike breaches when loss rises more than Threshold percent
+	// over the previous heartbeat's loss, or is not a number.
+	AlertRuleLossSpike AlertRuleKind = "loss_spike"
+	// AlertRuleThroughputFloor breaches while samples_per_sec is below Threshold.
+	AlertRuleThroughputFloor AlertRuleKind = "throughput_floor"
+	// AlertRuleHeartbeatGap breaches when a heartbeat arrives more than Threshold
+	// seconds after the previous one. Runs that stop heartbeating altogether are
+	// caught by the health monitor instead.
+	AlertRuleHeartbeatGap AlertRuleKind = "heartbeat_gap"
+)
+
+// AlertSeverity tags alert events so that routing can page on some only.
+type AlertSeverity string
+
+const (
+	AlertSeverityInfo     AlertSeverity = "info"
+	AlertSeverityWarning  AlertSeverity = "warning"
+	AlertSeverityCritical AlertSeverity = "critical"
+)
+
+// AlertRule is a threshold attached to a run. The orchestrator evaluates it on
+// every heartbeat while the run is running, raising an alert when the
+// threshold is first breached and resolving it once a heartbeat is back within
+// it.
+type AlertRule struct {
+	ID       string        `json:"id"`
+	RunID    string        `json:"run_id"`
+	Kind     AlertRuleKind `json:"kind"`
+	Severity AlertSeverity `json:"severity"`
+	// Threshold is a percentage for loss_spike, samples per second for
+	// throughput_floor and seconds for heartbeat_gap.
+	Threshold float64 `json:"threshold"`
+	Enabled   bool    `json:"enabled"`
+	// LastLoss carries the previous heartbeat's loss for loss_spike.
+	LastLoss    *float64   `json:"last_loss,omitempty"`
+	FiringSince *time.Time `json:"firing_since,omitempty"`
+	Breaches    int        `json:"breaches"`
+	LastMessage string     `json:"last_message,omitempty"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Validate checks the rule's kind, severity and threshold.
+func (r AlertRule) Validate() error {
+	switch r.Severity {
+	case AlertSeverityInfo, AlertSeverityWarning, AlertSeverityCritical:
+	default:
+		return fmt.Errorf("severity must be info, warning or critical, got %q", r.Severity)
+	}
+	if math.IsNaN(r.Threshold) || math.IsInf(r.Threshold, 0) || r.Threshold <= 0 {
+		return errors.New("threshold must be positive and finite")
+	}
+	switch r.Kind {
+	case AlertRuleLossSpike, AlertRuleThroughputFloor, AlertRuleHeartbeatGap:
+	default:
+		return fmt.Errorf("unsupported rule kind %q", r.Kind)
+	}
+	return nil
+}
+
+// Firing reports whether the rule's alert is currently raised.
+func (r AlertRule) Firing() bool {
+	return r.FiringSince != nil
+}
+
+// Observe checks a heartbeat's loss, throughput and the gap since the previous
+// heartbeat against the rule. It returns the updated rule state and, when the
+// threshold is breached, a description of the breach.
+func (r AlertRule) Observe(loss, samplesPerSecond float64, gap time.Duration) (AlertRule, string) {
+	switch r.Kind {
+	case AlertRuleLossSpike:
+		if math.IsNaN(loss) {
+			return r, "loss is NaN"
+		}
+		previous := r.LastLoss
+		r.LastLoss = &loss
+		if previous != nil && loss-*previous > math.Abs(*previous)*r.Threshold/100 {
+			return r, fmt.Sprintf("loss rose from %g to %g, more than %g%%", *previous, loss, r.Threshold)
+		}
+	case AlertRuleThroughputFloor:
+		if samplesPerSecond < r.Threshold {
+			return r, fmt.Sprintf("samples_per_sec %g below %g", samplesPerSecond, r.Threshold)
+		}
+	case AlertRuleHeartbeatGap:
+		if limit := time.Duration(r.Threshold * float64(time.Second)); gap > limit {
+			return r, fmt.Sprintf("heartbeat gap of %s exceeded %s", gap.Round(time.Second), limit)
+		}
+	}
+	return r, ""
+}
+
+// ConfigChangeKind classifies a difference between two config documents.
+type ConfigChangeKind string
+
+const (
+	ConfigChangeAdded   ConfigChangeKind = "added"
+	ConfigChangeRemoved ConfigChangeKind = "removed"
+	ConfigChangeChanged ConfigChangeKind = "changed"
+)
+
+// ConfigChange describes a single dot-path difference between two configs.
+type ConfigChange struct {
+	Path   string           `json:"path"`
+	Kind   ConfigChangeKind `json:"kind"`
+	Before any              `json:"before,omitempty"`
+	After  any              `json:"after,omitempty"`
+}
+
+// DiffConfigs compares two JSON object documents key by key. Nested objects are
+// walked; arrays and scalars are compared as whole values.
+func DiffConfigs(before, after json.RawMessage) ([]ConfigChange, error) {
+	left, err := flattenConfig(before)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base config: %w", err)
+	}
+	right, err := flattenConfig(after)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target config: %w", err)
+	}
+	var changes []ConfigChange
+	for path, value := range left {
+		other, ok := right[path]
+		switch {
+		case !ok:
+			changes = append(changes, ConfigChange{Path: path, Kind: ConfigChangeRemoved, Before: value})
+		case !reflect.DeepEqual(value, other):
+			changes = append(changes, ConfigChange{Path: path, Kind: ConfigChangeChanged, Before: value, After: other})
+		}
+	}
+	for path, value := range right {
+		if _, ok := left[path]; !ok {
+			changes = append(changes, ConfigChange{Path: path, Kind: ConfigChangeAdded, After: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func flattenConfig(raw json.RawMessage) (map[string]any, error) {
+	flat := make(map[string]any)
+	if len(raw) == 0 {
+		return flat, nil
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	var walk func(prefix string, node map[string]any)
+	walk = func(prefix string, node map[string]any) {
+		for key, value := range node {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			if child, ok := value.(map[string]any); ok && len(child) > 0 {
+				walk(path, child)
+				continue
+			}
+			flat[path] = value
+		}
+	}
+	walk("", doc)
+	return flat, nil
 }
 
 // HeartbeatPayload is the payload accepted by the heartbeat endpoint.
@@ -126,6 +1516,7 @@ type HeartbeatPayload struct {
 	CheckpointVersion int64         `json:"checkpoint_version"`
 	QueuedCommands    []string      `json:"queued_commands,omitempty"`
 	Notes             string        `json:"notes,omitempty"`
+	Build             *BuildInfo    `json:"build,omitempty"`
 }
 
 // Validate ensures the payload respects schema invariants.
@@ -147,55 +1538,25 @@ func (h HeartbeatPayload) Validate(expectedRunID string, currentStep, currentChe
 	if h.CheckpointVersion < 0 {
 		return errors.New("checkpoint_version must be non-negative")
 	}
+	if h.Build != nil {
+		if err := h.Build.Validate(); err != nil {
+			return err
+		}
+	}
 	if currentStep > 0 && h.Step < currentStep {
-		return fmt.Errorf("step regression: %d < %d", h.Step, currentStep)
+		return fmt.Errorf("step %w: %d < %d", ErrRegression, h.Step, currentStep)
 	}
 	if currentCheckpoint > 0 && h.CheckpointVersion < currentCheckpoint {
-		return fmt.Errorf("checkpoint regression: %d < %d", h.CheckpointVersion, currentCheckpoint)
+		return fmt.Errorf("checkpoint %w: %d < %d", ErrRegression, h.CheckpointVersion, currentCheckpoint)
 	}
 	return nil
 }
 
-// Validate performs type-specific checks for run commands.
+// Validate performs type-specific checks for run commands using the payload
+// validator registered for the command's type.
 func (c RunCommand) Validate() error {
-	switch c.Type {
-	case CommandTypeTune:
-		var payload TunePayload
-		if err := json.Unmarshal(c.Payload, &payload); err != nil {
-			return fmt.Errorf("invalid tune payload: %w", err)
-		}
-		if payload.LearningRate == nil && payload.EntropyCoef == nil && payload.ClipEpsilon == nil {
-			return errors.New("tune payload requires at least one tunable field")
-		}
-		if payload.LearningRate != nil {
-			if *payload.LearningRate <= 0 || *payload.LearningRate > 1 {
-				return errors.New("learning_rate must be in (0,1]")
-			}
-		}
-		if payload.EntropyCoef != nil {
-			if *payload.EntropyCoef < 0 || *payload.EntropyCoef > 0.1 {
-				return errors.New("entropy_coef must be within [0,0.1]")
-			}
-		}
-		if payload.ClipEpsilon != nil {
-			if *payload.ClipEpsilon < 0.05 || *payload.ClipEpsilon > 0.3 {
-				return errors.New("clip_epsilon must be within [0.05,0.3]")
-			}
-		}
-	case CommandTypePause, CommandTypeResume:
-		if len(c.Payload) > 0 && string(c.Payload) != "{}" {
-			return errors.New("pause/resume payload must be empty")
-		}
-	case CommandTypeTerminate:
-		var payload TerminatePayload
-		if err := json.Unmarshal(c.Payload, &payload); err != nil {
-			return fmt.Errorf("invalid terminate payload: %w", err)
-		}
-		if payload.Reason == "" {
-			return errors.New("terminate payload requires reason")
-		}
-	default:
-		return fmt.Errorf("unsupported command type %q", c.Type)
+	if err := c.Type.ValidatePayload(c.Payload); err != nil {
+		return err
 	}
 	switch c.Actor.Type {
 	case CommandActorOperator, CommandActorSystem:
@@ -208,16 +1569,77 @@ func (c RunCommand) Validate() error {
 	if c.IssuedAt.IsZero() {
 		return errors.New("issued_at is required")
 	}
+	if c.ExpiresAt != nil && !c.ExpiresAt.After(c.IssuedAt) {
+		return errors.New("expires_at must be after issued_at")
+	}
 	return nil
 }
 
+// ApplyTransition moves the run to next, stamping lifecycle timestamps.
+func (r Run) ApplyTransition(next RunState, reason string, at time.Time) (Run, error) {
+	if err := r.State.ValidateTransition(next); err != nil {
+		return r, err
+	}
+	r.State = next
+	if reason != "" {
+		r.StatusMessage = reason
+	}
+	if next != RunStatePaused {
+		// Preemption only describes why a run is paused; its slot is reclaimed on resume.
+		r.PreemptedBy = ""
+	}
+	if next == RunStateRunning && r.StartedAt == nil {
+		r.StartedAt = &at
+	}
+	if next.IsTerminal() {
+		r.EndedAt = &at
+	}
+	r.UpdatedAt = at
+	return r, nil
+}
+
+// HeartbeatTarget returns the lifecycle state a learner-reported runtime status
+// moves the run into, if any. Only errored and terminating reports drive the
+// lifecycle; running and paused leave it to operators and the dispatcher.
+func (r Run) HeartbeatTarget(status RuntimeStatus) (RunState, bool) {
+	var next RunState
+	switch status {
+	case RuntimeStatusErrored:
+		next = RunStateErrored
+	case RuntimeStatusTerminating:
+		next = RunStateTerminating
+	default:
+		return "", false
+	}
+	if r.State == next || !r.State.CanTransitionTo(next) {
+		return "", false
+	}
+	return next, true
+}
+
 // MergeHeartbeat applies the heartbeat values to a run and returns the updated copy.
 func (r Run) MergeHeartbeat(h HeartbeatPayload, receivedAt time.Time) Run {
+	if r.LastHeartbeatAt != nil && receivedAt.After(*r.LastHeartbeatAt) {
+		gap := receivedAt.Sub(*r.LastHeartbeatAt)
+		r.SamplesProcessed += int64(math.Round(h.SamplesPerSecond * gap.Seconds()))
+		// Gaps across provisioning or a restart say nothing about the learner's cadence.
+		if r.State == RunStateRunning || r.State == RunStatePaused {
+			var gaps HeartbeatGapStats
+			if r.HeartbeatGaps != nil {
+				gaps = *r.HeartbeatGaps
+			}
+			gaps = gaps.Observe(gap)
+			r.HeartbeatGaps = &gaps
+		}
+	}
 	r.LastHeartbeatAt = &receivedAt
 	r.RuntimeStatus = h.Status
 	r.CurrentStep = h.Step
 	r.SamplesPerSecond = h.SamplesPerSecond
 	r.Loss = h.Loss
 	r.CheckpointVersion = h.CheckpointVersion
+	if h.Build != nil {
+		r.LearnerBuild = h.Build
+	}
 	return r
 }
@@ -2,6 +2,9 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
+	"strings"
 	"testing"
 	"time"
 )
@@ -20,6 +23,21 @@ func TestHeartbeatValidateRegression(t *testing.T) {
 	}
 }
 
+func TestBuildInfoValidate(t *testing.T) {
+	if err := (BuildInfo{Version: "v1.4.0", Commit: strings.Repeat("a", 40)}).Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	for _, build := range []BuildInfo{{Commit: "abc123"}, {Version: strings.Repeat("v", 129)}} {
+		if err := build.Validate(); err == nil {
+			t.Fatalf("expected %+v to be rejected", build)
+		}
+	}
+	h := HeartbeatPayload{RunID: "run-1", Status: RuntimeStatusRunning, Build: &BuildInfo{}}
+	if err := h.Validate("run-1", 0, 0); err == nil || !strings.Contains(err.Error(), "build.version") {
+		t.Fatalf("expected the heartbeat's build to be validated, got %v", err)
+	}
+}
+
 func TestRunCommandValidateTunePayload(t *testing.T) {
 	cmd := RunCommand{
 		ID:       "cmd-1",
@@ -53,4 +71,279 @@ func TestRunCommandValidateTuneMissingPayload(t *testing.T) {
 	}
 }
 
+func TestCommandTypeRegistry(t *testing.T) {
+	cmd := RunCommand{
+		ID:       "cmd-1",
+		RunID:    "run-1",
+		Actor:    CommandActor{Type: CommandActorOperator, ID: "user@example.com"},
+		IssuedAt: time.Now(),
+	}
+	cases := []struct {
+		typ     CommandType
+		payload string
+		valid   bool
+	}{
+		{CommandTypeCheckpointNow, ``, true},
+		{CommandTypeCheckpointNow, `{"tag": "pre-tune"}`, true},
+		{CommandTypeCheckpointNow, `{"tga": "typo"}`, false},
+		{CommandTypeEvalNow, `{"suite": "vs-random", "episodes": 50}`, true},
+		{CommandTypeEvalNow, `{"episodes": -1}`, false},
+		{CommandTypeResizeActors, `{"replicas": 8}`, true},
+		{CommandTypeResizeActors, `{}`, false},
+		{CommandTypePause, `{"now": true}`, false},
+		{"snapshot_replay", `{}`, false},
+	}
+	for _, c := range cases {
+		cmd.Type, cmd.Payload = c.typ, json.RawMessage(c.payload)
+		if err := cmd.Validate(); (err == nil) != c.valid {
+			t.Errorf("%s %s: expected valid=%v, got %v", c.typ, c.payload, c.valid, err)
+		}
+	}
+
+	// Registering a type makes it deliverable without touching Validate.
+	replay := CommandType("snapshot_replay")
+	if err := RegisterCommandType(replay, func(payload json.RawMessage) error {
+		if string(payload) != `{"buffer":"main"}` {
+			return errors.New("buffer must be main")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	t.Cleanup(func() {
+		commandTypesMu.Lock()
+		defer commandTypesMu.Unlock()
+		delete(commandTypes, replay)
+	})
+	cmd.Type, cmd.Payload = replay, json.RawMessage(`{"buffer":"main"}`)
+	if err := cmd.Validate(); err != nil {
+		t.Fatalf("expected the registered type to validate, got %v", err)
+	}
+	if err := RegisterCommandType(CommandTypeTune, validateTunePayload); err == nil {
+		t.Fatal("expected a duplicate registration to fail")
+	}
+	registered := CommandTypes()
+	if len(registered) != 8 || registered[0] != CommandTypeCheckpointNow {
+		t.Fatalf("expected the built-in and registered types in name order, got %v", registered)
+	}
+}
+
+func TestRunStateTransitions(t *testing.T) {
+	cases := []struct {
+		from, to RunState
+		ok       bool
+	}{
+		{RunStateQueued, RunStateProvisioning, true},
+		{RunStateProvisioning, RunStateRunning, true},
+		{RunStateRunning, RunStatePaused, true},
+		{RunStatePaused, RunStateRunning, true},
+		{RunStateRunning, RunStateCompleted, true},
+		{RunStateQueued, RunStateRunning, false},
+		{RunStateCompleted, RunStateRunning, false},
+		{RunStateTerminated, RunStateQueued, false},
+	}
+	for _, tc := range cases {
+		if got := tc.from.CanTransitionTo(tc.to); got != tc.ok {
+			t.Errorf("%s -> %s: expected %v, got %v", tc.from, tc.to, tc.ok, got)
+		}
+	}
+}
+
+func TestRunApplyTransitionStampsTimestamps(t *testing.T) {
+	now := time.Now()
+	run := Run{ID: "run-1", State: RunStateProvisioning}
+	run, err := run.ApplyTransition(RunStateRunning, "", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.StartedAt == nil || run.EndedAt != nil {
+		t.Fatalf("expected started_at only, got started=%v ended=%v", run.StartedAt, run.EndedAt)
+	}
+	run, err = run.ApplyTransition(RunStateCompleted, "done", now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if run.EndedAt == nil || run.StatusMessage != "done" {
+		t.Fatalf("expected ended_at and status message, got %+v", run)
+	}
+	if _, err := run.ApplyTransition(RunStateRunning, "", now); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestRunStateActionTarget(t *testing.T) {
+	if next, err := RunStatePaused.ActionTarget(RunActionResume); err != nil || next != RunStateRunning {
+		t.Fatalf("expected paused resume -> running, got %s (%v)", next, err)
+	}
+	if _, err := RunStateRunning.ActionTarget(RunActionResume); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected resume of running run to be rejected, got %v", err)
+	}
+	if next, _ := RunStateQueued.ActionTarget(RunActionTerminate); next != RunStateTerminated {
+		t.Fatalf("expected queued terminate -> terminated, got %s", next)
+	}
+	if next, _ := RunStateRunning.ActionTarget(RunActionTerminate); next != RunStateTerminating {
+		t.Fatalf("expected running terminate -> terminating, got %s", next)
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	before := json.RawMessage(`{"trainer":{"lr":0.0003,"gamma":0.99},"num_envs":32}`)
+	after := json.RawMessage(`{"trainer":{"lr":0.001,"gamma":0.99},"algo":"PPO"}`)
+	changes, err := DiffConfigs(before, after)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	want := []struct {
+		path string
+		kind ConfigChangeKind
+	}{{"algo", ConfigChangeAdded}, {"num_envs", ConfigChangeRemoved}, {"trainer.lr", ConfigChangeChanged}}
+	for i, w := range want {
+		if changes[i].Path != w.path || changes[i].Kind != w.kind {
+			t.Fatalf("change %d: expected %s %s, got %s %s", i, w.kind, w.path, changes[i].Kind, changes[i].Path)
+		}
+	}
+	if _, err := DiffConfigs(json.RawMessage(`[1]`), after); err == nil {
+		t.Fatalf("expected non-object config to be rejected")
+	}
+}
+
+func TestRunTemplateMaterialize(t *testing.T) {
+	template := RunTemplate{
+		ID:       "atari",
+		Manifest: json.RawMessage(`{"game":{"env_id":"${env}"},"trainer":{"batch_size":"${batch_size}","lr":"${lr}"},"tags":["seed-${seed}"],"debug":"${debug}"}`),
+		Parameters: []TemplateParameter{
+			{Name: "env", Type: TemplateParameterString},
+			{Name: "batch_size", Type: TemplateParameterInteger, Default: json.RawMessage(`256`)},
+			{Name: "lr", Type: TemplateParameterNumber, Default: json.RawMessage(`0.0003`)},
+			{Name: "seed", Type: TemplateParameterInteger},
+			{Name: "debug", Type: TemplateParameterBoolean, Default: json.RawMessage(`false`)},
+		},
+	}
+	if err := template.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	manifest, err := template.Materialize(map[string]json.RawMessage{"env": json.RawMessage(`"pong"`), "seed": json.RawMessage(`7`), "lr": json.RawMessage(`0.001`)})
+	if err != nil {
+		t.Fatalf("materialize: %v", err)
+	}
+	want := `{"debug":false,"game":{"env_id":"pong"},"tags":["seed-7"],"trainer":{"batch_size":256,"lr":0.001}}`
+	if string(manifest) != want {
+		t.Fatalf("expected %s, got %s", want, manifest)
+	}
+
+	failures := map[string]map[string]json.RawMessage{
+		"missing template parameters: env, seed": {},
+		"parameter seed: must be an integer":     {"env": json.RawMessage(`"pong"`), "seed": json.RawMessage(`7.5`)},
+		"parameter env: must be a string":        {"env": json.RawMessage(`3`), "seed": json.RawMessage(`1`)},
+		"unknown template parameters: gamma":     {"env": json.RawMessage(`"pong"`), "seed": json.RawMessage(`1`), "gamma": json.RawMessage(`0.9`)},
+	}
+	for want, params := range failures {
+		if _, err := template.Materialize(params); err == nil || err.Error() != want {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+
+	invalid := []RunTemplate{
+		{Manifest: json.RawMessage(`{"env":"${env}"}`)},
+		{Manifest: json.RawMessage(`[]`)},
+		{Manifest: json.RawMessage(`{}`), Parameters: []TemplateParameter{{Name: "x", Type: "list"}}},
+		{Manifest: json.RawMessage(`{}`), Parameters: []TemplateParameter{{Name: "x"}, {Name: "x"}}},
+		{Manifest: json.RawMessage(`{}`), Parameters: []TemplateParameter{{Name: "n", Type: TemplateParameterInteger, Default: json.RawMessage(`"many"`)}}},
+	}
+	for i, template := range invalid {
+		if err := template.Validate(); err == nil {
+			t.Fatalf("template %d: expected validation error", i)
+		}
+	}
+}
+
+func TestStoppingRuleObserve(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	plateau := StoppingRule{Kind: StoppingRuleLossPlateau, Action: CommandTypeTerminate, Heartbeats: 3, MinDelta: 0.01}
+	if err := plateau.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	var reason string
+	for i, loss := range []float64{1.0, 0.9, 0.895, 0.899, 0.85, 0.849, 0.845, 0.86} {
+		plateau, reason = plateau.Observe(loss, 0, start)
+		if (reason != "") != (i == 7) {
+			t.Fatalf("heartbeat %d (loss %g): unexpected reason %q (streak %d)", i, loss, reason, plateau.Streak)
+		}
+	}
+	if *plateau.BestLoss != 0.85 {
+		t.Fatalf("expected best loss 0.85, got %g", *plateau.BestLoss)
+	}
+
+	above := StoppingRule{Kind: StoppingRuleLossAbove, Action: CommandTypePause, Threshold: 10}
+	if above, reason = above.Observe(5, 0, start); reason != "" {
+		t.Fatalf("unexpected reason %q", reason)
+	}
+	if _, reason = above.Observe(math.NaN(), 0, start); reason == "" {
+		t.Fatalf("expected NaN loss to fire loss_above")
+	}
+
+	slow := StoppingRule{Kind: StoppingRuleThroughputBelow, Action: CommandTypePause, Threshold: 100, WindowSeconds: 60}
+	steps := []struct {
+		offset time.Duration
+		sps    float64
+		fires  bool
+	}{{0, 50, false}, {30 * time.Second, 150, false}, {40 * time.Second, 80, false}, {90 * time.Second, 90, false}, {100 * time.Second, 20, true}}
+	for _, step := range steps {
+		slow, reason = slow.Observe(0, step.sps, start.Add(step.offset))
+		if (reason != "") != step.fires {
+			t.Fatalf("at %s: unexpected reason %q", step.offset, reason)
+		}
+	}
+
+	for _, invalid := range []StoppingRule{
+		{Kind: StoppingRuleLossPlateau, Action: CommandTypePause},
+		{Kind: StoppingRuleThroughputBelow, Action: CommandTypePause, Threshold: 10},
+		{Kind: StoppingRuleLossAbove, Action: CommandTypeTune},
+		{Kind: "gradient_norm", Action: CommandTypePause},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Fatalf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestHeartbeatGapStats(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := Run{State: RunStateProvisioning}
+	at := start
+	run = run.MergeHeartbeat(HeartbeatPayload{Status: RuntimeStatusRunning}, at)
+	at = at.Add(time.Minute)
+	run = run.MergeHeartbeat(HeartbeatPayload{Status: RuntimeStatusRunning}, at)
+	if run.HeartbeatGaps != nil {
+		t.Fatalf("expected gaps before the run started to be ignored, got %+v", run.HeartbeatGaps)
+	}
+
+	run.State = RunStateRunning
+	for i := 1; i <= 40; i++ {
+		gap := 10 * time.Second
+		if i == 40 {
+			gap = 90 * time.Second
+		}
+		at = at.Add(gap)
+		run = run.MergeHeartbeat(HeartbeatPayload{Status: RuntimeStatusRunning}, at)
+	}
+	gaps := run.HeartbeatGaps
+	if gaps.Count != 40 || len(gaps.RecentSeconds) != HeartbeatGapWindow || gaps.LastSeconds != 90 {
+		t.Fatalf("unexpected gap window %+v", gaps)
+	}
+	if gaps.P50Seconds != 10 || gaps.P95Seconds != 10 || gaps.MaxSeconds != 90 {
+		t.Fatalf("expected a single outlier to move only the max, got %+v", gaps)
+	}
+	for i := 0; i < 2; i++ {
+		at = at.Add(60 * time.Second)
+		run = run.MergeHeartbeat(HeartbeatPayload{Status: RuntimeStatusRunning}, at)
+	}
+	if run.HeartbeatGaps.P95Seconds != 60 {
+		t.Fatalf("expected repeated slow heartbeats to raise p95, got %+v", run.HeartbeatGaps)
+	}
+}
+
 func floatPtr(v float64) *float64 { return &v }
@@ -0,0 +1,69 @@
+package watchdog
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/service"
+)
+
+// Watchdog periodically stops runs that have exceeded their max duration.
+type Watchdog struct {
+	orch     *service.Orchestrator
+	interval time.Duration
+	logger   zerolog.Logger
+	onTick   func()
+}
+
+// New creates a watchdog that checks running runs every interval.
+func New(orch *service.Orchestrator, interval time.Duration, logger zerolog.Logger) *Watchdog {
+	return &Watchdog{orch: orch, interval: interval, logger: logger}
+}
+
+// OnTick registers fn to be called when the loop starts and after every pass,
+// letting liveness probes confirm it is making progress.
+func (w *Watchdog) OnTick(fn func()) {
+	w.onTick = fn
+}
+
+// Start runs the watchdog loop until ctx is cancelled.
+func (w *Watchdog) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Info().Dur("interval", w.interval).Msg("Starting run watchdog")
+	w.beat()
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("Run watchdog stopped")
+			return
+		case <-ticker.C:
+			w.tick(ctx)
+			w.beat()
+		}
+	}
+}
+
+func (w *Watchdog) beat() {
+	if w.onTick != nil {
+		w.onTick()
+	}
+}
+
+func (w *Watchdog) tick(ctx context.Context) {
+	runs, err := w.orch.EnforceMaxDurations(ctx)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("Failed to enforce run max durations")
+		return
+	}
+	for _, run := range runs {
+		w.logger.Warn().
+			Str("run_id", run.ID).
+			Str("state", string(run.State)).
+			Int64("max_duration_seconds", run.MaxDurationSeconds).
+			Msg("Stopped run exceeding its max duration")
+	}
+}
@@ -0,0 +1,104 @@
+package watchdog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/orchestrator/internal/events"
+	"github.com/cartridge/orchestrator/internal/service"
+	"github.com/cartridge/orchestrator/internal/storage"
+	"github.com/cartridge/orchestrator/internal/types"
+)
+
+type statusRecorder struct {
+	events.NoopPublisher
+	statuses []events.RunStatusEvent
+}
+
+func (r *statusRecorder) PublishRunStatus(_ context.Context, event events.RunStatusEvent) error {
+	r.statuses = append(r.statuses, event)
+	return nil
+}
+
+func TestWatchdogStopsRunsPastMaxDuration(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	logger := zerolog.New(io.Discard)
+	publisher := &statusRecorder{}
+	orch := service.NewOrchestrator(store, publisher, logger)
+	now := time.Date(2024, time.March, 1, 20, 0, 0, 0, time.UTC)
+	orch.WithNow(func() time.Time { return now })
+	orch.WithDefaultMaxDuration(24 * time.Hour)
+
+	for _, input := range []service.CreateRunInput{
+		{ID: "run-terminate", MaxDuration: "8h"},
+		{ID: "run-pause", MaxDuration: "8h", MaxDurationAction: types.CommandTypePause},
+		{ID: "run-default"},
+	} {
+		input.ExperimentID, input.VersionID = "exp-1", "ver-1"
+		if _, err := orch.CreateRun(ctx, input); err != nil {
+			t.Fatalf("create %s: %v", input.ID, err)
+		}
+		for _, action := range []types.RunAction{types.RunActionProvision, types.RunActionStart} {
+			if _, err := orch.PerformAction(ctx, input.ID, action, "tester", ""); err != nil {
+				t.Fatalf("%s %s: %v", action, input.ID, err)
+			}
+		}
+	}
+	if _, err := orch.CreateRun(ctx, service.CreateRunInput{ID: "run-bad", ExperimentID: "exp-1", VersionID: "ver-1", MaxDuration: "10s"}); err == nil {
+		t.Fatal("expected a max duration under a minute to be rejected")
+	}
+	watchdog := New(orch, time.Minute, *logger)
+
+	now = now.Add(8 * time.Hour)
+	watchdog.tick(ctx)
+	if run, _ := orch.GetRun(ctx, "run-terminate"); run.State != types.RunStateRunning {
+		t.Fatalf("expected no action at exactly the max duration, got %s", run.State)
+	}
+
+	now = now.Add(time.Minute)
+	publisher.statuses = nil
+	watchdog.tick(ctx)
+	terminated, _ := orch.GetRun(ctx, "run-terminate")
+	if terminated.State != types.RunStateTerminating || terminated.MaxDurationExceededAt == nil {
+		t.Fatalf("expected the run to drain, got %+v", terminated)
+	}
+	paused, _ := orch.GetRun(ctx, "run-pause")
+	if paused.State != types.RunStatePaused {
+		t.Fatalf("expected the run to pause, got %s", paused.State)
+	}
+	if run, _ := orch.GetRun(ctx, "run-default"); run.State != types.RunStateRunning || run.MaxDurationSeconds != 24*3600 {
+		t.Fatalf("expected the default cap to leave the run running, got %+v", run)
+	}
+	if len(publisher.statuses) != 2 || publisher.statuses[0].Reason != "max duration exceeded" {
+		t.Fatalf("expected a status event per stopped run, got %+v", publisher.statuses)
+	}
+
+	cmd, err := orch.NextCommand(ctx, "run-terminate")
+	if err != nil || cmd.Type != types.CommandTypeTerminate || cmd.Actor.ID != "watchdog" {
+		t.Fatalf("expected a terminate command from the watchdog, got %+v %v", cmd, err)
+	}
+	var payload types.TerminatePayload
+	json.Unmarshal(cmd.Payload, &payload)
+	if payload.Reason != "max duration exceeded" {
+		t.Fatalf("unexpected terminate payload %+v", payload)
+	}
+	if cmd, err := orch.NextCommand(ctx, "run-pause"); err != nil || cmd.Type != types.CommandTypePause {
+		t.Fatalf("expected a pause command, got %+v %v", cmd, err)
+	}
+
+	// A run resumed after the watchdog paused it is left alone.
+	if _, err := orch.PerformAction(ctx, "run-pause", types.RunActionResume, "tester", ""); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	now = now.Add(time.Hour)
+	watchdog.tick(ctx)
+	if run, _ := orch.GetRun(ctx, "run-pause"); run.State != types.RunStateRunning {
+		t.Fatalf("expected the watchdog to act only once, got %s", run.State)
+	}
+}
@@ -0,0 +1,154 @@
+// Command replay-parquet exports the replay buffer to a Parquet file for
+// offline analysis, e.g. in pandas, and imports Parquet files into it, e.g.
+// to seed a buffer with a pre-collected dataset.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+)
+
+// maxReplayMessage bounds the Export responses the tool accepts.
+const maxReplayMessage = 64 << 20
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// options are the global flags shared by every subcommand.
+type options struct {
+	replayAddr string
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:           "replay-parquet",
+		Short:         "Export the replay buffer to Parquet and import Parquet files into it",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&opts.replayAddr, "replay-addr", os.Getenv("REPLAY_ADDR"), "replay service gRPC address (env REPLAY_ADDR)")
+
+	root.AddCommand(
+		newExportCommand(opts),
+		newImportCommand(opts),
+	)
+	return root
+}
+
+func newExportCommand(opts *options) *cobra.Command {
+	var output, envID string
+	var archived bool
+	cmd := &cobra.Command{
+		Use:   "export --output FILE",
+		Short: "Write the replay buffer's transitions to a Parquet file, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, closeReplay, err := opts.dialReplay()
+			if err != nil {
+				return err
+			}
+			defer closeReplay()
+
+			// Write next to output and rename into place, so a failed export
+			// never leaves a partial file under the requested name.
+			tmp, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp.Name())
+			written, err := exportParquet(cmd.Context(), client, &replayv1.ExportRequest{EnvId: envID, Archived: archived}, tmp)
+			if err != nil {
+				tmp.Close()
+				return err
+			}
+			if err := tmp.Close(); err != nil {
+				return err
+			}
+			if err := os.Rename(tmp.Name(), output); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "wrote %d transitions to %s\n", written, output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Parquet file to write (required)")
+	cmd.Flags().StringVar(&envID, "env", "", "export only this environment's transitions")
+	cmd.Flags().BoolVar(&archived, "archived", false, "export the archive tier of evicted transitions instead")
+	cmd.MarkFlagRequired("output")
+	return cmd
+}
+
+func newImportCommand(opts *options) *cobra.Command {
+	var batchSize int
+	cmd := &cobra.Command{
+		Use:   "import FILE...",
+		Short: "Store the transitions of Parquet files in the replay buffer",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if batchSize < 1 {
+				return errors.New("--batch-size must be at least 1")
+			}
+			client, closeReplay, err := opts.dialReplay()
+			if err != nil {
+				return err
+			}
+			defer closeReplay()
+			for _, path := range args {
+				stored, err := importFile(cmd.Context(), client, path, batchSize)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "stored %d transitions from %s\n", stored, path)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&batchSize, "batch-size", 256, "transitions per StoreBatch call")
+	return cmd
+}
+
+// importFile stores the transitions of the Parquet file at path.
+func importFile(ctx context.Context, client replayv1.ReplayClient, path string, batchSize int) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return importParquet(ctx, client, file, info.Size(), batchSize)
+}
+
+func (o *options) dialReplay() (replayv1.ReplayClient, func() error, error) {
+	if o.replayAddr == "" {
+		return nil, nil, errors.New("no replay service address; set --replay-addr")
+	}
+	conn, err := grpc.NewClient(o.replayAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxReplayMessage)),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to dial replay service: %w", err)
+	}
+	return replayv1.NewReplayClient(conn), conn.Close, nil
+}
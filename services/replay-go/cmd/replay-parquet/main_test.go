@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/replay/replaytest"
+)
+
+func TestExportAndImportParquet(t *testing.T) {
+	ctx := context.Background()
+	source := replaytest.NewServer(100)
+	defer source.Close()
+	transitions := []*replayv1.Transition{
+		{Id: "t1", EnvId: "tictactoe", EpisodeId: "ep1", State: []byte{0}, Action: []byte{4}, NextState: []byte{1}, Priority: 2, Timestamp: 1700000000, Metadata: map[string]string{"run_id": "run-a"}},
+		{Id: "t2", EnvId: "tictactoe", EpisodeId: "ep1", StepNumber: 1, State: []byte{1}, Action: []byte{0}, Reward: 1, Done: true, Priority: 1, Timestamp: 1700000001},
+		{Id: "t3", EnvId: "gridworld", EpisodeId: "ep2", State: []byte{2}, Action: []byte{1}, Observation: []byte{7}, Priority: 1, Timestamp: 1700000002},
+	}
+	if _, err := client(t, source.Addr).StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: transitions}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		root := newRootCommand()
+		var out bytes.Buffer
+		root.SetOut(&out)
+		root.SetArgs(args)
+		err := root.ExecuteContext(ctx)
+		return out.String(), err
+	}
+
+	dir := t.TempDir()
+	all := filepath.Join(dir, "all.parquet")
+	if out, err := run("export", "--replay-addr", source.Addr, "-o", all); err != nil || !strings.Contains(out, "wrote 3 transitions") {
+		t.Fatalf("export: %v\n%s", err, out)
+	}
+	tictactoe := filepath.Join(dir, "tictactoe.parquet")
+	if out, err := run("export", "--replay-addr", source.Addr, "--env", "tictactoe", "-o", tictactoe); err != nil || !strings.Contains(out, "wrote 2 transitions") {
+		t.Fatalf("export --env: %v\n%s", err, out)
+	}
+
+	target := replaytest.NewServer(100)
+	defer target.Close()
+	if out, err := run("import", "--replay-addr", target.Addr, "--batch-size", "2", all); err != nil || !strings.Contains(out, "stored 3 transitions") {
+		t.Fatalf("import: %v\n%s", err, out)
+	}
+	got := export(t, target.Addr)
+	if !reflect.DeepEqual(ids(got), []string{"t1", "t2", "t3"}) {
+		t.Fatalf("imported buffer differs: %v", got)
+	}
+	for i, want := range transitions {
+		if got[i].Timestamp != want.Timestamp || got[i].Priority != want.Priority || got[i].Done != want.Done ||
+			!bytes.Equal(got[i].State, want.State) || !bytes.Equal(got[i].NextState, want.NextState) ||
+			!bytes.Equal(got[i].Observation, want.Observation) || got[i].StepNumber != want.StepNumber {
+			t.Fatalf("transition %s differs: got %v, want %v", want.Id, got[i], want)
+		}
+	}
+	if got[0].Metadata["run_id"] != "run-a" {
+		t.Fatalf("metadata lost: %v", got[0].Metadata)
+	}
+}
+
+func TestImportRejectsInvalidFile(t *testing.T) {
+	target := replaytest.NewServer(100)
+	defer target.Close()
+	path := filepath.Join(t.TempDir(), "bogus.parquet")
+	if err := os.WriteFile(path, []byte("not parquet"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := newRootCommand()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"import", "--replay-addr", target.Addr, path})
+	if err := root.ExecuteContext(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid Parquet file") {
+		t.Fatalf("expected an invalid file error, got %v", err)
+	}
+}
+
+func client(t *testing.T, addr string) replayv1.ReplayClient {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return replayv1.NewReplayClient(conn)
+}
+
+func export(t *testing.T, addr string) []*replayv1.Transition {
+	t.Helper()
+	stream, err := client(t, addr).Export(context.Background(), &replayv1.ExportRequest{})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	var transitions []*replayv1.Transition
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return transitions
+		}
+		transitions = append(transitions, resp.Transitions...)
+	}
+}
+
+func ids(transitions []*replayv1.Transition) []string {
+	var out []string
+	for _, transition := range transitions {
+		out = append(out, transition.Id)
+	}
+	return out
+}
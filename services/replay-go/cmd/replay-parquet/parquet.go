@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+)
+
+// rowGroupSize is how many transitions each row group of an exported file
+// holds, which bounds the memory a reader needs.
+const rowGroupSize = 10000
+
+// transitionRow is a transition as a Parquet row. Columns are named after
+// the Transition fields of the replay proto; blobs stay in their engine
+// encodings.
+type transitionRow struct {
+	ID              string            `parquet:"id"`
+	EnvID           string            `parquet:"env_id"`
+	EpisodeID       string            `parquet:"episode_id"`
+	StepNumber      uint32            `parquet:"step_number"`
+	State           []byte            `parquet:"state"`
+	Action          []byte            `parquet:"action"`
+	NextState       []byte            `parquet:"next_state"`
+	Observation     []byte            `parquet:"observation"`
+	NextObservation []byte            `parquet:"next_observation"`
+	Reward          float32           `parquet:"reward"`
+	Done            bool              `parquet:"done"`
+	Priority        float32           `parquet:"priority"`
+	Timestamp       time.Time         `parquet:"timestamp,timestamp(millisecond)"`
+	Metadata        map[string]string `parquet:"metadata"`
+}
+
+func toRow(transition *replayv1.Transition) transitionRow {
+	return transitionRow{
+		ID:              transition.Id,
+		EnvID:           transition.EnvId,
+		EpisodeID:       transition.EpisodeId,
+		StepNumber:      transition.StepNumber,
+		State:           transition.State,
+		Action:          transition.Action,
+		NextState:       transition.NextState,
+		Observation:     transition.Observation,
+		NextObservation: transition.NextObservation,
+		Reward:          transition.Reward,
+		Done:            transition.Done,
+		Priority:        transition.Priority,
+		Timestamp:       time.Unix(int64(transition.Timestamp), 0).UTC(),
+		Metadata:        transition.Metadata,
+	}
+}
+
+func fromRow(row *transitionRow) *replayv1.Transition {
+	transition := &replayv1.Transition{
+		Id:              row.ID,
+		EnvId:           row.EnvID,
+		EpisodeId:       row.EpisodeID,
+		StepNumber:      row.StepNumber,
+		State:           row.State,
+		Action:          row.Action,
+		NextState:       row.NextState,
+		Observation:     row.Observation,
+		NextObservation: row.NextObservation,
+		Reward:          row.Reward,
+		Done:            row.Done,
+		Priority:        row.Priority,
+		Metadata:        row.Metadata,
+	}
+	// A dataset without timestamps is stamped by the replay service.
+	if !row.Timestamp.IsZero() && row.Timestamp.Unix() > 0 {
+		transition.Timestamp = uint64(row.Timestamp.Unix())
+	}
+	return transition
+}
+
+// exportParquet writes the transitions req selects to w as zstd-compressed
+// Parquet, returning how many it wrote.
+func exportParquet(ctx context.Context, client replayv1.ReplayClient, req *replayv1.ExportRequest, w io.Writer) (int64, error) {
+	stream, err := client.Export(ctx, req)
+	if err != nil {
+		return 0, fmt.Errorf("replay Export: %w", err)
+	}
+	writer := parquet.NewGenericWriter[transitionRow](w, parquet.Compression(&parquet.Zstd))
+	var written int64
+	rows := make([]transitionRow, 0, rowGroupSize)
+	flush := func() error {
+		if _, err := writer.Write(rows); err != nil {
+			return err
+		}
+		rows = rows[:0]
+		return writer.Flush()
+	}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return written, fmt.Errorf("replay Export: %w", err)
+		}
+		for _, transition := range resp.Transitions {
+			rows = append(rows, toRow(transition))
+			written++
+			if len(rows) == rowGroupSize {
+				if err := flush(); err != nil {
+					return written, err
+				}
+			}
+		}
+	}
+	if len(rows) > 0 {
+		if err := flush(); err != nil {
+			return written, err
+		}
+	}
+	return written, writer.Close()
+}
+
+// importParquet stores the transitions of the Parquet file r, of size bytes,
+// batchSize at a time, returning how many were stored. Columns missing from
+// the file are left empty.
+func importParquet(ctx context.Context, client replayv1.ReplayClient, r io.ReaderAt, size int64, batchSize int) (int64, error) {
+	file, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Parquet file: %w", err)
+	}
+	reader := parquet.NewGenericReader[transitionRow](file)
+	defer reader.Close()
+
+	var stored int64
+	rows := make([]transitionRow, batchSize)
+	for {
+		n, err := reader.Read(rows)
+		if n > 0 {
+			batch := make([]*replayv1.Transition, n)
+			for i := range rows[:n] {
+				batch[i] = fromRow(&rows[i])
+			}
+			resp, err := client.StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: batch})
+			if err != nil {
+				return stored, fmt.Errorf("replay StoreBatch: %w", err)
+			}
+			stored += int64(resp.GetStoredCount())
+			if resp.GetFailedCount() > 0 {
+				return stored, fmt.Errorf("replay StoreBatch: %d transitions failed: %v", resp.GetFailedCount(), resp.GetErrorMessages())
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return stored, nil
+		}
+		if err != nil {
+			return stored, fmt.Errorf("invalid Parquet file: %w", err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Command replay-tfrecord exports the replay buffer as TFRecords of
+// tf.train.Example, so offline RL pipelines in TensorFlow or JAX can read
+// collected experience with tf.data.TFRecordDataset.
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+)
+
+// maxReplayMessage bounds the Export responses the tool accepts.
+const maxReplayMessage = 64 << 20
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// options are the global flags shared by every subcommand.
+type options struct {
+	replayAddr string
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:           "replay-tfrecord",
+		Short:         "Export the replay buffer as TFRecords for offline RL training",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&opts.replayAddr, "replay-addr", os.Getenv("REPLAY_ADDR"), "replay service gRPC address (env REPLAY_ADDR)")
+
+	root.AddCommand(newExportCommand(opts))
+	return root
+}
+
+func newExportCommand(opts *options) *cobra.Command {
+	var output, envID, since, until string
+	var archived, compress bool
+	cmd := &cobra.Command{
+		Use:   "export --output FILE",
+		Short: "Write the replay buffer's transitions to a TFRecord file, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			req := &replayv1.ExportRequest{EnvId: envID, Archived: archived}
+			var err error
+			if req.MinTimestamp, err = parseTime("--since", since); err != nil {
+				return err
+			}
+			if req.MaxTimestamp, err = parseTime("--until", until); err != nil {
+				return err
+			}
+			if req.MaxTimestamp > 0 && req.MaxTimestamp < req.MinTimestamp {
+				return errors.New("--until must not be before --since")
+			}
+			client, closeReplay, err := opts.dialReplay()
+			if err != nil {
+				return err
+			}
+			defer closeReplay()
+
+			// Write next to output and rename into place, so a failed export
+			// never leaves a partial file under the requested name.
+			tmp, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp.Name())
+			written, err := writeExport(cmd.Context(), client, req, tmp, compress)
+			if err != nil {
+				tmp.Close()
+				return err
+			}
+			if err := tmp.Close(); err != nil {
+				return err
+			}
+			if err := os.Rename(tmp.Name(), output); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "wrote %d transitions to %s\n", written, output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "TFRecord file to write (required)")
+	cmd.Flags().StringVar(&envID, "env", "", "export only this environment's transitions")
+	cmd.Flags().StringVar(&since, "since", "", "export only transitions stored at or after this time (RFC 3339)")
+	cmd.Flags().StringVar(&until, "until", "", "export only transitions stored at or before this time (RFC 3339)")
+	cmd.Flags().BoolVar(&archived, "archived", false, "export the archive tier of evicted transitions instead")
+	cmd.Flags().BoolVar(&compress, "gzip", false, "gzip the file, for TFRecordDataset's compression_type=\"GZIP\"")
+	cmd.MarkFlagRequired("output")
+	return cmd
+}
+
+// writeExport exports to w, gzipped when compress is set.
+func writeExport(ctx context.Context, client replayv1.ReplayClient, req *replayv1.ExportRequest, w io.Writer, compress bool) (int64, error) {
+	if !compress {
+		return exportTFRecord(ctx, client, req, w)
+	}
+	zw := gzip.NewWriter(w)
+	written, err := exportTFRecord(ctx, client, req, zw)
+	if err != nil {
+		return written, err
+	}
+	return written, zw.Close()
+}
+
+// parseTime parses the RFC 3339 value of flag as a Unix timestamp, or 0 when
+// it is empty.
+func parseTime(flag, value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", flag, err)
+	}
+	if t.Unix() <= 0 {
+		return 0, fmt.Errorf("%s: must be after the Unix epoch", flag)
+	}
+	return uint64(t.Unix()), nil
+}
+
+func (o *options) dialReplay() (replayv1.ReplayClient, func() error, error) {
+	if o.replayAddr == "" {
+		return nil, nil, errors.New("no replay service address; set --replay-addr")
+	}
+	conn, err := grpc.NewClient(o.replayAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxReplayMessage)),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to dial replay service: %w", err)
+	}
+	return replayv1.NewReplayClient(conn), conn.Close, nil
+}
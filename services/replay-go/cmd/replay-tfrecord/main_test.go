@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/replay/replaytest"
+)
+
+func TestExportTFRecord(t *testing.T) {
+	ctx := context.Background()
+	server := replaytest.NewServer(100)
+	defer server.Close()
+	transitions := []*replayv1.Transition{
+		{Id: "t1", EnvId: "tictactoe", EpisodeId: "ep1", State: []byte{0}, Action: []byte{4}, Priority: 2, Timestamp: 1700000000},
+		{Id: "t2", EnvId: "tictactoe", EpisodeId: "ep1", StepNumber: 1, State: []byte{1}, Action: []byte{0}, Reward: 1.5, Done: true, Priority: 1, Timestamp: 1700000100, Metadata: map[string]string{"run_id": "run-a"}},
+		{Id: "t3", EnvId: "gridworld", EpisodeId: "ep2", State: []byte{2}, Action: []byte{1}, Priority: 1, Timestamp: 1700000200},
+	}
+	if _, err := client(t, server.Addr).StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: transitions}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		root := newRootCommand()
+		var out bytes.Buffer
+		root.SetOut(&out)
+		root.SetArgs(append([]string{"export", "--replay-addr", server.Addr}, args...))
+		err := root.ExecuteContext(ctx)
+		return out.String(), err
+	}
+
+	dir := t.TempDir()
+	all := filepath.Join(dir, "all.tfrecord")
+	if out, err := run("-o", all); err != nil || !strings.Contains(out, "wrote 3 transitions") {
+		t.Fatalf("export: %v\n%s", err, out)
+	}
+	examples := readExamples(t, all, false)
+	if len(examples) != 3 {
+		t.Fatalf("expected 3 examples, got %d", len(examples))
+	}
+	second := examples[1]
+	if got := string(second["id"].bytes); got != "t2" {
+		t.Fatalf("unexpected id %q", got)
+	}
+	if second["reward"].float != 1.5 || second["done"].int64 != 1 || second["step_number"].int64 != 1 || second["timestamp"].int64 != 1700000100 {
+		t.Fatalf("unexpected features: %+v", second)
+	}
+	if !bytes.Equal(second["state"].bytes, []byte{1}) || string(second["metadata/run_id"].bytes) != "run-a" {
+		t.Fatalf("unexpected features: %+v", second)
+	}
+	if _, ok := examples[0]["next_state"]; !ok {
+		t.Fatal("expected every example to carry next_state")
+	}
+
+	ranged := filepath.Join(dir, "ranged.tfrecord.gz")
+	if out, err := run("-o", ranged, "--env", "tictactoe", "--since", "2023-11-14T22:14:00Z", "--gzip"); err != nil || !strings.Contains(out, "wrote 1 transitions") {
+		t.Fatalf("export --env --since: %v\n%s", err, out)
+	}
+	if got := readExamples(t, ranged, true); len(got) != 1 || string(got[0]["id"].bytes) != "t2" {
+		t.Fatalf("unexpected ranged export: %v", got)
+	}
+
+	if _, err := run("-o", all, "--since", "2023-11-15T00:00:00Z", "--until", "2023-11-14T00:00:00Z"); err == nil {
+		t.Fatal("expected --until before --since to fail")
+	}
+}
+
+// featureValue is the first value of a decoded tf.train.Feature.
+type featureValue struct {
+	bytes []byte
+	float float32
+	int64 int64
+}
+
+// readExamples reads the TFRecord file at path, checking each record's CRCs,
+// and decodes its tf.train.Examples.
+func readExamples(t *testing.T, path string, gzipped bool) []map[string]featureValue {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gzipped {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var examples []map[string]featureValue
+	for len(data) > 0 {
+		if len(data) < 16 {
+			t.Fatalf("truncated record header")
+		}
+		length := binary.LittleEndian.Uint64(data[:8])
+		if binary.LittleEndian.Uint32(data[8:12]) != maskedCRC(data[:8]) {
+			t.Fatal("bad length CRC")
+		}
+		record := data[12 : 12+length]
+		if binary.LittleEndian.Uint32(data[12+length:16+length]) != maskedCRC(record) {
+			t.Fatal("bad data CRC")
+		}
+		data = data[16+length:]
+		examples = append(examples, decodeExample(t, record))
+	}
+	return examples
+}
+
+func decodeExample(t *testing.T, record []byte) map[string]featureValue {
+	t.Helper()
+	features := fields(t, fields(t, record)[0])
+	example := make(map[string]featureValue)
+	for _, entry := range features {
+		kv := fields(t, entry)
+		kind, list := consume(t, kv[1])
+		value := fields(t, list)[0]
+		var decoded featureValue
+		switch kind {
+		case 1:
+			decoded.bytes = value
+		case 2:
+			decoded.float = math.Float32frombits(binary.LittleEndian.Uint32(value))
+		case 3:
+			v, _ := protowire.ConsumeVarint(value)
+			decoded.int64 = int64(v)
+		}
+		example[string(kv[0])] = decoded
+	}
+	return example
+}
+
+// fields returns the length-delimited fields of message in order.
+func fields(t *testing.T, message []byte) [][]byte {
+	t.Helper()
+	var out [][]byte
+	for len(message) > 0 {
+		_, value := consume(t, message)
+		out = append(out, value)
+		_, _, n := protowire.ConsumeField(message)
+		message = message[n:]
+	}
+	return out
+}
+
+func consume(t *testing.T, message []byte) (protowire.Number, []byte) {
+	t.Helper()
+	number, typ, n := protowire.ConsumeTag(message)
+	if n < 0 || typ != protowire.BytesType {
+		t.Fatalf("unexpected field type %v", typ)
+	}
+	value, m := protowire.ConsumeBytes(message[n:])
+	if m < 0 {
+		t.Fatal("truncated field")
+	}
+	return number, value
+}
+
+func client(t *testing.T, addr string) replayv1.ReplayClient {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return replayv1.NewReplayClient(conn)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+)
+
+// metadataPrefix prefixes the feature names of transition metadata.
+const metadataPrefix = "metadata/"
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// recordWriter writes TFRecord framing: each record is its little-endian
+// uint64 length, the masked CRC-32C of that length, the data, and the
+// masked CRC-32C of the data.
+type recordWriter struct {
+	w io.Writer
+}
+
+func (r *recordWriter) write(data []byte) error {
+	var header [12]byte
+	binary.LittleEndian.PutUint64(header[:8], uint64(len(data)))
+	binary.LittleEndian.PutUint32(header[8:], maskedCRC(header[:8]))
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], maskedCRC(data))
+	for _, part := range [][]byte{header[:], data, footer[:]} {
+		if _, err := r.w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func maskedCRC(data []byte) uint32 {
+	crc := crc32.Checksum(data, castagnoli)
+	return (crc>>15 | crc<<17) + 0xa282ead8
+}
+
+// exportTFRecord writes the transitions req selects to w as TFRecords of
+// tf.train.Example, returning how many it wrote.
+func exportTFRecord(ctx context.Context, client replayv1.ReplayClient, req *replayv1.ExportRequest, w io.Writer) (int64, error) {
+	stream, err := client.Export(ctx, req)
+	if err != nil {
+		return 0, fmt.Errorf("replay Export: %w", err)
+	}
+	records := &recordWriter{w: w}
+	var written int64
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return written, nil
+		}
+		if err != nil {
+			return written, fmt.Errorf("replay Export: %w", err)
+		}
+		for _, transition := range resp.Transitions {
+			if err := records.write(encodeExample(transition)); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+}
+
+// encodeExample encodes transition as a tf.train.Example. Every example has
+// the same features, so they parse with tf.io.FixedLenFeature, except for
+// one metadata/<key> feature per metadata entry.
+func encodeExample(transition *replayv1.Transition) []byte {
+	features := map[string][]byte{
+		"id":               bytesFeature([]byte(transition.Id)),
+		"env_id":           bytesFeature([]byte(transition.EnvId)),
+		"episode_id":       bytesFeature([]byte(transition.EpisodeId)),
+		"step_number":      int64Feature(int64(transition.StepNumber)),
+		"state":            bytesFeature(transition.State),
+		"action":           bytesFeature(transition.Action),
+		"next_state":       bytesFeature(transition.NextState),
+		"observation":      bytesFeature(transition.Observation),
+		"next_observation": bytesFeature(transition.NextObservation),
+		"reward":           floatFeature(transition.Reward),
+		"done":             int64Feature(boolToInt(transition.Done)),
+		"priority":         floatFeature(transition.Priority),
+		"timestamp":        int64Feature(int64(transition.Timestamp)),
+	}
+	for key, value := range transition.Metadata {
+		features[metadataPrefix+key] = bytesFeature([]byte(value))
+	}
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Features is a map<string, Feature> in field 1.
+	var encoded []byte
+	for _, name := range names {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, name)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, features[name])
+		encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+		encoded = protowire.AppendBytes(encoded, entry)
+	}
+	// Example holds its Features in field 1.
+	example := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(example, encoded)
+}
+
+// bytesFeature encodes a Feature holding a BytesList of value.
+func bytesFeature(value []byte) []byte {
+	list := protowire.AppendTag(nil, 1, protowire.BytesType)
+	list = protowire.AppendBytes(list, value)
+	return feature(1, list)
+}
+
+// floatFeature encodes a Feature holding a FloatList of value.
+func floatFeature(value float32) []byte {
+	list := protowire.AppendTag(nil, 1, protowire.BytesType)
+	list = protowire.AppendBytes(list, protowire.AppendFixed32(nil, math.Float32bits(value)))
+	return feature(2, list)
+}
+
+// int64Feature encodes a Feature holding an Int64List of value.
+func int64Feature(value int64) []byte {
+	list := protowire.AppendTag(nil, 1, protowire.BytesType)
+	list = protowire.AppendBytes(list, protowire.AppendVarint(nil, uint64(value)))
+	return feature(3, list)
+}
+
+// feature wraps list in the Feature oneof field kind.
+func feature(kind protowire.Number, list []byte) []byte {
+	encoded := protowire.AppendTag(nil, kind, protowire.BytesType)
+	return protowire.AppendBytes(encoded, list)
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
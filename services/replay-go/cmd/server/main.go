@@ -2,79 +2,308 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/cartridge/chaos"
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/replay/internal/archive"
+	"github.com/cartridge/replay/internal/auth"
+	"github.com/cartridge/replay/internal/config"
+	"github.com/cartridge/replay/internal/expiry"
+	"github.com/cartridge/replay/internal/flags"
+	"github.com/cartridge/replay/internal/migrations"
 	"github.com/cartridge/replay/internal/service"
 	"github.com/cartridge/replay/internal/storage"
-	replayv1 "github.com/cartridge/replay/pkg/proto/replay/v1"
+	"github.com/cartridge/telemetry"
+	"github.com/cartridge/telemetry/buildinfo"
+	"github.com/cartridge/telemetry/capture"
+	"github.com/cartridge/telemetry/logging"
+	"github.com/cartridge/telemetry/metrics"
+	"github.com/cartridge/telemetry/middleware"
+	"github.com/cartridge/telemetry/tracing"
 )
 
 func main() {
-	var (
-		port    = flag.Int("port", 8080, "gRPC server port")
-		maxSize = flag.Uint64("max-size", 100000, "Maximum number of transitions to store")
-	)
-	flag.Parse()
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
 
-	log.Printf("Starting Replay service on port %d", *port)
+	telemetryCfg, err := telemetry.FromEnv("replay")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid telemetry configuration: %v\n", err)
+		os.Exit(1)
+	}
+	logger, err := logging.New(telemetryCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), telemetryCfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to initialize tracing")
+	}
+
+	faults, err := chaos.FromEnv()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid fault injection configuration")
+	}
+	injector := chaos.New(faults)
+
+	logger.Info().Int("port", cfg.Port).Msg("Starting Replay service")
 
 	// Create storage backend
-	backend := storage.NewMemoryBackend(*maxSize)
+	eviction := storage.EvictionPolicy(cfg.Eviction)
+	envEviction := make(map[string]storage.EvictionPolicy, len(cfg.EnvEviction))
+	for envID, policy := range cfg.EnvEviction {
+		envEviction[envID] = storage.EvictionPolicy(policy)
+	}
+	var backend storage.Backend
+	switch cfg.Backend {
+	case "disk":
+		backend, err = storage.OpenDiskBackend(storage.DiskOptions{
+			Dir:         cfg.DataDir,
+			MaxSize:     cfg.MaxSize,
+			Sync:        cfg.DiskSync,
+			Eviction:    eviction,
+			EnvEviction: envEviction,
+			Dedup:       cfg.Dedup,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Str("data_dir", cfg.DataDir).Msg("Failed to open disk backend")
+		}
+		logger.Info().Str("data_dir", cfg.DataDir).Msg("Replay buffer loaded from disk")
+	case "redis":
+		backend, err = storage.NewRedisBackend(storage.RedisOptions{
+			Addr:      cfg.RedisAddr,
+			Password:  cfg.RedisPassword,
+			DB:        cfg.RedisDB,
+			KeyPrefix: cfg.RedisKeyPrefix,
+			MaxSize:   cfg.MaxSize,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to connect to Redis backend")
+		}
+		logger.Info().Str("redis_addr", cfg.RedisAddr).Str("key_prefix", cfg.RedisKeyPrefix).Msg("Replay buffer shared in Redis")
+	case "postgres":
+		backend, err = openPostgres(cfg, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to open Postgres backend")
+		}
+	default:
+		memory := storage.NewMemoryBackend(cfg.MaxSize)
+		memory.SetEvictionPolicy(eviction, envEviction)
+		memory.SetDedup(cfg.Dedup)
+		if cfg.SnapshotFile != "" {
+			loaded, err := memory.LoadSnapshot(cfg.SnapshotFile)
+			if err != nil {
+				logger.Fatal().Err(err).Str("snapshot_file", cfg.SnapshotFile).Msg("Failed to restore replay buffer")
+			}
+			if loaded {
+				stats, _ := memory.GetStats(context.Background(), "")
+				logger.Info().Str("snapshot_file", cfg.SnapshotFile).Uint64("transitions", stats.TotalTransitions).Msg("Replay buffer restored from snapshot")
+			}
+		}
+		backend = memory
+	}
 	defer func() {
 		if err := backend.Close(); err != nil {
-			log.Printf("Error closing backend: %v", err)
+			logger.Error().Err(err).Msg("Error closing backend")
 		}
 	}()
 
+	// Snapshot the buffer once the server has stopped, before it is closed.
+	if memory, ok := backend.(*storage.MemoryBackend); ok && cfg.SnapshotFile != "" {
+		defer func() {
+			if err := memory.SaveSnapshot(cfg.SnapshotFile); err != nil {
+				logger.Error().Err(err).Str("snapshot_file", cfg.SnapshotFile).Msg("Failed to snapshot replay buffer")
+				return
+			}
+			logger.Info().Str("snapshot_file", cfg.SnapshotFile).Msg("Replay buffer snapshotted")
+		}()
+	}
+
+	// Record the buffer's stores, samples, evictions and size alongside the
+	// gRPC metrics
+	registry := metrics.NewRegistry()
+	backend = storage.WithMetrics(backend, registry)
+
+	// Archive evicted transitions to object storage. The archiver closes
+	// before the backend, writing the chunks it holds.
+	var archiveReader *archive.Reader
+	if cfg.ArchiveURL != "" {
+		store, prefix, err := archive.Open(cfg.ArchiveURL, archive.S3Config{
+			Endpoint:        cfg.ArchiveS3Endpoint,
+			Region:          cfg.ArchiveS3Region,
+			AccessKeyID:     cfg.ArchiveS3AccessKeyID,
+			SecretAccessKey: cfg.ArchiveS3SecretAccessKey,
+			PathStyle:       cfg.ArchiveS3PathStyle,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to open archive")
+		}
+		archiver := archive.NewArchiver(store, archive.Options{
+			Prefix:        prefix,
+			ChunkSize:     cfg.ArchiveChunkSize,
+			FlushInterval: cfg.ArchiveFlushInterval,
+			Logger:        logger,
+		})
+		backend.(storage.EvictionNotifier).OnEvict(archiver.Add)
+		archiveReader = archive.NewReader(store, prefix)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+			defer cancel()
+			if err := archiver.Close(ctx); err != nil {
+				logger.Error().Err(err).Msg("Error flushing archive")
+			}
+			if dropped := archiver.Dropped(); dropped > 0 {
+				logger.Warn().Uint64("dropped", dropped).Msg("Some evicted transitions were not archived")
+			}
+		}()
+		logger.Info().Str("archive_url", cfg.ArchiveURL).Msg("Archiving evicted transitions")
+	}
+
+	// Feature flags come from configuration, overridden by the orchestrator's
+	// flags for the run when one is configured
+	featureFlags := flags.New(cfg.FeatureFlags)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.RunID != "" {
+		client := &http.Client{Timeout: 10 * time.Second}
+		go featureFlags.Watch(watchCtx, client, cfg.OrchestratorAddr, cfg.RunID, cfg.FlagsRefresh, logger)
+	}
+	logger.Info().Interface("flags", featureFlags.All()).Msg("Feature flags loaded")
+
+	// Expire stale transitions in the background
+	if cfg.MaxAge > 0 {
+		go expiry.Sweep(watchCtx, backend, cfg.MaxAge, cfg.SweepInterval, logger)
+		logger.Info().Dur("max_age", cfg.MaxAge).Msg("Expiring old transitions")
+	}
+
 	// Create gRPC service
-	replayService := service.NewReplayService(backend)
+	replayService := service.NewReplayService(storage.WithFaults(storage.WithCompression(backend, cfg.CompressEnvs), injector)).
+		WithFlags(featureFlags).
+		WithArchive(archiveReader)
+	if cfg.PriorityBetaSamples > 0 {
+		replayService.WithBetaSchedule(&service.BetaSchedule{Start: cfg.PriorityBetaStart, Samples: cfg.PriorityBetaSamples})
+	}
 
-	// Create gRPC server
-	server := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor),
-	)
+	// Create gRPC server, instrumented with the shared metrics, tracing and logging
+	unary := []grpc.UnaryServerInterceptor{logging.UnaryServerInterceptor(logger)}
+	stream := []grpc.StreamServerInterceptor{logging.StreamServerInterceptor(logger)}
+	if tokens := auth.NewTokens(cfg.AuthTokens); tokens != nil {
+		// Calls without a valid token are logged and counted, but not captured.
+		unary = append(unary, tokens.UnaryServerInterceptor())
+		stream = append(stream, tokens.StreamServerInterceptor())
+		logger.Info().Int("tokens", len(cfg.AuthTokens)).Msg("Requiring bearer tokens")
+	}
+	if cfg.CaptureFile != "" {
+		recorder, err := capture.NewRecorder(cfg.CaptureFile, int64(cfg.CaptureMaxSize))
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to open capture file")
+		}
+		defer func() {
+			if dropped := recorder.Dropped(); dropped > 0 {
+				logger.Warn().Uint64("dropped", dropped).Msg("Capture file filled up, later calls were not recorded")
+			}
+			recorder.Close()
+		}()
+		logger.Info().Str("file", cfg.CaptureFile).Msg("Capturing StoreBatch and Sample traffic")
+		unary = append(unary, recorder.UnaryServerInterceptor(replayv1.Replay_StoreBatch_FullMethodName, replayv1.Replay_Sample_FullMethodName))
+	}
+	if injector != nil {
+		// Injected faults sit inside the instrumentation so they are logged and counted.
+		logger.Warn().Interface("faults", faults).Msg("Fault injection enabled")
+		unary = append(unary, injector.UnaryServerInterceptor())
+		stream = append(stream, injector.StreamServerInterceptor())
+	}
+	serverOpts := middleware.GRPCServerOptions(middleware.NewGRPCMetrics(registry), unary, stream)
+	serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(int(cfg.MaxMessageSize)))
+	if cfg.TLSCertFile != "" {
+		creds, err := auth.ServerTLS(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to set up TLS")
+		}
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+		logger.Info().Bool("client_certs", cfg.TLSClientCAFile != "").Msg("Serving gRPC over TLS")
+	}
+	server := grpc.NewServer(serverOpts...)
 
-	// Register service
+	// Register service, and the standard health service that probes and load
+	// balancers check. Both report serving until shutdown begins.
 	replayv1.RegisterReplayServer(server, replayService)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(replayv1.Replay_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
 
 	// Enable reflection for development
 	reflection.Register(server)
 
 	// Create listener
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Port))
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logger.Fatal().Err(err).Msg("Failed to listen")
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Replay service listening on %s", lis.Addr())
+		logger.Info().Str("addr", lis.Addr().String()).Msg("Replay service listening")
 		if err := server.Serve(lis); err != nil {
-			log.Fatalf("Failed to serve: %v", err)
+			logger.Fatal().Err(err).Msg("Failed to serve")
 		}
 	}()
 
+	// Serve metrics on their own port so scrapes never compete with gRPC traffic
+	var metricsServer *http.Server
+	if cfg.MetricsPort != 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(registry))
+		mux.Handle("/version", buildinfo.Handler("replay"))
+		metricsServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.MetricsPort),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			logger.Info().Str("addr", metricsServer.Addr).Msg("Metrics listening")
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Fatal().Err(err).Msg("Failed to serve metrics")
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
 
-	log.Println("Shutting down gracefully...")
+	logger.Info().Msg("Shutting down gracefully...")
 
 	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
+	// Report not serving so load balancers stop sending new calls, and end the
+	// streams that would otherwise run until their clients cancel
+	healthServer.Shutdown()
+	replayService.Stop()
 	stopped := make(chan struct{})
 	go func() {
 		server.GracefulStop()
@@ -83,33 +312,42 @@ func main() {
 
 	select {
 	case <-ctx.Done():
-		log.Println("Shutdown timeout exceeded, forcing stop")
+		logger.Warn().Msg("Shutdown timeout exceeded, forcing stop")
 		server.Stop()
 	case <-stopped:
-		log.Println("Server stopped gracefully")
+		logger.Info().Msg("Server stopped gracefully")
+	}
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			logger.Error().Err(err).Msg("Error stopping metrics server")
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error().Err(err).Msg("Error flushing traces")
 	}
 }
 
-// loggingInterceptor logs gRPC requests
-func loggingInterceptor(
-	ctx context.Context,
-	req interface{},
-	info *grpc.UnaryServerInfo,
-	handler grpc.UnaryHandler,
-) (interface{}, error) {
-	start := time.Now()
-
-	// Call the handler
-	resp, err := handler(ctx, req)
-
-	// Log the request
-	duration := time.Since(start)
-	status := "OK"
+// openPostgres connects to the postgres backend's database and, with
+// DBAutoMigrate, brings its schema up to date.
+func openPostgres(cfg *config.Config, logger zerolog.Logger) (*storage.PostgresBackend, error) {
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
-		status = "ERROR"
+		return nil, err
 	}
-
-	log.Printf("[%s] %s - %v (%s)", status, info.FullMethod, duration, req)
-
-	return resp, err
-}
\ No newline at end of file
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if cfg.DBAutoMigrate {
+		applied, err := migrations.Up(ctx, db)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		logger.Info().Int("applied", applied).Msg("Database migrations complete")
+	}
+	return storage.NewPostgresBackend(db, cfg.MaxSize), nil
+}
@@ -7,10 +7,16 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/proto/rpcerr"
+	"github.com/cartridge/proto/runctx"
+	"github.com/cartridge/replay/internal/flags"
 	"github.com/cartridge/replay/internal/service"
 	"github.com/cartridge/replay/internal/storage"
-	replayv1 "github.com/cartridge/replay/pkg/proto/replay/v1"
 )
 
 // TestReplayServiceIntegration tests the full service with real-like engine data
@@ -85,6 +91,91 @@ func TestReplayServiceIntegration(t *testing.T) {
 	})
 
 	// Test sampling
+	t.Run("GetEpisode", func(t *testing.T) {
+		resp, err := svc.GetEpisode(ctx, &replayv1.GetEpisodeRequest{EpisodeId: "episode-1"})
+		require.NoError(t, err)
+		require.Len(t, resp.Transitions, 2)
+		for i, transition := range resp.Transitions {
+			assert.Equal(t, uint32(i), transition.StepNumber)
+			assert.Equal(t, tictactoeTransitions[i].State, transition.State)
+		}
+
+		_, err = svc.GetEpisode(ctx, &replayv1.GetEpisodeRequest{EpisodeId: "missing"})
+		assert.Equal(t, codes.NotFound, rpcerr.Parse(err).Code)
+		assert.Equal(t, rpcerr.NotFound, rpcerr.Parse(err).Reason)
+		_, err = svc.GetEpisode(ctx, &replayv1.GetEpisodeRequest{})
+		assert.Equal(t, rpcerr.InvalidRequest, rpcerr.Parse(err).Reason)
+	})
+
+	t.Run("SampleSequences", func(t *testing.T) {
+		resp, err := svc.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 2, EnvId: "tictactoe", SequenceLength: 3},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Transitions, 6)
+		require.Len(t, resp.Mask, 6)
+		assert.Len(t, resp.Weights, 2)
+
+		// Both windows of the two-step episode run past its end.
+		for w := 0; w < 2; w++ {
+			window, mask := resp.Transitions[w*3:w*3+3], resp.Mask[w*3:w*3+3]
+			assert.True(t, mask[0])
+			assert.False(t, mask[2])
+			assert.Empty(t, window[2].Id)
+			if window[0].StepNumber == 0 {
+				assert.True(t, mask[1])
+				assert.Equal(t, uint32(1), window[1].StepNumber)
+			} else {
+				assert.False(t, mask[1])
+			}
+		}
+	})
+
+	t.Run("SampleNStep", func(t *testing.T) {
+		resp, err := svc.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 2, EnvId: "tictactoe", NStep: 3, Gamma: 0.9},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Transitions, 2)
+		require.Len(t, resp.Returns, 2)
+		require.Len(t, resp.ReturnSteps, 2)
+
+		// The episode's stored steps run out before n_step.
+		for i, transition := range resp.Transitions {
+			assert.Equal(t, uint32(2-transition.StepNumber), resp.ReturnSteps[i])
+			assert.Equal(t, tictactoeTransitions[1].NextState, transition.NextState)
+			assert.Zero(t, resp.Returns[i])
+		}
+
+		_, err = svc.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 2, NStep: 3},
+		})
+		assert.Equal(t, codes.InvalidArgument, rpcerr.Parse(err).Code)
+		_, err = svc.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 2, NStep: 3, Gamma: 0.9, SequenceLength: 2},
+		})
+		assert.Equal(t, codes.InvalidArgument, rpcerr.Parse(err).Code)
+	})
+
+	t.Run("SampleStratified", func(t *testing.T) {
+		// No episode has ended yet, so the rest of the batch fills in.
+		resp, err := svc.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 2, EnvId: "tictactoe", TerminalFraction: 0.5, WinningFraction: 0.5},
+		})
+		require.NoError(t, err)
+		assert.Len(t, resp.Transitions, 2)
+		assert.Len(t, resp.Weights, 2)
+
+		for _, config := range []*replayv1.SampleConfig{
+			{BatchSize: 2, TerminalFraction: 0.6, WinningFraction: 0.6},
+			{BatchSize: 2, TerminalFraction: -0.1},
+			{BatchSize: 2, TerminalFraction: 0.5, SequenceLength: 2},
+		} {
+			_, err = svc.Sample(ctx, &replayv1.SampleRequest{Config: config})
+			assert.Equal(t, codes.InvalidArgument, rpcerr.Parse(err).Code)
+		}
+	})
+
 	t.Run("Sample", func(t *testing.T) {
 		// Test uniform sampling
 		resp, err := svc.Sample(ctx, &replayv1.SampleRequest{
@@ -122,6 +213,24 @@ func TestReplayServiceIntegration(t *testing.T) {
 		require.NoError(t, err)
 		assert.Len(t, prioritizedResp.Transitions, 2)
 		assert.Len(t, prioritizedResp.Weights, 2)
+
+		// Test prioritized sampling v2 behind its feature flag
+		flagged := service.NewReplayService(backend).WithFlags(flags.New(map[string]bool{
+			flags.PrioritizedSamplingV2: true,
+			flags.CompressTransitions:   true,
+		}))
+		reservoirResp, err := flagged.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{
+				BatchSize:     2,
+				EnvId:         "tictactoe",
+				Prioritized:   true,
+				PriorityAlpha: 1.0,
+			},
+		})
+
+		require.NoError(t, err)
+		assert.Len(t, reservoirResp.Transitions, 2)
+		assert.Equal(t, []float32{1.0, 1.0}, reservoirResp.Weights) // Equal priorities
 	})
 
 	// Test priority updates
@@ -200,6 +309,245 @@ func TestReplayServiceIntegration(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, clearResp.RemainingCount, stats.TotalTransitions)
 	})
+
+	t.Run("RunContext", func(t *testing.T) {
+		run := runctx.Context{RunID: "run-1", ExperimentID: "exp-1"}
+		// The actor sends its run context as gRPC metadata
+		incoming := metadata.NewIncomingContext(ctx, metadata.Pairs(
+			runctx.MetadataRunID, run.RunID,
+			runctx.MetadataExperimentID, run.ExperimentID,
+		))
+		_, err := svc.StoreBatch(incoming, &replayv1.StoreBatchRequest{
+			Transitions: []*replayv1.Transition{
+				{EnvId: "tictactoe", EpisodeId: "episode-run", Action: []byte{1}},
+				{EnvId: "tictactoe", EpisodeId: "episode-run", Action: []byte{2}, Metadata: map[string]string{runctx.RunIDKey: "run-2"}},
+			},
+		})
+		require.NoError(t, err)
+
+		resp, err := svc.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 10, RunId: "run-1"},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Transitions, 1)
+		assert.Equal(t, run, runctx.FromLabels(resp.Transitions[0].Metadata))
+		assert.Equal(t, uint32(1), resp.TotalAvailable)
+
+		stats, err := svc.GetStats(ctx, &replayv1.GetStatsRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]uint64{"run-1": 1, "run-2": 1}, stats.TransitionsByRun)
+	})
+
+	t.Run("ErrorDetails", func(t *testing.T) {
+		_, err := svc.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 1, EnvId: "chess"},
+		})
+		detail := rpcerr.Parse(err)
+		assert.Equal(t, codes.FailedPrecondition, detail.Code)
+		assert.Equal(t, rpcerr.NoTransitions, detail.Reason)
+		assert.True(t, detail.Retryable)
+		assert.Equal(t, time.Second, detail.RetryAfter)
+
+		_, err = svc.Sample(ctx, &replayv1.SampleRequest{})
+		detail = rpcerr.Parse(err)
+		assert.Equal(t, rpcerr.InvalidRequest, detail.Reason)
+		assert.False(t, detail.Retryable)
+	})
+
+	t.Run("SampleStream", func(t *testing.T) {
+		stream := &sampleStream{ctx: ctx}
+		err := svc.SampleStream(&replayv1.SampleStreamRequest{
+			Config:           &replayv1.SampleConfig{BatchSize: 1, EnvId: "tictactoe"},
+			BatchesPerSecond: 1000,
+			MaxBatches:       3,
+		}, stream)
+		require.NoError(t, err)
+		require.Len(t, stream.sent, 3)
+		for _, resp := range stream.sent {
+			require.Len(t, resp.Transitions, 1)
+			assert.Equal(t, "tictactoe", resp.Transitions[0].EnvId)
+		}
+
+		// An empty buffer keeps the stream waiting until the learner gives up.
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		stream = &sampleStream{ctx: waitCtx}
+		err = svc.SampleStream(&replayv1.SampleStreamRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 1, EnvId: "chess"},
+		}, stream)
+		assert.Equal(t, codes.DeadlineExceeded, rpcerr.Parse(err).Code)
+		assert.Empty(t, stream.sent)
+
+		err = svc.SampleStream(&replayv1.SampleStreamRequest{
+			Config:           &replayv1.SampleConfig{BatchSize: 1},
+			BatchesPerSecond: -1,
+		}, &sampleStream{ctx: ctx})
+		assert.Equal(t, rpcerr.InvalidRequest, rpcerr.Parse(err).Reason)
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream := newWatchStream(watchCtx)
+		done := make(chan error, 1)
+		go func() {
+			done <- svc.Watch(&replayv1.WatchRequest{EnvId: "tictactoe", IncludeTransitions: true}, stream)
+		}()
+		<-stream.subscribed
+
+		resp, err := svc.StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: []*replayv1.Transition{
+			{EnvId: "gridworld", State: []byte{1}},
+			{EnvId: "tictactoe", State: []byte{2}},
+		}})
+		require.NoError(t, err)
+		event := <-stream.events
+		assert.Equal(t, resp.TransitionIds[1:], event.TransitionIds)
+		require.Len(t, event.Transitions, 1)
+		assert.Equal(t, []byte{2}, event.Transitions[0].State)
+
+		cancel()
+		assert.Equal(t, codes.Canceled, rpcerr.Parse(<-done).Code)
+
+		// Stop ends the watch so the server can stop gracefully.
+		stopping := service.NewReplayService(backend)
+		stream = newWatchStream(ctx)
+		go func() {
+			done <- stopping.Watch(&replayv1.WatchRequest{}, stream)
+		}()
+		<-stream.subscribed
+		stopping.Stop()
+		assert.Equal(t, codes.Unavailable, rpcerr.Parse(<-done).Code)
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		resp, err := svc.Version(ctx, &replayv1.VersionRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "replay", resp.Service)
+		assert.Equal(t, "dev", resp.Version) // Unstamped test build
+	})
+}
+
+// sampleStream records the batches SampleStream sends.
+type sampleStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*replayv1.SampleResponse
+}
+
+func (s *sampleStream) Context() context.Context { return s.ctx }
+
+func (s *sampleStream) Send(resp *replayv1.SampleResponse) error {
+	s.sent = append(s.sent, resp)
+	return nil
+}
+
+// watchStream passes on the events Watch sends.
+type watchStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	subscribed chan struct{}
+	events     chan *replayv1.WatchEvent
+}
+
+func newWatchStream(ctx context.Context) *watchStream {
+	return &watchStream{ctx: ctx, subscribed: make(chan struct{}), events: make(chan *replayv1.WatchEvent, 16)}
+}
+
+func (s *watchStream) Context() context.Context { return s.ctx }
+
+func (s *watchStream) SendHeader(metadata.MD) error {
+	close(s.subscribed)
+	return nil
+}
+
+func (s *watchStream) Send(event *replayv1.WatchEvent) error {
+	s.events <- event
+	return nil
+}
+
+// TestWatchDedup checks that watchers see only the transitions a deduplicating
+// backend stored, not the duplicates it dropped.
+func TestWatchDedup(t *testing.T) {
+	backend := storage.NewMemoryBackend(100)
+	defer backend.Close()
+	backend.SetDedup(true)
+	svc := service.NewReplayService(backend)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := svc.StoreTransition(ctx, &replayv1.StoreTransitionRequest{Transition: &replayv1.Transition{EnvId: "tictactoe", State: []byte{1}}})
+	require.NoError(t, err)
+
+	stream := newWatchStream(ctx)
+	go svc.Watch(&replayv1.WatchRequest{IncludeTransitions: true}, stream)
+	<-stream.subscribed
+
+	_, err = svc.StoreTransition(ctx, &replayv1.StoreTransitionRequest{Transition: &replayv1.Transition{EnvId: "tictactoe", State: []byte{1}}})
+	require.NoError(t, err)
+	resp, err := svc.StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: []*replayv1.Transition{
+		{EnvId: "tictactoe", State: []byte{1}},
+		{EnvId: "tictactoe", State: []byte{2}},
+		{EnvId: "tictactoe", State: []byte{2}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), resp.StoredCount)
+
+	event := <-stream.events
+	assert.Equal(t, resp.TransitionIds[1:2], event.TransitionIds)
+	require.Len(t, event.Transitions, 1)
+	assert.Equal(t, []byte{2}, event.Transitions[0].State)
+	assert.Empty(t, stream.events)
+}
+
+// TestPriorityBeta checks the importance-sampling weights of prioritized
+// samples, with priority_beta and with the server's schedule.
+func TestPriorityBeta(t *testing.T) {
+	backend := storage.NewMemoryBackend(1000)
+	defer backend.Close()
+	ctx := context.Background()
+	// With alpha 1 the probabilities are 1/4 and 3/4, so the 1/(N*p) weights
+	// are 2 and 2/3.
+	_, err := backend.StoreBatch(ctx, []*storage.Transition{{ID: "low", Priority: 1}, {ID: "high", Priority: 3}})
+	require.NoError(t, err)
+
+	sample := func(svc *service.ReplayService, beta float32) map[string]float32 {
+		t.Helper()
+		resp, err := svc.Sample(ctx, &replayv1.SampleRequest{
+			Config: &replayv1.SampleConfig{BatchSize: 2, Prioritized: true, PriorityAlpha: 1, PriorityBeta: beta},
+		})
+		require.NoError(t, err)
+		weights := make(map[string]float32)
+		for i, transition := range resp.Transitions {
+			weights[transition.Id] = resp.Weights[i]
+		}
+		return weights
+	}
+
+	svc := service.NewReplayService(backend)
+	weights := sample(svc, 0)
+	assert.InDelta(t, 2, weights["low"], 1e-5) // Uncorrected without a schedule
+	assert.InDelta(t, 2.0/3, weights["high"], 1e-5)
+	weights = sample(svc, 1)
+	assert.InDelta(t, 1, weights["low"], 1e-5)
+	assert.InDelta(t, 1.0/3, weights["high"], 1e-5)
+	weights = sample(svc, 0.5)
+	assert.InDelta(t, 1, weights["low"], 1e-5)
+	assert.InDelta(t, 0.57735, weights["high"], 1e-5)
+
+	_, err = svc.Sample(ctx, &replayv1.SampleRequest{
+		Config: &replayv1.SampleConfig{BatchSize: 2, Prioritized: true, PriorityBeta: 1.5},
+	})
+	assert.Equal(t, codes.InvalidArgument, rpcerr.Parse(err).Code)
+	assert.Contains(t, rpcerr.Parse(err).Message, "priority_beta must be in [0, 1]")
+
+	// The schedule anneals beta from 0.5 to 1 over two samples, and a
+	// request's own priority_beta takes precedence without advancing it.
+	scheduled := service.NewReplayService(backend).WithBetaSchedule(&service.BetaSchedule{Start: 0.5, Samples: 2})
+	assert.InDelta(t, 0.57735, sample(scheduled, 0)["high"], 1e-5)
+	assert.InDelta(t, 1.0/3, sample(scheduled, 1)["high"], 1e-5)
+	assert.InDelta(t, 0.43869, sample(scheduled, 0)["high"], 1e-5) // beta 0.75
+	assert.InDelta(t, 1.0/3, sample(scheduled, 0)["high"], 1e-5)
 }
 
 // TestEngineDataFormats verifies that our replay service can handle
@@ -0,0 +1,267 @@
+// Package archive is the replay buffer's cold tier. Transitions evicted from
+// the buffer are batched per environment into chunks of gzipped JSON lines
+// and written to object storage, where the Reader exports or samples them for
+// offline training.
+//
+// Chunks are named <prefix>/<env>/<first>-<last>-<count>-<random>.jsonl.gz,
+// where env is path-escaped ("_" when empty), first and last are the oldest
+// and newest timestamps in Unix nanoseconds, zero-padded to 20 digits, and
+// count is the number of transitions.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/replay/internal/storage"
+)
+
+// chunkExt ends every chunk's name.
+const chunkExt = ".jsonl.gz"
+
+// uploadQueue is how many full chunks may wait for upload before further
+// chunks are dropped rather than holding up the buffer.
+const uploadQueue = 16
+
+// uploadAttempts is how many times a chunk upload is tried.
+const uploadAttempts = 3
+
+// Options configures an Archiver.
+type Options struct {
+	// Prefix starts every chunk's key.
+	Prefix string
+	// ChunkSize is the number of transitions per chunk.
+	ChunkSize int
+	// FlushInterval is how long a partial chunk may wait before it is
+	// written, so quiet environments are archived too.
+	FlushInterval time.Duration
+	Logger        zerolog.Logger
+}
+
+// Archiver writes evicted transitions to an ObjectStore. Add it to a backend
+// with storage.EvictionNotifier.OnEvict.
+type Archiver struct {
+	store ObjectStore
+	opts  Options
+
+	mu      sync.Mutex
+	pending map[string][]*storage.Transition // by EnvID
+	closed  bool
+
+	uploads chan []*storage.Transition
+	stop    chan struct{}
+	done    sync.WaitGroup
+
+	archived atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// NewArchiver starts an archiver writing to store.
+func NewArchiver(store ObjectStore, opts Options) *Archiver {
+	if opts.ChunkSize < 1 {
+		opts.ChunkSize = 1
+	}
+	a := &Archiver{
+		store:   store,
+		opts:    opts,
+		pending: make(map[string][]*storage.Transition),
+		uploads: make(chan []*storage.Transition, uploadQueue),
+		stop:    make(chan struct{}),
+	}
+	a.done.Add(2)
+	go a.upload()
+	go a.flushPeriodically()
+	return a
+}
+
+// Add queues an evicted transition for archiving. It never blocks: if uploads
+// fall behind, whole chunks are dropped and counted.
+func (a *Archiver) Add(transition *storage.Transition) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		a.dropped.Add(1)
+		return
+	}
+	chunk := append(a.pending[transition.EnvID], transition)
+	if len(chunk) < a.opts.ChunkSize {
+		a.pending[transition.EnvID] = chunk
+		return
+	}
+	delete(a.pending, transition.EnvID)
+	a.enqueue(chunk)
+}
+
+// Flush queues every partial chunk for upload.
+func (a *Archiver) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.closed {
+		a.flushLocked()
+	}
+}
+
+// Archived returns the number of transitions written to the store.
+func (a *Archiver) Archived() uint64 {
+	return a.archived.Load()
+}
+
+// Dropped returns the number of transitions that were not archived, because
+// uploads fell behind or failed.
+func (a *Archiver) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Close writes the partial chunks and waits for the queued uploads, or until
+// ctx is done. Transitions added afterwards are dropped.
+func (a *Archiver) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.flushLocked()
+	a.closed = true
+	close(a.stop)
+	close(a.uploads)
+	a.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		a.done.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flushLocked queues the partial chunks; a.mu must be held.
+func (a *Archiver) flushLocked() {
+	for env, chunk := range a.pending {
+		delete(a.pending, env)
+		a.enqueue(chunk)
+	}
+}
+
+// enqueue hands a chunk to the uploader, or drops it if the queue is full;
+// a.mu must be held.
+func (a *Archiver) enqueue(chunk []*storage.Transition) {
+	select {
+	case a.uploads <- chunk:
+	default:
+		a.dropped.Add(uint64(len(chunk)))
+		a.opts.Logger.Warn().Int("transitions", len(chunk)).Msg("Archive uploads are behind, dropped a chunk")
+	}
+}
+
+func (a *Archiver) flushPeriodically() {
+	defer a.done.Done()
+	if a.opts.FlushInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(a.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.Flush()
+		}
+	}
+}
+
+func (a *Archiver) upload() {
+	defer a.done.Done()
+	for chunk := range a.uploads {
+		key, body, err := encodeChunk(a.opts.Prefix, chunk)
+		if err == nil {
+			err = a.put(key, body)
+		}
+		if err != nil {
+			a.dropped.Add(uint64(len(chunk)))
+			a.opts.Logger.Error().Err(err).Int("transitions", len(chunk)).Msg("Failed to archive chunk")
+			continue
+		}
+		a.archived.Add(uint64(len(chunk)))
+		a.opts.Logger.Debug().Str("key", key).Int("transitions", len(chunk)).Msg("Archived chunk")
+	}
+}
+
+// put writes a chunk, retrying with backoff.
+func (a *Archiver) put(key string, body []byte) error {
+	var err error
+	for attempt := 0; attempt < uploadAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		err = a.store.Put(ctx, key, body)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// encodeChunk returns the key and gzipped JSON lines of a chunk of
+// transitions from one environment.
+func encodeChunk(prefix string, chunk []*storage.Transition) (string, []byte, error) {
+	first, last := chunk[0].Timestamp, chunk[0].Timestamp
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, transition := range chunk {
+		if transition.Timestamp.Before(first) {
+			first = transition.Timestamp
+		}
+		if transition.Timestamp.After(last) {
+			last = transition.Timestamp
+		}
+		if err := enc.Encode(transition); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return "", nil, err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", nil, err
+	}
+	name := fmt.Sprintf("%020d-%020d-%d-%s%s", first.UnixNano(), last.UnixNano(), len(chunk), hex.EncodeToString(suffix), chunkExt)
+	return path.Join(prefix, envDir(chunk[0].EnvID), name), buf.Bytes(), nil
+}
+
+// envDir is the directory of an environment's chunks.
+func envDir(envID string) string {
+	switch envID {
+	case "":
+		return "_"
+	case "_":
+		return "%5F"
+	}
+	if strings.Trim(envID, ".") == "" {
+		// "." and ".." would not name a directory of their own.
+		return strings.ReplaceAll(envID, ".", "%2E")
+	}
+	return url.PathEscape(envID)
+}
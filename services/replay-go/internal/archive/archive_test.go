@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cartridge/replay/internal/storage"
+)
+
+func exportIDs(t *testing.T, reader *Reader, envID string) []string {
+	t.Helper()
+	var ids []string
+	require.NoError(t, reader.Export(context.Background(), envID, func(transition *storage.Transition) error {
+		ids = append(ids, transition.ID)
+		return nil
+	}))
+	return ids
+}
+
+func TestArchiver_RoundTrip(t *testing.T) {
+	store := DirStore(t.TempDir())
+	archiver := NewArchiver(store, Options{Prefix: "cold", ChunkSize: 3, Logger: zerolog.Nop()})
+
+	now := time.Now()
+	var want []string
+	for i := 0; i < 7; i++ {
+		id := fmt.Sprintf("t%d", i)
+		want = append(want, id)
+		archiver.Add(&storage.Transition{ID: id, EnvID: "tictactoe", State: []byte{byte(i)}, Timestamp: now.Add(time.Duration(i) * time.Second)})
+	}
+	archiver.Add(&storage.Transition{ID: "g", EnvID: "grid/world", Timestamp: now})
+	archiver.Add(&storage.Transition{ID: "none", Timestamp: now})
+
+	// The partial chunks are written on Close.
+	require.NoError(t, archiver.Close(context.Background()))
+	assert.Equal(t, uint64(9), archiver.Archived())
+	assert.Zero(t, archiver.Dropped())
+	archiver.Add(&storage.Transition{ID: "late"})
+	assert.Equal(t, uint64(1), archiver.Dropped())
+
+	reader := NewReader(store, "cold")
+	ctx := context.Background()
+	chunks, err := reader.Chunks(ctx, "tictactoe")
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, []int{3, 3, 1}, []int{chunks[0].Count, chunks[1].Count, chunks[2].Count})
+	assert.Equal(t, "tictactoe", chunks[0].EnvID)
+	assert.True(t, chunks[0].First.Equal(now))
+	assert.True(t, chunks[0].Last.Equal(now.Add(2*time.Second)))
+
+	assert.Equal(t, want, exportIDs(t, reader, "tictactoe"))
+	assert.Equal(t, []string{"g"}, exportIDs(t, reader, "grid/world"))
+	assert.Len(t, exportIDs(t, reader, ""), 9)
+
+	all, err := reader.Chunks(ctx, "")
+	require.NoError(t, err)
+	envs := map[string]bool{}
+	for _, chunk := range all {
+		envs[chunk.EnvID] = true
+	}
+	assert.Equal(t, map[string]bool{"tictactoe": true, "grid/world": true, "": true}, envs)
+
+	exported := map[string]*storage.Transition{}
+	require.NoError(t, reader.Export(ctx, "tictactoe", func(transition *storage.Transition) error {
+		exported[transition.ID] = transition
+		return nil
+	}))
+	assert.Equal(t, []byte{4}, exported["t4"].State)
+}
+
+func TestArchiver_FlushInterval(t *testing.T) {
+	store := DirStore(t.TempDir())
+	archiver := NewArchiver(store, Options{ChunkSize: 100, FlushInterval: 10 * time.Millisecond, Logger: zerolog.Nop()})
+	defer archiver.Close(context.Background())
+
+	archiver.Add(&storage.Transition{ID: "a", EnvID: "tictactoe", Timestamp: time.Now()})
+	require.Eventually(t, func() bool { return archiver.Archived() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []string{"a"}, exportIDs(t, NewReader(store, ""), "tictactoe"))
+}
+
+func TestReader_Sample(t *testing.T) {
+	store := DirStore(t.TempDir())
+	archiver := NewArchiver(store, Options{Prefix: "cold", ChunkSize: 4, Logger: zerolog.Nop()})
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		archiver.Add(&storage.Transition{ID: fmt.Sprintf("t%d", i), EnvID: "tictactoe", Timestamp: now.Add(time.Duration(i) * time.Second)})
+	}
+	archiver.Add(&storage.Transition{ID: "g", EnvID: "gridworld", Timestamp: now})
+	require.NoError(t, archiver.Close(context.Background()))
+
+	reader := NewReader(store, "cold")
+	ctx := context.Background()
+
+	sampled, total, err := reader.Sample(ctx, "tictactoe", 5)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), total)
+	require.Len(t, sampled, 5)
+	seen := map[string]bool{}
+	for _, transition := range sampled {
+		assert.Equal(t, "tictactoe", transition.EnvID)
+		seen[transition.ID] = true
+	}
+	assert.Len(t, seen, 5, "a batch never repeats a transition")
+
+	// A batch larger than the archive returns all of it.
+	sampled, total, err = reader.Sample(ctx, "", 100)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), total)
+	assert.Len(t, sampled, 11)
+
+	// Every transition is equally likely.
+	counts := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		sampled, _, err := reader.Sample(ctx, "tictactoe", 1)
+		require.NoError(t, err)
+		counts[sampled[0].ID]++
+	}
+	for i := 0; i < 10; i++ {
+		assert.InDelta(t, 200, counts[fmt.Sprintf("t%d", i)], 60)
+	}
+
+	_, _, err = reader.Sample(ctx, "missing", 1)
+	assert.ErrorIs(t, err, storage.ErrNoTransitions)
+}
+
+func TestReader_SkipsForeignKeys(t *testing.T) {
+	store := DirStore(t.TempDir())
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "cold/tictactoe/notes.txt", []byte("hello")))
+	require.NoError(t, store.Put(ctx, "cold/tictactoe/x-y-z-w.jsonl.gz", []byte("junk")))
+	require.NoError(t, store.Put(ctx, "cold/a/b/1-2-3-abcd.jsonl.gz", []byte("junk")))
+
+	chunks, err := NewReader(store, "cold").Chunks(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+}
+
+func TestEnvDir(t *testing.T) {
+	assert.Equal(t, "_", envDir(""))
+	assert.Equal(t, "%5F", envDir("_"))
+	assert.Equal(t, "tictactoe", envDir("tictactoe"))
+	assert.Equal(t, "grid%2Fworld", envDir("grid/world"))
+	assert.Equal(t, "%2E%2E", envDir(".."))
+}
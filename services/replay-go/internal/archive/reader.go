@@ -0,0 +1,245 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cartridge/replay/internal/storage"
+)
+
+// Chunk describes an archived chunk, as its key records it.
+type Chunk struct {
+	Key   string
+	EnvID string
+	// First and Last are the oldest and newest timestamps in the chunk.
+	First time.Time
+	Last  time.Time
+	Count int
+}
+
+// Reader reads the chunks an Archiver wrote under a prefix.
+type Reader struct {
+	store  ObjectStore
+	prefix string
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewReader returns a reader of the archive under prefix in store.
+func NewReader(store ObjectStore, prefix string) *Reader {
+	return &Reader{
+		store:  store,
+		prefix: prefix,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Chunks lists the chunks archived for envID, or for every environment when
+// it is empty, oldest first.
+func (r *Reader) Chunks(ctx context.Context, envID string) ([]Chunk, error) {
+	listPrefix := ""
+	if r.prefix != "" {
+		listPrefix = r.prefix + "/"
+	}
+	if envID != "" {
+		listPrefix += envDir(envID) + "/"
+	}
+	keys, err := r.store.List(ctx, listPrefix)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([]Chunk, 0, len(keys))
+	for _, key := range keys {
+		if chunk, ok := r.parseChunk(key); ok {
+			chunks = append(chunks, chunk)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		if !chunks[i].First.Equal(chunks[j].First) {
+			return chunks[i].First.Before(chunks[j].First)
+		}
+		return chunks[i].Key < chunks[j].Key
+	})
+	return chunks, nil
+}
+
+// Export calls fn with every transition archived for envID, or for every
+// environment, chunk by chunk from the oldest. Chunks removed since they were
+// listed, e.g. by a lifecycle rule, are skipped.
+func (r *Reader) Export(ctx context.Context, envID string, fn func(*storage.Transition) error) error {
+	chunks, err := r.Chunks(ctx, envID)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		transitions, err := r.read(ctx, chunk.Key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, transition := range transitions {
+			if err := fn(transition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Sample draws up to n distinct transitions uniformly from those archived for
+// envID, or for every environment, reading only the chunks that hold them.
+// It also returns the number of transitions archived there.
+func (r *Reader) Sample(ctx context.Context, envID string, n int) ([]*storage.Transition, uint64, error) {
+	chunks, err := r.Chunks(ctx, envID)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := 0
+	for _, chunk := range chunks {
+		total += chunk.Count
+	}
+	if total == 0 {
+		return nil, 0, storage.ErrNoTransitions
+	}
+	if n > total {
+		n = total
+	}
+
+	picks := r.pick(total, n)
+	sampled := make([]*storage.Transition, 0, n)
+	offset := 0
+	for _, chunk := range chunks {
+		var local []int
+		for len(picks) > 0 && picks[0] < offset+chunk.Count {
+			local = append(local, picks[0]-offset)
+			picks = picks[1:]
+		}
+		offset += chunk.Count
+		if len(local) == 0 {
+			continue
+		}
+		transitions, err := r.read(ctx, chunk.Key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, i := range local {
+			if i < len(transitions) {
+				sampled = append(sampled, transitions[i])
+			}
+		}
+	}
+	if len(sampled) == 0 {
+		return nil, uint64(total), storage.ErrNoTransitions
+	}
+
+	r.mu.Lock()
+	r.rng.Shuffle(len(sampled), func(i, j int) { sampled[i], sampled[j] = sampled[j], sampled[i] })
+	r.mu.Unlock()
+	return sampled, uint64(total), nil
+}
+
+// pick returns n distinct integers below total in ascending order, chosen
+// uniformly with Floyd's algorithm.
+func (r *Reader) pick(total, n int) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	chosen := make(map[int]struct{}, n)
+	for j := total - n; j < total; j++ {
+		t := r.rng.Intn(j + 1)
+		if _, taken := chosen[t]; taken {
+			t = j
+		}
+		chosen[t] = struct{}{}
+	}
+	picks := make([]int, 0, n)
+	for i := range chosen {
+		picks = append(picks, i)
+	}
+	sort.Ints(picks)
+	return picks
+}
+
+// read decodes the transitions of the chunk at key.
+func (r *Reader) read(ctx context.Context, key string) ([]*storage.Transition, error) {
+	body, err := r.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("archive: chunk %s: %w", key, err)
+	}
+	var transitions []*storage.Transition
+	dec := json.NewDecoder(gz)
+	for {
+		transition := &storage.Transition{}
+		err := dec.Decode(transition)
+		if errors.Is(err, io.EOF) {
+			return transitions, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("archive: chunk %s: %w", key, err)
+		}
+		transitions = append(transitions, transition)
+	}
+}
+
+// parseChunk reads a chunk's description from its key, reporting false for
+// keys that do not name a chunk.
+func (r *Reader) parseChunk(key string) (Chunk, bool) {
+	rel := key
+	if r.prefix != "" {
+		if !strings.HasPrefix(key, r.prefix+"/") {
+			return Chunk{}, false
+		}
+		rel = key[len(r.prefix)+1:]
+	}
+	dir, name := path.Split(rel)
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" || strings.Contains(dir, "/") || !strings.HasSuffix(name, chunkExt) {
+		return Chunk{}, false
+	}
+	envID := ""
+	if dir != "_" {
+		var err error
+		if envID, err = url.PathUnescape(dir); err != nil {
+			return Chunk{}, false
+		}
+	}
+	fields := strings.Split(strings.TrimSuffix(name, chunkExt), "-")
+	if len(fields) != 4 {
+		return Chunk{}, false
+	}
+	first, err1 := strconv.ParseInt(fields[0], 10, 64)
+	last, err2 := strconv.ParseInt(fields[1], 10, 64)
+	count, err3 := strconv.Atoi(fields[2])
+	if err1 != nil || err2 != nil || err3 != nil || count < 0 {
+		return Chunk{}, false
+	}
+	return Chunk{
+		Key:   key,
+		EnvID: envID,
+		First: time.Unix(0, first),
+		Last:  time.Unix(0, last),
+		Count: count,
+	}, true
+}
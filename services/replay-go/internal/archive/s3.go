@@ -0,0 +1,197 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cartridge/sigv4"
+)
+
+// S3Config locates the archive bucket and the credentials that sign requests
+// to it. AWS S3, MinIO and Google Cloud Storage (through its XML API with HMAC
+// keys) all accept the Signature Version 4 signing used here.
+type S3Config struct {
+	// Endpoint is the service base URL, e.g. https://s3.us-east-1.amazonaws.com,
+	// https://storage.googleapis.com or http://minio:9000.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key,
+	// which MinIO and most self-hosted stores require.
+	PathStyle bool
+	// Client sends the requests; a client with a five minute timeout when nil.
+	Client *http.Client
+}
+
+// S3Store is an ObjectStore in an S3-compatible bucket.
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Store validates cfg and returns a store for its bucket.
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("archive: bucket and credentials are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("archive: invalid endpoint %q", cfg.Endpoint)
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	return &S3Store{cfg: cfg, endpoint: endpoint, client: client, now: time.Now}, nil
+}
+
+// WithNow allows tests to override the signing clock.
+func (s *S3Store) WithNow(now func() time.Time) {
+	s.now = now
+}
+
+// Put implements ObjectStore.
+func (s *S3Store) Put(ctx context.Context, key string, body []byte) error {
+	sum := sha256.Sum256(body)
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, body, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("archive: put %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError("put "+key, resp)
+	}
+	return nil
+}
+
+// Get implements ObjectStore.
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil, nil, sigv4.EmptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("archive: get %s: %w", key, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, responseError("get "+key, resp)
+	}
+}
+
+// listBucketResult is the part of a ListObjectsV2 response the store reads.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List implements ObjectStore with ListObjectsV2, following continuation
+// tokens until the listing is complete.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil, sigv4.EmptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("archive: list %s: %w", prefix, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := responseError("list "+prefix, resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("archive: list %s: %w", prefix, err)
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			sort.Strings(keys)
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// newRequest builds a request for key, or for the bucket when key is empty,
+// signed with the Authorization header.
+func (s *S3Store) newRequest(ctx context.Context, method, key string, query url.Values, body []byte, payloadHash string) (*http.Request, error) {
+	host, path := s.endpoint.Host, "/"+key
+	if s.cfg.PathStyle {
+		path = "/" + s.cfg.Bucket + path
+	} else {
+		host = s.cfg.Bucket + "." + host
+	}
+	if prefix := strings.TrimSuffix(s.endpoint.Path, "/"); prefix != "" {
+		path = prefix + path
+	}
+	target := s.endpoint.Scheme + "://" + host + sigv4.EncodePath(path)
+	if len(query) > 0 {
+		target += "?" + sigv4.EncodeQuery(query)
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	credentials := sigv4.Credentials{AccessKeyID: s.cfg.AccessKeyID, SecretAccessKey: s.cfg.SecretAccessKey, Region: s.cfg.Region}
+	credentials.Sign(req, payloadHash, s.now())
+	return req, nil
+}
+
+// responseError describes a failed request with the start of the error body
+// the store returned.
+func responseError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("archive: %s: %s: %s", op, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,118 @@
+package archive
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cartridge/sigv4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 serves path-style requests for one bucket, paging listings two keys
+// at a time.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), sigv4.Algorithm+" ") {
+		http.Error(w, "unsigned", http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/bucket/")
+	if !ok && r.URL.Path != "/bucket" {
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+	case key != "":
+		body, ok := f.objects[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	default:
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) && k > r.URL.Query().Get("continuation-token") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var result listBucketResult
+		if len(keys) > 2 {
+			keys = keys[:2]
+			result.IsTruncated = true
+			result.NextContinuationToken = keys[1]
+		}
+		for _, k := range keys {
+			result.Contents = append(result.Contents, struct {
+				Key string `xml:"Key"`
+			}{k})
+		}
+		xml.NewEncoder(w).Encode(result)
+	}
+}
+
+func TestS3Store_PutGetList(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}})
+	defer server.Close()
+
+	store, _, err := Open("s3://bucket/cold", S3Config{
+		Endpoint:        server.URL,
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, key := range []string{"cold/b", "cold/a", "cold/c d", "other/x"} {
+		require.NoError(t, store.Put(ctx, key, []byte(key)))
+	}
+
+	body, err := store.Get(ctx, "cold/c d")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "cold/c d", string(data))
+
+	_, err = store.Get(ctx, "cold/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	keys, err := store.List(ctx, "cold/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cold/a", "cold/b", "cold/c d"}, keys)
+}
+
+func TestOpen(t *testing.T) {
+	store, prefix, err := Open("file:///var/lib/replay/archive", S3Config{})
+	require.NoError(t, err)
+	assert.Equal(t, DirStore("/var/lib/replay/archive"), store)
+	assert.Empty(t, prefix)
+
+	_, prefix, err = Open("s3://bucket/replay/cold/", S3Config{AccessKeyID: "key", SecretAccessKey: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "replay/cold", prefix)
+
+	_, _, err = Open("s3://bucket", S3Config{})
+	assert.Error(t, err, "credentials are required")
+	_, _, err = Open("gs://bucket", S3Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned by ObjectStore.Get for a key that does not exist.
+var ErrNotFound = errors.New("archive: object not found")
+
+// ObjectStore holds the archive's chunk files.
+type ObjectStore interface {
+	// Put writes the object at key, replacing any there.
+	Put(ctx context.Context, key string, body []byte) error
+	// Get opens the object at key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the keys starting with prefix, in lexical order.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Open returns the store and key prefix that rawURL names: s3://bucket/prefix
+// for a bucket, reached with s3cfg, or file:///path for a local directory.
+func Open(rawURL string, s3cfg S3Config) (ObjectStore, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("archive: invalid URL %q: %w", rawURL, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, "", fmt.Errorf("archive: %q names no bucket", rawURL)
+		}
+		s3cfg.Bucket = u.Host
+		store, err := NewS3Store(s3cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		return store, prefix, nil
+	case "file":
+		if u.Path == "" {
+			return nil, "", fmt.Errorf("archive: %q names no directory", rawURL)
+		}
+		return DirStore(u.Path), "", nil
+	default:
+		return nil, "", fmt.Errorf("archive: unsupported URL scheme %q, want s3 or file", u.Scheme)
+	}
+}
+
+// DirStore is an ObjectStore in a local directory, where keys are paths
+// relative to it.
+type DirStore string
+
+// Put implements ObjectStore. The object is written beside its path and
+// renamed into place, so readers never see part of it.
+func (d DirStore) Put(ctx context.Context, key string, body []byte) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get implements ObjectStore.
+func (d DirStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// List implements ObjectStore.
+func (d DirStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(string(d), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == string(d) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(string(d), path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (d DirStore) path(key string) string {
+	return filepath.Join(string(d), filepath.FromSlash(key))
+}
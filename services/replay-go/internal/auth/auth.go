@@ -0,0 +1,107 @@
+// Package auth keeps the replay service from being open to anyone on the
+// network: TLS server credentials, optionally requiring client certificates,
+// and bearer tokens checked on every call.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/cartridge/proto/rpcerr"
+)
+
+// healthPrefix starts the methods of the grpc.health.v1 Health service, which
+// stays open so probes need no credentials.
+const healthPrefix = "/grpc.health.v1.Health/"
+
+// ServerTLS returns credentials serving the PEM certificate and key in
+// certFile and keyFile. With clientCAFile, clients must present a certificate
+// that chains to one of its PEM CA certificates.
+func ServerTLS(certFile, keyFile, clientCAFile string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// Tokens accepts calls that carry one of a set of bearer tokens as
+// "authorization: Bearer <token>" metadata.
+type Tokens struct {
+	tokens [][]byte
+}
+
+// NewTokens returns a Tokens accepting tokens, or nil when there are none.
+func NewTokens(tokens []string) *Tokens {
+	if len(tokens) == 0 {
+		return nil
+	}
+	t := &Tokens{}
+	for _, token := range tokens {
+		t.tokens = append(t.tokens, []byte(token))
+	}
+	return t
+}
+
+// UnaryServerInterceptor rejects unary calls without a valid token with
+// UNAUTHENTICATED.
+func (t *Tokens) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := t.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func (t *Tokens) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := t.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (t *Tokens) check(ctx context.Context, method string) error {
+	if strings.HasPrefix(method, healthPrefix) {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			continue
+		}
+		for _, want := range t.tokens {
+			if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), want) == 1 {
+				return nil
+			}
+		}
+	}
+	return rpcerr.New(codes.Unauthenticated, rpcerr.Unauthenticated, "missing or invalid bearer token")
+}
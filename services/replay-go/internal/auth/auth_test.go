@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/proto/rpcerr"
+	"github.com/cartridge/replay/internal/service"
+	"github.com/cartridge/replay/internal/storage"
+)
+
+// serve starts a replay service and health service with opts on a loopback
+// port and returns its address.
+func serve(t *testing.T, opts ...grpc.ServerOption) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(opts...)
+	backend := storage.NewMemoryBackend(10)
+	replayv1.RegisterReplayServer(server, service.NewReplayService(backend))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(func() {
+		server.Stop()
+		backend.Close()
+	})
+	return lis.Addr().String()
+}
+
+func dial(t *testing.T, addr string, creds credentials.TransportCredentials) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestTokens(t *testing.T) {
+	tokens := NewTokens([]string{"actor-secret", "learner-secret"})
+	addr := serve(t,
+		grpc.ChainUnaryInterceptor(tokens.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(tokens.StreamServerInterceptor()),
+	)
+	conn := dial(t, addr, insecure.NewCredentials())
+	client := replayv1.NewReplayClient(conn)
+	ctx := context.Background()
+
+	for _, header := range []string{"", "Bearer wrong", "learner-secret"} {
+		ctx := ctx
+		if header != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", header)
+		}
+		_, err := client.GetStats(ctx, &replayv1.GetStatsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), header)
+		assert.Equal(t, rpcerr.Unauthenticated, rpcerr.Parse(err).Reason, header)
+	}
+
+	_, err := client.GetStats(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer learner-secret"), &replayv1.GetStatsRequest{})
+	assert.NoError(t, err)
+
+	stream, err := client.Watch(ctx, &replayv1.WatchRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Probes need no token.
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	assert.Nil(t, NewTokens(nil))
+}
+
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := newCertificate(t, dir, "ca", nil, nil)
+	newCertificate(t, dir, "server", caCert, caKey)
+	newCertificate(t, dir, "client", caCert, caKey)
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	creds, err := ServerTLS(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt"))
+	require.NoError(t, err)
+	addr := serve(t, grpc.Creds(creds))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+	require.NoError(t, err)
+	conn := dial(t, addr, credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}}))
+	_, err = replayv1.NewReplayClient(conn).GetStats(ctx, &replayv1.GetStatsRequest{})
+	assert.NoError(t, err)
+
+	// Without a client certificate the handshake fails.
+	conn = dial(t, addr, credentials.NewTLS(&tls.Config{RootCAs: roots}))
+	_, err = replayv1.NewReplayClient(conn).GetStats(ctx, &replayv1.GetStatsRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = ServerTLS(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "server.key"))
+	assert.ErrorContains(t, err, "no certificates found in client CA")
+	_, err = ServerTLS(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "server.key"), "")
+	assert.ErrorContains(t, err, "failed to load TLS certificate")
+}
+
+// newCertificate writes a certificate for 127.0.0.1 and its key to
+// dir/name.crt and dir/name.key, signed by parent, or a self-signed CA when
+// parent is nil.
+func newCertificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
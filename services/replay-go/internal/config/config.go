@@ -0,0 +1,260 @@
+// Package config holds the replay service's settings, loaded with the shared
+// configuration library from a YAML file, the environment and flags.
+package config
+
+import (
+	"flag"
+	"net/url"
+	"time"
+
+	"github.com/cartridge/conf"
+)
+
+// Config holds all replay service configuration.
+type Config struct {
+	Port        int `env:"PORT" flag:"port" default:"8080" usage:"gRPC server port"`
+	MetricsPort int `env:"METRICS_PORT" flag:"metrics-port" default:"9090" usage:"Prometheus metrics port (0 disables)"`
+	// TLSCertFile and TLSKeyFile serve gRPC over TLS, and TLSClientCAFile
+	// also requires clients to present a certificate it signed. AuthTokens
+	// requires every call but health checks to carry one of the tokens as
+	// "authorization: Bearer <token>".
+	TLSCertFile     string   `env:"TLS_CERT_FILE" flag:"tls-cert-file" usage:"PEM certificate to serve gRPC over TLS with (plaintext when unset)"`
+	TLSKeyFile      string   `env:"TLS_KEY_FILE" flag:"tls-key-file" usage:"PEM private key of the TLS certificate"`
+	TLSClientCAFile string   `env:"TLS_CLIENT_CA_FILE" flag:"tls-client-ca-file" usage:"PEM CA certificates client certificates must chain to (mutual TLS, off when unset)"`
+	AuthTokens      []string `env:"AUTH_TOKENS" usage:"Comma-separated bearer tokens accepted on gRPC calls (off when unset)"`
+	// MaxSize is the number of transitions kept before the oldest are evicted.
+	MaxSize uint64 `env:"MAX_SIZE" flag:"max-size" default:"100000" usage:"Maximum number of transitions to store"`
+	// Backend is "memory", "disk" to keep the buffer in DataDir across
+	// restarts, "redis" to share one buffer in Redis between replicas, or
+	// "postgres" to keep it in PostgreSQL at DatabaseURL.
+	Backend  string `env:"BACKEND" flag:"backend" default:"memory" usage:"Storage backend: memory, disk, redis or postgres"`
+	DataDir  string `env:"DATA_DIR" flag:"data-dir" usage:"Directory the disk backend keeps the buffer in"`
+	DiskSync bool   `env:"DISK_SYNC" flag:"disk-sync" usage:"Flush every write to stable storage before acknowledging it (disk backend)"`
+	// SnapshotFile keeps the memory backend's buffer across deploys without
+	// the disk backend's per-write cost: it is saved there on graceful
+	// shutdown and restored on startup. A crash loses what was stored since.
+	SnapshotFile string `env:"SNAPSHOT_FILE" flag:"snapshot-file" usage:"File the memory backend saves the buffer to on shutdown and restores it from on startup (off when unset)"`
+	// Eviction chooses the transitions evicted once MaxSize is reached, and
+	// EnvEviction overrides it per environment, e.g. atari=lowest_priority.
+	// Both apply to the memory and disk backends, except reservoir, which
+	// only the memory backend supports and only as the default.
+	Eviction    string            `env:"EVICTION" flag:"eviction" default:"fifo" usage:"Eviction policy: fifo, lowest_priority, lowest_reward, random or reservoir"`
+	EnvEviction map[string]string `env:"ENV_EVICTION" flag:"env-eviction" usage:"Comma-separated env=policy eviction policy overrides"`
+	// Dedup drops transitions that repeat a stored one's environment, state,
+	// action and next state, returning the stored ID instead.
+	Dedup bool `env:"DEDUP" flag:"dedup" usage:"Drop transitions whose content is already stored, returning the stored ID (memory and disk backends)"`
+	// MaxAge expires transitions that much older than their timestamps,
+	// checked every SweepInterval, whichever backend holds them.
+	MaxAge        time.Duration `env:"MAX_AGE" flag:"max-age" usage:"Age at which transitions expire (0 keeps them until evicted)"`
+	SweepInterval time.Duration `env:"SWEEP_INTERVAL" flag:"sweep-interval" default:"1m" usage:"How often expired transitions are cleared"`
+	// The Redis settings apply to the redis backend. Replicas sharing a
+	// buffer must agree on RedisKeyPrefix and MaxSize.
+	RedisAddr      string `env:"REDIS_ADDR" flag:"redis-addr" default:"localhost:6379" usage:"Redis address (redis backend)"`
+	RedisPassword  string `env:"REDIS_PASSWORD" usage:"Redis password (redis backend)"`
+	RedisDB        int    `env:"REDIS_DB" flag:"redis-db" usage:"Redis database number (redis backend)"`
+	RedisKeyPrefix string `env:"REDIS_KEY_PREFIX" flag:"redis-key-prefix" default:"replay" usage:"Prefix of the keys the buffer is kept under (redis backend)"`
+	// DatabaseURL and DBAutoMigrate apply to the postgres backend. Without
+	// DBAutoMigrate the schema must have been migrated beforehand.
+	DatabaseURL   string `env:"DATABASE_URL" flag:"database-url" usage:"PostgreSQL URL or DSN (postgres backend)"`
+	DBAutoMigrate bool   `env:"DB_AUTO_MIGRATE" flag:"db-auto-migrate" usage:"Apply the schema migrations on startup (postgres backend)"`
+	// ArchiveURL, s3://bucket/prefix or file:///path, turns on the archive
+	// tier: transitions evicted from the memory or disk backend are written
+	// there in chunks of ArchiveChunkSize, or after ArchiveFlushInterval for
+	// quieter environments. The ArchiveS3 settings reach the bucket.
+	ArchiveURL               string        `env:"ARCHIVE_URL" flag:"archive-url" usage:"Object storage URL evicted transitions are archived to (off when unset)"`
+	ArchiveChunkSize         int           `env:"ARCHIVE_CHUNK_SIZE" flag:"archive-chunk-size" default:"10000" usage:"Transitions per archived chunk"`
+	ArchiveFlushInterval     time.Duration `env:"ARCHIVE_FLUSH_INTERVAL" flag:"archive-flush-interval" default:"5m" usage:"Longest a partial chunk waits before it is archived"`
+	ArchiveS3Endpoint        string        `env:"ARCHIVE_S3_ENDPOINT" flag:"archive-s3-endpoint" usage:"S3-compatible endpoint URL (AWS S3 in the region when unset)"`
+	ArchiveS3Region          string        `env:"ARCHIVE_S3_REGION" flag:"archive-s3-region" default:"us-east-1" usage:"Region requests to the archive bucket are signed for"`
+	ArchiveS3AccessKeyID     string        `env:"ARCHIVE_S3_ACCESS_KEY_ID" usage:"Access key for the archive bucket"`
+	ArchiveS3SecretAccessKey string        `env:"ARCHIVE_S3_SECRET_ACCESS_KEY" usage:"Secret key for the archive bucket"`
+	ArchiveS3PathStyle       bool          `env:"ARCHIVE_S3_PATH_STYLE" flag:"archive-s3-path-style" usage:"Address the archive bucket path-style, as MinIO requires"`
+	// CompressEnvs lists the environments whose transitions' state and
+	// observation blobs are held zstd-compressed, or "*" for all of them.
+	CompressEnvs []string `env:"COMPRESS_ENVS" flag:"compress-envs" usage:"Comma-separated environments whose transition blobs are stored zstd-compressed (* for all)"`
+	// PriorityBetaSamples anneals the importance-sampling exponent of
+	// prioritized samples that set no priority_beta from PriorityBetaStart to
+	// 1 over that many of them. Without it their weights are uncorrected.
+	PriorityBetaStart   float32 `env:"PRIORITY_BETA_START" flag:"priority-beta-start" default:"0.4" usage:"Importance-sampling exponent the priority_beta schedule starts from"`
+	PriorityBetaSamples uint64  `env:"PRIORITY_BETA_SAMPLES" flag:"priority-beta-samples" usage:"Prioritized samples over which the default priority_beta anneals to 1 (off when 0)"`
+	// MaxMessageSize bounds the gRPC requests accepted, which limits the size
+	// of a StoreBatch.
+	MaxMessageSize  conf.Size     `env:"MAX_MESSAGE_SIZE" flag:"max-message-size" default:"4MiB" usage:"Largest gRPC request accepted"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" default:"30s" usage:"How long to drain in-flight calls on shutdown"`
+	// FeatureFlags switch risky behaviours on or off, e.g.
+	// prioritized_sampling_v2=true. With OrchestratorAddr and RunID set, the
+	// flags the orchestrator resolves for the run take precedence.
+	FeatureFlags     map[string]bool `env:"FEATURE_FLAGS" flag:"feature-flags" usage:"Comma-separated name=bool feature flags"`
+	OrchestratorAddr string          `env:"ORCHESTRATOR_ADDR" flag:"orchestrator-addr" usage:"Orchestrator base URL to read the run's feature flags from"`
+	RunID            string          `env:"RUN_ID" flag:"run-id" usage:"Run whose feature flags apply"`
+	FlagsRefresh     time.Duration   `env:"FLAGS_REFRESH" flag:"flags-refresh" default:"30s" usage:"How often to re-read feature flags from the orchestrator"`
+	// CaptureFile records sanitized StoreBatch and Sample calls for
+	// cmd/apireplay in the orchestrator, until it holds CaptureMaxSize.
+	CaptureFile    string    `env:"CAPTURE_FILE" flag:"capture-file" usage:"File to record StoreBatch and Sample traffic to (off when unset)"`
+	CaptureMaxSize conf.Size `env:"CAPTURE_MAX_SIZE" flag:"capture-max-size" default:"1GiB" usage:"Size at which traffic capture stops"`
+}
+
+// Load loads the configuration from its defaults, overridden by the YAML file
+// named by -config or CONFIG_FILE, then by environment variables, then by the
+// flags in args, which are parsed with fs.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := &Config{}
+	if err := conf.Load(cfg, conf.WithFlags(fs, args)); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the ports and limits. Errors name settings by their path in
+// the configuration file.
+func (c *Config) Validate() error {
+	var errs conf.Errors
+	if c.Port < 1 || c.Port > 65535 {
+		errs.Add("port", "must be between 1 and 65535")
+	}
+	if c.MetricsPort < 0 || c.MetricsPort > 65535 {
+		errs.Add("metrics_port", "must be between 0 and 65535")
+	}
+	if c.MetricsPort != 0 && c.MetricsPort == c.Port {
+		errs.Add("metrics_port", "must differ from port")
+	}
+	if c.TLSCertFile != "" && c.TLSKeyFile == "" {
+		errs.Add("tls_key_file", "is required with tls_cert_file")
+	}
+	if c.TLSKeyFile != "" && c.TLSCertFile == "" {
+		errs.Add("tls_cert_file", "is required with tls_key_file")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		errs.Add("tls_client_ca_file", "requires tls_cert_file")
+	}
+	for _, token := range c.AuthTokens {
+		if token == "" {
+			errs.Add("auth_tokens", "must not contain empty tokens")
+			break
+		}
+	}
+	if c.MaxSize < 1 {
+		errs.Add("max_size", "must be at least 1")
+	}
+	switch c.Backend {
+	case "memory":
+	case "disk":
+		if c.DataDir == "" {
+			errs.Add("data_dir", "is required with the disk backend")
+		}
+	case "redis":
+		if c.RedisAddr == "" {
+			errs.Add("redis_addr", "is required with the redis backend")
+		}
+		if c.RedisKeyPrefix == "" {
+			errs.Add("redis_key_prefix", "is required with the redis backend")
+		}
+	case "postgres":
+		if c.DatabaseURL == "" {
+			errs.Add("database_url", "is required with the postgres backend")
+		}
+	default:
+		errs.Add("backend", "must be memory, disk, redis or postgres, got %q", c.Backend)
+	}
+	if c.MaxAge < 0 {
+		errs.Add("max_age", "must not be negative")
+	}
+	if c.MaxAge > 0 && c.SweepInterval <= 0 {
+		errs.Add("sweep_interval", "must be positive with max_age")
+	}
+	if !validEviction(c.Eviction) && c.Eviction != "reservoir" {
+		errs.Add("eviction", "must be fifo, lowest_priority, lowest_reward, random or reservoir, got %q", c.Eviction)
+	}
+	for envID, policy := range c.EnvEviction {
+		switch {
+		case policy == "reservoir":
+			errs.Add("env_eviction", "%s: reservoir applies to the whole buffer only", envID)
+		case !validEviction(policy):
+			errs.Add("env_eviction", "%s: must be fifo, lowest_priority, lowest_reward or random, got %q", envID, policy)
+		}
+	}
+	switch {
+	case (c.Eviction != "fifo" || len(c.EnvEviction) > 0) && c.Backend != "memory" && c.Backend != "disk":
+		// The shared backends evict oldest first inside Redis or PostgreSQL.
+		errs.Add("eviction", "requires the memory or disk backend")
+	case c.Eviction == "reservoir" && c.Backend != "memory":
+		// Replaying the disk log would draw the reservoir again, from only
+		// the transitions that survived compaction.
+		errs.Add("eviction", "reservoir requires the memory backend")
+	}
+	if c.PriorityBetaSamples > 0 && (c.PriorityBetaStart <= 0 || c.PriorityBetaStart > 1) {
+		errs.Add("priority_beta_start", "must be in (0, 1]")
+	}
+	if c.Dedup && c.Backend != "memory" && c.Backend != "disk" {
+		errs.Add("dedup", "requires the memory or disk backend")
+	}
+	if c.SnapshotFile != "" && c.Backend != "memory" {
+		errs.Add("snapshot_file", "requires the memory backend")
+	}
+	if c.ArchiveURL != "" {
+		c.validateArchive(&errs)
+	}
+	if c.MaxMessageSize < conf.KiB || c.MaxMessageSize > 2*conf.GiB-1 {
+		errs.Add("max_message_size", "must be between 1KiB and 2GiB")
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs.Add("shutdown_timeout", "must be positive")
+	}
+	if c.OrchestratorAddr != "" && c.RunID == "" {
+		errs.Add("run_id", "is required with orchestrator_addr")
+	}
+	if c.RunID != "" && c.OrchestratorAddr == "" {
+		errs.Add("orchestrator_addr", "is required with run_id")
+	}
+	if c.FlagsRefresh <= 0 {
+		errs.Add("flags_refresh", "must be positive")
+	}
+	if c.CaptureMaxSize < 0 {
+		errs.Add("capture_max_size", "must not be negative")
+	}
+	return errs.Err()
+}
+
+// validEviction reports whether policy names an eviction policy.
+func validEviction(policy string) bool {
+	switch policy {
+	case "fifo", "lowest_priority", "lowest_reward", "random":
+		return true
+	}
+	return false
+}
+
+// validateArchive checks the archive tier's settings.
+func (c *Config) validateArchive(errs *conf.Errors) {
+	u, err := url.Parse(c.ArchiveURL)
+	switch {
+	case err != nil:
+		errs.Add("archive_url", "is invalid: %v", err)
+	case u.Scheme == "s3":
+		if u.Host == "" {
+			errs.Add("archive_url", "must name a bucket")
+		}
+		if c.ArchiveS3AccessKeyID == "" {
+			errs.Add("archive_s3_access_key_id", "is required with an s3 archive_url")
+		}
+		if c.ArchiveS3SecretAccessKey == "" {
+			errs.Add("archive_s3_secret_access_key", "is required with an s3 archive_url")
+		}
+	case u.Scheme == "file":
+		if u.Path == "" {
+			errs.Add("archive_url", "must name a directory")
+		}
+	default:
+		errs.Add("archive_url", "must be an s3:// or file:// URL")
+	}
+	if c.Backend != "memory" && c.Backend != "disk" {
+		// The shared backends evict inside Redis or PostgreSQL, out of sight.
+		errs.Add("archive_url", "requires the memory or disk backend")
+	}
+	if c.ArchiveChunkSize < 1 {
+		errs.Add("archive_chunk_size", "must be at least 1")
+	}
+	if c.ArchiveFlushInterval <= 0 {
+		errs.Add("archive_flush_interval", "must be positive")
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cartridge/conf"
+)
+
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := Load(newFlagSet(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		Port:              8080,
+		MetricsPort:       9090,
+		MaxSize:           100000,
+		Backend:           "memory",
+		Eviction:          "fifo",
+		SweepInterval:     time.Minute,
+		PriorityBetaStart: 0.4,
+		MaxMessageSize:    4 * conf.MiB,
+		ShutdownTimeout:   30 * time.Second,
+		FlagsRefresh:      30 * time.Second,
+		CaptureMaxSize:    conf.GiB,
+		RedisAddr:         "localhost:6379",
+		RedisKeyPrefix:    "replay",
+
+		ArchiveChunkSize:     10000,
+		ArchiveFlushInterval: 5 * time.Minute,
+		ArchiveS3Region:      "us-east-1",
+	}, cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("port: 8081\nmax_size: 500000\nmax_message_size: 16MiB\n"), 0o600))
+	t.Setenv("MAX_SIZE", "250000")
+	t.Setenv("SHUTDOWN_TIMEOUT", "1m")
+	t.Setenv("COMPRESS_ENVS", "atari, pong")
+
+	cfg, err := Load(newFlagSet(), []string{"-config", path, "-max-size", "1000"})
+	require.NoError(t, err)
+	assert.Equal(t, 8081, cfg.Port)
+	assert.Equal(t, uint64(1000), cfg.MaxSize)
+	assert.Equal(t, 16*conf.MiB, cfg.MaxMessageSize)
+	assert.Equal(t, time.Minute, cfg.ShutdownTimeout)
+	assert.Equal(t, []string{"atari", "pong"}, cfg.CompressEnvs)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("METRICS_PORT", "8080")
+	t.Setenv("SNAPSHOT_FILE", "/var/lib/replay/buffer.snapshot")
+	t.Setenv("MAX_AGE", "-1h")
+	_, err := Load(newFlagSet(), []string{"-max-size", "0", "-backend", "disk"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_size: must be at least 1 (from flag -max-size)")
+	assert.Contains(t, err.Error(), "max_age: must not be negative (from env MAX_AGE)")
+	assert.Contains(t, err.Error(), "metrics_port: must differ from port (from env METRICS_PORT)")
+	assert.Contains(t, err.Error(), "data_dir: is required with the disk backend")
+	assert.Contains(t, err.Error(), "snapshot_file: requires the memory backend (from env SNAPSHOT_FILE)")
+}
+
+func TestLoad_FeatureFlags(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("FEATURE_FLAGS", "prioritized_sampling_v2=true, compress_transitions=false")
+	cfg, err := Load(newFlagSet(), []string{"-orchestrator-addr", "http://orchestrator:8080", "-run-id", "run-1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"prioritized_sampling_v2": true, "compress_transitions": false}, cfg.FeatureFlags)
+	assert.Equal(t, "run-1", cfg.RunID)
+
+	_, err = Load(newFlagSet(), []string{"-orchestrator-addr", "http://orchestrator:8080"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "run_id: is required with orchestrator_addr")
+}
+
+func TestLoad_Archive(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := Load(newFlagSet(), []string{"-archive-url", "file:///var/lib/replay/archive", "-archive-chunk-size", "500"})
+	require.NoError(t, err)
+	assert.Equal(t, 500, cfg.ArchiveChunkSize)
+
+	_, err = Load(newFlagSet(), []string{"-archive-url", "s3://bucket/replay", "-backend", "redis"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "archive_s3_access_key_id: is required with an s3 archive_url")
+	assert.Contains(t, err.Error(), "archive_url: requires the memory or disk backend")
+
+	_, err = Load(newFlagSet(), []string{"-archive-url", "gs://bucket"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "archive_url: must be an s3:// or file:// URL")
+}
+
+func TestLoad_Eviction(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("ENV_EVICTION", "atari=lowest_priority, tictactoe=random")
+	cfg, err := Load(newFlagSet(), []string{"-eviction", "lowest_reward"})
+	require.NoError(t, err)
+	assert.Equal(t, "lowest_reward", cfg.Eviction)
+	assert.Equal(t, map[string]string{"atari": "lowest_priority", "tictactoe": "random"}, cfg.EnvEviction)
+
+	t.Setenv("ENV_EVICTION", "atari=newest")
+	t.Setenv("DEDUP", "true")
+	_, err = Load(newFlagSet(), []string{"-eviction", "largest", "-backend", "redis"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `eviction: must be fifo, lowest_priority, lowest_reward, random or reservoir, got "largest"`)
+	assert.Contains(t, err.Error(), `env_eviction: atari: must be fifo, lowest_priority, lowest_reward or random, got "newest"`)
+	assert.Contains(t, err.Error(), "eviction: requires the memory or disk backend")
+	assert.Contains(t, err.Error(), "dedup: requires the memory or disk backend")
+
+	t.Setenv("ENV_EVICTION", "atari=reservoir")
+	t.Setenv("DEDUP", "false")
+	_, err = Load(newFlagSet(), []string{"-eviction", "reservoir", "-backend", "disk", "-data-dir", t.TempDir()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "env_eviction: atari: reservoir applies to the whole buffer only")
+	assert.Contains(t, err.Error(), "eviction: reservoir requires the memory backend")
+}
+
+func TestLoad_PriorityBeta(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("PRIORITY_BETA_SAMPLES", "100000")
+	cfg, err := Load(newFlagSet(), []string{"-priority-beta-start", "0.5"})
+	require.NoError(t, err)
+	assert.Equal(t, float32(0.5), cfg.PriorityBetaStart)
+	assert.Equal(t, uint64(100000), cfg.PriorityBetaSamples)
+
+	_, err = Load(newFlagSet(), []string{"-priority-beta-start", "1.5"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "priority_beta_start: must be in (0, 1]")
+}
+
+func TestLoad_Auth(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("AUTH_TOKENS", "actor-secret,learner-secret")
+	cfg, err := Load(newFlagSet(), []string{"-tls-cert-file", "/etc/replay/tls.crt", "-tls-key-file", "/etc/replay/tls.key"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"actor-secret", "learner-secret"}, cfg.AuthTokens)
+	assert.Equal(t, "/etc/replay/tls.crt", cfg.TLSCertFile)
+
+	t.Setenv("AUTH_TOKENS", "")
+	_, err = Load(newFlagSet(), []string{"-tls-cert-file", "/etc/replay/tls.crt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tls_key_file: is required with tls_cert_file")
+
+	_, err = Load(newFlagSet(), []string{"-tls-client-ca-file", "/etc/replay/ca.crt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tls_client_ca_file: requires tls_cert_file")
+}
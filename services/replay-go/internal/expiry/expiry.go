@@ -0,0 +1,40 @@
+// Package expiry discards transitions from the replay buffer once they reach
+// a maximum age, so algorithms that should not train on stale experience need
+// not call Clear themselves.
+package expiry
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cartridge/replay/internal/storage"
+)
+
+// Expire clears the transitions of backend whose timestamps are more than
+// maxAge before now, returning how many were cleared.
+func Expire(ctx context.Context, backend storage.Backend, maxAge time.Duration, now time.Time) (uint64, error) {
+	cutoff := now.Add(-maxAge)
+	return backend.Clear(ctx, "", &cutoff, 0)
+}
+
+// Sweep expires transitions older than maxAge every interval until ctx is
+// done, logging each sweep that clears any and each failure.
+func Sweep(ctx context.Context, backend storage.Backend, maxAge, interval time.Duration, logger zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cleared, err := Expire(ctx, backend, maxAge, now)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Failed to expire transitions")
+			} else if cleared > 0 {
+				logger.Debug().Uint64("cleared", cleared).Dur("max_age", maxAge).Msg("Expired transitions")
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package expiry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cartridge/replay/internal/storage"
+)
+
+func TestExpire(t *testing.T) {
+	backend := storage.NewMemoryBackend(1000)
+	defer backend.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err := backend.StoreBatch(ctx, []*storage.Transition{
+		{ID: "stale", EnvID: "tictactoe", Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "fresh", EnvID: "tictactoe", Timestamp: now.Add(-time.Minute)},
+	})
+	require.NoError(t, err)
+
+	cleared, err := Expire(ctx, backend, time.Hour, now)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), cleared)
+	stats, err := backend.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stats.TotalTransitions)
+}
+
+func TestSweep(t *testing.T) {
+	backend := storage.NewMemoryBackend(1000)
+	defer backend.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, backend.Store(ctx, &storage.Transition{ID: "a", Timestamp: time.Now().Add(-time.Hour)}))
+
+	done := make(chan struct{})
+	go func() {
+		Sweep(ctx, backend, time.Minute, 10*time.Millisecond, zerolog.Nop())
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		stats, err := backend.GetStats(context.Background(), "")
+		return err == nil && stats.TotalTransitions == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,114 @@
+// Package flags holds the feature flags that gate risky replay behaviours.
+// Flags are read from configuration and, when the replay service serves a run,
+// from the orchestrator, so they can be rolled out per run or experiment
+// without a redeploy.
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// PrioritizedSamplingV2 draws prioritized batches with weighted reservoir
+	// sampling instead of one scan of the buffer per transition drawn.
+	PrioritizedSamplingV2 = "prioritized_sampling_v2"
+	// CompressTransitions gzip-compresses Sample responses for clients that
+	// accept it. Actors compress their StoreBatch calls under the same flag.
+	CompressTransitions = "compress_transitions"
+)
+
+// Set is the feature flags in effect. The orchestrator's value for a flag
+// takes precedence over the configured one. A nil Set has every flag off.
+type Set struct {
+	mu         sync.RWMutex
+	configured map[string]bool
+	remote     map[string]bool
+}
+
+// New returns a Set holding the configured flags.
+func New(configured map[string]bool) *Set {
+	return &Set{configured: maps.Clone(configured)}
+}
+
+// Enabled reports whether the flag called name is on.
+func (s *Set) Enabled(name string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if enabled, ok := s.remote[name]; ok {
+		return enabled
+	}
+	return s.configured[name]
+}
+
+// All returns every flag in effect.
+func (s *Set) All() map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := maps.Clone(s.configured)
+	if all == nil {
+		all = make(map[string]bool, len(s.remote))
+	}
+	maps.Copy(all, s.remote)
+	return all
+}
+
+// Refresh replaces the orchestrator's flags with those it currently resolves
+// for runID. On failure the flags last fetched stay in effect.
+func (s *Set) Refresh(ctx context.Context, client *http.Client, orchestratorAddr, runID string) error {
+	endpoint := strings.TrimRight(orchestratorAddr, "/") + "/api/v1/runs/" + url.PathEscape(runID) + "/flags"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", endpoint, res.Status)
+	}
+	var payload struct {
+		Flags map[string]bool `json:"flags"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("decoding flags for run %s: %w", runID, err)
+	}
+	s.mu.Lock()
+	s.remote = payload.Flags
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch refreshes the flags every interval until ctx is done, logging each
+// change and failure.
+func (s *Set) Watch(ctx context.Context, client *http.Client, orchestratorAddr, runID string, interval time.Duration, logger zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		before := s.All()
+		if err := s.Refresh(ctx, client, orchestratorAddr, runID); err != nil {
+			logger.Warn().Err(err).Str("run_id", runID).Msg("Failed to refresh feature flags")
+		} else if after := s.All(); !maps.Equal(before, after) {
+			logger.Info().Interface("flags", after).Str("run_id", runID).Msg("Feature flags changed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
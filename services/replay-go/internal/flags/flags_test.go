@@ -0,0 +1,39 @@
+package flags
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet_OrchestratorOverridesConfigured(t *testing.T) {
+	var status = http.StatusOK
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/runs/run-1/flags", r.URL.Path)
+		w.WriteHeader(status)
+		w.Write([]byte(`{"run_id": "run-1", "flags": {"prioritized_sampling_v2": false, "compress_transitions": true}}`))
+	}))
+	defer orchestrator.Close()
+
+	set := New(map[string]bool{PrioritizedSamplingV2: true, "other": true})
+	assert.True(t, set.Enabled(PrioritizedSamplingV2))
+	assert.False(t, set.Enabled(CompressTransitions))
+
+	require.NoError(t, set.Refresh(context.Background(), orchestrator.Client(), orchestrator.URL+"/", "run-1"))
+	assert.False(t, set.Enabled(PrioritizedSamplingV2))
+	assert.True(t, set.Enabled(CompressTransitions))
+	assert.True(t, set.Enabled("other"), "flags the orchestrator does not know keep their configured value")
+
+	status = http.StatusNotFound
+	require.Error(t, set.Refresh(context.Background(), orchestrator.Client(), orchestrator.URL, "run-1"))
+	assert.True(t, set.Enabled(CompressTransitions), "a failed refresh keeps the last flags")
+}
+
+func TestSet_NilIsAllOff(t *testing.T) {
+	var set *Set
+	assert.False(t, set.Enabled(PrioritizedSamplingV2))
+}
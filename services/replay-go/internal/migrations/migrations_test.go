@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrdersEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.SQL, "migration %s is empty", m.Name)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version, "migrations out of order")
+		}
+	}
+}
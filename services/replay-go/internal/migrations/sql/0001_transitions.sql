@@ -0,0 +1,35 @@
+-- Transitions of the postgres storage backend. run_id repeats the run_id
+-- metadata so samples and stats can filter on it.
+CREATE TABLE replay_transitions (
+    seq              bigserial PRIMARY KEY,
+    id               text NOT NULL UNIQUE,
+    env_id           text NOT NULL DEFAULT '',
+    episode_id       text NOT NULL DEFAULT '',
+    run_id           text NOT NULL DEFAULT '',
+    step_number      bigint NOT NULL DEFAULT 0,
+    state            bytea,
+    action           bytea,
+    next_state       bytea,
+    observation      bytea,
+    next_observation bytea,
+    reward           real NOT NULL DEFAULT 0,
+    done             boolean NOT NULL DEFAULT false,
+    priority         real NOT NULL DEFAULT 1,
+    ts               timestamptz NOT NULL,
+    metadata         jsonb NOT NULL DEFAULT '{}'
+);
+
+-- Eviction and exports walk transitions oldest first; ties keep insertion order.
+CREATE INDEX replay_transitions_ts_idx ON replay_transitions (ts, seq);
+CREATE INDEX replay_transitions_env_ts_idx ON replay_transitions (env_id, ts);
+CREATE INDEX replay_transitions_run_idx ON replay_transitions (run_id) WHERE run_id <> '';
+CREATE INDEX replay_transitions_episode_idx ON replay_transitions (episode_id) WHERE episode_id <> '';
+
+-- The number of stored transitions, kept by the backend so that eviction does
+-- not count the table. Writers lock the row, which orders them across replicas.
+CREATE TABLE replay_buffer (
+    singleton   boolean PRIMARY KEY DEFAULT true CHECK (singleton),
+    transitions bigint NOT NULL DEFAULT 0
+);
+
+INSERT INTO replay_buffer (singleton, transitions) VALUES (true, 0);
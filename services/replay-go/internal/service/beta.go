@@ -0,0 +1,22 @@
+package service
+
+import "sync/atomic"
+
+// BetaSchedule anneals the importance-sampling exponent of prioritized
+// samples that set no priority_beta, linearly from Start to 1 over the first
+// Samples of them the server serves.
+type BetaSchedule struct {
+	Start   float32
+	Samples uint64
+
+	served atomic.Uint64
+}
+
+// next returns the exponent for the next prioritized sample.
+func (b *BetaSchedule) next() float32 {
+	n := b.served.Add(1) - 1
+	if n >= b.Samples {
+		return 1
+	}
+	return b.Start + (1-b.Start)*float32(n)/float32(b.Samples)
+}
@@ -2,36 +2,91 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/status"
 
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/proto/rpcerr"
+	"github.com/cartridge/proto/runctx"
+	"github.com/cartridge/replay/internal/archive"
+	"github.com/cartridge/replay/internal/flags"
 	"github.com/cartridge/replay/internal/storage"
-	replayv1 "github.com/cartridge/replay/pkg/proto/replay/v1"
+	"github.com/cartridge/replay/internal/watch"
+	"github.com/cartridge/telemetry/buildinfo"
 )
 
+// noTransitionsRetryDelay is how long Sample asks callers to wait when the
+// buffer holds nothing to sample.
+const noTransitionsRetryDelay = time.Second
+
+// defaultExportBatch is how many transitions each Export response carries
+// when the request does not say.
+const defaultExportBatch = 256
+
 // ReplayService implements the Replay gRPC service
 type ReplayService struct {
 	replayv1.UnimplementedReplayServer
-	backend storage.Backend
+	backend  storage.Backend
+	flags    *flags.Set
+	archive  *archive.Reader
+	watchers *watch.Hub
+	beta     *BetaSchedule
+
+	stopOnce sync.Once
+	stopping chan struct{} // Closed by Stop to end SampleStream and Watch calls
 }
 
 // NewReplayService creates a new ReplayService
 func NewReplayService(backend storage.Backend) *ReplayService {
 	return &ReplayService{
-		backend: backend,
+		backend:  backend,
+		watchers: watch.NewHub(),
+		stopping: make(chan struct{}),
 	}
 }
 
+// Stop ends the SampleStream and Watch calls in progress, and any made later,
+// with UNAVAILABLE, so a graceful server stop need not wait for streams that
+// only end when their clients cancel them.
+func (s *ReplayService) Stop() {
+	s.stopOnce.Do(func() { close(s.stopping) })
+}
+
+// WithFlags gates the service's risky behaviours on set.
+func (s *ReplayService) WithFlags(set *flags.Set) *ReplayService {
+	s.flags = set
+	return s
+}
+
+// WithArchive serves archived Sample and Export requests from reader.
+func (s *ReplayService) WithArchive(reader *archive.Reader) *ReplayService {
+	s.archive = reader
+	return s
+}
+
+// WithBetaSchedule anneals the importance-sampling weights of prioritized
+// samples that set no priority_beta by schedule. Without one, their weights
+// are the uncorrected 1/(N*p).
+func (s *ReplayService) WithBetaSchedule(schedule *BetaSchedule) *ReplayService {
+	s.beta = schedule
+	return s
+}
+
 // StoreTransition stores a single transition
 func (s *ReplayService) StoreTransition(ctx context.Context, req *replayv1.StoreTransitionRequest) (*replayv1.StoreTransitionResponse, error) {
 	if req.Transition == nil {
-		return nil, status.Error(codes.InvalidArgument, "transition is required")
+		return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "transition is required")
 	}
 
 	// Convert proto transition to storage transition
 	transition := protoToStorageTransition(req.Transition)
+	transition.Metadata = runctx.FromIncomingContext(ctx).Stamp(transition.Metadata)
 
 	// Store the transition
 	if err := s.backend.Store(ctx, transition); err != nil {
@@ -40,6 +95,9 @@ func (s *ReplayService) StoreTransition(ctx context.Context, req *replayv1.Store
 			ErrorMessage: err.Error(),
 		}, nil
 	}
+	if !transition.Duplicate {
+		s.watchers.Publish([]*storage.Transition{transition})
+	}
 
 	return &replayv1.StoreTransitionResponse{
 		TransitionId: transition.ID,
@@ -57,13 +115,16 @@ func (s *ReplayService) StoreBatch(ctx context.Context, req *replayv1.StoreBatch
 	}
 
 	// Convert proto transitions to storage transitions
+	run := runctx.FromIncomingContext(ctx)
 	transitions := make([]*storage.Transition, len(req.Transitions))
 	for i, protoTransition := range req.Transitions {
 		transitions[i] = protoToStorageTransition(protoTransition)
+		transitions[i].Metadata = run.Stamp(transitions[i].Metadata)
 	}
 
 	// Store the batch
 	ids, err := s.backend.StoreBatch(ctx, transitions)
+	s.watchers.Publish(newlyStored(transitions[:len(ids)]))
 	if err != nil {
 		return &replayv1.StoreBatchResponse{
 			StoredCount:    uint32(len(ids)),
@@ -80,24 +141,245 @@ func (s *ReplayService) StoreBatch(ctx context.Context, req *replayv1.StoreBatch
 	}, nil
 }
 
+// newlyStored returns the transitions that dedup did not drop, so watchers
+// see each stored transition once.
+func newlyStored(transitions []*storage.Transition) []*storage.Transition {
+	stored := make([]*storage.Transition, 0, len(transitions))
+	for _, transition := range transitions {
+		if !transition.Duplicate {
+			stored = append(stored, transition)
+		}
+	}
+	return stored
+}
+
 // Sample samples transitions for training
 func (s *ReplayService) Sample(ctx context.Context, req *replayv1.SampleRequest) (*replayv1.SampleResponse, error) {
 	if req.Config == nil {
-		return nil, status.Error(codes.InvalidArgument, "sample config is required")
+		return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "sample config is required")
 	}
+	if s.flags.Enabled(flags.CompressTransitions) {
+		compressResponse(ctx)
+	}
+	return s.sample(ctx, req.Config)
+}
 
-	// Convert proto config to storage config
-	config := protoToStorageConfig(req.Config)
+// SampleStream sends sampled batches until max_batches have been sent or the
+// learner cancels, at most batches_per_second of them a second. Sends block
+// while the learner's flow-control window is full, so it prefetches as many
+// batches as its window holds. While the buffer is empty the stream waits
+// for transitions rather than failing.
+func (s *ReplayService) SampleStream(req *replayv1.SampleStreamRequest, stream replayv1.Replay_SampleStreamServer) error {
+	if req.Config == nil {
+		return rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "sample config is required")
+	}
+	if req.BatchesPerSecond < 0 {
+		return rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "batches_per_second must not be negative")
+	}
+	ctx := stream.Context()
+	if s.flags.Enabled(flags.CompressTransitions) {
+		compressResponse(ctx)
+	}
+
+	var tick <-chan time.Time
+	if req.BatchesPerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / float64(req.BatchesPerSecond)))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	wait := func(next <-chan time.Time) error {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.stopping:
+			return errStopping()
+		case <-next:
+			return nil
+		}
+	}
+
+	for sent := uint32(0); req.MaxBatches == 0 || sent < req.MaxBatches; {
+		select {
+		case <-s.stopping:
+			return errStopping()
+		default:
+		}
+		response, err := s.sample(ctx, req.Config)
+		if err != nil && rpcerr.Parse(err).Reason == rpcerr.NoTransitions {
+			if err := wait(time.After(noTransitionsRetryDelay)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+		sent++
+		if tick != nil && (req.MaxBatches == 0 || sent < req.MaxBatches) {
+			if err := wait(tick); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetEpisode returns an episode's stored transitions in step order, so tools
+// can reconstruct whole games. Transitions already evicted are missing.
+func (s *ReplayService) GetEpisode(ctx context.Context, req *replayv1.GetEpisodeRequest) (*replayv1.GetEpisodeResponse, error) {
+	if req.EpisodeId == "" {
+		return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "episode_id is required")
+	}
+	episode, err := s.backend.GetEpisode(ctx, req.EpisodeId)
+	if err != nil {
+		return nil, rpcerr.New(codes.Internal, rpcerr.StorageFailure, err.Error())
+	}
+	if len(episode) == 0 {
+		return nil, rpcerr.New(codes.NotFound, rpcerr.NotFound, "no transitions of episode "+req.EpisodeId+" are stored")
+	}
+	if s.flags.Enabled(flags.CompressTransitions) {
+		compressResponse(ctx)
+	}
+
+	transitions := make([]*replayv1.Transition, len(episode))
+	for i, transition := range episode {
+		transitions[i] = storageToProtoTransition(transition)
+	}
+	return &replayv1.GetEpisodeResponse{Transitions: transitions}, nil
+}
+
+// Watch sends the transitions stored through this server from now on that
+// match the request's filter. Headers are sent once the watch is in place,
+// so a client that waits for them misses nothing stored afterwards. A
+// watcher that falls behind misses events rather than holding up writers,
+// and is told how many transitions it missed.
+func (s *ReplayService) Watch(req *replayv1.WatchRequest, stream replayv1.Replay_WatchServer) error {
+	watcher := s.watchers.Subscribe(watch.Filter{EnvID: req.EnvId, RunID: req.RunId})
+	defer s.watchers.Unsubscribe(watcher)
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.stopping:
+			return errStopping()
+		case event := <-watcher.Events():
+			response := &replayv1.WatchEvent{
+				TransitionIds: make([]string, len(event.Transitions)),
+				Dropped:       event.Dropped,
+			}
+			for i, transition := range event.Transitions {
+				response.TransitionIds[i] = transition.ID
+				if req.IncludeTransitions {
+					response.Transitions = append(response.Transitions, storageToProtoTransition(transition))
+				}
+			}
+			if err := stream.Send(response); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// errStopping is returned by streams ended by Stop. Clients should reconnect,
+// to another replica if there is one.
+func errStopping() error {
+	return rpcerr.New(codes.Unavailable, rpcerr.Unavailable, "replay service is shutting down")
+}
+
+// sample draws one batch for Sample or SampleStream.
+func (s *ReplayService) sample(ctx context.Context, sampleConfig *replayv1.SampleConfig) (*replayv1.SampleResponse, error) {
+	if sampleConfig.Archived {
+		return s.sampleArchive(ctx, sampleConfig)
+	}
+	if sampleConfig.SequenceLength > 1 && sampleConfig.NStep > 1 {
+		return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "sequence_length and n_step cannot be combined")
+	}
+	if sampleConfig.NStep > 1 && (sampleConfig.Gamma <= 0 || sampleConfig.Gamma > 1) {
+		return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "gamma must be in (0, 1] with n_step")
+	}
+	if sampleConfig.PriorityBeta < 0 || sampleConfig.PriorityBeta > 1 {
+		return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "priority_beta must be in [0, 1]")
+	}
+	var strata []storage.Stratum
+	if sampleConfig.TerminalFraction != 0 || sampleConfig.WinningFraction != 0 {
+		terminal, winning := sampleConfig.TerminalFraction, sampleConfig.WinningFraction
+		if terminal < 0 || winning < 0 || terminal+winning > 1 {
+			return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "terminal_fraction and winning_fraction must not be negative or sum to more than 1")
+		}
+		if sampleConfig.SequenceLength > 1 || sampleConfig.NStep > 1 {
+			return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "stratified samples cannot be combined with sequence_length or n_step")
+		}
+		strata = []storage.Stratum{{Fraction: terminal, Terminal: true}, {Fraction: winning, Winning: true}}
+	}
 
-	// Sample transitions
-	transitions, weights, err := s.backend.Sample(ctx, config)
+	// Convert proto config to storage config
+	config := protoToStorageConfig(sampleConfig)
+	config.Reservoir = s.flags.Enabled(flags.PrioritizedSamplingV2)
+
+	// Sample transitions, or windows of them flattened into one batch
+	var transitions []*storage.Transition
+	var weights []float32
+	var mask []bool
+	var returns []float32
+	var returnSteps []uint32
+	var err error
+	if length := int(sampleConfig.SequenceLength); length > 1 {
+		var windows [][]*storage.Transition
+		windows, weights, err = storage.SampleSequences(ctx, s.backend, config, length)
+		for _, window := range windows {
+			for _, transition := range window {
+				transitions = append(transitions, transition)
+				mask = append(mask, transition != nil)
+			}
+		}
+	} else if n := int(sampleConfig.NStep); n > 1 {
+		var windows [][]*storage.Transition
+		windows, weights, err = storage.SampleSequences(ctx, s.backend, config, n)
+		for _, window := range windows {
+			transition, sum, steps := storage.NStepReturn(window, sampleConfig.Gamma)
+			transitions = append(transitions, transition)
+			returns = append(returns, sum)
+			returnSteps = append(returnSteps, steps)
+		}
+	} else if strata != nil {
+		transitions, weights, err = storage.SampleStratified(ctx, s.backend, config, strata)
+	} else {
+		transitions, weights, err = s.backend.Sample(ctx, config)
+	}
+	if errors.Is(err, storage.ErrNoTransitions) {
+		// Actors fill the buffer over time, so the learner should retry.
+		return nil, rpcerr.New(codes.FailedPrecondition, rpcerr.NoTransitions, err.Error(), rpcerr.RetryAfter(noTransitionsRetryDelay))
+	}
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, rpcerr.New(codes.Internal, rpcerr.StorageFailure, err.Error())
+	}
+
+	if config.Prioritized {
+		beta := sampleConfig.PriorityBeta
+		if beta == 0 && s.beta != nil {
+			beta = s.beta.next()
+		}
+		if beta > 0 {
+			weights = storage.ImportanceWeights(weights, beta)
+		}
 	}
 
 	// Convert storage transitions to proto transitions
 	protoTransitions := make([]*replayv1.Transition, len(transitions))
 	for i, transition := range transitions {
+		if transition == nil {
+			// Padding past the end of an episode
+			protoTransitions[i] = &replayv1.Transition{}
+			continue
+		}
 		protoTransitions[i] = storageToProtoTransition(transition)
 	}
 
@@ -105,7 +387,9 @@ func (s *ReplayService) Sample(ctx context.Context, req *replayv1.SampleRequest)
 	stats, _ := s.backend.GetStats(ctx, config.EnvID)
 	totalAvailable := uint32(0)
 	if stats != nil {
-		if config.EnvID != "" {
+		if config.RunID != "" {
+			totalAvailable = uint32(stats.TransitionsByRun[config.RunID])
+		} else if config.EnvID != "" {
 			if count, exists := stats.TransitionsByEnv[config.EnvID]; exists {
 				totalAvailable = uint32(count)
 			}
@@ -118,6 +402,41 @@ func (s *ReplayService) Sample(ctx context.Context, req *replayv1.SampleRequest)
 		Transitions:    protoTransitions,
 		TotalAvailable: totalAvailable,
 		Weights:        weights,
+		Mask:           mask,
+		Returns:        returns,
+		ReturnSteps:    returnSteps,
+	}, nil
+}
+
+// sampleArchive draws a uniform batch from the archive tier, whose chunks
+// are indexed only by environment.
+func (s *ReplayService) sampleArchive(ctx context.Context, config *replayv1.SampleConfig) (*replayv1.SampleResponse, error) {
+	if s.archive == nil {
+		return nil, rpcerr.New(codes.FailedPrecondition, rpcerr.InvalidRequest, "no archive is configured")
+	}
+	if config.Prioritized || config.RunId != "" || config.MinTimestamp != 0 || config.MaxTimestamp != 0 || config.SequenceLength > 1 || config.NStep > 1 || config.TerminalFraction != 0 || config.WinningFraction != 0 {
+		return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "archived samples support only env_id and batch_size")
+	}
+
+	transitions, total, err := s.archive.Sample(ctx, config.EnvId, int(config.BatchSize))
+	if errors.Is(err, storage.ErrNoTransitions) {
+		return nil, rpcerr.New(codes.FailedPrecondition, rpcerr.NoTransitions, err.Error(), rpcerr.RetryAfter(noTransitionsRetryDelay))
+	}
+	if err != nil {
+		return nil, rpcerr.New(codes.Internal, rpcerr.StorageFailure, err.Error())
+	}
+
+	protoTransitions := make([]*replayv1.Transition, len(transitions))
+	weights := make([]float32, len(transitions))
+	for i, transition := range transitions {
+		// Evicted transitions are archived as the backend held them.
+		protoTransitions[i] = storageToProtoTransition(storage.Decompressed(transition))
+		weights[i] = 1
+	}
+	return &replayv1.SampleResponse{
+		Transitions:    protoTransitions,
+		TotalAvailable: uint32(total),
+		Weights:        weights,
 	}, nil
 }
 
@@ -125,7 +444,7 @@ func (s *ReplayService) Sample(ctx context.Context, req *replayv1.SampleRequest)
 func (s *ReplayService) GetStats(ctx context.Context, req *replayv1.GetStatsRequest) (*replayv1.StatsResponse, error) {
 	stats, err := s.backend.GetStats(ctx, req.EnvId)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, rpcerr.New(codes.Internal, rpcerr.StorageFailure, err.Error())
 	}
 
 	response := &replayv1.StatsResponse{
@@ -133,6 +452,7 @@ func (s *ReplayService) GetStats(ctx context.Context, req *replayv1.GetStatsRequ
 		TotalEpisodes:     stats.TotalEpisodes,
 		TransitionsByEnv:  stats.TransitionsByEnv,
 		StorageBytes:      stats.StorageBytes,
+		TransitionsByRun:  stats.TransitionsByRun,
 	}
 
 	if stats.OldestTimestamp != nil {
@@ -148,7 +468,7 @@ func (s *ReplayService) GetStats(ctx context.Context, req *replayv1.GetStatsRequ
 // UpdatePriorities updates transition priorities for prioritized replay
 func (s *ReplayService) UpdatePriorities(ctx context.Context, req *replayv1.UpdatePrioritiesRequest) (*replayv1.UpdatePrioritiesResponse, error) {
 	if len(req.TransitionIds) != len(req.NewPriorities) {
-		return nil, status.Error(codes.InvalidArgument, "transition IDs and priorities must have same length")
+		return nil, rpcerr.New(codes.InvalidArgument, rpcerr.InvalidRequest, "transition IDs and priorities must have same length")
 	}
 
 	err := s.backend.UpdatePriorities(ctx, req.TransitionIds, req.NewPriorities)
@@ -174,7 +494,7 @@ func (s *ReplayService) Clear(ctx context.Context, req *replayv1.ClearRequest) (
 
 	clearedCount, err := s.backend.Clear(ctx, req.EnvId, beforeTimestamp, req.KeepLastN)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, rpcerr.New(codes.Internal, rpcerr.StorageFailure, err.Error())
 	}
 
 	// Get remaining count
@@ -196,8 +516,88 @@ func (s *ReplayService) Clear(ctx context.Context, req *replayv1.ClearRequest) (
 	}, nil
 }
 
+// Version reports the build the service is running
+func (s *ReplayService) Version(ctx context.Context, req *replayv1.VersionRequest) (*replayv1.VersionResponse, error) {
+	info := buildinfo.Get("replay")
+	return &replayv1.VersionResponse{
+		Service:   info.Service,
+		Version:   info.Version,
+		Commit:    info.Commit,
+		BuildTime: info.BuildTime,
+	}, nil
+}
+
+// Export streams the stored transitions, or the archived ones, oldest first,
+// in batches of req.BatchSize.
+func (s *ReplayService) Export(req *replayv1.ExportRequest, stream replayv1.Replay_ExportServer) error {
+	export := s.backend.Export
+	if req.Archived {
+		if s.archive == nil {
+			return rpcerr.New(codes.FailedPrecondition, rpcerr.InvalidRequest, "no archive is configured")
+		}
+		export = func(ctx context.Context, envID string, fn func(*storage.Transition) error) error {
+			// Evicted transitions are archived as the backend held them.
+			return s.archive.Export(ctx, envID, func(transition *storage.Transition) error {
+				return fn(storage.Decompressed(transition))
+			})
+		}
+	}
+	batchSize := int(req.BatchSize)
+	if batchSize == 0 {
+		batchSize = defaultExportBatch
+	}
+	batch := make([]*replayv1.Transition, 0, batchSize)
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := stream.Send(&replayv1.ExportResponse{Transitions: batch})
+		batch = make([]*replayv1.Transition, 0, batchSize)
+		return err
+	}
+	err := export(stream.Context(), req.EnvId, func(transition *storage.Transition) error {
+		if ts := uint64(transition.Timestamp.Unix()); ts < req.MinTimestamp || (req.MaxTimestamp > 0 && ts > req.MaxTimestamp) {
+			return nil
+		}
+		batch = append(batch, storageToProtoTransition(transition))
+		if len(batch) < batchSize {
+			return nil
+		}
+		return send()
+	})
+	if err == nil {
+		err = send()
+	}
+	if err == nil {
+		return nil
+	}
+	if ctxErr := stream.Context().Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		// Sending failed; the stream's status says why.
+		return err
+	}
+	return rpcerr.New(codes.Internal, rpcerr.StorageFailure, err.Error())
+}
+
 // Conversion functions
 
+// compressResponse gzips the response when the client accepts gzip; other
+// clients get it uncompressed.
+func compressResponse(ctx context.Context) {
+	accepted, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil {
+		return
+	}
+	for _, name := range accepted {
+		if name == gzip.Name {
+			_ = grpc.SetSendCompressor(ctx, gzip.Name)
+			return
+		}
+	}
+}
+
 func protoToStorageTransition(proto *replayv1.Transition) *storage.Transition {
 	transition := &storage.Transition{
 		ID:              proto.Id,
@@ -247,6 +647,7 @@ func protoToStorageConfig(proto *replayv1.SampleConfig) *storage.SampleConfig {
 		EnvID:         proto.EnvId,
 		Prioritized:   proto.Prioritized,
 		PriorityAlpha: proto.PriorityAlpha,
+		RunID:         proto.RunId,
 	}
 
 	if proto.MinTimestamp > 0 {
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdMagic starts every zstd frame. Blobs that start with it are taken to
+// be compressed.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// The encoder and decoder are safe for concurrent EncodeAll and DecodeAll
+// calls, so one of each serves every backend.
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+		return dec
+	})
+)
+
+// WithCompression returns backend with the State, Observation, NextState and
+// NextObservation of the transitions of envIDs compressed with zstd as they
+// are stored, and decompressed as they are read back, so callers only ever
+// see the original bytes. "*" compresses every environment's transitions. No
+// envIDs returns backend unchanged.
+func WithCompression(backend Backend, envIDs []string) Backend {
+	if len(envIDs) == 0 {
+		return backend
+	}
+	envs := make(map[string]bool, len(envIDs))
+	for _, envID := range envIDs {
+		envs[envID] = true
+	}
+	return &compressingBackend{Backend: backend, envs: envs}
+}
+
+type compressingBackend struct {
+	Backend
+	envs map[string]bool
+}
+
+// Store implements Backend.Store
+func (c *compressingBackend) Store(ctx context.Context, transition *Transition) error {
+	_, err := c.StoreBatch(ctx, []*Transition{transition})
+	return err
+}
+
+// StoreBatch implements Backend.StoreBatch. The transitions stored are
+// compressed copies; the ID, timestamp and priority the backend fills in, and
+// whether it dropped them as duplicates, are copied back to the caller's.
+func (c *compressingBackend) StoreBatch(ctx context.Context, transitions []*Transition) ([]string, error) {
+	stored := make([]*Transition, len(transitions))
+	for i, transition := range transitions {
+		copied := *transition
+		if c.envs["*"] || c.envs[transition.EnvID] {
+			enc := zstdEncoder()
+			copied.State = compressBlob(enc, copied.State)
+			copied.Observation = compressBlob(enc, copied.Observation)
+			copied.NextState = compressBlob(enc, copied.NextState)
+			copied.NextObservation = compressBlob(enc, copied.NextObservation)
+		}
+		stored[i] = &copied
+	}
+	ids, err := c.Backend.StoreBatch(ctx, stored)
+	for i := range ids {
+		transitions[i].ID = stored[i].ID
+		transitions[i].Timestamp = stored[i].Timestamp
+		transitions[i].Priority = stored[i].Priority
+		transitions[i].Duplicate = stored[i].Duplicate
+	}
+	return ids, err
+}
+
+// Sample implements Backend.Sample
+func (c *compressingBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Transition, []float32, error) {
+	transitions, weights, err := c.Backend.Sample(ctx, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decompressAll(transitions), weights, nil
+}
+
+// Export implements Backend.Export
+func (c *compressingBackend) Export(ctx context.Context, envID string, fn func(*Transition) error) error {
+	return c.Backend.Export(ctx, envID, func(transition *Transition) error {
+		return fn(Decompressed(transition))
+	})
+}
+
+// GetEpisode implements Backend.GetEpisode
+func (c *compressingBackend) GetEpisode(ctx context.Context, episodeID string) ([]*Transition, error) {
+	episode, err := c.Backend.GetEpisode(ctx, episodeID)
+	if err != nil {
+		return nil, err
+	}
+	return decompressAll(episode), nil
+}
+
+// Decompressed returns transition with the blobs WithCompression compressed
+// restored, copying it if any were. Transitions read around the wrapper, e.g.
+// from the archive tier, pass through it.
+func Decompressed(transition *Transition) *Transition {
+	if !isCompressed(transition.State) && !isCompressed(transition.Observation) &&
+		!isCompressed(transition.NextState) && !isCompressed(transition.NextObservation) {
+		return transition
+	}
+	// Backends may return the transitions they hold, which must stay compressed.
+	copied := *transition
+	dec := zstdDecoder()
+	copied.State = decompressBlob(dec, copied.State)
+	copied.Observation = decompressBlob(dec, copied.Observation)
+	copied.NextState = decompressBlob(dec, copied.NextState)
+	copied.NextObservation = decompressBlob(dec, copied.NextObservation)
+	return &copied
+}
+
+func decompressAll(transitions []*Transition) []*Transition {
+	for i, transition := range transitions {
+		transitions[i] = Decompressed(transition)
+	}
+	return transitions
+}
+
+// compressBlob compresses blob, unless compression would not shrink it.
+func compressBlob(enc *zstd.Encoder, blob []byte) []byte {
+	if len(blob) == 0 {
+		return blob
+	}
+	compressed := enc.EncodeAll(blob, make([]byte, 0, len(blob)))
+	if len(compressed) >= len(blob) && !isCompressed(blob) {
+		return blob
+	}
+	return compressed
+}
+
+// decompressBlob undoes compressBlob. A blob that starts like a zstd frame
+// but does not decode was stored as it is.
+func decompressBlob(dec *zstd.Decoder, blob []byte) []byte {
+	if !isCompressed(blob) {
+		return blob
+	}
+	decompressed, err := dec.DecodeAll(blob, nil)
+	if err != nil {
+		return blob
+	}
+	return decompressed
+}
+
+func isCompressed(blob []byte) bool {
+	return bytes.HasPrefix(blob, zstdMagic)
+}
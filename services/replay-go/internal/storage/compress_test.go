@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCompression(t *testing.T) {
+	memory := NewMemoryBackend(1000)
+	defer memory.Close()
+	backend := WithCompression(memory, []string{"atari"})
+
+	ctx := context.Background()
+	frame := bytes.Repeat([]byte{0, 1, 2, 3}, 84*84)
+	atari := &Transition{EnvID: "atari", EpisodeID: "ep1", State: []byte{1}, Observation: frame, NextObservation: frame}
+	tictactoe := &Transition{EnvID: "tictactoe", Observation: frame}
+	// A blob that merely looks like a zstd frame survives either way.
+	lookalike := append(append([]byte{}, zstdMagic...), 1, 2, 3)
+	mimic := &Transition{EnvID: "atari", EpisodeID: "ep1", StepNumber: 1, State: lookalike}
+	ids, err := backend.StoreBatch(ctx, []*Transition{atari, tictactoe, mimic})
+	require.NoError(t, err)
+	require.Len(t, ids, 3)
+
+	// The caller's transitions keep their bytes and learn their IDs.
+	assert.Equal(t, ids[0], atari.ID)
+	assert.Equal(t, frame, atari.Observation)
+
+	// Only the configured environment's blobs are held compressed.
+	held := memory.transitions[atari.ID]
+	assert.Less(t, len(held.Observation), len(frame)/10)
+	assert.Equal(t, []byte{1}, held.State, "blobs that would grow are kept as they are")
+	assert.Equal(t, frame, memory.transitions[tictactoe.ID].Observation)
+
+	sampled, _, err := backend.Sample(ctx, &SampleConfig{BatchSize: 3})
+	require.NoError(t, err)
+	for _, transition := range sampled {
+		if transition.EnvID == "atari" && transition.StepNumber == 0 {
+			assert.Equal(t, frame, transition.Observation)
+			assert.Equal(t, frame, transition.NextObservation)
+		}
+	}
+	assert.Less(t, len(memory.transitions[atari.ID].Observation), len(frame)/10, "reads leave the held copy compressed")
+
+	episode, err := backend.GetEpisode(ctx, "ep1")
+	require.NoError(t, err)
+	require.Len(t, episode, 2)
+	assert.Equal(t, frame, episode[0].Observation)
+	assert.Equal(t, lookalike, episode[1].State)
+
+	var exported int
+	require.NoError(t, backend.Export(ctx, "atari", func(transition *Transition) error {
+		exported++
+		assert.Equal(t, Decompressed(transition), transition)
+		return nil
+	}))
+	assert.Equal(t, 2, exported)
+
+	assert.Same(t, memory, WithCompression(memory, nil))
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+)
+
+// contentHash identifies a transition by its environment, state, action and
+// next state, which actors resubmitting a batch reproduce exactly.
+type contentHash [sha256.Size]byte
+
+func hashContent(transition *Transition) contentHash {
+	h := sha256.New()
+	writeField(h, []byte(transition.EnvID))
+	writeField(h, transition.State)
+	writeField(h, transition.Action)
+	writeField(h, transition.NextState)
+	var sum contentHash
+	h.Sum(sum[:0])
+	return sum
+}
+
+// writeField writes field length-prefixed, so adjacent fields cannot run
+// into each other.
+func writeField(h hash.Hash, field []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+	h.Write(length[:])
+	h.Write(field)
+}
+
+// SetDedup turns deduplication on or off. With it, a stored transition whose
+// environment, state, action and next state match one already in the buffer
+// is dropped, and its ID becomes the stored transition's, so actors that
+// resubmit overlapping batches after a restart do not skew sampling toward
+// the overlap.
+func (m *MemoryBackend) SetDedup(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hashes = nil
+	if !enabled {
+		return
+	}
+	m.hashes = make(map[contentHash]string, len(m.transitions))
+	for _, id := range m.timeIndex {
+		m.hashes[hashContent(m.transitions[id])] = id
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBackend_Dedup(t *testing.T) {
+	backend := NewMemoryBackend(2)
+	defer backend.Close()
+
+	ctx := context.Background()
+	require.NoError(t, backend.Store(ctx, &Transition{ID: "a", EnvID: "tictactoe", State: []byte{1}, Action: []byte{4}}))
+	backend.SetDedup(true)
+
+	// A restarted actor resubmits a and a new transition, in one batch with a
+	// repeat of the new one.
+	batch := []*Transition{
+		{EnvID: "tictactoe", State: []byte{1}, Action: []byte{4}, Reward: 1},
+		{ID: "b", EnvID: "tictactoe", State: []byte{1}, Action: []byte{5}},
+		{ID: "b-again", EnvID: "tictactoe", State: []byte{1}, Action: []byte{5}},
+	}
+	ids, err := backend.StoreBatch(ctx, batch)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "b"}, ids)
+	assert.Equal(t, []bool{true, false, true}, []bool{batch[0].Duplicate, batch[1].Duplicate, batch[2].Duplicate})
+	assert.Equal(t, 2, backend.len())
+
+	// The same content in another environment, or with fields shifted
+	// between state and action, is new.
+	hash := hashContent(&Transition{EnvID: "tictactoe", State: []byte{1}, Action: []byte{4}})
+	assert.NotEqual(t, hash, hashContent(&Transition{EnvID: "gridworld", State: []byte{1}, Action: []byte{4}}))
+	assert.NotEqual(t, hash, hashContent(&Transition{EnvID: "tictactoe", State: []byte{1, 4}}))
+
+	// Once evicted, a transition may be stored again.
+	require.NoError(t, backend.Store(ctx, &Transition{ID: "c", EnvID: "tictactoe", State: []byte{2}}))
+	assert.NotContains(t, backend.hashes, hash)
+	again := &Transition{ID: "a2", EnvID: "tictactoe", State: []byte{1}, Action: []byte{4}}
+	require.NoError(t, backend.Store(ctx, again))
+	assert.Equal(t, "a2", again.ID)
+}
+
+func TestDiskBackend_DedupNotLogged(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskOptions{Dir: dir, MaxSize: 100, Dedup: true}
+	backend, err := OpenDiskBackend(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, backend.Store(ctx, &Transition{ID: "a", EnvID: "tictactoe", State: []byte{1}}))
+	}
+	require.NoError(t, backend.Close())
+
+	// Without dedup on replay, a logged duplicate would be stored again.
+	opts.Dedup = false
+	reopened, err := OpenDiskBackend(opts)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, []string{"a"}, exportIDs(t, reopened))
+}
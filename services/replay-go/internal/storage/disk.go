@@ -0,0 +1,496 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSegmentSize is the size at which the disk backend starts a new
+// segment file.
+const DefaultSegmentSize = 64 << 20
+
+// segmentExt names the disk backend's segment files, which are numbered in the
+// order they were written.
+const segmentExt = ".seg"
+
+// compactMinRecords is how many records the log may hold beyond twice the live
+// transitions before it is compacted.
+const compactMinRecords = 1024
+
+// Operations recorded in the disk backend's log.
+const (
+	opStore      = "store"
+	opPriorities = "priorities"
+	opClear      = "clear"
+	// opReset starts a snapshot: the records before it no longer apply.
+	opReset = "reset"
+)
+
+// DiskOptions configures a DiskBackend.
+type DiskOptions struct {
+	// Dir holds the segment files. It is created if it does not exist.
+	Dir string
+	// MaxSize is the number of transitions kept before the oldest are
+	// evicted, as for NewMemoryBackend.
+	MaxSize uint64
+	// SegmentSize is the size at which a new segment file is started;
+	// DefaultSegmentSize when zero.
+	SegmentSize int64
+	// Eviction and EnvEviction choose the transitions evicted once the
+	// buffer is full, as for MemoryBackend.SetEvictionPolicy; the oldest
+	// when unset.
+	Eviction    EvictionPolicy
+	EnvEviction map[string]EvictionPolicy
+	// Dedup drops transitions whose content is already stored, as for
+	// MemoryBackend.SetDedup.
+	Dedup bool
+	// Sync flushes every write to stable storage before it returns. Without
+	// it, writes that the operating system had not flushed are lost if the
+	// machine fails, though not if only the process does.
+	Sync bool
+}
+
+// DiskBackend is a replay buffer that survives restarts. Every change is
+// appended to a log of segment files in DiskOptions.Dir and applied to an
+// in-memory buffer, which serves reads; opening the directory replays the log.
+// Once the log holds well over the live transitions, it is compacted into a
+// snapshot of them.
+type DiskBackend struct {
+	// mu orders changes so the log replays them as they were applied.
+	mu      sync.Mutex
+	opts    DiskOptions
+	memory  *MemoryBackend
+	segment *os.File
+	seq     uint64 // number of the segment being appended to
+	size    int64  // bytes in the current segment
+	logged  int    // records since the last snapshot
+}
+
+// diskRecord is one entry in the log.
+type diskRecord struct {
+	Op         string      `json:"op"`
+	Transition *Transition `json:"transition,omitempty"`
+	IDs        []string    `json:"ids,omitempty"`
+	Priorities []float32   `json:"priorities,omitempty"`
+	EnvID      string      `json:"env_id,omitempty"`
+	Before     *time.Time  `json:"before,omitempty"`
+	KeepLastN  uint32      `json:"keep_last_n,omitempty"`
+}
+
+// OpenDiskBackend opens the buffer stored in opts.Dir, or creates an empty one.
+// A record left incomplete at the end of the log by a crash is discarded.
+func OpenDiskBackend(opts DiskOptions) (*DiskBackend, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	d := &DiskBackend{opts: opts}
+	d.memory = d.newMemory()
+	seqs, err := d.segments()
+	if err != nil {
+		return nil, err
+	}
+	for i, seq := range seqs {
+		if err := d.replay(seq, i == len(seqs)-1); err != nil {
+			d.memory.Close()
+			return nil, err
+		}
+	}
+	if len(seqs) == 0 {
+		err = d.startSegment(1)
+	} else {
+		err = d.appendTo(seqs[len(seqs)-1])
+	}
+	if err != nil {
+		d.memory.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Store implements Backend.Store
+func (d *DiskBackend) Store(ctx context.Context, transition *Transition) error {
+	_, err := d.StoreBatch(ctx, []*Transition{transition})
+	return err
+}
+
+// StoreBatch implements Backend.StoreBatch
+func (d *DiskBackend) StoreBatch(ctx context.Context, transitions []*Transition) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The memory buffer fills in IDs, timestamps and priorities, so the log
+	// records the transitions as stored. Duplicates it dropped are not logged.
+	ids, stored := d.memory.storeBatch(transitions)
+	records := make([]diskRecord, 0, len(ids))
+	for i := range ids {
+		if stored[i] {
+			records = append(records, diskRecord{Op: opStore, Transition: transitions[i]})
+		}
+	}
+	if err := d.append(records...); err != nil {
+		return ids, err
+	}
+	return ids, nil
+}
+
+// Sample implements Backend.Sample
+func (d *DiskBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Transition, []float32, error) {
+	return d.memory.Sample(ctx, config)
+}
+
+// GetStats implements Backend.GetStats. StorageBytes is the size of the log.
+func (d *DiskBackend) GetStats(ctx context.Context, envID string) (*Stats, error) {
+	stats, err := d.memory.GetStats(ctx, envID)
+	if err != nil {
+		return nil, err
+	}
+	seqs, err := d.segments()
+	if err != nil {
+		return nil, err
+	}
+	stats.StorageBytes = 0
+	for _, seq := range seqs {
+		if info, err := os.Stat(d.segmentPath(seq)); err == nil {
+			stats.StorageBytes += uint64(info.Size())
+		}
+	}
+	return stats, nil
+}
+
+// UpdatePriorities implements Backend.UpdatePriorities
+func (d *DiskBackend) UpdatePriorities(ctx context.Context, transitionIDs []string, priorities []float32) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.memory.UpdatePriorities(ctx, transitionIDs, priorities); err != nil {
+		return err
+	}
+	return d.append(diskRecord{Op: opPriorities, IDs: transitionIDs, Priorities: priorities})
+}
+
+// Clear implements Backend.Clear
+func (d *DiskBackend) Clear(ctx context.Context, envID string, beforeTimestamp *time.Time, keepLastN uint32) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cleared, err := d.memory.Clear(ctx, envID, beforeTimestamp, keepLastN)
+	if err != nil || cleared == 0 {
+		return cleared, err
+	}
+	return cleared, d.append(diskRecord{Op: opClear, EnvID: envID, Before: beforeTimestamp, KeepLastN: keepLastN})
+}
+
+// Export implements Backend.Export
+func (d *DiskBackend) Export(ctx context.Context, envID string, fn func(*Transition) error) error {
+	return d.memory.Export(ctx, envID, fn)
+}
+
+// GetEpisode implements Backend.GetEpisode
+func (d *DiskBackend) GetEpisode(ctx context.Context, episodeID string) ([]*Transition, error) {
+	return d.memory.GetEpisode(ctx, episodeID)
+}
+
+// OnEvict implements EvictionNotifier. Transitions evicted while the log is
+// replayed on opening are not reported again.
+func (d *DiskBackend) OnEvict(fn func(*Transition)) {
+	d.memory.OnEvict(fn)
+}
+
+// Close implements Backend.Close
+func (d *DiskBackend) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err := d.segment.Sync()
+	if closeErr := d.segment.Close(); err == nil {
+		err = closeErr
+	}
+	d.memory.Close()
+	return err
+}
+
+// newMemory returns an empty memory buffer configured by d.opts.
+func (d *DiskBackend) newMemory() *MemoryBackend {
+	memory := NewMemoryBackend(d.opts.MaxSize)
+	if d.opts.Eviction != "" || len(d.opts.EnvEviction) > 0 {
+		memory.SetEvictionPolicy(d.opts.Eviction, d.opts.EnvEviction)
+	}
+	memory.SetDedup(d.opts.Dedup)
+	return memory
+}
+
+// apply replays a logged record against the memory buffer.
+func (d *DiskBackend) apply(ctx context.Context, rec diskRecord) error {
+	switch rec.Op {
+	case opStore:
+		if rec.Transition == nil {
+			return errors.New("store record without a transition")
+		}
+		return d.memory.Store(ctx, rec.Transition)
+	case opPriorities:
+		return d.memory.UpdatePriorities(ctx, rec.IDs, rec.Priorities)
+	case opClear:
+		_, err := d.memory.Clear(ctx, rec.EnvID, rec.Before, rec.KeepLastN)
+		return err
+	case opReset:
+		d.memory.Close()
+		d.memory = d.newMemory()
+		d.logged = 0
+		return nil
+	}
+	return fmt.Errorf("unknown operation %q", rec.Op)
+}
+
+// append writes records to the current segment, then rotates or compacts the
+// log as needed.
+func (d *DiskBackend) append(records ...diskRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, rec := range records {
+		if err := encodeRecord(&buf, rec); err != nil {
+			return err
+		}
+	}
+	n, err := d.segment.Write(buf.Bytes())
+	d.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to persist: %w", err)
+	}
+	if d.opts.Sync {
+		if err := d.segment.Sync(); err != nil {
+			return fmt.Errorf("failed to persist: %w", err)
+		}
+	}
+	d.logged += len(records)
+	if d.logged > 2*d.memory.len()+compactMinRecords {
+		return d.compact()
+	}
+	if d.size >= d.opts.SegmentSize {
+		if err := d.segment.Close(); err != nil {
+			return err
+		}
+		return d.startSegment(d.seq + 1)
+	}
+	return nil
+}
+
+// compact writes the live transitions to a new segment that starts with a
+// reset record, then removes the older segments. Until the snapshot is
+// complete it is written under a temporary name, so a crash leaves the old log
+// intact.
+func (d *DiskBackend) compact() error {
+	next := d.seq + 1
+	tmpPath := d.segmentPath(next) + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+	var buf bytes.Buffer
+	if err := encodeRecord(&buf, diskRecord{Op: opReset}); err != nil {
+		tmp.Close()
+		return err
+	}
+	live := 0
+	err = d.memory.Export(context.Background(), "", func(transition *Transition) error {
+		live++
+		if err := encodeRecord(&buf, diskRecord{Op: opStore, Transition: transition}); err != nil {
+			return err
+		}
+		if buf.Len() < 1<<20 {
+			return nil
+		}
+		_, err := buf.WriteTo(tmp)
+		return err
+	})
+	if err == nil {
+		_, err = buf.WriteTo(tmp)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compact: %w", err)
+	}
+	if err := os.Rename(tmpPath, d.segmentPath(next)); err != nil {
+		return fmt.Errorf("failed to compact: %w", err)
+	}
+	syncDir(d.opts.Dir)
+
+	if err := d.segment.Close(); err != nil {
+		return err
+	}
+	old, err := d.segments()
+	if err != nil {
+		return err
+	}
+	for _, seq := range old {
+		if seq < next {
+			os.Remove(d.segmentPath(seq))
+		}
+	}
+	if err := d.appendTo(next); err != nil {
+		return err
+	}
+	d.logged = live
+	return nil
+}
+
+// replay applies the records in segment seq. In the last segment, a torn or
+// corrupt record ends the log and is truncated away, with everything after it.
+func (d *DiskBackend) replay(seq uint64, last bool) error {
+	path := d.segmentPath(seq)
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, 1<<20)
+	ctx := context.Background()
+	var offset int64
+	for {
+		rec, n, err := decodeRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if !last {
+				return fmt.Errorf("segment %s at offset %d: %w", filepath.Base(path), offset, err)
+			}
+			return os.Truncate(path, offset)
+		}
+		if err := d.apply(ctx, rec); err != nil {
+			return fmt.Errorf("segment %s at offset %d: %w", filepath.Base(path), offset, err)
+		}
+		if rec.Op != opReset {
+			d.logged++
+		}
+		offset += n
+	}
+}
+
+// startSegment creates segment seq and appends to it.
+func (d *DiskBackend) startSegment(seq uint64) error {
+	file, err := os.OpenFile(d.segmentPath(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+	syncDir(d.opts.Dir)
+	d.segment, d.seq, d.size = file, seq, 0
+	return nil
+}
+
+// appendTo reopens the existing segment seq for appending.
+func (d *DiskBackend) appendTo(seq uint64) error {
+	file, err := os.OpenFile(d.segmentPath(seq), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	d.segment, d.seq, d.size = file, seq, info.Size()
+	return nil
+}
+
+// segments returns the numbers of the segment files in order.
+func (d *DiskBackend) segments() ([]uint64, error) {
+	entries, err := os.ReadDir(d.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), segmentExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+func (d *DiskBackend) segmentPath(seq uint64) string {
+	return filepath.Join(d.opts.Dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// Records are framed as a big-endian uint32 length and CRC-32 of the JSON
+// payload that follows.
+const recordHeader = 8
+
+func encodeRecord(w io.Writer, rec diskRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	var header [recordHeader]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(payload)
+	return err
+}
+
+// decodeRecord reads the next record and returns its framed size. It returns
+// io.EOF at a clean end of the log and io.ErrUnexpectedEOF for a torn record.
+func decodeRecord(r io.Reader) (diskRecord, int64, error) {
+	var rec diskRecord
+	var header [recordHeader]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return rec, 0, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[0:4]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return rec, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return rec, 0, errors.New("checksum mismatch")
+	}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, 0, err
+	}
+	return rec, int64(recordHeader + len(payload)), nil
+}
+
+// syncDir makes a created or renamed file in dir durable. Failures are
+// ignored, as not every platform supports syncing a directory.
+func syncDir(dir string) {
+	if f, err := os.Open(dir); err == nil {
+		f.Sync()
+		f.Close()
+	}
+}
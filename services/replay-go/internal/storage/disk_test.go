@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportIDs(t *testing.T, backend Backend) []string {
+	t.Helper()
+	var ids []string
+	require.NoError(t, backend.Export(context.Background(), "", func(transition *Transition) error {
+		ids = append(ids, transition.ID)
+		return nil
+	}))
+	return ids
+}
+
+func TestDiskBackend_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := OpenDiskBackend(DiskOptions{Dir: dir, MaxSize: 1000, Sync: true})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err = backend.StoreBatch(ctx, []*Transition{
+		{ID: "a", EnvID: "tictactoe", EpisodeID: "ep1", State: []byte{1}, Timestamp: now.Add(-time.Hour)},
+		{ID: "b", EnvID: "tictactoe", EpisodeID: "ep1", State: []byte{2}, Timestamp: now.Add(-time.Minute)},
+		{ID: "c", EnvID: "gridworld", EpisodeID: "ep2", State: []byte{3}, Timestamp: now, Metadata: map[string]string{"run_id": "run-1"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, backend.Store(ctx, &Transition{EnvID: "gridworld", State: []byte{4}, Timestamp: now.Add(time.Minute)}))
+	require.NoError(t, backend.UpdatePriorities(ctx, []string{"b"}, []float32{5}))
+	cutoff := now.Add(-30 * time.Minute)
+	cleared, err := backend.Clear(ctx, "", &cutoff, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), cleared)
+	before := exportIDs(t, backend)
+	require.NoError(t, backend.Close())
+
+	reopened, err := OpenDiskBackend(DiskOptions{Dir: dir, MaxSize: 1000})
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, before, exportIDs(t, reopened))
+
+	stats, err := reopened.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), stats.TotalTransitions)
+	assert.Equal(t, uint64(1), stats.TransitionsByRun["run-1"])
+	assert.Positive(t, stats.StorageBytes)
+
+	// Priorities survive, so prioritized sampling behaves as before.
+	transitions, weights, err := reopened.Sample(ctx, &SampleConfig{BatchSize: 1, EnvID: "tictactoe", Prioritized: true, PriorityAlpha: 1})
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, "b", transitions[0].ID)
+	assert.Equal(t, float32(5), transitions[0].Priority)
+	assert.Len(t, weights, 1)
+}
+
+func TestDiskBackend_DiscardsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := OpenDiskBackend(DiskOptions{Dir: dir, MaxSize: 100})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, backend.Store(ctx, &Transition{ID: "a", EnvID: "test", State: []byte{1}}))
+	require.NoError(t, backend.Close())
+
+	// A crash mid-write leaves part of a record at the end of the log.
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	file, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{0, 0, 1, 0, 9, 9})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	reopened, err := OpenDiskBackend(DiskOptions{Dir: dir, MaxSize: 100})
+	require.NoError(t, err)
+	require.NoError(t, reopened.Store(ctx, &Transition{ID: "b", EnvID: "test", State: []byte{2}, Timestamp: time.Now().Add(time.Second)}))
+	require.NoError(t, reopened.Close())
+
+	reopened, err = OpenDiskBackend(DiskOptions{Dir: dir, MaxSize: 100})
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, []string{"a", "b"}, exportIDs(t, reopened))
+}
+
+func TestDiskBackend_RotatesAndCompacts(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskOptions{Dir: dir, MaxSize: 10, SegmentSize: 4096}
+	backend, err := OpenDiskBackend(opts)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	start := time.Now()
+	rotated := false
+	for i := 0; i < 2*compactMinRecords; i++ {
+		require.NoError(t, backend.Store(ctx, &Transition{EnvID: "test", State: []byte{byte(i)}, Timestamp: start.Add(time.Duration(i) * time.Second)}))
+		if segments, _ := backend.segments(); len(segments) > 1 {
+			rotated = true
+		}
+	}
+	assert.True(t, rotated, "expected the log to span several segments")
+	// Evicted transitions are compacted away rather than kept in the log.
+	assert.LessOrEqual(t, backend.logged, 2*10+compactMinRecords)
+	want := exportIDs(t, backend)
+	require.Len(t, want, 10)
+	require.NoError(t, backend.Close())
+
+	reopened, err := OpenDiskBackend(opts)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, want, exportIDs(t, reopened))
+}
+
+func TestDiskBackend_EvictionSurvivesCompaction(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskOptions{Dir: dir, MaxSize: 2, Eviction: EvictLowestReward}
+	backend, err := OpenDiskBackend(opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+	for i := 0; i < 2*compactMinRecords; i++ {
+		require.NoError(t, backend.Store(ctx, &Transition{ID: fmt.Sprint(i), Reward: float32(i)}))
+	}
+	require.NoError(t, backend.Close())
+
+	// The log now starts with a snapshot, which resets the buffer on replay.
+	reopened, err := OpenDiskBackend(opts)
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.NoError(t, reopened.Store(ctx, &Transition{ID: "low", Reward: -1}))
+	last := 2*compactMinRecords - 1
+	assert.Equal(t, []string{fmt.Sprint(last - 1), fmt.Sprint(last)}, exportIDs(t, reopened))
+}
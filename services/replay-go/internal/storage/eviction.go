@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"container/heap"
+	"math/rand"
+)
+
+// EvictionPolicy chooses the transition a full buffer evicts to make room.
+type EvictionPolicy string
+
+// Eviction policies.
+const (
+	// EvictFIFO evicts the oldest transition.
+	EvictFIFO EvictionPolicy = "fifo"
+	// EvictLowestPriority evicts the transition with the lowest priority,
+	// keeping those the learner still finds surprising.
+	EvictLowestPriority EvictionPolicy = "lowest_priority"
+	// EvictLowestReward evicts the transition with the lowest reward.
+	EvictLowestReward EvictionPolicy = "lowest_reward"
+	// EvictRandom evicts a transition chosen uniformly at random, so the
+	// buffer keeps a spread of ages.
+	EvictRandom EvictionPolicy = "random"
+	// EvictReservoir keeps a uniform sample of every transition the buffer
+	// has seen (reservoir sampling): a new transition is kept with
+	// probability max_size/seen, in place of one chosen at random, so old
+	// transitions are as likely to stay as new ones.
+	EvictReservoir EvictionPolicy = "reservoir"
+)
+
+// evictionQueue orders transitions by how soon a policy evicts them.
+type evictionQueue interface {
+	// set adds transition, or reorders it after its priority changed.
+	set(transition *Transition)
+	remove(transition *Transition)
+	// victim returns the transition to evict; the queue must not be empty.
+	victim(rng *rand.Rand) *Transition
+	len() int
+}
+
+// newEvictionQueue returns an empty queue for policy, or nil for FIFO, which
+// evicts in the order of the backend's own indexes.
+func newEvictionQueue(policy EvictionPolicy) evictionQueue {
+	switch policy {
+	case EvictLowestPriority:
+		return newHeapQueue(func(transition *Transition) float32 { return transition.Priority })
+	case EvictLowestReward:
+		return newHeapQueue(func(transition *Transition) float32 { return transition.Reward })
+	case EvictRandom:
+		return &randomQueue{slots: make(map[string]int)}
+	case EvictReservoir:
+		return &reservoirQueue{randomQueue: randomQueue{slots: make(map[string]int)}}
+	}
+	return nil
+}
+
+// evictionIndex queues a memory backend's transitions for eviction. The
+// default policy picks a victim from the whole buffer; when the victim's
+// environment overrides the policy, its own policy then picks which of the
+// environment's transitions goes instead.
+type evictionIndex struct {
+	policy   EvictionPolicy
+	all      evictionQueue // nil for FIFO
+	policies map[string]EvictionPolicy
+	byEnv    map[string]evictionQueue // of the overriding environments not on FIFO
+}
+
+func newEvictionIndex(policy EvictionPolicy, byEnv map[string]EvictionPolicy) *evictionIndex {
+	return &evictionIndex{
+		policy:   policy,
+		all:      newEvictionQueue(policy),
+		policies: byEnv,
+		byEnv:    make(map[string]evictionQueue),
+	}
+}
+
+func (e *evictionIndex) set(transition *Transition) {
+	if e.all != nil {
+		e.all.set(transition)
+	}
+	if policy, ok := e.policies[transition.EnvID]; ok && policy != EvictFIFO {
+		queue, ok := e.byEnv[transition.EnvID]
+		if !ok {
+			queue = newEvictionQueue(policy)
+			e.byEnv[transition.EnvID] = queue
+		}
+		queue.set(transition)
+	}
+}
+
+func (e *evictionIndex) remove(transition *Transition) {
+	if e.all != nil {
+		e.all.remove(transition)
+	}
+	if queue, ok := e.byEnv[transition.EnvID]; ok {
+		queue.remove(transition)
+		if queue.len() == 0 {
+			delete(e.byEnv, transition.EnvID)
+		}
+	}
+}
+
+// heapQueue is a min-heap of transitions by key, oldest first among equals.
+type heapQueue struct {
+	key   func(*Transition) float32
+	items []*Transition
+	slots map[string]int // ID -> index in items
+}
+
+func newHeapQueue(key func(*Transition) float32) *heapQueue {
+	return &heapQueue{key: key, slots: make(map[string]int)}
+}
+
+func (q *heapQueue) Len() int { return len(q.items) }
+
+func (q *heapQueue) Less(i, j int) bool {
+	if ki, kj := q.key(q.items[i]), q.key(q.items[j]); ki != kj {
+		return ki < kj
+	}
+	return q.items[i].Timestamp.Before(q.items[j].Timestamp)
+}
+
+func (q *heapQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.slots[q.items[i].ID] = i
+	q.slots[q.items[j].ID] = j
+}
+
+func (q *heapQueue) Push(x any) {
+	transition := x.(*Transition)
+	q.slots[transition.ID] = len(q.items)
+	q.items = append(q.items, transition)
+}
+
+func (q *heapQueue) Pop() any {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	delete(q.slots, last.ID)
+	return last
+}
+
+func (q *heapQueue) set(transition *Transition) {
+	if slot, ok := q.slots[transition.ID]; ok {
+		q.items[slot] = transition
+		heap.Fix(q, slot)
+		return
+	}
+	heap.Push(q, transition)
+}
+
+func (q *heapQueue) remove(transition *Transition) {
+	if slot, ok := q.slots[transition.ID]; ok {
+		heap.Remove(q, slot)
+	}
+}
+
+func (q *heapQueue) victim(*rand.Rand) *Transition { return q.items[0] }
+
+func (q *heapQueue) len() int { return len(q.items) }
+
+// randomQueue holds transitions for eviction uniformly at random.
+type randomQueue struct {
+	items []*Transition
+	slots map[string]int // ID -> index in items
+}
+
+func (q *randomQueue) set(transition *Transition) {
+	if slot, ok := q.slots[transition.ID]; ok {
+		q.items[slot] = transition
+		return
+	}
+	q.slots[transition.ID] = len(q.items)
+	q.items = append(q.items, transition)
+}
+
+func (q *randomQueue) remove(transition *Transition) {
+	slot, ok := q.slots[transition.ID]
+	if !ok {
+		return
+	}
+	last := q.items[len(q.items)-1]
+	q.items[slot] = last
+	q.slots[last.ID] = slot
+	q.items = q.items[:len(q.items)-1]
+	delete(q.slots, transition.ID)
+}
+
+func (q *randomQueue) victim(rng *rand.Rand) *Transition { return q.items[rng.Intn(len(q.items))] }
+
+func (q *randomQueue) len() int { return len(q.items) }
+
+// reservoirQueue picks victims for reservoir sampling. It expects a victim to
+// be chosen right after each new transition pushes the buffer over its size,
+// so the transitions already stored are the reservoir.
+type reservoirQueue struct {
+	randomQueue
+	seen   uint64      // transitions ever added
+	newest *Transition // the last transition added, nil once removed
+}
+
+func (q *reservoirQueue) set(transition *Transition) {
+	if _, ok := q.slots[transition.ID]; !ok {
+		q.seen++
+		q.newest = transition
+	}
+	q.randomQueue.set(transition)
+}
+
+func (q *reservoirQueue) remove(transition *Transition) {
+	if q.newest != nil && q.newest.ID == transition.ID {
+		q.newest = nil
+	}
+	q.randomQueue.remove(transition)
+}
+
+// victim returns the newest transition with probability 1 - k/seen, where k
+// is the size of the reservoir it joined, and otherwise one of the others
+// uniformly at random.
+func (q *reservoirQueue) victim(rng *rand.Rand) *Transition {
+	if q.newest == nil || len(q.items) < 2 {
+		return q.randomQueue.victim(rng)
+	}
+	k := uint64(len(q.items) - 1)
+	if uint64(rng.Int63n(int64(q.seen))) >= k {
+		return q.newest
+	}
+	// Draw from the others by standing the last item in for the newest.
+	i := rng.Intn(len(q.items) - 1)
+	if q.items[i].ID == q.newest.ID {
+		i = len(q.items) - 1
+	}
+	return q.items[i]
+}
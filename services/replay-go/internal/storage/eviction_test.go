@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedIDs returns the IDs backend holds, sorted.
+func storedIDs(backend *MemoryBackend) []string {
+	var ids []string
+	for id := range backend.transitions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestMemoryBackend_EvictionPolicies(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	// Transition i is the i-th oldest, with its priority and reward falling
+	// as i rises from 0 to 4.
+	transitions := func() []*Transition {
+		var batch []*Transition
+		for i := 0; i < 5; i++ {
+			batch = append(batch, &Transition{
+				ID:        fmt.Sprint(i),
+				EnvID:     "tictactoe",
+				Priority:  float32(10 - i),
+				Reward:    float32(-i),
+				Timestamp: now.Add(time.Duration(i) * time.Second),
+			})
+		}
+		return batch
+	}
+
+	for _, tt := range []struct {
+		policy EvictionPolicy
+		want   []string
+	}{
+		{EvictFIFO, []string{"2", "3", "4"}},
+		{EvictLowestPriority, []string{"0", "1", "2"}},
+		{EvictLowestReward, []string{"0", "1", "2"}},
+	} {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			backend := NewMemoryBackend(3)
+			defer backend.Close()
+			backend.SetEvictionPolicy(tt.policy, nil)
+			_, err := backend.StoreBatch(ctx, transitions())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, storedIDs(backend))
+		})
+	}
+
+	t.Run("random", func(t *testing.T) {
+		backend := NewMemoryBackend(3)
+		defer backend.Close()
+		backend.rng = rand.New(rand.NewSource(1))
+		backend.SetEvictionPolicy(EvictRandom, nil)
+		_, err := backend.StoreBatch(ctx, transitions())
+		require.NoError(t, err)
+		assert.Len(t, storedIDs(backend), 3)
+		assert.Equal(t, 3, backend.eviction.all.len())
+	})
+}
+
+func TestMemoryBackend_EvictionFollowsPriorities(t *testing.T) {
+	backend := NewMemoryBackend(2)
+	defer backend.Close()
+	backend.SetEvictionPolicy(EvictLowestPriority, nil)
+
+	ctx := context.Background()
+	_, err := backend.StoreBatch(ctx, []*Transition{{ID: "a", Priority: 1}, {ID: "b", Priority: 2}})
+	require.NoError(t, err)
+	require.NoError(t, backend.UpdatePriorities(ctx, []string{"a"}, []float32{5}))
+
+	require.NoError(t, backend.Store(ctx, &Transition{ID: "c", Priority: 3}))
+	assert.Equal(t, []string{"a", "c"}, storedIDs(backend))
+
+	// A new transition below every stored priority is evicted at once.
+	require.NoError(t, backend.Store(ctx, &Transition{ID: "d", Priority: 0.5}))
+	assert.Equal(t, []string{"a", "c"}, storedIDs(backend))
+}
+
+func TestMemoryBackend_EnvEviction(t *testing.T) {
+	backend := NewMemoryBackend(4)
+	defer backend.Close()
+	backend.SetEvictionPolicy(EvictFIFO, map[string]EvictionPolicy{"atari": EvictLowestReward})
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err := backend.StoreBatch(ctx, []*Transition{
+		{ID: "atari-high", EnvID: "atari", Reward: 5, Timestamp: now},
+		{ID: "atari-low", EnvID: "atari", Reward: -5, Timestamp: now.Add(time.Second)},
+		{ID: "tictactoe-1", EnvID: "tictactoe", Timestamp: now.Add(2 * time.Second)},
+		{ID: "tictactoe-2", EnvID: "tictactoe", Timestamp: now.Add(3 * time.Second)},
+	})
+	require.NoError(t, err)
+
+	// The oldest transition is atari's, so atari gives up its lowest reward.
+	require.NoError(t, backend.Store(ctx, &Transition{ID: "tictactoe-3", EnvID: "tictactoe", Timestamp: now.Add(4 * time.Second)}))
+	assert.Equal(t, []string{"atari-high", "tictactoe-1", "tictactoe-2", "tictactoe-3"}, storedIDs(backend))
+
+	// The override applies only to the victim's environment.
+	require.NoError(t, backend.Store(ctx, &Transition{ID: "tictactoe-4", EnvID: "tictactoe", Timestamp: now.Add(5 * time.Second)}))
+	assert.Equal(t, []string{"tictactoe-1", "tictactoe-2", "tictactoe-3", "tictactoe-4"}, storedIDs(backend))
+	assert.Empty(t, backend.eviction.byEnv)
+}
+
+func TestMemoryBackend_ReservoirIsUniform(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	// Over many trials, a reservoir of 10 out of 100 transitions keeps
+	// each with probability 1/10, whatever its age.
+	kept := make([]int, 100)
+	const trials = 500
+	for trial := 0; trial < trials; trial++ {
+		backend := NewMemoryBackend(10)
+		backend.rng = rand.New(rand.NewSource(rng.Int63()))
+		backend.SetEvictionPolicy(EvictReservoir, nil)
+		for i := range kept {
+			require.NoError(t, backend.Store(ctx, &Transition{ID: fmt.Sprint(i)}))
+		}
+		require.Len(t, backend.transitions, 10)
+		for i := range kept {
+			if _, ok := backend.transitions[fmt.Sprint(i)]; ok {
+				kept[i]++
+			}
+		}
+		backend.Close()
+	}
+	oldest, newest := 0, 0
+	for i := 0; i < 50; i++ {
+		oldest += kept[i]
+		newest += kept[50+i]
+	}
+	// Each half expects trials*10/2 = 2500.
+	assert.InDelta(t, 2500, oldest, 150)
+	assert.InDelta(t, 2500, newest, 150)
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cartridge/chaos"
+)
+
+// WithFaults returns backend with injector's partial batch failures applied to
+// StoreBatch: a batch chosen to fail stores only a leading part of its
+// transitions and reports the rest as failed. A nil injector returns backend
+// unchanged.
+func WithFaults(backend Backend, injector *chaos.Injector) Backend {
+	if injector == nil {
+		return backend
+	}
+	return &faultyBackend{Backend: backend, injector: injector}
+}
+
+type faultyBackend struct {
+	Backend
+	injector *chaos.Injector
+}
+
+// StoreBatch implements Backend.StoreBatch
+func (f *faultyBackend) StoreBatch(ctx context.Context, transitions []*Transition) ([]string, error) {
+	keep := f.injector.Partial(len(transitions))
+	ids, err := f.Backend.StoreBatch(ctx, transitions[:keep])
+	if err != nil || keep == len(transitions) {
+		return ids, err
+	}
+	return ids, fmt.Errorf("chaos: injected partial failure, %d of %d transitions rejected", len(transitions)-keep, len(transitions))
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cartridge/chaos"
+)
+
+func TestWithFaults_PartialStoreBatch(t *testing.T) {
+	memory := NewMemoryBackend(1000)
+	defer memory.Close()
+	assert.Same(t, Backend(memory), WithFaults(memory, nil))
+
+	backend := WithFaults(memory, chaos.New(chaos.Config{PartialRate: 1, Seed: 7}))
+	ctx := context.Background()
+
+	transitions := make([]*Transition, 10)
+	for i := range transitions {
+		transitions[i] = &Transition{EnvID: "tictactoe", EpisodeID: "episode-1", State: []byte{byte(i)}, Action: []byte{0}}
+	}
+	ids, err := backend.StoreBatch(ctx, transitions)
+	require.Error(t, err)
+	assert.Less(t, len(ids), len(transitions))
+
+	// Only the reported transitions were stored, and other calls are untouched.
+	stats, err := backend.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(ids)), stats.TotalTransitions)
+}
@@ -2,9 +2,14 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNoTransitions is returned by Sample when no stored transition matches
+// the configuration.
+var ErrNoTransitions = errors.New("no transitions available for sampling")
+
 // Transition represents a single experience transition
 type Transition struct {
 	ID              string            `json:"id"`
@@ -21,6 +26,9 @@ type Transition struct {
 	Priority        float32           `json:"priority"`
 	Timestamp       time.Time         `json:"timestamp"`
 	Metadata        map[string]string `json:"metadata"`
+	// Duplicate is set by StoreBatch on a transition dedup dropped, which
+	// was given the ID of the stored transition it repeats.
+	Duplicate bool `json:"-"`
 }
 
 // SampleConfig defines parameters for sampling transitions
@@ -31,6 +39,16 @@ type SampleConfig struct {
 	PriorityAlpha float32
 	MinTimestamp  *time.Time
 	MaxTimestamp  *time.Time
+	// Reservoir draws prioritized batches in a single pass over the
+	// candidates (the prioritized_sampling_v2 feature flag).
+	Reservoir bool
+	// RunID limits sampling to transitions whose run_id metadata matches.
+	RunID string
+	// Terminal limits sampling to transitions that end their episode.
+	Terminal bool
+	// Winning limits sampling to transitions of winning episodes: those with
+	// a terminal transition of positive reward.
+	Winning bool
 }
 
 // Stats represents replay buffer statistics
@@ -38,6 +56,8 @@ type Stats struct {
 	TotalTransitions   uint64
 	TotalEpisodes      uint64
 	TransitionsByEnv   map[string]uint64
+	// TransitionsByRun counts transitions by their run_id metadata.
+	TransitionsByRun   map[string]uint64
 	OldestTimestamp    *time.Time
 	NewestTimestamp    *time.Time
 	StorageBytes       uint64
@@ -63,6 +83,14 @@ type Backend interface {
 	// Clear transitions based on criteria
 	Clear(ctx context.Context, envID string, beforeTimestamp *time.Time, keepLastN uint32) (uint64, error)
 
+	// Export calls fn with a copy of every stored transition, or only those of
+	// envID when it is set, oldest first. It stops at the first error fn returns.
+	Export(ctx context.Context, envID string, fn func(*Transition) error) error
+
+	// GetEpisode returns the stored transitions of episodeID in step order,
+	// or none when none are stored.
+	GetEpisode(ctx context.Context, episodeID string) ([]*Transition, error)
+
 	// Close the backend and cleanup resources
 	Close() error
 }
\ No newline at end of file
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/cartridge/proto/runctx"
 )
 
 // MemoryBackend implements an in-memory replay buffer
@@ -18,9 +20,23 @@ type MemoryBackend struct {
 	transitions map[string]*Transition // ID -> Transition
 	episodes    map[string][]string    // EpisodeID -> TransitionIDs
 	envIndex    map[string][]string    // EnvID -> TransitionIDs
+	runIndex    map[string][]string    // run_id metadata -> TransitionIDs
 	timeIndex   []string               // TransitionIDs sorted by timestamp
 	maxSize     uint64                 // Maximum number of transitions to store
 	rng         *rand.Rand
+	onEvict     func(*Transition)      // Called with each transition evicted for space
+	priorities  *priorityIndex         // Sum trees for prioritized sampling, built on first use
+	eviction    *evictionIndex         // Eviction queues, nil when evicting oldest first
+	hashes      map[contentHash]string // Content hash -> TransitionID, nil without dedup
+}
+
+// EvictionNotifier is implemented by backends that can report the transitions
+// they evict to stay within their maximum size, e.g. to archive them.
+type EvictionNotifier interface {
+	// OnEvict sets the function called with each evicted transition. It is
+	// called while the backend is locked, so it must not block or call back
+	// into the backend.
+	OnEvict(fn func(*Transition))
 }
 
 // NewMemoryBackend creates a new in-memory storage backend
@@ -29,6 +45,7 @@ func NewMemoryBackend(maxSize uint64) *MemoryBackend {
 		transitions: make(map[string]*Transition),
 		episodes:    make(map[string][]string),
 		envIndex:    make(map[string][]string),
+		runIndex:    make(map[string][]string),
 		timeIndex:   make([]string, 0),
 		maxSize:     maxSize,
 		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
@@ -40,6 +57,23 @@ func (m *MemoryBackend) Store(ctx context.Context, transition *Transition) error
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.store(transition)
+	return nil
+}
+
+// store fills in transition's ID, timestamp and priority and stores it. With
+// dedup, a transition whose content is already stored is dropped and takes
+// the stored transition's ID instead, and is marked Duplicate; store reports
+// false for it. m.mu must be held.
+func (m *MemoryBackend) store(transition *Transition) bool {
+	if m.hashes != nil {
+		if id, exists := m.hashes[hashContent(transition)]; exists {
+			transition.ID = id
+			transition.Duplicate = true
+			return false
+		}
+	}
+
 	// Generate ID if not provided
 	if transition.ID == "" {
 		transition.ID = uuid.New().String()
@@ -55,7 +89,14 @@ func (m *MemoryBackend) Store(ctx context.Context, transition *Transition) error
 		transition.Priority = 1.0
 	}
 
-	// Store the transition
+	m.add(transition)
+	return true
+}
+
+// add stores transition, which has its ID, timestamp and priority set, in
+// the indexes and evicts the oldest transitions if the buffer is then over
+// its maximum size. m.mu must be held.
+func (m *MemoryBackend) add(transition *Transition) {
 	m.transitions[transition.ID] = transition
 
 	// Update episode index
@@ -68,31 +109,57 @@ func (m *MemoryBackend) Store(ctx context.Context, transition *Transition) error
 		m.envIndex[transition.EnvID] = append(m.envIndex[transition.EnvID], transition.ID)
 	}
 
+	// Update run index
+	if runID := transition.Metadata[runctx.RunIDKey]; runID != "" {
+		m.runIndex[runID] = append(m.runIndex[runID], transition.ID)
+	}
+
 	// Update time index (maintain sorted order)
 	m.insertInTimeIndex(transition.ID, transition.Timestamp)
 
+	if m.priorities != nil {
+		m.priorities.set(transition)
+	}
+	if m.eviction != nil {
+		m.eviction.set(transition)
+	}
+	if m.hashes != nil {
+		m.hashes[hashContent(transition)] = transition.ID
+	}
+
 	// Evict old transitions if we exceed maxSize
 	m.evictIfNeeded()
-
-	return nil
 }
 
 // StoreBatch implements Backend.StoreBatch
 func (m *MemoryBackend) StoreBatch(ctx context.Context, transitions []*Transition) ([]string, error) {
-	ids := make([]string, len(transitions))
+	ids, _ := m.storeBatch(transitions)
+	return ids, nil
+}
+
+// storeBatch stores transitions, reporting which were stored rather than
+// dropped by dedup.
+func (m *MemoryBackend) storeBatch(transitions []*Transition) ([]string, []bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
+	ids := make([]string, len(transitions))
+	stored := make([]bool, len(transitions))
 	for i, transition := range transitions {
-		if err := m.Store(ctx, transition); err != nil {
-			return ids[:i], err
-		}
+		stored[i] = m.store(transition)
 		ids[i] = transition.ID
 	}
-
-	return ids, nil
+	return ids, stored
 }
 
-// Sample implements Backend.Sample
+// Sample implements Backend.Sample. Prioritized samples filtered by no more
+// than environment are drawn from sum trees in O(batch log n); others, and
+// all reservoir samples, scan the candidates.
 func (m *MemoryBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Transition, []float32, error) {
+	if config.Prioritized && !config.Reservoir && config.RunID == "" && config.MinTimestamp == nil && config.MaxTimestamp == nil && !config.Terminal && !config.Winning {
+		return m.sampleIndexed(config)
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -100,7 +167,7 @@ func (m *MemoryBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Tr
 	candidates := m.getCandidates(config)
 
 	if len(candidates) == 0 {
-		return nil, nil, fmt.Errorf("no transitions available for sampling")
+		return nil, nil, ErrNoTransitions
 	}
 
 	// Determine sample size
@@ -112,10 +179,13 @@ func (m *MemoryBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Tr
 	var sampled []*Transition
 	var weights []float32
 
-	if config.Prioritized {
-		sampled, weights = m.prioritizedSample(candidates, sampleSize, config.PriorityAlpha)
-	} else {
-		sampled = m.uniformSample(candidates, sampleSize)
+	switch {
+	case config.Prioritized && config.Reservoir:
+		sampled, weights = reservoirSample(m.rng, candidates, sampleSize, config.PriorityAlpha)
+	case config.Prioritized:
+		sampled, weights = prioritizedSample(m.rng, candidates, sampleSize, config.PriorityAlpha)
+	default:
+		sampled = uniformSample(m.rng, candidates, sampleSize)
 		weights = make([]float32, sampleSize)
 		for i := range weights {
 			weights[i] = 1.0
@@ -125,6 +195,26 @@ func (m *MemoryBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Tr
 	return sampled, weights, nil
 }
 
+// sampleIndexed draws a prioritized sample from the sum trees. They hold
+// priorities scaled by one alpha, so they are rebuilt when a sample asks for
+// another.
+func (m *MemoryBackend) sampleIndexed(config *SampleConfig) ([]*Transition, []float32, error) {
+	// Drawing without replacement zeroes the drawn leaves until the batch is
+	// done, so the trees are locked for writing.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.priorities == nil || m.priorities.alpha != config.PriorityAlpha {
+		m.priorities = newPriorityIndex(m.transitions, config.PriorityAlpha)
+	}
+	tree := m.priorities.tree(config.EnvID)
+	if tree == nil || tree.len() == 0 {
+		return nil, nil, ErrNoTransitions
+	}
+	sampled, weights := tree.sample(m.rng, int(config.BatchSize))
+	return sampled, weights, nil
+}
+
 // GetStats implements Backend.GetStats
 func (m *MemoryBackend) GetStats(ctx context.Context, envID string) (*Stats, error) {
 	m.mu.RLock()
@@ -134,6 +224,7 @@ func (m *MemoryBackend) GetStats(ctx context.Context, envID string) (*Stats, err
 		TotalTransitions: uint64(len(m.transitions)),
 		TotalEpisodes:    uint64(len(m.episodes)),
 		TransitionsByEnv: make(map[string]uint64),
+		TransitionsByRun: make(map[string]uint64),
 	}
 
 	// Calculate storage bytes (approximate)
@@ -149,6 +240,15 @@ func (m *MemoryBackend) GetStats(ctx context.Context, envID string) (*Stats, err
 		}
 	}
 
+	// Count transitions by run
+	for run, transitions := range m.runIndex {
+		for _, id := range transitions {
+			if envID == "" || m.transitions[id].EnvID == envID {
+				stats.TransitionsByRun[run]++
+			}
+		}
+	}
+
 	// Find oldest and newest timestamps
 	if len(m.timeIndex) > 0 {
 		oldest := m.transitions[m.timeIndex[0]]
@@ -172,6 +272,12 @@ func (m *MemoryBackend) UpdatePriorities(ctx context.Context, transitionIDs []st
 	for i, id := range transitionIDs {
 		if transition, exists := m.transitions[id]; exists {
 			transition.Priority = priorities[i]
+			if m.priorities != nil {
+				m.priorities.set(transition)
+			}
+			if m.eviction != nil {
+				m.eviction.set(transition)
+			}
 		}
 	}
 
@@ -232,6 +338,45 @@ func (m *MemoryBackend) Clear(ctx context.Context, envID string, beforeTimestamp
 	return uint64(len(toDelete)), nil
 }
 
+// Export implements Backend.Export. The matching transitions are copied under
+// the lock, so fn may be slow without holding up writers.
+func (m *MemoryBackend) Export(ctx context.Context, envID string, fn func(*Transition) error) error {
+	m.mu.RLock()
+	snapshot := make([]Transition, 0, len(m.timeIndex))
+	for _, id := range m.timeIndex {
+		if transition := m.transitions[id]; envID == "" || transition.EnvID == envID {
+			snapshot = append(snapshot, *transition)
+		}
+	}
+	m.mu.RUnlock()
+
+	for i := range snapshot {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&snapshot[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetEpisode implements Backend.GetEpisode, returning copies of the
+// transitions from the episode index.
+func (m *MemoryBackend) GetEpisode(ctx context.Context, episodeID string) ([]*Transition, error) {
+	m.mu.RLock()
+	ids := m.episodes[episodeID]
+	episode := make([]*Transition, len(ids))
+	for i, id := range ids {
+		transition := *m.transitions[id]
+		episode[i] = &transition
+	}
+	m.mu.RUnlock()
+
+	sortBySteps(episode)
+	return episode, nil
+}
+
 // Close implements Backend.Close
 func (m *MemoryBackend) Close() error {
 	m.mu.Lock()
@@ -240,13 +385,54 @@ func (m *MemoryBackend) Close() error {
 	m.transitions = nil
 	m.episodes = nil
 	m.envIndex = nil
+	m.runIndex = nil
 	m.timeIndex = nil
+	m.priorities = nil
+	m.eviction = nil
+	m.hashes = nil
 
 	return nil
 }
 
+// OnEvict implements EvictionNotifier. Transitions removed by Clear are not
+// reported.
+func (m *MemoryBackend) OnEvict(fn func(*Transition)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvict = fn
+}
+
+// SetEvictionPolicy sets the policy that chooses which transition a full
+// buffer evicts, and the policies that override it for the environments in
+// byEnv. The default policy picks a victim from the whole buffer; if the
+// victim's environment has its own policy, that picks which of the
+// environment's transitions goes instead. An empty policy is FIFO, which
+// evicts the oldest transition, as the buffer does by default. EvictReservoir
+// is only a default policy: it counts the transitions seen from when it is
+// set, and an environment's share of the buffer is not a reservoir of its own.
+func (m *MemoryBackend) SetEvictionPolicy(policy EvictionPolicy, byEnv map[string]EvictionPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.eviction = nil
+	if (policy == "" || policy == EvictFIFO) && len(byEnv) == 0 {
+		return
+	}
+	m.eviction = newEvictionIndex(policy, byEnv)
+	for _, transition := range m.transitions {
+		m.eviction.set(transition)
+	}
+}
+
 // Helper methods
 
+// len returns the number of transitions stored.
+func (m *MemoryBackend) len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.transitions)
+}
+
 func (m *MemoryBackend) insertInTimeIndex(id string, timestamp time.Time) {
 	// Binary search for insertion point
 	idx := sort.Search(len(m.timeIndex), func(i int) bool {
@@ -264,13 +450,36 @@ func (m *MemoryBackend) evictIfNeeded() {
 		return
 	}
 
-	// Remove oldest transitions
 	toRemove := uint64(len(m.transitions)) - m.maxSize
 	for i := uint64(0); i < toRemove; i++ {
-		if len(m.timeIndex) > 0 {
-			oldestID := m.timeIndex[0]
-			m.deleteTransition(oldestID)
+		victim := m.victim()
+		if m.onEvict != nil {
+			m.onEvict(victim)
 		}
+		m.deleteTransition(victim.ID)
+	}
+}
+
+// victim returns the transition the eviction policies evict next: by default
+// the oldest.
+func (m *MemoryBackend) victim() *Transition {
+	e := m.eviction
+	if e == nil {
+		return m.transitions[m.timeIndex[0]]
+	}
+	var victim *Transition
+	if e.all == nil {
+		victim = m.transitions[m.timeIndex[0]]
+	} else {
+		victim = e.all.victim(m.rng)
+	}
+	switch policy, ok := e.policies[victim.EnvID]; {
+	case !ok:
+		return victim
+	case policy == EvictFIFO:
+		return m.transitions[m.envIndex[victim.EnvID][0]]
+	default:
+		return e.byEnv[victim.EnvID].victim(m.rng)
 	}
 }
 
@@ -282,6 +491,17 @@ func (m *MemoryBackend) deleteTransition(id string) {
 
 	// Remove from main storage
 	delete(m.transitions, id)
+	if m.priorities != nil {
+		m.priorities.remove(transition)
+	}
+	if m.eviction != nil {
+		m.eviction.remove(transition)
+	}
+	if m.hashes != nil {
+		if hash := hashContent(transition); m.hashes[hash] == id {
+			delete(m.hashes, hash)
+		}
+	}
 
 	// Remove from episode index
 	if transition.EpisodeID != "" {
@@ -303,6 +523,16 @@ func (m *MemoryBackend) deleteTransition(id string) {
 		}
 	}
 
+	// Remove from run index
+	if runID := transition.Metadata[runctx.RunIDKey]; runID != "" {
+		if runTransitions, exists := m.runIndex[runID]; exists {
+			m.runIndex[runID] = removeString(runTransitions, id)
+			if len(m.runIndex[runID]) == 0 {
+				delete(m.runIndex, runID)
+			}
+		}
+	}
+
 	// Remove from time index
 	m.timeIndex = removeString(m.timeIndex, id)
 }
@@ -310,9 +540,11 @@ func (m *MemoryBackend) deleteTransition(id string) {
 func (m *MemoryBackend) getCandidates(config *SampleConfig) []*Transition {
 	var candidates []*Transition
 
-	// Start with all transitions or filter by environment
+	// Start with all transitions or filter by run or environment
 	var transitionIDs []string
-	if config.EnvID != "" {
+	if config.RunID != "" {
+		transitionIDs = m.runIndex[config.RunID]
+	} else if config.EnvID != "" {
 		if envTransitions, exists := m.envIndex[config.EnvID]; exists {
 			transitionIDs = envTransitions
 		}
@@ -323,16 +555,36 @@ func (m *MemoryBackend) getCandidates(config *SampleConfig) []*Transition {
 		}
 	}
 
-	// Apply timestamp filters
+	// Apply timestamp and outcome filters
+	var winning map[string]bool // EpisodeID -> whether it was won
+	if config.Winning {
+		winning = make(map[string]bool)
+	}
 	for _, id := range transitionIDs {
 		transition := m.transitions[id]
 
+		if config.EnvID != "" && transition.EnvID != config.EnvID {
+			continue
+		}
 		if config.MinTimestamp != nil && transition.Timestamp.Before(*config.MinTimestamp) {
 			continue
 		}
 		if config.MaxTimestamp != nil && transition.Timestamp.After(*config.MaxTimestamp) {
 			continue
 		}
+		if config.Terminal && !transition.Done {
+			continue
+		}
+		if config.Winning {
+			won, checked := winning[transition.EpisodeID]
+			if !checked {
+				won = m.won(transition.EpisodeID)
+				winning[transition.EpisodeID] = won
+			}
+			if !won {
+				continue
+			}
+		}
 
 		candidates = append(candidates, transition)
 	}
@@ -340,7 +592,21 @@ func (m *MemoryBackend) getCandidates(config *SampleConfig) []*Transition {
 	return candidates
 }
 
-func (m *MemoryBackend) uniformSample(candidates []*Transition, sampleSize int) []*Transition {
+// won reports whether the episode has a terminal transition of positive
+// reward. m.mu must be held.
+func (m *MemoryBackend) won(episodeID string) bool {
+	if episodeID == "" {
+		return false
+	}
+	for _, id := range m.episodes[episodeID] {
+		if transition := m.transitions[id]; transition.Done && transition.Reward > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func uniformSample(rng *rand.Rand, candidates []*Transition, sampleSize int) []*Transition {
 	if sampleSize >= len(candidates) {
 		return candidates
 	}
@@ -352,7 +618,7 @@ func (m *MemoryBackend) uniformSample(candidates []*Transition, sampleSize int)
 	}
 
 	for i := len(indices) - 1; i > 0; i-- {
-		j := m.rng.Intn(i + 1)
+		j := rng.Intn(i + 1)
 		indices[i], indices[j] = indices[j], indices[i]
 	}
 
@@ -364,7 +630,7 @@ func (m *MemoryBackend) uniformSample(candidates []*Transition, sampleSize int)
 	return sampled
 }
 
-func (m *MemoryBackend) prioritizedSample(candidates []*Transition, sampleSize int, alpha float32) ([]*Transition, []float32) {
+func prioritizedSample(rng *rand.Rand, candidates []*Transition, sampleSize int, alpha float32) ([]*Transition, []float32) {
 	numCandidates := len(candidates)
 	if sampleSize >= numCandidates {
 		sampled := make([]*Transition, numCandidates)
@@ -382,7 +648,7 @@ func (m *MemoryBackend) prioritizedSample(candidates []*Transition, sampleSize i
 	priorities := computeScaledPriorities(candidates, alpha)
 	totalWeight := sumFloat64(priorities)
 	if totalWeight == 0 {
-		return m.uniformSample(candidates, sampleSize), makeUniformWeights(sampleSize)
+		return uniformSample(rng, candidates, sampleSize), makeUniformWeights(sampleSize)
 	}
 
 	probabilities := normalizeProbabilities(priorities, totalWeight)
@@ -392,7 +658,7 @@ func (m *MemoryBackend) prioritizedSample(candidates []*Transition, sampleSize i
 
 	remainingWeight := totalWeight
 	for len(sampled) < sampleSize && remainingWeight > 0 {
-		target := m.rng.Float64() * remainingWeight
+		target := rng.Float64() * remainingWeight
 		cumulative := 0.0
 
 		for i, priority := range currentPriorities {
@@ -428,7 +694,7 @@ func (m *MemoryBackend) prioritizedSample(candidates []*Transition, sampleSize i
 
 	if len(sampled) < sampleSize {
 		// Fill any remaining slots uniformly
-		remaining := m.uniformSample(candidates, sampleSize)
+		remaining := uniformSample(rng, candidates, sampleSize)
 		used := make(map[*Transition]struct{}, len(sampled))
 		for _, s := range sampled {
 			used[s] = struct{}{}
@@ -448,8 +714,44 @@ func (m *MemoryBackend) prioritizedSample(candidates []*Transition, sampleSize i
 	return sampled, weights
 }
 
+// reservoirSample draws the same distribution as prioritizedSample, without
+// replacement and in proportion to the scaled priorities, but in one pass:
+// each candidate gets the key log(u)/priority and the largest keys are taken
+// (Efraimidis and Spirakis' weighted reservoir sampling).
+func reservoirSample(rng *rand.Rand, candidates []*Transition, sampleSize int, alpha float32) ([]*Transition, []float32) {
+	priorities := computeScaledPriorities(candidates, alpha)
+	probabilities := normalizeProbabilities(priorities, sumFloat64(priorities))
+
+	keys := make([]float64, len(candidates))
+	order := make([]int, len(candidates))
+	for i, priority := range priorities {
+		keys[i] = math.Log(rng.Float64()) / priority
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return keys[order[a]] > keys[order[b]] })
+
+	sampled := make([]*Transition, sampleSize)
+	weights := make([]float32, sampleSize)
+	for i, index := range order[:sampleSize] {
+		sampled[i] = candidates[index]
+		weights[i] = importanceWeight(probabilities[index], len(candidates))
+	}
+	return sampled, weights
+}
+
 // Utility functions
 
+// sortBySteps orders an episode's transitions by step number, and transitions
+// stored with the same step by timestamp.
+func sortBySteps(episode []*Transition) {
+	sort.SliceStable(episode, func(i, j int) bool {
+		if episode[i].StepNumber != episode[j].StepNumber {
+			return episode[i].StepNumber < episode[j].StepNumber
+		}
+		return episode[i].Timestamp.Before(episode[j].Timestamp)
+	})
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -469,16 +771,21 @@ func removeString(slice []string, item string) []string {
 }
 
 func computeScaledPriorities(candidates []*Transition, alpha float32) []float64 {
-	const epsilon = 1e-12
-
 	priorities := make([]float64, len(candidates))
 	for i, candidate := range candidates {
-		priority := math.Max(float64(candidate.Priority), epsilon)
-		priorities[i] = math.Pow(priority, float64(alpha))
+		priorities[i] = scalePriority(candidate.Priority, alpha)
 	}
 	return priorities
 }
 
+// scalePriority returns priority^alpha, treating priorities below a tiny
+// epsilon as epsilon so every transition can be drawn.
+func scalePriority(priority, alpha float32) float64 {
+	const epsilon = 1e-12
+
+	return math.Pow(math.Max(float64(priority), epsilon), float64(alpha))
+}
+
 func computePrioritizedProbabilities(candidates []*Transition, alpha float32) []float64 {
 	if len(candidates) == 0 {
 		return nil
@@ -504,6 +811,25 @@ func importanceWeight(probability float64, total int) float32 {
 	return float32(weight)
 }
 
+// ImportanceWeights turns the 1/(N*p) weights of a prioritized sample into
+// the (N*p)^-beta weights of prioritized replay, divided by the largest of
+// them so that corrections only ever scale updates down.
+func ImportanceWeights(weights []float32, beta float32) []float32 {
+	scaled := make([]float32, len(weights))
+	var largest float64
+	for i, weight := range weights {
+		w := math.Pow(float64(weight), float64(beta))
+		scaled[i] = float32(w)
+		largest = math.Max(largest, w)
+	}
+	if largest > 0 {
+		for i := range scaled {
+			scaled[i] = float32(float64(scaled[i]) / largest)
+		}
+	}
+	return scaled
+}
+
 func normalizeProbabilities(priorities []float64, total float64) []float64 {
 	probabilities := make([]float64, len(priorities))
 	if total == 0 {
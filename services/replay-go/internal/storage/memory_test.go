@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -150,6 +152,20 @@ func TestMemoryBackend_PrioritizedSampleWeightsNonIntegerAlpha(t *testing.T) {
 	}
 }
 
+func TestImportanceWeights(t *testing.T) {
+	// Probabilities 1/2, 1/4 and 1/4 of 3 candidates.
+	weights := []float32{2.0 / 3, 4.0 / 3, 4.0 / 3}
+	scaled := ImportanceWeights(weights, 0.5)
+	assert.InDelta(t, math.Sqrt(0.5), scaled[0], 1e-6)
+	assert.InDelta(t, 1, scaled[1], 1e-6)
+	assert.InDelta(t, 1, scaled[2], 1e-6)
+
+	// Beta 1 is the full correction, normalized.
+	assert.InDeltaSlice(t, []float32{0.5, 1, 1}, ImportanceWeights(weights, 1), 1e-6)
+	assert.Equal(t, []float32{1, 1, 1}, ImportanceWeights([]float32{1, 1, 1}, 0.4))
+	assert.Empty(t, ImportanceWeights(nil, 0.4))
+}
+
 func TestMemoryBackend_PrioritizedSampleDistribution(t *testing.T) {
 	backend := NewMemoryBackend(1000)
 	defer backend.Close()
@@ -192,6 +208,124 @@ func TestMemoryBackend_PrioritizedSampleDistribution(t *testing.T) {
 	}
 }
 
+func TestMemoryBackend_ReservoirSampleDistribution(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+
+	backend.rng = rand.New(rand.NewSource(123))
+	ctx := context.Background()
+
+	transitions := []*Transition{
+		{ID: "low", Priority: 0.1},
+		{ID: "medium", Priority: 1.0},
+		{ID: "high", Priority: 2.4},
+	}
+
+	_, err := backend.StoreBatch(ctx, transitions)
+	require.NoError(t, err)
+
+	config := &SampleConfig{
+		BatchSize:     1,
+		Prioritized:   true,
+		PriorityAlpha: 0.6,
+		Reservoir:     true,
+	}
+
+	iterations := 2000
+	counts := map[string]int{}
+
+	for i := 0; i < iterations; i++ {
+		sampled, weights, err := backend.Sample(ctx, config)
+		require.NoError(t, err)
+		require.Len(t, sampled, 1)
+		require.Len(t, weights, 1)
+		counts[sampled[0].ID]++
+	}
+
+	probabilities := computePrioritizedProbabilities(transitions, config.PriorityAlpha)
+	tolerance := float64(iterations) * 0.05
+
+	for i, transition := range transitions {
+		expected := float64(iterations) * probabilities[i]
+		actual := float64(counts[transition.ID])
+		assert.InDeltaf(t, expected, actual, tolerance, "unexpected sampling frequency for %s", transition.ID)
+	}
+
+	config.BatchSize = uint32(len(transitions))
+	sampled, _, err := backend.Sample(ctx, config)
+	require.NoError(t, err)
+	require.Len(t, sampled, len(transitions))
+	seen := map[string]bool{}
+	for _, transition := range sampled {
+		seen[transition.ID] = true
+	}
+	assert.Len(t, seen, len(transitions), "a batch never repeats a transition")
+}
+
+func TestMemoryBackend_ReservoirBypassesSumTrees(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+
+	ctx := context.Background()
+	_, err := backend.StoreBatch(ctx, []*Transition{
+		{ID: "a", Priority: 0.5},
+		{ID: "b", Priority: 2.0},
+	})
+	require.NoError(t, err)
+
+	config := &SampleConfig{BatchSize: 2, Prioritized: true, PriorityAlpha: 0.6, Reservoir: true}
+	sampled, _, err := backend.Sample(ctx, config)
+	require.NoError(t, err)
+	require.Len(t, sampled, 2)
+	assert.Nil(t, backend.priorities, "an unfiltered reservoir sample must not be drawn from the sum trees")
+
+	config.Reservoir = false
+	_, _, err = backend.Sample(ctx, config)
+	require.NoError(t, err)
+	assert.NotNil(t, backend.priorities, "an unfiltered prioritized sample is drawn from the sum trees")
+}
+
+func TestMemoryBackend_RunIndex(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	transitions := []*Transition{
+		{EnvID: "tictactoe", State: []byte{1}, Metadata: map[string]string{"run_id": "run-a"}},
+		{EnvID: "tictactoe", State: []byte{2}, Metadata: map[string]string{"run_id": "run-b"}},
+		{EnvID: "gridworld", State: []byte{3}, Metadata: map[string]string{"run_id": "run-a"}},
+		{EnvID: "tictactoe", State: []byte{4}},
+	}
+
+	_, err := backend.StoreBatch(ctx, transitions)
+	require.NoError(t, err)
+
+	stats, err := backend.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"run-a": 2, "run-b": 1}, stats.TransitionsByRun)
+
+	stats, err = backend.GetStats(ctx, "tictactoe")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"run-a": 1, "run-b": 1}, stats.TransitionsByRun)
+
+	// Run and environment filters combine
+	sampled, _, err := backend.Sample(ctx, &SampleConfig{BatchSize: 10, RunID: "run-a", EnvID: "tictactoe"})
+	require.NoError(t, err)
+	require.Len(t, sampled, 1)
+	assert.Equal(t, []byte{1}, sampled[0].State)
+
+	_, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 10, RunID: "run-c"})
+	assert.ErrorIs(t, err, ErrNoTransitions)
+
+	// Cleared transitions leave the index
+	_, err = backend.Clear(ctx, "", nil, 1)
+	require.NoError(t, err)
+	stats, err = backend.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, stats.TransitionsByRun)
+}
+
 func TestMemoryBackend_UpdatePriorities(t *testing.T) {
 	backend := NewMemoryBackend(1000)
 	defer backend.Close()
@@ -250,6 +384,74 @@ func TestMemoryBackend_Clear(t *testing.T) {
 	assert.Equal(t, uint64(2), stats.TotalTransitions)
 }
 
+func TestMemoryBackend_GetEpisode(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err := backend.StoreBatch(ctx, []*Transition{
+		{ID: "b", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 1, Timestamp: now},
+		{ID: "other", EnvID: "tictactoe", EpisodeID: "ep2", StepNumber: 0, Timestamp: now},
+		{ID: "a", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 0, Timestamp: now.Add(time.Second)},
+		{ID: "c", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 2, Timestamp: now},
+	})
+	require.NoError(t, err)
+
+	episode, err := backend.GetEpisode(ctx, "ep1")
+	require.NoError(t, err)
+	var ids []string
+	for _, transition := range episode {
+		ids = append(ids, transition.ID)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+
+	// The episode is a copy.
+	episode[0].Priority = 7
+	again, err := backend.GetEpisode(ctx, "ep1")
+	require.NoError(t, err)
+	assert.Equal(t, float32(1), again[0].Priority)
+
+	episode, err = backend.GetEpisode(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, episode)
+}
+
+func TestMemoryBackend_Export(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err := backend.StoreBatch(ctx, []*Transition{
+		{ID: "b", EnvID: "tictactoe", State: []byte{2}, Timestamp: now},
+		{ID: "c", EnvID: "gridworld", State: []byte{3}, Timestamp: now.Add(time.Minute)},
+		{ID: "a", EnvID: "tictactoe", State: []byte{1}, Timestamp: now.Add(-time.Minute)},
+	})
+	require.NoError(t, err)
+
+	var ids []string
+	collect := func(transition *Transition) error {
+		ids = append(ids, transition.ID)
+		return nil
+	}
+	require.NoError(t, backend.Export(ctx, "", collect))
+	assert.Equal(t, []string{"a", "b", "c"}, ids) // Oldest first
+
+	ids = nil
+	require.NoError(t, backend.Export(ctx, "tictactoe", collect))
+	assert.Equal(t, []string{"a", "b"}, ids)
+
+	stop := errors.New("stop")
+	ids = nil
+	err = backend.Export(ctx, "", func(transition *Transition) error {
+		ids = append(ids, transition.ID)
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []string{"a"}, ids)
+}
+
 func TestMemoryBackend_MaxSize(t *testing.T) {
 	backend := NewMemoryBackend(2) // Max 2 transitions
 	defer backend.Close()
@@ -274,6 +476,33 @@ func TestMemoryBackend_MaxSize(t *testing.T) {
 	assert.Equal(t, uint64(2), stats.TotalTransitions) // Should evict oldest
 }
 
+func TestMemoryBackend_OnEvict(t *testing.T) {
+	backend := NewMemoryBackend(2)
+	defer backend.Close()
+
+	var evicted []string
+	backend.OnEvict(func(transition *Transition) {
+		evicted = append(evicted, transition.ID)
+	})
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err := backend.StoreBatch(ctx, []*Transition{
+		{ID: "a", EnvID: "test", Timestamp: now},
+		{ID: "b", EnvID: "test", Timestamp: now.Add(time.Minute)},
+		{ID: "c", EnvID: "test", Timestamp: now.Add(2 * time.Minute)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, evicted)
+
+	// Clearing is not eviction.
+	cutoff := now.Add(time.Hour)
+	cleared, err := backend.Clear(ctx, "", &cutoff, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), cleared)
+	assert.Equal(t, []string{"a"}, evicted)
+}
+
 func TestMemoryBackend_TimeFiltering(t *testing.T) {
 	backend := NewMemoryBackend(1000)
 	defer backend.Close()
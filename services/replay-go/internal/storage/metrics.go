@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statsTimeout bounds the GetStats call each scrape of the buffer gauges makes.
+const statsTimeout = 5 * time.Second
+
+// WithMetrics returns backend with its stores, samples and evictions recorded
+// on reg, and a collector registered that reports the buffer's size on each
+// scrape. Evictions are only counted for backends that are EvictionNotifiers,
+// and the backend returned is one too if backend is: functions set with its
+// OnEvict are called after the eviction is counted.
+func WithMetrics(backend Backend, reg prometheus.Registerer) Backend {
+	batchBuckets := prometheus.ExponentialBuckets(1, 2, 12)
+	m := &metricsBackend{
+		Backend: backend,
+		stored: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "replay_transitions_stored_total",
+			Help: "Transitions stored in the replay buffer.",
+		}),
+		storeSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "replay_store_batch_size",
+			Help:    "Transitions per store call.",
+			Buckets: batchBuckets,
+		}),
+		sampled: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "replay_transitions_sampled_total",
+			Help: "Transitions sampled from the replay buffer.",
+		}),
+		sampleSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "replay_sample_batch_size",
+			Help:    "Transitions returned per sample call.",
+			Buckets: batchBuckets,
+		}),
+		starved: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "replay_samples_starved_total",
+			Help: "Sample calls that found no transitions to sample.",
+		}),
+		evicted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "replay_transitions_evicted_total",
+			Help: "Transitions evicted to keep the replay buffer within its maximum size.",
+		}),
+	}
+	reg.MustRegister(m.stored, m.storeSize, m.sampled, m.sampleSize, m.starved, m.evicted, &bufferCollector{backend: backend})
+
+	notifier, ok := backend.(EvictionNotifier)
+	if !ok {
+		return m
+	}
+	n := &metricsNotifier{metricsBackend: m, notifier: notifier}
+	notifier.OnEvict(n.evict)
+	return n
+}
+
+type metricsBackend struct {
+	Backend
+	stored     prometheus.Counter
+	storeSize  prometheus.Histogram
+	sampled    prometheus.Counter
+	sampleSize prometheus.Histogram
+	starved    prometheus.Counter
+	evicted    prometheus.Counter
+}
+
+// Store implements Backend.Store
+func (m *metricsBackend) Store(ctx context.Context, transition *Transition) error {
+	err := m.Backend.Store(ctx, transition)
+	m.storeSize.Observe(1)
+	if err == nil {
+		m.stored.Inc()
+	}
+	return err
+}
+
+// StoreBatch implements Backend.StoreBatch. Transitions are counted as stored
+// when the backend returns their IDs, even if the batch as a whole failed.
+func (m *metricsBackend) StoreBatch(ctx context.Context, transitions []*Transition) ([]string, error) {
+	ids, err := m.Backend.StoreBatch(ctx, transitions)
+	m.storeSize.Observe(float64(len(transitions)))
+	m.stored.Add(float64(len(ids)))
+	return ids, err
+}
+
+// Sample implements Backend.Sample
+func (m *metricsBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Transition, []float32, error) {
+	transitions, weights, err := m.Backend.Sample(ctx, config)
+	if errors.Is(err, ErrNoTransitions) {
+		m.starved.Inc()
+	}
+	if err == nil {
+		m.sampleSize.Observe(float64(len(transitions)))
+		m.sampled.Add(float64(len(transitions)))
+	}
+	return transitions, weights, err
+}
+
+// metricsNotifier is a metricsBackend around an EvictionNotifier.
+type metricsNotifier struct {
+	*metricsBackend
+	notifier EvictionNotifier
+}
+
+// OnEvict implements EvictionNotifier. fn is called after the eviction is
+// counted.
+func (n *metricsNotifier) OnEvict(fn func(*Transition)) {
+	n.notifier.OnEvict(func(transition *Transition) {
+		n.evicted.Inc()
+		fn(transition)
+	})
+}
+
+func (n *metricsNotifier) evict(*Transition) {
+	n.evicted.Inc()
+}
+
+// bufferCollector reports the size of the buffer from the backend's stats
+// when it is scraped.
+type bufferCollector struct {
+	backend Backend
+}
+
+var (
+	bufferTransitionsDesc = prometheus.NewDesc("replay_buffer_transitions", "Transitions in the replay buffer.", nil, nil)
+	bufferBytesDesc       = prometheus.NewDesc("replay_buffer_bytes", "Approximate size of the transitions in the replay buffer.", nil, nil)
+	bufferEnvDesc         = prometheus.NewDesc("replay_buffer_env_transitions", "Transitions in the replay buffer, by environment.", []string{"env_id"}, nil)
+)
+
+// Describe implements prometheus.Collector
+func (c *bufferCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bufferTransitionsDesc
+	ch <- bufferBytesDesc
+	ch <- bufferEnvDesc
+}
+
+// Collect implements prometheus.Collector. A failure to get the stats is
+// reported as an invalid metric, which the scrape counts as an error.
+func (c *bufferCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+	stats, err := c.backend.GetStats(ctx, "")
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(bufferTransitionsDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(bufferTransitionsDesc, prometheus.GaugeValue, float64(stats.TotalTransitions))
+	ch <- prometheus.MustNewConstMetric(bufferBytesDesc, prometheus.GaugeValue, float64(stats.StorageBytes))
+	for envID, count := range stats.TransitionsByEnv {
+		ch <- prometheus.MustNewConstMetric(bufferEnvDesc, prometheus.GaugeValue, float64(count), envID)
+	}
+}
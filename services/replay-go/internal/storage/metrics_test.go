@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	memory := NewMemoryBackend(5)
+	defer memory.Close()
+	backend := WithMetrics(memory, reg)
+	ctx := context.Background()
+
+	_, _, err := backend.Sample(ctx, &SampleConfig{BatchSize: 4})
+	require.ErrorIs(t, err, ErrNoTransitions)
+
+	var archived []*Transition
+	backend.(EvictionNotifier).OnEvict(func(transition *Transition) {
+		archived = append(archived, transition)
+	})
+	batch := make([]*Transition, 8)
+	for i := range batch {
+		batch[i] = &Transition{EnvID: "tictactoe", EpisodeID: "ep", StepNumber: uint32(i), State: []byte("state")}
+	}
+	_, err = backend.StoreBatch(ctx, batch)
+	require.NoError(t, err)
+	assert.Len(t, archived, 3)
+
+	transitions, _, err := backend.Sample(ctx, &SampleConfig{BatchSize: 4})
+	require.NoError(t, err)
+	require.Len(t, transitions, 4)
+
+	want := `
+# HELP replay_buffer_env_transitions Transitions in the replay buffer, by environment.
+# TYPE replay_buffer_env_transitions gauge
+replay_buffer_env_transitions{env_id="tictactoe"} 5
+# HELP replay_buffer_transitions Transitions in the replay buffer.
+# TYPE replay_buffer_transitions gauge
+replay_buffer_transitions 5
+# HELP replay_samples_starved_total Sample calls that found no transitions to sample.
+# TYPE replay_samples_starved_total counter
+replay_samples_starved_total 1
+# HELP replay_transitions_evicted_total Transitions evicted to keep the replay buffer within its maximum size.
+# TYPE replay_transitions_evicted_total counter
+replay_transitions_evicted_total 3
+# HELP replay_transitions_sampled_total Transitions sampled from the replay buffer.
+# TYPE replay_transitions_sampled_total counter
+replay_transitions_sampled_total 4
+# HELP replay_transitions_stored_total Transitions stored in the replay buffer.
+# TYPE replay_transitions_stored_total counter
+replay_transitions_stored_total 8
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"replay_buffer_env_transitions", "replay_buffer_transitions", "replay_samples_starved_total",
+		"replay_transitions_evicted_total", "replay_transitions_sampled_total", "replay_transitions_stored_total"))
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	sums := map[string]float64{}
+	for _, family := range families {
+		if histogram := family.GetMetric()[0].GetHistogram(); histogram != nil {
+			assert.Equal(t, uint64(1), histogram.GetSampleCount(), family.GetName())
+			sums[family.GetName()] = histogram.GetSampleSum()
+		}
+	}
+	assert.Equal(t, map[string]float64{"replay_store_batch_size": 8, "replay_sample_batch_size": 4}, sums)
+}
+
+func TestWithMetrics_NotifierOnlyWhenBackendIs(t *testing.T) {
+	backend := WithMetrics(&faultyBackend{Backend: NewMemoryBackend(10)}, prometheus.NewRegistry())
+	defer backend.Close()
+	_, ok := backend.(EvictionNotifier)
+	assert.False(t, ok)
+}
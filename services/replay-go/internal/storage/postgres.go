@@ -0,0 +1,473 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/cartridge/proto/runctx"
+)
+
+// postgresInsertRows bounds the rows of one INSERT, keeping its parameters
+// well under PostgreSQL's limit of 65535.
+const postgresInsertRows = 1000
+
+// postgresExportBatch is how many transitions Export reads per query.
+const postgresExportBatch = 256
+
+// transitionColumns is the column list scanned by scanTransition, and the
+// order in which insertTransitions binds them.
+const transitionColumns = `id, env_id, episode_id, run_id, step_number, state, action, next_state,
+	observation, next_observation, reward, done, priority, ts, metadata`
+
+// transitionColumnCount is the number of columns in transitionColumns.
+const transitionColumnCount = 15
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// PostgresBackend is a replay buffer kept in the replay_transitions table, so
+// it can outgrow memory, be shared by replicas and be queried with SQL. The
+// schema comes from internal/migrations. Samples, stats and clears filter in
+// SQL; writes lock the replay_buffer row, which counts the transitions for
+// eviction, so the buffer should only be changed through the service.
+type PostgresBackend struct {
+	db      *sql.DB
+	maxSize uint64
+}
+
+// NewPostgresBackend creates a backend on db, which it closes on Close.
+// maxSize is the number of transitions kept before the oldest are evicted, as
+// for NewMemoryBackend.
+func NewPostgresBackend(db *sql.DB, maxSize uint64) *PostgresBackend {
+	return &PostgresBackend{db: db, maxSize: maxSize}
+}
+
+// Store implements Backend.Store
+func (p *PostgresBackend) Store(ctx context.Context, transition *Transition) error {
+	_, err := p.StoreBatch(ctx, []*Transition{transition})
+	return err
+}
+
+// StoreBatch implements Backend.StoreBatch. The batch is stored in one
+// transaction, replacing transitions with the same IDs.
+func (p *PostgresBackend) StoreBatch(ctx context.Context, transitions []*Transition) ([]string, error) {
+	if len(transitions) == 0 {
+		return []string{}, nil
+	}
+	ids := make([]string, len(transitions))
+	latest := make(map[string]int, len(transitions))
+	for i, transition := range transitions {
+		if transition.ID == "" {
+			transition.ID = uuid.New().String()
+		}
+		if transition.Timestamp.IsZero() {
+			transition.Timestamp = time.Now()
+		}
+		if transition.Priority == 0 {
+			transition.Priority = 1.0
+		}
+		ids[i] = transition.ID
+		latest[transition.ID] = i
+	}
+	// A batch that repeats an ID stores the last transition with it.
+	rows := make([]*Transition, 0, len(latest))
+	unique := make([]string, 0, len(latest))
+	for i, transition := range transitions {
+		if latest[transition.ID] == i {
+			rows = append(rows, transition)
+			unique = append(unique, transition.ID)
+		}
+	}
+
+	err := p.withBuffer(ctx, func(tx *sql.Tx, count int64) (int64, error) {
+		result, err := tx.ExecContext(ctx, `DELETE FROM replay_transitions WHERE id = ANY($1)`, pq.Array(unique))
+		if err != nil {
+			return 0, fmt.Errorf("failed to replace transitions: %w", err)
+		}
+		replaced, _ := result.RowsAffected()
+		count -= replaced
+
+		for start := 0; start < len(rows); start += postgresInsertRows {
+			end := start + postgresInsertRows
+			if end > len(rows) {
+				end = len(rows)
+			}
+			query, args, err := insertTransitions(rows[start:end])
+			if err != nil {
+				return 0, err
+			}
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return 0, fmt.Errorf("failed to insert transitions: %w", err)
+			}
+			count += int64(end - start)
+		}
+
+		if p.maxSize > 0 && count > int64(p.maxSize) {
+			result, err := tx.ExecContext(ctx, `
+				DELETE FROM replay_transitions WHERE seq IN (
+					SELECT seq FROM replay_transitions ORDER BY ts, seq LIMIT $1
+				)`, count-int64(p.maxSize))
+			if err != nil {
+				return 0, fmt.Errorf("failed to evict transitions: %w", err)
+			}
+			evicted, _ := result.RowsAffected()
+			count -= evicted
+		}
+		return count, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Sample implements Backend.Sample. Prioritized samples, with or without
+// Reservoir, order the candidates by the weighted reservoir key of
+// reservoirSample, which draws the distribution of prioritizedSample.
+func (p *PostgresBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Transition, []float32, error) {
+	where, args := sampleFilter(config)
+	var query string
+	if config.Prioritized {
+		args = append(args, float64(config.PriorityAlpha), config.BatchSize)
+		query = fmt.Sprintf(`
+			SELECT %s, weight / sum(weight) OVER (), count(*) OVER ()
+			FROM (
+				SELECT *, power(greatest(priority, 1e-12), $%d::float8) AS weight
+				FROM replay_transitions%s
+			) candidates
+			ORDER BY ln(1 - random()) / weight DESC
+			LIMIT $%d`, transitionColumns, len(args)-1, where, len(args))
+	} else {
+		args = append(args, config.BatchSize)
+		query = fmt.Sprintf(`SELECT %s FROM replay_transitions%s ORDER BY random() LIMIT $%d`,
+			transitionColumns, where, len(args))
+	}
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sample transitions: %w", err)
+	}
+	defer rows.Close()
+
+	var sampled []*Transition
+	var weights []float32
+	for rows.Next() {
+		var probability float64
+		var candidates int64
+		var transition *Transition
+		if config.Prioritized {
+			transition, err = scanTransition(rows, &probability, &candidates)
+		} else {
+			transition, err = scanTransition(rows)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to sample transitions: %w", err)
+		}
+		sampled = append(sampled, transition)
+		if config.Prioritized {
+			weights = append(weights, importanceWeight(probability, int(candidates)))
+		} else {
+			weights = append(weights, 1.0)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to sample transitions: %w", err)
+	}
+	if len(sampled) == 0 {
+		return nil, nil, ErrNoTransitions
+	}
+	return sampled, weights, nil
+}
+
+// GetStats implements Backend.GetStats. StorageBytes is the size of the
+// replay_transitions table with its indexes.
+func (p *PostgresBackend) GetStats(ctx context.Context, envID string) (*Stats, error) {
+	stats := &Stats{
+		TransitionsByEnv: make(map[string]uint64),
+		TransitionsByRun: make(map[string]uint64),
+	}
+	var oldest, newest sql.NullTime
+	err := p.db.QueryRowContext(ctx, `
+		SELECT (SELECT transitions FROM replay_buffer),
+			   (SELECT count(DISTINCT episode_id) FROM replay_transitions WHERE episode_id <> ''),
+			   min(ts), max(ts), pg_total_relation_size('replay_transitions')
+		FROM replay_transitions`).Scan(&stats.TotalTransitions, &stats.TotalEpisodes, &oldest, &newest, &stats.StorageBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	if oldest.Valid {
+		stats.OldestTimestamp = &oldest.Time
+		stats.NewestTimestamp = &newest.Time
+	}
+
+	counts := map[string]map[string]uint64{"env_id": stats.TransitionsByEnv, "run_id": stats.TransitionsByRun}
+	for column, into := range counts {
+		rows, err := p.db.QueryContext(ctx, fmt.Sprintf(`
+			SELECT %[1]s, count(*) FROM replay_transitions
+			WHERE %[1]s <> '' AND ($1 = '' OR env_id = $1)
+			GROUP BY %[1]s`, column), envID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stats: %w", err)
+		}
+		for rows.Next() {
+			var value string
+			var n uint64
+			if err := rows.Scan(&value, &n); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to get stats: %w", err)
+			}
+			into[value] = n
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get stats: %w", err)
+		}
+	}
+	return stats, nil
+}
+
+// UpdatePriorities implements Backend.UpdatePriorities
+func (p *PostgresBackend) UpdatePriorities(ctx context.Context, transitionIDs []string, priorities []float32) error {
+	if len(transitionIDs) != len(priorities) {
+		return fmt.Errorf("mismatched lengths: %d IDs vs %d priorities", len(transitionIDs), len(priorities))
+	}
+	if len(transitionIDs) == 0 {
+		return nil
+	}
+	values := make([]float64, len(priorities))
+	for i, priority := range priorities {
+		values[i] = float64(priority)
+	}
+	_, err := p.db.ExecContext(ctx, `
+		UPDATE replay_transitions AS t SET priority = u.priority
+		FROM unnest($1::text[], $2::real[]) AS u(id, priority)
+		WHERE t.id = u.id`, pq.Array(transitionIDs), pq.Array(values))
+	if err != nil {
+		return fmt.Errorf("failed to update priorities: %w", err)
+	}
+	return nil
+}
+
+// Clear implements Backend.Clear
+func (p *PostgresBackend) Clear(ctx context.Context, envID string, beforeTimestamp *time.Time, keepLastN uint32) (uint64, error) {
+	args := []any{envID}
+	var criteria []string
+	if beforeTimestamp != nil {
+		args = append(args, *beforeTimestamp)
+		criteria = append(criteria, fmt.Sprintf("ts < $%d", len(args)))
+	}
+	if keepLastN > 0 {
+		args = append(args, keepLastN)
+		criteria = append(criteria, fmt.Sprintf(`seq NOT IN (
+			SELECT seq FROM replay_transitions WHERE $1 = '' OR env_id = $1
+			ORDER BY ts DESC, seq DESC LIMIT $%d)`, len(args)))
+	}
+	if len(criteria) == 0 {
+		return 0, nil
+	}
+
+	var cleared int64
+	err := p.withBuffer(ctx, func(tx *sql.Tx, count int64) (int64, error) {
+		result, err := tx.ExecContext(ctx, `DELETE FROM replay_transitions WHERE ($1 = '' OR env_id = $1) AND (`+
+			strings.Join(criteria, " OR ")+`)`, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to clear transitions: %w", err)
+		}
+		cleared, _ = result.RowsAffected()
+		return count - cleared, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(cleared), nil
+}
+
+// Export implements Backend.Export. Transitions are read postgresExportBatch
+// at a time, and fn is called between queries, so the export is not a
+// snapshot of the table.
+func (p *PostgresBackend) Export(ctx context.Context, envID string, fn func(*Transition) error) error {
+	var afterTS time.Time
+	var afterSeq int64
+	for {
+		rows, err := p.db.QueryContext(ctx, `
+			SELECT `+transitionColumns+`, seq FROM replay_transitions
+			WHERE ($1 = '' OR env_id = $1) AND (ts, seq) > ($2, $3)
+			ORDER BY ts, seq LIMIT $4`, envID, afterTS, afterSeq, postgresExportBatch)
+		if err != nil {
+			return fmt.Errorf("failed to export transitions: %w", err)
+		}
+		var page []*Transition
+		for rows.Next() {
+			transition, err := scanTransition(rows, &afterSeq)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to export transitions: %w", err)
+			}
+			afterTS = transition.Timestamp
+			page = append(page, transition)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to export transitions: %w", err)
+		}
+
+		for _, transition := range page {
+			if err := fn(transition); err != nil {
+				return err
+			}
+		}
+		if len(page) < postgresExportBatch {
+			return nil
+		}
+	}
+}
+
+// GetEpisode implements Backend.GetEpisode with the episode_id index.
+func (p *PostgresBackend) GetEpisode(ctx context.Context, episodeID string) ([]*Transition, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+transitionColumns+` FROM replay_transitions
+		WHERE episode_id = $1
+		ORDER BY step_number, ts, seq`, episodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get episode: %w", err)
+	}
+	defer rows.Close()
+	var episode []*Transition
+	for rows.Next() {
+		transition, err := scanTransition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get episode: %w", err)
+		}
+		episode = append(episode, transition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get episode: %w", err)
+	}
+	return episode, nil
+}
+
+// Close implements Backend.Close
+func (p *PostgresBackend) Close() error {
+	return p.db.Close()
+}
+
+// withBuffer runs fn in a transaction holding the replay_buffer row, passing
+// it the number of stored transitions and recording the number it returns.
+func (p *PostgresBackend) withBuffer(ctx context.Context, fn func(tx *sql.Tx, count int64) (int64, error)) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var count int64
+	if err := tx.QueryRowContext(ctx, `SELECT transitions FROM replay_buffer FOR UPDATE`).Scan(&count); err != nil {
+		return fmt.Errorf("failed to lock replay_buffer: %w", err)
+	}
+	count, err = fn(tx, count)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE replay_buffer SET transitions = $1`, count); err != nil {
+		return fmt.Errorf("failed to update replay_buffer: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// sampleFilter returns the WHERE clause selecting a sample's candidates, with
+// its arguments numbered from $1.
+func sampleFilter(config *SampleConfig) (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if config.EnvID != "" {
+		add("env_id = $%d", config.EnvID)
+	}
+	if config.RunID != "" {
+		add("run_id = $%d", config.RunID)
+	}
+	if config.MinTimestamp != nil {
+		add("ts >= $%d", *config.MinTimestamp)
+	}
+	if config.MaxTimestamp != nil {
+		add("ts <= $%d", *config.MaxTimestamp)
+	}
+	if config.Terminal {
+		conditions = append(conditions, "done")
+	}
+	if config.Winning {
+		conditions = append(conditions, "episode_id IN (SELECT episode_id FROM replay_transitions WHERE done AND reward > 0 AND episode_id <> '')")
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// insertTransitions returns a multi-row INSERT of transitions and its
+// arguments.
+func insertTransitions(transitions []*Transition) (string, []any, error) {
+	var query strings.Builder
+	query.WriteString(`INSERT INTO replay_transitions (` + transitionColumns + `) VALUES `)
+	args := make([]any, 0, transitionColumnCount*len(transitions))
+	for i, t := range transitions {
+		metadata := []byte("{}")
+		if len(t.Metadata) > 0 {
+			var err error
+			if metadata, err = json.Marshal(t.Metadata); err != nil {
+				return "", nil, fmt.Errorf("transition %s: %w", t.ID, err)
+			}
+		}
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteByte('(')
+		for column := 1; column <= transitionColumnCount; column++ {
+			if column > 1 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", len(args)+column)
+		}
+		query.WriteByte(')')
+		args = append(args, t.ID, t.EnvID, t.EpisodeID, t.Metadata[runctx.RunIDKey], int64(t.StepNumber),
+			t.State, t.Action, t.NextState, t.Observation, t.NextObservation,
+			t.Reward, t.Done, t.Priority, t.Timestamp, string(metadata))
+	}
+	return query.String(), args, nil
+}
+
+// scanTransition scans the transitionColumns of a row, followed by extra.
+func scanTransition(row rowScanner, extra ...any) (*Transition, error) {
+	var t Transition
+	var runID string
+	var step int64
+	var metadata []byte
+	dest := []any{&t.ID, &t.EnvID, &t.EpisodeID, &runID, &step, &t.State, &t.Action, &t.NextState,
+		&t.Observation, &t.NextObservation, &t.Reward, &t.Done, &t.Priority, &t.Timestamp, &metadata}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	t.StepNumber = uint32(step)
+	if err := json.Unmarshal(metadata, &t.Metadata); err != nil {
+		return nil, fmt.Errorf("transition %s: invalid metadata: %w", t.ID, err)
+	}
+	if len(t.Metadata) == 0 {
+		t.Metadata = nil
+	}
+	return &t, nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleFilter(t *testing.T) {
+	where, args := sampleFilter(&SampleConfig{BatchSize: 4})
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+
+	minTime := time.Unix(1700000000, 0)
+	maxTime := minTime.Add(time.Hour)
+	where, args = sampleFilter(&SampleConfig{EnvID: "tictactoe", RunID: "run-1", MinTimestamp: &minTime, MaxTimestamp: &maxTime})
+	assert.Equal(t, " WHERE env_id = $1 AND run_id = $2 AND ts >= $3 AND ts <= $4", where)
+	assert.Equal(t, []any{"tictactoe", "run-1", minTime, maxTime}, args)
+
+	where, args = sampleFilter(&SampleConfig{MaxTimestamp: &maxTime})
+	assert.Equal(t, " WHERE ts <= $1", where)
+	assert.Equal(t, []any{maxTime}, args)
+
+	where, args = sampleFilter(&SampleConfig{EnvID: "tictactoe", Terminal: true, Winning: true})
+	assert.Equal(t, " WHERE env_id = $1 AND done AND episode_id IN (SELECT episode_id FROM replay_transitions WHERE done AND reward > 0 AND episode_id <> '')", where)
+	assert.Equal(t, []any{"tictactoe"}, args)
+}
+
+func TestInsertTransitions(t *testing.T) {
+	now := time.Now()
+	query, args, err := insertTransitions([]*Transition{
+		{ID: "a", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 3, State: []byte{1}, Reward: 1, Priority: 2, Timestamp: now, Metadata: map[string]string{"run_id": "run-1"}},
+		{ID: "b", EnvID: "gridworld", Timestamp: now},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(query, "INSERT INTO replay_transitions ("))
+	assert.Contains(t, query, "($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15), ($16,")
+	assert.True(t, strings.HasSuffix(query, "$30)"))
+	require.Len(t, args, 2*transitionColumnCount)
+	assert.Equal(t, strings.Count(transitionColumns, ",")+1, transitionColumnCount)
+
+	// run_id is copied out of the metadata so it can be indexed.
+	assert.Equal(t, []any{"a", "tictactoe", "ep1", "run-1", int64(3)}, args[:5])
+	assert.Equal(t, `{"run_id":"run-1"}`, args[transitionColumnCount-1])
+	assert.Equal(t, "", args[transitionColumnCount+3])
+	assert.Equal(t, "{}", args[2*transitionColumnCount-1])
+}
@@ -0,0 +1,608 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/cartridge/proto/runctx"
+)
+
+// DefaultRedisKeyPrefix starts the keys of a RedisBackend unless
+// RedisOptions.KeyPrefix says otherwise.
+const DefaultRedisKeyPrefix = "replay"
+
+// redisExportBatch is how many transitions Export reads from Redis at a time.
+const redisExportBatch = 256
+
+// RedisOptions configures a RedisBackend.
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix starts every key the backend uses, so several buffers can
+	// share a database; DefaultRedisKeyPrefix when empty.
+	KeyPrefix string
+	// MaxSize is the number of transitions kept before the oldest are
+	// evicted, as for NewMemoryBackend. It bounds the shared buffer, not each
+	// replica's share of it.
+	MaxSize uint64
+}
+
+// RedisBackend is a replay buffer kept in Redis, so several replay servers
+// can serve one buffer. Under KeyPrefix it keeps:
+//
+//	<prefix>:t:<id>              hash of the transition's JSON and its index values
+//	<prefix>:time                sorted set of IDs scored by timestamp (µs)
+//	<prefix>:priority            sorted set of IDs scored by priority
+//	<prefix>:env:<env>           set of the environment's IDs, and likewise
+//	<prefix>:episode:<episode>   for episodes and for run_id metadata
+//	<prefix>:run:<run>
+//	<prefix>:envs, :episodes, :runs   sets of the values indexed
+//	<prefix>:bytes               total size of the stored JSON
+//
+// Writes run as Lua scripts, so each is atomic with respect to the other
+// replicas. Samples read the candidates and their priorities in one script
+// and choose among them as the memory backend does, then fetch the chosen
+// transitions; one evicted in between is left out of the batch.
+type RedisBackend struct {
+	client *redis.Client
+	prefix string
+	max    uint64
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+}
+
+// redisRemove is shared by the scripts that delete transitions. It removes one
+// from the indexes and drops index entries left empty.
+const redisRemove = `
+local indexes = {'env', 'episode', 'run'}
+
+local function remove(p, id)
+	local key = p .. ':t:' .. id
+	if redis.call('EXISTS', key) == 0 then
+		return false
+	end
+	local values = redis.call('HMGET', key, unpack(indexes))
+	redis.call('DECRBY', p .. ':bytes', redis.call('HSTRLEN', key, 'data'))
+	redis.call('DEL', key)
+	redis.call('ZREM', p .. ':time', id)
+	redis.call('ZREM', p .. ':priority', id)
+	for i, index in ipairs(indexes) do
+		local value = values[i]
+		if value and value ~= '' then
+			local set = p .. ':' .. index .. ':' .. value
+			redis.call('SREM', set, id)
+			if redis.call('SCARD', set) == 0 then
+				redis.call('SREM', p .. ':' .. index .. 's', value)
+			end
+		end
+	end
+	return true
+end
+`
+
+// redisStore stores transitions given as ARGV[3:] in groups of id, JSON,
+// timestamp, priority, env, episode and run, replacing any with the same ID,
+// then evicts the oldest beyond ARGV[2].
+var redisStore = redis.NewScript(redisRemove + `
+local p, max = ARGV[1], tonumber(ARGV[2])
+for i = 3, #ARGV, 7 do
+	local id, data = ARGV[i], ARGV[i + 1]
+	local values = {ARGV[i + 4], ARGV[i + 5], ARGV[i + 6]}
+	remove(p, id)
+	local key = p .. ':t:' .. id
+	redis.call('HSET', key, 'data', data, 'env', values[1], 'episode', values[2], 'run', values[3])
+	redis.call('INCRBY', p .. ':bytes', string.len(data))
+	redis.call('ZADD', p .. ':time', ARGV[i + 2], id)
+	redis.call('ZADD', p .. ':priority', ARGV[i + 3], id)
+	for j, index in ipairs(indexes) do
+		if values[j] ~= '' then
+			redis.call('SADD', p .. ':' .. index .. ':' .. values[j], id)
+			redis.call('SADD', p .. ':' .. index .. 's', values[j])
+		end
+	end
+end
+if max > 0 then
+	local excess = redis.call('ZCARD', p .. ':time') - max
+	if excess > 0 then
+		for _, id in ipairs(redis.call('ZRANGE', p .. ':time', 0, excess - 1)) do
+			remove(p, id)
+		end
+	end
+end
+return 0
+`)
+
+// redisCandidates returns the IDs and priorities, interleaved, of the
+// transitions matching the env (ARGV[2]) and run (ARGV[3]) and timestamped
+// between ARGV[4] and ARGV[5].
+var redisCandidates = redis.NewScript(`
+local p, env, run, lower, upper = ARGV[1], ARGV[2], ARGV[3], ARGV[4], ARGV[5]
+local ids
+local filter = lower ~= '-inf' or upper ~= '+inf'
+if run ~= '' and env ~= '' then
+	ids = redis.call('SINTER', p .. ':run:' .. run, p .. ':env:' .. env)
+elseif run ~= '' then
+	ids = redis.call('SMEMBERS', p .. ':run:' .. run)
+elseif env ~= '' then
+	ids = redis.call('SMEMBERS', p .. ':env:' .. env)
+else
+	ids = redis.call('ZRANGEBYSCORE', p .. ':time', lower, upper)
+	filter = false
+end
+local lo, hi = -math.huge, math.huge
+if lower ~= '-inf' then lo = tonumber(lower) end
+if upper ~= '+inf' then hi = tonumber(upper) end
+local reply = {}
+for _, id in ipairs(ids) do
+	local keep = true
+	if filter then
+		local time = tonumber(redis.call('ZSCORE', p .. ':time', id))
+		keep = time >= lo and time <= hi
+	end
+	if keep then
+		reply[#reply + 1] = id
+		reply[#reply + 1] = redis.call('ZSCORE', p .. ':priority', id)
+	end
+end
+return reply
+`)
+
+// redisClear deletes the transitions of env ARGV[2], or all of them,
+// timestamped before ARGV[3] or older than the newest ARGV[4], and returns how
+// many it deleted.
+var redisClear = redis.NewScript(redisRemove + `
+local p, env, before, keep = ARGV[1], ARGV[2], ARGV[3], tonumber(ARGV[4])
+local doomed, seen = {}, {}
+local function doom(id)
+	if not seen[id] then
+		seen[id] = true
+		doomed[#doomed + 1] = id
+	end
+end
+if env == '' then
+	if before ~= '' then
+		for _, id in ipairs(redis.call('ZRANGEBYSCORE', p .. ':time', '-inf', '(' .. before)) do
+			doom(id)
+		end
+	end
+	if keep > 0 then
+		local excess = redis.call('ZCARD', p .. ':time') - keep
+		if excess > 0 then
+			for _, id in ipairs(redis.call('ZRANGE', p .. ':time', 0, excess - 1)) do
+				doom(id)
+			end
+		end
+	end
+else
+	local entries = {}
+	for _, id in ipairs(redis.call('SMEMBERS', p .. ':env:' .. env)) do
+		entries[#entries + 1] = {id, tonumber(redis.call('ZSCORE', p .. ':time', id))}
+	end
+	table.sort(entries, function(a, b)
+		return a[2] < b[2] or (a[2] == b[2] and a[1] < b[1])
+	end)
+	for i, entry in ipairs(entries) do
+		if (before ~= '' and entry[2] < tonumber(before)) or (keep > 0 and i <= #entries - keep) then
+			doom(entry[1])
+		end
+	end
+end
+for _, id in ipairs(doomed) do
+	remove(p, id)
+end
+return #doomed
+`)
+
+// redisStats returns the totals, the oldest and newest timestamps, and the
+// transitions per env and per run, limited to env ARGV[2] when it is set.
+var redisStats = redis.NewScript(`
+local p, env = ARGV[1], ARGV[2]
+local function counts(index)
+	local reply = {}
+	for _, value in ipairs(redis.call('SMEMBERS', p .. ':' .. index .. 's')) do
+		local set = p .. ':' .. index .. ':' .. value
+		local n
+		if env == '' then
+			n = redis.call('SCARD', set)
+		elseif index == 'env' then
+			n = value == env and redis.call('SCARD', set) or 0
+		else
+			n = #redis.call('SINTER', set, p .. ':env:' .. env)
+		end
+		if n > 0 then
+			reply[#reply + 1] = value
+			reply[#reply + 1] = n
+		end
+	end
+	return reply
+end
+local oldest = redis.call('ZRANGE', p .. ':time', 0, 0, 'WITHSCORES')
+local newest = redis.call('ZRANGE', p .. ':time', -1, -1, 'WITHSCORES')
+return {
+	redis.call('ZCARD', p .. ':time'),
+	redis.call('SCARD', p .. ':episodes'),
+	tonumber(redis.call('GET', p .. ':bytes') or '0'),
+	oldest[2] or '',
+	newest[2] or '',
+	counts('env'),
+	counts('run'),
+}
+`)
+
+// NewRedisBackend connects to Redis and verifies the connection.
+func NewRedisBackend(opts RedisOptions) (*RedisBackend, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", opts.Addr, err)
+	}
+	prefix := opts.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultRedisKeyPrefix
+	}
+	return &RedisBackend{
+		client: client,
+		prefix: prefix,
+		max:    opts.MaxSize,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Store implements Backend.Store
+func (r *RedisBackend) Store(ctx context.Context, transition *Transition) error {
+	_, err := r.StoreBatch(ctx, []*Transition{transition})
+	return err
+}
+
+// StoreBatch implements Backend.StoreBatch. The batch is stored atomically:
+// on error, none of it is.
+func (r *RedisBackend) StoreBatch(ctx context.Context, transitions []*Transition) ([]string, error) {
+	if len(transitions) == 0 {
+		return []string{}, nil
+	}
+	ids := make([]string, len(transitions))
+	args := make([]interface{}, 0, 2+7*len(transitions))
+	args = append(args, r.prefix, r.max)
+	for i, transition := range transitions {
+		if transition.ID == "" {
+			transition.ID = uuid.New().String()
+		}
+		if transition.Timestamp.IsZero() {
+			transition.Timestamp = time.Now()
+		}
+		if transition.Priority == 0 {
+			transition.Priority = 1.0
+		}
+		data, err := json.Marshal(transition)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, transition.ID, data, redisTime(transition.Timestamp), redisPriority(transition.Priority),
+			transition.EnvID, transition.EpisodeID, transition.Metadata[runctx.RunIDKey])
+		ids[i] = transition.ID
+	}
+	if err := redisStore.Run(ctx, r.client, nil, args...).Err(); err != nil {
+		return nil, fmt.Errorf("redis store: %w", err)
+	}
+	return ids, nil
+}
+
+// Sample implements Backend.Sample
+func (r *RedisBackend) Sample(ctx context.Context, config *SampleConfig) ([]*Transition, []float32, error) {
+	lower, upper := "-inf", "+inf"
+	if config.MinTimestamp != nil {
+		lower = redisTime(*config.MinTimestamp)
+	}
+	if config.MaxTimestamp != nil {
+		upper = redisTime(*config.MaxTimestamp)
+	}
+	reply, err := redisCandidates.Run(ctx, r.client, nil, r.prefix, config.EnvID, config.RunID, lower, upper).StringSlice()
+	if err != nil {
+		return nil, nil, fmt.Errorf("redis sample: %w", err)
+	}
+
+	// The candidates carry only what sampling needs; the chosen ones are
+	// loaded afterwards.
+	candidates := make([]*Transition, 0, len(reply)/2)
+	for i := 0; i+1 < len(reply); i += 2 {
+		priority, err := strconv.ParseFloat(reply[i+1], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("redis sample: priority of %s: %w", reply[i], err)
+		}
+		candidates = append(candidates, &Transition{ID: reply[i], Priority: float32(priority)})
+	}
+	if config.Terminal || config.Winning {
+		if candidates, err = r.filterOutcomes(ctx, candidates, config); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoTransitions
+	}
+
+	sampleSize := int(config.BatchSize)
+	if sampleSize > len(candidates) {
+		sampleSize = len(candidates)
+	}
+	var chosen []*Transition
+	var weights []float32
+	r.rngMu.Lock()
+	switch {
+	case config.Prioritized && config.Reservoir:
+		chosen, weights = reservoirSample(r.rng, candidates, sampleSize, config.PriorityAlpha)
+	case config.Prioritized:
+		chosen, weights = prioritizedSample(r.rng, candidates, sampleSize, config.PriorityAlpha)
+	default:
+		chosen = uniformSample(r.rng, candidates, sampleSize)
+		weights = makeUniformWeights(sampleSize)
+	}
+	r.rngMu.Unlock()
+
+	ids := make([]string, len(chosen))
+	for i, candidate := range chosen {
+		ids[i] = candidate.ID
+	}
+	loaded, err := r.load(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	sampled := make([]*Transition, 0, len(loaded))
+	kept := make([]float32, 0, len(loaded))
+	for i, transition := range loaded {
+		if transition != nil {
+			sampled = append(sampled, transition)
+			kept = append(kept, weights[i])
+		}
+	}
+	if len(sampled) == 0 {
+		return nil, nil, ErrNoTransitions
+	}
+	return sampled, kept, nil
+}
+
+// GetStats implements Backend.GetStats. StorageBytes is the size of the stored
+// JSON, not Redis' memory use.
+func (r *RedisBackend) GetStats(ctx context.Context, envID string) (*Stats, error) {
+	reply, err := redisStats.Run(ctx, r.client, nil, r.prefix, envID).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("redis stats: %w", err)
+	}
+	if len(reply) != 7 {
+		return nil, fmt.Errorf("redis stats: unexpected reply %v", reply)
+	}
+	stats := &Stats{
+		TotalTransitions: redisUint(reply[0]),
+		TotalEpisodes:    redisUint(reply[1]),
+		StorageBytes:     redisUint(reply[2]),
+		OldestTimestamp:  redisTimestamp(reply[3]),
+		NewestTimestamp:  redisTimestamp(reply[4]),
+		TransitionsByEnv: redisCounts(reply[5]),
+		TransitionsByRun: redisCounts(reply[6]),
+	}
+	return stats, nil
+}
+
+// UpdatePriorities implements Backend.UpdatePriorities
+func (r *RedisBackend) UpdatePriorities(ctx context.Context, transitionIDs []string, priorities []float32) error {
+	if len(transitionIDs) != len(priorities) {
+		return fmt.Errorf("mismatched lengths: %d IDs vs %d priorities", len(transitionIDs), len(priorities))
+	}
+	if len(transitionIDs) == 0 {
+		return nil
+	}
+
+	// XX leaves IDs that are not stored alone.
+	members := make([]redis.Z, len(transitionIDs))
+	for i, id := range transitionIDs {
+		members[i] = redis.Z{Score: float64(priorities[i]), Member: id}
+	}
+	if err := r.client.ZAddXX(ctx, r.key("priority"), members...).Err(); err != nil {
+		return fmt.Errorf("redis update priorities: %w", err)
+	}
+	return nil
+}
+
+// Clear implements Backend.Clear
+func (r *RedisBackend) Clear(ctx context.Context, envID string, beforeTimestamp *time.Time, keepLastN uint32) (uint64, error) {
+	before := ""
+	if beforeTimestamp != nil {
+		before = redisTime(*beforeTimestamp)
+	}
+	cleared, err := redisClear.Run(ctx, r.client, nil, r.prefix, envID, before, keepLastN).Uint64()
+	if err != nil {
+		return 0, fmt.Errorf("redis clear: %w", err)
+	}
+	return cleared, nil
+}
+
+// Export implements Backend.Export. The transitions are read redisExportBatch
+// at a time, so the export is not a snapshot: transitions stored meanwhile
+// are left out and those evicted meanwhile are skipped.
+func (r *RedisBackend) Export(ctx context.Context, envID string, fn func(*Transition) error) error {
+	ids, err := r.client.ZRange(ctx, r.key("time"), 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("redis export: %w", err)
+	}
+	for start := 0; start < len(ids); start += redisExportBatch {
+		end := start + redisExportBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		transitions, err := r.load(ctx, ids[start:end])
+		if err != nil {
+			return err
+		}
+		for _, transition := range transitions {
+			if transition == nil || (envID != "" && transition.EnvID != envID) {
+				continue
+			}
+			if err := fn(transition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetEpisode implements Backend.GetEpisode from the episode's index set.
+func (r *RedisBackend) GetEpisode(ctx context.Context, episodeID string) ([]*Transition, error) {
+	ids, err := r.client.SMembers(ctx, r.key("episode", episodeID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis get episode: %w", err)
+	}
+	loaded, err := r.load(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	episode := make([]*Transition, 0, len(loaded))
+	for _, transition := range loaded {
+		if transition != nil {
+			episode = append(episode, transition)
+		}
+	}
+	sortBySteps(episode)
+	return episode, nil
+}
+
+// filterOutcomes keeps the candidates that pass config's Terminal and Winning
+// filters. The candidates script sees only the indexes, so this loads every
+// candidate, and the episodes of those that ended.
+func (r *RedisBackend) filterOutcomes(ctx context.Context, candidates []*Transition, config *SampleConfig) ([]*Transition, error) {
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.ID
+	}
+	loaded, err := r.load(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	winning := make(map[string]bool) // EpisodeID -> whether it was won
+	kept := candidates[:0]
+	for i, transition := range loaded {
+		if transition == nil || (config.Terminal && !transition.Done) {
+			continue
+		}
+		if config.Winning {
+			won, checked := winning[transition.EpisodeID]
+			if !checked && transition.EpisodeID != "" {
+				episode, err := r.GetEpisode(ctx, transition.EpisodeID)
+				if err != nil {
+					return nil, err
+				}
+				for _, step := range episode {
+					won = won || (step.Done && step.Reward > 0)
+				}
+				winning[transition.EpisodeID] = won
+			}
+			if !won {
+				continue
+			}
+		}
+		kept = append(kept, candidates[i])
+	}
+	return kept, nil
+}
+
+// Close implements Backend.Close
+func (r *RedisBackend) Close() error {
+	return r.client.Close()
+}
+
+// load reads the transitions with the given IDs, with their current
+// priorities. An ID no longer stored leaves nil in its place.
+func (r *RedisBackend) load(ctx context.Context, ids []string) ([]*Transition, error) {
+	pipe := r.client.Pipeline()
+	data := make([]*redis.StringCmd, len(ids))
+	scores := make([]*redis.FloatCmd, len(ids))
+	for i, id := range ids {
+		data[i] = pipe.HGet(ctx, r.key("t", id), "data")
+		scores[i] = pipe.ZScore(ctx, r.key("priority"), id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("redis load: %w", err)
+	}
+
+	transitions := make([]*Transition, len(ids))
+	for i, id := range ids {
+		raw, err := data[i].Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redis load: %w", err)
+		}
+		transition := &Transition{}
+		if err := json.Unmarshal(raw, transition); err != nil {
+			return nil, fmt.Errorf("redis load: transition %s: %w", id, err)
+		}
+		if priority, err := scores[i].Result(); err == nil {
+			transition.Priority = float32(priority)
+		}
+		transitions[i] = transition
+	}
+	return transitions, nil
+}
+
+func (r *RedisBackend) key(parts ...string) string {
+	return r.prefix + ":" + strings.Join(parts, ":")
+}
+
+// redisTime is a timestamp's score in the time index.
+func redisTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixMicro(), 10)
+}
+
+// redisPriority formats a priority so that it reads back exactly.
+func redisPriority(priority float32) string {
+	return strconv.FormatFloat(float64(priority), 'g', -1, 32)
+}
+
+func redisUint(value interface{}) uint64 {
+	n, _ := value.(int64)
+	if n < 0 {
+		return 0
+	}
+	return uint64(n)
+}
+
+func redisTimestamp(value interface{}) *time.Time {
+	score, _ := value.(string)
+	if score == "" {
+		return nil
+	}
+	micros, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return nil
+	}
+	t := time.UnixMicro(int64(micros))
+	return &t
+}
+
+func redisCounts(value interface{}) map[string]uint64 {
+	counts := make(map[string]uint64)
+	pairs, _ := value.([]interface{})
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if name, ok := pairs[i].(string); ok {
+			counts[name] = redisUint(pairs[i+1])
+		}
+	}
+	return counts
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisBackend(t *testing.T, server *miniredis.Miniredis, maxSize uint64) *RedisBackend {
+	t.Helper()
+	backend, err := NewRedisBackend(RedisOptions{Addr: server.Addr(), MaxSize: maxSize})
+	require.NoError(t, err)
+	t.Cleanup(func() { backend.Close() })
+	return backend
+}
+
+func TestRedisBackend_StoreAndStats(t *testing.T) {
+	backend := newTestRedisBackend(t, miniredis.RunT(t), 1000)
+	ctx := context.Background()
+	now := time.Now()
+
+	ids, err := backend.StoreBatch(ctx, []*Transition{
+		{EnvID: "tictactoe", EpisodeID: "ep1", State: []byte{1}, Timestamp: now.Add(-time.Minute), Metadata: map[string]string{"run_id": "run-1"}},
+		{EnvID: "tictactoe", EpisodeID: "ep1", State: []byte{2}, Timestamp: now},
+		{EnvID: "gridworld", EpisodeID: "ep2", State: []byte{3}, Timestamp: now.Add(time.Minute), Metadata: map[string]string{"run_id": "run-1"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 3)
+	assert.NotEmpty(t, ids[0])
+
+	stats, err := backend.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), stats.TotalTransitions)
+	assert.Equal(t, uint64(2), stats.TotalEpisodes)
+	assert.Equal(t, map[string]uint64{"tictactoe": 2, "gridworld": 1}, stats.TransitionsByEnv)
+	assert.Equal(t, map[string]uint64{"run-1": 2}, stats.TransitionsByRun)
+	assert.Positive(t, stats.StorageBytes)
+	require.NotNil(t, stats.OldestTimestamp)
+	assert.Equal(t, now.Add(-time.Minute).UnixMicro(), stats.OldestTimestamp.UnixMicro())
+	assert.Equal(t, now.Add(time.Minute).UnixMicro(), stats.NewestTimestamp.UnixMicro())
+
+	stats, err = backend.GetStats(ctx, "gridworld")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"gridworld": 1}, stats.TransitionsByEnv)
+	assert.Equal(t, map[string]uint64{"run-1": 1}, stats.TransitionsByRun)
+
+	require.NoError(t, backend.Store(ctx, &Transition{EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 2, State: []byte{4}}))
+	require.NoError(t, backend.UpdatePriorities(ctx, []string{ids[1]}, []float32{3}))
+	episode, err := backend.GetEpisode(ctx, "ep1")
+	require.NoError(t, err)
+	require.Len(t, episode, 3)
+	assert.Equal(t, []byte{1}, episode[0].State)
+	assert.Equal(t, float32(3), episode[1].Priority)
+	assert.Equal(t, uint32(2), episode[2].StepNumber)
+	episode, err = backend.GetEpisode(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, episode)
+}
+
+func TestRedisBackend_Sample(t *testing.T) {
+	backend := newTestRedisBackend(t, miniredis.RunT(t), 1000)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		env := "tictactoe"
+		if i%2 == 1 {
+			env = "gridworld"
+		}
+		require.NoError(t, backend.Store(ctx, &Transition{
+			EnvID:     env,
+			State:     []byte{byte(i)},
+			Reward:    float32(i),
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Metadata:  map[string]string{"run_id": "run-1"},
+		}))
+	}
+
+	transitions, weights, err := backend.Sample(ctx, &SampleConfig{BatchSize: 4, EnvID: "tictactoe"})
+	require.NoError(t, err)
+	assert.Len(t, transitions, 4)
+	assert.Equal(t, []float32{1, 1, 1, 1}, weights)
+	for _, transition := range transitions {
+		assert.Equal(t, "tictactoe", transition.EnvID)
+		assert.Equal(t, "run-1", transition.Metadata["run_id"])
+	}
+
+	// Time windows and run filters narrow the candidates.
+	minTime, maxTime := now.Add(2*time.Second), now.Add(5*time.Second)
+	transitions, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 10, RunID: "run-1", EnvID: "gridworld", MinTimestamp: &minTime, MaxTimestamp: &maxTime})
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	for _, transition := range transitions {
+		assert.Contains(t, []float32{3, 5}, transition.Reward)
+	}
+	transitions, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 10, MinTimestamp: &minTime, MaxTimestamp: &maxTime})
+	require.NoError(t, err)
+	assert.Len(t, transitions, 4)
+
+	_, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 1, EnvID: "chess"})
+	assert.ErrorIs(t, err, ErrNoTransitions)
+}
+
+func TestRedisBackend_PrioritizedSample(t *testing.T) {
+	backend := newTestRedisBackend(t, miniredis.RunT(t), 1000)
+	ctx := context.Background()
+
+	ids, err := backend.StoreBatch(ctx, []*Transition{
+		{EnvID: "test", State: []byte{0}},
+		{EnvID: "test", State: []byte{1}},
+		{EnvID: "test", State: []byte{2}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, backend.UpdatePriorities(ctx, ids, []float32{0.5, 1e-9, 1e-9}))
+	// IDs that are not stored are ignored.
+	require.NoError(t, backend.UpdatePriorities(ctx, []string{"missing"}, []float32{3}))
+	assert.Error(t, backend.UpdatePriorities(ctx, ids, []float32{1}))
+
+	for _, reservoir := range []bool{false, true} {
+		transitions, weights, err := backend.Sample(ctx, &SampleConfig{BatchSize: 1, Prioritized: true, PriorityAlpha: 1, Reservoir: reservoir})
+		require.NoError(t, err)
+		require.Len(t, transitions, 1)
+		assert.Equal(t, ids[0], transitions[0].ID)
+		assert.Equal(t, float32(0.5), transitions[0].Priority)
+		assert.Len(t, weights, 1)
+	}
+
+	stats, err := backend.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), stats.TotalTransitions)
+}
+
+func TestRedisBackend_ClearAndEvict(t *testing.T) {
+	backend := newTestRedisBackend(t, miniredis.RunT(t), 5)
+	ctx := context.Background()
+	start := time.Now()
+
+	for i := 0; i < 8; i++ {
+		env := "tictactoe"
+		if i%2 == 1 {
+			env = "gridworld"
+		}
+		require.NoError(t, backend.Store(ctx, &Transition{ID: string(rune('a' + i)), EnvID: env, EpisodeID: env, Timestamp: start.Add(time.Duration(i) * time.Second)}))
+	}
+	// The oldest three were evicted.
+	assert.Equal(t, []string{"d", "e", "f", "g", "h"}, exportIDs(t, backend))
+
+	cutoff := start.Add(5 * time.Second)
+	cleared, err := backend.Clear(ctx, "gridworld", &cutoff, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), cleared)
+	assert.Equal(t, []string{"e", "f", "g", "h"}, exportIDs(t, backend))
+
+	cleared, err = backend.Clear(ctx, "", nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), cleared)
+	assert.Equal(t, []string{"h"}, exportIDs(t, backend))
+
+	stats, err := backend.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"gridworld": 1}, stats.TransitionsByEnv)
+	assert.Equal(t, uint64(1), stats.TotalEpisodes)
+
+	cleared, err = backend.Clear(ctx, "", &cutoff, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), cleared)
+	cutoff = start.Add(time.Hour)
+	cleared, err = backend.Clear(ctx, "", &cutoff, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), cleared)
+
+	stats, err = backend.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), stats.TotalTransitions)
+	assert.Equal(t, uint64(0), stats.StorageBytes)
+	assert.Empty(t, stats.TransitionsByEnv)
+	assert.Nil(t, stats.OldestTimestamp)
+}
+
+func TestRedisBackend_SharedBetweenReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	first := newTestRedisBackend(t, server, 100)
+	second := newTestRedisBackend(t, server, 100)
+	ctx := context.Background()
+
+	require.NoError(t, first.Store(ctx, &Transition{ID: "a", EnvID: "test", State: []byte{1}}))
+	require.NoError(t, second.Store(ctx, &Transition{ID: "b", EnvID: "test", State: []byte{2}, Timestamp: time.Now().Add(time.Second)}))
+	require.NoError(t, second.UpdatePriorities(ctx, []string{"a"}, []float32{4}))
+
+	transitions, _, err := first.Sample(ctx, &SampleConfig{BatchSize: 2, EnvID: "test"})
+	require.NoError(t, err)
+	assert.Len(t, transitions, 2)
+	assert.Equal(t, []string{"a", "b"}, exportIDs(t, first))
+
+	var priority float32
+	require.NoError(t, first.Export(ctx, "test", func(transition *Transition) error {
+		if transition.ID == "a" {
+			priority = transition.Priority
+		}
+		return nil
+	}))
+	assert.Equal(t, float32(4), priority)
+
+	// Backends with different prefixes do not see each other's transitions.
+	other, err := NewRedisBackend(RedisOptions{Addr: server.Addr(), KeyPrefix: "other"})
+	require.NoError(t, err)
+	defer other.Close()
+	assert.Empty(t, exportIDs(t, other))
+}
+
+func TestRedisBackend_OutcomeFilters(t *testing.T) {
+	backend := newTestRedisBackend(t, miniredis.RunT(t), 1000)
+	storeEpisodes(t, backend)
+	ctx := context.Background()
+
+	transitions, _, err := backend.Sample(ctx, &SampleConfig{BatchSize: 100, EnvID: "tictactoe", Terminal: true})
+	require.NoError(t, err)
+	assert.Len(t, transitions, 10)
+	for _, transition := range transitions {
+		assert.True(t, transition.Done)
+	}
+
+	transitions, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 100, Winning: true})
+	require.NoError(t, err)
+	assert.Len(t, transitions, 10)
+	for _, transition := range transitions {
+		assert.Equal(t, "ep0", transition.EpisodeID)
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import "context"
+
+// SampleSequences samples config.BatchSize windows of length consecutive
+// transitions of an episode from backend, for recurrent learners. Each window
+// starts at a transition drawn by backend.Sample and continues with the
+// transitions that follow it in its episode. A window that runs past the end
+// of its episode, or starts at a transition stored without one, is padded
+// with nils. The weights are those of the windows' first transitions.
+func SampleSequences(ctx context.Context, backend Backend, config *SampleConfig, length int) ([][]*Transition, []float32, error) {
+	starts, weights, err := backend.Sample(ctx, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Batches often draw several windows from one episode.
+	episodes := make(map[string][]*Transition)
+	windows := make([][]*Transition, len(starts))
+	for i, start := range starts {
+		window := make([]*Transition, length)
+		window[0] = start
+		if start.EpisodeID != "" {
+			episode, cached := episodes[start.EpisodeID]
+			if !cached {
+				episode, err = backend.GetEpisode(ctx, start.EpisodeID)
+				if err != nil {
+					return nil, nil, err
+				}
+				episodes[start.EpisodeID] = episode
+			}
+			for j, transition := range episode {
+				if transition.ID == start.ID {
+					copy(window[1:], episode[j+1:])
+					break
+				}
+			}
+		}
+		windows[i] = window
+	}
+	return windows, weights, nil
+}
+
+// NStepReturn folds a window sampled by SampleSequences into the n-step
+// return of its first transition: the rewards of its steps up to the first
+// done or padding, discounted by gamma. It returns a copy of the first
+// transition with the next state, next observation and done of the last step
+// summed, the return, and the number of steps summed.
+func NStepReturn(window []*Transition, gamma float32) (*Transition, float32, uint32) {
+	folded := *window[0]
+	var sum float32
+	discount := float32(1)
+	var steps uint32
+	for _, transition := range window {
+		if transition == nil {
+			break
+		}
+		sum += discount * transition.Reward
+		discount *= gamma
+		steps++
+		folded.NextState = transition.NextState
+		folded.NextObservation = transition.NextObservation
+		folded.Done = transition.Done
+		if transition.Done {
+			break
+		}
+	}
+	return &folded, sum, steps
+}
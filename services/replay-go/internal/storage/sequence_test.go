@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleSequences(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+
+	ctx := context.Background()
+	_, err := backend.StoreBatch(ctx, []*Transition{
+		{ID: "b", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 1},
+		{ID: "a", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 0},
+		{ID: "c", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 2},
+		{ID: "lone", EnvID: "tictactoe"},
+	})
+	require.NoError(t, err)
+
+	windows, weights, err := SampleSequences(ctx, backend, &SampleConfig{BatchSize: 4}, 2)
+	require.NoError(t, err)
+	require.Len(t, windows, 4)
+	assert.Len(t, weights, 4)
+
+	byStart := map[string][]string{}
+	for _, window := range windows {
+		require.Len(t, window, 2)
+		var ids []string
+		for _, transition := range window {
+			if transition == nil {
+				ids = append(ids, "")
+				continue
+			}
+			ids = append(ids, transition.ID)
+		}
+		byStart[ids[0]] = ids
+	}
+	assert.Equal(t, map[string][]string{
+		"a":    {"a", "b"},
+		"b":    {"b", "c"},
+		"c":    {"c", ""},
+		"lone": {"lone", ""},
+	}, byStart)
+
+	_, _, err = SampleSequences(ctx, backend, &SampleConfig{BatchSize: 1, EnvID: "missing"}, 2)
+	assert.ErrorIs(t, err, ErrNoTransitions)
+}
+
+func TestNStepReturn(t *testing.T) {
+	window := []*Transition{
+		{ID: "a", Reward: 1, NextState: []byte{1}},
+		{ID: "b", Reward: 2, NextState: []byte{2}},
+		{ID: "c", Reward: 4, NextState: []byte{3}, Done: true},
+		{ID: "d", Reward: 8, NextState: []byte{4}},
+	}
+
+	folded, sum, steps := NStepReturn(window[:2], 0.5)
+	assert.Equal(t, "a", folded.ID)
+	assert.Equal(t, []byte{2}, folded.NextState)
+	assert.False(t, folded.Done)
+	assert.Equal(t, float32(2), sum)
+	assert.Equal(t, uint32(2), steps)
+	assert.Equal(t, []byte{1}, window[0].NextState, "the window is unchanged")
+
+	// Returns stop at the end of the episode.
+	folded, sum, steps = NStepReturn(window, 0.5)
+	assert.Equal(t, []byte{3}, folded.NextState)
+	assert.True(t, folded.Done)
+	assert.Equal(t, float32(3), sum)
+	assert.Equal(t, uint32(3), steps)
+
+	// And at padding.
+	_, sum, steps = NStepReturn([]*Transition{window[0], nil, nil}, 0.5)
+	assert.Equal(t, float32(1), sum)
+	assert.Equal(t, uint32(1), steps)
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot writes every stored transition, with its priority, to w as
+// gzipped JSON lines, oldest first. The transitions are copied under the
+// lock, so the snapshot is consistent while writers carry on.
+func (m *MemoryBackend) Snapshot(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	if err := m.Export(context.Background(), "", func(transition *Transition) error {
+		return enc.Encode(transition)
+	}); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the buffer's contents with the snapshot read from r and
+// rebuilds its indexes. Transitions beyond the maximum size are evicted,
+// oldest first, as if they had just been stored. If the snapshot cannot be
+// read, the buffer is left unchanged.
+func (m *MemoryBackend) Restore(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var transitions []*Transition
+	dec := json.NewDecoder(gz)
+	for {
+		transition := &Transition{}
+		err := dec.Decode(transition)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		transitions = append(transitions, transition)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transitions = make(map[string]*Transition, len(transitions))
+	m.episodes = make(map[string][]string)
+	m.envIndex = make(map[string][]string)
+	m.runIndex = make(map[string][]string)
+	m.timeIndex = make([]string, 0, len(transitions))
+	// The sum trees are rebuilt by the next prioritized sample.
+	m.priorities = nil
+	if m.eviction != nil {
+		m.eviction = newEvictionIndex(m.eviction.policy, m.eviction.policies)
+	}
+	if m.hashes != nil {
+		m.hashes = make(map[contentHash]string, len(transitions))
+	}
+	for _, transition := range transitions {
+		m.add(transition)
+	}
+	return nil
+}
+
+// SaveSnapshot writes a snapshot of the buffer to path. The previous snapshot
+// there is replaced only once the new one is complete.
+func (m *MemoryBackend) SaveSnapshot(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	err = m.Snapshot(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// LoadSnapshot restores the buffer from the snapshot at path, reporting false
+// when there is none.
+func (m *MemoryBackend) LoadSnapshot(path string) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer f.Close()
+	if err := m.Restore(f); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBackend_SnapshotRestore(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	_, err := backend.StoreBatch(ctx, []*Transition{
+		{ID: "a", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 0, State: []byte{1}, Timestamp: now, Metadata: map[string]string{"run_id": "run-1"}},
+		{ID: "b", EnvID: "tictactoe", EpisodeID: "ep1", StepNumber: 1, State: []byte{2}, Timestamp: now.Add(time.Second)},
+		{ID: "c", EnvID: "gridworld", Timestamp: now.Add(2 * time.Second)},
+	})
+	require.NoError(t, err)
+	require.NoError(t, backend.UpdatePriorities(ctx, []string{"b"}, []float32{5}))
+
+	path := filepath.Join(t.TempDir(), "buffer.snapshot")
+	require.NoError(t, backend.SaveSnapshot(path))
+
+	restored := NewMemoryBackend(1000)
+	defer restored.Close()
+	loaded, err := restored.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.True(t, loaded)
+
+	// The transitions, their priorities and the indexes are all back.
+	var exported []*Transition
+	require.NoError(t, restored.Export(ctx, "", func(transition *Transition) error {
+		exported = append(exported, transition)
+		return nil
+	}))
+	require.Len(t, exported, 3)
+	assert.Equal(t, "a", exported[0].ID)
+	assert.True(t, now.Equal(exported[0].Timestamp))
+	assert.Equal(t, float32(5), exported[1].Priority)
+	episode, err := restored.GetEpisode(ctx, "ep1")
+	require.NoError(t, err)
+	require.Len(t, episode, 2)
+	assert.Equal(t, []byte{2}, episode[1].State)
+	stats, err := restored.GetStats(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.TransitionsByEnv["tictactoe"])
+	assert.Equal(t, uint64(1), stats.TransitionsByRun["run-1"])
+
+	// A smaller buffer keeps the newest transitions.
+	small := NewMemoryBackend(2)
+	defer small.Close()
+	_, err = small.LoadSnapshot(path)
+	require.NoError(t, err)
+	_, exists := small.transitions["a"]
+	assert.False(t, exists)
+	assert.Equal(t, 2, small.len())
+}
+
+func TestMemoryBackend_RestoreErrors(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+
+	loaded, err := backend.LoadSnapshot(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.False(t, loaded)
+
+	// A corrupt snapshot leaves the buffer as it was.
+	require.NoError(t, backend.Store(context.Background(), &Transition{ID: "a"}))
+	var buf bytes.Buffer
+	require.NoError(t, backend.Snapshot(&buf))
+	assert.Error(t, backend.Restore(bytes.NewReader(buf.Bytes()[:buf.Len()/2])))
+	assert.Equal(t, 1, backend.len())
+
+	path := filepath.Join(t.TempDir(), "corrupt")
+	require.NoError(t, os.WriteFile(path, []byte("not a snapshot"), 0o644))
+	_, err = backend.LoadSnapshot(path)
+	assert.Error(t, err)
+}
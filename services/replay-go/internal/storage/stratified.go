@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// Stratum is a share of a stratified batch, selected by the SampleConfig
+// filters of the same names.
+type Stratum struct {
+	// Fraction is the least fraction of the batch drawn from the stratum.
+	Fraction float32
+	Terminal bool
+	Winning  bool
+}
+
+// SampleStratified samples config.BatchSize transitions from backend,
+// drawing each stratum's fraction of the batch, rounded up, from the
+// transitions it selects, and the rest from all of config's candidates. This
+// gives sparse-reward environments batches with a learning signal. A stratum
+// with too few transitions gives what it has, and the rest of the batch makes
+// up the difference. The strata come first in the batch, in order. Each draw
+// weighs its transitions as backend.Sample does, so prioritized weights are
+// relative to the draw, and a transition may be drawn by more than one.
+func SampleStratified(ctx context.Context, backend Backend, config *SampleConfig, strata []Stratum) ([]*Transition, []float32, error) {
+	var sampled []*Transition
+	var weights []float32
+	draw := func(n uint32, stratum Stratum) error {
+		drawConfig := *config
+		drawConfig.BatchSize = n
+		drawConfig.Terminal = config.Terminal || stratum.Terminal
+		drawConfig.Winning = config.Winning || stratum.Winning
+		transitions, drawWeights, err := backend.Sample(ctx, &drawConfig)
+		if err != nil {
+			return err
+		}
+		sampled = append(sampled, transitions...)
+		weights = append(weights, drawWeights...)
+		return nil
+	}
+
+	for _, stratum := range strata {
+		// The tolerance keeps e.g. float32(0.3) of 10 from rounding up to 4.
+		n := uint32(math.Ceil(float64(stratum.Fraction)*float64(config.BatchSize) - 1e-4))
+		if remaining := config.BatchSize - uint32(len(sampled)); n > remaining {
+			n = remaining
+		}
+		if n == 0 {
+			continue
+		}
+		if err := draw(n, stratum); err != nil && !errors.Is(err, ErrNoTransitions) {
+			return nil, nil, err
+		}
+	}
+	if rest := config.BatchSize - uint32(len(sampled)); rest > 0 {
+		err := draw(rest, Stratum{})
+		if err != nil && !(errors.Is(err, ErrNoTransitions) && len(sampled) > 0) {
+			return nil, nil, err
+		}
+	}
+	return sampled, weights, nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeEpisodes stores 10 episodes of 10 steps. Only ep0 ends with a positive
+// reward, so 10 transitions are winning and 10 terminal.
+func storeEpisodes(t *testing.T, backend Backend) {
+	t.Helper()
+	var batch []*Transition
+	for episode := 0; episode < 10; episode++ {
+		for step := 0; step < 10; step++ {
+			transition := &Transition{EnvID: "tictactoe", EpisodeID: fmt.Sprintf("ep%d", episode), StepNumber: uint32(step)}
+			if step == 9 {
+				transition.Done = true
+				transition.Reward = -1
+				if episode == 0 {
+					transition.Reward = 1
+				}
+			}
+			batch = append(batch, transition)
+		}
+	}
+	_, err := backend.StoreBatch(context.Background(), batch)
+	require.NoError(t, err)
+}
+
+func TestMemoryBackend_OutcomeFilters(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+	storeEpisodes(t, backend)
+	ctx := context.Background()
+
+	transitions, _, err := backend.Sample(ctx, &SampleConfig{BatchSize: 100, Terminal: true})
+	require.NoError(t, err)
+	assert.Len(t, transitions, 10)
+	for _, transition := range transitions {
+		assert.True(t, transition.Done)
+	}
+
+	transitions, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 100, Winning: true, Prioritized: true, PriorityAlpha: 1})
+	require.NoError(t, err)
+	assert.Len(t, transitions, 10)
+	for _, transition := range transitions {
+		assert.Equal(t, "ep0", transition.EpisodeID)
+	}
+
+	transitions, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 100, Terminal: true, Winning: true})
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, float32(1), transitions[0].Reward)
+}
+
+func TestSampleStratified(t *testing.T) {
+	backend := NewMemoryBackend(1000)
+	defer backend.Close()
+	storeEpisodes(t, backend)
+	ctx := context.Background()
+
+	transitions, weights, err := SampleStratified(ctx, backend, &SampleConfig{BatchSize: 10}, []Stratum{
+		{Fraction: 0.3, Terminal: true},
+		{Fraction: 0.2, Winning: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, transitions, 10)
+	assert.Len(t, weights, 10)
+	for _, transition := range transitions[:3] {
+		assert.True(t, transition.Done)
+	}
+	for _, transition := range transitions[3:5] {
+		assert.Equal(t, "ep0", transition.EpisodeID)
+	}
+
+	// A stratum with too few transitions leaves the rest to the others.
+	transitions, _, err = SampleStratified(ctx, backend, &SampleConfig{BatchSize: 30}, []Stratum{{Fraction: 1, Terminal: true, Winning: true}})
+	require.NoError(t, err)
+	require.Len(t, transitions, 30)
+	assert.Equal(t, float32(1), transitions[0].Reward)
+
+	_, _, err = SampleStratified(ctx, backend, &SampleConfig{BatchSize: 10, EnvID: "chess"}, []Stratum{{Fraction: 0.5, Terminal: true}})
+	assert.ErrorIs(t, err, ErrNoTransitions)
+}
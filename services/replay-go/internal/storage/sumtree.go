@@ -0,0 +1,202 @@
+package storage
+
+import "math/rand"
+
+// sumTreeMinCapacity is the number of slots a new sumTree starts with.
+const sumTreeMinCapacity = 16
+
+// sumTree is a complete binary tree over transition slots in which every
+// node holds the sum of the values below it, so a slot's value can be
+// changed, and a slot drawn in proportion to its value, in O(log n).
+type sumTree struct {
+	nodes []float64      // nodes[1] is the root; slot i is leaf nodes[len(items)+i]
+	items []*Transition  // by slot
+	slots map[string]int // transition ID -> slot
+	free  []int          // emptied slots below next
+	next  int            // first slot never used
+}
+
+func newSumTree() *sumTree {
+	return &sumTree{
+		nodes: make([]float64, 2*sumTreeMinCapacity),
+		items: make([]*Transition, sumTreeMinCapacity),
+		slots: make(map[string]int),
+	}
+}
+
+// len returns the number of transitions in the tree.
+func (t *sumTree) len() int {
+	return len(t.slots)
+}
+
+// total returns the sum of every value.
+func (t *sumTree) total() float64 {
+	return t.nodes[1]
+}
+
+// set adds transition with value, or changes its value.
+func (t *sumTree) set(transition *Transition, value float64) {
+	slot, ok := t.slots[transition.ID]
+	if !ok {
+		slot = t.allocate()
+		t.slots[transition.ID] = slot
+	}
+	t.items[slot] = transition
+	t.update(slot, value)
+}
+
+// remove drops the transition with id, if the tree holds it.
+func (t *sumTree) remove(id string) {
+	slot, ok := t.slots[id]
+	if !ok {
+		return
+	}
+	t.update(slot, 0)
+	t.items[slot] = nil
+	delete(t.slots, id)
+	t.free = append(t.free, slot)
+}
+
+// sample draws up to n distinct transitions, each draw in proportion to the
+// values of those not yet drawn, and returns them with their importance
+// weights.
+func (t *sumTree) sample(rng *rand.Rand, n int) ([]*Transition, []float32) {
+	total, count := t.total(), t.len()
+	if n > count {
+		n = count
+	}
+	sampled := make([]*Transition, 0, n)
+	weights := make([]float32, 0, n)
+	drawn := make([]int, 0, n)
+	values := make([]float64, 0, n)
+	for len(sampled) < n && t.total() > 0 {
+		slot := t.find(rng.Float64() * t.total())
+		value := t.value(slot)
+		if value <= 0 {
+			break
+		}
+		sampled = append(sampled, t.items[slot])
+		weights = append(weights, importanceWeight(value/total, count))
+		drawn = append(drawn, slot)
+		values = append(values, value)
+		// Zero the slot so it is not drawn again, until the batch is done.
+		t.update(slot, 0)
+	}
+	for i, slot := range drawn {
+		t.update(slot, values[i])
+	}
+	return sampled, weights
+}
+
+// find returns the slot whose cumulative range contains target, where
+// 0 <= target < total.
+func (t *sumTree) find(target float64) int {
+	capacity := len(t.items)
+	i := 1
+	for i < capacity {
+		left, right := t.nodes[2*i], t.nodes[2*i+1]
+		// Rounding can leave target at or past the end of a subtree; never
+		// descend into an empty one.
+		if left > 0 && (target < left || right <= 0) {
+			i = 2 * i
+		} else {
+			target -= left
+			i = 2*i + 1
+		}
+	}
+	return i - capacity
+}
+
+func (t *sumTree) value(slot int) float64 {
+	return t.nodes[len(t.items)+slot]
+}
+
+// update sets a slot's value and recomputes the sums above it from their
+// children, so removing a value leaves no rounding error behind.
+func (t *sumTree) update(slot int, value float64) {
+	i := len(t.items) + slot
+	t.nodes[i] = value
+	for i > 1 {
+		i /= 2
+		t.nodes[i] = t.nodes[2*i] + t.nodes[2*i+1]
+	}
+}
+
+func (t *sumTree) allocate() int {
+	if n := len(t.free); n > 0 {
+		slot := t.free[n-1]
+		t.free = t.free[:n-1]
+		return slot
+	}
+	if t.next == len(t.items) {
+		t.grow()
+	}
+	t.next++
+	return t.next - 1
+}
+
+// grow doubles the tree's capacity, keeping every slot.
+func (t *sumTree) grow() {
+	capacity := 2 * len(t.items)
+	nodes := make([]float64, 2*capacity)
+	copy(nodes[capacity:], t.nodes[len(t.items):])
+	for i := capacity - 1; i >= 1; i-- {
+		nodes[i] = nodes[2*i] + nodes[2*i+1]
+	}
+	items := make([]*Transition, capacity)
+	copy(items, t.items)
+	t.nodes, t.items = nodes, items
+}
+
+// priorityIndex keeps the scaled priorities of every transition, and of
+// each environment's, in sum trees for prioritized sampling.
+type priorityIndex struct {
+	alpha float32
+	all   *sumTree
+	byEnv map[string]*sumTree
+}
+
+func newPriorityIndex(transitions map[string]*Transition, alpha float32) *priorityIndex {
+	index := &priorityIndex{
+		alpha: alpha,
+		all:   newSumTree(),
+		byEnv: make(map[string]*sumTree),
+	}
+	for _, transition := range transitions {
+		index.set(transition)
+	}
+	return index
+}
+
+// set adds transition, or updates its priority.
+func (p *priorityIndex) set(transition *Transition) {
+	value := scalePriority(transition.Priority, p.alpha)
+	p.all.set(transition, value)
+	if transition.EnvID != "" {
+		tree, ok := p.byEnv[transition.EnvID]
+		if !ok {
+			tree = newSumTree()
+			p.byEnv[transition.EnvID] = tree
+		}
+		tree.set(transition, value)
+	}
+}
+
+func (p *priorityIndex) remove(transition *Transition) {
+	p.all.remove(transition.ID)
+	if tree, ok := p.byEnv[transition.EnvID]; ok {
+		tree.remove(transition.ID)
+		if tree.len() == 0 {
+			delete(p.byEnv, transition.EnvID)
+		}
+	}
+}
+
+// tree returns the tree of envID's transitions, or of all of them when
+// envID is empty; nil when there are none.
+func (p *priorityIndex) tree(envID string) *sumTree {
+	if envID == "" {
+		return p.all
+	}
+	return p.byEnv[envID]
+}
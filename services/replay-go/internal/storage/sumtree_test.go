@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSumTree_SetRemoveGrow(t *testing.T) {
+	tree := newSumTree()
+	for i := 0; i < 40; i++ {
+		tree.set(&Transition{ID: fmt.Sprint(i)}, float64(i))
+	}
+	assert.Equal(t, 40, tree.len())
+	assert.Equal(t, float64(780), tree.total())
+
+	tree.set(&Transition{ID: "39"}, 1)
+	tree.remove("0")
+	tree.remove("10")
+	tree.remove("missing")
+	assert.Equal(t, 38, tree.len())
+	assert.Equal(t, float64(780-38-10), tree.total())
+
+	// Removed slots are reused before the tree grows.
+	capacity := len(tree.items)
+	tree.set(&Transition{ID: "a"}, 2)
+	tree.set(&Transition{ID: "b"}, 3)
+	assert.Equal(t, capacity, len(tree.items))
+	assert.Equal(t, float64(780-38-10+5), tree.total())
+}
+
+func TestSumTree_Find(t *testing.T) {
+	tree := newSumTree()
+	tree.set(&Transition{ID: "a"}, 1)
+	tree.set(&Transition{ID: "b"}, 0)
+	tree.set(&Transition{ID: "c"}, 2)
+
+	assert.Equal(t, "a", tree.items[tree.find(0)].ID)
+	assert.Equal(t, "a", tree.items[tree.find(0.999)].ID)
+	assert.Equal(t, "c", tree.items[tree.find(1)].ID)
+	// A target rounded up to the total still lands on a transition.
+	assert.Equal(t, "c", tree.items[tree.find(3)].ID)
+}
+
+func TestSumTree_Sample(t *testing.T) {
+	tree := newSumTree()
+	transitions := []*Transition{
+		{ID: "low", Priority: 0.1},
+		{ID: "medium", Priority: 1.0},
+		{ID: "high", Priority: 2.4},
+	}
+	for _, transition := range transitions {
+		tree.set(transition, scalePriority(transition.Priority, 0.6))
+	}
+
+	rng := rand.New(rand.NewSource(7))
+	iterations := 2000
+	counts := map[string]int{}
+	for i := 0; i < iterations; i++ {
+		sampled, weights := tree.sample(rng, 1)
+		require.Len(t, sampled, 1)
+		require.Len(t, weights, 1)
+		counts[sampled[0].ID]++
+	}
+	probabilities := computePrioritizedProbabilities(transitions, 0.6)
+	for i, transition := range transitions {
+		assert.InDelta(t, float64(iterations)*probabilities[i], float64(counts[transition.ID]), float64(iterations)*0.05, transition.ID)
+	}
+
+	// A batch never repeats a transition, and the tree is unchanged after it.
+	total := tree.total()
+	sampled, weights := tree.sample(rng, 10)
+	require.Len(t, sampled, 3)
+	seen := map[string]bool{}
+	for i, transition := range sampled {
+		seen[transition.ID] = true
+		assert.InDelta(t, importanceWeight(probabilities[indexOf(transitions, transition)], 3), weights[i], 1e-6)
+	}
+	assert.Len(t, seen, 3)
+	assert.Equal(t, total, tree.total())
+}
+
+func indexOf(transitions []*Transition, transition *Transition) int {
+	for i, candidate := range transitions {
+		if candidate == transition {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestMemoryBackend_PriorityIndexFollowsWrites(t *testing.T) {
+	backend := NewMemoryBackend(3)
+	defer backend.Close()
+
+	backend.rng = rand.New(rand.NewSource(1))
+	ctx := context.Background()
+	_, err := backend.StoreBatch(ctx, []*Transition{
+		{ID: "a", EnvID: "tictactoe", Priority: 1},
+		{ID: "b", EnvID: "tictactoe", Priority: 1},
+		{ID: "c", EnvID: "gridworld", Priority: 1},
+	})
+	require.NoError(t, err)
+
+	config := &SampleConfig{BatchSize: 1, EnvID: "tictactoe", Prioritized: true, PriorityAlpha: 1}
+	_, _, err = backend.Sample(ctx, config)
+	require.NoError(t, err)
+	require.NotNil(t, backend.priorities)
+
+	// Once one transition holds nearly all the priority, it is nearly always drawn.
+	require.NoError(t, backend.UpdatePriorities(ctx, []string{"a", "b"}, []float32{1e-6, 1000}))
+	for i := 0; i < 20; i++ {
+		sampled, _, err := backend.Sample(ctx, config)
+		require.NoError(t, err)
+		assert.Equal(t, "b", sampled[0].ID)
+	}
+
+	// Stores, evictions and clears reach the trees.
+	require.NoError(t, backend.Store(ctx, &Transition{ID: "d", EnvID: "gridworld", Priority: 1}))
+	assert.Equal(t, 3, backend.priorities.all.len())
+	_, exists := backend.priorities.all.slots["a"]
+	assert.False(t, exists, "the evicted transition leaves the tree")
+	_, err = backend.Clear(ctx, "gridworld", nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.priorities.all.len())
+	assert.Nil(t, backend.priorities.tree("missing"))
+
+	_, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 1, EnvID: "missing", Prioritized: true, PriorityAlpha: 1})
+	assert.ErrorIs(t, err, ErrNoTransitions)
+
+	// Another alpha rebuilds the trees.
+	_, _, err = backend.Sample(ctx, &SampleConfig{BatchSize: 2, Prioritized: true, PriorityAlpha: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, float32(0.5), backend.priorities.alpha)
+	assert.Equal(t, 2, backend.priorities.all.len())
+}
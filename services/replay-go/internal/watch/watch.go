@@ -0,0 +1,111 @@
+// Package watch fans the transitions stored through the replay service out to
+// its Watch streams.
+package watch
+
+import (
+	"sync"
+
+	"github.com/cartridge/proto/runctx"
+	"github.com/cartridge/replay/internal/storage"
+)
+
+// eventBuffer is how many events a watcher may fall behind by before further
+// transitions are dropped for it.
+const eventBuffer = 64
+
+// Filter selects the transitions a watcher is sent. Empty fields match
+// every transition.
+type Filter struct {
+	EnvID string
+	RunID string
+}
+
+// Match reports whether transition passes the filter.
+func (f Filter) Match(transition *storage.Transition) bool {
+	if f.EnvID != "" && transition.EnvID != f.EnvID {
+		return false
+	}
+	return f.RunID == "" || transition.Metadata[runctx.RunIDKey] == f.RunID
+}
+
+// Event carries transitions stored together, e.g. by one StoreBatch.
+type Event struct {
+	Transitions []*storage.Transition
+	// Dropped counts the matching transitions not sent since the previous
+	// event because the watcher fell behind.
+	Dropped uint64
+}
+
+// Watcher receives the events that match its filter.
+type Watcher struct {
+	filter  Filter
+	events  chan Event
+	dropped uint64 // guarded by the hub's mutex
+}
+
+// Events returns the watcher's events.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Hub publishes stored transitions to its watchers.
+type Hub struct {
+	mu       sync.Mutex
+	watchers map[*Watcher]struct{}
+}
+
+// NewHub returns a hub with no watchers.
+func NewHub() *Hub {
+	return &Hub{watchers: make(map[*Watcher]struct{})}
+}
+
+// Subscribe adds a watcher of the transitions that match filter.
+func (h *Hub) Subscribe(filter Filter) *Watcher {
+	w := &Watcher{filter: filter, events: make(chan Event, eventBuffer)}
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+	return w
+}
+
+// Unsubscribe removes a watcher.
+func (h *Hub) Unsubscribe(w *Watcher) {
+	h.mu.Lock()
+	delete(h.watchers, w)
+	h.mu.Unlock()
+}
+
+// Publish sends each watcher the transitions that match its filter. It never
+// blocks: a watcher whose buffer is full misses the event, and is told how
+// many transitions it missed with its next one. The transitions are copied,
+// so the caller's may be changed afterwards.
+func (h *Hub) Publish(transitions []*storage.Transition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.watchers) == 0 {
+		return
+	}
+
+	copies := make([]*storage.Transition, len(transitions))
+	for i, transition := range transitions {
+		copied := *transition
+		copies[i] = &copied
+	}
+	for w := range h.watchers {
+		var matched []*storage.Transition
+		for _, transition := range copies {
+			if w.filter.Match(transition) {
+				matched = append(matched, transition)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		select {
+		case w.events <- Event{Transitions: matched, Dropped: w.dropped}:
+			w.dropped = 0
+		default:
+			w.dropped += uint64(len(matched))
+		}
+	}
+}
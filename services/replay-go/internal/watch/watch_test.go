@@ -0,0 +1,74 @@
+package watch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cartridge/replay/internal/storage"
+)
+
+func TestHub_Filters(t *testing.T) {
+	hub := NewHub()
+	all := hub.Subscribe(Filter{})
+	tictactoe := hub.Subscribe(Filter{EnvID: "tictactoe"})
+	run := hub.Subscribe(Filter{EnvID: "tictactoe", RunID: "run-1"})
+
+	stored := []*storage.Transition{
+		{ID: "a", EnvID: "tictactoe", Metadata: map[string]string{"run_id": "run-1"}},
+		{ID: "b", EnvID: "tictactoe"},
+		{ID: "c", EnvID: "gridworld", Metadata: map[string]string{"run_id": "run-1"}},
+	}
+	hub.Publish(stored)
+	stored[0].Priority = 5
+
+	ids := func(w *Watcher) []string {
+		t.Helper()
+		select {
+		case event := <-w.Events():
+			var ids []string
+			for _, transition := range event.Transitions {
+				ids = append(ids, transition.ID)
+			}
+			return ids
+		default:
+			return nil
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, ids(all))
+	assert.Equal(t, []string{"a", "b"}, ids(tictactoe))
+	assert.Equal(t, []string{"a"}, ids(run))
+
+	// Watchers only hear of matching transitions.
+	hub.Publish([]*storage.Transition{{ID: "d", EnvID: "gridworld"}})
+	assert.Nil(t, ids(tictactoe))
+	assert.Equal(t, []string{"d"}, ids(all))
+
+	hub.Unsubscribe(all)
+	hub.Publish(stored)
+	assert.Nil(t, ids(all))
+}
+
+func TestHub_CopiesAndDrops(t *testing.T) {
+	hub := NewHub()
+	w := hub.Subscribe(Filter{})
+
+	stored := &storage.Transition{ID: "a", Priority: 1}
+	for i := 0; i < eventBuffer+3; i++ {
+		hub.Publish([]*storage.Transition{stored})
+	}
+	stored.Priority = 2
+
+	for i := 0; i < eventBuffer; i++ {
+		event := <-w.Events()
+		assert.Zero(t, event.Dropped)
+		assert.Equal(t, float32(1), event.Transitions[0].Priority, "events carry copies")
+	}
+
+	// The next event reports what was missed while the buffer was full.
+	hub.Publish([]*storage.Transition{stored})
+	event := <-w.Events()
+	require.Len(t, event.Transitions, 1)
+	assert.Equal(t, uint64(3), event.Dropped)
+}
@@ -0,0 +1,75 @@
+// Package replaytest runs the replay service in-process, backed by memory, for
+// tests in other modules that need a real replay endpoint to talk to.
+package replaytest
+
+import (
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/cartridge/chaos"
+	replayv1 "github.com/cartridge/proto/replay/v1"
+	"github.com/cartridge/replay/internal/service"
+	"github.com/cartridge/replay/internal/storage"
+)
+
+// Server is a replay service listening on a local port.
+type Server struct {
+	// Addr is the host:port clients dial.
+	Addr string
+
+	server  *grpc.Server
+	backend *storage.MemoryBackend
+}
+
+// Option configures a Server.
+type Option func(*options)
+
+type options struct {
+	faults chaos.Config
+}
+
+// WithFaults injects faults into the server as the service does when built
+// with the chaos tag: errors and latency on matching calls, and partial
+// StoreBatch failures.
+func WithFaults(cfg chaos.Config) Option {
+	return func(o *options) { o.faults = cfg }
+}
+
+// NewServer starts a replay service holding up to maxSize transitions on a
+// loopback port, alongside the grpc.health.v1 Health service. It panics if it cannot listen, as httptest.NewServer does.
+func NewServer(maxSize uint64, opts ...Option) *Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("replaytest: failed to listen: %v", err))
+	}
+	backend := storage.NewMemoryBackend(maxSize)
+	var serverOpts []grpc.ServerOption
+	injector := chaos.New(o.faults)
+	if injector != nil {
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(injector.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(injector.StreamServerInterceptor()),
+		)
+	}
+	server := grpc.NewServer(serverOpts...)
+	replayv1.RegisterReplayServer(server, service.NewReplayService(storage.WithFaults(backend, injector)))
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(replayv1.Replay_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(lis)
+	return &Server{Addr: lis.Addr().String(), server: server, backend: backend}
+}
+
+// Close stops the server, ending any calls in flight, and releases the buffer.
+func (s *Server) Close() {
+	s.server.Stop()
+	s.backend.Close()
+}
@@ -0,0 +1,124 @@
+package replaytest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/cartridge/chaos"
+	replayv1 "github.com/cartridge/proto/replay/v1"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(10)
+	defer server.Close()
+
+	conn, err := grpc.NewClient(server.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := replayv1.NewReplayClient(conn)
+
+	ctx := context.Background()
+	_, err = client.StoreTransition(ctx, &replayv1.StoreTransitionRequest{
+		Transition: &replayv1.Transition{EnvId: "tictactoe", EpisodeId: "episode-1", State: []byte{0}, Action: []byte{4}},
+	})
+	require.NoError(t, err)
+
+	stats, err := client.GetStats(ctx, &replayv1.GetStatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stats.TotalTransitions)
+	assert.Equal(t, uint64(1), stats.TransitionsByEnv["tictactoe"])
+}
+
+func TestServerExport(t *testing.T) {
+	server := NewServer(10)
+	defer server.Close()
+
+	conn, err := grpc.NewClient(server.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := replayv1.NewReplayClient(conn)
+
+	ctx := context.Background()
+	var batch []*replayv1.Transition
+	for i := 0; i < 5; i++ {
+		batch = append(batch, &replayv1.Transition{EnvId: "tictactoe", StepNumber: uint32(i), Timestamp: uint64(1700000000 + i), Action: []byte{4}})
+	}
+	_, err = client.StoreBatch(ctx, &replayv1.StoreBatchRequest{Transitions: batch})
+	require.NoError(t, err)
+
+	stream, err := client.Export(ctx, &replayv1.ExportRequest{BatchSize: 2})
+	require.NoError(t, err)
+	var sizes []int
+	var steps []uint32
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		sizes = append(sizes, len(resp.Transitions))
+		for _, transition := range resp.Transitions {
+			steps = append(steps, transition.StepNumber)
+		}
+	}
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+	assert.Equal(t, []uint32{0, 1, 2, 3, 4}, steps) // Oldest first
+
+	// Time bounds are inclusive.
+	stream, err = client.Export(ctx, &replayv1.ExportRequest{MinTimestamp: 1700000001, MaxTimestamp: 1700000003})
+	require.NoError(t, err)
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	steps = steps[:0]
+	for _, transition := range resp.Transitions {
+		steps = append(steps, transition.StepNumber)
+	}
+	assert.Equal(t, []uint32{1, 2, 3}, steps)
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestServerHealth(t *testing.T) {
+	server := NewServer(10)
+	defer server.Close()
+
+	conn, err := grpc.NewClient(server.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	for _, name := range []string{"", replayv1.Replay_ServiceDesc.ServiceName} {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: name})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status, name)
+	}
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "cartridge.other.v1.Other"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServerWithFaults(t *testing.T) {
+	server := NewServer(10, WithFaults(chaos.Config{ErrorRate: 1, Match: []string{"GetStats"}}))
+	defer server.Close()
+
+	conn, err := grpc.NewClient(server.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := replayv1.NewReplayClient(conn)
+
+	_, err = client.GetStats(context.Background(), &replayv1.GetStatsRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = client.StoreTransition(context.Background(), &replayv1.StoreTransitionRequest{
+		Transition: &replayv1.Transition{EnvId: "tictactoe", State: []byte{0}, Action: []byte{4}},
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,3 @@
+module github.com/cartridge/sigv4
+
+go 1.21
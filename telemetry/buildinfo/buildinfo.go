@@ -0,0 +1,67 @@
+// Package buildinfo reports the build a Cartridge Go service is running, on
+// GET /version and in the services' Version RPCs. Release builds stamp it
+// through -ldflags:
+//
+//	go build -ldflags "\
+//	  -X github.com/cartridge/telemetry/buildinfo.Version=v1.4.0 \
+//	  -X github.com/cartridge/telemetry/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/cartridge/telemetry/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with -ldflags -X; see the package documentation.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Info describes a service's build.
+type Info struct {
+	Service   string `json:"service"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build of service. Without -ldflags, the commit and build
+// time fall back to the VCS stamp go build records when building from a
+// checkout, and the version to "dev".
+func Get(service string) Info {
+	info := Info{
+		Service:   service,
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// Handler serves Get(service) as JSON.
+func Handler(service string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Get(service))
+	})
+}
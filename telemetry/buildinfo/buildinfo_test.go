@@ -0,0 +1,33 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetPrefersStampedValues(t *testing.T) {
+	defer func(version, commit, buildTime string) {
+		Version, Commit, BuildTime = version, commit, buildTime
+	}(Version, Commit, BuildTime)
+
+	Version, Commit, BuildTime = "", "", ""
+	if info := Get("replay"); info.Version != "dev" || info.Service != "replay" || info.GoVersion == "" {
+		t.Fatalf("expected an unstamped dev build, got %+v", info)
+	}
+
+	Version, Commit, BuildTime = "v1.4.0", "abc123", "2024-05-01T12:00:00Z"
+	rec := httptest.NewRecorder()
+	Handler("replay").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var info Info
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if info.Version != "v1.4.0" || info.Commit != "abc123" || info.BuildTime != "2024-05-01T12:00:00Z" {
+		t.Fatalf("expected the stamped build, got %+v", info)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("expected JSON, got %q", got)
+	}
+}
@@ -0,0 +1,267 @@
+// Package capture records the API traffic a service receives to a file, so
+// that cmd/apireplay in the orchestrator can replay production-shaped
+// workloads against a new build. Requests are sanitized before they are
+// written: headers and gRPC metadata are never recorded, and JSON fields whose
+// names suggest credentials are redacted.
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protocols a record can carry.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// MaxHTTPBody is the largest HTTP request body recorded. Larger requests are
+// served as usual but not captured.
+const MaxHTTPBody = 1 << 20
+
+// Redacted replaces the values of sensitive fields.
+const Redacted = "REDACTED"
+
+// Record is one captured call, stored as a line of JSON.
+type Record struct {
+	Time     time.Time `json:"time"`
+	Protocol string    `json:"protocol"`
+	// Method is the full gRPC method, such as /replay.v1.Replay/Sample, or
+	// the HTTP method.
+	Method string `json:"method"`
+	// Path is the HTTP request path and query.
+	Path string `json:"path,omitempty"`
+	// Body is the sanitized request: the message in protojson for gRPC, the
+	// JSON body for HTTP.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Status is the outcome the server gave: a gRPC code name such as "OK",
+	// or an HTTP status code.
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Recorder appends records to a file until it reaches its size limit. It is
+// safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	file     *os.File
+	written  int64
+	maxBytes int64
+	dropped  uint64
+}
+
+// NewRecorder appends records to path, creating it if needed, until the file
+// holds maxBytes; zero means no limit.
+func NewRecorder(path string, maxBytes int64) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Recorder{file: file, written: info.Size(), maxBytes: maxBytes}, nil
+}
+
+// ErrFull is returned by Record once the file has reached its size limit.
+var ErrFull = errors.New("capture file is full")
+
+// Record appends rec to the file.
+func (r *Recorder) Record(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxBytes > 0 && r.written+int64(len(line)) > r.maxBytes {
+		r.dropped++
+		return ErrFull
+	}
+	n, err := r.file.Write(line)
+	r.written += int64(n)
+	return err
+}
+
+// Dropped returns how many records the size limit turned away.
+func (r *Recorder) Dropped() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+// Close closes the file.
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// UnaryServerInterceptor records unary calls to the given full method names,
+// or to every method when none are given. Recording never fails a call.
+func (r *Recorder) UnaryServerInterceptor(methods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		msg, ok := req.(proto.Message)
+		if !ok || !selected(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		// Marshal before the handler runs, as handlers may modify the request.
+		body, marshalErr := protojson.Marshal(msg)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		if marshalErr == nil {
+			_ = r.Record(Record{
+				Time:       start.UTC(),
+				Protocol:   ProtocolGRPC,
+				Method:     info.FullMethod,
+				Body:       Sanitize(body),
+				Status:     status.Code(err).String(),
+				DurationMS: milliseconds(time.Since(start)),
+			})
+		}
+		return resp, err
+	}
+}
+
+// HTTP returns middleware recording the requests match selects. Bodies that
+// are not JSON or exceed MaxHTTPBody are not captured.
+func (r *Recorder) HTTP(match func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !match(req) {
+				next.ServeHTTP(w, req)
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(req.Body, MaxHTTPBody+1))
+			// Serve the request with its body intact, whatever was read.
+			req.Body = readCloser{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+			capturable := err == nil && len(body) <= MaxHTTPBody && (len(body) == 0 || json.Valid(body))
+
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, req)
+			if !capturable {
+				return
+			}
+			rec := Record{
+				Time:       start.UTC(),
+				Protocol:   ProtocolHTTP,
+				Method:     req.Method,
+				Path:       req.URL.RequestURI(),
+				Status:     strconv.Itoa(sw.status),
+				DurationMS: milliseconds(time.Since(start)),
+			}
+			if len(body) > 0 {
+				rec.Body = Sanitize(body)
+			}
+			_ = r.Record(rec)
+		})
+	}
+}
+
+// Sanitize returns body with the values of fields whose names suggest
+// credentials replaced by Redacted, at any depth. It returns nil if body is
+// not JSON.
+func Sanitize(body []byte) json.RawMessage {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil
+	}
+	sanitized, err := json.Marshal(redact(doc))
+	if err != nil {
+		return nil
+	}
+	return sanitized
+}
+
+func redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if sensitive(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = redact(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
+
+func sensitive(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "_", ""))
+	for _, word := range []string{"token", "secret", "password", "authorization", "apikey", "credential"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func selected(methods []string, method string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// statusWriter captures the status code written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
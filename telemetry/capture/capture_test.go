@@ -0,0 +1,86 @@
+package capture
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRecordAndReadBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	recorder, err := NewRecorder(path, 0)
+	if err != nil {
+		t.Fatalf("new recorder: %v", err)
+	}
+
+	intercept := recorder.UnaryServerInterceptor("/test.v1.Test/Store")
+	req, _ := structpb.NewStruct(map[string]any{"batch": []any{"a", "b"}, "runToken": "abc"})
+	failing := func(context.Context, any) (any, error) { return nil, status.Error(codes.Unavailable, "down") }
+	if _, err := intercept(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/test.v1.Test/Store"}, failing); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the handler's error, got %v", err)
+	}
+	intercept(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/test.v1.Test/Other"}, failing)
+
+	handler := recorder.HTTP(func(r *http.Request) bool { return strings.HasSuffix(r.URL.Path, "/heartbeat") })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if r.URL.Path == "/runs/r1/heartbeat" && string(body) != `{"step":7,"api_key":"k"}` {
+				t.Errorf("handler saw body %q", body)
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/runs/r1/heartbeat?x=1", strings.NewReader(`{"step":7,"api_key":"k"}`)))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/runs/r1/logs", strings.NewReader(`{}`)))
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	records, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected the selected gRPC call and heartbeat, got %+v", records)
+	}
+	grpcRec, httpRec := records[0], records[1]
+	if grpcRec.Protocol != ProtocolGRPC || grpcRec.Method != "/test.v1.Test/Store" || grpcRec.Status != "Unavailable" {
+		t.Fatalf("unexpected gRPC record %+v", grpcRec)
+	}
+	if got := string(grpcRec.Body); got != `{"batch":["a","b"],"runToken":"REDACTED"}` {
+		t.Fatalf("expected a sanitized body, got %s", got)
+	}
+	if httpRec.Protocol != ProtocolHTTP || httpRec.Method != http.MethodPost || httpRec.Path != "/runs/r1/heartbeat?x=1" || httpRec.Status != "202" {
+		t.Fatalf("unexpected HTTP record %+v", httpRec)
+	}
+	if got := string(httpRec.Body); got != `{"api_key":"REDACTED","step":7}` {
+		t.Fatalf("expected a sanitized body, got %s", got)
+	}
+}
+
+func TestRecorderStopsAtLimit(t *testing.T) {
+	recorder, err := NewRecorder(filepath.Join(t.TempDir(), "capture.jsonl"), 200)
+	if err != nil {
+		t.Fatalf("new recorder: %v", err)
+	}
+	defer recorder.Close()
+
+	rec := Record{Protocol: ProtocolHTTP, Method: http.MethodPost, Path: "/heartbeats", Status: "200"}
+	if err := recorder.Record(rec); err != nil {
+		t.Fatalf("first record: %v", err)
+	}
+	if err := recorder.Record(rec); err != ErrFull {
+		t.Fatalf("expected ErrFull past the limit, got %v", err)
+	}
+	if recorder.Dropped() != 1 {
+		t.Fatalf("expected one dropped record, got %d", recorder.Dropped())
+	}
+}
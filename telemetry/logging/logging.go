@@ -0,0 +1,111 @@
+// Package logging builds the zerolog logger every Cartridge Go service logs
+// through, so log lines share one shape: JSON with an RFC 3339 timestamp, the
+// service and version, and the trace ID of the request being served.
+package logging
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cartridge/telemetry"
+)
+
+// New returns a logger configured by cfg that writes to stdout.
+func New(cfg telemetry.Config) (zerolog.Logger, error) {
+	return NewWithWriter(cfg, os.Stdout)
+}
+
+// NewWithWriter is New writing to w.
+func NewWithWriter(cfg telemetry.Config, w io.Writer) (zerolog.Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return zerolog.Nop(), err
+	}
+	level := zerolog.InfoLevel
+	if cfg.LogLevel != "" {
+		parsed, err := zerolog.ParseLevel(cfg.LogLevel)
+		if err != nil {
+			return zerolog.Nop(), err
+		}
+		level = parsed
+	}
+	if cfg.LogFormat == "console" {
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}
+	}
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	ctx := zerolog.New(w).Level(level).With().Timestamp().Str("service", cfg.Service)
+	if cfg.Version != "" {
+		ctx = ctx.Str("version", cfg.Version)
+	}
+	return ctx.Logger(), nil
+}
+
+// WithTrace returns logger annotated with the trace and span IDs in ctx, if any.
+func WithTrace(ctx context.Context, logger zerolog.Logger) zerolog.Logger {
+	span := trace.SpanContextFromContext(ctx)
+	if !span.IsValid() {
+		return logger
+	}
+	return logger.With().
+		Str("trace_id", span.TraceID().String()).
+		Str("span_id", span.SpanID().String()).
+		Logger()
+}
+
+// UnaryServerInterceptor logs every unary call with its outcome and duration.
+// Failed calls log at warn, or error for codes that indicate a server fault.
+func UnaryServerInterceptor(logger zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls, logged
+// once the stream ends.
+func StreamServerInterceptor(logger zerolog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, logger zerolog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	logger = WithTrace(ctx, logger)
+	var event *zerolog.Event
+	switch {
+	case err == nil:
+		event = logger.Debug()
+	case isServerFault(code):
+		event = logger.Error().Err(err)
+	default:
+		event = logger.Warn().Err(err)
+	}
+	event.
+		Str("grpc_method", method).
+		Str("grpc_code", code.String()).
+		Dur("duration", time.Since(start)).
+		Msg("grpc call")
+}
+
+// isServerFault reports whether a gRPC status code blames the server rather
+// than the request.
+func isServerFault(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
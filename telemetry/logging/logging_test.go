@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cartridge/telemetry"
+)
+
+func TestNewWithWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewWithWriter(telemetry.Config{Service: "replay", Version: "1.2.3", LogLevel: "warn"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info().Msg("dropped")
+	logger.Warn().Msg("kept")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("want one JSON line, got %q: %v", buf.String(), err)
+	}
+	for key, want := range map[string]any{"service": "replay", "version": "1.2.3", "level": "warn", "message": "kept"} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %v", key, line[key], want)
+		}
+	}
+	if _, ok := line["time"]; !ok {
+		t.Error("line has no time")
+	}
+
+	if _, err := NewWithWriter(telemetry.Config{Service: "replay", LogLevel: "loud"}, &buf); err == nil {
+		t.Error("unknown level accepted")
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewWithWriter(telemetry.Config{Service: "replay", LogLevel: "debug"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := UnaryServerInterceptor(logger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/replay.v1.Replay/Sample"}
+
+	cases := []struct {
+		err       error
+		wantLevel string
+		wantCode  string
+	}{
+		{nil, "debug", "OK"},
+		{status.Error(codes.InvalidArgument, "bad batch"), "warn", "InvalidArgument"},
+		{errors.New("boom"), "error", "Unknown"},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		intercept(context.Background(), nil, info, func(context.Context, any) (any, error) { return nil, c.err })
+		var line map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatalf("want one JSON line, got %q: %v", buf.String(), err)
+		}
+		if line["level"] != c.wantLevel || line["grpc_code"] != c.wantCode || line["grpc_method"] != info.FullMethod {
+			t.Errorf("error %v logged %v", c.err, line)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package middleware instruments HTTP handlers and gRPC servers and clients
+// with the metrics and traces every Cartridge Go service reports.
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// unmatchedRoute labels requests that RouteFunc could not place, keeping
+// arbitrary paths out of metric labels.
+const unmatchedRoute = "unmatched"
+
+// RouteFunc returns the route template a request matched, such as
+// "/api/v1/runs/{runID}", or "" if it matched none.
+type RouteFunc func(*http.Request) string
+
+// HTTPMetrics are the request metrics recorded by HTTP.
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+// NewHTTPMetrics registers the HTTP request metrics on reg.
+func NewHTTPMetrics(reg prometheus.Registerer) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by method, route template and status code.",
+		}, []string{"method", "route", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time to serve HTTP requests, by method and route template.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests currently being served.",
+		}),
+	}
+	reg.MustRegister(m.requests, m.duration, m.inFlight)
+	return m
+}
+
+// HTTP returns middleware that records each request in m and in a server span
+// named after its route. route labels both; a nil route labels every request
+// as unmatched. Spans continue any trace the caller propagated.
+func HTTP(service string, m *HTTPMetrics, route RouteFunc) func(http.Handler) http.Handler {
+	label := func(r *http.Request) string {
+		if route == nil {
+			return unmatchedRoute
+		}
+		if template := route(r); template != "" {
+			return template
+		}
+		return unmatchedRoute
+	}
+	return func(next http.Handler) http.Handler {
+		measured := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			m.inFlight.Inc()
+			defer m.inFlight.Dec()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			template := label(r)
+			m.requests.WithLabelValues(r.Method, template, strconv.Itoa(rec.status)).Inc()
+			m.duration.WithLabelValues(r.Method, template).Observe(time.Since(start).Seconds())
+		})
+		return otelhttp.NewHandler(measured, service,
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method + " " + label(r)
+			}),
+		)
+	}
+}
+
+// statusRecorder captures the status code written through it. It passes
+// flushes through so streaming responses keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush satisfies http.Flusher when the underlying writer does.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		r.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHTTPRecordsRouteTemplates(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	route := func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/runs/") {
+			return "/runs/{runID}"
+		}
+		return ""
+	}
+	handler := HTTP("test", NewHTTPMetrics(reg), route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/runs/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, path := range []string{"/runs/a", "/runs/b", "/runs/missing", "/elsewhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := `
+# HELP http_requests_total HTTP requests served, by method, route template and status code.
+# TYPE http_requests_total counter
+http_requests_total{code="200",method="GET",route="/runs/{runID}"} 2
+http_requests_total{code="200",method="GET",route="unmatched"} 1
+http_requests_total{code="404",method="GET",route="/runs/{runID}"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "http_requests_total"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPPreservesFlusher(t *testing.T) {
+	var flushable bool
+	handler := HTTP("test", NewHTTPMetrics(prometheus.NewRegistry()), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, flushable = w.(http.Flusher)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !flushable {
+		t.Fatal("instrumented response writer does not implement http.Flusher")
+	}
+}
@@ -0,0 +1,67 @@
+// Package telemetry holds the observability settings shared by the Cartridge Go
+// services. The subpackages turn them into a logger (logging), a Prometheus
+// registry (metrics), an OpenTelemetry tracer provider (tracing) and the HTTP
+// and gRPC middleware that record both (middleware).
+package telemetry
+
+import (
+	"github.com/cartridge/conf"
+	"github.com/cartridge/telemetry/buildinfo"
+)
+
+// Config describes how a service reports logs, metrics and traces.
+type Config struct {
+	// Service names the service in log lines and trace resources.
+	Service string `yaml:"-"`
+	// Version is the build version, recorded alongside Service. It defaults
+	// to the version stamped by buildinfo.
+	Version string `env:"SERVICE_VERSION"`
+
+	// LogLevel is the minimum level logged: trace, debug, info, warn or error.
+	LogLevel string `env:"LOG_LEVEL" default:"info"`
+	// LogFormat is json, the default, or console for human-readable output.
+	LogFormat string `env:"LOG_FORMAT" default:"json"`
+
+	// OTLPEndpoint is the host:port of an OTLP gRPC collector. Tracing is
+	// disabled when it is empty, though trace context is still propagated.
+	OTLPEndpoint string `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	// OTLPInsecure sends traces without TLS.
+	OTLPInsecure bool `yaml:"otlp_insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	// SampleRatio is the fraction of new traces recorded; spans continuing a
+	// remote trace follow the caller's decision.
+	SampleRatio float64 `env:"OTEL_TRACES_SAMPLER_ARG" default:"1"`
+}
+
+// FromEnv reads the configuration for service from the standard variables:
+// LOG_LEVEL, LOG_FORMAT, SERVICE_VERSION, OTEL_EXPORTER_OTLP_ENDPOINT,
+// OTEL_EXPORTER_OTLP_INSECURE and OTEL_TRACES_SAMPLER_ARG.
+func FromEnv(service string) (Config, error) {
+	cfg := Config{Service: service, Version: buildinfo.Version}
+	if err := conf.Load(&cfg, conf.WithFile("")); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the log settings, which are otherwise only rejected when the
+// logger is built, and the sample ratio.
+func (c Config) Validate() error {
+	var errs conf.Errors
+	if c.Service == "" {
+		errs.Add("service", "is required")
+	}
+	switch c.LogLevel {
+	case "", "trace", "debug", "info", "warn", "error":
+	default:
+		errs.Add("log_level", "must be trace, debug, info, warn or error, got %q", c.LogLevel)
+	}
+	switch c.LogFormat {
+	case "", "json", "console":
+	default:
+		errs.Add("log_format", "must be json or console, got %q", c.LogFormat)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		errs.Add("sample_ratio", "must be between 0 and 1, got %v", c.SampleRatio)
+	}
+	return errs.Err()
+}